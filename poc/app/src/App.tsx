@@ -2,7 +2,7 @@ import { useState, useEffect, useContext, useCallback } from "react";
 import { DependencyGraph } from "./components/DependencyGraph";
 import { Terminal } from "./components/Terminal";
 import { DataTab, type BehavioralData } from "./components/DataTab";
-import { AnalysisTab, type SecurityAssessment } from "./components/AnalysisTab";
+import { AnalysisTab, type SecurityAssessment, type InstallScript } from "./components/AnalysisTab";
 import Header from "./components/Header";
 import { SocketContext } from "./providers/SocketProvider";
 import {
@@ -75,6 +75,7 @@ export default function App() {
   // Per-package data maps keyed by package_id ("name@version")
   const [behavioralDataMap, setBehavioralDataMap] = useState<Record<string, BehavioralData>>({});
   const [analysisMap, setAnalysisMap] = useState<Record<string, SecurityAssessment>>({});
+  const [installScriptsMap, setInstallScriptsMap] = useState<Record<string, InstallScript[]>>({});
 
   const { send, subscribe, isConnected } = useContext(SocketContext);
   const [expandedNodeIds, setExpandedNodeIds] = useState<Set<string>>(new Set());
@@ -88,6 +89,7 @@ export default function App() {
           const payload = msg.payload as {
             root_package: { id: string; name: string; version: string };
             nodes: PackageNode[];
+            edges: { from: string; to: string }[];
             edge_count: number;
           };
 
@@ -112,32 +114,21 @@ export default function App() {
             style: dataGatheringPkgStyle,
           }));
 
-          // Build edges from dependencies
-          const newEdges: Edge[] = [];
-
-          payload.nodes.forEach((pkg) => {
-            if (pkg.dependencies && Object.keys(pkg.dependencies).length > 0) {
-              Object.entries(pkg.dependencies).forEach(([depName]) => {
-                const dependentNode = payload.nodes.find(n => n.name === depName);
-                if (dependentNode) {
-                  newEdges.push({
-                    id: `${pkg.id}-${dependentNode.id}`,
-                    source: pkg.id,
-                    target: dependentNode.id,
-                    animated: true,
-                    markerEnd: {
-                      type: MarkerType.ArrowClosed,
-                      color: "#22c55e",
-                    },
-                    style: {
-                      stroke: "#22c55e",
-                      strokeWidth: 2,
-                    },
-                  });
-                }
-              });
-            }
-          });
+          // Build edges from the dependencies the server resolved to node IDs
+          const newEdges: Edge[] = (payload.edges ?? []).map(({ from, to }) => ({
+            id: `${from}-${to}`,
+            source: from,
+            target: to,
+            animated: true,
+            markerEnd: {
+              type: MarkerType.ArrowClosed,
+              color: "#22c55e",
+            },
+            style: {
+              stroke: "#22c55e",
+              strokeWidth: 2,
+            },
+          }));
 
           const layoutedNodes = getLayoutedElements(newNodes, newEdges);
           setNodes(layoutedNodes);
@@ -198,10 +189,39 @@ export default function App() {
           break;
         }
 
+        case "package_install_scripts": {
+          const payload = msg.payload as {
+            package_id: string;
+            name: string;
+            version: string;
+            scripts: InstallScript[];
+          };
+          setInstallScriptsMap((prev) => ({ ...prev, [payload.package_id]: payload.scripts }));
+          break;
+        }
+
+        case "package_analysis_stream": {
+          const payload = msg.payload as {
+            package_id: string;
+            kind: "reasoning" | "text" | "tool_call";
+            text: string;
+            tool?: string;
+          };
+          if (payload.kind === "tool_call") {
+            addLog(`⚙ ${payload.package_id} → ${payload.tool}: ${payload.text}`);
+          } else {
+            const prefix = payload.kind === "reasoning" ? "… " : "» ";
+            addLog(`${prefix}${payload.package_id}: ${payload.text}`);
+          }
+          break;
+        }
+
         case "complete": {
-          const payload = msg.payload as { success: boolean; message: string };
+          const payload = msg.payload as { success: boolean; message: string; cancelled?: boolean };
           if (payload.success) {
             addLog(`✓ ${payload.message}`);
+          } else if (payload.cancelled) {
+            addLog(`⚠ ${payload.message} (partial results)`);
           } else {
             addLog(`✗ ${payload.message}`);
           }
@@ -256,6 +276,7 @@ export default function App() {
     setEdges([]);
     setBehavioralDataMap({});
     setAnalysisMap({});
+    setInstallScriptsMap({});
 
     addLog("→ Starting analysis...");
     send({
@@ -266,6 +287,14 @@ export default function App() {
     });
   };
 
+  const cancelAnalysis = () => {
+    if (!isAnalyzing) {
+      return;
+    }
+    addLog("→ Cancelling analysis...");
+    send({ type: "cancel", payload: {} });
+  };
+
   const addLog = (log: string) => {
     setLogs((curLogs) => [...curLogs, log]);
   };
@@ -325,6 +354,7 @@ export default function App() {
     >
       <Header
         startAnalysis={startAnalysis}
+        cancelAnalysis={cancelAnalysis}
         uploadedFile={uploadedFile}
         onFileUpload={handleFileUpload}
         onFileRemove={handleFileRemove}
@@ -437,6 +467,7 @@ export default function App() {
                 <AnalysisTab
                   selectedNode={selectedNode}
                   assessment={selectedNode ? (analysisMap[selectedNode] ?? null) : null}
+                  installScripts={selectedNode ? (installScriptsMap[selectedNode] ?? []) : []}
                 />
               )}
             </div>
@@ -1,4 +1,4 @@
-import { Shield, ShieldAlert, AlertTriangle } from 'lucide-react';
+import { Shield, ShieldAlert, AlertTriangle, Terminal } from 'lucide-react';
 
 export type SecurityAssessment = {
   is_malicious: boolean;
@@ -7,12 +7,62 @@ export type SecurityAssessment = {
   indicators?: string[];
 };
 
+export type InstallScript = {
+  hook: string;
+  command: string;
+  implicit?: boolean;
+  files?: {
+    path: string;
+    sha256: string;
+    size: number;
+    content: string;
+    truncated?: boolean;
+  }[];
+};
+
 interface AnalysisTabProps {
   selectedNode: string | null;
   assessment: SecurityAssessment | null;
+  installScripts: InstallScript[];
+}
+
+// InstallScripts shows exactly what npm runs when installing the package
+function InstallScripts({ scripts }: { scripts: InstallScript[] }) {
+  if (scripts.length === 0) return null;
+  return (
+    <div className="rounded-lg border border-[#374151] bg-[#111827] p-4 text-left">
+      <div className="flex items-center gap-2 text-xs uppercase tracking-wider text-gray-500 mb-3">
+        <Terminal className="w-3.5 h-3.5 text-orange-400" />
+        <span>Install Scripts ({scripts.length})</span>
+      </div>
+      <div className="space-y-3">
+        {scripts.map((script) => (
+          <div key={script.hook} className="space-y-2">
+            <div className="text-sm font-mono">
+              <span className="text-orange-400">{script.hook}</span>
+              <span className="text-gray-500">: </span>
+              <span className="text-gray-200 break-all">{script.command}</span>
+              {script.implicit && <span className="text-gray-500 text-xs"> (npm default for binding.gyp)</span>}
+            </div>
+            {script.files?.map((file) => (
+              <div key={file.path} className="rounded border border-[#374151] bg-[#0a0a0a]">
+                <div className="px-2 py-1 border-b border-[#374151] text-[10px] font-mono text-gray-500 flex justify-between gap-2">
+                  <span className="truncate">{file.path}</span>
+                  <span className="flex-shrink-0">
+                    {file.size} bytes{file.truncated ? ", truncated" : ""}
+                  </span>
+                </div>
+                <pre className="p-2 text-xs font-mono text-gray-300 max-h-64 overflow-auto whitespace-pre-wrap break-all">{file.content}</pre>
+              </div>
+            ))}
+          </div>
+        ))}
+      </div>
+    </div>
+  );
 }
 
-export function AnalysisTab({ selectedNode, assessment }: AnalysisTabProps) {
+export function AnalysisTab({ selectedNode, assessment, installScripts }: AnalysisTabProps) {
   if (!selectedNode) {
     return (
       <div className="h-full flex items-center justify-center" style={{ background: "#0a0a0a" }}>
@@ -28,6 +78,11 @@ export function AnalysisTab({ selectedNode, assessment }: AnalysisTabProps) {
           <Shield className="w-8 h-8 text-green-500 mx-auto opacity-60" />
           <p className="text-gray-400 text-sm">No anomalies detected</p>
           <p className="text-gray-600 text-xs">Clean behavioral diff — package treated as safe</p>
+          {installScripts.length > 0 && (
+            <div className="pt-4 px-4 max-w-2xl">
+              <InstallScripts scripts={installScripts} />
+            </div>
+          )}
         </div>
       </div>
     );
@@ -119,6 +174,8 @@ export function AnalysisTab({ selectedNode, assessment }: AnalysisTabProps) {
             </ul>
           </div>
         )}
+
+        <InstallScripts scripts={installScripts} />
       </div>
 
       {/* Footer */}
@@ -10,6 +10,7 @@ export type BehavioralData = {
       ips: Record<string, number>;
       dns_records: Record<string, number>;
     };
+    risk_flags?: string[];
   }>;
   count_processes: number;
   baseline_source: string;
@@ -17,6 +18,19 @@ export type BehavioralData = {
   removed_files: number;
   removed_commands: number;
   removed_syscalls: number;
+  risk_flags?: string[];
+  phases?: {
+    phases: string[];
+    rows: { kind: string; value: string; counts: Record<string, number> }[];
+  };
+  intel?: {
+    indicator: string;
+    kind: string;
+    asn?: number;
+    as_org?: string;
+    country?: string;
+    verdicts?: string[];
+  }[];
 };
 
 interface DataTabProps {
@@ -37,6 +51,16 @@ export const DataTab = ({ selectedNode, data }: DataTabProps) => {
     ));
   };
 
+  const renderRiskFlags = (flags?: string[]) => {
+    if (!flags || flags.length === 0) return null;
+
+    return flags.map((flag) => (
+      <span key={flag} className="text-[10px] px-1 rounded bg-red-900/30 text-red-300 border border-red-800">
+        {flag.replace(/_/g, " ")}
+      </span>
+    ));
+  };
+
   if (!selectedNode) {
     return (
       <div className="h-full flex items-center justify-center" style={{ background: "#0a0a0a" }}>
@@ -71,8 +95,63 @@ export const DataTab = ({ selectedNode, data }: DataTabProps) => {
             <span className="text-red-400">-{data.removed_syscalls} Syscalls</span>
           </span>
         </div>
+        {data.risk_flags && data.risk_flags.length > 0 && (
+          <div className="flex flex-wrap gap-2 mt-2">{renderRiskFlags(data.risk_flags)}</div>
+        )}
       </div>
       <div className="flex-1 overflow-y-auto p-4 space-y-8">
+        {data.phases && data.phases.phases.length > 1 && (
+          <section className="border-l-2 border-[#374151] pl-4 py-1">
+            <div className="flex items-center gap-2 mb-4 text-sm font-semibold text-gray-300">
+              <Activity className="w-4 h-4" /> By Test Phase
+            </div>
+            <table className="w-full font-mono text-xs bg-black border border-gray-800">
+              <thead>
+                <tr className="text-[10px] uppercase text-gray-500">
+                  <th className="text-left p-2">Indicator</th>
+                  {data.phases.phases.map((phase) => (
+                    <th key={phase} className="text-right p-2">{phase}</th>
+                  ))}
+                </tr>
+              </thead>
+              <tbody>
+                {data.phases.rows.map((row) => (
+                  <tr key={`${row.kind}:${row.value}`} className="border-t border-gray-900">
+                    <td className="p-2 truncate max-w-0 w-full">
+                      <span className="text-gray-500">{row.kind}</span>{" "}
+                      <span style={{ color: Object.keys(row.counts).length === 1 ? "#f87171" : "#4ade80" }}>{row.value}</span>
+                    </td>
+                    {data.phases!.phases.map((phase) => (
+                      <td key={phase} className="text-right p-2 text-gray-400">{row.counts[phase] ?? "-"}</td>
+                    ))}
+                  </tr>
+                ))}
+              </tbody>
+            </table>
+          </section>
+        )}
+        {data.intel && data.intel.length > 0 && (
+          <section className="border-l-2 border-[#374151] pl-4 py-1">
+            <div className="flex items-center gap-2 mb-4 text-sm font-semibold text-gray-300">
+              <Network className="w-4 h-4" /> Threat Intel
+            </div>
+            <div className="font-mono text-xs bg-black p-3 rounded border border-gray-800 space-y-1">
+              {data.intel.map((finding) => (
+                <div key={finding.indicator} className="flex justify-between items-center gap-2">
+                  <span className="truncate" style={{ color: finding.verdicts?.length ? "#f87171" : "#4ade80" }}>
+                    {finding.indicator}
+                  </span>
+                  <span className="text-gray-500 text-[10px] text-right">
+                    {[
+                      finding.asn ? `AS${finding.asn} ${finding.as_org ?? ""} (${finding.country ?? ""})` : "",
+                      ...(finding.verdicts ?? []),
+                    ].filter(Boolean).join(" · ")}
+                  </span>
+                </div>
+              ))}
+            </div>
+          </section>
+        )}
         {Object.entries(data.per_process).map(([processName, instances]) => (
           <section key={processName} className="border-l-2 border-[#374151] pl-4 py-1">
             <div className="flex items-center gap-2 mb-4">
@@ -83,6 +162,7 @@ export const DataTab = ({ selectedNode, data }: DataTabProps) => {
               <span className="text-[10px] bg-blue-900/30 px-1 rounded text-blue-300">
                 {Object.keys(instances).length} instance(s)
               </span>
+              {renderRiskFlags(instances.risk_flags)}
             </div>
             <div className="space-y-6 mb-8 last:mb-0 bg-[#111827]/50 p-4 rounded-lg">
               <div>
@@ -1,8 +1,9 @@
-import { Play, Loader2, Wifi, WifiOff } from "lucide-react";
+import { Play, Loader2, Wifi, WifiOff, Square } from "lucide-react";
 import { FileUpload } from "./FileUpload";
 
 interface HeaderProps {
   startAnalysis: () => void;
+  cancelAnalysis: () => void;
   uploadedFile: File | null;
   onFileUpload: (file: File) => void;
   onFileRemove: () => void;
@@ -12,6 +13,7 @@ interface HeaderProps {
 
 function Header({
   startAnalysis,
+  cancelAnalysis,
   uploadedFile,
   onFileUpload,
   onFileRemove,
@@ -92,6 +94,20 @@ function Header({
             )}
           </button>
         )}
+
+        {isAnalyzing && (
+          <button
+            onClick={cancelAnalysis}
+            className="flex items-center gap-2 px-4 py-2 rounded-lg transition-colors hover:bg-opacity-80 cursor-pointer font-medium"
+            style={{
+              background: "#7f1d1d",
+              color: "#ef4444",
+            }}
+          >
+            <Square className="w-4 h-4" />
+            <span>Cancel</span>
+          </button>
+        )}
       </div>
     </header>
   );
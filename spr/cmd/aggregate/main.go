@@ -1,23 +1,82 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// processKeyMode is how processes are keyed in the output (-process-key)
+var processKeyMode = aggregate.KeyAncestry
+
+// syscallThreshold decides which syscall increases over the baseline are kept
+// (-syscall-ratio, -syscall-min-delta)
+var syscallThreshold = aggregate.DefaultSyscallThreshold
+
+// accessThreshold decides which files and commands the baseline also has are
+// kept for being used far more often (-access-ratio, -access-min-delta)
+var accessThreshold = aggregate.DefaultAccessThreshold
+
+// allowlist drops known-benign activity from diffs (-allowlist); nil disables it
+var allowlist *aggregate.Allowlist
+
+// maxLineSize is the longest trace line aggregated, in bytes (-max-line-mb)
+var maxLineSize = aggregate.DefaultMaxLineSize
+
+// outputFormat is what per-process stats and diffs are written as (-format)
+var outputFormat = aggregate.FormatJSON
+
+// newProcessAggregator creates an aggregator using the selected key mode
+func newProcessAggregator() *aggregate.ProcessAggregator {
+	aggregator := aggregate.NewProcessAggregator()
+	aggregator.SetKeyMode(processKeyMode)
+	aggregator.SetMaxLineSize(maxLineSize)
+	return aggregator
+}
+
+// newProxyAggregator creates a proxy log aggregator using the line limit
+func newProxyAggregator() *aggregate.ProxyAggregator {
+	aggregator := aggregate.NewProxyAggregator()
+	aggregator.SetMaxLineSize(maxLineSize)
+	return aggregator
+}
+
 func main() {
 	var (
 		inputFile   = flag.String("input", "", "Path to behavior.jsonl file (required if -dir not used)")
 		dirPath     = flag.String("dir", "", "Path to directory containing package subdirectories with behavior.jsonl files")
 		collection  = flag.String("collection", "default", "Collection name (used when -input specified)")
-		outputFile  = flag.String("output", "", "Output JSON file (optional, defaults to stdout; used with -input)")
+		outputFile  = flag.String("output", "", "Output file (optional, defaults to stdout; used with -input, and -dir with -format csv or parquet)")
 		dedupSource = flag.String("dedup-source", "", "Path to safe baseline JSON file for deduplication (required for batch mode)")
+		proxyFile   = flag.String("proxy", "", "Path to intercepting proxy log (proxy.jsonl) to merge into the output (optional, used with -input)")
+		compare     = flag.String("compare", "", "Compare environment variants of one package: name=behavior.jsonl,name2=behavior.jsonl (optional)")
+		processKey  = flag.String("process-key", string(aggregate.KeyAncestry), "Key processes by name, ancestry or cmdline")
+		ratio       = flag.Float64("syscall-ratio", aggregate.DefaultSyscallThreshold.Ratio, "Keep a syscall count only if above baseline times this ratio")
+		minDelta    = flag.Int("syscall-min-delta", aggregate.DefaultSyscallThreshold.MinDelta, "Keep a syscall count only if more than this many calls above the baseline")
+		zScore      = flag.Float64("syscall-zscore", aggregate.DefaultSyscallThreshold.ZScore, "Against a multi-sample baseline, keep a syscall count only if this many standard deviations above the mean")
+		accessRatio = flag.Float64("access-ratio", aggregate.DefaultAccessThreshold.Ratio, "Keep a file or command the baseline has only if used this many times as often (0 never)")
+		accessDelta = flag.Int("access-min-delta", aggregate.DefaultAccessThreshold.MinDelta, "Keep a file or command the baseline has only if used more than this many times more")
+		allowFile   = flag.String("allowlist", "", "YAML allowlist of benign files, commands, IP ranges and domains to drop from diffs (optional)")
+		samples     = flag.String("samples", "", "Build a baseline with mean/variance from several runs: a.jsonl,b.jsonl,... (optional)")
+		maxLineMB   = flag.Int("max-line-mb", aggregate.DefaultMaxLineSize>>20, "Skip trace lines longer than this many MiB")
+		concurrency = flag.Int("concurrency", runtime.NumCPU(), "Packages aggregated in parallel (used with -dir)")
+		format      = flag.String("format", string(aggregate.FormatJSON), "Output format: json, csv or parquet (csv and parquet with -input or -dir)")
+		mongoURI    = flag.String("mongo-uri", "", "Ingest the raw events of -input into this MongoDB instead of aggregating (optional)")
+		mongoDB     = flag.String("mongo-db", "tracee_analysis", "MongoDB database to ingest into (used with -mongo-uri)")
 		help        = flag.Bool("help", false, "Show help")
 	)
 
@@ -28,6 +87,39 @@ func main() {
 		os.Exit(0)
 	}
 
+	mode, err := aggregate.ParseKeyMode(*processKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	processKeyMode = mode
+	syscallThreshold = aggregate.SyscallThreshold{Ratio: *ratio, MinDelta: *minDelta, ZScore: *zScore}
+	accessThreshold = aggregate.AccessThreshold{Ratio: *accessRatio, MinDelta: *accessDelta}
+	maxLineSize = *maxLineMB << 20
+	if outputFormat, err = aggregate.ParseFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if outputFormat != aggregate.FormatJSON && (*samples != "" || *compare != "") {
+		fmt.Fprintf(os.Stderr, "Error: -format %s only applies to -input and -dir\n", outputFormat)
+		os.Exit(1)
+	}
+	if *allowFile != "" {
+		if allowlist, err = aggregate.LoadAllowlist(*allowFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Baseline mode: merge several known-safe runs into one baseline
+	if *samples != "" {
+		if err := buildBaseline(*samples, *collection, *outputFile); err != nil {
+			fmt.Fprintf(os.Stderr, "Error building baseline: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Load dedup source if provided
 	var baseline *aggregate.PerProcessStats
 	if *dedupSource != "" {
@@ -44,9 +136,18 @@ func main() {
 		fmt.Fprintf(os.Stderr, "Loaded baseline from %s (%d processes)\n", *dedupSource, baseline.CountProcesses)
 	}
 
+	// Comparison mode: flag behavior seen under only one environment variant
+	if *compare != "" {
+		if err := compareVariants(*compare, *outputFile, baseline); err != nil {
+			fmt.Fprintf(os.Stderr, "Error comparing variants: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	// Batch mode: process directory
 	if *dirPath != "" {
-		if err := processDirectory(*dirPath, baseline); err != nil {
+		if err := processDirectory(*dirPath, baseline, *concurrency, *outputFile); err != nil {
 			fmt.Fprintf(os.Stderr, "Error processing directory: %v\n", err)
 			os.Exit(1)
 		}
@@ -65,29 +166,81 @@ func main() {
 		os.Exit(1)
 	}
 
-	processSingleFile(*inputFile, *collection, *outputFile, baseline)
+	// Ingest mode: store every raw event for the stats API to query
+	if *mongoURI != "" {
+		if err := ingestMongo(*inputFile, *mongoURI, *mongoDB, *collection); err != nil {
+			fmt.Fprintf(os.Stderr, "Error ingesting into MongoDB: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	processSingleFile(*inputFile, *collection, *outputFile, *proxyFile, baseline)
 }
 
-func processSingleFile(inputFile, collection, outputFile string, baseline *aggregate.PerProcessStats) {
+// ingestMongo streams the events of inputFile into collection of a MongoDB
+// database in bulk batches
+func ingestMongo(inputFile, uri, database, collection string) error {
+	f, err := os.Open(inputFile)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ctx := context.Background()
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		return fmt.Errorf("failed to connect: %w", err)
+	}
+	defer client.Disconnect(ctx)
+
+	startTime := time.Now()
+	fmt.Fprintf(os.Stderr, "Ingesting %s into %s.%s...\n", inputFile, database, collection)
+	writer := aggregate.NewMongoEventWriter(client.Database(database))
+	result, err := aggregate.Ingest(ctx, f, writer, collection, aggregate.DefaultIngestBatch)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Inserted %d events in %d batches (%d lines skipped) in %v\n", result.Events, result.Batches, result.Skipped, time.Since(startTime))
+	return nil
+}
+
+func processSingleFile(inputFile, collection, outputFile, proxyFile string, baseline *aggregate.PerProcessStats) {
 	startTime := time.Now()
 	fmt.Fprintf(os.Stderr, "Processing %s...\n", inputFile)
 
 	// Always use per-process aggregation
-	aggregator := aggregate.NewProcessAggregator()
+	aggregator := newProcessAggregator()
 	result, err := aggregator.ProcessFile(inputFile, collection)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+	if n := aggregator.OversizedLines(); n > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d lines longer than -max-line-mb\n", n)
+	}
+
+	// Merge proxy-captured HTTP activity if provided
+	if proxyFile != "" {
+		httpActivity, err := newProxyAggregator().ProcessFile(proxyFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error processing proxy log: %v\n", err)
+			os.Exit(1)
+		}
+		result.HTTPActivity = httpActivity
+		fmt.Fprintf(os.Stderr, "Merged %d HTTP requests from %s\n", httpActivity.TotalRequests, proxyFile)
+	}
 
 	duration := time.Since(startTime)
 	fmt.Fprintf(os.Stderr, "Aggregation completed in %v\n", duration)
 
 	// Apply deduplication if baseline provided
-	var output interface{} = result
+	var output tabular = result
 	if baseline != nil {
 		dedupStart := time.Now()
-		deduped := aggregate.Dedup(result, baseline)
+		deduped := aggregate.DedupWithThresholds(result, baseline, syscallThreshold, accessThreshold)
+		allowlist.Apply(deduped)
+		deduped.Generator = version.Stamp()
 		dedupDuration := time.Since(dedupStart)
 		fmt.Fprintf(os.Stderr, "Dedup completed in %v\n", dedupDuration)
 		fmt.Fprintf(os.Stderr, "Removed: %d processes, %d files, %d commands, %d syscalls\n",
@@ -98,26 +251,63 @@ func processSingleFile(inputFile, collection, outputFile string, baseline *aggre
 		output = deduped
 	}
 
-	// Marshal to JSON with indentation
-	jsonBytes, err := json.MarshalIndent(output, "", "  ")
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error marshaling JSON: %v\n", err)
+	if err := writeOutput(outputFile, output); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing output: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Write output
 	if outputFile != "" {
-		if err := os.WriteFile(outputFile, jsonBytes, 0o644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing output file: %v\n", err)
-			os.Exit(1)
-		}
 		fmt.Fprintf(os.Stderr, "Output written to: %s\n", outputFile)
-	} else {
-		fmt.Println(string(jsonBytes))
 	}
 }
 
-func processDirectory(dirPath string, baseline *aggregate.PerProcessStats) error {
+// tabular is aggregation output that flattens into rows for -format csv and
+// parquet
+type tabular interface {
+	Rows() []aggregate.Row
+}
+
+// writeOutput writes output in the -format to outputFile, or to stdout when
+// it is empty
+func writeOutput(outputFile string, output tabular) error {
+	var buf bytes.Buffer
+	if outputFormat == aggregate.FormatJSON {
+		jsonBytes, err := json.MarshalIndent(output, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		buf.Write(jsonBytes)
+		buf.WriteByte('\n')
+	} else if err := aggregate.WriteRows(&buf, outputFormat, output.Rows()); err != nil {
+		return err
+	}
+
+	if outputFile == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return os.WriteFile(outputFile, buf.Bytes(), 0o644)
+}
+
+// rowTable is rows gathered from several packages, written as one table
+type rowTable []aggregate.Row
+
+func (t rowTable) Rows() []aggregate.Row { return t }
+
+// packageResult is the outcome of aggregating one package of a batch directory
+type packageResult struct {
+	name     string
+	diffFile string
+	deduped  *aggregate.DedupedProcessStats
+	duration time.Duration
+	skipped  bool // No behavior.jsonl
+	err      error
+}
+
+// processDirectory writes a diff.json next to the behavior.jsonl of every
+// package subdirectory, aggregating up to concurrency packages at a time.
+// With -format csv or parquet, the diffs of all packages are also written to
+// outputFile (stdout if empty) as one table, one collection per package.
+func processDirectory(dirPath string, baseline *aggregate.PerProcessStats, concurrency int, outputFile string) error {
 	if baseline == nil {
 		return fmt.Errorf("-dedup-source is required for batch directory processing")
 	}
@@ -126,71 +316,257 @@ func processDirectory(dirPath string, baseline *aggregate.PerProcessStats) error
 	if err != nil {
 		return fmt.Errorf("failed to read directory: %w", err)
 	}
+	var packages []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			packages = append(packages, entry.Name())
+		}
+	}
+	concurrency = max(1, min(concurrency, len(packages)))
+	fmt.Fprintf(os.Stderr, "Aggregating %d packages with %d workers\n", len(packages), concurrency)
 
-	processed := 0
-	errors := 0
+	startTime := time.Now()
+	work := make(chan string)
+	results := make(chan packageResult)
+	var wg sync.WaitGroup
+	for range concurrency {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for name := range work {
+				results <- aggregatePackage(dirPath, name, baseline)
+			}
+		}()
+	}
+	go func() {
+		for _, name := range packages {
+			work <- name
+		}
+		close(work)
+		wg.Wait()
+		close(results)
+	}()
 
-	for _, entry := range entries {
-		if !entry.IsDir() {
+	// Results are reported by the collector alone so lines don't interleave
+	var timings []packageResult
+	var table rowTable
+	processed, skipped, errors := 0, 0, 0
+	done := 0
+	for result := range results {
+		done++
+		switch {
+		case result.skipped:
+			skipped++
+			fmt.Fprintf(os.Stderr, "[%d/%d] Skipping %s: no behavior.jsonl found\n", done, len(packages), result.name)
 			continue
+		case result.err != nil:
+			errors++
+			fmt.Fprintf(os.Stderr, "[%d/%d] Error processing %s after %v: %v\n", done, len(packages), result.name, result.duration.Round(time.Millisecond), result.err)
+		default:
+			processed++
+			fmt.Fprintf(os.Stderr, "[%d/%d] Created %s in %v (removed %d processes, %d files, %d commands, %d syscalls)\n",
+				done, len(packages),
+				result.diffFile,
+				result.duration.Round(time.Millisecond),
+				result.deduped.RemovedProcesses,
+				result.deduped.RemovedFiles,
+				result.deduped.RemovedCommands,
+				result.deduped.RemovedSyscalls)
+			if outputFormat != aggregate.FormatJSON {
+				table = append(table, result.deduped.Rows()...)
+			}
 		}
+		timings = append(timings, result)
+	}
+	elapsed := time.Since(startTime)
 
-		packageName := entry.Name()
-		packageDir := filepath.Join(dirPath, packageName)
-		behaviorFile := filepath.Join(packageDir, "behavior.jsonl")
-		diffFile := filepath.Join(packageDir, "diff.json")
+	fmt.Fprintf(os.Stderr, "\n=== Summary ===\n")
+	fmt.Fprintf(os.Stderr, "Processed: %d packages\n", processed)
+	fmt.Fprintf(os.Stderr, "Skipped: %d\n", skipped)
+	fmt.Fprintf(os.Stderr, "Errors: %d\n", errors)
+	printTimings(timings, elapsed)
 
-		// Check if behavior.jsonl exists
-		if _, err := os.Stat(behaviorFile); os.IsNotExist(err) {
-			fmt.Fprintf(os.Stderr, "Skipping %s: no behavior.jsonl found\n", packageName)
-			continue
+	if outputFormat != aggregate.FormatJSON {
+		if err := writeOutput(outputFile, table); err != nil {
+			return fmt.Errorf("failed to write %s table: %w", outputFormat, err)
+		}
+		if outputFile != "" {
+			fmt.Fprintf(os.Stderr, "%d rows from %d packages written to: %s\n", len(table), processed, outputFile)
 		}
+	}
+	return nil
+}
+
+// aggregatePackage aggregates the behavior (and proxy log, if any) of one
+// package subdirectory and writes its diff.json
+func aggregatePackage(dirPath, packageName string, baseline *aggregate.PerProcessStats) packageResult {
+	packageDir := filepath.Join(dirPath, packageName)
+	behaviorFile := filepath.Join(packageDir, "behavior.jsonl")
+	proxyFile := filepath.Join(packageDir, "proxy.jsonl")
+	result := packageResult{name: packageName, diffFile: filepath.Join(packageDir, "diff.json")}
+
+	// Check if behavior.jsonl exists
+	if _, err := os.Stat(behaviorFile); os.IsNotExist(err) {
+		result.skipped = true
+		return result
+	}
+
+	startTime := time.Now()
+	result.deduped, result.err = aggregateDiff(behaviorFile, proxyFile, packageName, baseline, result.diffFile)
+	result.duration = time.Since(startTime)
+	return result
+}
 
-		fmt.Fprintf(os.Stderr, "\n=== Processing %s ===\n", packageName)
-		startTime := time.Now()
+// aggregateDiff aggregates a behavior file, merges the proxy log if present,
+// dedups it against the baseline and writes the diff to diffFile
+func aggregateDiff(behaviorFile, proxyFile, packageName string, baseline *aggregate.PerProcessStats, diffFile string) (*aggregate.DedupedProcessStats, error) {
+	aggregator := newProcessAggregator()
+	result, err := aggregator.ProcessFile(behaviorFile, packageName)
+	if err != nil {
+		return nil, err
+	}
 
-		// Process the file
-		aggregator := aggregate.NewProcessAggregator()
-		result, err := aggregator.ProcessFile(behaviorFile, packageName)
+	// Merge proxy-captured HTTP activity if present
+	if _, err := os.Stat(proxyFile); err == nil {
+		httpActivity, err := newProxyAggregator().ProcessFile(proxyFile)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error processing %s: %v\n", packageName, err)
-			errors++
+			return nil, fmt.Errorf("failed to process proxy log: %w", err)
+		}
+		result.HTTPActivity = httpActivity
+	}
+
+	// Apply deduplication
+	deduped := aggregate.DedupWithThresholds(result, baseline, syscallThreshold, accessThreshold)
+	allowlist.Apply(deduped)
+	deduped.Generator = version.Stamp()
+
+	jsonBytes, err := json.MarshalIndent(deduped, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	if err := os.WriteFile(diffFile, jsonBytes, 0o644); err != nil {
+		return nil, fmt.Errorf("failed to write diff.json: %w", err)
+	}
+	return deduped, nil
+}
+
+// slowestShown is how many of the slowest packages the batch summary lists
+const slowestShown = 5
+
+// printTimings prints the per-package timing stats of a batch run: total
+// and mean aggregation time, and the slowest packages
+func printTimings(timings []packageResult, elapsed time.Duration) {
+	fmt.Fprintf(os.Stderr, "Wall time: %v\n", elapsed.Round(time.Millisecond))
+	if len(timings) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, t := range timings {
+		total += t.duration
+	}
+	fmt.Fprintf(os.Stderr, "Aggregation time: %v total, %v mean per package (%.1fx speedup)\n",
+		total.Round(time.Millisecond),
+		(total / time.Duration(len(timings))).Round(time.Millisecond),
+		float64(total)/float64(max(elapsed, 1)))
+
+	sort.Slice(timings, func(i, j int) bool { return timings[i].duration > timings[j].duration })
+	fmt.Fprintf(os.Stderr, "Slowest:\n")
+	for _, t := range timings[:min(slowestShown, len(timings))] {
+		fmt.Fprintf(os.Stderr, "  %-40s %v\n", t.name, t.duration.Round(time.Millisecond))
+	}
+}
+
+// buildBaseline aggregates each behavior file in the comma-separated list and
+// writes a baseline storing the mean and variance of every syscall counter
+func buildBaseline(list, collection, outputFile string) error {
+	var runs []*aggregate.PerProcessStats
+	for _, path := range strings.Split(list, ",") {
+		path = strings.TrimSpace(path)
+		if path == "" {
 			continue
 		}
+		result, err := newProcessAggregator().ProcessFile(path, collection)
+		if err != nil {
+			return fmt.Errorf("failed to process %s: %w", path, err)
+		}
+		fmt.Fprintf(os.Stderr, "Aggregated sample %s (%d processes)\n", path, result.CountProcesses)
+		runs = append(runs, result)
+	}
+	if len(runs) < 2 {
+		fmt.Fprintf(os.Stderr, "Warning: a single sample gives no variance; use -input for single-run baselines\n")
+	}
+
+	baseline, err := aggregate.BuildBaseline(collection, runs)
+	if err != nil {
+		return err
+	}
 
-		// Apply deduplication
-		deduped := aggregate.Dedup(result, baseline)
+	jsonBytes, err := json.MarshalIndent(baseline, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
 
-		// Marshal to JSON
-		jsonBytes, err := json.MarshalIndent(deduped, "", "  ")
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, jsonBytes, 0o644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Baseline from %d samples written to: %s\n", len(runs), outputFile)
+	} else {
+		fmt.Println(string(jsonBytes))
+	}
+	return nil
+}
+
+// compareVariants aggregates each name=path pair in spec (deduped against the
+// baseline when one is loaded) and writes the environment-conditional behavior
+func compareVariants(spec, outputFile string, baseline *aggregate.PerProcessStats) error {
+	variants := make(map[string]map[string]*aggregate.ProcessSummary)
+
+	for _, pair := range strings.Split(spec, ",") {
+		name, path, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || name == "" || path == "" {
+			return fmt.Errorf("invalid variant %q, expected name=behavior.jsonl", pair)
+		}
+		if _, exists := variants[name]; exists {
+			return fmt.Errorf("duplicate variant name %q", name)
+		}
+
+		result, err := newProcessAggregator().ProcessFile(path, name)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error marshaling JSON for %s: %v\n", packageName, err)
-			errors++
-			continue
+			return fmt.Errorf("failed to process %s: %w", path, err)
 		}
 
-		// Write diff.json
-		if err := os.WriteFile(diffFile, jsonBytes, 0o644); err != nil {
-			fmt.Fprintf(os.Stderr, "Error writing diff.json for %s: %v\n", packageName, err)
-			errors++
-			continue
+		if baseline != nil {
+			deduped := aggregate.DedupWithThresholds(result, baseline, syscallThreshold, accessThreshold)
+			allowlist.Apply(deduped)
+			variants[name] = deduped.PerProcess
+		} else {
+			variants[name] = result.PerProcess
 		}
+		fmt.Fprintf(os.Stderr, "Aggregated variant %s from %s (%d processes)\n", name, path, len(variants[name]))
+	}
 
-		duration := time.Since(startTime)
-		fmt.Fprintf(os.Stderr, "Created %s in %v (removed %d processes, %d files, %d commands, %d syscalls)\n",
-			diffFile,
-			duration,
-			deduped.RemovedProcesses,
-			deduped.RemovedFiles,
-			deduped.RemovedCommands,
-			deduped.RemovedSyscalls)
-		processed++
+	if len(variants) < 2 {
+		return fmt.Errorf("at least two variants are required")
 	}
 
-	fmt.Fprintf(os.Stderr, "\n=== Summary ===\n")
-	fmt.Fprintf(os.Stderr, "Processed: %d packages\n", processed)
-	fmt.Fprintf(os.Stderr, "Errors: %d\n", errors)
+	conditional := aggregate.CompareVariants(variants)
+	fmt.Fprintf(os.Stderr, "Found %d environment-conditional behaviors\n", len(conditional))
 
+	jsonBytes, err := json.MarshalIndent(conditional, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+
+	if outputFile != "" {
+		if err := os.WriteFile(outputFile, jsonBytes, 0o644); err != nil {
+			return fmt.Errorf("failed to write output file: %w", err)
+		}
+		fmt.Fprintf(os.Stderr, "Output written to: %s\n", outputFile)
+	} else {
+		fmt.Println(string(jsonBytes))
+	}
 	return nil
 }
 
@@ -205,5 +581,21 @@ func printUsage() {
 	fmt.Println("  -collection string    Collection name (default: \"default\")")
 	fmt.Println("  -output string        Output JSON file (optional, defaults to stdout)")
 	fmt.Println("  -dedup-source string  Path to safe baseline JSON for deduplication (optional)")
+	fmt.Println("  -proxy string         Path to intercepting proxy log (proxy.jsonl) to merge (optional)")
+	fmt.Println("  -compare string       Compare variants: name=behavior.jsonl,name2=behavior.jsonl (optional)")
+	fmt.Println("  -process-key string   Key processes by name, ancestry (npm>node>sh) or cmdline (default: ancestry)")
+	fmt.Println("  -syscall-ratio float  Keep a syscall count only if above baseline times this (default: 1.5)")
+	fmt.Println("  -syscall-min-delta n  ...and more than n calls above the baseline (default: 50)")
+	fmt.Println("  -syscall-zscore float Against a multi-sample baseline, standard deviations above the mean instead of the ratio (default: 3)")
+	fmt.Println("  -access-ratio float   Keep a file or command the baseline has only if used this many times as often (default: 10; 0 never)")
+	fmt.Println("  -access-min-delta n   ...and more than n times more (default: 100)")
+	fmt.Println("  -allowlist string     YAML allowlist of benign files, commands, IP ranges and domains dropped from diffs (optional)")
+	fmt.Println("  -samples string       Build a mean/variance baseline from runs: a.jsonl,b.jsonl,... (use with -output)")
+	fmt.Println("  -max-line-mb n        Skip trace lines longer than n MiB; multi-GB traces are streamed (default: 16)")
+	fmt.Println("  -concurrency n        Packages aggregated in parallel with -dir (default: number of CPUs)")
+	fmt.Println("  -format string        Output json, or csv/parquet rows of collection,process,category,key,count;")
+	fmt.Println("                        with -dir, one table of every package's diff is written to -output (default: json)")
+	fmt.Println("  -mongo-uri string     Ingest the raw events of -input into -collection of this MongoDB instead (optional)")
+	fmt.Println("  -mongo-db string      MongoDB database to ingest into (default: tracee_analysis)")
 	fmt.Println("  -help                 Show this help message")
 }
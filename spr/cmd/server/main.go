@@ -3,16 +3,30 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
 
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/artifacts"
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+	"github.com/acheong08/hackeurope-spr/internal/intel"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
 	"github.com/acheong08/hackeurope-spr/internal/server"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/internal/tester"
+	"github.com/acheong08/hackeurope-spr/internal/ticketing"
+	"github.com/acheong08/hackeurope-spr/internal/version"
 )
 
 // Config holds all environment configuration
@@ -21,11 +35,13 @@ type Config struct {
 	Port string
 
 	// Unsafe (staging) registry
+	RegistryType  registry.Type
 	RegistryURL   string
 	RegistryToken string
 	RegistryOwner string
 
 	// Safe (approved) registry — promotion only happens when token is set
+	SafeRegistryType  registry.Type
 	SafeRegistryURL   string
 	SafeRegistryToken string
 	SafeRegistryOwner string
@@ -34,15 +50,104 @@ type Config struct {
 	GitHubToken string
 	RepoOwner   string
 	RepoName    string
+	// Secret of the workflow_run webhook at /webhooks/workflow (empty
+	// disables it and workflows are polled)
+	GitHubWebhookSecret string
+	RunWaker            *orchestrator.RunWaker
+
+	// Bearer token self-hosted runners upload traces to /api/jobs/{id}/artifacts
+	// with, stored under ArtifactIngestDir (empty token disables it)
+	ArtifactIngestToken string
+	ArtifactIngestDir   string
 
 	// Mongo (for aggregation)
 	MongoURI string
 
 	// Baseline for diff generation
 	BaselinePath string
+	// Known-benign activity suppressed in diffs (nil when ALLOWLIST is unset)
+	Allowlist *aggregate.Allowlist
+	// Threat intel sources for contacted IPs and domains (nil when INTEL is unset)
+	Intel *intel.Enricher
 
-	// OpenAI API key for AI analysis
+	// AI analysis: API key, provider, base URL and model (empty values use
+	// the provider defaults)
 	OpenAIAPIKey string
+	AIProvider   string
+	AIBaseURL    string
+	AIModel      string
+	// Backends the analysis agent queries with tools (diff.json and/or the
+	// trace stats service at TRACE_API_BASE)
+	AnalysisSources []analysis.Source
+	// Language AI justifications are translated into (empty keeps English)
+	AnalysisLanguage string
+
+	// Write-once evidence archive for flagged packages (empty disables)
+	QuarantineDir string
+
+	// Hash-chained, tamper-evident log of verdicts (empty disables)
+	ResultsLog string
+	// Serve the verdicts of packages published as safe from ResultsLog at
+	// /api/verdicts/, without authentication, cached for VerdictCacheTTL
+	PublicVerdicts  bool
+	VerdictCacheTTL time.Duration
+
+	// Baseline effectiveness per run (empty disables), reported stale when
+	// below DriftThreshold for DriftRuns runs in a row
+	DriftHistory   string
+	DriftThreshold float64
+	DriftRuns      int
+
+	// Consecutive failures tripping the circuit breaker of GitHub, the
+	// registries, npm or the LLM (zero never trips), and how long a tripped
+	// breaker fails calls before probing again
+	BreakerFailures int
+	BreakerCooldown time.Duration
+
+	// Opt-in endpoint for anonymous run stats (empty disables)
+	TelemetryURL string
+
+	// SIEM collector flagged packages are forwarded to (empty URL disables)
+	SIEMURL    string
+	SIEMFormat siem.Format
+	SIEMToken  string
+
+	// Files a ticket per flagged package in GitHub Issues or Jira (nil when
+	// TICKETS is unset)
+	Tickets *ticketing.Filer
+
+	// S3-compatible storage for analysis artifacts (nil when ARTIFACT_S3_BUCKET is unset)
+	ArtifactSink artifacts.Sink
+
+	// Pack and upload git/URL dependencies instead of aborting
+	AllowNonNpm bool
+
+	// How processes are keyed in diffs: name, ancestry or cmdline
+	ProcessKey aggregate.KeyMode
+
+	// Syscall dedup: keep a count only if > baseline×ratio and Δ > min delta
+	SyscallThreshold aggregate.SyscallThreshold
+	// File/command dedup: keep one the baseline has only if used > baseline×ratio
+	// times and Δ > min delta
+	AccessThreshold aggregate.AccessThreshold
+
+	// Per-stage concurrency of each analysis
+	StageConcurrency orchestrator.StageConcurrency
+
+	// Per-package workflow timeout overrides and re-triggers of failed runs
+	PackageTimeouts map[string]time.Duration
+	WorkflowRetries int
+	// Extra inputs passed to every workflow dispatch
+	WorkflowInputs map[string]string
+	// Behavioral tests each workflow run is limited to
+	Tests []string
+
+	// Log output format: "text" or "json"
+	LogFormat string
+
+	// Concurrent analysis limits (0 = unlimited)
+	MaxAnalysesPerClient int
+	MaxAnalyses          int
 }
 
 func loadConfig() (*Config, error) {
@@ -62,7 +167,161 @@ func loadConfig() (*Config, error) {
 		RepoName:          getEnv("REPO_NAME", "hackeurope-spr"),
 		MongoURI:          getEnv("MONGO_URI", "mongodb://localhost:27017"),
 		BaselinePath:      getEnv("BASELINE_PATH", "safe-sample.json"),
-		OpenAIAPIKey:      getEnv("OPENAI_API_KEY", ""),
+		OpenAIAPIKey:      getEnv("AI_API_KEY", getEnv("OPENAI_API_KEY", "")),
+		AIProvider:        getEnv("AI_PROVIDER", analysis.DefaultProvider),
+		AIBaseURL:         getEnv("AI_BASE_URL", ""),
+		AIModel:           getEnv("AI_MODEL", ""),
+		AnalysisLanguage:  getEnv("ANALYSIS_LANGUAGE", ""),
+		QuarantineDir:     getEnv("QUARANTINE_DIR", "./quarantine"),
+		ResultsLog:        getEnv("RESULTS_LOG", ""),
+		DriftHistory:      getEnv("BASELINE_HISTORY", ""),
+		DriftThreshold:    getEnvFloat("BASELINE_DRIFT_THRESHOLD", orchestrator.DefaultDriftThreshold),
+		DriftRuns:         getEnvInt("BASELINE_DRIFT_RUNS", orchestrator.DefaultDriftRuns),
+		TelemetryURL:      getEnv("TELEMETRY_URL", ""),
+		AllowNonNpm:       getEnvBool("ALLOW_NON_NPM_DEPS", false),
+		WorkflowRetries:   getEnvInt("WORKFLOW_RETRIES", 1),
+		LogFormat:         getEnv("LOG_FORMAT", "text"),
+
+		MaxAnalysesPerClient: getEnvInt("MAX_ANALYSES_PER_CLIENT", 3),
+		MaxAnalyses:          getEnvInt("MAX_ANALYSES", 10),
+	}
+
+	registryType, err := registry.ParseType(getEnv("REGISTRY_TYPE", "gitea"))
+	if err != nil {
+		return nil, err
+	}
+	config.RegistryType = registryType
+	safeRegistryType, err := registry.ParseType(getEnv("SAFE_REGISTRY_TYPE", string(registryType)))
+	if err != nil {
+		return nil, fmt.Errorf("SAFE_REGISTRY_TYPE: %w", err)
+	}
+	config.SafeRegistryType = safeRegistryType
+
+	keyMode, err := aggregate.ParseKeyMode(getEnv("PROCESS_KEY", "ancestry"))
+	if err != nil {
+		return nil, err
+	}
+	config.ProcessKey = keyMode
+	config.SyscallThreshold = aggregate.SyscallThreshold{
+		Ratio:    getEnvFloat("SYSCALL_RATIO", aggregate.DefaultSyscallThreshold.Ratio),
+		MinDelta: getEnvInt("SYSCALL_MIN_DELTA", aggregate.DefaultSyscallThreshold.MinDelta),
+		ZScore:   getEnvFloat("SYSCALL_ZSCORE", aggregate.DefaultSyscallThreshold.ZScore),
+	}
+	if path := getEnv("ALLOWLIST", ""); path != "" {
+		allowlist, err := aggregate.LoadAllowlist(path)
+		if err != nil {
+			return nil, fmt.Errorf("ALLOWLIST: %w", err)
+		}
+		config.Allowlist = allowlist
+	}
+	if path := getEnv("INTEL", ""); path != "" {
+		enricher, err := intel.Load(path)
+		if err != nil {
+			return nil, fmt.Errorf("INTEL: %w", err)
+		}
+		config.Intel = enricher
+	}
+	config.AccessThreshold = aggregate.AccessThreshold{
+		Ratio:    getEnvFloat("ACCESS_RATIO", aggregate.DefaultAccessThreshold.Ratio),
+		MinDelta: getEnvInt("ACCESS_MIN_DELTA", aggregate.DefaultAccessThreshold.MinDelta),
+	}
+
+	config.StageConcurrency = orchestrator.StageConcurrency{
+		Upload:    getEnvInt("UPLOAD_CONCURRENCY", orchestrator.DefaultStageConcurrency.Upload),
+		Workflow:  getEnvInt("WORKFLOW_CONCURRENCY", orchestrator.DefaultStageConcurrency.Workflow),
+		Aggregate: getEnvInt("AGGREGATE_CONCURRENCY", orchestrator.DefaultStageConcurrency.Aggregate),
+		AI:        getEnvInt("AI_CONCURRENCY", orchestrator.DefaultStageConcurrency.AI),
+	}
+	if err := config.StageConcurrency.Validate(); err != nil {
+		return nil, err
+	}
+
+	sources, err := analysis.ParseSources(getEnv("ANALYSIS_SOURCES", analysis.DefaultSources), getEnv("TRACE_API_BASE", ""), getEnv("TRACE_API_TOKEN", ""))
+	if err != nil {
+		return nil, fmt.Errorf("ANALYSIS_SOURCES: %w", err)
+	}
+	config.AnalysisSources = sources
+
+	ai := analysis.ProviderConfig{Provider: config.AIProvider, APIKey: config.OpenAIAPIKey, BaseURL: config.AIBaseURL, Model: config.AIModel}
+	if ai.Enabled() {
+		if _, err := ai.Resolve(); err != nil {
+			return nil, fmt.Errorf("AI_PROVIDER: %w", err)
+		}
+	}
+
+	packageTimeouts, err := orchestrator.ParsePackageTimeouts(getEnv("PACKAGE_TIMEOUTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("PACKAGE_TIMEOUTS: %w", err)
+	}
+	config.PackageTimeouts = packageTimeouts
+
+	workflowInputs, err := orchestrator.ParseWorkflowInputs(getEnv("WORKFLOW_INPUTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("WORKFLOW_INPUTS: %w", err)
+	}
+	config.WorkflowInputs = workflowInputs
+
+	tests, err := tester.ParseTests(getEnv("TESTS", ""))
+	if err != nil {
+		return nil, fmt.Errorf("TESTS: %w", err)
+	}
+	config.Tests = tests
+
+	siemFormat, err := siem.ParseFormat(getEnv("SIEM_FORMAT", "cef"))
+	if err != nil {
+		return nil, fmt.Errorf("SIEM_FORMAT: %w", err)
+	}
+	config.SIEMURL = getEnv("SIEM_URL", "")
+	config.SIEMFormat = siemFormat
+	config.SIEMToken = getEnv("SIEM_TOKEN", "")
+
+	tickets, err := ticketing.Config{
+		Kind:          getEnv("TICKETS", ""),
+		Labels:        ticketing.ParseLabels(getEnv("TICKET_LABELS", "security,malicious-package")),
+		TemplatePath:  getEnv("TICKET_TEMPLATE", ""),
+		GitHubRepo:    getEnv("TICKET_REPO", config.RepoOwner+"/"+config.RepoName),
+		GitHubToken:   getEnv("TICKET_GITHUB_TOKEN", config.GitHubToken),
+		JiraURL:       getEnv("JIRA_URL", ""),
+		JiraUser:      getEnv("JIRA_USER", ""),
+		JiraToken:     getEnv("JIRA_TOKEN", ""),
+		JiraProject:   getEnv("JIRA_PROJECT", ""),
+		JiraIssueType: getEnv("JIRA_ISSUE_TYPE", "Bug"),
+	}.Filer()
+	if err != nil {
+		return nil, fmt.Errorf("TICKETS: %w", err)
+	}
+	config.Tickets = tickets
+
+	if bucket := getEnv("ARTIFACT_S3_BUCKET", ""); bucket != "" {
+		sink, err := artifacts.NewS3(artifacts.S3Config{
+			Endpoint:  getEnv("ARTIFACT_S3_ENDPOINT", ""),
+			Region:    getEnv("ARTIFACT_S3_REGION", "us-east-1"),
+			Bucket:    bucket,
+			Prefix:    getEnv("ARTIFACT_S3_PREFIX", ""),
+			AccessKey: getEnv("ARTIFACT_S3_ACCESS_KEY", ""),
+			SecretKey: getEnv("ARTIFACT_S3_SECRET_KEY", ""),
+			PathStyle: getEnvBool("ARTIFACT_S3_PATH_STYLE", true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("artifact storage: %w", err)
+		}
+		config.ArtifactSink = sink
+	}
+
+	config.GitHubWebhookSecret = getEnv("GITHUB_WEBHOOK_SECRET", "")
+	if config.GitHubWebhookSecret != "" {
+		config.RunWaker = orchestrator.NewRunWaker()
+	}
+
+	config.ArtifactIngestToken = getEnv("ARTIFACT_INGEST_TOKEN", "")
+	config.ArtifactIngestDir = getEnv("ARTIFACT_INGEST_DIR", "./ingest")
+
+	config.PublicVerdicts = getEnvBool("PUBLIC_VERDICTS", false)
+	config.VerdictCacheTTL = time.Duration(getEnvInt("VERDICT_CACHE_SECONDS", int(server.DefaultVerdictCacheTTL.Seconds()))) * time.Second
+	config.BreakerFailures = getEnvInt("BREAKER_FAILURES", breaker.DefaultFailures)
+	config.BreakerCooldown = time.Duration(getEnvInt("BREAKER_COOLDOWN_SECONDS", int(breaker.DefaultCooldown.Seconds()))) * time.Second
+	if config.PublicVerdicts && config.ResultsLog == "" {
+		return nil, fmt.Errorf("PUBLIC_VERDICTS requires RESULTS_LOG, which the verdicts are served from")
 	}
 
 	// Validate required fields
@@ -83,6 +342,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 // WebSocket upgrader
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -98,16 +384,16 @@ type Client struct {
 	conn   *websocket.Conn
 	config *Config
 	send   chan server.Message
-	// Track if analysis is running (one at a time)
-	analysisCtx    context.Context
-	analysisCancel context.CancelFunc
+	// Shared across connections; tracks this client's concurrent analyses
+	manager *server.AnalysisManager
 }
 
-func newClient(conn *websocket.Conn, config *Config) *Client {
+func newClient(conn *websocket.Conn, config *Config, manager *server.AnalysisManager) *Client {
 	return &Client{
-		conn:   conn,
-		config: config,
-		send:   make(chan server.Message, 256),
+		conn:    conn,
+		config:  config,
+		send:    make(chan server.Message, 256),
+		manager: manager,
 	}
 }
 
@@ -162,10 +448,8 @@ func (c *Client) writePump() {
 
 func (c *Client) readPump() {
 	defer func() {
-		// Cancel any running analysis
-		if c.analysisCancel != nil {
-			c.analysisCancel()
-		}
+		// Cancel any running analyses
+		c.manager.CancelAll(c)
 		c.conn.Close()
 	}()
 
@@ -181,6 +465,8 @@ func (c *Client) readPump() {
 		switch msg.Type {
 		case server.TypeAnalyze:
 			c.handleAnalyze(msg)
+		case server.TypeCancel:
+			c.handleCancel(msg)
 		case server.TypePing:
 			// Respond with pong
 			c.SendMessage(server.Message{Type: "pong"})
@@ -190,13 +476,25 @@ func (c *Client) readPump() {
 	}
 }
 
-func (c *Client) handleAnalyze(msg server.Message) {
-	// Check if already analyzing
-	if c.analysisCtx != nil && c.analysisCtx.Err() == nil {
-		c.SendError("Analysis already in progress", nil)
+func (c *Client) handleCancel(msg server.Message) {
+	if msg.AnalysisID == "" {
+		if c.manager.CancelAll(c) == 0 {
+			c.SendError("No analysis in progress", nil)
+			return
+		}
+		c.SendLog("Cancelling all analyses...", "warning")
+		return
+	}
+
+	sender := server.WithAnalysisID(c, msg.AnalysisID)
+	if !c.manager.Cancel(c, msg.AnalysisID) {
+		sender.SendError("No such analysis in progress", nil)
 		return
 	}
+	sender.SendLog("Cancelling analysis...", "warning")
+}
 
+func (c *Client) handleAnalyze(msg server.Message) {
 	// Parse payload
 	payload, err := server.ParseAnalyzePayload(msg)
 	if err != nil {
@@ -204,38 +502,75 @@ func (c *Client) handleAnalyze(msg server.Message) {
 		return
 	}
 
-	// Create cancellable context for this analysis
-	c.analysisCtx, c.analysisCancel = context.WithCancel(context.Background())
-	defer func() {
-		c.analysisCtx = nil
-		c.analysisCancel = nil
-	}()
-
-	// Run analysis pipeline
 	pipeline := server.NewPipeline(c.config.RegistryURL, c.config.RegistryToken, c.config.RegistryOwner,
-		c.config.GitHubToken, c.config.RepoOwner, c.config.RepoName, c, c.config.BaselinePath, c.config.OpenAIAPIKey,
+		c.config.GitHubToken, c.config.RepoOwner, c.config.RepoName, nil, c.config.BaselinePath, c.config.OpenAIAPIKey,
 		c.config.SafeRegistryURL, c.config.SafeRegistryToken, c.config.SafeRegistryOwner)
 
-	if err := pipeline.Run(c.analysisCtx, payload.PackageJSON); err != nil {
-		if c.analysisCtx.Err() == context.Canceled {
-			c.SendLog("Analysis cancelled", "warning")
-		} else {
-			c.SendError("Analysis failed", err)
-		}
+	// Client may pick the ID to correlate its request; otherwise reuse the run ID
+	analysisID := msg.AnalysisID
+	if analysisID == "" {
+		analysisID = pipeline.RunID()
+	}
+	sender := server.WithAnalysisID(c, analysisID)
+	pipeline.SetSender(sender)
+	pipeline.SetQuarantineDir(c.config.QuarantineDir)
+	pipeline.SetResultsLog(c.config.ResultsLog)
+	pipeline.SetBaselineDrift(c.config.DriftHistory, c.config.DriftThreshold, c.config.DriftRuns)
+	pipeline.SetTelemetry(c.config.TelemetryURL)
+	pipeline.SetSIEM(c.config.SIEMURL, c.config.SIEMFormat, c.config.SIEMToken)
+	pipeline.SetTicketing(c.config.Tickets)
+	pipeline.SetProcessKeyMode(c.config.ProcessKey)
+	pipeline.SetAllowNonNpm(c.config.AllowNonNpm)
+	pipeline.SetRegistryTypes(c.config.RegistryType, c.config.SafeRegistryType)
+	pipeline.SetSyscallThreshold(c.config.SyscallThreshold)
+	pipeline.SetAccessThreshold(c.config.AccessThreshold)
+	pipeline.SetAllowlist(c.config.Allowlist)
+	pipeline.SetIntel(c.config.Intel)
+	pipeline.SetArtifactSink(c.config.ArtifactSink)
+	pipeline.SetWorkflowRetries(c.config.PackageTimeouts, c.config.WorkflowRetries)
+	pipeline.SetWorkflowInputs(c.config.WorkflowInputs)
+	pipeline.SetTests(c.config.Tests)
+	pipeline.SetRunWaker(c.config.RunWaker)
+	if c.config.ArtifactIngestToken != "" {
+		pipeline.SetIngestDir(c.config.ArtifactIngestDir)
+	}
+	pipeline.SetAIProvider(c.config.AIProvider, c.config.AIBaseURL, c.config.AIModel)
+	pipeline.SetAnalysisSources(c.config.AnalysisSources)
+	pipeline.SetAnalysisLanguage(c.config.AnalysisLanguage)
+	pipeline.SetStageConcurrency(c.config.StageConcurrency)
+
+	ctx, err := c.manager.Start(c, analysisID)
+	if err != nil {
+		sender.SendError("Cannot start analysis", err)
 		return
 	}
+	sender.SendMessage(server.NewStartedMessage(analysisID))
+
+	// Run off the read loop so further analyze/cancel messages can arrive
+	go func() {
+		defer c.manager.Finish(c, analysisID)
+
+		if err := pipeline.Run(ctx, payload.PackageJSON); err != nil {
+			if errors.Is(err, orchestrator.ErrCancelled) || ctx.Err() == context.Canceled {
+				sender.SendMessage(server.NewCancelledMessage("Analysis cancelled"))
+			} else {
+				sender.SendError("Analysis failed", err)
+			}
+			return
+		}
 
-	c.SendMessage(server.NewCompleteMessage(true, "Analysis complete"))
+		sender.SendMessage(server.NewCompleteMessage(true, "Analysis complete"))
+	}()
 }
 
-func serveWs(config *Config, w http.ResponseWriter, r *http.Request) {
+func serveWs(config *Config, manager *server.AnalysisManager, w http.ResponseWriter, r *http.Request) {
 	conn, err := upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade connection: %v", err)
 		return
 	}
 
-	client := newClient(conn, config)
+	client := newClient(conn, config, manager)
 
 	// Start goroutines for reading and writing
 	go client.writePump()
@@ -247,15 +582,50 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
+	logging.Setup(config.LogFormat)
+	breaker.SetDefaults(config.BreakerFailures, config.BreakerCooldown)
 
-	// Health check endpoint
+	// Health check endpoint. The server itself is up whenever it answers, so
+	// a degraded dependency is reported in the body, not the status code.
 	http.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(breaker.CurrentHealth())
+	})
+
+	// Build info, also stamped into every generated artifact
+	http.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(version.Get())
 	})
 
+	manager := server.NewAnalysisManager(config.MaxAnalysesPerClient, config.MaxAnalyses)
+
+	// GitHub workflow_run webhooks wake analyses waiting on a run
+	if config.RunWaker != nil {
+		http.Handle("/webhooks/workflow", server.WorkflowWebhookHandler(config.GitHubWebhookSecret, config.RunWaker))
+	}
+
+	// Trace uploads from runners without a filesystem shared with the server
+	if config.ArtifactIngestToken != "" {
+		http.Handle(server.IngestRoute, server.ArtifactIngestHandler(config.ArtifactIngestToken, config.ArtifactIngestDir))
+	}
+
+	// Cached, unauthenticated verdicts for developer portals. With a safe
+	// registry, only versions actually published to it are served.
+	if config.PublicVerdicts {
+		var published server.PublishedFunc
+		if config.SafeRegistryToken != "" {
+			safe := registry.NewUploader(config.SafeRegistryURL, config.SafeRegistryOwner, config.SafeRegistryToken)
+			safe.SetBackend(registry.NewBackend(config.SafeRegistryType, config.SafeRegistryURL, config.SafeRegistryOwner, config.SafeRegistryToken))
+			published = safe.PackageExists
+		}
+		index := server.NewVerdictIndex(config.ResultsLog, config.VerdictCacheTTL, published)
+		http.Handle(server.VerdictRoute, server.VerdictHandler(index))
+		http.Handle(server.BulkVerdictRoute, server.BulkVerdictHandler(index))
+	}
+
 	// WebSocket endpoint
 	http.HandleFunc("/ws", func(w http.ResponseWriter, r *http.Request) {
-		serveWs(config, w, r)
+		serveWs(config, manager, w, r)
 	})
 
 	port := config.Port
@@ -263,7 +633,7 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("Server starting on port %s", port)
+	log.Printf("%s: server starting on port %s", version.Get(), port)
 	if err := http.ListenAndServe(":"+port, nil); err != nil {
 		log.Fatalf("Server failed: %v", err)
 	}
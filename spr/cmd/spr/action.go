@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// runActionCommand analyzes GitHub Actions: the action workflow checks each
+// one out and runs it as a workflow step would, with canary secrets, and the
+// trace goes through the same aggregation, rules and AI analysis as a
+// package's
+func runActionCommand(cfg *Config, args []string) {
+	runSeconds := 0
+	var with []string
+	var refs []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-output":
+			if i+1 < len(args) {
+				cfg.OutputDir = args[i+1]
+				i++
+			}
+		case "-workflow":
+			if i+1 < len(args) {
+				cfg.ActionWorkflowFile = args[i+1]
+				i++
+			}
+		case "-baseline":
+			if i+1 < len(args) {
+				cfg.ActionBaselinePath = args[i+1]
+				i++
+			}
+		case "-with":
+			if i+1 < len(args) {
+				key, _, ok := strings.Cut(args[i+1], "=")
+				if !ok || strings.TrimSpace(key) == "" || strings.Contains(args[i+1], "\n") {
+					fmt.Fprintf(os.Stderr, "Error: invalid -with: %q, expected key=value\n", args[i+1])
+					os.Exit(1)
+				}
+				with = append(with, args[i+1])
+				i++
+			}
+		case "-run-seconds":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid -run-seconds: %q\n", args[i+1])
+					os.Exit(1)
+				}
+				runSeconds = n
+				i++
+			}
+		case "-process-key":
+			if i+1 < len(args) {
+				cfg.ProcessKey = args[i+1]
+				i++
+			}
+		case "-help":
+			printActionUsage()
+			os.Exit(0)
+		default:
+			refs = append(refs, args[i])
+		}
+	}
+
+	if len(refs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no action given, e.g. spr action actions/setup-node@v4")
+		printActionUsage()
+		os.Exit(1)
+	}
+	actions := make([]models.Package, 0, len(refs))
+	for _, ref := range refs {
+		action, err := orchestrator.ParseActionRef(ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		actions = append(actions, action)
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -process-key: %v\n", err)
+		os.Exit(1)
+	}
+	workflowInputs, err := orchestrator.ParseWorkflowInputs(cfg.WorkflowInputs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid WORKFLOW_INPUTS: %v\n", err)
+		os.Exit(1)
+	}
+	if runSeconds > 0 {
+		workflowInputs["run_seconds"] = strconv.Itoa(runSeconds)
+	}
+	if len(with) > 0 {
+		// One key=value per line, as the workflow turns them into INPUT_ variables
+		workflowInputs["with"] = strings.Join(with, "\n")
+	}
+
+	logging.Setup(cfg.LogFormat)
+	runLogger := slog.Default().With(logging.KeyRunID, logging.NewRunID())
+
+	results, err := analyzeTargets(context.Background(), cfg, actions, cfg.ActionWorkflowFile, cfg.ActionBaselinePath, workflowInputs, keyMode, runLogger,
+		func(orch *orchestrator.Orchestrator) { orch.SetActionMode(true) })
+	if printTargetVerdicts(cfg, actions, results, err, orchestrator.ActionRef) {
+		os.Exit(1)
+	}
+}
+
+func printActionUsage() {
+	fmt.Println("Usage: spr action [options] <owner/action@ref>...")
+	fmt.Println("")
+	fmt.Println("Analyzes GitHub Actions before a workflow uses them. The action workflow checks out each")
+	fmt.Println("action and runs it as a step would (node, Docker and composite actions) in a sandbox with")
+	fmt.Println("canary secrets: a GITHUB_TOKEN, runtime token and cloud and registry credentials that")
+	fmt.Println("exist nowhere else. The trace goes through the same aggregation, rules and AI analysis as")
+	fmt.Println("npm packages, and a canary value leaving the sandbox flags the action outright.")
+	fmt.Println("Exits non-zero when an action is flagged or fails analysis.")
+	fmt.Println("")
+	fmt.Println("Examples:")
+	fmt.Println("  spr action actions/setup-node@v4")
+	fmt.Println("  spr action -with node-version=20 -with cache=npm actions/setup-node@<commit sha>")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -output <dir>          Output directory for artifacts (default: ./analysis-results)")
+	fmt.Println("  -workflow <file>       Action workflow file (default: analyze-action.yml)")
+	fmt.Println("  -with <key=value>      Input passed to the action, as in with: (repeatable)")
+	fmt.Println("  -baseline <file>       Stats subtracted from each trace (default: none, every traced")
+	fmt.Println("                         activity is kept)")
+	fmt.Println("  -run-seconds <n>       How long the action may run before it is stopped (default: 300)")
+	fmt.Println("  -process-key <mode>    Key processes by name, ancestry or cmdline (default: ancestry)")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/popularity"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// analyzeGraph uploads graph to the staging registry and runs the behavioral
+// pipeline for pkgs, leaving results in cfg.OutputDir. Packages passing
+// analysis are promoted to the safe registry only when promote is set and
+// SAFE_REGISTRY_TOKEN is configured. Used by the commands that analyze a few
+// packages at a time (diff, watch); check drives the orchestrator itself.
+func analyzeGraph(ctx context.Context, cfg *Config, graph *models.DependencyGraph, pkgs []models.Package, keyMode aggregate.KeyMode, logger *slog.Logger, promote bool) ([]orchestrator.PackageResult, error) {
+	if cfg.RegistryToken == "" {
+		return nil, fmt.Errorf("REGISTRY_TOKEN is required")
+	}
+	if cfg.GitHubToken == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN is required")
+	}
+	packageTimeouts, err := orchestrator.ParsePackageTimeouts(cfg.PackageTimeouts)
+	if err != nil {
+		return nil, fmt.Errorf("PACKAGE_TIMEOUTS: %w", err)
+	}
+	trustedPublishers, skipTrusted, err := cfg.trustedPublishers()
+	if err != nil {
+		return nil, fmt.Errorf("TRUSTED_PUBLISHERS: %w", err)
+	}
+	agePolicy, err := orchestrator.ParseAgePolicy(cfg.MinPackageAge, cfg.PackageAgeAction)
+	if err != nil {
+		return nil, fmt.Errorf("MIN_PACKAGE_AGE: %w", err)
+	}
+	confusionChecker, err := cfg.confusionChecker(".")
+	if err != nil {
+		return nil, fmt.Errorf(".npmrc: %w", err)
+	}
+	typosquatChecker, err := cfg.typosquatChecker()
+	if err != nil {
+		return nil, fmt.Errorf("TYPOSQUAT_NAMES: %w", err)
+	}
+	if err := cfg.stageConcurrency().Validate(); err != nil {
+		return nil, err
+	}
+	analysisSources, err := analysis.ParseSources(cfg.AnalysisSources, cfg.TraceAPIURL, cfg.TraceAPIToken)
+	if err != nil {
+		return nil, fmt.Errorf("ANALYSIS_SOURCES: %w", err)
+	}
+	siemFormat, err := siem.ParseFormat(cfg.SIEMFormat)
+	if err != nil {
+		return nil, fmt.Errorf("SIEM_FORMAT: %w", err)
+	}
+	allowlist, err := cfg.allowlist()
+	if err != nil {
+		return nil, fmt.Errorf("ALLOWLIST: %w", err)
+	}
+	enricher, err := cfg.intel()
+	if err != nil {
+		return nil, fmt.Errorf("INTEL: %w", err)
+	}
+	ticketFiler, err := cfg.ticketFiler()
+	if err != nil {
+		return nil, fmt.Errorf("TICKETS: %w", err)
+	}
+	uploader, err := newStagingUploader(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+	tarballDir, err := os.MkdirTemp("", "spr-tarballs-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tarball directory: %w", err)
+	}
+	defer os.RemoveAll(tarballDir)
+	uploader.SetTarballDir(tarballDir)
+	if err := uploader.UploadGraph(ctx, graph); err != nil {
+		return nil, fmt.Errorf("uploading to registry: %w", err)
+	}
+
+	var safeUploader *registry.Uploader
+	if promote && cfg.SafeRegistryToken != "" {
+		safeRegistryType, err := registry.ParseType(cfg.SafeRegistryType)
+		if err != nil {
+			return nil, fmt.Errorf("SAFE_REGISTRY_TYPE: %w", err)
+		}
+		safeUploader = registry.NewUploader(cfg.SafeRegistryURL, cfg.SafeRegistryOwner, cfg.SafeRegistryToken)
+		safeUploader.Concurrency = cfg.UploadConcurrency
+		safeUploader.SetLogger(logger)
+		safeUploader.SetBackend(registry.NewBackend(safeRegistryType, cfg.SafeRegistryURL, cfg.SafeRegistryOwner, cfg.SafeRegistryToken))
+		safeUploader.SetAllowNonNpm(cfg.AllowNonNpm)
+	}
+
+	if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp("", "spr-analysis-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	orch := orchestrator.NewOrchestrator(
+		cfg.GitHubToken,
+		cfg.RepoOwner,
+		cfg.RepoName,
+		cfg.WorkflowFile,
+		cfg.Concurrency,
+		time.Duration(cfg.TimeoutMinutes)*time.Minute,
+		nil,
+		cfg.BaselinePath,
+		cfg.OpenAIAPIKey,
+		safeUploader,
+		graph,
+	)
+	orch.SetLogger(logger)
+	orch.SetAIProvider(cfg.AIProvider, cfg.AIBaseURL, cfg.AIModel)
+	orch.SetMaxAIBudget(cfg.MaxAIBudget)
+	orch.SetMaxPromptIndicators(cfg.MaxPromptIndicators)
+	orch.SetAnalysisSources(analysisSources)
+	orch.SetAnalysisLanguage(cfg.AnalysisLanguage)
+	orch.SetStageConcurrency(cfg.stageConcurrency())
+	orch.SetSkipDiskCheck(cfg.SkipDiskCheck)
+	orch.SetKeepTraces(cfg.KeepTraces)
+	orch.SetInterceptTLS(cfg.InterceptTLS)
+	orch.SetPackageTimeouts(packageTimeouts)
+	orch.SetRetries(cfg.WorkflowRetries)
+	orch.SetKeepGoing(cfg.KeepGoing)
+	orch.SetQuarantineDir(cfg.QuarantineDir)
+	orch.SetResultsLog(cfg.ResultsLog, "")
+	orch.SetBaselineDrift(cfg.DriftHistory, cfg.DriftThreshold, cfg.DriftRuns)
+	orch.SetTelemetry(cfg.TelemetryURL)
+	orch.SetSIEM(cfg.SIEMURL, siemFormat, cfg.SIEMToken)
+	orch.SetTicketing(ticketFiler)
+	orch.SetProcessKeyMode(keyMode)
+	orch.SetSyscallThreshold(aggregate.SyscallThreshold{Ratio: cfg.SyscallRatio, MinDelta: cfg.SyscallMinDelta, ZScore: cfg.SyscallZScore})
+	orch.SetAccessThreshold(aggregate.AccessThreshold{Ratio: cfg.AccessRatio, MinDelta: cfg.AccessMinDelta})
+	orch.SetMaxLineSize(cfg.MaxLineMB << 20)
+	orch.SetAllowlist(allowlist)
+	orch.SetIntel(enricher)
+	orch.SetTrustedPublishers(trustedPublishers, skipTrusted)
+	orch.SetConfusionCheck(confusionChecker)
+	orch.SetTyposquatCheck(typosquatChecker)
+	orch.SetAgePolicy(agePolicy)
+	orch.SetTarballDir(tarballDir)
+	if !cfg.SkipPopularity {
+		orch.SetPopularity(popularity.NewClient(popularity.DefaultCacheFile))
+	}
+
+	results, err := orch.RunPackages(ctx, pkgs, tempDir, cfg.OutputDir)
+	printAIUsage(orch.AIUsage(), cfg.MaxAIBudget)
+	printTrusted(orch.TrustedPackages())
+	printTyposquats(orch.TyposquatFindings())
+	printConfusion(orch.ConfusionFindings())
+	printTooNew(orch.TooNew())
+	printStaticFlagged(orch.StaticFlagged())
+	if err != nil {
+		return results, err
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			return results, fmt.Errorf("%s@%s: %w", result.Package.Name, result.Package.Version, result.Error)
+		}
+	}
+	return results, nil
+}
+
+// newStagingUploader returns an uploader for the registry packages are
+// analyzed from
+func newStagingUploader(cfg *Config, logger *slog.Logger) (*registry.Uploader, error) {
+	registryType, err := registry.ParseType(cfg.RegistryType)
+	if err != nil {
+		return nil, err
+	}
+	uploader := registry.NewUploader(cfg.RegistryURL, cfg.RegistryOwner, cfg.RegistryToken)
+	uploader.Concurrency = cfg.UploadConcurrency
+	uploader.SetLogger(logger)
+	uploader.SetBackend(registry.NewBackend(registryType, cfg.RegistryURL, cfg.RegistryOwner, cfg.RegistryToken))
+	uploader.SetAllowNonNpm(cfg.AllowNonNpm)
+	return uploader, nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/parser"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// cacheDir is where the orchestrator persists results across runs
+const cacheDir = "analysis-results"
+
+// runDiffCommand compares the behavior of two package versions. Each version
+// is loaded from cached results when available and analyzed otherwise; the
+// older version then serves as the baseline of the newer one, so the output
+// is exactly what the update added.
+func runDiffCommand(cfg *Config, args []string) {
+	jsonOutput := false
+	cachedOnly := false
+	var specs []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-output":
+			if i+1 < len(args) {
+				cfg.OutputDir = args[i+1]
+				i++
+			}
+		case "-process-key":
+			if i+1 < len(args) {
+				cfg.ProcessKey = args[i+1]
+				i++
+			}
+		case "-json":
+			jsonOutput = true
+		case "-cached":
+			cachedOnly = true
+		case "-help":
+			printDiffUsage()
+			os.Exit(0)
+		default:
+			specs = append(specs, args[i])
+		}
+	}
+
+	if len(specs) != 2 {
+		fmt.Fprintln(os.Stderr, "Error: expected two package specs, e.g. spr diff left-pad@1.2.0 left-pad@1.3.0")
+		printDiffUsage()
+		os.Exit(1)
+	}
+
+	var pkgs [2]models.Package
+	for i, spec := range specs {
+		pkg, err := parsePackageSpec(spec)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		pkgs[i] = pkg
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -process-key: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Diffing needs the raw traces, so don't prune them after analyzing
+	cfg.KeepTraces = true
+
+	logging.Setup(cfg.LogFormat)
+	runLogger := slog.Default().With(logging.KeyRunID, logging.NewRunID())
+	ctx := context.Background()
+
+	var stats [2]*aggregate.PerProcessStats
+	for i, pkg := range pkgs {
+		behaviorPath := findCachedBehavior(pkg, cfg.OutputDir)
+		if behaviorPath == "" {
+			if cachedOnly {
+				fmt.Fprintf(os.Stderr, "Error: no cached results for %s@%s\n", pkg.Name, pkg.Version)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "No cached results for %s@%s, analyzing...\n", pkg.Name, pkg.Version)
+			if err := analyzeSinglePackage(ctx, cfg, pkg, keyMode, runLogger); err != nil {
+				fmt.Fprintf(os.Stderr, "Error analyzing %s@%s: %v\n", pkg.Name, pkg.Version, err)
+				os.Exit(1)
+			}
+			if behaviorPath = findCachedBehavior(pkg, cfg.OutputDir); behaviorPath == "" {
+				fmt.Fprintf(os.Stderr, "Error: analysis of %s@%s produced no behavior.jsonl\n", pkg.Name, pkg.Version)
+				fmt.Fprintf(os.Stderr, "Its cached trace may have been pruned after diffing; delete %s to rerun it\n",
+					filepath.Join(cacheDir, naming.DirName(pkg.Name, pkg.Version), "diff.json"))
+				os.Exit(1)
+			}
+		}
+
+		s, err := loadBehavior(behaviorPath, keyMode, cfg.MaxLineMB<<20)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		stats[i] = s
+	}
+
+	threshold := aggregate.SyscallThreshold{Ratio: cfg.SyscallRatio, MinDelta: cfg.SyscallMinDelta, ZScore: cfg.SyscallZScore}
+	access := aggregate.AccessThreshold{Ratio: cfg.AccessRatio, MinDelta: cfg.AccessMinDelta}
+	delta := aggregate.DedupWithThresholds(stats[1], stats[0], threshold, access)
+	allowlist, err := cfg.allowlist()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid allowlist: %v\n", err)
+		os.Exit(1)
+	}
+	allowlist.Apply(delta)
+	enricher, err := cfg.intel()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid intel: %v\n", err)
+		os.Exit(1)
+	}
+	enricher.Enrich(delta)
+
+	if jsonOutput {
+		out, err := json.MarshalIndent(delta, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(out))
+		return
+	}
+	printBehaviorDelta(pkgs[0], pkgs[1], delta)
+}
+
+// parsePackageSpec splits name@version, allowing scoped names
+func parsePackageSpec(spec string) (models.Package, error) {
+	i := strings.LastIndex(spec, "@")
+	if i <= 0 || i == len(spec)-1 {
+		return models.Package{}, fmt.Errorf("invalid package spec %q (expected name@version)", spec)
+	}
+	return models.Package{Name: spec[:i], Version: spec[i+1:]}, nil
+}
+
+// findCachedBehavior returns the path of a previous run's behavior.jsonl for
+// pkg, or "" when there is none
+func findCachedBehavior(pkg models.Package, outputDir string) string {
+	pkgKey := naming.DirName(pkg.Name, pkg.Version)
+	for _, dir := range []string{outputDir, cacheDir} {
+		path := filepath.Join(dir, pkgKey, "behavior.jsonl")
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// loadBehavior aggregates a behavior.jsonl along with the proxy.jsonl next to
+// it, if the run intercepted TLS, skipping lines longer than maxLine bytes
+func loadBehavior(behaviorPath string, keyMode aggregate.KeyMode, maxLine int) (*aggregate.PerProcessStats, error) {
+	dir := filepath.Dir(behaviorPath)
+	aggregator := aggregate.NewProcessAggregator()
+	aggregator.SetKeyMode(keyMode)
+	aggregator.SetMaxLineSize(maxLine)
+	stats, err := aggregator.ProcessFile(behaviorPath, filepath.Base(dir))
+	if err != nil {
+		return nil, fmt.Errorf("failed to process %s: %w", behaviorPath, err)
+	}
+	warnOversized(behaviorPath, aggregator.OversizedLines())
+
+	proxyPath := filepath.Join(dir, "proxy.jsonl")
+	if _, err := os.Stat(proxyPath); err == nil {
+		proxy := aggregate.NewProxyAggregator()
+		proxy.SetMaxLineSize(maxLine)
+		httpActivity, err := proxy.ProcessFile(proxyPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to process %s: %w", proxyPath, err)
+		}
+		warnOversized(proxyPath, proxy.OversizedLines())
+		stats.HTTPActivity = httpActivity
+	}
+	return stats, nil
+}
+
+// warnOversized reports trace lines skipped for their size
+func warnOversized(path string, lines int) {
+	if lines > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: skipped %d lines of %s longer than -max-line-mb\n", lines, path)
+	}
+}
+
+// analyzeSinglePackage runs the check pipeline for one package version: a
+// throwaway package.json depending on it is resolved, uploaded to the
+// registry and analyzed, leaving results in cfg.OutputDir and the cache
+func analyzeSinglePackage(ctx context.Context, cfg *Config, pkg models.Package, keyMode aggregate.KeyMode, logger *slog.Logger) error {
+	tempDir, err := os.MkdirTemp("", "spr-diff-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	pkgJSON, err := json.Marshal(map[string]interface{}{
+		"name":         "spr-diff",
+		"version":      "0.0.0",
+		"private":      true,
+		"dependencies": map[string]string{pkg.Name: pkg.Version},
+	})
+	if err != nil {
+		return err
+	}
+	pkgJSONPath := filepath.Join(tempDir, "package.json")
+	if err := os.WriteFile(pkgJSONPath, pkgJSON, 0o644); err != nil {
+		return fmt.Errorf("failed to write package.json: %w", err)
+	}
+	graph, err := parser.BuildGraphFromPackageJSON(pkgJSONPath)
+	if err != nil {
+		return fmt.Errorf("building dependency graph: %w", err)
+	}
+
+	// Never promote from a diff
+	_, err = analyzeGraph(ctx, cfg, graph, []models.Package{pkg}, keyMode, logger, false)
+	return err
+}
+
+// printBehaviorDelta prints what newer does that older didn't
+func printBehaviorDelta(older, newer models.Package, delta *aggregate.DedupedProcessStats) {
+	fmt.Printf("Behavioral changes from %s@%s to %s@%s\n", older.Name, older.Version, newer.Name, newer.Version)
+
+	files := make(map[string]int)
+	writes := make(map[string]int)
+	commands := make(map[string]int)
+	domains := make(map[string]int)
+	ips := make(map[string]int)
+	syscalls := make(map[string]int)
+	var newProcesses []string
+	for procName, proc := range delta.PerProcess {
+		newProcesses = append(newProcesses, procName)
+		for k, v := range proc.FileAccess {
+			files[k] += v
+		}
+		for k, v := range proc.FileWrites {
+			writes[k] += v
+		}
+		for k, v := range proc.ExecutedCommands {
+			commands[k] += v
+		}
+		for k, v := range proc.NetworkActivity.DNSRecords {
+			domains[k] += v
+		}
+		for k, v := range proc.NetworkActivity.IPs {
+			ips[k] += v
+		}
+		for k, v := range proc.SyscallProfile {
+			syscalls[procName+": "+k] += v
+		}
+	}
+
+	var hosts map[string]int
+	if delta.HTTPActivity != nil {
+		hosts = delta.HTTPActivity.Hosts
+	}
+	urls := make(map[string]int, len(delta.URLIndicators))
+	for _, ind := range delta.URLIndicators {
+		urls[fmt.Sprintf("%s [%s]", ind.URL, ind.Class)] = ind.Count
+	}
+
+	empty := true
+	for _, section := range []struct {
+		title  string
+		values map[string]int
+	}{
+		{"New commands executed", commands},
+		{"New domains looked up", domains},
+		{"New IPs contacted", ips},
+		{"New HTTP(S) hosts", hosts},
+		{"New URLs in command lines", urls},
+		{"New files written", writes},
+		{"New files accessed", files},
+		{"Syscalls above the older version", syscalls},
+	} {
+		if len(section.values) == 0 {
+			continue
+		}
+		empty = false
+		fmt.Printf("\n%s:\n", section.title)
+		keys := make([]string, 0, len(section.values))
+		for k := range section.values {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Printf("  + %s (%d)\n", k, section.values[k])
+		}
+	}
+
+	if empty {
+		fmt.Println("\nNo new behavior.")
+		return
+	}
+	sort.Strings(newProcesses)
+	fmt.Printf("\nProcesses with new behavior: %s\n", strings.Join(newProcesses, ", "))
+}
+
+func printDiffUsage() {
+	fmt.Println("Usage: spr diff [options] <pkg>@<old> <pkg>@<new>")
+	fmt.Println("")
+	fmt.Println("Compares the runtime behavior of two package versions, using the older one as the")
+	fmt.Println("baseline of the newer one. Versions without cached results are analyzed first")
+	fmt.Println("(requires the same registry and GitHub settings as spr check).")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -output <dir>          Results directory searched before the analysis-results cache (default: ./analysis-results)")
+	fmt.Println("  -cached                Only use cached results, fail instead of analyzing")
+	fmt.Println("  -process-key <mode>    Key processes by name, ancestry or cmdline (default: ancestry)")
+	fmt.Println("  -json                  Print the delta as JSON (diff.json format)")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// runEventsCommand prints the raw trace events of one process or event type,
+// seeking to them through the trace's index instead of scanning the trace
+func runEventsCommand(cfg *Config, args []string) {
+	process := ""
+	event := ""
+	limit := 0
+	list := false
+	tracePath := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-process":
+			if i+1 < len(args) {
+				process = args[i+1]
+				i++
+			}
+		case "-event":
+			if i+1 < len(args) {
+				event = args[i+1]
+				i++
+			}
+		case "-limit":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n < 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid -limit: %q\n", args[i+1])
+					os.Exit(1)
+				}
+				limit = n
+				i++
+			}
+		case "-list":
+			list = true
+		case "-help":
+			printEventsUsage()
+			os.Exit(0)
+		default:
+			tracePath = args[i]
+		}
+	}
+
+	if tracePath == "" {
+		fmt.Fprintln(os.Stderr, "Error: no trace given")
+		printEventsUsage()
+		os.Exit(1)
+	}
+
+	index, err := loadOrBuildIndex(cfg, tracePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	if list {
+		printIndexSummary(index)
+		return
+	}
+
+	offsets := index.Lookup(process, event)
+	if limit > 0 && len(offsets) > limit {
+		offsets = offsets[:limit]
+	}
+
+	f, err := os.Open(tracePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	defer f.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	err = aggregate.ReadLinesAt(f, offsets, cfg.MaxLineMB<<20, func(_ int64, line []byte) error {
+		out.Write(line)
+		return out.WriteByte('\n')
+	})
+	if err != nil {
+		out.Flush()
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// loadOrBuildIndex loads the index written next to a trace when it was
+// aggregated, or indexes the trace now (and saves the index) if there is
+// none or the trace changed since
+func loadOrBuildIndex(cfg *Config, tracePath string) (*aggregate.EventIndex, error) {
+	indexPath := aggregate.IndexPath(tracePath)
+	if index, err := aggregate.LoadEventIndex(indexPath); err == nil && !index.Stale(tracePath) {
+		return index, nil
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		return nil, fmt.Errorf("PROCESS_KEY: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Indexing %s...\n", tracePath)
+	index, err := aggregate.BuildEventIndex(tracePath, keyMode, cfg.MaxLineMB<<20)
+	if err != nil {
+		return nil, err
+	}
+	if err := aggregate.WriteEventIndex(indexPath, index); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	return index, nil
+}
+
+// printIndexSummary lists the processes of a trace and their event counts
+func printIndexSummary(index *aggregate.EventIndex) {
+	processes := make([]string, 0, len(index.Processes))
+	for process := range index.Processes {
+		processes = append(processes, process)
+	}
+	sort.Strings(processes)
+
+	fmt.Printf("%s: %d events, %d processes (process keys: %s)\n", index.Source, index.Events, len(processes), index.KeyMode)
+	for _, process := range processes {
+		events := index.Processes[process]
+		names := make([]string, 0, len(events))
+		for name := range events {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		fmt.Printf("  %s\n", process)
+		for _, name := range names {
+			fmt.Printf("    %-28s %d\n", name, len(events[name]))
+		}
+	}
+}
+
+func printEventsUsage() {
+	fmt.Println("Usage: spr events [options] <behavior.jsonl>")
+	fmt.Println("")
+	fmt.Println("Print the raw trace events of a process or event type, e.g. to see the")
+	fmt.Println("arguments behind a connect in diff.json. spr check indexes each trace")
+	fmt.Println("(behavior.index.json) when it aggregates it, so only the matching events")
+	fmt.Println("are read; traces without a current index are indexed first.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -process <key>         Only events of this process, keyed as in diff.json per_process")
+	fmt.Println("  -event <name>          Only events of this type, e.g. connect or openat")
+	fmt.Println("  -limit <n>             Print at most n events (default: all)")
+	fmt.Println("  -list                  List the processes and their event counts instead")
+	fmt.Println("  -help                  Show this help message")
+	fmt.Println("")
+	fmt.Println("Traces are indexed with PROCESS_KEY and MAX_LINE_MB.")
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/parser"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// runFixCommand pins the flagged packages of a project to the nearest clean
+// version in the results store, by writing an overrides block (npm) or
+// resolutions block (yarn) to its package.json. With -pr the change is pushed
+// to a new branch of a GitHub repository and a pull request is opened instead
+// of only editing the file locally.
+func runFixCommand(cfg *Config, args []string) {
+	packageJSONPath := "package.json"
+	yarn := false
+	offline := false
+	prRepo := ""
+	prBase := ""
+	prPath := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-package":
+			if i+1 < len(args) {
+				packageJSONPath = args[i+1]
+				i++
+			}
+		case "-output":
+			if i+1 < len(args) {
+				cfg.OutputDir = args[i+1]
+				i++
+			}
+		case "-yarn":
+			yarn = true
+		case "-offline":
+			offline = true
+		case "-pr":
+			if i+1 < len(args) {
+				prRepo = args[i+1]
+				i++
+			}
+		case "-base":
+			if i+1 < len(args) {
+				prBase = args[i+1]
+				i++
+			}
+		case "-pr-path":
+			if i+1 < len(args) {
+				prPath = args[i+1]
+				i++
+			}
+		case "-help":
+			printFixUsage()
+			os.Exit(0)
+		}
+	}
+
+	var prOwner, prName string
+	if prRepo != "" {
+		var ok bool
+		prOwner, prName, ok = strings.Cut(prRepo, "/")
+		if !ok || prOwner == "" || prName == "" {
+			fmt.Fprintf(os.Stderr, "Error: -pr expects owner/repo, got %q\n", prRepo)
+			os.Exit(1)
+		}
+		if cfg.GitHubToken == "" {
+			fmt.Fprintln(os.Stderr, "Error: GitHub token is required for -pr (set GITHUB_TOKEN)")
+			os.Exit(1)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	projectDir := filepath.Dir(packageJSONPath)
+	if fileExists(filepath.Join(projectDir, "yarn.lock")) {
+		yarn = true
+	}
+	field := parser.OverridesField
+	if yarn {
+		field = parser.ResolutionsField
+	}
+
+	// Without a lockfile every flagged package in the store is pinned; with
+	// one, only those the project actually installs
+	var graph *models.DependencyGraph
+	if lockfilePath := filepath.Join(projectDir, "package-lock.json"); fileExists(lockfilePath) {
+		var err error
+		if _, graph, err = loadDependencyGraph("", lockfilePath); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		fmt.Println("No package-lock.json found, pinning every flagged package in the results store")
+	}
+	direct := make(map[string]bool)
+	if graph != nil {
+		for _, dep := range graph.GetDirectDependencies() {
+			direct[dep.ID] = true
+		}
+	}
+
+	flagged, err := flaggedPackages(cfg.OutputDir, cacheDir)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	pins := make(map[string]string)
+	var unresolved []string
+	for _, pkg := range flagged {
+		id := pkg.Name + "@" + pkg.Version
+		if graph != nil && graph.Nodes[id] == nil {
+			continue
+		}
+		rem, err := orchestrator.FindRemediation(ctx, pkg, cfg.OutputDir, direct[id], offline)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: failed to list versions of %s, using cached ones only: %v\n", pkg.Name, err)
+		}
+		if rem.Replacement == "" {
+			msg := fmt.Sprintf("  %s: no clean version analyzed yet", id)
+			if len(rem.Candidates) > 0 {
+				msg += "; analyze " + strings.Join(rem.Candidates, ", ") + " next"
+			}
+			unresolved = append(unresolved, msg)
+			continue
+		}
+		if pinned, ok := pins[pkg.Name]; ok && pinned != rem.Replacement {
+			// Several flagged versions of one package; keep the newest pin
+			if registry.CompareSemver(pinned, rem.Replacement) > 0 {
+				continue
+			}
+		}
+		pins[pkg.Name] = rem.Replacement
+	}
+
+	if len(pins) == 0 {
+		fmt.Println("No flagged packages with a clean replacement")
+		for _, msg := range unresolved {
+			fmt.Println(msg)
+		}
+		return
+	}
+
+	if err := parser.SetOverrides(packageJSONPath, field, pins); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	fmt.Printf("Pinned %d packages in the %s field of %s:\n", len(names), field, packageJSONPath)
+	var body strings.Builder
+	body.WriteString("spr flagged the following dependencies as malicious and pins them to the nearest version with a clean verdict:\n\n")
+	for _, name := range names {
+		fmt.Printf("  %s -> %s\n", name, pins[name])
+		fmt.Fprintf(&body, "- `%s` → `%s`\n", name, pins[name])
+	}
+	if len(unresolved) > 0 {
+		fmt.Println("Not pinned:")
+		body.WriteString("\nNo clean version has been analyzed yet for:\n\n")
+		for _, msg := range unresolved {
+			fmt.Println(msg)
+			fmt.Fprintf(&body, "- %s\n", strings.TrimSpace(msg))
+		}
+	}
+	if yarn {
+		fmt.Println("Run yarn install to apply the resolutions")
+		body.WriteString("\nRun `yarn install` to update the lockfile.\n")
+	} else {
+		fmt.Println("Run npm install to apply the overrides")
+		body.WriteString("\nRun `npm install` to update the lockfile.\n")
+	}
+
+	if prRepo == "" {
+		return
+	}
+
+	content, err := os.ReadFile(packageJSONPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if prPath == "" {
+		prPath = "package.json"
+	}
+	client := orchestrator.NewGitHubClient(cfg.GitHubToken, prOwner, prName)
+	url, err := client.OpenFilePullRequest(ctx, orchestrator.FilePullRequest{
+		Path:          prPath,
+		Content:       content,
+		Branch:        "spr-fix-" + time.Now().UTC().Format("20060102-150405"),
+		Base:          prBase,
+		CommitMessage: fmt.Sprintf("Pin %d flagged dependencies to clean versions", len(names)),
+		Title:         "Pin flagged dependencies to clean versions",
+		Body:          body.String(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error opening pull request: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Opened pull request: %s\n", url)
+}
+
+// flaggedPackages lists the packages with a remediation in any of dirs, that
+// is, the packages a previous run flagged as malicious
+func flaggedPackages(dirs ...string) ([]models.Package, error) {
+	seen := make(map[string]bool)
+	var pkgs []models.Package
+	for _, dir := range dirs {
+		paths, err := filepath.Glob(filepath.Join(dir, "*", orchestrator.RemediationFile))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", path, err)
+			}
+			var rem orchestrator.Remediation
+			if err := json.Unmarshal(data, &rem); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+			}
+			if seen[rem.Package] {
+				continue
+			}
+			pkg, err := parsePackageSpec(rem.Package)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			seen[rem.Package] = true
+			pkgs = append(pkgs, pkg)
+		}
+	}
+	return pkgs, nil
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func printFixUsage() {
+	fmt.Println("Usage: spr fix [options]")
+	fmt.Println("")
+	fmt.Println("Pins packages flagged by previous spr check runs to the nearest version with a clean verdict,")
+	fmt.Println("by writing an overrides block (npm) or resolutions block (yarn) to package.json. Only packages")
+	fmt.Println("in the project's package-lock.json are pinned when one is present.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -package <path>        Path to package.json (default: ./package.json)")
+	fmt.Println("  -output <dir>          Directory with analysis results (default: ./analysis-results)")
+	fmt.Println("  -yarn                  Write yarn resolutions (default when yarn.lock exists)")
+	fmt.Println("  -offline               Only consider versions in the results store, don't query the registry")
+	fmt.Println("  -pr <owner/repo>       Push the change to a new branch and open a pull request (needs GITHUB_TOKEN)")
+	fmt.Println("  -base <branch>         Pull request base branch (default: the repository's default branch)")
+	fmt.Println("  -pr-path <path>        Path of package.json in the repository (default: package.json)")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// runImageCommand analyzes container images: each image's entrypoint runs
+// under Tracee in the image workflow, and the trace goes through the same
+// aggregation, rules and AI analysis as a package's. Nothing is uploaded to
+// a registry, so only the GitHub settings are required.
+func runImageCommand(cfg *Config, args []string) {
+	runSeconds := 0
+	entrypointArgs := ""
+	var refs []string
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-output":
+			if i+1 < len(args) {
+				cfg.OutputDir = args[i+1]
+				i++
+			}
+		case "-workflow":
+			if i+1 < len(args) {
+				cfg.ImageWorkflowFile = args[i+1]
+				i++
+			}
+		case "-baseline":
+			if i+1 < len(args) {
+				cfg.ImageBaselinePath = args[i+1]
+				i++
+			}
+		case "-run-seconds":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n <= 0 {
+					fmt.Fprintf(os.Stderr, "Error: invalid -run-seconds: %q\n", args[i+1])
+					os.Exit(1)
+				}
+				runSeconds = n
+				i++
+			}
+		case "-args":
+			if i+1 < len(args) {
+				entrypointArgs = args[i+1]
+				i++
+			}
+		case "-process-key":
+			if i+1 < len(args) {
+				cfg.ProcessKey = args[i+1]
+				i++
+			}
+		case "-help":
+			printImageUsage()
+			os.Exit(0)
+		default:
+			refs = append(refs, args[i])
+		}
+	}
+
+	if len(refs) == 0 {
+		fmt.Fprintln(os.Stderr, "Error: no image given, e.g. spr image nginx:1.25")
+		printImageUsage()
+		os.Exit(1)
+	}
+	images := make([]models.Package, 0, len(refs))
+	for _, ref := range refs {
+		image, err := orchestrator.ParseImageRef(ref)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		images = append(images, image)
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -process-key: %v\n", err)
+		os.Exit(1)
+	}
+	workflowInputs, err := orchestrator.ParseWorkflowInputs(cfg.WorkflowInputs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid WORKFLOW_INPUTS: %v\n", err)
+		os.Exit(1)
+	}
+	if runSeconds > 0 {
+		workflowInputs["run_seconds"] = strconv.Itoa(runSeconds)
+	}
+	if entrypointArgs != "" {
+		workflowInputs["args"] = entrypointArgs
+	}
+
+	logging.Setup(cfg.LogFormat)
+	runLogger := slog.Default().With(logging.KeyRunID, logging.NewRunID())
+
+	results, err := analyzeTargets(context.Background(), cfg, images, cfg.ImageWorkflowFile, cfg.ImageBaselinePath, workflowInputs, keyMode, runLogger,
+		func(orch *orchestrator.Orchestrator) { orch.SetImageMode(true) })
+	if printTargetVerdicts(cfg, images, results, err, orchestrator.ImageRef) {
+		os.Exit(1)
+	}
+}
+
+// printTargetVerdicts prints the verdict of each analyzed target, named by
+// ref, and reports whether any was flagged or failed analysis
+func printTargetVerdicts(cfg *Config, targets []models.Package, results []orchestrator.PackageResult, err error, ref func(models.Package) string) bool {
+	flagged := err != nil
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+	}
+
+	verdicts, verr := orchestrator.LoadVerdicts(cfg.OutputDir, targets)
+	if verr != nil {
+		fmt.Fprintf(os.Stderr, "Error loading verdicts: %v\n", verr)
+		flagged = true
+	}
+	fmt.Println("\nVerdicts:")
+	for _, result := range results {
+		name := ref(result.Package)
+		assessment, ok := verdicts[result.Package.Name+"@"+result.Package.Version]
+		switch {
+		case result.Error != nil:
+			fmt.Printf("  %s: ERROR (%v)\n", name, result.Error)
+			flagged = true
+		case !ok:
+			fmt.Printf("  %s: no result\n", name)
+			flagged = true
+		case assessment == nil:
+			fmt.Printf("  %s: SAFE (no anomalous behavior)\n", name)
+		case assessment.IsMalicious:
+			fmt.Printf("  %s: MALICIOUS (confidence %.2f)\n", name, assessment.Confidence)
+			for _, indicator := range assessment.Indicators {
+				fmt.Printf("      - %s\n", indicator)
+			}
+			flagged = true
+		default:
+			fmt.Printf("  %s: SAFE (confidence %.2f)\n", name, assessment.Confidence)
+		}
+	}
+
+	fmt.Printf("\nArtifacts saved to: %s\n", cfg.OutputDir)
+	return flagged
+}
+
+// analyzeTargets runs workflowFile for targets other than npm packages
+// (images, actions) and analyzes the traces, leaving results in
+// cfg.OutputDir. setMode puts the orchestrator in the targets' mode.
+func analyzeTargets(ctx context.Context, cfg *Config, targets []models.Package, workflowFile, baselinePath string, workflowInputs map[string]string, keyMode aggregate.KeyMode, logger *slog.Logger, setMode func(*orchestrator.Orchestrator)) ([]orchestrator.PackageResult, error) {
+	if cfg.GitHubToken == "" {
+		return nil, fmt.Errorf("GITHUB_TOKEN is required")
+	}
+	if err := cfg.stageConcurrency().Validate(); err != nil {
+		return nil, err
+	}
+	analysisSources, err := analysis.ParseSources(cfg.AnalysisSources, cfg.TraceAPIURL, cfg.TraceAPIToken)
+	if err != nil {
+		return nil, fmt.Errorf("ANALYSIS_SOURCES: %w", err)
+	}
+	siemFormat, err := siem.ParseFormat(cfg.SIEMFormat)
+	if err != nil {
+		return nil, fmt.Errorf("SIEM_FORMAT: %w", err)
+	}
+	allowlist, err := cfg.allowlist()
+	if err != nil {
+		return nil, fmt.Errorf("ALLOWLIST: %w", err)
+	}
+	enricher, err := cfg.intel()
+	if err != nil {
+		return nil, fmt.Errorf("INTEL: %w", err)
+	}
+	ticketFiler, err := cfg.ticketFiler()
+	if err != nil {
+		return nil, fmt.Errorf("TICKETS: %w", err)
+	}
+
+	if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create output directory: %w", err)
+	}
+	tempDir, err := os.MkdirTemp("", "spr-analysis-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// No dependency graph or safe registry: targets aren't promoted, and the
+	// npm checks of the tree (typosquats, confusion, age, provenance, static
+	// scan) skip themselves
+	orch := orchestrator.NewOrchestrator(
+		cfg.GitHubToken,
+		cfg.RepoOwner,
+		cfg.RepoName,
+		workflowFile,
+		cfg.Concurrency,
+		time.Duration(cfg.TimeoutMinutes)*time.Minute,
+		nil,
+		baselinePath,
+		cfg.OpenAIAPIKey,
+		nil,
+		nil,
+	)
+	orch.SetLogger(logger)
+	setMode(orch)
+	orch.SetAIProvider(cfg.AIProvider, cfg.AIBaseURL, cfg.AIModel)
+	orch.SetMaxAIBudget(cfg.MaxAIBudget)
+	orch.SetMaxPromptIndicators(cfg.MaxPromptIndicators)
+	orch.SetAnalysisSources(analysisSources)
+	orch.SetAnalysisLanguage(cfg.AnalysisLanguage)
+	orch.SetRulesOnly(cfg.RulesOnly)
+	orch.SetStageConcurrency(cfg.stageConcurrency())
+	orch.SetSkipDiskCheck(cfg.SkipDiskCheck)
+	orch.SetKeepTraces(cfg.KeepTraces)
+	orch.SetWorkflowInputs(workflowInputs)
+	orch.SetRetries(cfg.WorkflowRetries)
+	orch.SetKeepGoing(cfg.KeepGoing)
+	orch.SetQuarantineDir(cfg.QuarantineDir)
+	orch.SetResultsLog(cfg.ResultsLog, "")
+	orch.SetBaselineDrift(cfg.DriftHistory, cfg.DriftThreshold, cfg.DriftRuns)
+	orch.SetTelemetry(cfg.TelemetryURL)
+	orch.SetSIEM(cfg.SIEMURL, siemFormat, cfg.SIEMToken)
+	orch.SetTicketing(ticketFiler)
+	orch.SetProcessKeyMode(keyMode)
+	orch.SetSyscallThreshold(aggregate.SyscallThreshold{Ratio: cfg.SyscallRatio, MinDelta: cfg.SyscallMinDelta, ZScore: cfg.SyscallZScore})
+	orch.SetAccessThreshold(aggregate.AccessThreshold{Ratio: cfg.AccessRatio, MinDelta: cfg.AccessMinDelta})
+	orch.SetMaxLineSize(cfg.MaxLineMB << 20)
+	orch.SetAllowlist(allowlist)
+	orch.SetIntel(enricher)
+
+	results, err := orch.RunPackages(ctx, targets, tempDir, cfg.OutputDir)
+	printAIUsage(orch.AIUsage(), cfg.MaxAIBudget)
+	return results, err
+}
+
+func printImageUsage() {
+	fmt.Println("Usage: spr image [options] <image>...")
+	fmt.Println("")
+	fmt.Println("Analyzes container images, e.g. third-party base images and tools: each image's")
+	fmt.Println("entrypoint runs under Tracee for a bounded time in the image workflow, and its trace")
+	fmt.Println("goes through the same aggregation, rules and AI analysis as npm packages. Images are")
+	fmt.Println("pulled by the workflow; no registry settings are needed, only the GitHub ones.")
+	fmt.Println("Exits non-zero when an image is flagged or fails analysis.")
+	fmt.Println("")
+	fmt.Println("Examples:")
+	fmt.Println("  spr image nginx:1.25")
+	fmt.Println("  spr image -run-seconds 30 -args --version ghcr.io/owner/tool@sha256:<digest>")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -output <dir>          Output directory for artifacts (default: ./analysis-results)")
+	fmt.Println("  -workflow <file>       Image workflow file (default: analyze-image.yml)")
+	fmt.Println("  -baseline <file>       Stats of a reference image subtracted from each trace (default: none,")
+	fmt.Println("                         every traced activity is kept)")
+	fmt.Println("  -run-seconds <n>       How long the entrypoint runs before it is stopped (default: 120)")
+	fmt.Println("  -args <args>           Arguments passed to the entrypoint instead of the image CMD")
+	fmt.Println("  -process-key <mode>    Key processes by name, ancestry or cmdline (default: ancestry)")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/acheong08/hackeurope-spr/internal/advisory"
+	"github.com/acheong08/hackeurope-spr/internal/editor"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// runLSPCommand answers package.json diagnostics requests from editor
+// extensions, one JSON object per line on stdin and stdout, with the
+// verdicts of previous spr check runs and optionally a verdict API
+func runLSPCommand(cfg *Config, args []string) {
+	outputDir := cfg.OutputDir
+	verdictAPI := cfg.VerdictAPI
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-output":
+			if i+1 < len(args) {
+				outputDir = args[i+1]
+				i++
+			}
+		case "-verdict-api":
+			if i+1 < len(args) {
+				verdictAPI = args[i+1]
+				i++
+			}
+		case "-help":
+			printLSPUsage()
+			os.Exit(0)
+		default:
+			fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", args[i])
+			printLSPUsage()
+			os.Exit(1)
+		}
+	}
+
+	lookup := localVerdicts(outputDir)
+	if verdictAPI != "" {
+		lookup = editor.Chain(lookup, editor.RemoteLookup(verdictAPI))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// stdout carries the protocol; anything else goes to stderr
+	if err := editor.Serve(ctx, os.Stdin, os.Stdout, lookup); err != nil && ctx.Err() == nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// localVerdicts looks packages up in the artifacts of spr check runs in
+// outputDir, pointing at their drafted advisory when there is one
+func localVerdicts(outputDir string) editor.Lookup {
+	return func(ctx context.Context, packages []models.Package) (map[string]editor.Verdict, error) {
+		assessments, err := orchestrator.LoadVerdicts(outputDir, packages)
+		if err != nil {
+			return nil, err
+		}
+		verdicts := make(map[string]editor.Verdict, len(assessments))
+		for _, pkg := range packages {
+			key := pkg.Name + "@" + pkg.Version
+			assessment, ok := assessments[key]
+			if !ok {
+				continue
+			}
+			v := editor.Verdict{Verdict: models.VerdictClean}
+			if assessment != nil {
+				v.Verdict, v.Score, v.Summary = models.VerdictSafe, assessment.Confidence, assessment.Justification
+				if assessment.IsMalicious {
+					v.Verdict = models.VerdictMalicious
+				}
+			}
+			path := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version), advisory.MarkdownFile)
+			if _, err := os.Stat(path); err == nil {
+				if abs, err := filepath.Abs(path); err == nil {
+					path = abs
+				}
+				v.Advisory = path
+			}
+			verdicts[key] = v
+		}
+		return verdicts, nil
+	}
+}
+
+func printLSPUsage() {
+	fmt.Println("Usage: spr lsp [options]")
+	fmt.Println("")
+	fmt.Println("Serve package.json diagnostics to editor extensions over stdin/stdout.")
+	fmt.Println("Each request is a JSON object on one line:")
+	fmt.Println("")
+	fmt.Println(`  {"id": 1, "uri": "file:///app/package.json", "text": "<buffer>", "lockfile": "<optional>"}`)
+	fmt.Println("")
+	fmt.Println("and is answered on one line with LSP-style diagnostics for each dependency")
+	fmt.Println("whose version is known (pinned, or installed per the lockfile; without one,")
+	fmt.Println("the package-lock.json next to a file:// URI is read):")
+	fmt.Println("")
+	fmt.Println(`  {"id": 1, "uri": "...", "diagnostics": [{"range": ..., "severity": 1, "code": "malicious",`)
+	fmt.Println(`    "message": "...", "data": {"package": ..., "version": ..., "verdict": ..., "score": ..., "advisory": ...}}]}`)
+	fmt.Println("")
+	fmt.Println("Malicious dependencies are errors, analyzed ones information and unanalyzed ones hints.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -output <dir>          Artifacts of previous spr check runs (default: OUTPUT_DIR)")
+	fmt.Println("  -verdict-api <url>     Also query this spr server's public verdict API (default: VERDICT_API)")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -2,41 +2,145 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/artifacts"
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+	"github.com/acheong08/hackeurope-spr/internal/confusion"
+	"github.com/acheong08/hackeurope-spr/internal/intel"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
 	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
 	"github.com/acheong08/hackeurope-spr/internal/parser"
+	"github.com/acheong08/hackeurope-spr/internal/popularity"
+	"github.com/acheong08/hackeurope-spr/internal/provenance"
 	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/internal/scoring"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/internal/tester"
+	"github.com/acheong08/hackeurope-spr/internal/ticketing"
+	"github.com/acheong08/hackeurope-spr/internal/typosquat"
 	"github.com/acheong08/hackeurope-spr/pkg/models"
 	"github.com/joho/godotenv"
 )
 
 // Config holds all environment/flag configuration for the spr CLI.
 type Config struct {
-	PackageJSONPath string
-	LockfilePath    string
-	OutputDir       string
-	RegistryURL     string
-	RegistryOwner   string
-	RegistryToken   string
-	GitHubToken     string
-	RepoOwner       string
-	RepoName        string
-	WorkflowFile    string
-	Concurrency     int
-	TimeoutMinutes  int
-	BaselinePath    string
-	OpenAIAPIKey    string
+	PackageJSONPath      string
+	LockfilePath         string
+	OutputDir            string
+	RegistryType         string
+	RegistryURL          string
+	RegistryOwner        string
+	RegistryToken        string
+	GitHubToken          string
+	RepoOwner            string
+	RepoName             string
+	WorkflowFile         string
+	ImageWorkflowFile    string
+	ActionWorkflowFile   string
+	Concurrency          int // Workflow runs in flight
+	UploadConcurrency    int
+	AggregateConcurrency int
+	AIConcurrency        int
+	TimeoutMinutes       int
+	PackageTimeouts      string
+	WorkflowRetries      int
+	KeepGoing            bool
+	KeepTraces           bool
+	SkipDiskCheck        bool
+	SkipPreflight        bool   // Don't probe GitHub, the registries and the LLM before uploading
+	BreakerFailures      int    // Consecutive failures tripping a service's circuit breaker — zero never trips
+	BreakerCooldown      int    // Seconds an open circuit breaker fails calls before probing again
+	SkipPopularity       bool   // Don't look up npm download counts for the rules
+	SkipTyposquat        bool   // Don't compare dependency names against popular packages
+	TyposquatNames       string // Extra popular (or accepted) package names, one per line
+	BaselinePath         string
+	ImageBaselinePath    string // Baseline of spr image; empty keeps every traced activity
+	ActionBaselinePath   string // Baseline of spr action; empty keeps every traced activity
+	AllowlistPath        string
+	IntelPath            string // YAML list of GeoIP/ASN and blocklist sources, see intel.Config
+	TrustedPublishers    string
+	ProvenancePolicy     string // What trusted provenance means: skip or record
+	PrivateScopes        string
+	PrivateRegistries    string
+	PrivateRegistryToken string
+	MinPackageAge        string // Go duration or days, e.g. 72h or 3d; empty disables the age gate
+	PackageAgeAction     string // What too-new versions get: block or quarantine
+	OpenAIAPIKey         string
+	AIProvider           string
+	AIBaseURL            string
+	AIModel              string
+	MaxAIBudget          float64
+	MaxPromptIndicators  int // Longest activity list in AI prompts before sampling
+	AnalysisSources      string
+	TraceAPIURL          string
+	TraceAPIToken        string
+	AnalysisLanguage     string
+	InterceptTLS         bool
+	ObserveMinutes       int
+	ClockSkew            string
+	EnvMatrix            string
+	WorkflowInputs       string
+	Tests                string
+	SpoofCI              bool
+	Profile              string
+	Scope                string // Packages analyzed: direct or all
+	Runs                 int
+	RulesOnly            bool
+	MaxRisk              int // Fail check when a package scores above it — zero disables the gate
+	Seed                 int // Sandbox Math.random seed — zero picks one per run
+	QuarantineDir        string
+	ResultsLog           string
+	DriftHistory         string // Baseline effectiveness per run — empty disables drift tracking
+	DriftThreshold       float64
+	DriftRuns            int
+	VerdictAPI           string
+	TelemetryURL         string
+	SIEMURL              string
+	SIEMFormat           string
+	SIEMToken            string
+	AllowNonNpm          bool
+	ProcessKey           string
+	SyscallRatio         float64
+	SyscallMinDelta      int
+	SyscallZScore        float64
+	AccessRatio          float64
+	AccessMinDelta       int
+	MaxLineMB            int // Longest trace line aggregated, in MiB
+	LogFormat            string
 
 	// Safe registry — packages are promoted here after passing AI analysis.
 	// Leave SAFE_REGISTRY_TOKEN empty to disable promotion.
+	SafeRegistryType  string
 	SafeRegistryURL   string
 	SafeRegistryToken string
 	SafeRegistryOwner string
+
+	// Artifact export to S3-compatible storage.
+	// Leave ARTIFACT_S3_BUCKET empty to disable it.
+	ArtifactS3        artifacts.S3Config
+	ArtifactKeepLocal bool
+
+	// A ticket per flagged package in GitHub Issues or Jira.
+	// Leave TICKETS empty to disable it.
+	Tickets ticketing.Config
+
+	// Set by spr reproduce: the recorded run being repeated, whose inputs
+	// are checked against this run's
+	reproducing *orchestrator.Reproduction
+	allowDrift  bool
 }
 
 func loadConfig() *Config {
@@ -44,23 +148,192 @@ func loadConfig() *Config {
 	_ = godotenv.Load()
 
 	return &Config{
-		OutputDir:      getEnv("OUTPUT_DIR", "./analysis-results"),
-		RegistryURL:    getEnv("REGISTRY_URL", "https://git.duti.dev"),
-		RegistryOwner:  getEnv("REGISTRY_OWNER", "acheong08"),
-		RegistryToken:  getEnv("REGISTRY_TOKEN", ""),
-		GitHubToken:    getEnv("GITHUB_TOKEN", ""),
-		RepoOwner:      getEnv("REPO_OWNER", "acheong08"),
-		RepoName:       getEnv("REPO_NAME", "hackeurope-spr"),
-		WorkflowFile:   getEnv("WORKFLOW_FILE", "analyze-package.yml"),
-		Concurrency:    getEnvInt("CONCURRENCY", 5),
-		TimeoutMinutes: getEnvInt("TIMEOUT_MINUTES", 5),
-		BaselinePath:   getEnv("BASELINE_PATH", "safe-sample.json"),
-		OpenAIAPIKey:   getEnv("OPENAI_API_KEY", ""),
+		OutputDir:            getEnv("OUTPUT_DIR", "./analysis-results"),
+		RegistryType:         getEnv("REGISTRY_TYPE", "gitea"),
+		RegistryURL:          getEnv("REGISTRY_URL", "https://git.duti.dev"),
+		RegistryOwner:        getEnv("REGISTRY_OWNER", "acheong08"),
+		RegistryToken:        getEnv("REGISTRY_TOKEN", ""),
+		GitHubToken:          getEnv("GITHUB_TOKEN", ""),
+		RepoOwner:            getEnv("REPO_OWNER", "acheong08"),
+		RepoName:             getEnv("REPO_NAME", "hackeurope-spr"),
+		WorkflowFile:         getEnv("WORKFLOW_FILE", "analyze-package.yml"),
+		ImageWorkflowFile:    getEnv("IMAGE_WORKFLOW_FILE", orchestrator.DefaultImageWorkflowFile),
+		ActionWorkflowFile:   getEnv("ACTION_WORKFLOW_FILE", orchestrator.DefaultActionWorkflowFile),
+		Concurrency:          getEnvInt("WORKFLOW_CONCURRENCY", getEnvInt("CONCURRENCY", orchestrator.DefaultStageConcurrency.Workflow)),
+		UploadConcurrency:    getEnvInt("UPLOAD_CONCURRENCY", orchestrator.DefaultStageConcurrency.Upload),
+		AggregateConcurrency: getEnvInt("AGGREGATE_CONCURRENCY", orchestrator.DefaultStageConcurrency.Aggregate),
+		AIConcurrency:        getEnvInt("AI_CONCURRENCY", orchestrator.DefaultStageConcurrency.AI),
+		TimeoutMinutes:       getEnvInt("TIMEOUT_MINUTES", 5),
+		PackageTimeouts:      getEnv("PACKAGE_TIMEOUTS", ""),
+		WorkflowRetries:      getEnvInt("WORKFLOW_RETRIES", 1),
+		KeepGoing:            getEnvBool("KEEP_GOING", false),
+		KeepTraces:           getEnvBool("KEEP_TRACES", false),
+		SkipDiskCheck:        getEnvBool("SKIP_DISK_CHECK", false),
+		SkipPreflight:        getEnvBool("SKIP_PREFLIGHT", false),
+		BreakerFailures:      getEnvInt("BREAKER_FAILURES", breaker.DefaultFailures),
+		BreakerCooldown:      getEnvInt("BREAKER_COOLDOWN_SECONDS", int(breaker.DefaultCooldown/time.Second)),
+		SkipPopularity:       getEnvBool("SKIP_POPULARITY", false),
+		SkipTyposquat:        getEnvBool("SKIP_TYPOSQUAT", false),
+		TyposquatNames:       getEnv("TYPOSQUAT_NAMES", ""),
+		BaselinePath:         getEnv("BASELINE_PATH", "safe-sample.json"),
+		ImageBaselinePath:    getEnv("IMAGE_BASELINE_PATH", ""),
+		ActionBaselinePath:   getEnv("ACTION_BASELINE_PATH", ""),
+		AllowlistPath:        getEnv("ALLOWLIST", ""),
+		IntelPath:            getEnv("INTEL", ""),
+		TrustedPublishers:    getEnv("TRUSTED_PUBLISHERS", ""),
+		ProvenancePolicy:     getEnv("PROVENANCE_POLICY", "skip"),
+		PrivateScopes:        getEnv("PRIVATE_SCOPES", ""),
+		PrivateRegistries:    getEnv("PRIVATE_REGISTRIES", ""),
+		PrivateRegistryToken: getEnv("PRIVATE_REGISTRY_TOKEN", ""),
+		MinPackageAge:        getEnv("MIN_PACKAGE_AGE", ""),
+		PackageAgeAction:     getEnv("PACKAGE_AGE_ACTION", orchestrator.AgeBlock),
+		OpenAIAPIKey:         getEnv("AI_API_KEY", getEnv("OPENAI_API_KEY", "")),
+		AIProvider:           getEnv("AI_PROVIDER", analysis.DefaultProvider),
+		AIBaseURL:            getEnv("AI_BASE_URL", ""),
+		AIModel:              getEnv("AI_MODEL", ""),
+		MaxAIBudget:          getEnvFloat("MAX_AI_BUDGET", 0),
+		MaxPromptIndicators:  getEnvInt("MAX_PROMPT_INDICATORS", analysis.DefaultMaxIndicators),
+		AnalysisSources:      getEnv("ANALYSIS_SOURCES", analysis.DefaultSources),
+		TraceAPIURL:          getEnv("TRACE_API_BASE", ""),
+		TraceAPIToken:        getEnv("TRACE_API_TOKEN", ""),
+		AnalysisLanguage:     getEnv("ANALYSIS_LANGUAGE", ""),
+		InterceptTLS:         getEnvBool("INTERCEPT_TLS", false),
+		ObserveMinutes:       getEnvInt("OBSERVE_MINUTES", 0),
+		ClockSkew:            getEnv("CLOCK_SKEW", "+30d x10"),
+		EnvMatrix:            getEnv("ENV_MATRIX", ""),
+		WorkflowInputs:       getEnv("WORKFLOW_INPUTS", ""),
+		Tests:                getEnv("TESTS", ""),
+		SpoofCI:              getEnvBool("SPOOF_CI", false),
+		Profile:              getEnv("PROFILE", ""),
+		Scope:                getEnv("SCOPE", "direct"),
+		Runs:                 getEnvInt("RUNS", 1),
+		RulesOnly:            getEnvBool("RULES_ONLY", false),
+		MaxRisk:              getEnvInt("MAX_RISK", 0),
+		Seed:                 getEnvInt("SEED", 0),
+		QuarantineDir:        getEnv("QUARANTINE_DIR", "./quarantine"),
+		ResultsLog:           getEnv("RESULTS_LOG", ""),
+		DriftHistory:         getEnv("BASELINE_HISTORY", ""),
+		DriftThreshold:       getEnvFloat("BASELINE_DRIFT_THRESHOLD", orchestrator.DefaultDriftThreshold),
+		DriftRuns:            getEnvInt("BASELINE_DRIFT_RUNS", orchestrator.DefaultDriftRuns),
+		VerdictAPI:           getEnv("VERDICT_API", ""),
+		TelemetryURL:         getEnv("TELEMETRY_URL", ""),
+		SIEMURL:              getEnv("SIEM_URL", ""),
+		SIEMFormat:           getEnv("SIEM_FORMAT", "cef"),
+		SIEMToken:            getEnv("SIEM_TOKEN", ""),
+		ProcessKey:           getEnv("PROCESS_KEY", "ancestry"),
+		SyscallRatio:         getEnvFloat("SYSCALL_RATIO", aggregate.DefaultSyscallThreshold.Ratio),
+		SyscallMinDelta:      getEnvInt("SYSCALL_MIN_DELTA", aggregate.DefaultSyscallThreshold.MinDelta),
+		SyscallZScore:        getEnvFloat("SYSCALL_ZSCORE", aggregate.DefaultSyscallThreshold.ZScore),
+		AccessRatio:          getEnvFloat("ACCESS_RATIO", aggregate.DefaultAccessThreshold.Ratio),
+		AccessMinDelta:       getEnvInt("ACCESS_MIN_DELTA", aggregate.DefaultAccessThreshold.MinDelta),
+		MaxLineMB:            getEnvInt("MAX_LINE_MB", aggregate.DefaultMaxLineSize>>20),
+		LogFormat:            getEnv("LOG_FORMAT", "text"),
 
+		SafeRegistryType:  getEnv("SAFE_REGISTRY_TYPE", getEnv("REGISTRY_TYPE", "gitea")),
 		SafeRegistryURL:   getEnv("SAFE_REGISTRY_URL", "https://git.duti.dev"),
 		SafeRegistryToken: getEnv("SAFE_REGISTRY_TOKEN", ""),
 		SafeRegistryOwner: getEnv("SAFE_REGISTRY_OWNER", "secure"),
+
+		ArtifactS3: artifacts.S3Config{
+			Endpoint:  getEnv("ARTIFACT_S3_ENDPOINT", ""),
+			Region:    getEnv("ARTIFACT_S3_REGION", "us-east-1"),
+			Bucket:    getEnv("ARTIFACT_S3_BUCKET", ""),
+			Prefix:    getEnv("ARTIFACT_S3_PREFIX", ""),
+			AccessKey: getEnv("ARTIFACT_S3_ACCESS_KEY", ""),
+			SecretKey: getEnv("ARTIFACT_S3_SECRET_KEY", ""),
+			PathStyle: getEnvBool("ARTIFACT_S3_PATH_STYLE", true),
+		},
+		ArtifactKeepLocal: getEnvBool("ARTIFACT_KEEP_LOCAL", true),
+
+		Tickets: ticketing.Config{
+			Kind:          getEnv("TICKETS", ""),
+			Labels:        ticketing.ParseLabels(getEnv("TICKET_LABELS", "security,malicious-package")),
+			TemplatePath:  getEnv("TICKET_TEMPLATE", ""),
+			GitHubRepo:    getEnv("TICKET_REPO", ""),
+			GitHubToken:   getEnv("TICKET_GITHUB_TOKEN", getEnv("GITHUB_TOKEN", "")),
+			JiraURL:       getEnv("JIRA_URL", ""),
+			JiraUser:      getEnv("JIRA_USER", ""),
+			JiraToken:     getEnv("JIRA_TOKEN", ""),
+			JiraProject:   getEnv("JIRA_PROJECT", ""),
+			JiraIssueType: getEnv("JIRA_ISSUE_TYPE", "Bug"),
+		},
+	}
+}
+
+// stageConcurrency returns the per-stage concurrency limits
+func (c *Config) stageConcurrency() orchestrator.StageConcurrency {
+	return orchestrator.StageConcurrency{Upload: c.UploadConcurrency, Workflow: c.Concurrency, Aggregate: c.AggregateConcurrency, AI: c.AIConcurrency}
+}
+
+// allowlist loads the diff allowlist, if one is configured
+func (c *Config) allowlist() (*aggregate.Allowlist, error) {
+	if c.AllowlistPath == "" {
+		return nil, nil
+	}
+	return aggregate.LoadAllowlist(c.AllowlistPath)
+}
+
+// intel loads the threat intel sources, if any are configured
+func (c *Config) intel() (*intel.Enricher, error) {
+	if c.IntelPath == "" {
+		return nil, nil
+	}
+	return intel.Load(c.IntelPath)
+}
+
+// typosquatChecker builds the typosquatting check, nil when it is skipped
+func (c *Config) typosquatChecker() (*typosquat.Checker, error) {
+	if c.SkipTyposquat {
+		return nil, nil
 	}
+	return typosquat.Load(c.TyposquatNames)
+}
+
+// trustedPublishers parses the trusted publishers, and whether packages they
+// built skip dynamic analysis or are only recorded as such
+func (c *Config) trustedPublishers() (*provenance.Policy, bool, error) {
+	policy, err := provenance.ParsePolicy(c.TrustedPublishers)
+	if err != nil {
+		return nil, false, err
+	}
+	switch c.ProvenancePolicy {
+	case "skip", "":
+		return policy, true, nil
+	case "record":
+		return policy, false, nil
+	}
+	return nil, false, fmt.Errorf("provenance policy %q: want skip or record", c.ProvenancePolicy)
+}
+
+// confusionChecker builds the dependency confusion check from the private
+// scopes and registries configured here and in the project's .npmrc. It is
+// disabled (nil) when neither configures any.
+func (c *Config) confusionChecker(projectDir string) (*confusion.Checker, error) {
+	checker := confusion.NewChecker("https://registry.npmjs.org", strings.Split(c.PrivateScopes, ","), strings.Split(c.PrivateRegistries, ","), c.PrivateRegistryToken)
+	rc, err := confusion.LoadNpmrc(filepath.Join(projectDir, ".npmrc"))
+	if err != nil {
+		return nil, err
+	}
+	checker.UseNpmrc(rc)
+	if !checker.Enabled() {
+		return nil, nil
+	}
+	return checker, nil
+}
+
+// ticketFiler builds the ticket filer, if ticketing is configured. GitHub
+// issues go to the workflow repository unless TICKET_REPO says otherwise.
+func (c *Config) ticketFiler() (*ticketing.Filer, error) {
+	tickets := c.Tickets
+	if tickets.GitHubRepo == "" {
+		tickets.GitHubRepo = c.RepoOwner + "/" + c.RepoName
+	}
+	return tickets.Filer()
+}
+
+// aiProvider returns the AI provider configuration for the analyzer
+func (c *Config) aiProvider() analysis.ProviderConfig {
+	return analysis.ProviderConfig{Provider: c.AIProvider, APIKey: c.OpenAIAPIKey, BaseURL: c.AIBaseURL, Model: c.AIModel}
 }
 
 func getEnv(key, defaultValue string) string {
@@ -79,6 +352,24 @@ func getEnvInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if v := os.Getenv(key); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if v := os.Getenv(key); v != "" {
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 func main() {
 	// Check for subcommands
 	if len(os.Args) < 2 {
@@ -86,7 +377,13 @@ func main() {
 		os.Exit(1)
 	}
 
+	if os.Args[1] == "version" || os.Args[1] == "-version" {
+		runVersionCommand(os.Args[2:])
+		return
+	}
+
 	cfg := loadConfig()
+	breaker.SetDefaults(cfg.BreakerFailures, time.Duration(cfg.BreakerCooldown)*time.Second)
 	subcommand := os.Args[1]
 
 	switch subcommand {
@@ -94,6 +391,28 @@ func main() {
 		runCheckCommand(cfg, os.Args[2:])
 	case "test":
 		runTestCommand(os.Args[2:])
+	case "sbom":
+		runSBOMCommand(cfg, os.Args[2:])
+	case "diff":
+		runDiffCommand(cfg, os.Args[2:])
+	case "watch":
+		runWatchCommand(cfg, os.Args[2:])
+	case "prepublish":
+		runPrepublishCommand(cfg, os.Args[2:])
+	case "fix":
+		runFixCommand(cfg, os.Args[2:])
+	case "verify-log":
+		runVerifyLogCommand(cfg, os.Args[2:])
+	case "events":
+		runEventsCommand(cfg, os.Args[2:])
+	case "image":
+		runImageCommand(cfg, os.Args[2:])
+	case "action":
+		runActionCommand(cfg, os.Args[2:])
+	case "lsp":
+		runLSPCommand(cfg, os.Args[2:])
+	case "reproduce":
+		runReproduceCommand(cfg, os.Args[2:])
 	default:
 		fmt.Fprintf(os.Stderr, "Unknown command: %s\n\n", subcommand)
 		printUsage()
@@ -107,9 +426,32 @@ func printUsage() {
 	fmt.Println("Usage:")
 	fmt.Println("  spr check [options]     Analyze package.json, upload to registry, trigger workflows")
 	fmt.Println("  spr test <command>      Generate test packages for behavioral analysis")
+	fmt.Println("  spr sbom [options]      Export the dependency graph as a CycloneDX or SPDX SBOM")
+	fmt.Println("  spr diff <old> <new>    Compare the behavior of two package versions")
+	fmt.Println("  spr watch [options]     Re-analyze added or upgraded dependencies as package.json changes")
+	fmt.Println("  spr prepublish          Block npm publish when the package or its new dependencies are flagged")
+	fmt.Println("  spr fix [options]       Pin flagged packages to clean versions via package.json overrides")
+	fmt.Println("  spr verify-log [path]   Verify the hash chain of a results log")
+	fmt.Println("  spr events <trace>      Print the raw events of a process or event type from behavior.jsonl")
+	fmt.Println("  spr image <image>...    Analyze container images by running their entrypoint under trace")
+	fmt.Println("  spr action <action>...  Analyze GitHub Actions by running them with canary secrets")
+	fmt.Println("  spr lsp [options]       Serve package.json diagnostics to editor extensions over stdin/stdout")
+	fmt.Println("  spr reproduce <file>    Repeat a check run from its reproducibility manifest (reproduce.json)")
+	fmt.Println("  spr version [-json]     Print build info (commit, build date, component versions)")
 	fmt.Println("")
 	fmt.Println("Commands:")
 	fmt.Println("  check                   Full analysis pipeline")
+	fmt.Println("  sbom                    CycloneDX/SPDX SBOM with analysis verdicts")
+	fmt.Println("  diff                    New files, domains and commands of an update, e.g. left-pad@1.2.0 left-pad@1.3.0")
+	fmt.Println("  watch                   Incremental verdicts while editing dependencies")
+	fmt.Println("  prepublish              Pre-publish gate for package authors (run from prepublishOnly)")
+	fmt.Println("  fix                     Write overrides/resolutions for flagged packages, optionally as a pull request")
+	fmt.Println("  verify-log              Detect tampering with the verdicts recorded by -results-log")
+	fmt.Println("  events                  Drill down from a diff to the trace events behind it, via the trace index")
+	fmt.Println("  image                   Vet third-party base images and tools, e.g. nginx:1.25 or ghcr.io/owner/tool@sha256:…")
+	fmt.Println("  action                  Vet marketplace actions before using them, e.g. actions/setup-node@v4")
+	fmt.Println("  lsp                     Verdict, score and advisory of each dependency line, for inline rendering")
+	fmt.Println("  reproduce               Same settings, seed and checked inputs as a recorded run")
 	fmt.Println("  test generate           Generate test packages for a specific dependency")
 	fmt.Println("  test list               List all generated test packages")
 	fmt.Println("")
@@ -120,6 +462,16 @@ func runCheckCommand(cfg *Config, args []string) {
 	// Flag values start from config (env / .env defaults); CLI flags override.
 	packageJSONPath := cfg.PackageJSONPath
 	lockfilePath := cfg.LockfilePath
+	localDir := ""
+	fresh := false
+	offline := false
+	jsonPath := "" // Machine-readable summary; "-" for stdout
+
+	// A profile presets several settings at once; the flags below override it
+	if err := cfg.applyProfile(profileArg(args, cfg.Profile)); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid -profile: %v\n", err)
+		os.Exit(1)
+	}
 
 	// Parse flags manually (single dash); flags override env/config.
 	for i := 0; i < len(args); i++ {
@@ -134,11 +486,21 @@ func runCheckCommand(cfg *Config, args []string) {
 				lockfilePath = args[i+1]
 				i++
 			}
+		case "-local":
+			if i+1 < len(args) {
+				localDir = args[i+1]
+				i++
+			}
 		case "-output":
 			if i+1 < len(args) {
 				cfg.OutputDir = args[i+1]
 				i++
 			}
+		case "-registry-type":
+			if i+1 < len(args) {
+				cfg.RegistryType = args[i+1]
+				i++
+			}
 		case "-registry-url":
 			if i+1 < len(args) {
 				cfg.RegistryURL = args[i+1]
@@ -181,6 +543,27 @@ func runCheckCommand(cfg *Config, args []string) {
 				}
 				i++
 			}
+		case "-upload-concurrency":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.UploadConcurrency = n
+				}
+				i++
+			}
+		case "-aggregate-concurrency":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.AggregateConcurrency = n
+				}
+				i++
+			}
+		case "-ai-concurrency":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.AIConcurrency = n
+				}
+				i++
+			}
 		case "-timeout":
 			if i+1 < len(args) {
 				if n, err := strconv.Atoi(args[i+1]); err == nil {
@@ -188,124 +571,492 @@ func runCheckCommand(cfg *Config, args []string) {
 				}
 				i++
 			}
+		case "-package-timeouts":
+			if i+1 < len(args) {
+				cfg.PackageTimeouts = args[i+1]
+				i++
+			}
+		case "-retries":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.WorkflowRetries = n
+				}
+				i++
+			}
 		case "-baseline":
 			if i+1 < len(args) {
 				cfg.BaselinePath = args[i+1]
 				i++
 			}
+		case "-allowlist":
+			if i+1 < len(args) {
+				cfg.AllowlistPath = args[i+1]
+				i++
+			}
+		case "-intel":
+			if i+1 < len(args) {
+				cfg.IntelPath = args[i+1]
+				i++
+			}
+		case "-trusted-publishers":
+			if i+1 < len(args) {
+				cfg.TrustedPublishers = args[i+1]
+				i++
+			}
+		case "-provenance-policy":
+			if i+1 < len(args) {
+				cfg.ProvenancePolicy = args[i+1]
+				i++
+			}
+		case "-private-scopes":
+			if i+1 < len(args) {
+				cfg.PrivateScopes = args[i+1]
+				i++
+			}
+		case "-private-registries":
+			if i+1 < len(args) {
+				cfg.PrivateRegistries = args[i+1]
+				i++
+			}
+		case "-min-package-age":
+			if i+1 < len(args) {
+				cfg.MinPackageAge = args[i+1]
+				i++
+			}
+		case "-package-age-action":
+			if i+1 < len(args) {
+				cfg.PackageAgeAction = args[i+1]
+				i++
+			}
+		case "-ai-provider":
+			if i+1 < len(args) {
+				cfg.AIProvider = args[i+1]
+				i++
+			}
+		case "-ai-base-url":
+			if i+1 < len(args) {
+				cfg.AIBaseURL = args[i+1]
+				i++
+			}
+		case "-ai-model":
+			if i+1 < len(args) {
+				cfg.AIModel = args[i+1]
+				i++
+			}
+		case "-max-ai-budget":
+			if i+1 < len(args) {
+				if f, err := strconv.ParseFloat(args[i+1], 64); err == nil {
+					cfg.MaxAIBudget = f
+				}
+				i++
+			}
+		case "-max-prompt-indicators":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil && n > 0 {
+					cfg.MaxPromptIndicators = n
+				}
+				i++
+			}
+		case "-analysis-sources":
+			if i+1 < len(args) {
+				cfg.AnalysisSources = args[i+1]
+				i++
+			}
+		case "-language":
+			if i+1 < len(args) {
+				cfg.AnalysisLanguage = args[i+1]
+				i++
+			}
+		case "-trace-api":
+			if i+1 < len(args) {
+				cfg.TraceAPIURL = args[i+1]
+				i++
+			}
+		case "-spoof-ci":
+			cfg.SpoofCI = true
+		case "-profile":
+			// Already applied
+			i++
+		case "-scope":
+			if i+1 < len(args) {
+				cfg.Scope = args[i+1]
+				i++
+			}
+		case "-runs":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.Runs = n
+				}
+				i++
+			}
+		case "-rules-only":
+			cfg.RulesOnly = true
+		case "-max-risk":
+			if i+1 < len(args) {
+				n, err := strconv.Atoi(args[i+1])
+				if err != nil || n < 0 || n > 100 {
+					fmt.Fprintf(os.Stderr, "Error: invalid -max-risk: %q, expected 0-100\n", args[i+1])
+					os.Exit(1)
+				}
+				cfg.MaxRisk = n
+				i++
+			}
+		case "-seed":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.Seed = n
+				}
+				i++
+			}
+		case "-fresh":
+			fresh = true
+		case "-keep-going":
+			cfg.KeepGoing = true
+		case "-keep-traces":
+			cfg.KeepTraces = true
+		case "-skip-disk-check":
+			cfg.SkipDiskCheck = true
+		case "-skip-preflight":
+			cfg.SkipPreflight = true
+		case "-skip-popularity":
+			cfg.SkipPopularity = true
+		case "-skip-typosquat":
+			cfg.SkipTyposquat = true
+		case "-typosquat-names":
+			if i+1 < len(args) {
+				cfg.TyposquatNames = args[i+1]
+				i++
+			}
+		case "-offline":
+			offline = true
+		case "-json":
+			if i+1 >= len(args) {
+				fmt.Fprintln(os.Stderr, "Error: -json requires a file, or - for stdout")
+				os.Exit(1)
+			}
+			jsonPath = args[i+1]
+			i++
+		case "-intercept-tls":
+			cfg.InterceptTLS = true
+		case "-allow-non-npm":
+			cfg.AllowNonNpm = true
+		case "-observe-minutes":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.ObserveMinutes = n
+				}
+				i++
+			}
+		case "-clock-skew":
+			if i+1 < len(args) {
+				cfg.ClockSkew = args[i+1]
+				i++
+			}
+		case "-env-matrix":
+			if i+1 < len(args) {
+				cfg.EnvMatrix = args[i+1]
+				i++
+			}
+		case "-input":
+			if i+1 < len(args) {
+				if cfg.WorkflowInputs != "" {
+					cfg.WorkflowInputs += ","
+				}
+				cfg.WorkflowInputs += args[i+1]
+				i++
+			}
+		case "-tests":
+			if i+1 < len(args) {
+				cfg.Tests = args[i+1]
+				i++
+			}
+		case "-process-key":
+			if i+1 < len(args) {
+				cfg.ProcessKey = args[i+1]
+				i++
+			}
+		case "-syscall-ratio":
+			if i+1 < len(args) {
+				if f, err := strconv.ParseFloat(args[i+1], 64); err == nil {
+					cfg.SyscallRatio = f
+				}
+				i++
+			}
+		case "-syscall-min-delta":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.SyscallMinDelta = n
+				}
+				i++
+			}
+		case "-syscall-zscore":
+			if i+1 < len(args) {
+				if f, err := strconv.ParseFloat(args[i+1], 64); err == nil {
+					cfg.SyscallZScore = f
+				}
+				i++
+			}
+		case "-access-ratio":
+			if i+1 < len(args) {
+				if f, err := strconv.ParseFloat(args[i+1], 64); err == nil {
+					cfg.AccessRatio = f
+				}
+				i++
+			}
+		case "-access-min-delta":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.AccessMinDelta = n
+				}
+				i++
+			}
+		case "-max-line-mb":
+			if i+1 < len(args) {
+				if n, err := strconv.Atoi(args[i+1]); err == nil {
+					cfg.MaxLineMB = n
+				}
+				i++
+			}
+		case "-artifact-bucket":
+			if i+1 < len(args) {
+				cfg.ArtifactS3.Bucket = args[i+1]
+				i++
+			}
+		case "-artifact-remote-only":
+			cfg.ArtifactKeepLocal = false
+		case "-quarantine":
+			if i+1 < len(args) {
+				cfg.QuarantineDir = args[i+1]
+				i++
+			}
+		case "-results-log":
+			if i+1 < len(args) {
+				cfg.ResultsLog = args[i+1]
+				i++
+			}
+		case "-telemetry":
+			if i+1 < len(args) {
+				cfg.TelemetryURL = args[i+1]
+				i++
+			}
+		case "-siem-url":
+			if i+1 < len(args) {
+				cfg.SIEMURL = args[i+1]
+				i++
+			}
+		case "-siem-format":
+			if i+1 < len(args) {
+				cfg.SIEMFormat = args[i+1]
+				i++
+			}
+		case "-tickets":
+			if i+1 < len(args) {
+				cfg.Tickets.Kind = args[i+1]
+				i++
+			}
+		case "-log-format":
+			if i+1 < len(args) {
+				cfg.LogFormat = args[i+1]
+				i++
+			}
 		case "-help":
 			printCheckUsage()
 			os.Exit(0)
 		}
 	}
 
-	// Validate required tokens early
-	if cfg.RegistryToken == "" {
-		fmt.Fprintln(os.Stderr, "Error: -registry-token is required (or set REGISTRY_TOKEN in environment / .env)")
-		printCheckUsage()
-		os.Exit(1)
-	}
-
-	if cfg.GitHubToken == "" {
-		fmt.Fprintln(os.Stderr, "Error: -github-token is required (or set GITHUB_TOKEN in environment / .env)")
-		printCheckUsage()
-		os.Exit(1)
+	// A summary on stdout moves the human-readable output to stderr, so CI
+	// steps can pipe stdout straight into a JSON parser
+	summaryOut := os.Stdout
+	if jsonPath == "-" {
+		os.Stdout = os.Stderr
 	}
 
-	// Need either package.json or lockfile
-	if packageJSONPath == "" && lockfilePath == "" {
-		// Auto-detect in current directory
-		cwd, err := os.Getwd()
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error getting current directory: %v\n", err)
-			os.Exit(1)
-		}
+	logging.Setup(cfg.LogFormat)
+	runID := logging.NewRunID()
+	runLogger := slog.Default().With(logging.KeyRunID, runID)
 
-		// Try package-lock.json first, then package.json
-		if _, err := os.Stat(filepath.Join(cwd, "package-lock.json")); err == nil {
-			lockfilePath = filepath.Join(cwd, "package-lock.json")
-		} else {
-			path, err := parser.FindPackageJSON(cwd)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-				os.Exit(1)
+	// Runs that stop before analysis still write a failed summary with -json,
+	// so CI parsing it gets a document instead of empty input
+	exit := func(message string) {
+		if jsonPath != "" {
+			summary := orchestrator.NewSummary(cfg.OutputDir)
+			summary.RunID = runID
+			summary.MaxRisk = cfg.MaxRisk
+			summary.Passed = false
+			summary.Error = message
+			if err := writeJSONSummary(jsonPath, summaryOut, summary); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON summary: %v\n", err)
 			}
-			packageJSONPath = path
 		}
+		os.Exit(1)
+	}
+	fail := func(format string, args ...any) {
+		message := fmt.Sprintf(format, args...)
+		fmt.Fprintf(os.Stderr, "Error: %s\n", message)
+		exit(message)
 	}
 
-	var pkgJSON *parser.PackageJSON
-	var graph *models.DependencyGraph
+	if offline && localDir != "" {
+		fail("-offline cannot be combined with -local (local packages are never cached)")
+	}
 
-	if lockfilePath != "" {
-		// Using lockfile directly
-		fmt.Printf("Using lockfile: %s\n", lockfilePath)
+	// Validate required tokens early; offline runs touch neither registry nor GitHub
+	if cfg.RegistryToken == "" && !offline {
+		fmt.Fprintln(os.Stderr, "Error: -registry-token is required (or set REGISTRY_TOKEN in environment / .env)")
+		printCheckUsage()
+		exit("-registry-token is required")
+	}
 
-		// Extract root package from lockfile
-		lm := parser.NewLockfileManager()
-		rootPackage, err := lm.ExtractRootPackage(lockfilePath)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error extracting root from lockfile: %v\n", err)
-			os.Exit(1)
-		}
+	if cfg.GitHubToken == "" && !offline {
+		fmt.Fprintln(os.Stderr, "Error: -github-token is required (or set GITHUB_TOKEN in environment / .env)")
+		printCheckUsage()
+		exit("-github-token is required")
+	}
 
-		// Parse lockfile to get full graph
-		graph, err = lm.ParseLockfile(lockfilePath, rootPackage)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing lockfile: %v\n", err)
-			os.Exit(1)
-		}
+	envSpec := cfg.EnvMatrix
+	if cfg.SpoofCI {
+		// Compare runs with and without a CI-like environment
+		envSpec += ";ci"
+	}
+	envMatrix, err := orchestrator.ParseEnvMatrix(envSpec)
+	if err != nil {
+		fail("invalid -env-matrix: %v", err)
+	}
+	workflowInputs, err := orchestrator.ParseWorkflowInputs(cfg.WorkflowInputs)
+	if err != nil {
+		fail("invalid -input: %v", err)
+	}
+	if cfg.Runs < 1 {
+		fail("invalid -runs: %d (at least 1)", cfg.Runs)
+	}
+	envMatrix = append(envMatrix, orchestrator.RepeatRuns(cfg.Runs)...)
+	if cfg.Seed < 0 || cfg.Seed > math.MaxInt32 {
+		fail("invalid -seed: %d (want 1 to %d, or 0 for a random one)", cfg.Seed, math.MaxInt32)
+	}
+	if cfg.Scope != "direct" && cfg.Scope != "all" {
+		fail("invalid -scope: %q (want direct or all)", cfg.Scope)
+	}
+	if err := cfg.validateProfile(); err != nil {
+		fail("%v", err)
+	}
+	if err := orchestrator.ValidateObservation(cfg.ObserveMinutes, cfg.ClockSkew); err != nil {
+		fail("invalid -observe-minutes or -clock-skew: %v", err)
+	}
+	tests, err := tester.ParseTests(cfg.Tests)
+	if err != nil {
+		fail("invalid -tests: %v", err)
+	}
+	siemFormat, err := siem.ParseFormat(cfg.SIEMFormat)
+	if err != nil {
+		fail("invalid -siem-format: %v", err)
+	}
+	allowlist, err := cfg.allowlist()
+	if err != nil {
+		fail("invalid -allowlist: %v", err)
+	}
+	enricher, err := cfg.intel()
+	if err != nil {
+		fail("invalid -intel: %v", err)
+	}
+	ticketFiler, err := cfg.ticketFiler()
+	if err != nil {
+		fail("invalid -tickets: %v", err)
+	}
+	packageTimeouts, err := orchestrator.ParsePackageTimeouts(cfg.PackageTimeouts)
+	if err != nil {
+		fail("invalid -package-timeouts: %v", err)
+	}
+	trustedPublishers, skipTrusted, err := cfg.trustedPublishers()
+	if err != nil {
+		fail("invalid -trusted-publishers: %v", err)
+	}
+	agePolicy, err := orchestrator.ParseAgePolicy(cfg.MinPackageAge, cfg.PackageAgeAction)
+	if err != nil {
+		fail("invalid -min-package-age: %v", err)
+	}
 
-		// Create a synthetic pkgJSON for display purposes
-		pkgJSON = &parser.PackageJSON{
-			Name:    "package",
-			Version: rootPackage.Version,
-		}
-	} else {
-		// Using package.json
-		// Validate package.json
-		if err := parser.ValidatePackageJSON(packageJSONPath); err != nil {
-			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
-			os.Exit(1)
+	if ai := cfg.aiProvider(); ai.Enabled() {
+		if _, err := ai.Resolve(); err != nil {
+			fail("invalid AI provider: %v", err)
 		}
+	}
+	if err := cfg.stageConcurrency().Validate(); err != nil {
+		fail("%v", err)
+	}
+	analysisSources, err := analysis.ParseSources(cfg.AnalysisSources, cfg.TraceAPIURL, cfg.TraceAPIToken)
+	if err != nil {
+		fail("invalid -analysis-sources: %v", err)
+	}
 
-		// Parse package.json
-		var err error
-		pkgJSON, err = parser.ParsePackageJSON(packageJSONPath)
+	var artifactSink artifacts.Sink
+	if cfg.ArtifactS3.Bucket != "" {
+		sink, err := artifacts.NewS3(cfg.ArtifactS3)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "Error parsing package.json: %v\n", err)
-			os.Exit(1)
+			fail("invalid artifact storage: %v", err)
 		}
+		artifactSink = sink
+	}
 
-		// Build dependency graph
-		if lockfilePath != "" {
-			// Use provided lockfile
-			lm := parser.NewLockfileManager()
-			graph, err = lm.ParseLockfile(lockfilePath, pkgJSON.ToPackage())
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error parsing lockfile: %v\n", err)
-				os.Exit(1)
-			}
-		} else {
-			// Generate and parse lockfile
-			fmt.Println("Generating lockfile...")
-			graph, err = parser.BuildGraphFromPackageJSON(packageJSONPath)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error building dependency graph: %v\n", err)
-				os.Exit(1)
-			}
-		}
+	registryType, err := registry.ParseType(cfg.RegistryType)
+	if err != nil {
+		fail("invalid -registry-type: %v", err)
+	}
+	safeRegistryType, err := registry.ParseType(cfg.SafeRegistryType)
+	if err != nil {
+		fail("invalid SAFE_REGISTRY_TYPE: %v", err)
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		fail("invalid -process-key: %v", err)
+	}
+
+	if localDir != "" {
+		// The local package is the root; its dependencies come from its own
+		// package.json (and package-lock.json, if present)
+		packageJSONPath = filepath.Join(localDir, "package.json")
+		lockfilePath = ""
+	}
+
+	pkgJSON, graph, err := loadDependencyGraph(packageJSONPath, lockfilePath)
+	if err != nil {
+		fail("%v", err)
 	}
 
 	fmt.Printf("Analyzing: %s@%s\n", pkgJSON.Name, pkgJSON.Version)
 
+	projectDir := "."
+	if lockfilePath != "" {
+		projectDir = filepath.Dir(lockfilePath)
+	} else if packageJSONPath != "" {
+		projectDir = filepath.Dir(packageJSONPath)
+	}
+	confusionChecker, err := cfg.confusionChecker(projectDir)
+	if err != nil {
+		fail("reading .npmrc: %v", err)
+	}
+	typosquatChecker, err := cfg.typosquatChecker()
+	if err != nil {
+		fail("invalid -typosquat-names: %v", err)
+	}
+
 	// Print summary
 	fmt.Printf("\nDependency Graph Summary:\n")
 	fmt.Printf("   Root: %s@%s\n", graph.RootPackage.Name, graph.RootPackage.Version)
 	fmt.Printf("   Total packages: %d\n", len(graph.Nodes))
 
 	directDeps := graph.GetDirectDependencies()
-	fmt.Printf("   Direct dependencies: %d\n\n", len(directDeps))
+	fmt.Printf("   Direct dependencies: %d\n", len(directDeps))
+
+	cycles := graph.Cycles()
+	fmt.Printf("   Circular dependency clusters: %d\n\n", len(cycles))
+	for _, cycle := range cycles {
+		fmt.Printf("   ! %s\n", strings.Join(cycle, " <-> "))
+	}
+	if len(cycles) > 0 {
+		fmt.Println()
+	}
 
 	if len(directDeps) > 0 {
 		fmt.Println("Direct Dependencies:")
@@ -315,53 +1066,158 @@ func runCheckCommand(cfg *Config, args []string) {
 		}
 	}
 
-	// Step 1: Upload all packages to registry
-	fmt.Println("\nUploading packages to registry...")
-	uploader := registry.NewUploader(cfg.RegistryURL, cfg.RegistryOwner, cfg.RegistryToken)
+	// Convert the dependencies in scope to []models.Package
+	scopeDeps, scopeLabel := directDeps, "direct dependencies"
+	if cfg.Scope == "all" {
+		scopeDeps, scopeLabel = graph.GetAllDependencies(), "packages in the dependency tree"
+	}
+	packagesToAnalyze := make([]models.Package, len(scopeDeps))
+	for i, dep := range scopeDeps {
+		packagesToAnalyze[i] = models.Package{
+			Name:    dep.Name,
+			Version: dep.Version,
+		}
+	}
+
+	// A local package is analyzed itself, under a throwaway prerelease version
+	var localPkg *models.Package
+	if localDir != "" {
+		localPkg = &models.Package{Name: pkgJSON.Name, Version: registry.LocalVersion(pkgJSON.Version, time.Now())}
+		packagesToAnalyze = []models.Package{*localPkg}
+		fmt.Printf("\nAnalyzing local package %s as %s@%s\n", localDir, localPkg.Name, localPkg.Version)
+	}
 
+	// Record the run's settings and inputs so spr reproduce can repeat it
+	repro, err := cfg.reproduction(context.Background(), cfg.reproSettings(packageJSONPath, lockfilePath, localDir, offline), graph, fresh)
+	if err != nil {
+		fail("%v", err)
+	}
+	if cfg.reproducing != nil {
+		if err := checkReproduction(cfg.reproducing, repro, cfg.allowDrift); err != nil {
+			fail("%v", err)
+		}
+	}
+	if err := repro.Save(cfg.OutputDir); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+	}
+	fmt.Printf("\nSeed: %d (reproduce with: spr reproduce %s)\n", repro.Seed, filepath.Join(cfg.OutputDir, orchestrator.ReproductionFile))
+
+	// Load the run manifest so an interrupted run resumes where it left off
+	if fresh {
+		if err := os.Remove(filepath.Join(cfg.OutputDir, orchestrator.ManifestFile)); err != nil && !os.IsNotExist(err) {
+			fail("removing run manifest: %v", err)
+		}
+	}
+	manifest, err := orchestrator.LoadManifest(cfg.OutputDir)
+	if err != nil {
+		fail("loading run manifest: %v", err)
+	}
+
+	// Fail fast with the cause when a service the run needs is down
 	ctx := context.Background()
-	if err := uploader.UploadGraph(ctx, graph); err != nil {
-		fmt.Fprintf(os.Stderr, "Error uploading to registry: %v\n", err)
-		os.Exit(1)
+	if !offline && !cfg.SkipPreflight {
+		if down := cfg.preflight(ctx); len(down) > 0 {
+			fmt.Fprintln(os.Stderr, "Error: preflight failed, services unavailable:")
+			for _, line := range down {
+				fmt.Fprintf(os.Stderr, "  %s\n", line)
+			}
+			fmt.Fprintln(os.Stderr, "Retry once they recover, or pass -skip-preflight")
+			exit("preflight failed, services unavailable: " + strings.Join(down, "; "))
+		}
 	}
-	fmt.Println("Successfully uploaded all packages")
 
-	// Step 2: Trigger GitHub Actions for direct dependencies only
-	if len(directDeps) == 0 {
-		fmt.Println("\nNo direct dependencies to analyze")
-		return
+	// Step 1: Upload all packages to registry, keeping the downloaded
+	// tarballs for the static scan
+	tarballDir, err := os.MkdirTemp("", "spr-tarballs-*")
+	if err != nil {
+		fail("creating tarball directory: %v", err)
 	}
+	defer os.RemoveAll(tarballDir)
+	uploaded := len(packagesToAnalyze) > 0
+	for _, pkg := range packagesToAnalyze {
+		if !manifest.Reached(pkg, orchestrator.StageUploaded) {
+			uploaded = false
+			break
+		}
+	}
+	if offline {
+		fmt.Println("\nOffline: skipping registry upload")
+	} else if uploaded {
+		fmt.Println("\nPackages already uploaded by a previous run, skipping upload")
+	} else {
+		fmt.Println("\nUploading packages to registry...")
+		uploader := registry.NewUploader(cfg.RegistryURL, cfg.RegistryOwner, cfg.RegistryToken)
+		uploader.Concurrency = cfg.UploadConcurrency
+		uploader.SetLogger(runLogger)
+		uploader.SetBackend(registry.NewBackend(registryType, cfg.RegistryURL, cfg.RegistryOwner, cfg.RegistryToken))
+		uploader.SetAllowNonNpm(cfg.AllowNonNpm)
+		uploader.SetTarballDir(tarballDir)
 
-	// Convert direct dependencies to []models.Package
-	packagesToAnalyze := make([]models.Package, len(directDeps))
-	for i, dep := range directDeps {
-		packagesToAnalyze[i] = models.Package{
-			Name:    dep.Name,
-			Version: dep.Version,
+		if err := uploader.UploadGraph(ctx, graph); err != nil {
+			fail("uploading to registry: %v", err)
+		}
+		if localPkg != nil {
+			if _, err := uploader.UploadLocal(ctx, localDir, localPkg.Version); err != nil {
+				fail("uploading local package: %v", err)
+			}
+		}
+		fmt.Println("Successfully uploaded all packages")
+
+		for _, pkg := range packagesToAnalyze {
+			if err := manifest.Advance(pkg, orchestrator.StageUploaded, 0); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			}
+		}
+	}
+
+	// Step 2: Trigger GitHub Actions for the packages in scope
+	if len(packagesToAnalyze) == 0 {
+		fmt.Printf("\nNo %s to analyze\n", scopeLabel)
+		if jsonPath != "" {
+			summary := orchestrator.NewSummary(cfg.OutputDir)
+			summary.RunID = runID
+			summary.MaxRisk = cfg.MaxRisk
+			if err := writeJSONSummary(jsonPath, summaryOut, summary); err != nil {
+				fmt.Fprintf(os.Stderr, "Error writing JSON summary: %v\n", err)
+				os.Exit(1)
+			}
 		}
+		return
 	}
 
 	// Create output directory
 	if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating output directory: %v\n", err)
-		os.Exit(1)
+		fail("creating output directory: %v", err)
 	}
 
 	// Create temp directory for artifacts
 	tempDir, err := os.MkdirTemp("", "spr-analysis-*")
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "Error creating temp directory: %v\n", err)
-		os.Exit(1)
+		fail("creating temp directory: %v", err)
 	}
 	defer os.RemoveAll(tempDir)
 
 	// Run analysis workflows
-	fmt.Printf("\nTriggering analysis workflows for %d direct dependencies (max %d concurrent)...\n", len(packagesToAnalyze), cfg.Concurrency)
+	if offline {
+		fmt.Printf("\nOffline: analyzing %d %s from cached results only\n", len(packagesToAnalyze), scopeLabel)
+	} else {
+		fmt.Printf("\nTriggering analysis workflows for %d %s (max %d concurrent)...\n", len(packagesToAnalyze), scopeLabel, cfg.Concurrency)
+	}
 
 	// Build safe registry uploader (nil when token not configured → promotion disabled)
 	var safeUploader *registry.Uploader
-	if cfg.SafeRegistryToken != "" {
+	if localPkg != nil {
+		fmt.Println("Safe registry promotion disabled for local packages")
+	} else if offline {
+		fmt.Println("Safe registry promotion disabled (offline)")
+	} else if cfg.RulesOnly {
+		fmt.Println("Safe registry promotion disabled (rules-only analysis)")
+	} else if cfg.SafeRegistryToken != "" {
 		safeUploader = registry.NewUploader(cfg.SafeRegistryURL, cfg.SafeRegistryOwner, cfg.SafeRegistryToken)
+		safeUploader.Concurrency = cfg.UploadConcurrency
+		safeUploader.SetLogger(runLogger)
+		safeUploader.SetBackend(registry.NewBackend(safeRegistryType, cfg.SafeRegistryURL, cfg.SafeRegistryOwner, cfg.SafeRegistryToken))
+		safeUploader.SetAllowNonNpm(cfg.AllowNonNpm)
 		fmt.Printf("Safe registry promotion enabled (%s / %s)\n", cfg.SafeRegistryURL, cfg.SafeRegistryOwner)
 	} else {
 		fmt.Println("Safe registry promotion disabled (SAFE_REGISTRY_TOKEN not set)")
@@ -381,26 +1237,321 @@ func runCheckCommand(cfg *Config, args []string) {
 		graph,
 	)
 
-	_, err = orch.RunPackages(ctx, packagesToAnalyze, tempDir, cfg.OutputDir)
+	orch.SetLogger(runLogger)
+	orch.SetAIProvider(cfg.AIProvider, cfg.AIBaseURL, cfg.AIModel)
+	orch.SetMaxAIBudget(cfg.MaxAIBudget)
+	orch.SetMaxPromptIndicators(cfg.MaxPromptIndicators)
+	orch.SetAnalysisSources(analysisSources)
+	orch.SetAnalysisLanguage(cfg.AnalysisLanguage)
+	orch.SetRulesOnly(cfg.RulesOnly)
+	orch.SetStageConcurrency(cfg.stageConcurrency())
+	orch.SetSkipDiskCheck(cfg.SkipDiskCheck)
+	orch.SetKeepTraces(cfg.KeepTraces)
+	orch.SetInterceptTLS(cfg.InterceptTLS)
+	orch.SetObservation(cfg.ObserveMinutes, cfg.ClockSkew)
+	orch.SetEnvMatrix(envMatrix)
+	orch.SetWorkflowInputs(workflowInputs)
+	orch.SetTests(tests)
+	orch.SetSeed(repro.Seed)
+	orch.SetPackageTimeouts(packageTimeouts)
+	orch.SetRetries(cfg.WorkflowRetries)
+	// Uncached packages fail offline; still report on the cached ones
+	orch.SetKeepGoing(cfg.KeepGoing || offline)
+	orch.SetOffline(offline)
+	orch.SetManifest(manifest)
+	orch.SetQuarantineDir(cfg.QuarantineDir)
+	orch.SetResultsLog(cfg.ResultsLog, runID)
+	orch.SetBaselineDrift(cfg.DriftHistory, cfg.DriftThreshold, cfg.DriftRuns)
+	orch.SetTelemetry(cfg.TelemetryURL)
+	orch.SetSIEM(cfg.SIEMURL, siemFormat, cfg.SIEMToken)
+	orch.SetTicketing(ticketFiler)
+	if artifactSink != nil {
+		orch.SetArtifactSink(artifactSink, runID, cfg.ArtifactKeepLocal)
+	}
+	orch.SetProcessKeyMode(keyMode)
+	orch.SetSyscallThreshold(aggregate.SyscallThreshold{Ratio: cfg.SyscallRatio, MinDelta: cfg.SyscallMinDelta, ZScore: cfg.SyscallZScore})
+	orch.SetAccessThreshold(aggregate.AccessThreshold{Ratio: cfg.AccessRatio, MinDelta: cfg.AccessMinDelta})
+	orch.SetMaxLineSize(cfg.MaxLineMB << 20)
+	orch.SetAllowlist(allowlist)
+	orch.SetIntel(enricher)
+	orch.SetTrustedPublishers(trustedPublishers, skipTrusted)
+	orch.SetConfusionCheck(confusionChecker)
+	orch.SetTyposquatCheck(typosquatChecker)
+	orch.SetAgePolicy(agePolicy)
+	orch.SetTarballDir(tarballDir)
+	if !cfg.SkipPopularity {
+		orch.SetPopularity(popularity.NewClient(popularity.DefaultCacheFile))
+	}
+
+	results, err := orch.RunPackages(ctx, packagesToAnalyze, tempDir, cfg.OutputDir)
+	printAIUsage(orch.AIUsage(), cfg.MaxAIBudget)
+	printTrusted(orch.TrustedPackages())
+	printTyposquats(orch.TyposquatFindings())
+	printConfusion(orch.ConfusionFindings())
+	printTooNew(orch.TooNew())
+	printStaticFlagged(orch.StaticFlagged())
+	riskGateFailed := printRiskGate(orch.RiskScores(), cfg.MaxRisk)
+	if jsonPath != "" {
+		summary, sumErr := orch.Summary(results, cfg.OutputDir)
+		if sumErr == nil {
+			summary.RunID = runID
+			summary.MaxRisk = cfg.MaxRisk
+			summary.Passed = summary.Passed && err == nil && !riskGateFailed
+			if err != nil {
+				summary.Error = err.Error()
+			}
+			sumErr = writeJSONSummary(jsonPath, summaryOut, summary)
+		}
+		if sumErr != nil {
+			fmt.Fprintf(os.Stderr, "Error writing JSON summary: %v\n", sumErr)
+			os.Exit(1)
+		}
+	}
+	if errors.Is(err, orchestrator.ErrPartialFailure) {
+		printFailureSummary(results)
+		fmt.Printf("\nArtifacts for the remaining packages saved to: %s\n", cfg.OutputDir)
+		os.Exit(1)
+	}
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "\nAnalysis failed: %v\n", err)
 		os.Exit(1)
 	}
 
 	fmt.Printf("\nAnalysis complete. Artifacts saved to: %s\n", cfg.OutputDir)
+	if riskGateFailed {
+		os.Exit(1)
+	}
+}
+
+// writeJSONSummary writes the run summary of -json to path, or to stdout
+// when path is "-"
+func writeJSONSummary(path string, stdout *os.File, summary *orchestrator.Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	if path == "-" {
+		_, err = stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// printRiskGate lists the packages whose risk score is above maxRisk and
+// reports whether there were any. A zero maxRisk disables the gate.
+func printRiskGate(scores map[models.Package]scoring.Score, maxRisk int) bool {
+	if maxRisk <= 0 {
+		return false
+	}
+	var lines []string
+	for pkg, score := range scores {
+		if score.Score <= maxRisk {
+			continue
+		}
+		line := fmt.Sprintf("  %s@%s: risk %d", pkg.Name, pkg.Version, score.Score)
+		if len(score.Factors) > 0 {
+			line += fmt.Sprintf(" (%s: %s)", score.Factors[0].Signal, score.Factors[0].Detail)
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 0 {
+		return false
+	}
+	sort.Strings(lines)
+	fmt.Fprintf(os.Stderr, "\n%d packages above the maximum risk score of %d (see %s):\n", len(lines), maxRisk, orchestrator.RiskFile)
+	for _, line := range lines {
+		fmt.Fprintln(os.Stderr, line)
+	}
+	return true
+}
+
+// printAIUsage prints the token usage and estimated cost of AI analysis, if
+// any LLM calls were made
+func printAIUsage(usage analysis.Usage, budget float64) {
+	if usage.PromptTokens == 0 && usage.CompletionTokens == 0 {
+		return
+	}
+	fmt.Printf("\nAI usage: %s", usage)
+	if budget > 0 {
+		fmt.Printf(" of $%.2f budget", budget)
+	}
+	fmt.Println()
+}
+
+// printTrusted lists the packages that skipped dynamic analysis for trusted
+// provenance
+func printTrusted(trusted map[models.Package]orchestrator.ProvenanceRecord) {
+	if len(trusted) == 0 {
+		return
+	}
+	lines := make([]string, 0, len(trusted))
+	for pkg, record := range trusted {
+		lines = append(lines, fmt.Sprintf("  %s@%s: %s", pkg.Name, pkg.Version, record.Publisher))
+	}
+	sort.Strings(lines)
+	fmt.Printf("\n%d packages %s (not dynamically analyzed):\n", len(trusted), orchestrator.TrustedViaProvenance)
+	for _, line := range lines {
+		fmt.Println(line)
+	}
+}
+
+// printConfusion lists the likely dependency confusion targets
+func printConfusion(findings []confusion.Result) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%d likely dependency confusion targets (see %s):\n", len(findings), orchestrator.ConfusionFile)
+	for _, finding := range findings {
+		fmt.Fprintf(os.Stderr, "  %s: %s, %s\n", finding.Package, finding.Kind, finding.Reason)
+	}
+}
+
+// printTyposquats lists the likely typosquats
+func printTyposquats(findings []typosquat.Finding) {
+	if len(findings) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%d likely typosquats (see %s):\n", len(findings), orchestrator.TyposquatFile)
+	for _, finding := range findings {
+		fmt.Fprintf(os.Stderr, "  %s: imitates %s (%s)\n", finding.Package, finding.Target, finding.Kind)
+	}
+}
+
+// printStaticFlagged lists the packages the static scan found risks in
+func printStaticFlagged(records []orchestrator.StaticRecord) {
+	if len(records) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%d packages with static findings (see %s):\n", len(records), orchestrator.StaticFile)
+	for _, record := range records {
+		fmt.Fprintf(os.Stderr, "  %s: static risk %.2f (%s)\n", record.Package, record.Score, strings.Join(record.Kinds(), ", "))
+	}
+}
+
+// printTooNew lists the versions under the minimum package age
+func printTooNew(records []orchestrator.AgeRecord) {
+	if len(records) == 0 {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\n%d package versions under the minimum age (see %s):\n", len(records), orchestrator.AgeFile)
+	for _, record := range records {
+		fmt.Fprintf(os.Stderr, "  %s@%s: published %s ago, %s\n", record.Package, record.Version, record.Age, record.Action)
+	}
+}
+
+// printFailureSummary lists the packages that failed in a keep-going run
+func printFailureSummary(results []orchestrator.PackageResult) {
+	var failures []string
+	for _, result := range results {
+		if result.Error != nil {
+			failures = append(failures, fmt.Sprintf("  %s@%s: %v", result.Package.Name, result.Package.Version, result.Error))
+		}
+	}
+	sort.Strings(failures)
+	fmt.Fprintf(os.Stderr, "\n%d of %d packages failed analysis:\n", len(failures), len(results))
+	for _, failure := range failures {
+		fmt.Fprintln(os.Stderr, failure)
+	}
+}
+
+// loadDependencyGraph builds the dependency graph from a lockfile or
+// package.json, auto-detecting either in the current directory when neither
+// path is given
+func loadDependencyGraph(packageJSONPath, lockfilePath string) (pkgJSON *parser.PackageJSON, graph *models.DependencyGraph, err error) {
+	// Need either package.json or lockfile
+	if packageJSONPath == "" && lockfilePath == "" {
+		// Auto-detect in current directory
+		cwd, err := os.Getwd()
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting current directory: %w", err)
+		}
+
+		// Try package-lock.json first, then package.json
+		if _, err := os.Stat(filepath.Join(cwd, "package-lock.json")); err == nil {
+			lockfilePath = filepath.Join(cwd, "package-lock.json")
+		} else {
+			path, err := parser.FindPackageJSON(cwd)
+			if err != nil {
+				return nil, nil, err
+			}
+			packageJSONPath = path
+		}
+	}
+
+	if lockfilePath != "" {
+		// Using lockfile directly
+		fmt.Printf("Using lockfile: %s\n", lockfilePath)
+
+		// Extract root package from lockfile
+		lm := parser.NewLockfileManager()
+		rootPackage, err := lm.ExtractRootPackage(lockfilePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("extracting root from lockfile: %w", err)
+		}
+
+		// Parse lockfile to get full graph
+		graph, err = lm.ParseLockfile(lockfilePath, rootPackage)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing lockfile: %w", err)
+		}
+
+		// Create a synthetic pkgJSON for display purposes
+		pkgJSON = &parser.PackageJSON{
+			Name:    "package",
+			Version: rootPackage.Version,
+		}
+	} else {
+		// Using package.json
+		// Validate package.json
+		if err := parser.ValidatePackageJSON(packageJSONPath); err != nil {
+			return nil, nil, err
+		}
+
+		// Parse package.json
+		pkgJSON, err = parser.ParsePackageJSON(packageJSONPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("parsing package.json: %w", err)
+		}
+
+		// Build dependency graph
+		if lockfilePath != "" {
+			// Use provided lockfile
+			lm := parser.NewLockfileManager()
+			graph, err = lm.ParseLockfile(lockfilePath, pkgJSON.ToPackage())
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing lockfile: %w", err)
+			}
+		} else {
+			// Generate and parse lockfile
+			fmt.Println("Generating lockfile...")
+			graph, err = parser.BuildGraphFromPackageJSON(packageJSONPath)
+			if err != nil {
+				return nil, nil, fmt.Errorf("building dependency graph: %w", err)
+			}
+		}
+	}
+
+	return pkgJSON, graph, nil
 }
 
 func printCheckUsage() {
 	fmt.Println("Usage: spr check [options]")
 	fmt.Println("")
 	fmt.Println("Analyzes npm packages by uploading to registry and running behavioral tests.")
-	fmt.Println("Requires either -package or -lockfile (auto-detects if neither specified).")
+	fmt.Println("Requires -package, -lockfile or -local (auto-detects package.json or lockfile if none specified).")
 	fmt.Println("")
 	fmt.Println("Options:")
+	fmt.Println("  -profile <name>        Preset: quick (direct deps, install+import tests, rules only, no promotion) or")
+	fmt.Println("                         thorough (full tree, all tests, locale and CI variants, 3 runs, AI). Other")
+	fmt.Println("                         flags override the preset")
 	fmt.Println("  -package <path>        Path to package.json (generates lockfile if needed)")
 	fmt.Println("  -lockfile <path>       Path to package-lock.json (uses existing lockfile)")
+	fmt.Println("  -local <dir>           Pack an unpublished package directory (npm pack, scripts not run) and analyze")
+	fmt.Println("                         it under a temporary <version>-spr.<timestamp> version")
 	fmt.Println("  -output <dir>          Output directory for artifacts (default: ./analysis-results)")
-	fmt.Println("  -registry-url <url>    Gitea registry URL (default: https://git.duti.dev)")
+	fmt.Println("  -registry-type <type>  Registry API: gitea, verdaccio or npm (default: gitea)")
+	fmt.Println("  -registry-url <url>    Registry URL (default: https://git.duti.dev)")
 	fmt.Println("  -registry-owner <own>  Gitea registry owner (default: acheong08)")
 	fmt.Println("  -registry-token <tok>  Gitea registry token (required)")
 	fmt.Println("  -github-token <tok>    GitHub token for workflow triggers (required)")
@@ -408,8 +1559,97 @@ func printCheckUsage() {
 	fmt.Println("  -repo-name <name>      GitHub repo name (default: hackeurope)")
 	fmt.Println("  -workflow <file>       Workflow file name (default: analyze-package.yml)")
 	fmt.Println("  -concurrency <n>       Max concurrent workflows (default: 5)")
+	fmt.Println("  -upload-concurrency <n>")
+	fmt.Println("                         Max concurrent registry uploads (default: 10)")
+	fmt.Println("  -aggregate-concurrency <n>")
+	fmt.Println("                         Max concurrent artifact copies and diffs; workflows wait when these")
+	fmt.Println("                         fall behind (default: 4)")
+	fmt.Println("  -ai-concurrency <n>    Max concurrent AI analyses (default: 5)")
 	fmt.Println("  -timeout <minutes>     Timeout per workflow in minutes (default: 5)")
+	fmt.Println("  -package-timeouts <s>  Per-package timeouts, e.g. \"esbuild=20m,@scope/pkg@1.2.3=45\" (bare numbers are minutes)")
+	fmt.Println("  -retries <n>           Re-trigger a failed or timed-out workflow up to n times (default: 1)")
 	fmt.Println("  -baseline <path>       Path to baseline JSON for diff generation (default: safe-sample.json)")
+	fmt.Println("  -allowlist <path>      YAML allowlist of benign files, commands, IP ranges and domains to drop from diffs")
+	fmt.Println("  -intel <path>          YAML list of ASN database and blocklists to enrich contacted IPs and domains")
+	fmt.Println("  -trusted-publishers <s>")
+	fmt.Println("                         Comma-separated CI workflows whose npm provenance is trusted, e.g.")
+	fmt.Println("                         \"github.com/acme,github.com/org/repo/.github/workflows/release.yml@refs/tags/*\"")
+	fmt.Println("  -provenance-policy <p> For packages with trusted provenance: skip dynamic analysis, or only record")
+	fmt.Println("                         it (default: skip)")
+	fmt.Println("  -private-scopes <s>    Comma-separated internal scopes, e.g. @acme, checked for dependency confusion")
+	fmt.Println("  -private-registries <s>")
+	fmt.Println("                         Comma-separated private registry URLs; scoped registries in .npmrc are added.")
+	fmt.Println("                         Token: PRIVATE_REGISTRY_TOKEN, or the registry's _authToken in .npmrc")
+	fmt.Println("  -min-package-age <d>   Gate versions published upstream less than this long ago, e.g. 72h or 3d")
+	fmt.Println("  -package-age-action <a>")
+	fmt.Println("                         For versions under the minimum age: block promotion of the tree, or")
+	fmt.Println("                         quarantine them (promote the rest without them) (default: block)")
+	fmt.Println("  -ai-provider <name>    AI provider: openai, anthropic, ollama or openai-compatible (default: openai)")
+	fmt.Println("  -ai-base-url <url>     AI API base URL (default: the provider's, e.g. http://localhost:11434/v1 for ollama)")
+	fmt.Println("  -ai-model <name>       AI model (default: gpt-5-mini, claude-sonnet-4-5 or llama3.1 by provider)")
+	fmt.Println("  -max-ai-budget <usd>   Stop AI analysis once its estimated cost reaches this many USD (default: unlimited)")
+	fmt.Println("  -max-prompt-indicators <n>")
+	fmt.Println("                         Sample each process's file, command and network lists longer than n in AI prompts,")
+	fmt.Println("                         keeping every sensitive path (default: 200)")
+	fmt.Println("  -analysis-sources <s>  Backends the AI agent can query: diff, stats or diff,stats (default: diff)")
+	fmt.Println("  -trace-api <url>       Trace stats service (cmd/statsd) for the stats source; traces are uploaded to it.")
+	fmt.Println("                         Its bearer token is read from TRACE_API_TOKEN")
+	fmt.Println("  -language <lang>       Translate AI justifications into this language, e.g. German (default: English)")
+	fmt.Println("  -intercept-tls         Capture HTTP(S) payload metadata via a TLS-intercepting proxy")
+	fmt.Println("  -allow-non-npm         Clone/download git and URL dependencies, npm pack and upload them instead of aborting")
+	fmt.Println("  -observe-minutes <n>   Keep sandbox alive n minutes post-import to catch time bombs, at most 110")
+	fmt.Println("                         (default: 0, off)")
+	fmt.Println("  -clock-skew <spec>     faketime spec used during observation (default: \"+30d x10\")")
+	fmt.Println("  -env-matrix <spec>     Rerun tests per env variant, e.g. \"ru:TZ=Europe/Moscow,LANG=ru_RU.UTF-8;cn:TZ=Asia/Shanghai\"")
+	fmt.Println("                         or \"default\" for the built-in locale/timezone matrix")
+	fmt.Println("  -spoof-ci              Also run with and without CI env (CI, GITHUB_ACTIONS, fake AWS creds), crossed with -env-matrix")
+	fmt.Println("  -runs <n>              Run the install and import tests n times, to catch payloads that only fire")
+	fmt.Println("                         sometimes (default: 1)")
+	fmt.Println("  -scope <s>             Packages to analyze: direct dependencies or all of the tree (default: direct)")
+	fmt.Println("  -rules-only            Decide with the deterministic rules alone, without LLM calls; packages they")
+	fmt.Println("                         don't flag are cleared unreviewed, so safe registry promotion is disabled")
+	fmt.Println("  -max-risk <n>          Exit non-zero when a package's combined risk score (0-100, see risk.json)")
+	fmt.Println("                         is above n (default: 0, no gate)")
+	fmt.Println("  -json <file>           Write a machine-readable summary (verdicts, risk scores, indicators and artifact")
+	fmt.Println("                         paths per package) to file, or to stdout with -, moving other output to stderr")
+	fmt.Println("  -seed <n>              Seed Math.random in the sandbox's tests (default: random). Recorded with the")
+	fmt.Println("                         run's inputs in reproduce.json for spr reproduce")
+	fmt.Println("  -input <key=value>     Extra workflow input for every run, e.g. node_version=20; repeatable.")
+	fmt.Println("  -tests <list>          Behavioral tests to run, e.g. install,import (default: all; install always runs).")
+	fmt.Println("                         Must be declared by the workflow")
+	fmt.Println("  -fresh                 Ignore the run manifest (run.json) and start over instead of resuming")
+	fmt.Println("  -keep-going            Keep analyzing other packages when one fails and summarize failures at the end")
+	fmt.Println("  -keep-traces           Keep behavior.jsonl and proxy.jsonl after diffing instead of deleting them from")
+	fmt.Println("                         the output and cache; needed to re-diff cached results against a new baseline")
+	fmt.Println("  -skip-disk-check       Skip the free disk space check before running workflows")
+	fmt.Println("  -skip-preflight        Don't check that GitHub, the registries, npm and the AI provider are up before")
+	fmt.Println("                         uploading; outages then fail the run when first hit")
+	fmt.Println("  -skip-popularity       Don't look up weekly npm downloads, which the rules weigh against install scripts")
+	fmt.Println("                         and network activity (counts are cached in analysis-results/downloads.json)")
+	fmt.Println("  -skip-typosquat        Don't compare dependency names against popular npm packages before analysis")
+	fmt.Println("  -typosquat-names <path>")
+	fmt.Println("                         More popular package names to compare against, one per line; listing a flagged")
+	fmt.Println("                         name accepts it")
+	fmt.Println("  -offline               Only use cached results from analysis-results; never upload or trigger workflows, so")
+	fmt.Println("                         no registry or GitHub token is needed. Delete a cached diff.json or ai-analysis.json")
+	fmt.Println("                         to recompute it. Implies -keep-going.")
+	fmt.Println("  -process-key <mode>    Key processes in diffs by name, ancestry or cmdline (default: ancestry)")
+	fmt.Println("  -syscall-ratio <x>     Keep a syscall count only if above baseline times x (default: 1.5)")
+	fmt.Println("  -syscall-min-delta <n> ...and more than n calls above the baseline (default: 50)")
+	fmt.Println("  -syscall-zscore <z>    With a multi-sample baseline, require z std devs above the mean instead (default: 3)")
+	fmt.Println("  -access-ratio <x>      Keep a file or command the baseline has only if used x times as often (default: 10; 0 never)")
+	fmt.Println("  -access-min-delta <n>  ...and more than n times more (default: 100)")
+	fmt.Println("  -max-line-mb <n>       Skip trace lines longer than n MiB when aggregating, with a warning (default: 16)")
+	fmt.Println("  -quarantine <dir>      Archive evidence for flagged packages here, empty disables (default: ./quarantine)")
+	fmt.Println("  -results-log <path>    Append verdicts to this hash-chained, tamper-evident log (check it with spr verify-log)")
+	fmt.Println("  -telemetry <url>       Opt in to posting anonymous counts and stage timings (no package names) to this URL")
+	fmt.Println("  -siem-url <url>        Forward flagged packages to this SIEM HTTP collector (token: SIEM_TOKEN)")
+	fmt.Println("  -siem-format <f>       SIEM event format: cef, leef or json (default: cef)")
+	fmt.Println("  -tickets <tracker>     Open a ticket per flagged package in github or jira (see TICKET_* and JIRA_* env vars)")
+	fmt.Println("  -artifact-bucket <b>   Also upload behavior.jsonl, diff.json and ai-analysis.json to this S3 bucket")
+	fmt.Println("                         (endpoint and credentials from ARTIFACT_S3_* env vars)")
+	fmt.Println("  -artifact-remote-only  Delete local copies once uploaded to the artifact bucket")
+	fmt.Println("  -log-format <fmt>      Log output format: text or json (default: text)")
 	fmt.Println("  -help                  Show this help message")
 }
 
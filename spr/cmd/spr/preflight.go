@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+)
+
+// preflightCheck is a service a check run depends on
+type preflightCheck struct {
+	dependency string
+	url        string
+}
+
+// preflight probes GitHub, the staging registry, npm and, when the AI
+// verdict is used, the LLM provider before anything is uploaded, returning
+// one line per service that is down. Probes go through the circuit
+// breakers, so an outage found here also fails later calls at once.
+func (c *Config) preflight(ctx context.Context) []string {
+	checks := []preflightCheck{
+		{breaker.GitHub, "https://api.github.com"},
+		{breaker.Registry, c.RegistryURL},
+		{breaker.Npm, "https://registry.npmjs.org/-/ping"},
+	}
+	if !c.RulesOnly && c.aiProvider().Enabled() {
+		if resolved, err := c.aiProvider().Resolve(); err == nil {
+			checks = append(checks, preflightCheck{breaker.LLM, strings.TrimSuffix(resolved.BaseURL, "/") + "/models"})
+		}
+	}
+
+	failures := make([]string, len(checks))
+	var wg sync.WaitGroup
+	for i, check := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := breaker.Probe(ctx, check.dependency, check.url); err != nil {
+				failures[i] = fmt.Sprintf("%s (%s): %v", check.dependency, check.url, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var down []string
+	for _, failure := range failures {
+		if failure != "" {
+			down = append(down, failure)
+		}
+	}
+	return down
+}
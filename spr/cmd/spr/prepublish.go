@@ -0,0 +1,231 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"syscall"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/parser"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// runPrepublishCommand gates npm publish on behavioral analysis. It is meant
+// to run from a package's prepublishOnly script: the package about to be
+// published is analyzed as with check -local, together with the runtime
+// dependencies that were added or changed since the last published version.
+// Any finding or failed analysis exits non-zero, which aborts the publish.
+func runPrepublishCommand(cfg *Config, args []string) {
+	dir := "."
+	warnOnly := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-dir":
+			if i+1 < len(args) {
+				dir = args[i+1]
+				i++
+			}
+		case "-output":
+			if i+1 < len(args) {
+				cfg.OutputDir = args[i+1]
+				i++
+			}
+		case "-warn-only":
+			warnOnly = true
+		case "-help":
+			printPrepublishUsage()
+			os.Exit(0)
+		}
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid PROCESS_KEY: %v\n", err)
+		os.Exit(1)
+	}
+
+	logging.Setup(cfg.LogFormat)
+	runLogger := slog.Default().With(logging.KeyRunID, logging.NewRunID())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	pkgJSON, graph, err := loadDependencyGraph(filepath.Join(dir, "package.json"), "")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if pkgJSON.Name == "" {
+		fmt.Fprintf(os.Stderr, "Error: package.json in %s has no name\n", dir)
+		os.Exit(1)
+	}
+
+	uploader, err := newStagingUploader(cfg, runLogger)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid REGISTRY_TYPE: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Dependencies unchanged since the last release were already vetted
+	// (or not) when it was published; only the delta is analyzed
+	var published map[string]string
+	if metadata, err := uploader.FetchPackageMetadata(ctx, pkgJSON.Name, "latest"); err != nil {
+		fmt.Printf("No published version of %s found (%v), analyzing all dependencies\n", pkgJSON.Name, err)
+	} else {
+		published = metadataDependencies(metadata)
+		fmt.Printf("Comparing dependencies against published %s@%v\n", pkgJSON.Name, metadata["version"])
+	}
+	changedDeps := dependencyDelta(pkgJSON.Dependencies, published)
+
+	localPkg := models.Package{Name: pkgJSON.Name, Version: registry.LocalVersion(pkgJSON.Version, time.Now())}
+	pkgs := append([]models.Package{localPkg}, resolveDelta(changedDeps, pkgJSON.Dependencies, directDependencyVersions(graph))...)
+	fmt.Printf("Analyzing %s@%s as %s plus %d changed dependencies\n", pkgJSON.Name, pkgJSON.Version, localPkg.Version, len(pkgs)-1)
+
+	if _, err := uploader.UploadLocal(ctx, dir, localPkg.Version); err != nil {
+		fmt.Fprintf(os.Stderr, "Error uploading local package: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Never promote an unpublished package to the safe registry
+	results, err := analyzeGraph(ctx, cfg, graph, pkgs, keyMode, runLogger, false)
+	blocked := false
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+		blocked = true
+	}
+
+	verdicts, verr := orchestrator.LoadVerdicts(cfg.OutputDir, pkgs)
+	if verr != nil {
+		fmt.Fprintf(os.Stderr, "Error loading verdicts: %v\n", verr)
+		blocked = true
+	}
+	fmt.Println("\nVerdicts:")
+	if prepublishBlocked(os.Stdout, pkgs, results, verdicts) {
+		blocked = true
+	}
+
+	if !blocked {
+		fmt.Printf("\nNo findings, %s@%s may be published\n", pkgJSON.Name, pkgJSON.Version)
+		return
+	}
+	if warnOnly {
+		fmt.Println("\nFindings above would block the publish (-warn-only set, continuing)")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\nPublish of %s@%s blocked; see %s for details\n", pkgJSON.Name, pkgJSON.Version, cfg.OutputDir)
+	os.Exit(1)
+}
+
+// resolveDelta maps the changed dependency names from package.json to the
+// versions resolved in the graph. Dependencies missing from the graph (not
+// installed yet) are skipped.
+func resolveDelta(changed []string, specs, resolved map[string]string) []models.Package {
+	var pkgs []models.Package
+	for _, name := range changed {
+		spec := specs[name]
+		if real, _, ok := parser.ParseAlias(spec); ok {
+			// The graph records aliases under the real package name
+			name = real
+		}
+		version, ok := resolved[name]
+		if !ok {
+			continue
+		}
+		fmt.Printf("  ~ %s@%s (%s)\n", name, version, spec)
+		pkgs = append(pkgs, models.Package{Name: name, Version: version})
+	}
+	return pkgs
+}
+
+// prepublishBlocked prints the verdict of each analyzed package to w and
+// reports whether any of them blocks the publish: a malicious verdict, a
+// failed analysis, or a package that was requested but has no result at all
+func prepublishBlocked(w io.Writer, pkgs []models.Package, results []orchestrator.PackageResult, verdicts map[string]*analysis.SecurityAssessment) bool {
+	blocked := false
+	reported := make(map[string]bool)
+	for _, result := range results {
+		pkg := result.Package
+		key := pkg.Name + "@" + pkg.Version
+		reported[key] = true
+		assessment, ok := verdicts[key]
+		switch {
+		case result.Error != nil:
+			fmt.Fprintf(w, "  %s: ERROR (%v)\n", key, result.Error)
+			blocked = true
+		case !ok:
+			fmt.Fprintf(w, "  %s: no result\n", key)
+			blocked = true
+		case assessment == nil:
+			fmt.Fprintf(w, "  %s: SAFE (no anomalous behavior)\n", key)
+		case assessment.IsMalicious:
+			fmt.Fprintf(w, "  %s: MALICIOUS (confidence %.2f)\n", key, assessment.Confidence)
+			for _, indicator := range assessment.Indicators {
+				fmt.Fprintf(w, "      - %s\n", indicator)
+			}
+			blocked = true
+		default:
+			fmt.Fprintf(w, "  %s: SAFE (confidence %.2f)\n", key, assessment.Confidence)
+		}
+	}
+	for _, pkg := range pkgs {
+		if key := pkg.Name + "@" + pkg.Version; !reported[key] {
+			fmt.Fprintf(w, "  %s: not analyzed\n", key)
+			blocked = true
+		}
+	}
+	return blocked
+}
+
+// metadataDependencies returns the dependencies of npm registry metadata
+func metadataDependencies(metadata map[string]interface{}) map[string]string {
+	deps := make(map[string]string)
+	raw, _ := metadata["dependencies"].(map[string]interface{})
+	for name, spec := range raw {
+		if s, ok := spec.(string); ok {
+			deps[name] = s
+		}
+	}
+	return deps
+}
+
+// dependencyDelta returns the names of dependencies in current that are not
+// in published with the same version range, sorted
+func dependencyDelta(current, published map[string]string) []string {
+	var names []string
+	for name, spec := range current {
+		if old, ok := published[name]; !ok || old != spec {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func printPrepublishUsage() {
+	fmt.Println("Usage: spr prepublish [options]")
+	fmt.Println("")
+	fmt.Println("Analyzes a package before it is published, along with the runtime dependencies added or")
+	fmt.Println("changed since its latest published version, and exits non-zero on any finding or failed")
+	fmt.Println("analysis. Uses the same registry, GitHub and analysis settings as spr check. Add it to")
+	fmt.Println("package.json to block npm publish:")
+	fmt.Println("")
+	fmt.Println("  \"scripts\": { \"prepublishOnly\": \"spr prepublish\" }")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -dir <path>            Package directory (default: current directory)")
+	fmt.Println("  -output <dir>          Output directory for artifacts (default: ./analysis-results)")
+	fmt.Println("  -warn-only             Report findings without blocking the publish")
+	fmt.Println("  -help                  Show this help message")
+}
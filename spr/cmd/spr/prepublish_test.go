@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDependencyDelta(t *testing.T) {
+	current := map[string]string{"a": "^1.0.0", "b": "^2.0.0", "c": "~3.1.0"}
+	published := map[string]string{"a": "^1.0.0", "b": "^1.0.0", "gone": "^1.0.0"}
+
+	assert.Equal(t, []string{"b", "c"}, dependencyDelta(current, published))
+	assert.Equal(t, []string{"a", "b", "c"}, dependencyDelta(current, nil), "nothing published yet")
+	assert.Empty(t, dependencyDelta(published, published))
+}
+
+func TestMetadataDependencies(t *testing.T) {
+	metadata := map[string]interface{}{
+		"version":      "1.2.0",
+		"dependencies": map[string]interface{}{"a": "^1.0.0", "bad": 42},
+	}
+	assert.Equal(t, map[string]string{"a": "^1.0.0"}, metadataDependencies(metadata))
+	assert.Empty(t, metadataDependencies(map[string]interface{}{}))
+}
+
+func TestResolveDelta(t *testing.T) {
+	specs := map[string]string{"a": "^1.0.0", "lodash4": "npm:lodash@^4.17.0", "missing": "^1.0.0"}
+	resolved := map[string]string{"a": "1.4.2", "lodash": "4.17.21"}
+
+	assert.Equal(t, []models.Package{
+		{Name: "a", Version: "1.4.2"},
+		{Name: "lodash", Version: "4.17.21"},
+	}, resolveDelta([]string{"a", "lodash4", "missing"}, specs, resolved))
+}
+
+func TestPrepublishBlocked(t *testing.T) {
+	local := models.Package{Name: "my-lib", Version: "1.0.0-local.1"}
+	dep := models.Package{Name: "dep", Version: "2.0.0"}
+	pkgs := []models.Package{local, dep}
+	ok := []orchestrator.PackageResult{{Package: local, Success: true}, {Package: dep, Success: true}}
+
+	tests := []struct {
+		name     string
+		results  []orchestrator.PackageResult
+		verdicts map[string]*analysis.SecurityAssessment
+		blocked  bool
+		output   string
+	}{
+		{
+			name:     "clean",
+			results:  ok,
+			verdicts: map[string]*analysis.SecurityAssessment{"my-lib@1.0.0-local.1": nil, "dep@2.0.0": {Confidence: 0.9}},
+			output:   "dep@2.0.0: SAFE (confidence 0.90)",
+		},
+		{
+			name:    "malicious dependency",
+			results: ok,
+			verdicts: map[string]*analysis.SecurityAssessment{
+				"my-lib@1.0.0-local.1": nil,
+				"dep@2.0.0":            {IsMalicious: true, Confidence: 0.8, Indicators: []string{"reads ~/.npmrc"}},
+			},
+			blocked: true,
+			output:  "- reads ~/.npmrc",
+		},
+		{
+			name:     "failed analysis",
+			results:  []orchestrator.PackageResult{ok[0], {Package: dep, Error: errors.New("workflow timed out")}},
+			verdicts: map[string]*analysis.SecurityAssessment{"my-lib@1.0.0-local.1": nil},
+			blocked:  true,
+			output:   "dep@2.0.0: ERROR (workflow timed out)",
+		},
+		{
+			name:     "no verdict",
+			results:  ok,
+			verdicts: map[string]*analysis.SecurityAssessment{"dep@2.0.0": nil},
+			blocked:  true,
+			output:   "my-lib@1.0.0-local.1: no result",
+		},
+		{
+			name:     "package never analyzed",
+			results:  ok[:1],
+			verdicts: map[string]*analysis.SecurityAssessment{"my-lib@1.0.0-local.1": nil},
+			blocked:  true,
+			output:   "dep@2.0.0: not analyzed",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			assert.Equal(t, tt.blocked, prepublishBlocked(&out, pkgs, tt.results, tt.verdicts))
+			assert.Contains(t, out.String(), tt.output)
+		})
+	}
+
+	assert.True(t, prepublishBlocked(io.Discard, pkgs, nil, nil), "nothing analyzed blocks")
+}
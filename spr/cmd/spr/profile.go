@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// analysisProfile is a named preset for the settings that trade analysis
+// depth for speed and cost. Profile settings override the environment;
+// flags given alongside -profile override the profile.
+type analysisProfile struct {
+	Scope     string // Packages analyzed: direct or all
+	Tests     string
+	EnvMatrix string
+	SpoofCI   bool
+	Runs      int
+	RulesOnly bool
+	NeedsAI   bool // Fail unless an AI provider is configured
+}
+
+var analysisProfiles = map[string]analysisProfile{
+	// Fast pre-screen: direct dependencies, one run of the install and import
+	// tests, decided by the deterministic rules without LLM calls
+	"quick": {
+		Scope:     "direct",
+		Tests:     "install,import",
+		Runs:      1,
+		RulesOnly: true,
+	},
+	// Full tree, every test under the locale and CI matrices, three runs
+	// each, and AI review of whatever the rules don't decide
+	"thorough": {
+		Scope:     "all",
+		EnvMatrix: "default",
+		SpoofCI:   true,
+		Runs:      3,
+		NeedsAI:   true,
+	},
+}
+
+// profileNames lists the profiles for usage and error messages
+func profileNames() string {
+	names := make([]string, 0, len(analysisProfiles))
+	for name := range analysisProfiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return strings.Join(names, ", ")
+}
+
+// profileArg returns the value of the last -profile flag in args, or
+// fallback if there is none. Profiles are applied before the other flags
+// are parsed, so those can override them.
+func profileArg(args []string, fallback string) string {
+	for i := 0; i < len(args)-1; i++ {
+		if args[i] == "-profile" {
+			fallback = args[i+1]
+			i++
+		}
+	}
+	return fallback
+}
+
+// applyProfile sets the settings bundled by the named profile; an empty name
+// leaves them alone
+func (c *Config) applyProfile(name string) error {
+	c.Profile = name
+	if name == "" {
+		return nil
+	}
+	p, ok := analysisProfiles[name]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (want %s)", name, profileNames())
+	}
+	c.Scope = p.Scope
+	c.Tests = p.Tests
+	c.EnvMatrix = p.EnvMatrix
+	c.SpoofCI = p.SpoofCI
+	c.Runs = p.Runs
+	c.RulesOnly = p.RulesOnly
+	return nil
+}
+
+// validateProfile checks what the selected profile needs once all flags are
+// parsed
+func (c *Config) validateProfile() error {
+	if c.Profile == "" {
+		return nil
+	}
+	if analysisProfiles[c.Profile].NeedsAI && !c.RulesOnly && !c.aiProvider().Enabled() {
+		return fmt.Errorf("the %s profile needs AI analysis: set OPENAI_API_KEY or choose a keyless -ai-provider such as ollama", c.Profile)
+	}
+	return nil
+}
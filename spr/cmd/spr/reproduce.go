@@ -0,0 +1,392 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// reproSettings are the check settings that affect results, recorded in the
+// reproducibility manifest. Tokens, concurrency and integrations that only
+// report results (SIEM, tickets, artifact export...) are left out, so a
+// reproduction uses the current environment's.
+type reproSettings struct {
+	PackageJSON       string  `json:"package_json,omitempty"`
+	Lockfile          string  `json:"lockfile,omitempty"`
+	Local             string  `json:"local,omitempty"`
+	Offline           bool    `json:"offline,omitempty"`
+	RegistryType      string  `json:"registry_type"`
+	RegistryURL       string  `json:"registry_url"`
+	RegistryOwner     string  `json:"registry_owner"`
+	RepoOwner         string  `json:"repo_owner"`
+	RepoName          string  `json:"repo_name"`
+	WorkflowFile      string  `json:"workflow_file"`
+	TimeoutMinutes    int     `json:"timeout_minutes"`
+	PackageTimeouts   string  `json:"package_timeouts,omitempty"`
+	WorkflowRetries   int     `json:"workflow_retries"`
+	BaselinePath      string  `json:"baseline,omitempty"`
+	AllowlistPath     string  `json:"allowlist,omitempty"`
+	IntelPath         string  `json:"intel,omitempty"`
+	TrustedPublishers string  `json:"trusted_publishers,omitempty"`
+	ProvenancePolicy  string  `json:"provenance_policy,omitempty"`
+	PrivateScopes     string  `json:"private_scopes,omitempty"`
+	PrivateRegistries string  `json:"private_registries,omitempty"`
+	MinPackageAge     string  `json:"min_package_age,omitempty"`
+	PackageAgeAction  string  `json:"package_age_action,omitempty"`
+	AIProvider        string  `json:"ai_provider"`
+	AIBaseURL         string  `json:"ai_base_url,omitempty"`
+	AIModel           string  `json:"ai_model,omitempty"`
+	AnalysisSources   string  `json:"analysis_sources"`
+	TraceAPIURL       string  `json:"trace_api,omitempty"`
+	Language          string  `json:"language,omitempty"`
+	InterceptTLS      bool    `json:"intercept_tls,omitempty"`
+	SkipPopularity    bool    `json:"skip_popularity,omitempty"`
+	SkipTyposquat     bool    `json:"skip_typosquat,omitempty"`
+	TyposquatNames    string  `json:"typosquat_names,omitempty"`
+	AllowNonNpm       bool    `json:"allow_non_npm,omitempty"`
+	ObserveMinutes    int     `json:"observe_minutes,omitempty"`
+	ClockSkew         string  `json:"clock_skew"`
+	EnvMatrix         string  `json:"env_matrix,omitempty"`
+	SpoofCI           bool    `json:"spoof_ci,omitempty"`
+	Runs              int     `json:"runs"`
+	Scope             string  `json:"scope"`
+	RulesOnly         bool    `json:"rules_only,omitempty"`
+	WorkflowInputs    string  `json:"inputs,omitempty"`
+	Tests             string  `json:"tests,omitempty"`
+	ProcessKey        string  `json:"process_key"`
+	SyscallRatio      float64 `json:"syscall_ratio"`
+	SyscallMinDelta   int     `json:"syscall_min_delta"`
+	SyscallZScore     float64 `json:"syscall_zscore"`
+	AccessRatio       float64 `json:"access_ratio"`
+	AccessMinDelta    int     `json:"access_min_delta"`
+	MaxLineMB         int     `json:"max_line_mb,omitempty"`
+	PromptIndicators  int     `json:"max_prompt_indicators,omitempty"`
+}
+
+// localInputs are the inputs read from disk. When they changed since the
+// recorded run, a reproduction would analyze something else, so it stops.
+var localInputs = map[string]bool{
+	"lockfile":     true,
+	"package_json": true,
+	"dependencies": true,
+	"baseline":     true,
+	"allowlist":    true,
+}
+
+// absPath makes a recorded path independent of the working directory
+func absPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// reproSettings records the settings of a check run
+func (c *Config) reproSettings(packageJSONPath, lockfilePath, localDir string, offline bool) reproSettings {
+	return reproSettings{
+		PackageJSON:       absPath(packageJSONPath),
+		Lockfile:          absPath(lockfilePath),
+		Local:             absPath(localDir),
+		Offline:           offline,
+		RegistryType:      c.RegistryType,
+		RegistryURL:       c.RegistryURL,
+		RegistryOwner:     c.RegistryOwner,
+		RepoOwner:         c.RepoOwner,
+		RepoName:          c.RepoName,
+		WorkflowFile:      c.WorkflowFile,
+		TimeoutMinutes:    c.TimeoutMinutes,
+		PackageTimeouts:   c.PackageTimeouts,
+		WorkflowRetries:   c.WorkflowRetries,
+		BaselinePath:      absPath(c.BaselinePath),
+		AllowlistPath:     absPath(c.AllowlistPath),
+		IntelPath:         absPath(c.IntelPath),
+		TrustedPublishers: c.TrustedPublishers,
+		ProvenancePolicy:  c.ProvenancePolicy,
+		PrivateScopes:     c.PrivateScopes,
+		PrivateRegistries: c.PrivateRegistries,
+		MinPackageAge:     c.MinPackageAge,
+		PackageAgeAction:  c.PackageAgeAction,
+		AIProvider:        c.AIProvider,
+		AIBaseURL:         c.AIBaseURL,
+		AIModel:           c.AIModel,
+		AnalysisSources:   c.AnalysisSources,
+		TraceAPIURL:       c.TraceAPIURL,
+		Language:          c.AnalysisLanguage,
+		InterceptTLS:      c.InterceptTLS,
+		SkipPopularity:    c.SkipPopularity,
+		SkipTyposquat:     c.SkipTyposquat,
+		TyposquatNames:    absPath(c.TyposquatNames),
+		AllowNonNpm:       c.AllowNonNpm,
+		ObserveMinutes:    c.ObserveMinutes,
+		ClockSkew:         c.ClockSkew,
+		EnvMatrix:         c.EnvMatrix,
+		SpoofCI:           c.SpoofCI,
+		Runs:              c.Runs,
+		Scope:             c.Scope,
+		RulesOnly:         c.RulesOnly,
+		WorkflowInputs:    c.WorkflowInputs,
+		Tests:             c.Tests,
+		ProcessKey:        c.ProcessKey,
+		SyscallRatio:      c.SyscallRatio,
+		SyscallMinDelta:   c.SyscallMinDelta,
+		SyscallZScore:     c.SyscallZScore,
+		AccessRatio:       c.AccessRatio,
+		AccessMinDelta:    c.AccessMinDelta,
+		MaxLineMB:         c.MaxLineMB,
+		PromptIndicators:  c.MaxPromptIndicators,
+	}
+}
+
+// apply restores recorded settings, replacing any profile
+func (s reproSettings) apply(c *Config) {
+	c.Profile = ""
+	c.PackageJSONPath = s.PackageJSON
+	c.LockfilePath = s.Lockfile
+	c.RegistryType = s.RegistryType
+	c.RegistryURL = s.RegistryURL
+	c.RegistryOwner = s.RegistryOwner
+	c.RepoOwner = s.RepoOwner
+	c.RepoName = s.RepoName
+	c.WorkflowFile = s.WorkflowFile
+	c.TimeoutMinutes = s.TimeoutMinutes
+	c.PackageTimeouts = s.PackageTimeouts
+	c.WorkflowRetries = s.WorkflowRetries
+	c.BaselinePath = s.BaselinePath
+	c.AllowlistPath = s.AllowlistPath
+	c.IntelPath = s.IntelPath
+	c.TrustedPublishers = s.TrustedPublishers
+	c.ProvenancePolicy = s.ProvenancePolicy
+	c.PrivateScopes = s.PrivateScopes
+	c.PrivateRegistries = s.PrivateRegistries
+	c.MinPackageAge = s.MinPackageAge
+	c.PackageAgeAction = s.PackageAgeAction
+	c.AIProvider = s.AIProvider
+	c.AIBaseURL = s.AIBaseURL
+	c.AIModel = s.AIModel
+	c.AnalysisSources = s.AnalysisSources
+	c.TraceAPIURL = s.TraceAPIURL
+	c.AnalysisLanguage = s.Language
+	c.InterceptTLS = s.InterceptTLS
+	c.SkipPopularity = s.SkipPopularity
+	c.SkipTyposquat = s.SkipTyposquat
+	c.TyposquatNames = s.TyposquatNames
+	c.AllowNonNpm = s.AllowNonNpm
+	c.ObserveMinutes = s.ObserveMinutes
+	c.ClockSkew = s.ClockSkew
+	c.EnvMatrix = s.EnvMatrix
+	c.SpoofCI = s.SpoofCI
+	c.Runs = s.Runs
+	c.Scope = s.Scope
+	c.RulesOnly = s.RulesOnly
+	c.WorkflowInputs = s.WorkflowInputs
+	c.Tests = s.Tests
+	c.ProcessKey = s.ProcessKey
+	c.SyscallRatio = s.SyscallRatio
+	c.SyscallMinDelta = s.SyscallMinDelta
+	c.SyscallZScore = s.SyscallZScore
+	c.AccessRatio = s.AccessRatio
+	c.AccessMinDelta = s.AccessMinDelta
+	if s.MaxLineMB > 0 {
+		c.MaxLineMB = s.MaxLineMB
+	}
+	if s.PromptIndicators > 0 {
+		c.MaxPromptIndicators = s.PromptIndicators
+	}
+}
+
+// reproduction builds the reproducibility manifest of a check run. The seed
+// is -seed if given, else that of the run being resumed in the output
+// directory, else a new random one.
+func (c *Config) reproduction(ctx context.Context, settings reproSettings, graph *models.DependencyGraph, fresh bool) (*orchestrator.Reproduction, error) {
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal settings: %w", err)
+	}
+	r := &orchestrator.Reproduction{
+		Seed:      int64(c.Seed),
+		CreatedAt: time.Now().UTC(),
+		Generator: version.Stamp(),
+		Settings:  data,
+	}
+	if r.Seed == 0 && !fresh {
+		if previous, err := orchestrator.LoadReproduction(filepath.Join(c.OutputDir, orchestrator.ReproductionFile)); err == nil {
+			r.Seed = previous.Seed
+		}
+	}
+	if r.Seed == 0 {
+		// V8's --random-seed takes a positive int
+		r.Seed = rand.Int64N(math.MaxInt32) + 1
+	}
+
+	inputs := &r.Inputs
+	if inputs.Lockfile, err = orchestrator.HashFile(settings.Lockfile); err != nil {
+		return nil, fmt.Errorf("failed to hash lockfile: %w", err)
+	}
+	if settings.Lockfile == "" {
+		if inputs.PackageJSON, err = orchestrator.HashFile(settings.PackageJSON); err != nil {
+			return nil, fmt.Errorf("failed to hash package.json: %w", err)
+		}
+	}
+	var deps []string
+	for _, dep := range graph.GetAllDependencies() {
+		deps = append(deps, dep.ID)
+	}
+	inputs.Dependencies = orchestrator.HashStrings(deps)
+	if inputs.Baseline, err = orchestrator.HashFile(settings.BaselinePath); err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("failed to hash baseline: %w", err)
+	}
+	if inputs.Allowlist, err = orchestrator.HashFile(settings.AllowlistPath); err != nil {
+		return nil, fmt.Errorf("failed to hash allowlist: %w", err)
+	}
+	inputs.Workflow = c.WorkflowFile
+	inputs.Ruleset = analysis.RulesetHash(analysis.DefaultRules)
+	if ai := c.aiProvider(); !c.RulesOnly && ai.Enabled() {
+		if resolved, err := ai.Resolve(); err == nil {
+			inputs.AIModel = resolved.Provider + "/" + resolved.Model
+			inputs.Prompt = analysis.PromptHash()
+		}
+	}
+
+	// Runs check out the workflow and templates of the dispatch branch as it
+	// is when they start, which can't be pinned; record what that is now
+	if !settings.Offline {
+		client := orchestrator.NewGitHubClient(c.GitHubToken, c.RepoOwner, c.RepoName)
+		sha, templates, err := client.WorkflowRevision(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not record the workflow revision: %v\n", err)
+		} else {
+			inputs.WorkflowSHA, inputs.Templates = sha, templates
+		}
+	}
+	return r, nil
+}
+
+// checkReproduction compares a reproduction's inputs with the recorded run's.
+// Changed remote inputs are warned about; changed local inputs fail unless
+// allowDrift is set.
+func checkReproduction(recorded, now *orchestrator.Reproduction, allowDrift bool) error {
+	short := func(s string) string {
+		if len(s) > 12 {
+			return s[:12]
+		}
+		if s == "" {
+			return "(none)"
+		}
+		return s
+	}
+	var local []string
+	for _, change := range now.Changes(recorded) {
+		fmt.Fprintf(os.Stderr, "Warning: %s changed since the recorded run: was %s, now %s\n", change.Input, short(change.Was), short(change.Now))
+		if localInputs[change.Input] {
+			local = append(local, change.Input)
+		}
+	}
+	if len(local) > 0 && !allowDrift {
+		return fmt.Errorf("%s changed since the recorded run; restore the recorded inputs or pass -allow-drift", strings.Join(local, ", "))
+	}
+	return nil
+}
+
+// runReproduceCommand repeats a check run with the settings and seed recorded
+// in its reproducibility manifest, into a new output directory
+func runReproduceCommand(cfg *Config, args []string) {
+	manifestPath := ""
+	outputDir := ""
+	allowDrift := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-output":
+			if i+1 < len(args) {
+				outputDir = args[i+1]
+				i++
+			}
+		case "-allow-drift":
+			allowDrift = true
+		case "-help":
+			printReproduceUsage()
+			os.Exit(0)
+		default:
+			if strings.HasPrefix(args[i], "-") || manifestPath != "" {
+				fmt.Fprintf(os.Stderr, "Unknown option: %s\n\n", args[i])
+				printReproduceUsage()
+				os.Exit(1)
+			}
+			manifestPath = args[i]
+		}
+	}
+	if manifestPath == "" {
+		printReproduceUsage()
+		os.Exit(1)
+	}
+
+	// A directory stands for the manifest in it
+	if info, err := os.Stat(manifestPath); err == nil && info.IsDir() {
+		manifestPath = filepath.Join(manifestPath, orchestrator.ReproductionFile)
+	}
+	recorded, err := orchestrator.LoadReproduction(manifestPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	var settings reproSettings
+	if err := json.Unmarshal(recorded.Settings, &settings); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid settings in %s: %v\n", manifestPath, err)
+		os.Exit(1)
+	}
+
+	recordedDir := filepath.Dir(absPath(manifestPath))
+	if outputDir == "" {
+		outputDir = recordedDir + "-reproduce"
+	}
+	if absPath(outputDir) == recordedDir {
+		fmt.Fprintln(os.Stderr, "Error: -output must differ from the recorded run's directory, whose results it would overwrite")
+		os.Exit(1)
+	}
+
+	settings.apply(cfg)
+	cfg.OutputDir = outputDir
+	cfg.Seed = int(recorded.Seed)
+	cfg.reproducing = recorded
+	cfg.allowDrift = allowDrift
+
+	checkArgs := []string{"-fresh"}
+	if settings.Local != "" {
+		checkArgs = append(checkArgs, "-local", settings.Local)
+	}
+	if settings.Offline {
+		checkArgs = append(checkArgs, "-offline")
+	}
+
+	fmt.Printf("Reproducing the run of %s (%s, seed %d) into %s\n\n", recorded.CreatedAt.Format(time.RFC3339), recorded.Generator, recorded.Seed, outputDir)
+	runCheckCommand(cfg, checkArgs)
+}
+
+func printReproduceUsage() {
+	fmt.Println("Usage: spr reproduce [options] <manifest>")
+	fmt.Println("")
+	fmt.Println("Repeat a spr check run with the settings and seed recorded in its reproduce.json")
+	fmt.Println("(or the output directory containing it). Tokens and integrations come from the")
+	fmt.Println("current environment. The lockfile, baseline and other inputs are hashed again and")
+	fmt.Println("compared with the recorded ones; changed workflow commits, templates, models,")
+	fmt.Println("prompts and rules are reported, as runs always use the current ones.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -output <dir>          Output directory (default: the recorded run's, suffixed -reproduce)")
+	fmt.Println("  -allow-drift           Reproduce even though local inputs changed since the recorded run")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/sbom"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// runSBOMCommand exports the dependency graph as a CycloneDX or SPDX SBOM, attaching
+// verdicts from a previous `spr check` run when its results are available
+func runSBOMCommand(cfg *Config, args []string) {
+	packageJSONPath := cfg.PackageJSONPath
+	lockfilePath := cfg.LockfilePath
+	resultsDir := cfg.OutputDir
+	format := "cyclonedx"
+	outputPath := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-package":
+			if i+1 < len(args) {
+				packageJSONPath = args[i+1]
+				i++
+			}
+		case "-lockfile":
+			if i+1 < len(args) {
+				lockfilePath = args[i+1]
+				i++
+			}
+		case "-results":
+			if i+1 < len(args) {
+				resultsDir = args[i+1]
+				i++
+			}
+		case "-format":
+			if i+1 < len(args) {
+				format = args[i+1]
+				i++
+			}
+		case "-o":
+			if i+1 < len(args) {
+				outputPath = args[i+1]
+				i++
+			}
+		case "-help":
+			printSBOMUsage()
+			os.Exit(0)
+		}
+	}
+
+	if format != "cyclonedx" && format != "spdx" {
+		fmt.Fprintf(os.Stderr, "Error: unknown -format %q (expected cyclonedx or spdx)\n", format)
+		os.Exit(1)
+	}
+
+	_, graph, err := loadDependencyGraph(packageJSONPath, lockfilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+
+	var packages []models.Package
+	for _, node := range graph.Nodes {
+		packages = append(packages, node.Package)
+	}
+	verdicts, err := orchestrator.LoadVerdicts(resultsDir, packages)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error loading analysis results: %v\n", err)
+		os.Exit(1)
+	}
+
+	switch format {
+	case "spdx":
+		if outputPath == "" {
+			outputPath = orchestrator.SPDXSBOMFile
+		}
+		fmt.Println("Fetching license information from npm...")
+		licenses := sbom.FetchLicenses(context.Background(), graph, 10)
+		err = sbom.NewSPDX(graph, verdicts, licenses).Write(outputPath)
+	default:
+		if outputPath == "" {
+			outputPath = orchestrator.SBOMFile
+		}
+		err = sbom.NewCycloneDX(graph, verdicts).Write(outputPath)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing SBOM: %v\n", err)
+		os.Exit(1)
+	}
+
+	fmt.Printf("Wrote %s SBOM for %d packages (%d with verdicts) to %s\n", format, len(graph.Nodes), len(verdicts), outputPath)
+}
+
+func printSBOMUsage() {
+	fmt.Println("Usage: spr sbom [options]")
+	fmt.Println("")
+	fmt.Println("Exports the dependency graph as a CycloneDX or SPDX 2.3 JSON SBOM, including")
+	fmt.Println("integrity hashes, resolved URLs and verdicts from previous analysis results.")
+	fmt.Println("SPDX output also includes licenses declared in npm metadata.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -package <path>        Path to package.json (generates lockfile if needed)")
+	fmt.Println("  -lockfile <path>       Path to package-lock.json (uses existing lockfile)")
+	fmt.Println("  -results <dir>         Analysis results to take verdicts from (default: ./analysis-results)")
+	fmt.Println("  -format <fmt>          cyclonedx or spdx (default: cyclonedx)")
+	fmt.Println("  -o <file>              Output file (default: sbom.cdx.json or sbom.spdx.json)")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -18,6 +18,7 @@ func TestGenerateCommand(args []string) {
 		registryURL    = "https://git.duti.dev"
 		registryOwner  = "acheong08"
 		registryToken  = ""
+		testsSpec      = ""
 	)
 
 	// Parse flags
@@ -58,6 +59,11 @@ func TestGenerateCommand(args []string) {
 				registryToken = args[i+1]
 				i++
 			}
+		case "--tests":
+			if i+1 < len(args) {
+				testsSpec = args[i+1]
+				i++
+			}
 		}
 	}
 
@@ -86,6 +92,13 @@ func TestGenerateCommand(args []string) {
 		fmt.Fprintln(os.Stderr, "  --registry-url <url>       Registry URL (default: https://git.duti.dev)")
 		fmt.Fprintln(os.Stderr, "  --registry-owner <owner>   Registry owner (default: acheong08)")
 		fmt.Fprintln(os.Stderr, "  --registry-token <token>   Registry token (optional, uses npm registry if not set)")
+		fmt.Fprintln(os.Stderr, "  --tests <list>             Tests to generate, e.g. install,import (default: all)")
+		os.Exit(1)
+	}
+
+	tests, err := tester.ParseTests(testsSpec)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ Invalid --tests: %v\n", err)
 		os.Exit(1)
 	}
 
@@ -98,6 +111,7 @@ func TestGenerateCommand(args []string) {
 	} else {
 		generator = tester.NewGenerator(templatesDir)
 	}
+	generator.SetTests(tests)
 
 	// Generate all test packages
 	fmt.Printf("📝 Generating test packages...\n")
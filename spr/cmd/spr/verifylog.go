@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/acheong08/hackeurope-spr/internal/resultlog"
+)
+
+// runVerifyLogCommand checks the hash chain of a results log written by
+// -results-log, optionally against a head hash recorded elsewhere, which
+// also catches entries deleted from the end
+func runVerifyLogCommand(cfg *Config, args []string) {
+	path := cfg.ResultsLog
+	expectHead := ""
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-head":
+			if i+1 < len(args) {
+				expectHead = args[i+1]
+				i++
+			}
+		case "-help":
+			printVerifyLogUsage()
+			os.Exit(0)
+		default:
+			path = args[i]
+		}
+	}
+
+	if path == "" {
+		fmt.Fprintln(os.Stderr, "Error: no results log given and RESULTS_LOG is not set")
+		printVerifyLogUsage()
+		os.Exit(1)
+	}
+
+	result, err := resultlog.Verify(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "FAIL: %v (%d entries verified before it)\n", err, result.Entries)
+		os.Exit(1)
+	}
+	if expectHead != "" && result.Head != expectHead {
+		fmt.Fprintf(os.Stderr, "FAIL: head is %s, expected %s; entries were removed or appended since\n", result.Head, expectHead)
+		os.Exit(1)
+	}
+	fmt.Printf("OK: %d entries, head %s\n", result.Entries, result.Head)
+}
+
+func printVerifyLogUsage() {
+	fmt.Println("Usage: spr verify-log [options] [path]")
+	fmt.Println("")
+	fmt.Println("Verify the hash chain of a results log written by spr check -results-log.")
+	fmt.Println("Editing, reordering or deleting any entry breaks the chain. Keep the printed")
+	fmt.Println("head hash elsewhere and pass it to -head to also detect a truncated log.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -head <hash>           Fail unless the last entry has this hash")
+	fmt.Println("  -help                  Show this help message")
+	fmt.Println("")
+	fmt.Println("The path defaults to RESULTS_LOG.")
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+)
+
+// runVersionCommand prints the build info that is also stamped into every
+// generated artifact
+func runVersionCommand(args []string) {
+	asJSON := false
+	for _, arg := range args {
+		switch arg {
+		case "-json":
+			asJSON = true
+		case "-help":
+			printVersionUsage()
+			os.Exit(0)
+		}
+	}
+
+	info := version.Get()
+	if asJSON {
+		data, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		fmt.Println(string(data))
+		return
+	}
+
+	fmt.Printf("spr %s\n", info.Version)
+	commit := info.Commit
+	if commit == "" {
+		commit = "unknown"
+	} else if info.Modified {
+		commit += " (modified)"
+	}
+	fmt.Printf("  commit:     %s\n", commit)
+	if info.BuildDate != "" {
+		fmt.Printf("  build date: %s\n", info.BuildDate)
+	}
+	fmt.Printf("  go:         %s\n", info.GoVersion)
+
+	names := make([]string, 0, len(info.Components))
+	for name := range info.Components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-11s %s\n", name+":", info.Components[name])
+	}
+}
+
+func printVersionUsage() {
+	fmt.Println("Usage: spr version [-json]")
+	fmt.Println("")
+	fmt.Println("Print the commit, build date and component versions of this build.")
+	fmt.Println("The same info is stamped as \"generator\" into diff.json, ai-analysis.json,")
+	fmt.Println("run.json and the other artifacts spr writes.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -json                  Print as JSON")
+	fmt.Println("  -help                  Show this help message")
+}
@@ -0,0 +1,361 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/fsnotify/fsnotify"
+)
+
+// runWatchCommand re-runs analysis whenever package.json or the lockfile
+// changes, analyzing only direct dependencies that were added or changed
+// version since the last successful analysis. Changes are picked up with
+// filesystem notifications, or by polling where those are unavailable.
+func runWatchCommand(cfg *Config, args []string) {
+	packageJSONPath := cfg.PackageJSONPath
+	lockfilePath := cfg.LockfilePath
+	interval := 2 * time.Second
+	initial := false
+
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-package":
+			if i+1 < len(args) {
+				packageJSONPath = args[i+1]
+				i++
+			}
+		case "-lockfile":
+			if i+1 < len(args) {
+				lockfilePath = args[i+1]
+				i++
+			}
+		case "-output":
+			if i+1 < len(args) {
+				cfg.OutputDir = args[i+1]
+				i++
+			}
+		case "-interval":
+			if i+1 < len(args) {
+				if secs, err := strconv.ParseFloat(args[i+1], 64); err == nil && secs > 0 {
+					interval = time.Duration(secs * float64(time.Second))
+				}
+				i++
+			}
+		case "-initial":
+			initial = true
+		case "-help":
+			printWatchUsage()
+			os.Exit(0)
+		}
+	}
+
+	keyMode, err := aggregate.ParseKeyMode(cfg.ProcessKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid PROCESS_KEY: %v\n", err)
+		os.Exit(1)
+	}
+
+	logging.Setup(cfg.LogFormat)
+	runLogger := slog.Default().With(logging.KeyRunID, logging.NewRunID())
+
+	target := newWatchTarget(packageJSONPath, lockfilePath)
+	packageJSONPath, lockfilePath, ok := target.sources()
+	if !ok {
+		fmt.Fprintln(os.Stderr, "Error: no package.json or package-lock.json to watch")
+		printWatchUsage()
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	_, graph, err := loadDependencyGraph(packageJSONPath, lockfilePath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	analyzed := make(map[string]string)
+	if initial {
+		// Every dependency counts as new on the first pass
+		analyzeChanges(ctx, cfg, graph, analyzed, keyMode, runLogger)
+	} else {
+		analyzed = directDependencyVersions(graph)
+	}
+
+	fmt.Printf("\nWatching %s for dependency changes (Ctrl-C to stop)\n", target.dir())
+	target.watch(ctx, interval, func(packageJSONPath, lockfilePath string) {
+		fmt.Printf("\n[%s] Change detected, reloading dependency graph\n", time.Now().Format(time.TimeOnly))
+		_, graph, err := loadDependencyGraph(packageJSONPath, lockfilePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return
+		}
+		analyzeChanges(ctx, cfg, graph, analyzed, keyMode, runLogger)
+	})
+	fmt.Println("\nStopped watching")
+}
+
+// analyzeChanges analyzes the direct dependencies of graph that are not in
+// analyzed at the same version, prints their verdicts and records the ones
+// that finished. Removed dependencies are dropped from analyzed.
+func analyzeChanges(ctx context.Context, cfg *Config, graph *models.DependencyGraph, analyzed map[string]string, keyMode aggregate.KeyMode, logger *slog.Logger) {
+	current := directDependencyVersions(graph)
+	for name := range analyzed {
+		if _, ok := current[name]; !ok {
+			fmt.Printf("  - %s removed\n", name)
+			delete(analyzed, name)
+		}
+	}
+
+	var changed []models.Package
+	for name, version := range current {
+		if analyzed[name] == version {
+			continue
+		}
+		if old, ok := analyzed[name]; ok {
+			fmt.Printf("  ~ %s %s -> %s\n", name, old, version)
+		} else {
+			fmt.Printf("  + %s@%s\n", name, version)
+		}
+		changed = append(changed, models.Package{Name: name, Version: version})
+	}
+	if len(changed) == 0 {
+		fmt.Println("No new or upgraded direct dependencies")
+		return
+	}
+	sort.Slice(changed, func(i, j int) bool { return changed[i].Name < changed[j].Name })
+
+	fmt.Printf("Analyzing %d changed dependencies...\n", len(changed))
+	results, err := analyzeGraph(ctx, cfg, graph, changed, keyMode, logger, true)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Analysis failed: %v\n", err)
+		if len(results) == 0 {
+			return
+		}
+	}
+
+	verdicts, verr := orchestrator.LoadVerdicts(cfg.OutputDir, changed)
+	if verr != nil {
+		fmt.Fprintf(os.Stderr, "Error loading verdicts: %v\n", verr)
+	}
+	fmt.Println("\nVerdicts:")
+	for _, result := range results {
+		pkg := result.Package
+		key := pkg.Name + "@" + pkg.Version
+		assessment, ok := verdicts[key]
+		switch {
+		case result.Error != nil:
+			fmt.Printf("  %s: ERROR (%v)\n", key, result.Error)
+			continue
+		case !ok:
+			fmt.Printf("  %s: no result\n", key)
+			continue
+		case assessment == nil:
+			fmt.Printf("  %s: SAFE (no anomalous behavior)\n", key)
+		case assessment.IsMalicious:
+			fmt.Printf("  %s: MALICIOUS (confidence %.2f)\n", key, assessment.Confidence)
+		default:
+			fmt.Printf("  %s: SAFE (confidence %.2f)\n", key, assessment.Confidence)
+		}
+		analyzed[pkg.Name] = pkg.Version
+	}
+}
+
+// directDependencyVersions maps each direct dependency to its resolved version
+func directDependencyVersions(graph *models.DependencyGraph) map[string]string {
+	deps := make(map[string]string)
+	for _, dep := range graph.GetDirectDependencies() {
+		deps[dep.Name] = dep.Version
+	}
+	return deps
+}
+
+// watchTarget is the package.json and lockfile of one project. Both paths are
+// watched whether or not they exist yet, so a lockfile created after startup
+// (the first npm install) is picked up and used from then on.
+type watchTarget struct {
+	packageJSON string
+	lockfile    string
+}
+
+// newWatchTarget defaults to package.json in the current directory and the
+// lockfile next to package.json, or package.json next to the lockfile
+func newWatchTarget(packageJSONPath, lockfilePath string) watchTarget {
+	if packageJSONPath == "" && lockfilePath == "" {
+		packageJSONPath = "package.json"
+	}
+	if lockfilePath == "" {
+		lockfilePath = filepath.Join(filepath.Dir(packageJSONPath), "package-lock.json")
+	}
+	if packageJSONPath == "" {
+		packageJSONPath = filepath.Join(filepath.Dir(lockfilePath), "package.json")
+	}
+	return watchTarget{packageJSON: packageJSONPath, lockfile: lockfilePath}
+}
+
+// dir is the project directory being watched
+func (w watchTarget) dir() string {
+	return filepath.Dir(w.packageJSON)
+}
+
+// sources returns the files to load the dependency graph from right now,
+// empty for ones that don't exist. ok is false if neither does.
+func (w watchTarget) sources() (packageJSONPath, lockfilePath string, ok bool) {
+	if _, err := os.Stat(w.packageJSON); err == nil {
+		packageJSONPath = w.packageJSON
+	}
+	if _, err := os.Stat(w.lockfile); err == nil {
+		lockfilePath = w.lockfile
+	}
+	return packageJSONPath, lockfilePath, packageJSONPath != "" || lockfilePath != ""
+}
+
+// watch calls onChange with the current sources whenever either file is
+// created, modified or removed, once writes have settled for interval, until
+// ctx is done. The directories holding the files are watched with filesystem
+// notifications; if those can't be set up (e.g. inotify limits reached), the
+// files are polled every interval instead.
+func (w watchTarget) watch(ctx context.Context, interval time.Duration, onChange func(packageJSONPath, lockfilePath string)) {
+	watcher, err := w.notifier()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Filesystem notifications unavailable (%v), polling every %s\n", err, interval)
+		w.poll(ctx, interval, onChange)
+		return
+	}
+	defer watcher.Close()
+
+	files := []string{w.packageJSON, w.lockfile}
+	watched := map[string]bool{filepath.Clean(w.packageJSON): true, filepath.Clean(w.lockfile): true}
+	fingerprint := fingerprintFiles(files)
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if watched[filepath.Clean(event.Name)] {
+				// Wait for writes to settle (npm install rewrites both files)
+				settled = time.After(interval)
+			}
+			continue
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			fmt.Fprintf(os.Stderr, "Watch error: %v\n", err)
+			continue
+		case <-settled:
+			settled = nil
+		}
+
+		// Events also fire for writes that leave the contents as they were
+		if current := fingerprintFiles(files); current != fingerprint {
+			fingerprint = current
+			if packageJSONPath, lockfilePath, ok := w.sources(); ok {
+				onChange(packageJSONPath, lockfilePath)
+			}
+		}
+	}
+}
+
+// notifier watches the directories holding the files. Directories rather
+// than the files, so files that are created later or replaced by a rename
+// are seen.
+func (w watchTarget) notifier() (*fsnotify.Watcher, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	for _, dir := range []string{filepath.Dir(w.packageJSON), filepath.Dir(w.lockfile)} {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, err
+		}
+	}
+	return watcher, nil
+}
+
+// poll checks the files every interval, for when notifications are
+// unavailable, and otherwise behaves like watch
+func (w watchTarget) poll(ctx context.Context, interval time.Duration, onChange func(packageJSONPath, lockfilePath string)) {
+	files := []string{w.packageJSON, w.lockfile}
+	fingerprint := fingerprintFiles(files)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if fingerprintFiles(files) == fingerprint {
+			continue
+		}
+		// Wait for writes to settle (npm install rewrites both files)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+		fingerprint = fingerprintFiles(files)
+
+		if packageJSONPath, lockfilePath, ok := w.sources(); ok {
+			onChange(packageJSONPath, lockfilePath)
+		}
+	}
+}
+
+// fingerprintFiles hashes the contents of files, telling a missing file
+// apart from an empty one
+func fingerprintFiles(files []string) [sha256.Size]byte {
+	h := sha256.New()
+	for _, path := range files {
+		h.Write([]byte(path))
+		data, err := os.ReadFile(path)
+		if err != nil {
+			h.Write([]byte{0})
+			continue
+		}
+		h.Write([]byte{1})
+		h.Write(data)
+	}
+	var sum [sha256.Size]byte
+	copy(sum[:], h.Sum(nil))
+	return sum
+}
+
+func printWatchUsage() {
+	fmt.Println("Usage: spr watch [options]")
+	fmt.Println("")
+	fmt.Println("Watches package.json and package-lock.json, including a lockfile created later, and")
+	fmt.Println("analyzes direct dependencies that were added or upgraded whenever they change. Uses")
+	fmt.Println("the same registry, GitHub and analysis settings as spr check; passing packages are")
+	fmt.Println("promoted to the safe registry.")
+	fmt.Println("")
+	fmt.Println("Options:")
+	fmt.Println("  -package <path>        Path to package.json (default: ./package.json)")
+	fmt.Println("  -lockfile <path>       Path to package-lock.json (default: next to package.json)")
+	fmt.Println("  -output <dir>          Output directory for artifacts (default: ./analysis-results)")
+	fmt.Println("  -interval <seconds>    How long to let writes settle before analyzing, and how often to check")
+	fmt.Println("                         for changes where filesystem notifications are unavailable (default: 2)")
+	fmt.Println("  -initial               Analyze all direct dependencies on start instead of only later changes")
+	fmt.Println("  -help                  Show this help message")
+}
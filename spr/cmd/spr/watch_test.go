@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewWatchTarget(t *testing.T) {
+	w := newWatchTarget("", "")
+	assert.Equal(t, watchTarget{packageJSON: "package.json", lockfile: "package-lock.json"}, w)
+
+	w = newWatchTarget("app/package.json", "")
+	assert.Equal(t, filepath.Join("app", "package-lock.json"), w.lockfile)
+	assert.Equal(t, "app", w.dir())
+
+	w = newWatchTarget("", "app/package-lock.json")
+	assert.Equal(t, filepath.Join("app", "package.json"), w.packageJSON)
+}
+
+func TestWatchTargetSources(t *testing.T) {
+	dir := t.TempDir()
+	w := newWatchTarget(filepath.Join(dir, "package.json"), "")
+
+	_, _, ok := w.sources()
+	assert.False(t, ok, "nothing to load yet")
+
+	require.NoError(t, os.WriteFile(w.packageJSON, []byte(`{}`), 0o644))
+	pkg, lock, ok := w.sources()
+	require.True(t, ok)
+	assert.Equal(t, w.packageJSON, pkg)
+	assert.Empty(t, lock, "a missing lockfile isn't passed on")
+}
+
+func TestWatchPicksUpNewLockfile(t *testing.T) {
+	t.Run("notifications", func(t *testing.T) {
+		testPicksUpNewLockfile(t, watchTarget.watch)
+	})
+	t.Run("polling", func(t *testing.T) {
+		testPicksUpNewLockfile(t, watchTarget.poll)
+	})
+}
+
+func testPicksUpNewLockfile(t *testing.T, watch func(watchTarget, context.Context, time.Duration, func(string, string))) {
+	dir := t.TempDir()
+	w := newWatchTarget(filepath.Join(dir, "package.json"), "")
+	require.NoError(t, os.WriteFile(w.packageJSON, []byte(`{"dependencies":{}}`), 0o644))
+
+	ctx, cancel := context.WithCancel(t.Context())
+	defer cancel()
+
+	changes := make(chan string, 1)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		watch(w, ctx, 10*time.Millisecond, func(_, lockfilePath string) {
+			changes <- lockfilePath
+		})
+	}()
+
+	// The lockfile didn't exist when watching started
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, os.WriteFile(w.lockfile, []byte(`{"lockfileVersion":3}`), 0o644))
+
+	select {
+	case lockfilePath := <-changes:
+		assert.Equal(t, w.lockfile, lockfilePath)
+	case <-time.After(5 * time.Second):
+		t.Fatal("lockfile creation was not detected")
+	}
+	cancel()
+	<-done
+}
+
+func TestFingerprintFiles(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "package-lock.json")
+
+	missing := fingerprintFiles([]string{path})
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	empty := fingerprintFiles([]string{path})
+	assert.NotEqual(t, missing, empty, "creating an empty file is a change")
+
+	require.NoError(t, os.WriteFile(path, []byte("{}"), 0o644))
+	assert.NotEqual(t, empty, fingerprintFiles([]string{path}))
+}
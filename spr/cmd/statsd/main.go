@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/statsd"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+)
+
+// Config holds all environment configuration
+type Config struct {
+	Port string
+
+	// MongoDB holding one collection per trace; when unset, traces are kept
+	// as one JSONL file per collection in DataDir instead
+	MongoURI string
+	DBName   string
+
+	// Directory holding one JSONL file per trace collection
+	DataDir string
+
+	// Bearer token required by every endpoint but /health. Starting without
+	// one requires NoAuth, so an exposed service isn't open by accident.
+	Token  string
+	NoAuth bool
+
+	// Log output format: text or json
+	LogFormat string
+}
+
+func loadConfig() *Config {
+	// Load .env file if it exists
+	_ = godotenv.Load()
+
+	return &Config{
+		Port:      getEnv("STATSD_PORT", "8001"),
+		MongoURI:  os.Getenv("MONGO_URI"),
+		DBName:    getEnv("DB_NAME", "tracee_analysis"),
+		DataDir:   getEnv("STATSD_DATA_DIR", "./statsd-data"),
+		Token:     os.Getenv("STATSD_TOKEN"),
+		NoAuth:    getEnvBool("STATSD_NO_AUTH", false),
+		LogFormat: getEnv("LOG_FORMAT", "text"),
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
+func main() {
+	config := loadConfig()
+	logging.Setup(config.LogFormat)
+
+	if config.Token == "" && !config.NoAuth {
+		log.Fatal("STATSD_TOKEN is not set (set STATSD_NO_AUTH=true to serve without authentication)")
+	}
+	if config.Token == "" {
+		log.Printf("Warning: serving without authentication")
+	}
+
+	var store statsd.Store
+	location := config.DataDir
+	if config.MongoURI != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		mongoStore, err := statsd.NewMongoStore(ctx, config.MongoURI, config.DBName)
+		cancel()
+		if err != nil {
+			log.Fatalf("Failed to open store: %v", err)
+		}
+		defer mongoStore.Close(context.Background())
+		store, location = mongoStore, "MongoDB database "+config.DBName
+	} else {
+		dirStore, err := statsd.NewDirStore(config.DataDir)
+		if err != nil {
+			log.Fatalf("Failed to open store: %v", err)
+		}
+		store = dirStore
+	}
+
+	log.Printf("%s: stats service starting on port %s (data in %s)", version.Get(), config.Port, location)
+	if err := http.ListenAndServe(":"+config.Port, statsd.NewServer(store, config.Token).Handler()); err != nil {
+		log.Fatalf("Server failed: %v", err)
+	}
+}
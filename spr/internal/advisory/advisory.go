@@ -0,0 +1,239 @@
+// Package advisory drafts security advisories for packages flagged as
+// malicious, in Markdown for humans and OSV JSON for vulnerability databases
+package advisory
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+)
+
+// File names written by Draft.Write
+const (
+	MarkdownFile = "advisory.md"
+	OSVFile      = "advisory.osv.json"
+)
+
+// credentialPaths are file path fragments whose access suggests secrets were
+// exposed and should be rotated
+var credentialPaths = []string{".npmrc", ".aws", ".ssh", ".env", ".git-credentials", ".docker/config.json", ".kube/config", ".netrc"}
+
+// IOCs are indicators of compromise observed beyond the baseline
+type IOCs struct {
+	Domains  []string `json:"domains,omitempty"`
+	IPs      []string `json:"ips,omitempty"`
+	URLs     []string `json:"urls,omitempty"`
+	Commands []string `json:"commands,omitempty"`
+	Files    []string `json:"files,omitempty"`
+}
+
+// Empty reports whether no IOCs were found
+func (i IOCs) Empty() bool {
+	return len(i.Domains)+len(i.IPs)+len(i.URLs)+len(i.Commands)+len(i.Files) == 0
+}
+
+// Draft is an advisory ready for review before submission to npm security or
+// an internal tracker
+type Draft struct {
+	Package     string
+	Versions    []string
+	Assessment  *analysis.SecurityAssessment
+	IOCs        IOCs
+	Conditional []aggregate.ConditionalBehavior // Behavior gated on the environment
+	Replacement string                          // Sibling version analyzed clean, if any
+	GeneratedAt time.Time
+}
+
+// New drafts an advisory from an assessment and the behavioral diff it was
+// based on. diff may be nil when it is unavailable.
+func New(name, version string, assessment *analysis.SecurityAssessment, diff *aggregate.DedupedProcessStats) *Draft {
+	d := &Draft{
+		Package:     name,
+		Versions:    []string{version},
+		Assessment:  assessment,
+		GeneratedAt: time.Now().UTC(),
+	}
+	if diff != nil {
+		d.IOCs = ExtractIOCs(diff)
+		d.Conditional = diff.EnvironmentConditional
+	}
+	return d
+}
+
+// ExtractIOCs collects the network, command and file activity left in a diff
+// after baseline removal, including activity seen under env variants only
+func ExtractIOCs(diff *aggregate.DedupedProcessStats) IOCs {
+	domains := make(map[string]bool)
+	ips := make(map[string]bool)
+	urls := make(map[string]bool)
+	commands := make(map[string]bool)
+	files := make(map[string]bool)
+
+	var collect func(d *aggregate.DedupedProcessStats)
+	collect = func(d *aggregate.DedupedProcessStats) {
+		for _, proc := range d.PerProcess {
+			for domain := range proc.NetworkActivity.DNSRecords {
+				domains[domain] = true
+			}
+			for ip := range proc.NetworkActivity.IPs {
+				ips[ip] = true
+			}
+			for cmd := range proc.ExecutedCommands {
+				commands[cmd] = true
+			}
+			for file := range proc.FileAccess {
+				files[file] = true
+			}
+			for file := range proc.FileWrites {
+				files[file] = true
+			}
+		}
+		if d.HTTPActivity != nil {
+			for host := range d.HTTPActivity.Hosts {
+				domains[host] = true
+			}
+			for _, req := range d.HTTPActivity.Requests {
+				urls[req.URL] = true
+			}
+		}
+		for _, variant := range d.Variants {
+			collect(variant)
+		}
+	}
+	collect(diff)
+
+	return IOCs{
+		Domains:  sortedKeys(domains),
+		IPs:      sortedKeys(ips),
+		URLs:     sortedKeys(urls),
+		Commands: sortedKeys(commands),
+		Files:    sortedKeys(files),
+	}
+}
+
+// RecommendedActions returns remediation steps tailored to the observed IOCs
+func (d *Draft) RecommendedActions() []string {
+	remove := fmt.Sprintf("Remove %s from all projects and lockfiles, or pin to a version released before %s and verified clean.", d.Package, strings.Join(d.Versions, ", "))
+	if d.Replacement != "" {
+		remove = fmt.Sprintf("Replace %s with %s@%s, which was analyzed and found clean: pin it in dependencies, and for transitive installs add `\"overrides\": {\"%s\": \"%s\"}` to package.json.", d.Package, d.Package, d.Replacement, d.Package, d.Replacement)
+	}
+	actions := []string{
+		remove,
+		"Treat any machine or CI runner that installed an affected version as compromised.",
+	}
+
+	if d.touchesCredentials() {
+		actions = append(actions, "Rotate every credential reachable from affected environments (npm tokens, cloud keys, SSH keys, .env secrets).")
+	}
+	if len(d.IOCs.Domains)+len(d.IOCs.IPs) > 0 {
+		actions = append(actions, "Block the listed domains and IPs at egress and search network logs for past connections to them.")
+	}
+	if len(d.Conditional) > 0 {
+		actions = append(actions, "Re-test under CI and production-like environments: some behavior only triggers under specific environment settings.")
+	}
+
+	return append(actions, "Report the package to npm security (https://www.npmjs.com/support) so it can be taken down.")
+}
+
+func (d *Draft) touchesCredentials() bool {
+	for _, file := range d.IOCs.Files {
+		for _, fragment := range credentialPaths {
+			if strings.Contains(file, fragment) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Markdown renders the advisory for human review
+func (d *Draft) Markdown() string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "# DRAFT: Malicious code in %s (npm)\n\n", d.Package)
+	fmt.Fprintf(&sb, "_Generated by %s on %s. Review before submitting._\n\n", version.Get(), d.GeneratedAt.Format(time.RFC3339))
+
+	sb.WriteString("## Affected package\n\n")
+	fmt.Fprintf(&sb, "- **Ecosystem:** npm\n- **Package:** `%s`\n- **Versions:** %s\n", d.Package, "`"+strings.Join(d.Versions, "`, `")+"`")
+	if d.Assessment != nil {
+		fmt.Fprintf(&sb, "- **Confidence:** %.2f\n", d.Assessment.Confidence)
+	}
+
+	if d.Assessment != nil && d.Assessment.Justification != "" {
+		sb.WriteString("\n## Summary\n\n")
+		sb.WriteString(d.Assessment.Justification)
+		sb.WriteString("\n")
+	}
+
+	if d.Assessment != nil && len(d.Assessment.Indicators) > 0 {
+		sb.WriteString("\n## Indicators\n\n")
+		for _, indicator := range d.Assessment.Indicators {
+			fmt.Fprintf(&sb, "- %s\n", indicator)
+		}
+	}
+
+	if !d.IOCs.Empty() {
+		sb.WriteString("\n## Indicators of compromise\n")
+		writeIOCList(&sb, "Domains", d.IOCs.Domains)
+		writeIOCList(&sb, "IP addresses", d.IOCs.IPs)
+		writeIOCList(&sb, "URLs", d.IOCs.URLs)
+		writeIOCList(&sb, "Executed commands", d.IOCs.Commands)
+		writeIOCList(&sb, "Files accessed", d.IOCs.Files)
+	}
+
+	if len(d.Conditional) > 0 {
+		sb.WriteString("\n## Environment-conditional behavior\n\n")
+		for _, c := range d.Conditional {
+			fmt.Fprintf(&sb, "- only under `%s`: %s `%s` (by %s)\n", c.Variant, c.Kind, c.Value, c.Process)
+		}
+	}
+
+	sb.WriteString("\n## Recommended actions\n\n")
+	for i, action := range d.RecommendedActions() {
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, action)
+	}
+
+	return sb.String()
+}
+
+func writeIOCList(sb *strings.Builder, title string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	fmt.Fprintf(sb, "\n### %s\n\n", title)
+	for _, v := range values {
+		fmt.Fprintf(sb, "- `%s`\n", v)
+	}
+}
+
+// Write saves the Markdown and OSV renderings into dir
+func (d *Draft) Write(dir string) error {
+	if err := os.WriteFile(filepath.Join(dir, MarkdownFile), []byte(d.Markdown()), 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", MarkdownFile, err)
+	}
+
+	data, err := d.OSV().Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, OSVFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", OSVFile, err)
+	}
+	return nil
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
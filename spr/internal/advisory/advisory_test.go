@@ -0,0 +1,93 @@
+package advisory
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testDiff() *aggregate.DedupedProcessStats {
+	return &aggregate.DedupedProcessStats{
+		PerProcess: map[string]*aggregate.ProcessSummary{
+			"node": {
+				FileAccess:       map[string]int{"/root/.npmrc": 1},
+				ExecutedCommands: map[string]int{"curl": 1},
+				NetworkActivity: aggregate.NetworkActivity{
+					IPs:        map[string]int{"203.0.113.7": 2},
+					DNSRecords: map[string]int{"exfil.example.com": 1},
+				},
+			},
+		},
+		Variants: map[string]*aggregate.DedupedProcessStats{
+			"ci": {PerProcess: map[string]*aggregate.ProcessSummary{
+				"sh": {NetworkActivity: aggregate.NetworkActivity{DNSRecords: map[string]int{"ci.example.net": 1}}},
+			}},
+		},
+	}
+}
+
+func TestExtractIOCs(t *testing.T) {
+	iocs := ExtractIOCs(testDiff())
+
+	assert.Equal(t, []string{"ci.example.net", "exfil.example.com"}, iocs.Domains)
+	assert.Equal(t, []string{"203.0.113.7"}, iocs.IPs)
+	assert.Equal(t, []string{"curl"}, iocs.Commands)
+	assert.Equal(t, []string{"/root/.npmrc"}, iocs.Files)
+}
+
+func TestDraftWrite(t *testing.T) {
+	assessment := &analysis.SecurityAssessment{
+		IsMalicious:   true,
+		Confidence:    0.9,
+		Justification: "Reads .npmrc and sends it to exfil.example.com",
+		Indicators:    []string{"credential theft"},
+	}
+	dir := t.TempDir()
+
+	draft := New("@evil/pkg", "1.0.0", assessment, testDiff())
+	require.NoError(t, draft.Write(dir))
+
+	md, err := os.ReadFile(filepath.Join(dir, MarkdownFile))
+	require.NoError(t, err)
+	assert.Contains(t, string(md), "# DRAFT: Malicious code in @evil/pkg (npm)")
+	assert.Contains(t, string(md), "`exfil.example.com`")
+	assert.Contains(t, string(md), "Rotate every credential")
+
+	data, err := os.ReadFile(filepath.Join(dir, OSVFile))
+	require.NoError(t, err)
+	var osv OSV
+	require.NoError(t, json.Unmarshal(data, &osv))
+	assert.Equal(t, "SPR-DRAFT-evil-pkg", osv.ID)
+	assert.Equal(t, "npm", osv.Affected[0].Package.Ecosystem)
+	assert.Equal(t, "pkg:npm/%40evil/pkg", osv.Affected[0].Package.PURL)
+	assert.Equal(t, []string{"1.0.0"}, osv.Affected[0].Versions)
+	require.NotNil(t, osv.DatabaseSpecific)
+	assert.Equal(t, []string{"203.0.113.7"}, osv.DatabaseSpecific.IOCs.IPs)
+}
+
+func TestRecommendedActionsWithoutIOCs(t *testing.T) {
+	draft := New("evil", "1.0.0", &analysis.SecurityAssessment{IsMalicious: true}, nil)
+	actions := draft.RecommendedActions()
+
+	assert.Len(t, actions, 3)
+	for _, action := range actions {
+		assert.NotContains(t, action, "Rotate")
+		assert.NotContains(t, action, "egress")
+	}
+}
+
+func TestRecommendedActionsReplacement(t *testing.T) {
+	draft := New("evil", "1.2.0", &analysis.SecurityAssessment{IsMalicious: true}, nil)
+	draft.Replacement = "1.1.0"
+
+	actions := draft.RecommendedActions()
+	assert.Contains(t, actions[0], "evil@1.1.0")
+	assert.Contains(t, actions[0], `"overrides": {"evil": "1.1.0"}`)
+	assert.Contains(t, draft.Markdown(), "evil@1.1.0")
+}
@@ -0,0 +1,100 @@
+package advisory
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+)
+
+// OSVSchemaVersion is the OSV schema version produced
+const OSVSchemaVersion = "1.6.0"
+
+// OSV is an advisory in the Open Source Vulnerability format
+// (https://ossf.github.io/osv-schema/), as used by the OpenSSF malicious
+// packages repository
+type OSV struct {
+	SchemaVersion    string           `json:"schema_version"`
+	ID               string           `json:"id"`
+	Modified         string           `json:"modified"`
+	Published        string           `json:"published"`
+	Summary          string           `json:"summary"`
+	Details          string           `json:"details"`
+	Affected         []OSVAffected    `json:"affected"`
+	DatabaseSpecific *OSVDatabaseInfo `json:"database_specific,omitempty"`
+}
+
+// OSVAffected lists the affected versions of one package
+type OSVAffected struct {
+	Package  OSVPackage `json:"package"`
+	Versions []string   `json:"versions"`
+}
+
+// OSVPackage identifies a package
+type OSVPackage struct {
+	Ecosystem string `json:"ecosystem"`
+	Name      string `json:"name"`
+	PURL      string `json:"purl,omitempty"`
+}
+
+// OSVDatabaseInfo carries spr-specific evidence
+type OSVDatabaseInfo struct {
+	Confidence float64       `json:"confidence"`
+	Indicators []string      `json:"indicators,omitempty"`
+	IOCs       IOCs          `json:"iocs"`
+	Generator  *version.Info `json:"generator,omitempty"`
+}
+
+// idUnsafe matches characters not allowed in the draft ID
+var idUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// OSV renders the advisory in OSV format. The ID is a placeholder to be
+// replaced by the receiving database.
+func (d *Draft) OSV() *OSV {
+	ts := d.GeneratedAt.Format(time.RFC3339)
+
+	details := fmt.Sprintf("%s was flagged as malicious by spr behavioral analysis.", d.Package)
+	if d.Assessment != nil && d.Assessment.Justification != "" {
+		details += "\n\n" + d.Assessment.Justification
+	}
+	details += "\n\nRecommended actions:\n"
+	for _, action := range d.RecommendedActions() {
+		details += "- " + action + "\n"
+	}
+
+	osv := &OSV{
+		SchemaVersion: OSVSchemaVersion,
+		ID:            "SPR-DRAFT-" + strings.Trim(idUnsafe.ReplaceAllString(d.Package, "-"), "-"),
+		Modified:      ts,
+		Published:     ts,
+		Summary:       fmt.Sprintf("Malicious code in %s (npm)", d.Package),
+		Details:       details,
+		Affected: []OSVAffected{{
+			Package:  OSVPackage{Ecosystem: "npm", Name: d.Package, PURL: "pkg:npm/" + strings.Replace(d.Package, "@", "%40", 1)},
+			Versions: d.Versions,
+		}},
+	}
+
+	if d.Assessment != nil {
+		osv.DatabaseSpecific = &OSVDatabaseInfo{
+			Confidence: d.Assessment.Confidence,
+			Indicators: d.Assessment.Indicators,
+			IOCs:       d.IOCs,
+			Generator:  version.Stamp(),
+		}
+	}
+
+	return osv
+}
+
+// Marshal encodes the advisory as indented JSON
+func (o *OSV) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(o, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal OSV advisory: %w", err)
+	}
+	return data, nil
+}
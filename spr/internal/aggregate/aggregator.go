@@ -1,8 +1,9 @@
 package aggregate
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -14,9 +15,18 @@ type Aggregator struct {
 	totalEvents      int
 	syscallProfile   map[string]int
 	fileAccess       map[string]int
+	fileWrites       map[string]int
+	fileCreates      map[string]int
 	executedCommands map[string]int
+	commandLines     map[string]int
+	env              *EnvAccess
 	ips              map[string]int
 	dnsRecords       map[string]int
+	urls             map[string]int
+	traffic          *trafficCounter
+	sockets          map[socketKey]string
+	maxLine          int // Longest line read; 0 means DefaultMaxLineSize
+	oversized        int // Lines skipped for exceeding maxLine
 }
 
 // NewAggregator creates a new Aggregator instance
@@ -24,9 +34,16 @@ func NewAggregator() *Aggregator {
 	return &Aggregator{
 		syscallProfile:   make(map[string]int),
 		fileAccess:       make(map[string]int),
+		fileWrites:       make(map[string]int),
+		fileCreates:      make(map[string]int),
 		executedCommands: make(map[string]int),
+		commandLines:     make(map[string]int),
+		env:              newEnvAccess(),
 		ips:              make(map[string]int),
 		dnsRecords:       make(map[string]int),
+		urls:             make(map[string]int),
+		traffic:          newTrafficCounter(),
+		sockets:          make(map[socketKey]string),
 	}
 }
 
@@ -41,27 +58,43 @@ func (a *Aggregator) ProcessFile(filename string, collection string) (*Stats, er
 	return a.ProcessReader(file, collection)
 }
 
-// ProcessReader reads from an io.Reader and aggregates statistics
+// SetMaxLineSize sets the longest line read, in bytes; longer lines are
+// skipped and counted in OversizedLines. Zero restores DefaultMaxLineSize.
+func (a *Aggregator) SetMaxLineSize(n int) {
+	a.maxLine = n
+}
+
+// OversizedLines returns how many lines were skipped for their size
+func (a *Aggregator) OversizedLines() int {
+	return a.oversized
+}
+
+// ProcessReader reads from an io.Reader and aggregates statistics. Events
+// are streamed: only the counts are kept, never the input.
 func (a *Aggregator) ProcessReader(reader io.Reader, collection string) (*Stats, error) {
-	scanner := bufio.NewScanner(reader)
+	lines := newLineReader(reader, a.maxLine)
+	defer func() { a.oversized += lines.oversized }()
+	decoder := NewEventDecoder()
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
+	for {
+		line, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		var event TraceeEvent
-		if err := json.Unmarshal([]byte(line), &event); err != nil {
+		event, err := decoder.Decode(line)
+		if err != nil {
 			// Skip invalid JSON lines (matching Python behavior)
 			continue
 		}
 
-		a.processEvent(&event)
-	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input: %w", err)
+		a.processEvent(event)
 	}
 
 	return a.buildStats(collection), nil
@@ -74,12 +107,16 @@ func (a *Aggregator) processEvent(event *TraceeEvent) {
 	switch event.EventName {
 	case "openat":
 		a.processOpenat(event)
-	case "execve":
+	case "execve", "execveat":
 		a.processExecve(event)
 	case "connect":
 		a.processConnect(event)
 	case "net_packet_dns_request":
 		a.processDNS(event)
+	default:
+		if isTrafficEvent(event.EventName) {
+			a.traffic.record(event, a.sockets)
+		}
 	}
 }
 
@@ -91,6 +128,14 @@ func (a *Aggregator) processOpenat(event *TraceeEvent) {
 				// Filter out node_modules paths
 				if !strings.Contains(pathname, "node_modules") {
 					a.fileAccess[pathname]++
+					a.env.recordOpen(pathname)
+					write, create := openMode(event)
+					if write {
+						a.fileWrites[pathname]++
+					}
+					if create {
+						a.fileCreates[pathname]++
+					}
 				}
 			}
 			break
@@ -108,6 +153,15 @@ func (a *Aggregator) processExecve(event *TraceeEvent) {
 			break
 		}
 	}
+	// The pathname alone hides what a command does (curl, but fetching what?)
+	argv := eventArgv(event)
+	if cmdline := commandLine(argv); cmdline != "" {
+		a.commandLines[cmdline]++
+	}
+	for _, u := range ExtractURLs(strings.Join(argv, " ")) {
+		a.urls[u]++
+	}
+	a.env.recordExec(event, argv)
 }
 
 func (a *Aggregator) processConnect(event *TraceeEvent) {
@@ -131,6 +185,7 @@ func (a *Aggregator) processConnect(event *TraceeEvent) {
 						key = fmt.Sprintf("%s:%s", sockAddr.SinAddr, sockAddr.SinPort)
 					}
 					a.ips[key]++
+					recordSocket(a.sockets, event, key)
 				}
 			}
 			break
@@ -146,7 +201,7 @@ func (a *Aggregator) processDNS(event *TraceeEvent) {
 			}
 			if err := json.Unmarshal(arg.Value, &questions); err == nil {
 				for _, q := range questions {
-					a.dnsRecords[q.Query]++
+					a.dnsRecords[NormalizeDomain(q.Query)]++
 				}
 			}
 			break
@@ -160,10 +215,16 @@ func (a *Aggregator) buildStats(collection string) *Stats {
 		TotalEvents:      a.totalEvents,
 		SyscallProfile:   a.syscallProfile,
 		FileAccess:       a.fileAccess,
+		FileWrites:       a.fileWrites,
+		FileCreates:      a.fileCreates,
 		ExecutedCommands: a.executedCommands,
+		CommandLines:     a.commandLines,
+		EnvAccess:        a.env.summary(),
 		NetworkActivity: NetworkActivity{
 			IPs:        a.ips,
 			DNSRecords: a.dnsRecords,
+			URLs:       a.urls,
+			Transfers:  a.traffic.transfers(),
 		},
 		RiskFlags: a.detectRiskFlags(),
 	}
@@ -171,78 +232,18 @@ func (a *Aggregator) buildStats(collection string) *Stats {
 	return stats
 }
 
+// detectRiskFlags flags the whole trace as if it were a single process
 func (a *Aggregator) detectRiskFlags() []string {
-	flags := make(map[string]bool)
-
-	sensitivePaths := []string{
-		"/etc/passwd",
-		"/etc/shadow",
-		"/root",
-		".ssh",
-	}
-
-	shellBinaries := []string{
-		"/bin/sh",
-		"/bin/bash",
-		"sh",
-		"bash",
-	}
-
-	// Check for sensitive file access
-	sensitiveAccessFound := false
-	for path := range a.fileAccess {
-		for _, sensitive := range sensitivePaths {
-			if strings.Contains(path, sensitive) {
-				sensitiveAccessFound = true
-				break
-			}
-		}
-		if sensitiveAccessFound {
-			break
-		}
-	}
-	if sensitiveAccessFound {
-		flags["sensitive_file_access"] = true
-	}
-
-	// Check for shell execution
-	shellSpawned := false
-	for cmd := range a.executedCommands {
-		for _, shell := range shellBinaries {
-			if strings.Contains(cmd, shell) {
-				shellSpawned = true
-				break
-			}
-		}
-		if shellSpawned {
-			break
-		}
-	}
-	if shellSpawned {
-		flags["shell_spawned"] = true
-	}
-
-	// Check for network activity
-	if len(a.ips) > 0 {
-		flags["network_activity"] = true
-	}
-
-	// Check for procfs access
-	procfsAccess := false
-	for path := range a.fileAccess {
-		if strings.Contains(path, "/proc") {
-			procfsAccess = true
-			break
-		}
-	}
-	if procfsAccess {
-		flags["procfs_access"] = true
-	}
-
-	// Convert map to slice
-	result := make([]string, 0, len(flags))
-	for flag := range flags {
-		result = append(result, flag)
-	}
-	return result
+	return (&ProcessSummary{
+		FileAccess:       a.fileAccess,
+		FileWrites:       a.fileWrites,
+		ExecutedCommands: a.executedCommands,
+		CommandLines:     a.commandLines,
+		EnvAccess:        a.env.summary(),
+		NetworkActivity: NetworkActivity{
+			IPs:        a.ips,
+			DNSRecords: a.dnsRecords,
+			URLs:       a.urls,
+		},
+	}).DetectRiskFlags()
 }
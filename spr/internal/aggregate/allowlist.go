@@ -0,0 +1,307 @@
+package aggregate
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// regexPrefix marks a file or command pattern as a regular expression rather
+// than a glob
+const regexPrefix = "re:"
+
+// Allowlist suppresses known-benign activity, e.g. an organization's internal
+// endpoints, that is left in a diff after baseline subtraction, without
+// regenerating baselines. It is loaded from YAML (or JSON):
+//
+//	files:            # globs; * stays within a directory, ** crosses them
+//	  - /opt/corp-agent/**
+//	  - re:^/var/log/corp-[0-9]+\.log$   # re: marks a regular expression
+//	commands:         # same syntax as files, matched against executable paths
+//	  - /usr/local/bin/corp-*
+//	ips:              # addresses or CIDR ranges
+//	  - 10.0.0.0/8
+//	domains:          # the domain and its subdomains
+//	  - corp.example.com
+//
+// Domains and IPs also suppress proxy-captured HTTP requests, command-line
+// URLs and URL indicators pointing at them.
+type Allowlist struct {
+	Files    []string `yaml:"files" json:"files"`
+	Commands []string `yaml:"commands" json:"commands"`
+	IPs      []string `yaml:"ips" json:"ips"`
+	Domains  []string `yaml:"domains" json:"domains"`
+
+	files    []*regexp.Regexp
+	commands []*regexp.Regexp
+	prefixes []netip.Prefix
+	domains  []string
+}
+
+// LoadAllowlist reads and compiles an allowlist file
+func LoadAllowlist(path string) (*Allowlist, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read allowlist: %w", err)
+	}
+	return ParseAllowlist(data)
+}
+
+// ParseAllowlist parses and compiles an allowlist document
+func ParseAllowlist(data []byte) (*Allowlist, error) {
+	var a Allowlist
+	if err := yaml.Unmarshal(data, &a); err != nil {
+		return nil, fmt.Errorf("failed to parse allowlist: %w", err)
+	}
+
+	var err error
+	if a.files, err = compilePatterns(a.Files); err != nil {
+		return nil, fmt.Errorf("files: %w", err)
+	}
+	if a.commands, err = compilePatterns(a.Commands); err != nil {
+		return nil, fmt.Errorf("commands: %w", err)
+	}
+	for _, s := range a.IPs {
+		prefix, err := parsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("ips: %w", err)
+		}
+		a.prefixes = append(a.prefixes, prefix)
+	}
+	for _, d := range a.Domains {
+		if d = NormalizeDomain(strings.TrimPrefix(strings.TrimSpace(d), "*.")); d != "" {
+			a.domains = append(a.domains, d)
+		}
+	}
+	return &a, nil
+}
+
+// compilePatterns compiles globs and re:-prefixed regular expressions
+func compilePatterns(patterns []string) ([]*regexp.Regexp, error) {
+	var out []*regexp.Regexp
+	for _, p := range patterns {
+		expr, isRegex := strings.CutPrefix(p, regexPrefix)
+		if !isRegex {
+			expr = globToRegexp(p)
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+		out = append(out, re)
+	}
+	return out, nil
+}
+
+// globToRegexp translates a path glob into an anchored regular expression:
+// ** matches anything, * and ? anything but a slash
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// parsePrefix parses a CIDR range or a single address
+func parsePrefix(s string) (netip.Prefix, error) {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+func matchesAny(patterns []*regexp.Regexp, s string) bool {
+	for _, re := range patterns {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsFile reports whether a file path is allowlisted
+func (a *Allowlist) AllowsFile(path string) bool {
+	return matchesAny(a.files, path)
+}
+
+// AllowsCommand reports whether an executable path is allowlisted
+func (a *Allowlist) AllowsCommand(path string) bool {
+	return matchesAny(a.commands, path)
+}
+
+// AllowsIP reports whether an address (optionally with a port) is in an
+// allowlisted range
+func (a *Allowlist) AllowsIP(ip string) bool {
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range a.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowsDomain reports whether a DNS name is an allowlisted domain or one of
+// its subdomains
+func (a *Allowlist) AllowsDomain(name string) bool {
+	name = NormalizeDomain(name)
+	for _, d := range a.domains {
+		if name == d || strings.HasSuffix(name, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowsHost reports whether a URL or HTTP host, optionally with a port, is
+// an allowlisted domain or address
+func (a *Allowlist) allowsHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.Trim(host, "[]")
+	return a.AllowsDomain(host) || a.AllowsIP(host)
+}
+
+// urlHost returns the host of a URL, or "" if it doesn't parse
+func urlHost(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// Apply removes allowlisted activity from a diff in place, counting removed
+// files and commands in its Removed* totals, and dropping processes left
+// without activity. Process summaries are copied before being filtered, as
+// a diff may share them with the target stats. A nil allowlist does nothing.
+func (a *Allowlist) Apply(d *DedupedProcessStats) {
+	if a == nil || d == nil {
+		return
+	}
+
+	for key, proc := range d.PerProcess {
+		filtered := &ProcessSummary{
+			SyscallProfile: proc.SyscallProfile,
+			CommandLines:   proc.CommandLines,
+			EnvAccess:      proc.EnvAccess,
+			SyscallStats:   proc.SyscallStats,
+		}
+		var removed int
+		filtered.FileAccess, removed = filterKeys(proc.FileAccess, a.AllowsFile)
+		d.RemovedFiles += removed
+		// Writes and creates are of files counted above
+		filtered.FileWrites, _ = filterKeys(proc.FileWrites, a.AllowsFile)
+		filtered.FileCreates, _ = filterKeys(proc.FileCreates, a.AllowsFile)
+		filtered.ExecutedCommands, removed = filterKeys(proc.ExecutedCommands, a.AllowsCommand)
+		d.RemovedCommands += removed
+		filtered.NetworkActivity.IPs, _ = filterKeys(proc.NetworkActivity.IPs, a.AllowsIP)
+		filtered.NetworkActivity.DNSRecords, _ = filterKeys(proc.NetworkActivity.DNSRecords, a.AllowsDomain)
+		filtered.NetworkActivity.URLs, _ = filterKeys(proc.NetworkActivity.URLs, func(u string) bool {
+			return a.allowsHost(urlHost(u))
+		})
+		for dest, t := range proc.NetworkActivity.Transfers {
+			if !a.AllowsIP(dest) {
+				if filtered.NetworkActivity.Transfers == nil {
+					filtered.NetworkActivity.Transfers = make(map[string]Transfer)
+				}
+				filtered.NetworkActivity.Transfers[dest] = t
+			}
+		}
+
+		if len(filtered.SyscallProfile) == 0 &&
+			len(filtered.FileAccess) == 0 &&
+			len(filtered.FileWrites) == 0 &&
+			len(filtered.FileCreates) == 0 &&
+			len(filtered.ExecutedCommands) == 0 &&
+			len(filtered.CommandLines) == 0 &&
+			len(filtered.NetworkActivity.IPs) == 0 &&
+			len(filtered.NetworkActivity.DNSRecords) == 0 &&
+			len(filtered.NetworkActivity.URLs) == 0 &&
+			len(filtered.NetworkActivity.Transfers) == 0 &&
+			filtered.EnvAccess == nil {
+			delete(d.PerProcess, key)
+			d.RemovedProcesses++
+			continue
+		}
+		d.PerProcess[key] = filtered
+	}
+	d.CountProcesses = len(d.PerProcess)
+
+	if d.HTTPActivity != nil {
+		activity := &HTTPActivity{
+			Hosts:    make(map[string]int),
+			Requests: make(map[string]*HTTPRequestSummary),
+		}
+		for host, count := range d.HTTPActivity.Hosts {
+			if !a.allowsHost(host) {
+				activity.Hosts[host] = count
+			}
+		}
+		for key, req := range d.HTTPActivity.Requests {
+			if !a.allowsHost(req.Host) {
+				activity.Requests[key] = req
+				activity.TotalRequests += req.Count
+			}
+		}
+		d.HTTPActivity = nil
+		if activity.TotalRequests > 0 {
+			d.HTTPActivity = activity
+		}
+	}
+
+	indicators := d.URLIndicators[:0:0]
+	for _, ind := range d.URLIndicators {
+		if !a.allowsHost(ind.Host) {
+			indicators = append(indicators, ind)
+		}
+	}
+	d.URLIndicators = indicators
+}
+
+// filterKeys returns counts without the keys allowed reports true for, and
+// how many were dropped
+func filterKeys(counts map[string]int, allowed func(string) bool) (map[string]int, int) {
+	out := make(map[string]int, len(counts))
+	removed := 0
+	for k, v := range counts {
+		if allowed(k) {
+			removed++
+			continue
+		}
+		out[k] = v
+	}
+	return out, removed
+}
@@ -0,0 +1,100 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testAllowlist = `
+files:
+  - /opt/corp-agent/**
+  - /etc/corp/*.conf
+  - re:^/var/log/corp-[0-9]+\.log$
+commands:
+  - /usr/local/bin/corp-*
+ips:
+  - 10.0.0.0/8
+  - 203.0.113.7
+domains:
+  - "*.corp.example.com"
+`
+
+func TestAllowlistMatching(t *testing.T) {
+	a, err := ParseAllowlist([]byte(testAllowlist))
+	require.NoError(t, err)
+
+	assert.True(t, a.AllowsFile("/opt/corp-agent/bin/x/y"))
+	assert.True(t, a.AllowsFile("/etc/corp/agent.conf"))
+	assert.False(t, a.AllowsFile("/etc/corp/sub/agent.conf"), "* stays within a directory")
+	assert.True(t, a.AllowsFile("/var/log/corp-42.log"))
+	assert.False(t, a.AllowsFile("/var/log/corp-x.log"))
+
+	assert.True(t, a.AllowsCommand("/usr/local/bin/corp-sync"))
+	assert.False(t, a.AllowsCommand("/usr/bin/curl"))
+
+	assert.True(t, a.AllowsIP("10.1.2.3:443"))
+	assert.True(t, a.AllowsIP("203.0.113.7"))
+	assert.False(t, a.AllowsIP("203.0.113.8"))
+
+	assert.True(t, a.AllowsDomain("corp.example.com."))
+	assert.True(t, a.AllowsDomain("api.CORP.example.com"))
+	assert.False(t, a.AllowsDomain("evilcorp.example.com"))
+
+	_, err = ParseAllowlist([]byte("ips: [not-an-ip]"))
+	assert.Error(t, err)
+	_, err = ParseAllowlist([]byte("files: ['re:[']"))
+	assert.Error(t, err)
+}
+
+func TestAllowlistApply(t *testing.T) {
+	a, err := ParseAllowlist([]byte(testAllowlist))
+	require.NoError(t, err)
+
+	agent := newProcessSummary()
+	agent.FileAccess["/opt/corp-agent/state"] = 3
+	agent.ExecutedCommands["/usr/local/bin/corp-sync"] = 1
+	agent.NetworkActivity.DNSRecords["updates.corp.example.com"] = 2
+	agent.NetworkActivity.IPs["10.0.0.5:443"] = 2
+
+	curl := newProcessSummary()
+	curl.FileAccess["/etc/corp/agent.conf"] = 1
+	curl.FileAccess["/root/.npmrc"] = 1
+	curl.NetworkActivity.DNSRecords["evil.example"] = 1
+	curl.NetworkActivity.URLs["https://updates.corp.example.com/v1"] = 1
+	curl.NetworkActivity.URLs["https://evil.example/x"] = 1
+
+	d := &DedupedProcessStats{
+		PerProcess: map[string]*ProcessSummary{"corp-agent": agent, "curl": curl},
+		HTTPActivity: &HTTPActivity{
+			TotalRequests: 3,
+			Hosts:         map[string]int{"updates.corp.example.com": 2, "evil.example": 1},
+			Requests: map[string]*HTTPRequestSummary{
+				"GET https://updates.corp.example.com/v1": {Host: "updates.corp.example.com", Count: 2},
+				"GET https://evil.example/x":              {Host: "evil.example", Count: 1},
+			},
+		},
+		URLIndicators: []URLIndicator{{URL: "https://updates.corp.example.com/v1", Host: "updates.corp.example.com"}, {URL: "https://evil.example/x", Host: "evil.example"}},
+	}
+	a.Apply(d)
+
+	assert.NotContains(t, d.PerProcess, "corp-agent")
+	assert.Equal(t, 1, d.RemovedProcesses)
+	assert.Equal(t, 1, d.CountProcesses)
+	require.Contains(t, d.PerProcess, "curl")
+	assert.Equal(t, map[string]int{"/root/.npmrc": 1}, d.PerProcess["curl"].FileAccess)
+	assert.Equal(t, map[string]int{"https://evil.example/x": 1}, d.PerProcess["curl"].NetworkActivity.URLs)
+	assert.Equal(t, 2, d.RemovedFiles)
+	assert.Equal(t, 1, d.RemovedCommands)
+	assert.Equal(t, 1, d.HTTPActivity.TotalRequests)
+	assert.Len(t, d.URLIndicators, 1)
+
+	// The original summaries aren't modified
+	assert.Len(t, curl.FileAccess, 2)
+
+	// A nil allowlist leaves diffs alone
+	var none *Allowlist
+	none.Apply(d)
+	assert.Len(t, d.PerProcess, 1)
+}
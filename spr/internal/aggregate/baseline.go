@@ -0,0 +1,155 @@
+package aggregate
+
+import (
+	"fmt"
+	"math"
+)
+
+// CounterStats is the distribution of a counter across baseline sample runs
+type CounterStats struct {
+	Mean     float64 `json:"mean"`
+	Variance float64 `json:"variance"` // Sample variance (n-1)
+}
+
+// StdDev returns the standard deviation of the counter
+func (c CounterStats) StdDev() float64 {
+	return math.Sqrt(c.Variance)
+}
+
+// DefaultZScore is how many standard deviations above the baseline mean a
+// syscall count must be to be kept when the baseline has variance data
+const DefaultZScore = 3.0
+
+// BuildBaseline merges N sample runs of the same known-safe workload into one
+// baseline. Syscall counts are stored as mean and variance per process
+// (SyscallStats), with the rounded mean in SyscallProfile so readers unaware
+// of the statistics still see sensible counts. A process or syscall missing
+// from a sample counts as 0 for that sample. Files, commands and network
+// activity are the union across samples, since Dedup only checks presence.
+func BuildBaseline(collection string, samples []*PerProcessStats) (*PerProcessStats, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("no baseline samples")
+	}
+	mode := samples[0].KeyMode.normalize()
+	for i, s := range samples[1:] {
+		if s.KeyMode.normalize() != mode {
+			return nil, fmt.Errorf("sample %d keyed by %s, expected %s", i+2, s.KeyMode.normalize(), mode)
+		}
+	}
+
+	// Per process, per syscall: the count in each sample
+	counts := make(map[string]map[string][]float64)
+	result := &PerProcessStats{
+		Collection: collection,
+		KeyMode:    samples[0].KeyMode,
+		PerProcess: make(map[string]*ProcessSummary),
+		Samples:    len(samples),
+	}
+
+	for i, sample := range samples {
+		for key, proc := range sample.PerProcess {
+			merged, ok := result.PerProcess[key]
+			if !ok {
+				merged = newProcessSummary()
+				result.PerProcess[key] = merged
+				counts[key] = make(map[string][]float64)
+			}
+			for syscall, n := range proc.SyscallProfile {
+				if counts[key][syscall] == nil {
+					counts[key][syscall] = make([]float64, len(samples))
+				}
+				counts[key][syscall][i] = float64(n)
+			}
+			mergeMax(merged.FileAccess, proc.FileAccess)
+			merged.FileWrites = mergeOptional(merged.FileWrites, proc.FileWrites, mergeMax)
+			merged.FileCreates = mergeOptional(merged.FileCreates, proc.FileCreates, mergeMax)
+			mergeMax(merged.ExecutedCommands, proc.ExecutedCommands)
+			merged.CommandLines = mergeOptional(merged.CommandLines, proc.CommandLines, mergeMax)
+			merged.EnvAccess = mergeEnvAccess(merged.EnvAccess, proc.EnvAccess, mergeMax)
+			mergeMax(merged.NetworkActivity.IPs, proc.NetworkActivity.IPs)
+			mergeMax(merged.NetworkActivity.DNSRecords, proc.NetworkActivity.DNSRecords)
+			mergeMax(merged.NetworkActivity.URLs, proc.NetworkActivity.URLs)
+			merged.NetworkActivity.Transfers = mergeTransfers(merged.NetworkActivity.Transfers, proc.NetworkActivity.Transfers, func(a, b int64) int64 { return max(a, b) })
+		}
+		result.HTTPActivity = mergeHTTPHosts(result.HTTPActivity, sample.HTTPActivity)
+	}
+
+	for key, bySyscall := range counts {
+		proc := result.PerProcess[key]
+		proc.SyscallStats = make(map[string]CounterStats, len(bySyscall))
+		for syscall, values := range bySyscall {
+			stats := counterStats(values)
+			proc.SyscallStats[syscall] = stats
+			proc.SyscallProfile[syscall] = int(math.Round(stats.Mean))
+		}
+	}
+
+	result.CountProcesses = len(result.PerProcess)
+	return result, nil
+}
+
+// counterStats computes the mean and sample variance of values
+func counterStats(values []float64) CounterStats {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+
+	if len(values) < 2 {
+		return CounterStats{Mean: mean}
+	}
+	var sq float64
+	for _, v := range values {
+		sq += (v - mean) * (v - mean)
+	}
+	return CounterStats{Mean: mean, Variance: sq / float64(len(values)-1)}
+}
+
+// mergeMax merges src into dst keeping the larger count for each key
+func mergeMax(dst, src map[string]int) {
+	for k, v := range src {
+		if v > dst[k] {
+			dst[k] = v
+		}
+	}
+}
+
+// mergeOptional merges src into dst, allocating dst on first use so it stays
+// nil when no source recorded the counts (traces predating the field)
+func mergeOptional(dst, src map[string]int, merge func(dst, src map[string]int)) map[string]int {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]int, len(src))
+	}
+	merge(dst, src)
+	return dst
+}
+
+// mergeHTTPHosts adds the hosts of src to dst; Dedup only compares hosts, so
+// requests are not carried over
+func mergeHTTPHosts(dst, src *HTTPActivity) *HTTPActivity {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &HTTPActivity{Hosts: make(map[string]int), Requests: make(map[string]*HTTPRequestSummary)}
+	}
+	mergeMax(dst.Hosts, src.Hosts)
+	return dst
+}
+
+func newProcessSummary() *ProcessSummary {
+	return &ProcessSummary{
+		SyscallProfile:   make(map[string]int),
+		FileAccess:       make(map[string]int),
+		ExecutedCommands: make(map[string]int),
+		NetworkActivity: NetworkActivity{
+			IPs:        make(map[string]int),
+			DNSRecords: make(map[string]int),
+			URLs:       make(map[string]int),
+		},
+	}
+}
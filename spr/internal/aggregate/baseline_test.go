@@ -0,0 +1,59 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func sampleRun(syscalls map[string]int, files ...string) *PerProcessStats {
+	proc := newProcessSummary()
+	proc.SyscallProfile = syscalls
+	for _, f := range files {
+		proc.FileAccess[f] = 1
+	}
+	return &PerProcessStats{KeyMode: KeyAncestry, PerProcess: map[string]*ProcessSummary{"npm>node": proc}}
+}
+
+func TestBuildBaseline(t *testing.T) {
+	baseline, err := BuildBaseline("safe", []*PerProcessStats{
+		sampleRun(map[string]int{"openat": 1000, "read": 10}, "/app/a.js"),
+		sampleRun(map[string]int{"openat": 1200}, "/app/b.js"),
+		sampleRun(map[string]int{"openat": 1100, "read": 20}),
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, baseline.Samples)
+	assert.Equal(t, KeyAncestry, baseline.KeyMode)
+	proc := baseline.PerProcess["npm>node"]
+	require.NotNil(t, proc)
+
+	assert.Equal(t, CounterStats{Mean: 1100, Variance: 10000}, proc.SyscallStats["openat"])
+	assert.Equal(t, 1100, proc.SyscallProfile["openat"])
+	// Missing from the second run, which counts as 0
+	assert.Equal(t, CounterStats{Mean: 10, Variance: 100}, proc.SyscallStats["read"])
+	assert.Equal(t, map[string]int{"/app/a.js": 1, "/app/b.js": 1}, proc.FileAccess)
+
+	_, err = BuildBaseline("safe", []*PerProcessStats{sampleRun(nil), {KeyMode: KeyCmdline}})
+	assert.Error(t, err)
+}
+
+func TestDedupZScore(t *testing.T) {
+	baseline, err := BuildBaseline("safe", []*PerProcessStats{
+		sampleRun(map[string]int{"openat": 1000, "connect": 2}),
+		sampleRun(map[string]int{"openat": 1200, "connect": 2}),
+		sampleRun(map[string]int{"openat": 1100, "connect": 2}),
+	})
+	require.NoError(t, err)
+
+	// openat: 1350 is 2.5σ above the mean (jitter), 1450 is 3.5σ
+	// connect: never varied, so the ratio rule applies
+	deduped := Dedup(sampleRun(map[string]int{"openat": 1350, "connect": 40}), baseline)
+	assert.Empty(t, deduped.PerProcess)
+	assert.Equal(t, 2, deduped.RemovedSyscalls)
+	assert.Equal(t, 3, deduped.BaselineSamples)
+
+	deduped = Dedup(sampleRun(map[string]int{"openat": 1450, "connect": 60}), baseline)
+	assert.Equal(t, map[string]int{"openat": 350, "connect": 58}, deduped.PerProcess["npm>node"].SyscallProfile)
+}
@@ -3,19 +3,43 @@ package aggregate
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"os"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
 )
 
 // DedupedProcessStats represents the result after deduplication
 type DedupedProcessStats struct {
 	Collection       string                     `json:"collection"`
+	KeyMode          KeyMode                    `json:"key_mode,omitempty"`
 	PerProcess       map[string]*ProcessSummary `json:"per_process"`
 	CountProcesses   int                        `json:"count_processes"`
 	BaselineSource   string                     `json:"baseline_source"`
+	BaselineSamples  int                        `json:"baseline_samples,omitempty"`
 	RemovedProcesses int                        `json:"removed_processes"`
 	RemovedFiles     int                        `json:"removed_files"`
 	RemovedCommands  int                        `json:"removed_commands"`
 	RemovedSyscalls  int                        `json:"removed_syscalls"`
+	BaselineRemoved  float64                    `json:"baseline_removed"` // Share of the traced activity the baseline removed, see RemovedShare
+	HTTPActivity     *HTTPActivity              `json:"http_activity,omitempty"`
+	URLIndicators    []URLIndicator             `json:"url_indicators,omitempty"`
+	ResourceUsage    *ResourceUsage             `json:"resource_usage,omitempty"`
+	Signals          *PackageSignals            `json:"signals,omitempty"`    // Registry signals, attached at analysis
+	RiskFlags        []string                   `json:"risk_flags,omitempty"` // Union over processes, see FlagRisks
+	Phases           *PhaseView                 `json:"phases,omitempty"`     // The diff's activity by test phase
+	Intel            []IntelFinding             `json:"intel,omitempty"`      // Contacted IPs and domains, see intel.Enricher
+	Generator        *version.Info              `json:"generator,omitempty"`  // Build of spr that wrote the file
+
+	// Variants holds per-variant diffs from the environment matrix
+	// (locale/timezone/... reruns), keyed by variant name
+	Variants map[string]*DedupedProcessStats `json:"variants,omitempty"`
+	// EnvironmentConditional lists behavior seen under only one of the variants
+	EnvironmentConditional []ConditionalBehavior `json:"environment_conditional,omitempty"`
+
+	// Tests lists the behavioral tests the trace covers when the run was
+	// limited to some of them; empty means all
+	Tests []string `json:"tests,omitempty"`
 }
 
 // LoadPerProcessStats loads per-process stats from a JSON file
@@ -33,12 +57,70 @@ func LoadPerProcessStats(filename string) (*PerProcessStats, error) {
 	return &stats, nil
 }
 
-// Dedup subtracts baseline data from target data
+// SyscallThreshold decides when a syscall count above the baseline count is
+// real signal rather than run-to-run jitter (e.g. 1203 vs 1187 openats).
+// Against a single-run baseline a count is kept only if it exceeds
+// baseline×Ratio AND the difference exceeds MinDelta. Against a baseline with
+// variance data (see BuildBaseline) it must instead be more than ZScore
+// standard deviations above the mean, and still more than MinDelta above it.
+// The zero value keeps any increase.
+type SyscallThreshold struct {
+	Ratio    float64
+	MinDelta int
+	ZScore   float64
+}
+
+// DefaultSyscallThreshold keeps a syscall only when it is both 50% above the
+// baseline (or 3 standard deviations above its mean) and more than 50 calls
+// higher
+var DefaultSyscallThreshold = SyscallThreshold{Ratio: 1.5, MinDelta: 50, ZScore: DefaultZScore}
+
+// keep reports whether count is significantly above baselineCount
+func (t SyscallThreshold) keep(count, baselineCount int) bool {
+	return count > baselineCount &&
+		float64(count) > float64(baselineCount)*t.Ratio &&
+		count-baselineCount > t.MinDelta
+}
+
+// keepStats is keep for a baseline counter with a known distribution. A
+// counter that never varied across samples falls back to the ratio rule
+// against its mean.
+func (t SyscallThreshold) keepStats(count int, stats CounterStats) bool {
+	sd := stats.StdDev()
+	if sd == 0 {
+		return t.keep(count, int(math.Round(stats.Mean)))
+	}
+	delta := float64(count) - stats.Mean
+	return delta > 0 && delta/sd > t.ZScore && delta > float64(t.MinDelta)
+}
+
+// Dedup subtracts baseline data from target data using
+// DefaultSyscallThreshold and DefaultAccessThreshold. See DedupWithThresholds.
 func Dedup(target *PerProcessStats, baseline *PerProcessStats) *DedupedProcessStats {
+	return DedupWithThresholds(target, baseline, DefaultSyscallThreshold, DefaultAccessThreshold)
+}
+
+// DedupWithThreshold is DedupWithThresholds with DefaultAccessThreshold
+func DedupWithThreshold(target *PerProcessStats, baseline *PerProcessStats, threshold SyscallThreshold) *DedupedProcessStats {
+	return DedupWithThresholds(target, baseline, threshold, DefaultAccessThreshold)
+}
+
+// DedupWithThresholds subtracts baseline data from target data. Processes are
+// matched by key when both sides use the same key mode; otherwise (e.g. an
+// older name-keyed baseline) target processes are matched against the
+// baseline processes sharing their bare name. Syscalls absent from the
+// baseline are always kept; those present are kept (as the difference from
+// the baseline count or mean) only when they pass threshold. Files, commands
+// and command lines are matched after NormalizePath, so temp dirs, PIDs and
+// UUIDs that differ between runs don't count as new; files and commands the
+// baseline has are still kept when they pass access.
+func DedupWithThresholds(target *PerProcessStats, baseline *PerProcessStats, threshold SyscallThreshold, access AccessThreshold) *DedupedProcessStats {
 	result := &DedupedProcessStats{
-		Collection:     target.Collection,
-		BaselineSource: baseline.Collection,
-		PerProcess:     make(map[string]*ProcessSummary),
+		Collection:      target.Collection,
+		KeyMode:         target.KeyMode,
+		BaselineSource:  baseline.Collection,
+		BaselineSamples: baseline.Samples,
+		PerProcess:      make(map[string]*ProcessSummary),
 	}
 
 	removedProcesses := 0
@@ -46,9 +128,11 @@ func Dedup(target *PerProcessStats, baseline *PerProcessStats) *DedupedProcessSt
 	removedCommands := 0
 	removedSyscalls := 0
 
+	lookup := baselineLookup(target.KeyMode, baseline)
+
 	for procName, targetProc := range target.PerProcess {
 		// Check if this process exists in baseline
-		baselineProc, exists := baseline.PerProcess[procName]
+		baselineProc, exists := lookup(procName)
 		if !exists {
 			// Process doesn't exist in baseline, keep it entirely
 			result.PerProcess[procName] = targetProc
@@ -56,45 +140,47 @@ func Dedup(target *PerProcessStats, baseline *PerProcessStats) *DedupedProcessSt
 		}
 
 		// Process exists, need to dedup
-		dedupedProc := &ProcessSummary{
-			SyscallProfile:   make(map[string]int),
-			FileAccess:       make(map[string]int),
-			ExecutedCommands: make(map[string]int),
-			NetworkActivity: NetworkActivity{
-				IPs:        make(map[string]int),
-				DNSRecords: make(map[string]int),
-			},
-		}
+		dedupedProc := newProcessSummary()
 
 		// Dedup syscalls (only include if count differs significantly)
 		for syscall, count := range targetProc.SyscallProfile {
-			if baselineCount, exists := baselineProc.SyscallProfile[syscall]; !exists || count > baselineCount {
-				// Keep the difference if count is higher
-				if exists && count > baselineCount {
-					dedupedProc.SyscallProfile[syscall] = count - baselineCount
-				} else {
-					dedupedProc.SyscallProfile[syscall] = count
-				}
-			} else {
+			baselineCount, exists := baselineProc.SyscallProfile[syscall]
+			stats, hasStats := baselineProc.SyscallStats[syscall]
+			switch {
+			case !exists:
+				dedupedProc.SyscallProfile[syscall] = count
+			case hasStats && threshold.keepStats(count, stats):
+				dedupedProc.SyscallProfile[syscall] = count - baselineCount
+			case hasStats:
+				removedSyscalls++
+			case threshold.keep(count, baselineCount):
+				// Keep the difference if count is significantly higher
+				dedupedProc.SyscallProfile[syscall] = count - baselineCount
+			default:
 				removedSyscalls++
 			}
 		}
 
-		// Dedup file access
-		for file, count := range targetProc.FileAccess {
-			if _, exists := baselineProc.FileAccess[file]; !exists {
-				dedupedProc.FileAccess[file] = count
-			} else {
-				removedFiles++
-			}
-		}
+		// Dedup file access and executed commands
+		var removed int
+		dedupedProc.FileAccess, removed = dedupAccesses(targetProc.FileAccess, baselineProc.FileAccess, access)
+		removedFiles += removed
+		dedupedProc.FileWrites = dedupFileModes(targetProc.FileWrites, baselineProc.FileWrites, dedupedProc.FileAccess, access)
+		dedupedProc.FileCreates = dedupFileModes(targetProc.FileCreates, baselineProc.FileCreates, dedupedProc.FileAccess, access)
+		dedupedProc.ExecutedCommands, removed = dedupAccesses(targetProc.ExecutedCommands, baselineProc.ExecutedCommands, access)
+		removedCommands += removed
 
-		// Dedup executed commands
-		for cmd, count := range targetProc.ExecutedCommands {
-			if _, exists := baselineProc.ExecutedCommands[cmd]; !exists {
-				dedupedProc.ExecutedCommands[cmd] = count
-			} else {
-				removedCommands++
+		// Dedup command lines, unless the baseline predates recording them
+		// and every one would look new
+		if baselineProc.CommandLines != nil {
+			baselineCmdlines := normalizedCounts(baselineProc.CommandLines)
+			for cmdline, count := range targetProc.CommandLines {
+				if !hasNormalized(baselineCmdlines, cmdline) {
+					if dedupedProc.CommandLines == nil {
+						dedupedProc.CommandLines = make(map[string]int)
+					}
+					dedupedProc.CommandLines[cmdline] = count
+				}
 			}
 		}
 
@@ -105,30 +191,143 @@ func Dedup(target *PerProcessStats, baseline *PerProcessStats) *DedupedProcessSt
 			}
 		}
 
-		// Dedup DNS records
+		// Dedup DNS records; baselines may predate domain normalization
+		baselineDomains := normalizeDomains(baselineProc.NetworkActivity.DNSRecords)
 		for dns, count := range targetProc.NetworkActivity.DNSRecords {
-			if _, exists := baselineProc.NetworkActivity.DNSRecords[dns]; !exists {
+			if _, exists := baselineDomains[NormalizeDomain(dns)]; !exists {
 				dedupedProc.NetworkActivity.DNSRecords[dns] = count
 			}
 		}
 
+		// Dedup URLs from command lines
+		for u, count := range targetProc.NetworkActivity.URLs {
+			if _, exists := baselineProc.NetworkActivity.URLs[u]; !exists {
+				dedupedProc.NetworkActivity.URLs[u] = count
+			}
+		}
+
+		// Dedup data volumes; baselines without any keep every destination
+		dedupedProc.NetworkActivity.Transfers = dedupTransfers(targetProc.NetworkActivity.Transfers, baselineProc.NetworkActivity.Transfers)
+
+		// Dedup environment access by path and variable name
+		dedupedProc.EnvAccess = dedupEnvAccess(targetProc.EnvAccess, baselineProc.EnvAccess)
+
 		// Only keep process if it has unique activity
 		if len(dedupedProc.SyscallProfile) > 0 ||
 			len(dedupedProc.FileAccess) > 0 ||
+			len(dedupedProc.FileWrites) > 0 ||
+			len(dedupedProc.FileCreates) > 0 ||
 			len(dedupedProc.ExecutedCommands) > 0 ||
+			len(dedupedProc.CommandLines) > 0 ||
 			len(dedupedProc.NetworkActivity.IPs) > 0 ||
-			len(dedupedProc.NetworkActivity.DNSRecords) > 0 {
+			len(dedupedProc.NetworkActivity.DNSRecords) > 0 ||
+			len(dedupedProc.NetworkActivity.URLs) > 0 ||
+			len(dedupedProc.NetworkActivity.Transfers) > 0 ||
+			dedupedProc.EnvAccess != nil {
 			result.PerProcess[procName] = dedupedProc
 		} else {
 			removedProcesses++
 		}
 	}
 
+	// Dedup proxy-captured HTTP activity by host
+	result.HTTPActivity = dedupHTTPActivity(target.HTTPActivity, baseline.HTTPActivity)
+	result.URLIndicators = BuildURLIndicators(result.PerProcess, target.PerProcess)
+	result.Phases = target.Phases.restrict(result.PerProcess)
+
 	result.CountProcesses = len(result.PerProcess)
 	result.RemovedProcesses = removedProcesses
 	result.RemovedFiles = removedFiles
 	result.RemovedCommands = removedCommands
 	result.RemovedSyscalls = removedSyscalls
+	result.BaselineRemoved, _ = RemovedShare(target, result)
+	result.FlagRisks()
 
 	return result
 }
+
+// RemovedShare returns the share of the target's activity (distinct
+// syscalls, files, commands, command lines, IPs, domains and URLs per
+// process) that baseline subtraction removed from deduped, a measure of how
+// well the baseline fits the sandbox. It is false when the target has no
+// activity to measure.
+func RemovedShare(target *PerProcessStats, deduped *DedupedProcessStats) (float64, bool) {
+	total := activityCount(target.PerProcess)
+	if total == 0 {
+		return 0, false
+	}
+	kept := min(activityCount(deduped.PerProcess), total)
+	return 1 - float64(kept)/float64(total), true
+}
+
+// activityCount counts the distinct activity of processes
+func activityCount(processes map[string]*ProcessSummary) int {
+	n := 0
+	for _, proc := range processes {
+		n += len(proc.SyscallProfile) + len(proc.FileAccess) + len(proc.ExecutedCommands) + len(proc.CommandLines) +
+			len(proc.NetworkActivity.IPs) + len(proc.NetworkActivity.DNSRecords) + len(proc.NetworkActivity.URLs)
+	}
+	return n
+}
+
+// baselineLookup returns a function finding the baseline process matching a
+// target process key under the given target key mode
+func baselineLookup(targetMode KeyMode, baseline *PerProcessStats) func(key string) (*ProcessSummary, bool) {
+	if targetMode.normalize() == baseline.KeyMode.normalize() {
+		return func(key string) (*ProcessSummary, bool) {
+			proc, ok := baseline.PerProcess[key]
+			return proc, ok
+		}
+	}
+
+	// Key schemes differ: fold the baseline down to bare names
+	byName := make(map[string]*ProcessSummary)
+	for key, proc := range baseline.PerProcess {
+		name := ProcessNameFromKey(key)
+		merged, ok := byName[name]
+		if !ok {
+			merged = newProcessSummary()
+			byName[name] = merged
+		}
+		mergeCounts(merged.SyscallProfile, proc.SyscallProfile)
+		mergeStats(merged, proc.SyscallStats)
+		mergeCounts(merged.FileAccess, proc.FileAccess)
+		merged.FileWrites = mergeOptional(merged.FileWrites, proc.FileWrites, mergeCounts)
+		merged.FileCreates = mergeOptional(merged.FileCreates, proc.FileCreates, mergeCounts)
+		mergeCounts(merged.ExecutedCommands, proc.ExecutedCommands)
+		merged.CommandLines = mergeOptional(merged.CommandLines, proc.CommandLines, mergeCounts)
+		merged.EnvAccess = mergeEnvAccess(merged.EnvAccess, proc.EnvAccess, mergeCounts)
+		mergeCounts(merged.NetworkActivity.IPs, proc.NetworkActivity.IPs)
+		mergeCounts(merged.NetworkActivity.DNSRecords, proc.NetworkActivity.DNSRecords)
+		mergeCounts(merged.NetworkActivity.URLs, proc.NetworkActivity.URLs)
+		merged.NetworkActivity.Transfers = mergeTransfers(merged.NetworkActivity.Transfers, proc.NetworkActivity.Transfers, func(a, b int64) int64 { return a + b })
+	}
+
+	return func(key string) (*ProcessSummary, bool) {
+		proc, ok := byName[ProcessNameFromKey(key)]
+		return proc, ok
+	}
+}
+
+func mergeCounts(dst, src map[string]int) {
+	for k, v := range src {
+		dst[k] += v
+	}
+}
+
+// mergeStats adds src to the syscall stats of dst, treating the processes
+// folded together as independent (means and variances both add)
+func mergeStats(dst *ProcessSummary, src map[string]CounterStats) {
+	if len(src) == 0 {
+		return
+	}
+	if dst.SyscallStats == nil {
+		dst.SyscallStats = make(map[string]CounterStats)
+	}
+	for k, v := range src {
+		s := dst.SyscallStats[k]
+		s.Mean += v.Mean
+		s.Variance += v.Variance
+		dst.SyscallStats[k] = s
+	}
+}
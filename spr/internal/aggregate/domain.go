@@ -0,0 +1,234 @@
+package aggregate
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// idnPrefix marks a punycode-encoded label (RFC 3490 ACE prefix)
+const idnPrefix = "xn--"
+
+// NormalizeDomain returns the canonical form of a DNS name used as a key in
+// DNS stats: lowercased, without trailing dots, with internationalized labels
+// in their punycode (xn--) form. Queries seen on the wire are already ASCII,
+// but case and trailing dots vary, and a hand-written baseline may contain
+// Unicode names. Labels that aren't valid punycode are kept as-is.
+func NormalizeDomain(name string) string {
+	name = strings.ToLower(strings.TrimRight(strings.TrimSpace(name), "."))
+	if name == "" {
+		return name
+	}
+
+	labels := strings.Split(name, ".")
+	for i, label := range labels {
+		if strings.HasPrefix(label, idnPrefix) {
+			// Re-encode so every spelling of the same label compares equal
+			decoded, err := punycodeDecode(label[len(idnPrefix):])
+			if err != nil {
+				continue
+			}
+			label = strings.ToLower(decoded)
+		}
+		if !isASCII(label) {
+			labels[i] = idnPrefix + punycodeEncode(label)
+		}
+	}
+	return strings.Join(labels, ".")
+}
+
+// UnicodeDomain returns name with its punycode labels decoded, i.e. how a
+// browser would render it, and whether it contained any. Homograph domains
+// such as xn--pple-43d.com (Cyrillic "а") only stand out when both forms are
+// shown.
+func UnicodeDomain(name string) (string, bool) {
+	labels := strings.Split(name, ".")
+	idn := false
+	for i, label := range labels {
+		if !strings.HasPrefix(strings.ToLower(label), idnPrefix) {
+			continue
+		}
+		if decoded, err := punycodeDecode(label[len(idnPrefix):]); err == nil {
+			labels[i] = decoded
+			idn = true
+		}
+	}
+	return strings.Join(labels, "."), idn
+}
+
+// normalizeDomains returns the canonical forms of the keys of records
+func normalizeDomains(records map[string]int) map[string]struct{} {
+	set := make(map[string]struct{}, len(records))
+	for name := range records {
+		set[NormalizeDomain(name)] = struct{}{}
+	}
+	return set
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// Punycode parameters (RFC 3492 section 5)
+const (
+	punyBase        = 36
+	punyTMin        = 1
+	punyTMax        = 26
+	punySkew        = 38
+	punyDamp        = 700
+	punyInitialBias = 72
+	punyInitialN    = 128
+)
+
+// punycodeEncode encodes a Unicode label without the xn-- prefix
+func punycodeEncode(label string) string {
+	input := []rune(label)
+	var out strings.Builder
+	for _, r := range input {
+		if r < utf8.RuneSelf {
+			out.WriteRune(r)
+		}
+	}
+	basic := out.Len()
+	handled := basic
+	if basic > 0 {
+		out.WriteByte('-')
+	}
+
+	n, delta, bias := rune(punyInitialN), 0, punyInitialBias
+	for handled < len(input) {
+		m := rune(utf8.MaxRune)
+		for _, r := range input {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+
+		for _, r := range input {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punyBase; ; k += punyBase {
+				t := punyThreshold(k, bias)
+				if q < t {
+					break
+				}
+				out.WriteByte(punyDigit(t + (q-t)%(punyBase-t)))
+				q = (q - t) / (punyBase - t)
+			}
+			out.WriteByte(punyDigit(q))
+			bias = punyAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return out.String()
+}
+
+// punycodeDecode decodes a label without the xn-- prefix
+func punycodeDecode(encoded string) (string, error) {
+	var output []rune
+	pos := 0
+	if b := strings.LastIndexByte(encoded, '-'); b >= 0 {
+		for _, r := range encoded[:b] {
+			if r >= utf8.RuneSelf {
+				return "", fmt.Errorf("non-ASCII basic code point in %q", encoded)
+			}
+			output = append(output, r)
+		}
+		pos = b + 1
+	}
+
+	n, i, bias := rune(punyInitialN), 0, punyInitialBias
+	for pos < len(encoded) {
+		oldi, w := i, 1
+		for k := punyBase; ; k += punyBase {
+			if pos >= len(encoded) {
+				return "", fmt.Errorf("truncated punycode %q", encoded)
+			}
+			digit := punyDigitValue(encoded[pos])
+			pos++
+			if digit < 0 {
+				return "", fmt.Errorf("invalid punycode digit in %q", encoded)
+			}
+			i += digit * w
+			if i > utf8.MaxRune*(len(output)+1) {
+				return "", fmt.Errorf("punycode overflow in %q", encoded)
+			}
+			t := punyThreshold(k, bias)
+			if digit < t {
+				break
+			}
+			w *= punyBase - t
+		}
+		bias = punyAdapt(i-oldi, len(output)+1, oldi == 0)
+		n += rune(i / (len(output) + 1))
+		i %= len(output) + 1
+		if n > utf8.MaxRune || !utf8.ValidRune(n) {
+			return "", fmt.Errorf("invalid code point in %q", encoded)
+		}
+
+		output = append(output, 0)
+		copy(output[i+1:], output[i:])
+		output[i] = n
+		i++
+	}
+	return string(output), nil
+}
+
+func punyThreshold(k, bias int) int {
+	switch {
+	case k <= bias:
+		return punyTMin
+	case k >= bias+punyTMax:
+		return punyTMax
+	}
+	return k - bias
+}
+
+func punyAdapt(delta, numPoints int, first bool) int {
+	if first {
+		delta /= punyDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / numPoints
+	k := 0
+	for delta > (punyBase-punyTMin)*punyTMax/2 {
+		delta /= punyBase - punyTMin
+		k += punyBase
+	}
+	return k + (punyBase-punyTMin+1)*delta/(delta+punySkew)
+}
+
+func punyDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+func punyDigitValue(c byte) int {
+	switch {
+	case 'a' <= c && c <= 'z':
+		return int(c - 'a')
+	case 'A' <= c && c <= 'Z':
+		return int(c - 'A')
+	case '0' <= c && c <= '9':
+		return int(c-'0') + 26
+	}
+	return -1
+}
@@ -0,0 +1,63 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPunycode(t *testing.T) {
+	cases := map[string]string{
+		"münchen": "mnchen-3ya",
+		"bücher":  "bcher-kva",
+		"аpple":   "pple-43d", // Cyrillic а
+		"例え":      "r8jz45g",
+		"ドメイン名例":  "eckwd4c7cu47r2wf",
+	}
+	for unicode, encoded := range cases {
+		assert.Equal(t, encoded, punycodeEncode(unicode), unicode)
+		decoded, err := punycodeDecode(encoded)
+		require.NoError(t, err, encoded)
+		assert.Equal(t, unicode, decoded, encoded)
+	}
+
+	_, err := punycodeDecode("abc-!!")
+	assert.Error(t, err)
+	_, err = punycodeDecode("9999999999")
+	assert.Error(t, err)
+}
+
+func TestNormalizeDomain(t *testing.T) {
+	assert.Equal(t, "registry.npmjs.org", NormalizeDomain("Registry.NPMJS.org."))
+	assert.Equal(t, "registry.npmjs.org", NormalizeDomain(" registry.npmjs.org.. "))
+	assert.Equal(t, "xn--pple-43d.com", NormalizeDomain("аpple.com"))
+	assert.Equal(t, "xn--pple-43d.com", NormalizeDomain("XN--PPLE-43D.COM."))
+	assert.Equal(t, "xn--mnchen-3ya.de", NormalizeDomain("MÜNCHEN.de"))
+	// Invalid punycode is only lowercased
+	assert.Equal(t, "xn--!!.com", NormalizeDomain("XN--!!.com"))
+	assert.Equal(t, "", NormalizeDomain("."))
+}
+
+func TestUnicodeDomain(t *testing.T) {
+	unicode, idn := UnicodeDomain("xn--pple-43d.com")
+	assert.True(t, idn)
+	assert.Equal(t, "аpple.com", unicode)
+
+	unicode, idn = UnicodeDomain("apple.com")
+	assert.False(t, idn)
+	assert.Equal(t, "apple.com", unicode)
+}
+
+func TestDedupNormalizesBaselineDomains(t *testing.T) {
+	baseline := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": newProcessSummary()}}
+	baseline.PerProcess["node"].NetworkActivity.DNSRecords["Registry.npmjs.org."] = 1
+
+	target := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"node": newProcessSummary()}}
+	target.PerProcess["node"].NetworkActivity.DNSRecords["registry.npmjs.org"] = 3
+	target.PerProcess["node"].NetworkActivity.DNSRecords["xn--pple-43d.com"] = 1
+
+	result := Dedup(target, baseline)
+	require.Contains(t, result.PerProcess, "node")
+	assert.Equal(t, map[string]int{"xn--pple-43d.com": 1}, result.PerProcess["node"].NetworkActivity.DNSRecords)
+}
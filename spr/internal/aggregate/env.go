@@ -0,0 +1,187 @@
+package aggregate
+
+import (
+	"regexp"
+	"strings"
+)
+
+// EnvAccess is a process's access to environment variables that may hold
+// credentials. Only paths and variable names are recorded, never values.
+type EnvAccess struct {
+	// Environ counts reads of /proc/self/environ and /proc/<pid>/environ,
+	// which dump every variable of a process at once
+	Environ map[string]int `json:"environ,omitempty"`
+
+	// Secrets counts, per secret-looking variable (see isSecretVar), the
+	// commands it was passed to: named in argv ($NPM_TOKEN, ${NPM_TOKEN},
+	// process.env.NPM_TOKEN, %NPM_TOKEN%), or, when Tracee records execve
+	// environments (--output option:exec-env), expanded into argv from it
+	Secrets map[string]int `json:"secrets,omitempty"`
+}
+
+// minSecretValue is the shortest environment value matched against argv;
+// shorter ones ("1", "true") turn up in command lines by chance
+const minSecretValue = 8
+
+// envRefPattern matches references to environment variables in a command
+// line, capturing the variable name
+var envRefPattern = regexp.MustCompile(`\$\{?([A-Za-z_][A-Za-z0-9_]*)|process\.env\.([A-Za-z_][A-Za-z0-9_]*)|process\.env\[['"]([A-Za-z_][A-Za-z0-9_]*)['"]\]|%([A-Za-z_][A-Za-z0-9_]*)%`)
+
+// secretVars are variables holding credentials whose names don't say so
+var secretVars = map[string]bool{
+	"NPM_TOKEN":                      true,
+	"NODE_AUTH_TOKEN":                true,
+	"GITHUB_TOKEN":                   true,
+	"GH_TOKEN":                       true,
+	"GOOGLE_APPLICATION_CREDENTIALS": true,
+}
+
+// isSecretVar reports whether an environment variable likely holds a
+// credential: npm and GitHub tokens, cloud provider keys (AWS_*, AZURE_*,
+// GCP_*; not the region), and anything named like a token, secret,
+// password or key
+func isSecretVar(name string) bool {
+	name = strings.ToUpper(name)
+	if secretVars[name] {
+		return true
+	}
+	if strings.HasSuffix(name, "_REGION") {
+		return false
+	}
+	for _, prefix := range []string{"AWS_", "AZURE_", "GCP_"} {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	for _, suffix := range []string{"_TOKEN", "_PASSWORD", "_API_KEY", "_ACCESS_KEY", "_PRIVATE_KEY"} {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return strings.Contains(name, "SECRET")
+}
+
+// isEnvironPath reports whether an opened path dumps a process environment
+func isEnvironPath(path string) bool {
+	return strings.HasPrefix(path, "/proc/") && strings.HasSuffix(path, "/environ")
+}
+
+func newEnvAccess() *EnvAccess {
+	return &EnvAccess{Environ: make(map[string]int), Secrets: make(map[string]int)}
+}
+
+// recordOpen counts an opened path if it is a process environment
+func (e *EnvAccess) recordOpen(path string) {
+	if isEnvironPath(path) {
+		e.Environ[path]++
+	}
+}
+
+// recordExec counts the secret variables an execve passed its command
+func (e *EnvAccess) recordExec(event *TraceeEvent, argv []string) {
+	for _, name := range execSecrets(argv, eventEnv(event)) {
+		e.Secrets[name]++
+	}
+}
+
+// summary returns e for a summary, or nil if nothing was recorded
+func (e *EnvAccess) summary() *EnvAccess {
+	if len(e.Environ) == 0 && len(e.Secrets) == 0 {
+		return nil
+	}
+	out := &EnvAccess{}
+	if len(e.Environ) > 0 {
+		out.Environ = e.Environ
+	}
+	if len(e.Secrets) > 0 {
+		out.Secrets = e.Secrets
+	}
+	return out
+}
+
+// execSecrets returns the secret variables named in argv, or whose value
+// from envp (NAME=value entries) appears in it, each once
+func execSecrets(argv, envp []string) []string {
+	seen := make(map[string]bool)
+	var names []string
+	found := func(name string) {
+		if isSecretVar(name) && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	cmdline := strings.Join(argv, " ")
+	for _, m := range envRefPattern.FindAllStringSubmatch(cmdline, -1) {
+		for _, name := range m[1:] {
+			if name != "" {
+				found(name)
+			}
+		}
+	}
+	for _, entry := range envp {
+		name, value, ok := strings.Cut(entry, "=")
+		if ok && len(value) >= minSecretValue && strings.Contains(cmdline, value) {
+			found(name)
+		}
+	}
+	return names
+}
+
+// eventEnv returns the environment of an execve event, which Tracee includes
+// as envp (or env, in some versions) only with exec-env output enabled
+func eventEnv(event *TraceeEvent) []string {
+	var envp []string
+	for _, name := range []string{"envp", "env"} {
+		if eventArg(event, name, &envp) {
+			return envp
+		}
+	}
+	return nil
+}
+
+// mergeEnvAccess merges src into dst with merge applied per map, allocating
+// dst if needed
+func mergeEnvAccess(dst, src *EnvAccess, merge func(dst, src map[string]int)) *EnvAccess {
+	if src == nil {
+		return dst
+	}
+	if dst == nil {
+		dst = &EnvAccess{}
+	}
+	dst.Environ = mergeOptional(dst.Environ, src.Environ, merge)
+	dst.Secrets = mergeOptional(dst.Secrets, src.Secrets, merge)
+	return dst
+}
+
+// dedupEnvAccess returns the environment reads (after NormalizePath) and
+// secret variables of target missing from the baseline, or nil if none are
+func dedupEnvAccess(target, baseline *EnvAccess) *EnvAccess {
+	if target == nil {
+		return nil
+	}
+	if baseline == nil {
+		baseline = &EnvAccess{}
+	}
+	kept := &EnvAccess{}
+	baseEnviron := normalizedCounts(baseline.Environ)
+	for path, count := range target.Environ {
+		if !hasNormalized(baseEnviron, path) {
+			if kept.Environ == nil {
+				kept.Environ = make(map[string]int)
+			}
+			kept.Environ[path] = count
+		}
+	}
+	for name, count := range target.Secrets {
+		if _, ok := baseline.Secrets[name]; !ok {
+			if kept.Secrets == nil {
+				kept.Secrets = make(map[string]int)
+			}
+			kept.Secrets[name] = count
+		}
+	}
+	if kept.Environ == nil && kept.Secrets == nil {
+		return nil
+	}
+	return kept
+}
@@ -0,0 +1,45 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// open(2) flags on Linux (octal, as in <fcntl.h>)
+const (
+	oAccMode = 0o3
+	oWronly  = 0o1
+	oRdwr    = 0o2
+	oCreat   = 0o100
+	oTrunc   = 0o1000
+)
+
+// openMode reports whether an openat event opened its file for writing and
+// whether it may have created it. Tracee sends flags as a number, or as
+// names like "O_WRONLY|O_CREAT" when parsing arguments; events without
+// flags count as reads.
+func openMode(event *TraceeEvent) (write, create bool) {
+	for _, arg := range event.Args {
+		if arg.Name != "flags" {
+			continue
+		}
+		var flags int
+		if err := json.Unmarshal(arg.Value, &flags); err == nil {
+			return flags&oAccMode == oWronly || flags&oAccMode == oRdwr || flags&oTrunc != 0, flags&oCreat != 0
+		}
+		var names string
+		if err := json.Unmarshal(arg.Value, &names); err != nil {
+			return false, false
+		}
+		for _, name := range strings.Split(names, "|") {
+			switch strings.TrimSpace(name) {
+			case "O_WRONLY", "O_RDWR", "O_TRUNC":
+				write = true
+			case "O_CREAT":
+				create = true
+			}
+		}
+		return write, create
+	}
+	return false, false
+}
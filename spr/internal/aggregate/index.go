@@ -0,0 +1,167 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// EventIndex maps each process and event type of a trace to the byte offsets
+// of its lines, so drill-down queries seek to the events they want instead of
+// re-scanning a multi-GB behavior.jsonl. The ProcessAggregator builds it while
+// aggregating; process keys are those of the KeyMode it was built with.
+type EventIndex struct {
+	Source    string                        `json:"source"` // Base name of the indexed trace
+	Size      int64                         `json:"size"`   // Of the indexed trace, to detect a stale index
+	KeyMode   KeyMode                       `json:"key_mode"`
+	Events    int                           `json:"events"`
+	Processes map[string]map[string]Offsets `json:"processes"` // Process key → event name → line offsets
+}
+
+// Offsets are ascending byte offsets of trace lines. They are stored as
+// deltas, which keeps indexes of large traces a fraction of their size.
+type Offsets []int64
+
+// MarshalJSON writes the offsets as deltas from the previous one
+func (o Offsets) MarshalJSON() ([]byte, error) {
+	deltas := make([]int64, len(o))
+	var prev int64
+	for i, off := range o {
+		deltas[i] = off - prev
+		prev = off
+	}
+	return json.Marshal(deltas)
+}
+
+// UnmarshalJSON reads offsets written by MarshalJSON
+func (o *Offsets) UnmarshalJSON(data []byte) error {
+	var deltas []int64
+	if err := json.Unmarshal(data, &deltas); err != nil {
+		return err
+	}
+	*o = make(Offsets, len(deltas))
+	var prev int64
+	for i, delta := range deltas {
+		prev += delta
+		(*o)[i] = prev
+	}
+	return nil
+}
+
+// IndexPath returns where the index of a trace is kept, e.g.
+// behavior.index.json next to behavior.jsonl
+func IndexPath(tracePath string) string {
+	return strings.TrimSuffix(tracePath, filepath.Ext(tracePath)) + ".index.json"
+}
+
+func newEventIndex(mode KeyMode) *EventIndex {
+	return &EventIndex{KeyMode: mode, Processes: make(map[string]map[string]Offsets)}
+}
+
+func (ix *EventIndex) add(process, event string, offset int64) {
+	events, ok := ix.Processes[process]
+	if !ok {
+		events = make(map[string]Offsets)
+		ix.Processes[process] = events
+	}
+	events[event] = append(events[event], offset)
+	ix.Events++
+}
+
+// Lookup returns the offsets of a process's events of one type, in file
+// order. An empty process or event matches all of them.
+func (ix *EventIndex) Lookup(process, event string) Offsets {
+	var result Offsets
+	for key, events := range ix.Processes {
+		if process != "" && key != process {
+			continue
+		}
+		for name, offsets := range events {
+			if event == "" || name == event {
+				result = append(result, offsets...)
+			}
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// Stale reports whether the trace at path changed size since it was indexed
+func (ix *EventIndex) Stale(path string) bool {
+	info, err := os.Stat(path)
+	return err != nil || info.Size() != ix.Size
+}
+
+// WriteEventIndex saves an index as JSON
+func WriteEventIndex(path string, ix *EventIndex) error {
+	data, err := json.Marshal(ix)
+	if err != nil {
+		return fmt.Errorf("failed to marshal index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write index: %w", err)
+	}
+	return nil
+}
+
+// LoadEventIndex loads an index saved by WriteEventIndex
+func LoadEventIndex(path string) (*EventIndex, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var ix EventIndex
+	if err := json.Unmarshal(data, &ix); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	return &ix, nil
+}
+
+// BuildEventIndex indexes a trace in one pass, for traces aggregated before
+// indexes were written or whose index is stale
+func BuildEventIndex(path string, mode KeyMode, maxLine int) (*EventIndex, error) {
+	pa := NewProcessAggregator()
+	pa.SetKeyMode(mode)
+	pa.SetMaxLineSize(maxLine)
+	pa.EnableIndex()
+	if _, err := pa.ProcessFile(path, filepath.Base(filepath.Dir(path))); err != nil {
+		return nil, err
+	}
+	return pa.Index(), nil
+}
+
+// ReadLinesAt calls fn with the trace line at each offset, in order, reading
+// only those lines. Runs of adjacent lines share one buffered read.
+func ReadLinesAt(r io.ReaderAt, offsets Offsets, maxLine int, fn func(offset int64, line []byte) error) error {
+	var lines *lineReader
+	var base int64
+	for _, offset := range offsets {
+		if lines == nil || base+lines.pos != offset {
+			base = offset
+			lines = newLineReader(io.NewSectionReader(r, offset, math.MaxInt64-offset), maxLine)
+		}
+		line, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			return fmt.Errorf("offset %d is past the end of the trace", offset)
+		}
+		if err != nil {
+			return fmt.Errorf("error reading offset %d: %w", offset, err)
+		}
+		if lines.start != offset-base {
+			// An oversized line was skipped; the index is not of this trace
+			return fmt.Errorf("no line at offset %d", offset)
+		}
+		if err := fn(offset, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEventIndex(t *testing.T) {
+	long := strings.Repeat("A", 3*lineBufferSize)
+	lines := []string{
+		`{"processId":3,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","` + long + `"]}]}`,
+		`{"processId":3,"processName":"sh","eventName":"openat","args":[{"name":"pathname","value":"/etc/passwd"}]}`,
+		`not json`,
+		`{"processId":4,"processName":"curl","eventName":"connect","args":[{"name":"remote_addr","value":{"sa_family":"AF_INET","sin_addr":"203.0.113.7","sin_port":"443"}}]}`,
+		`{"processId":3,"processName":"sh","eventName":"openat","args":[{"name":"pathname","value":"/etc/shadow"}]}`,
+	}
+	dir := t.TempDir()
+	tracePath := filepath.Join(dir, "behavior.jsonl")
+	require.NoError(t, os.WriteFile(tracePath, []byte(strings.Join(lines, "\r\n")), 0o644))
+
+	index, err := BuildEventIndex(tracePath, KeyName, 0)
+	require.NoError(t, err)
+	assert.Equal(t, "behavior.jsonl", index.Source)
+	assert.Equal(t, 4, index.Events)
+	assert.False(t, index.Stale(tracePath))
+
+	// Round-trips through the delta-encoded file
+	indexPath := IndexPath(tracePath)
+	assert.Equal(t, filepath.Join(dir, "behavior.index.json"), indexPath)
+	require.NoError(t, WriteEventIndex(indexPath, index))
+	loaded, err := LoadEventIndex(indexPath)
+	require.NoError(t, err)
+	assert.Equal(t, index, loaded)
+
+	read := func(process, event string) []string {
+		f, err := os.Open(tracePath)
+		require.NoError(t, err)
+		defer f.Close()
+		var got []string
+		require.NoError(t, ReadLinesAt(f, loaded.Lookup(process, event), 0, func(_ int64, line []byte) error {
+			got = append(got, string(line))
+			return nil
+		}))
+		return got
+	}
+	assert.Equal(t, []string{lines[1], lines[4]}, read("sh", "openat"))
+	assert.Equal(t, []string{lines[3]}, read("", "connect"))
+	assert.Equal(t, []string{lines[0], lines[1], lines[4]}, read("sh", ""))
+	assert.Len(t, read("", ""), 4)
+	assert.Empty(t, read("node", ""))
+
+	require.NoError(t, os.WriteFile(tracePath, []byte(strings.Join(lines[1:], "\n")), 0o644))
+	assert.True(t, loaded.Stale(tracePath))
+}
+
+func TestOffsetsJSON(t *testing.T) {
+	data, err := json.Marshal(Offsets{100, 250, 251})
+	require.NoError(t, err)
+	assert.JSONEq(t, `[100, 150, 1]`, string(data))
+
+	var offsets Offsets
+	require.NoError(t, json.Unmarshal(data, &offsets))
+	assert.Equal(t, Offsets{100, 250, 251}, offsets)
+}
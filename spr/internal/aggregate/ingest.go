@@ -0,0 +1,110 @@
+package aggregate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+)
+
+// DefaultIngestBatch is the number of events per bulk write
+const DefaultIngestBatch = 1000
+
+// EventWriter stores raw Tracee events in bulk, one collection per trace.
+// Events are written as read, so the full detail of every event stays
+// queryable rather than just the aggregated summaries.
+type EventWriter interface {
+	WriteEvents(ctx context.Context, collection string, events []json.RawMessage) error
+}
+
+// IndexSpec is an index over event fields, in dotted document paths
+type IndexSpec struct {
+	Name string
+	Keys []string
+}
+
+// EventIndexes is the index strategy for ingested collections, matching the
+// queries of the stats API: the syscall profile and per-process stats scan
+// by event name and process, the /specific drill-downs look events up by
+// name and argument value, and results are returned in trace order.
+// Document stores create them on each collection; stores that scan ignore
+// them.
+var EventIndexes = []IndexSpec{
+	{Name: "event", Keys: []string{"eventName"}},
+	{Name: "process_event", Keys: []string{"processName", "eventName"}},
+	{Name: "event_args", Keys: []string{"eventName", "args.name", "args.value"}},
+	{Name: "timestamp", Keys: []string{"timestamp"}},
+}
+
+var collectionUnsafe = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// CollectionName returns the collection holding the trace of a package,
+// "pkg_" followed by name@version with every non-identifier character
+// replaced, as the stats API only accepts identifiers
+func CollectionName(name, version string) string {
+	return "pkg_" + collectionUnsafe.ReplaceAllString(name+"@"+version, "_")
+}
+
+// IngestResult counts the events of an ingestion
+type IngestResult struct {
+	Events  int `json:"events"`
+	Skipped int `json:"skipped"` // Lines that weren't JSON objects or exceeded DefaultMaxLineSize
+	Batches int `json:"batches"`
+}
+
+// Ingest streams the Tracee JSONL read from reader into collection in
+// batches of batchSize events (DefaultIngestBatch if not positive). Invalid
+// lines are skipped as in ProcessReader. Batches already written stay
+// written if it fails.
+func Ingest(ctx context.Context, reader io.Reader, w EventWriter, collection string, batchSize int) (IngestResult, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultIngestBatch
+	}
+
+	var result IngestResult
+	batch := make([]json.RawMessage, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := w.WriteEvents(ctx, collection, batch); err != nil {
+			return fmt.Errorf("failed to write batch %d: %w", result.Batches+1, err)
+		}
+		result.Events += len(batch)
+		result.Batches++
+		batch = make([]json.RawMessage, 0, batchSize)
+		return nil
+	}
+
+	lines := newLineReader(reader, DefaultMaxLineSize)
+	for {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+		line, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("error reading input: %w", err)
+		}
+		var event map[string]json.RawMessage
+		if json.Unmarshal(line, &event) != nil {
+			if len(bytes.TrimSpace(line)) > 0 {
+				result.Skipped++
+			}
+			continue
+		}
+		batch = append(batch, json.RawMessage(append([]byte(nil), line...)))
+		if len(batch) == batchSize {
+			if err := flush(); err != nil {
+				return result, err
+			}
+		}
+	}
+	result.Skipped += lines.oversized
+	return result, flush()
+}
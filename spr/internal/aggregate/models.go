@@ -1,16 +1,23 @@
 package aggregate
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
 
-// TraceeEvent represents a single Tracee JSON event
+// TraceeEvent represents a single Tracee JSON event, in the layout of
+// SchemaV1. Decode events with an EventDecoder to accept the other schemas.
 type TraceeEvent struct {
-	Timestamp       int64         `json:"timestamp"`
-	ProcessID       int           `json:"processId"`
-	ProcessName     string        `json:"processName"`
-	ParentProcessID int           `json:"parentProcessId"`
-	EventName       string        `json:"eventName"`
-	Args            []TraceeArg   `json:"args"`
-	Container       ContainerInfo `json:"container"`
+	Timestamp       int64          `json:"timestamp"`
+	ProcessID       int            `json:"processId"`
+	ProcessName     string         `json:"processName"`
+	ParentProcessID int            `json:"parentProcessId"`
+	EventName       string         `json:"eventName"`
+	ReturnValue     int64          `json:"returnValue"`
+	Args            []TraceeArg    `json:"args"`
+	Container       ContainerInfo  `json:"container"`
+	Executable      ExecutableInfo `json:"executable"`
 }
 
 // TraceeArg represents an argument in a Tracee event
@@ -27,13 +34,148 @@ type ContainerInfo struct {
 	Image string `json:"image"`
 }
 
+// ExecutableInfo is the binary a process runs
+type ExecutableInfo struct {
+	Path string `json:"path"`
+}
+
+// SchemaVersion identifies the JSON layout of Tracee events, which changed
+// across Tracee releases
+type SchemaVersion int
+
+const (
+	SchemaUnknown SchemaVersion = iota
+
+	// SchemaV0 is the layout of older Tracee releases: TraceeEvent, but with
+	// the container in flat containerId, containerName and containerImage
+	// fields
+	SchemaV0
+
+	// SchemaV1 is the layout of TraceeEvent, printed by `tracee --output
+	// json` in the releases the analysis workflow installs
+	SchemaV1
+
+	// SchemaV2 is the layout of Tracee's protobuf event API (api/v1beta1),
+	// as streamed over gRPC: name, workload and typed data values. See
+	// decodeV2.
+	SchemaV2
+)
+
+func (v SchemaVersion) String() string {
+	switch v {
+	case SchemaV0:
+		return "v0"
+	case SchemaV1:
+		return "v1"
+	case SchemaV2:
+		return "v2"
+	}
+	return "unknown"
+}
+
+// ErrUnknownSchema is returned for JSON objects that match no Tracee schema
+var ErrUnknownSchema = errors.New("unknown tracee event schema")
+
+// DetectSchema returns the schema version of a Tracee JSON event from the
+// fields it has
+func DetectSchema(line []byte) (SchemaVersion, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(line, &fields); err != nil {
+		return SchemaUnknown, err
+	}
+	has := func(name string) bool {
+		_, ok := fields[name]
+		return ok
+	}
+	switch {
+	case has("eventName") && (has("containerId") || has("containerName") || has("containerImage")):
+		return SchemaV0, nil
+	case has("eventName"):
+		return SchemaV1, nil
+	case has("name") && (has("workload") || has("data")):
+		return SchemaV2, nil
+	}
+	return SchemaUnknown, ErrUnknownSchema
+}
+
+// EventDecoder decodes Tracee JSON events of any SchemaVersion into
+// TraceeEvent. The version is negotiated from the first event and detected
+// again whenever an event doesn't fit it, so traces concatenated from
+// runners with different Tracee releases still parse.
+type EventDecoder struct {
+	version SchemaVersion
+}
+
+// NewEventDecoder creates a decoder that has not yet seen an event
+func NewEventDecoder() *EventDecoder {
+	return &EventDecoder{}
+}
+
+// Version returns the schema of the events decoded last, or SchemaUnknown
+// before the first
+func (d *EventDecoder) Version() SchemaVersion {
+	return d.version
+}
+
+// Decode parses one JSON event
+func (d *EventDecoder) Decode(line []byte) (*TraceeEvent, error) {
+	if d.version == SchemaUnknown {
+		version, err := DetectSchema(line)
+		if err != nil {
+			return nil, err
+		}
+		d.version = version
+	}
+	event, err := decodeSchema(d.version, line)
+	if err == nil && event.EventName != "" {
+		return event, nil
+	}
+	version, detectErr := DetectSchema(line)
+	if detectErr != nil {
+		return nil, detectErr
+	}
+	if version == d.version {
+		return event, err
+	}
+	d.version = version
+	return decodeSchema(version, line)
+}
+
+// decodeSchema parses an event in the given schema
+func decodeSchema(version SchemaVersion, line []byte) (*TraceeEvent, error) {
+	switch version {
+	case SchemaV0, SchemaV1:
+		// V1 events can be read as V0: the flat fields are just absent
+		var event struct {
+			TraceeEvent
+			ContainerID    string `json:"containerId"`
+			ContainerName  string `json:"containerName"`
+			ContainerImage string `json:"containerImage"`
+		}
+		if err := json.Unmarshal(line, &event); err != nil {
+			return nil, err
+		}
+		if event.Container == (ContainerInfo{}) {
+			event.Container = ContainerInfo{ID: event.ContainerID, Name: event.ContainerName, Image: event.ContainerImage}
+		}
+		return &event.TraceeEvent, nil
+	case SchemaV2:
+		return decodeV2(line)
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownSchema, version)
+}
+
 // Stats represents the aggregated statistics
 type Stats struct {
 	Collection       string          `json:"collection"`
 	TotalEvents      int             `json:"total_events"`
 	SyscallProfile   map[string]int  `json:"syscall_profile"`
 	FileAccess       map[string]int  `json:"file_access"`
+	FileWrites       map[string]int  `json:"file_writes,omitempty"`  // See ProcessSummary
+	FileCreates      map[string]int  `json:"file_creates,omitempty"` // See ProcessSummary
 	ExecutedCommands map[string]int  `json:"executed_commands"`
+	CommandLines     map[string]int  `json:"command_lines,omitempty"` // Full argv, see ProcessSummary
+	EnvAccess        *EnvAccess      `json:"env_access,omitempty"`    // See ProcessSummary
 	NetworkActivity  NetworkActivity `json:"network_activity"`
 	RiskFlags        []string        `json:"risk_flags"`
 }
@@ -42,13 +184,58 @@ type Stats struct {
 type NetworkActivity struct {
 	IPs        map[string]int `json:"ips"`
 	DNSRecords map[string]int `json:"dns_records"`
+	URLs       map[string]int `json:"urls,omitempty"` // URLs in executed command lines
+
+	// Transfers counts bytes sent to and received from each ip:port, when
+	// packet or socket send/receive events were traced (see trafficCounter)
+	Transfers map[string]Transfer `json:"transfers,omitempty"`
+}
+
+// IntelFinding is threat intelligence about an IP or domain a package
+// contacted, such as the network it belongs to and the blocklists it is on
+type IntelFinding struct {
+	Indicator string   `json:"indicator"` // IP or domain
+	Kind      string   `json:"kind"`      // "ip" or "domain"
+	ASN       int      `json:"asn,omitempty"`
+	ASOrg     string   `json:"as_org,omitempty"`
+	Country   string   `json:"country,omitempty"`  // ISO 3166 code of the network
+	Verdicts  []string `json:"verdicts,omitempty"` // e.g. "known sinkhole", one per matching blocklist
+}
+
+// PackageSignals are facts about a package from outside its trace, weighed
+// together with its behavior
+type PackageSignals struct {
+	WeeklyDownloads int64 `json:"weekly_downloads"`         // npm downloads over the last week
+	InstallScript   bool  `json:"install_script,omitempty"` // Runs preinstall/install/postinstall scripts
+}
+
+// HTTPActivity contains request-level data captured by the intercepting proxy.
+// The proxy cannot attribute requests to processes, so this is tracked per run.
+type HTTPActivity struct {
+	TotalRequests int                            `json:"total_requests"`
+	Hosts         map[string]int                 `json:"hosts"`
+	Requests      map[string]*HTTPRequestSummary `json:"requests"` // keyed by "METHOD URL"
+}
+
+// HTTPRequestSummary aggregates all requests sharing a method and URL
+type HTTPRequestSummary struct {
+	Method        string `json:"method"`
+	URL           string `json:"url"`
+	Host          string `json:"host"`
+	Count         int    `json:"count"`
+	BytesSent     int64  `json:"bytes_sent"`
+	BytesReceived int64  `json:"bytes_received"`
 }
 
 // PerProcessStats contains stats grouped by process
 type PerProcessStats struct {
 	Collection     string                     `json:"collection"`
+	KeyMode        KeyMode                    `json:"key_mode,omitempty"` // How PerProcess is keyed; empty means KeyName
 	PerProcess     map[string]*ProcessSummary `json:"per_process"`
 	CountProcesses int                        `json:"count_processes"`
+	HTTPActivity   *HTTPActivity              `json:"http_activity,omitempty"`
+	Samples        int                        `json:"samples,omitempty"` // Runs merged by BuildBaseline; 0 for a single run
+	Phases         *PhaseView                 `json:"phases,omitempty"`  // Activity by test phase; nil in baselines and older stats
 }
 
 // ProcessSummary contains summary for a single process
@@ -57,4 +244,28 @@ type ProcessSummary struct {
 	FileAccess       map[string]int  `json:"file_access"`
 	ExecutedCommands map[string]int  `json:"executed_commands"`
 	NetworkActivity  NetworkActivity `json:"network_activity"`
+
+	// CommandLines counts the full argv of executed commands (truncated to
+	// maxCommandLine bytes); nil in traces aggregated before it was recorded
+	CommandLines map[string]int `json:"command_lines,omitempty"`
+
+	// FileWrites counts the opens of each file in FileAccess for writing
+	// (O_WRONLY, O_RDWR or O_TRUNC), and FileCreates those with O_CREAT, so
+	// writing ~/.bashrc stands apart from reading it. They are nil in traces
+	// aggregated before open flags were recorded, and kept when empty so
+	// Dedup can tell the two apart.
+	FileWrites  map[string]int `json:"file_writes"`
+	FileCreates map[string]int `json:"file_creates"`
+
+	// EnvAccess records reads of process environments and secrets such as
+	// NPM_TOKEN or AWS_* passed to commands; nil when there were none
+	EnvAccess *EnvAccess `json:"env_access,omitempty"`
+
+	// SyscallStats holds per-syscall mean and variance in baselines built
+	// from several samples; nil for a single run
+	SyscallStats map[string]CounterStats `json:"syscall_stats,omitempty"`
+
+	// RiskFlags are the Risk* flags the process's activity raises, see
+	// DetectRiskFlags; in diffs, only its activity beyond the baseline
+	RiskFlags []string `json:"risk_flags,omitempty"`
 }
@@ -0,0 +1,81 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	eventV0 = `{"timestamp":1700000000000000000,"processId":7,"parentProcessId":1,"processName":"node","eventName":"openat","containerId":"abc123","containerImage":"node:20","args":[{"name":"pathname","type":"const char*","value":"/etc/passwd"}]}`
+	eventV1 = `{"timestamp":1700000000000000000,"processId":7,"parentProcessId":1,"processName":"node","eventName":"openat","container":{"id":"abc123","image":"node:20"},"executable":{"path":"/usr/bin/node"},"args":[{"name":"pathname","type":"const char*","value":"/etc/passwd"}]}`
+	eventV2 = `{"timestamp":"2023-11-14T22:13:20Z","name":"openat","workload":{"process":{"executable":{"path":"/usr/bin/node"},"pid":7,"thread":{"name":"node"},"ancestors":[{"pid":{"value":1}}]},"container":{"id":"abc123","image":{"name":"node:20"}}},"data":[{"name":"pathname","str":"/etc/passwd"},{"name":"flags","int32":0}]}`
+)
+
+func TestDetectSchema(t *testing.T) {
+	for line, want := range map[string]SchemaVersion{eventV0: SchemaV0, eventV1: SchemaV1, eventV2: SchemaV2} {
+		version, err := DetectSchema([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, want, version, line)
+	}
+	_, err := DetectSchema([]byte(`{"level":"info","msg":"tracee started"}`))
+	assert.ErrorIs(t, err, ErrUnknownSchema)
+}
+
+func TestEventDecoderSchemas(t *testing.T) {
+	want := &TraceeEvent{
+		Timestamp:       1700000000000000000,
+		ProcessID:       7,
+		ParentProcessID: 1,
+		ProcessName:     "node",
+		EventName:       "openat",
+		Container:       ContainerInfo{ID: "abc123", Image: "node:20"},
+	}
+	for _, line := range []string{eventV0, eventV1, eventV2} {
+		event, err := NewEventDecoder().Decode([]byte(line))
+		require.NoError(t, err)
+		assert.Equal(t, want.Container, event.Container, line)
+		assert.Equal(t, want.Timestamp, event.Timestamp, line)
+		assert.Equal(t, want.ProcessID, event.ProcessID, line)
+		assert.Equal(t, want.ParentProcessID, event.ParentProcessID, line)
+		assert.Equal(t, want.ProcessName, event.ProcessName, line)
+		var pathname string
+		assert.True(t, eventArg(event, "pathname", &pathname), line)
+		assert.Equal(t, "/etc/passwd", pathname, line)
+	}
+}
+
+func TestEventDecoderRenegotiates(t *testing.T) {
+	decoder := NewEventDecoder()
+	_, err := decoder.Decode([]byte(eventV1))
+	require.NoError(t, err)
+	assert.Equal(t, SchemaV1, decoder.Version())
+
+	event, err := decoder.Decode([]byte(eventV2))
+	require.NoError(t, err)
+	assert.Equal(t, "openat", event.EventName)
+	assert.Equal(t, SchemaV2, decoder.Version())
+}
+
+// The shapes of execve, connect, DNS and packet arguments in SchemaV2
+const traceV2 = `{"name":"execve","workload":{"process":{"pid":3,"thread":{"name":"curl"},"ancestors":[{"pid":2}]}},"data":[{"name":"pathname","str":"/usr/bin/curl"},{"name":"argv","strArray":{"value":["curl","http://evil.example/x"]}}]}
+{"name":"connect","workload":{"process":{"pid":3,"thread":{"name":"curl"}}},"data":[{"name":"sockfd","int32":5},{"name":"addr","sockaddr":{"saFamily":"AF_INET","sinAddr":"203.0.113.9","sinPort":443}}]}
+{"name":"net_packet_dns_request","workload":{"process":{"pid":3,"thread":{"name":"curl"}}},"data":[{"name":"dns_questions","dnsQuestions":{"questions":[{"query":"evil.example","type":"A","class":"IN"}]}}]}
+{"name":"net_packet_ipv4","workload":{"process":{"pid":3,"thread":{"name":"curl"}}},"data":[{"name":"metadata","packetMetadata":{"srcIp":"10.0.0.2","dstIp":"203.0.113.9","srcPort":40000,"dstPort":443,"protocol":6,"packetLen":1500,"direction":"PACKET_EGRESS"}}]}
+`
+
+func TestAggregatorSchemaV2(t *testing.T) {
+	stats, err := NewAggregator().ProcessReader(strings.NewReader(traceV2), "test")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"/usr/bin/curl": 1}, stats.ExecutedCommands)
+	assert.Equal(t, map[string]int{"curl http://evil.example/x": 1}, stats.CommandLines)
+	assert.Equal(t, map[string]int{"203.0.113.9:443": 1}, stats.NetworkActivity.IPs)
+	assert.Equal(t, map[string]int{"evil.example": 1}, stats.NetworkActivity.DNSRecords)
+	assert.Equal(t, map[string]Transfer{"203.0.113.9:443": {BytesSent: 1500}}, stats.NetworkActivity.Transfers)
+
+	perProcess, err := NewProcessAggregator().ProcessReader(strings.NewReader(traceV2), "test")
+	require.NoError(t, err)
+	assert.Contains(t, perProcess.PerProcess, "curl")
+}
@@ -0,0 +1,86 @@
+package aggregate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoEventWriter is an EventWriter storing each event as its own document
+// in a MongoDB database, one collection per trace. Each batch is one
+// unordered BulkWrite, and EventIndexes are created on a collection before
+// its first batch.
+type MongoEventWriter struct {
+	db      *mongo.Database
+	mu      sync.Mutex
+	indexed map[string]bool // Collections whose indexes exist
+}
+
+// NewMongoEventWriter creates a writer into db
+func NewMongoEventWriter(db *mongo.Database) *MongoEventWriter {
+	return &MongoEventWriter{db: db, indexed: make(map[string]bool)}
+}
+
+func (w *MongoEventWriter) WriteEvents(ctx context.Context, collection string, events []json.RawMessage) error {
+	if err := w.EnsureIndexes(ctx, collection); err != nil {
+		return err
+	}
+
+	models := make([]mongo.WriteModel, 0, len(events))
+	for _, event := range events {
+		doc, err := eventDocument(event)
+		if err != nil {
+			return err
+		}
+		models = append(models, mongo.NewInsertOneModel().SetDocument(doc))
+	}
+	// Unordered, so the server may apply the inserts in parallel. Documents
+	// get their _id here, in input order, which keeps trace order for reads.
+	_, err := w.db.Collection(collection).BulkWrite(ctx, models, options.BulkWrite().SetOrdered(false))
+	return err
+}
+
+// EnsureIndexes creates EventIndexes on collection, once per writer. Creating
+// an index that already exists is a no-op on the server.
+func (w *MongoEventWriter) EnsureIndexes(ctx context.Context, collection string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.indexed[collection] {
+		return nil
+	}
+	if _, err := w.db.Collection(collection).Indexes().CreateMany(ctx, indexModels(EventIndexes)); err != nil {
+		return fmt.Errorf("failed to create indexes on %s: %w", collection, err)
+	}
+	w.indexed[collection] = true
+	return nil
+}
+
+// indexModels converts index specs to ascending MongoDB indexes
+func indexModels(specs []IndexSpec) []mongo.IndexModel {
+	models := make([]mongo.IndexModel, 0, len(specs))
+	for _, spec := range specs {
+		keys := make(bson.D, 0, len(spec.Keys))
+		for _, key := range spec.Keys {
+			keys = append(keys, bson.E{Key: key, Value: 1})
+		}
+		models = append(models, mongo.IndexModel{Keys: keys, Options: options.Index().SetName(spec.Name)})
+	}
+	return models
+}
+
+// eventDocument converts a JSON event to a BSON document, keeping its field
+// order. Integers that fit in 64 bits stay integers; larger ones (rare
+// unsigned Tracee arguments) become doubles.
+func eventDocument(event json.RawMessage) (bson.D, error) {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON(event, false, &doc); err != nil {
+		return nil, fmt.Errorf("failed to convert event to BSON: %w", err)
+	}
+	return doc, nil
+}
@@ -0,0 +1,89 @@
+package aggregate
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+func TestEventDocument(t *testing.T) {
+	doc, err := eventDocument([]byte(`{"timestamp":1700000000123456789,"eventName":"openat","processId":7,"args":[{"name":"pathname","value":"/etc/passwd"}]}`))
+	require.NoError(t, err)
+
+	keys := make([]string, len(doc))
+	for i, field := range doc {
+		keys[i] = field.Key
+	}
+	assert.Equal(t, []string{"timestamp", "eventName", "processId", "args"}, keys, "field order is kept")
+	assert.Equal(t, int64(1700000000123456789), doc[0].Value, "nanosecond timestamps stay exact")
+	assert.Equal(t, int32(7), doc[2].Value)
+	args, ok := doc[3].Value.(bson.A)
+	require.True(t, ok)
+	assert.Equal(t, bson.D{{Key: "name", Value: "pathname"}, {Key: "value", Value: "/etc/passwd"}}, args[0])
+
+	_, err = eventDocument([]byte(`[1,2]`))
+	assert.Error(t, err)
+}
+
+func TestIndexModels(t *testing.T) {
+	models := indexModels(EventIndexes)
+	require.Len(t, models, len(EventIndexes))
+
+	args := models[2]
+	assert.Equal(t, bson.D{{Key: "eventName", Value: 1}, {Key: "args.name", Value: 1}, {Key: "args.value", Value: 1}}, args.Keys)
+	var opts options.IndexOptions
+	for _, set := range args.Options.List() {
+		require.NoError(t, set(&opts))
+	}
+	require.NotNil(t, opts.Name)
+	assert.Equal(t, "event_args", *opts.Name)
+}
+
+// TestMongoEventWriter runs against a real server when MONGO_URI is set,
+// e.g. the mongo service of compose.yaml
+func TestMongoEventWriter(t *testing.T) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		t.Skip("MONGO_URI not set")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	require.NoError(t, err)
+	defer client.Disconnect(ctx)
+	db := client.Database("spr_test_" + bson.NewObjectID().Hex())
+	defer db.Drop(ctx)
+
+	input := `{"eventName":"execve","processName":"node","timestamp":1}
+not json
+{"eventName":"openat","processName":"node","timestamp":2}
+{"eventName":"connect","processName":"curl","timestamp":3}
+`
+	result, err := Ingest(ctx, strings.NewReader(input), NewMongoEventWriter(db), "pkg_left_pad_1_0_0", 2)
+	require.NoError(t, err)
+	assert.Equal(t, IngestResult{Events: 3, Skipped: 1, Batches: 2}, result)
+
+	coll := db.Collection("pkg_left_pad_1_0_0")
+	count, err := coll.CountDocuments(ctx, bson.D{{Key: "processName", Value: "node"}})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	specs, err := coll.Indexes().ListSpecifications(ctx)
+	require.NoError(t, err)
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	for _, index := range EventIndexes {
+		assert.Contains(t, names, index.Name)
+	}
+}
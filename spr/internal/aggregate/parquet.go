@@ -0,0 +1,29 @@
+package aggregate
+
+import (
+	"io"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// parquetRow is the Parquet schema of a Row, with RowColumns as its columns
+type parquetRow struct {
+	Collection string `parquet:"collection"`
+	Process    string `parquet:"process"`
+	Category   string `parquet:"category"`
+	Key        string `parquet:"key"`
+	Count      int64  `parquet:"count"`
+}
+
+// WriteParquet writes rows as a Parquet file with RowColumns as its columns
+func WriteParquet(w io.Writer, rows []Row) error {
+	pw := parquet.NewGenericWriter[parquetRow](w, parquet.CreatedBy("spr aggregate", "", ""))
+	records := make([]parquetRow, len(rows))
+	for i, r := range rows {
+		records[i] = parquetRow(r)
+	}
+	if _, err := pw.Write(records); err != nil {
+		return err
+	}
+	return pw.Close()
+}
@@ -0,0 +1,133 @@
+package aggregate
+
+import (
+	"regexp"
+	"unicode"
+)
+
+var (
+	uuidPattern = regexp.MustCompile(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`)
+	hexPattern  = regexp.MustCompile(`\b[0-9a-f]{16,}\b`)
+	procPattern = regexp.MustCompile(`/proc/\d+\b`)
+
+	// tempPathPattern matches paths under temp directories, up to the end of
+	// a command-line argument
+	tempPathPattern = regexp.MustCompile(`(/var/tmp|/tmp|/dev/shm)(/[^\s'"]*)`)
+	tokenPattern    = regexp.MustCompile(`[A-Za-z0-9]+`)
+)
+
+// NormalizePath replaces the parts of a path (or of a command line containing
+// paths) that change from run to run with placeholders, so the same access
+// matches across a baseline and a target run:
+//
+//	UUIDs                          → <uuid>
+//	hex strings of 16+ characters  → <hex>
+//	/proc/<pid>                    → /proc/<pid>
+//	random names under /tmp, /var/tmp and /dev/shm, e.g.
+//	/tmp/npm-1234-AbCdEf/x         → /tmp/npm-<rand>-<rand>/x
+//
+// Within temp directories a name part counts as random if it has a digit, or
+// mixes upper and lower case over six or more characters.
+func NormalizePath(s string) string {
+	s = uuidPattern.ReplaceAllString(s, "<uuid>")
+	s = hexPattern.ReplaceAllString(s, "<hex>")
+	s = procPattern.ReplaceAllString(s, "/proc/<pid>")
+	return tempPathPattern.ReplaceAllStringFunc(s, func(path string) string {
+		m := tempPathPattern.FindStringSubmatch(path)
+		return m[1] + tokenPattern.ReplaceAllStringFunc(m[2], func(token string) string {
+			if looksRandom(token) {
+				return "<rand>"
+			}
+			return token
+		})
+	})
+}
+
+// looksRandom reports whether a name part in a temp path is likely generated
+func looksRandom(token string) bool {
+	var digit, upper, lower bool
+	for _, r := range token {
+		switch {
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		}
+	}
+	return digit || (upper && lower && len(token) >= 6)
+}
+
+// normalizedCounts folds counts together by normalized key
+func normalizedCounts(counts map[string]int) map[string]int {
+	out := make(map[string]int, len(counts))
+	for k, v := range counts {
+		out[NormalizePath(k)] += v
+	}
+	return out
+}
+
+// hasNormalized reports whether the normalized form of key is in normalized
+func hasNormalized(normalized map[string]int, key string) bool {
+	_, ok := normalized[NormalizePath(key)]
+	return ok
+}
+
+// AccessThreshold decides when a file or command the baseline also touched
+// (after NormalizePath) is still reported because the target touched it far
+// more often: more than baseline×Ratio times AND more than MinDelta times
+// above the baseline. The zero value always drops it, as dedup did before
+// counts were compared.
+type AccessThreshold struct {
+	Ratio    float64
+	MinDelta int
+}
+
+// DefaultAccessThreshold keeps a file or command seen in the baseline only
+// when the target used it ten times as often and more than 100 times more
+var DefaultAccessThreshold = AccessThreshold{Ratio: 10, MinDelta: 100}
+
+// keep reports whether count is significantly above baselineCount
+func (t AccessThreshold) keep(count, baselineCount int) bool {
+	return t.Ratio > 0 &&
+		float64(count) > float64(baselineCount)*t.Ratio &&
+		count-baselineCount > t.MinDelta
+}
+
+// dedupAccesses returns the target accesses missing from the baseline, or
+// used far more often than in it, and how many were removed
+func dedupAccesses(target, baseline map[string]int, threshold AccessThreshold) (map[string]int, int) {
+	kept := make(map[string]int)
+	removed := 0
+	baseNorm := normalizedCounts(baseline)
+	targetNorm := normalizedCounts(target)
+	for key, count := range target {
+		norm := NormalizePath(key)
+		baseCount, exists := baseNorm[norm]
+		if !exists || threshold.keep(targetNorm[norm], baseCount) {
+			kept[key] = count
+		} else {
+			removed++
+		}
+	}
+	return kept, removed
+}
+
+// dedupFileModes returns the target writes (or creates) missing from the
+// baseline like dedupAccesses, so a file the baseline only read is kept when
+// the target writes it. Baselines predating open flags have none to compare
+// against; then only the modes of files kept as new accesses are.
+func dedupFileModes(target, baseline, keptAccesses map[string]int, threshold AccessThreshold) map[string]int {
+	if baseline != nil {
+		kept, _ := dedupAccesses(target, baseline, threshold)
+		return kept
+	}
+	kept := make(map[string]int)
+	for file, count := range target {
+		if _, ok := keptAccesses[file]; ok {
+			kept[file] = count
+		}
+	}
+	return kept
+}
@@ -1,34 +1,59 @@
 package aggregate
 
 import (
-	"bufio"
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
 // ProcessAggregator aggregates statistics per process
 type ProcessAggregator struct {
 	processes map[string]*processData
+	byPhase   map[string]map[string]*processData // The same activity split by test phase
+	keyMode   KeyMode
+	tracker   *processTracker
+	sockets   map[socketKey]string // Connected sockets, for socket traffic
+	maxLine   int                  // Longest line read; 0 means DefaultMaxLineSize
+	oversized int                  // Lines skipped for exceeding maxLine
+	index     *EventIndex          // Built while reading when enabled; nil otherwise
 }
 
 type processData struct {
 	syscallProfile   map[string]int
 	fileAccess       map[string]int
+	fileWrites       map[string]int
+	fileCreates      map[string]int
 	executedCommands map[string]int
+	commandLines     map[string]int
+	env              *EnvAccess
 	ips              map[string]int
 	dnsRecords       map[string]int
+	urls             map[string]int
+	traffic          *trafficCounter
 }
 
-// NewProcessAggregator creates a new ProcessAggregator
+// NewProcessAggregator creates a new ProcessAggregator keying processes by name
 func NewProcessAggregator() *ProcessAggregator {
 	return &ProcessAggregator{
 		processes: make(map[string]*processData),
+		byPhase:   make(map[string]map[string]*processData),
+		keyMode:   KeyName,
+		tracker:   newProcessTracker(KeyName),
+		sockets:   make(map[socketKey]string),
 	}
 }
 
+// SetKeyMode sets how processes are keyed. Must be called before processing.
+func (pa *ProcessAggregator) SetKeyMode(mode KeyMode) {
+	pa.keyMode = mode.normalize()
+	pa.tracker = newProcessTracker(pa.keyMode)
+}
+
 // ProcessFile reads a JSONL file and aggregates per-process statistics
 func (pa *ProcessAggregator) ProcessFile(filename string, collection string) (*PerProcessStats, error) {
 	file, err := os.Open(filename)
@@ -37,63 +62,126 @@ func (pa *ProcessAggregator) ProcessFile(filename string, collection string) (*P
 	}
 	defer file.Close()
 
+	if pa.index != nil {
+		pa.index.Source = filepath.Base(filename)
+	}
 	return pa.ProcessReader(file, collection)
 }
 
-// ProcessReader reads from an io.Reader and aggregates per-process statistics
+// EnableIndex records the offset of every event read, see Index. Must be
+// called after SetKeyMode.
+func (pa *ProcessAggregator) EnableIndex() {
+	pa.index = newEventIndex(pa.keyMode)
+}
+
+// Index returns the index of the events read, nil unless EnableIndex was
+// called
+func (pa *ProcessAggregator) Index() *EventIndex {
+	return pa.index
+}
+
+// SetMaxLineSize sets the longest line read, in bytes; longer lines are
+// skipped and counted in OversizedLines. Zero restores DefaultMaxLineSize.
+func (pa *ProcessAggregator) SetMaxLineSize(n int) {
+	pa.maxLine = n
+}
+
+// OversizedLines returns how many lines were skipped for their size
+func (pa *ProcessAggregator) OversizedLines() int {
+	return pa.oversized
+}
+
+// ProcessReader reads from an io.Reader and aggregates per-process
+// statistics, streaming events as the Aggregator does
 func (pa *ProcessAggregator) ProcessReader(reader io.Reader, collection string) (*PerProcessStats, error) {
-	scanner := bufio.NewScanner(reader)
+	lines := newLineReader(reader, pa.maxLine)
+	defer func() { pa.oversized += lines.oversized }()
+	decoder := NewEventDecoder()
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		if strings.TrimSpace(line) == "" {
+	for {
+		line, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		var event TraceeEvent
-		if err := json.Unmarshal([]byte(line), &event); err != nil {
+		event, err := decoder.Decode(line)
+		if err != nil {
 			continue
 		}
 
-		pa.processEvent(&event)
+		procName := pa.processEvent(event)
+		if pa.index != nil {
+			pa.index.add(procName, event.EventName, lines.start)
+		}
 	}
-
-	if err := scanner.Err(); err != nil {
-		return nil, fmt.Errorf("error reading input: %w", err)
+	if pa.index != nil {
+		pa.index.Size = lines.pos
 	}
 
 	return pa.buildStats(collection), nil
 }
 
-func (pa *ProcessAggregator) processEvent(event *TraceeEvent) {
-	procName := event.ProcessName
-	if procName == "" {
-		procName = fmt.Sprintf("pid_%d", event.ProcessID)
+// processEvent aggregates an event and returns the key of its process
+func (pa *ProcessAggregator) processEvent(event *TraceeEvent) string {
+	procName := pa.tracker.key(event)
+	pa.record(processDataFor(pa.processes, procName), event)
+
+	if phase := pa.tracker.phase(event.ProcessID); phase != "" {
+		processes, ok := pa.byPhase[phase]
+		if !ok {
+			processes = make(map[string]*processData)
+			pa.byPhase[phase] = processes
+		}
+		pa.record(processDataFor(processes, procName), event)
 	}
+	return procName
+}
 
-	data, exists := pa.processes[procName]
+// processDataFor returns the data of a process, adding it if it is new
+func processDataFor(processes map[string]*processData, procName string) *processData {
+	data, exists := processes[procName]
 	if !exists {
 		data = &processData{
 			syscallProfile:   make(map[string]int),
 			fileAccess:       make(map[string]int),
+			fileWrites:       make(map[string]int),
+			fileCreates:      make(map[string]int),
 			executedCommands: make(map[string]int),
+			commandLines:     make(map[string]int),
+			env:              newEnvAccess(),
 			ips:              make(map[string]int),
 			dnsRecords:       make(map[string]int),
+			urls:             make(map[string]int),
+			traffic:          newTrafficCounter(),
 		}
-		pa.processes[procName] = data
+		processes[procName] = data
 	}
+	return data
+}
 
+// record aggregates an event into a process's data
+func (pa *ProcessAggregator) record(data *processData, event *TraceeEvent) {
 	data.syscallProfile[event.EventName]++
 
 	switch event.EventName {
 	case "openat":
 		pa.processOpenat(data, event)
-	case "execve":
+	case "execve", "execveat":
 		pa.processExecve(data, event)
 	case "connect":
 		pa.processConnect(data, event)
 	case "net_packet_dns_request":
 		pa.processDNS(data, event)
+	default:
+		if isTrafficEvent(event.EventName) {
+			data.traffic.record(event, pa.sockets)
+		}
 	}
 }
 
@@ -105,6 +193,14 @@ func (pa *ProcessAggregator) processOpenat(data *processData, event *TraceeEvent
 				// Filter out node_modules paths
 				if !strings.Contains(pathname, "node_modules") {
 					data.fileAccess[pathname]++
+					data.env.recordOpen(pathname)
+					write, create := openMode(event)
+					if write {
+						data.fileWrites[pathname]++
+					}
+					if create {
+						data.fileCreates[pathname]++
+					}
 				}
 			}
 			break
@@ -122,6 +218,14 @@ func (pa *ProcessAggregator) processExecve(data *processData, event *TraceeEvent
 			break
 		}
 	}
+	argv := eventArgv(event)
+	if cmdline := commandLine(argv); cmdline != "" {
+		data.commandLines[cmdline]++
+	}
+	for _, u := range ExtractURLs(strings.Join(argv, " ")) {
+		data.urls[u]++
+	}
+	data.env.recordExec(event, argv)
 }
 
 func (pa *ProcessAggregator) processConnect(data *processData, event *TraceeEvent) {
@@ -145,6 +249,7 @@ func (pa *ProcessAggregator) processConnect(data *processData, event *TraceeEven
 						key = fmt.Sprintf("%s:%s", sockAddr.SinAddr, sockAddr.SinPort)
 					}
 					data.ips[key]++
+					recordSocket(pa.sockets, event, key)
 				}
 			}
 			break
@@ -160,7 +265,7 @@ func (pa *ProcessAggregator) processDNS(data *processData, event *TraceeEvent) {
 			}
 			if err := json.Unmarshal(arg.Value, &questions); err == nil {
 				for _, q := range questions {
-					data.dnsRecords[q.Query]++
+					data.dnsRecords[NormalizeDomain(q.Query)]++
 				}
 			}
 			break
@@ -169,9 +274,33 @@ func (pa *ProcessAggregator) processDNS(data *processData, event *TraceeEvent) {
 }
 
 func (pa *ProcessAggregator) buildStats(collection string) *PerProcessStats {
-	perProcess := make(map[string]*ProcessSummary)
+	perProcess := summarize(pa.processes)
+	for _, proc := range perProcess {
+		proc.RiskFlags = proc.DetectRiskFlags()
+	}
 
-	for procName, data := range pa.processes {
+	var phases *PhaseView
+	if len(pa.byPhase) > 0 {
+		byPhase := make(map[string]map[string]*ProcessSummary, len(pa.byPhase))
+		for phase, processes := range pa.byPhase {
+			byPhase[phase] = summarize(processes)
+		}
+		phases = BuildPhaseView(byPhase)
+	}
+
+	return &PerProcessStats{
+		Collection:     collection,
+		KeyMode:        pa.keyMode,
+		PerProcess:     perProcess,
+		CountProcesses: len(perProcess),
+		Phases:         phases,
+	}
+}
+
+// summarize turns aggregated process data into summaries
+func summarize(processes map[string]*processData) map[string]*ProcessSummary {
+	perProcess := make(map[string]*ProcessSummary, len(processes))
+	for procName, data := range processes {
 		perProcess[procName] = &ProcessSummary{
 			SyscallProfile:   data.syscallProfile,
 			FileAccess:       data.fileAccess,
@@ -179,13 +308,14 @@ func (pa *ProcessAggregator) buildStats(collection string) *PerProcessStats {
 			NetworkActivity: NetworkActivity{
 				IPs:        data.ips,
 				DNSRecords: data.dnsRecords,
+				URLs:       data.urls,
+				Transfers:  data.traffic.transfers(),
 			},
+			CommandLines: data.commandLines,
+			FileWrites:   data.fileWrites,
+			FileCreates:  data.fileCreates,
+			EnvAccess:    data.env.summary(),
 		}
 	}
-
-	return &PerProcessStats{
-		Collection:     collection,
-		PerProcess:     perProcess,
-		CountProcesses: len(perProcess),
-	}
+	return perProcess
 }
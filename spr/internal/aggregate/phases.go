@@ -0,0 +1,141 @@
+package aggregate
+
+import (
+	"sort"
+	"strings"
+)
+
+// Test phases: the behavioral tests run one after another in the same traced
+// sandbox, named as in tester.AllTests (plus the observation test)
+const (
+	PhaseInstall   = "install"
+	PhaseImport    = "import"
+	PhasePrototype = "prototype"
+	PhaseObserve   = "observe"
+	PhaseCLI       = "cli"
+	PhaseNpx       = "npx"
+)
+
+// PhaseOrder is the order the analyze workflow runs the phases in
+var PhaseOrder = []string{PhaseInstall, PhaseImport, PhasePrototype, PhaseObserve, PhaseCLI, PhaseNpx}
+
+// phaseMarkers identify the command each test starts in the sandbox, e.g.
+// sh -c "cd /test && npm install". Processes started by that command inherit
+// its phase, so only commands run outside any phase are matched.
+var phaseMarkers = []struct{ phase, marker string }{
+	{PhaseInstall, "npm install"},
+	{PhasePrototype, "test-prototype.js"},
+	{PhaseObserve, "observe.js"},
+	{PhaseImport, "index.js"},
+	{PhaseNpx, "run-npx.sh"}, // tester.NpxScript
+	{PhaseCLI, "npx "},
+}
+
+// phaseOf returns the phase a command line starts, empty if none
+func phaseOf(cmdline string) string {
+	for _, m := range phaseMarkers {
+		if strings.Contains(cmdline, m.marker) {
+			return m.phase
+		}
+	}
+	return ""
+}
+
+// PhaseView aligns the same indicator across test phases, side by side, so
+// e.g. network activity seen only at install time stands out
+type PhaseView struct {
+	Phases []string   `json:"phases"` // Phases with any activity, in PhaseOrder
+	Rows   []PhaseRow `json:"rows"`
+}
+
+// PhaseRow is one indicator and how often each phase exhibited it
+type PhaseRow struct {
+	Kind   string         `json:"kind"` // As ConditionalBehavior.Kind
+	Value  string         `json:"value"`
+	Counts map[string]int `json:"counts"` // By phase; phases without it are absent
+}
+
+// Only reports the single phase that exhibited the indicator, empty if
+// several did
+func (r PhaseRow) Only() string {
+	if len(r.Counts) != 1 {
+		return ""
+	}
+	for phase := range r.Counts {
+		return phase
+	}
+	return ""
+}
+
+// BuildPhaseView aligns the per-process activity of each phase. Rows are
+// sorted by kind and value; nil if no activity was attributed to a phase.
+func BuildPhaseView(byPhase map[string]map[string]*ProcessSummary) *PhaseView {
+	rows := make(map[behaviorKey]*PhaseRow)
+	present := make(map[string]bool)
+	for phase, perProcess := range byPhase {
+		forEachBehavior(perProcess, func(_, kind, value string, count int) {
+			key := behaviorKey{kind: kind, value: value}
+			row, ok := rows[key]
+			if !ok {
+				row = &PhaseRow{Kind: kind, Value: value, Counts: make(map[string]int)}
+				rows[key] = row
+			}
+			row.Counts[phase] += count
+			present[phase] = true
+		})
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	view := &PhaseView{}
+	for _, phase := range PhaseOrder {
+		if present[phase] {
+			view.Phases = append(view.Phases, phase)
+		}
+	}
+	for _, row := range rows {
+		view.Rows = append(view.Rows, *row)
+	}
+	sort.Slice(view.Rows, func(i, j int) bool {
+		a, b := view.Rows[i], view.Rows[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Value < b.Value
+	})
+	return view
+}
+
+// restrict returns the view limited to the indicators perProcess exhibits,
+// as a diff's view is limited to what survived dedup; nil if none remain
+func (v *PhaseView) restrict(perProcess map[string]*ProcessSummary) *PhaseView {
+	if v == nil {
+		return nil
+	}
+	keep := make(map[behaviorKey]bool)
+	forEachBehavior(perProcess, func(_, kind, value string, _ int) {
+		keep[behaviorKey{kind: kind, value: value}] = true
+	})
+
+	restricted := &PhaseView{}
+	present := make(map[string]bool)
+	for _, row := range v.Rows {
+		if !keep[behaviorKey{kind: row.Kind, value: row.Value}] {
+			continue
+		}
+		restricted.Rows = append(restricted.Rows, row)
+		for phase := range row.Counts {
+			present[phase] = true
+		}
+	}
+	if len(restricted.Rows) == 0 {
+		return nil
+	}
+	for _, phase := range v.Phases {
+		if present[phase] {
+			restricted.Phases = append(restricted.Phases, phase)
+		}
+	}
+	return restricted
+}
@@ -0,0 +1,71 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// The install and import tests as the workflow runs them, one after another:
+// the install script exfiltrates, the import only reads its own files
+const traceWithPhases = `{"processId":10,"parentProcessId":0,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","cd /test && npm install"]}]}
+{"processId":11,"parentProcessId":10,"processName":"npm","eventName":"execve","args":[{"name":"pathname","value":"/usr/bin/npm"},{"name":"argv","value":["npm","install"]}]}
+{"processId":12,"parentProcessId":11,"processName":"node","eventName":"execve","args":[{"name":"pathname","value":"/usr/bin/node"},{"name":"argv","value":["node","index.js"]}]}
+{"processId":12,"parentProcessId":11,"processName":"node","eventName":"openat","args":[{"name":"pathname","value":"/test/package.json"}]}
+{"processId":12,"parentProcessId":11,"processName":"node","eventName":"net_packet_dns_request","args":[{"name":"dns_questions","value":[{"query":"exfil.example"}]}]}
+{"processId":12,"parentProcessId":11,"processName":"node","eventName":"connect","args":[{"name":"addr","value":{"sa_family":"AF_INET","sin_addr":"203.0.113.7","sin_port":"443"}}]}
+{"processId":20,"parentProcessId":0,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","cd /test && node index.js"]}]}
+{"processId":21,"parentProcessId":20,"processName":"node","eventName":"execve","args":[{"name":"pathname","value":"/usr/bin/node"},{"name":"argv","value":["node","index.js"]}]}
+{"processId":21,"parentProcessId":20,"processName":"node","eventName":"openat","args":[{"name":"pathname","value":"/test/package.json"}]}
+{"processId":30,"parentProcessId":0,"processName":"mkdir","eventName":"execve","args":[{"name":"pathname","value":"/bin/mkdir"},{"name":"argv","value":["mkdir","-p","/test"]}]}
+`
+
+func TestPhaseView(t *testing.T) {
+	pa := NewProcessAggregator()
+	stats, err := pa.ProcessReader(strings.NewReader(traceWithPhases), "evil@1.0.0")
+	require.NoError(t, err)
+	require.NotNil(t, stats.Phases)
+	assert.Equal(t, []string{PhaseInstall, PhaseImport}, stats.Phases.Phases)
+
+	rows := make(map[string]PhaseRow)
+	for _, row := range stats.Phases.Rows {
+		rows[row.Kind+" "+row.Value] = row
+	}
+	assert.Equal(t, map[string]int{PhaseInstall: 1}, rows["dns exfil.example"].Counts)
+	assert.Equal(t, PhaseInstall, rows["ip 203.0.113.7:443"].Only())
+	assert.Equal(t, map[string]int{PhaseInstall: 1, PhaseImport: 1}, rows["file /test/package.json"].Counts)
+	assert.Empty(t, rows["file /test/package.json"].Only())
+	assert.NotContains(t, rows, "command /bin/mkdir", "commands outside any test have no phase")
+
+	// The diff keeps only the rows of activity beyond the baseline
+	baselineNode := newProcessSummary()
+	baselineNode.FileAccess["/test/package.json"] = 1
+	baselineNode.ExecutedCommands["/usr/bin/node"] = 1
+	baseline := &PerProcessStats{Collection: "baseline", PerProcess: map[string]*ProcessSummary{
+		"node": baselineNode,
+		"sh":   newProcessSummary(),
+		"npm":  newProcessSummary(),
+	}}
+	baseline.PerProcess["sh"].ExecutedCommands["/bin/sh"] = 1
+	baseline.PerProcess["npm"].ExecutedCommands["/usr/bin/npm"] = 1
+	baseline.PerProcess["mkdir"] = newProcessSummary()
+	baseline.PerProcess["mkdir"].ExecutedCommands["/bin/mkdir"] = 1
+
+	diff := Dedup(stats, baseline)
+	require.NotNil(t, diff.Phases)
+	var kinds []string
+	for _, row := range diff.Phases.Rows {
+		kinds = append(kinds, row.Kind+" "+row.Value)
+	}
+	assert.Subset(t, kinds, []string{"dns exfil.example", "ip 203.0.113.7:443"})
+	assert.NotContains(t, kinds, "file /test/package.json")
+	assert.NotContains(t, kinds, "command /usr/bin/node")
+}
+
+func TestPhaseOf(t *testing.T) {
+	assert.Equal(t, PhaseCLI, phaseOf("sh -c cd /test && timeout 30s npx pkg --version"))
+	assert.Equal(t, PhaseNpx, phaseOf("sh -c cd /npx-test && sh run-npx.sh"))
+	assert.Equal(t, "", phaseOf("node server.js"))
+}
@@ -0,0 +1,171 @@
+package aggregate
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// ProxyEvent represents a single request logged by the TLS-intercepting proxy
+// (one JSON object per line, written by mitm/flow_logger.py)
+type ProxyEvent struct {
+	Timestamp    float64 `json:"timestamp"`
+	Method       string  `json:"method"`
+	URL          string  `json:"url"`
+	Host         string  `json:"host"`
+	Status       int     `json:"status"`
+	RequestSize  int64   `json:"request_size"`
+	ResponseSize int64   `json:"response_size"`
+}
+
+// ProxyAggregator aggregates HTTP(S) request logs from the intercepting proxy
+type ProxyAggregator struct {
+	totalRequests int
+	hosts         map[string]int
+	requests      map[string]*HTTPRequestSummary
+	maxLine       int // Longest line read; 0 means DefaultMaxLineSize
+	oversized     int // Lines skipped for exceeding maxLine
+}
+
+// NewProxyAggregator creates a new ProxyAggregator
+func NewProxyAggregator() *ProxyAggregator {
+	return &ProxyAggregator{
+		hosts:    make(map[string]int),
+		requests: make(map[string]*HTTPRequestSummary),
+	}
+}
+
+// ProcessFile reads a proxy JSONL log and aggregates HTTP activity
+func (pa *ProxyAggregator) ProcessFile(filename string) (*HTTPActivity, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+
+	return pa.ProcessReader(file)
+}
+
+// SetMaxLineSize sets the longest line read, in bytes; longer lines are
+// skipped and counted in OversizedLines. Zero restores DefaultMaxLineSize.
+func (pa *ProxyAggregator) SetMaxLineSize(n int) {
+	pa.maxLine = n
+}
+
+// OversizedLines returns how many lines were skipped for their size
+func (pa *ProxyAggregator) OversizedLines() int {
+	return pa.oversized
+}
+
+// ProcessReader reads proxy log lines from an io.Reader and aggregates HTTP activity
+func (pa *ProxyAggregator) ProcessReader(reader io.Reader) (*HTTPActivity, error) {
+	lines := newLineReader(reader, pa.maxLine)
+	defer func() { pa.oversized += lines.oversized }()
+
+	for {
+		line, err := lines.next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		var event ProxyEvent
+		if err := json.Unmarshal(line, &event); err != nil {
+			continue
+		}
+
+		pa.processEvent(&event)
+	}
+
+	return pa.buildActivity(), nil
+}
+
+func (pa *ProxyAggregator) processEvent(event *ProxyEvent) {
+	if event.URL == "" {
+		return
+	}
+
+	host := event.Host
+	if host == "" {
+		if u, err := url.Parse(event.URL); err == nil {
+			host = u.Hostname()
+		}
+	}
+
+	method := strings.ToUpper(event.Method)
+	if method == "" {
+		method = "GET"
+	}
+
+	pa.totalRequests++
+	if host != "" {
+		pa.hosts[host]++
+	}
+
+	key := method + " " + event.URL
+	summary, exists := pa.requests[key]
+	if !exists {
+		summary = &HTTPRequestSummary{
+			Method: method,
+			URL:    event.URL,
+			Host:   host,
+		}
+		pa.requests[key] = summary
+	}
+	summary.Count++
+	summary.BytesSent += event.RequestSize
+	summary.BytesReceived += event.ResponseSize
+}
+
+func (pa *ProxyAggregator) buildActivity() *HTTPActivity {
+	return &HTTPActivity{
+		TotalRequests: pa.totalRequests,
+		Hosts:         pa.hosts,
+		Requests:      pa.requests,
+	}
+}
+
+// dedupHTTPActivity removes requests to hosts that also appear in the baseline.
+// Returns nil when nothing anomalous remains.
+func dedupHTTPActivity(target, baseline *HTTPActivity) *HTTPActivity {
+	if target == nil {
+		return nil
+	}
+	if baseline == nil {
+		return target
+	}
+
+	result := &HTTPActivity{
+		Hosts:    make(map[string]int),
+		Requests: make(map[string]*HTTPRequestSummary),
+	}
+
+	for host, count := range target.Hosts {
+		if _, exists := baseline.Hosts[host]; !exists {
+			result.Hosts[host] = count
+		}
+	}
+
+	for key, req := range target.Requests {
+		if _, exists := baseline.Hosts[req.Host]; exists {
+			continue
+		}
+		result.Requests[key] = req
+		result.TotalRequests += req.Count
+	}
+
+	if result.TotalRequests == 0 {
+		return nil
+	}
+	return result
+}
@@ -0,0 +1,73 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProxyAggregator(t *testing.T) {
+	log := strings.Join([]string{
+		`{"timestamp":1,"method":"get","url":"https://registry.npmjs.org/left-pad","host":"registry.npmjs.org","status":200,"request_size":10,"response_size":500}`,
+		`{"timestamp":2,"method":"GET","url":"https://registry.npmjs.org/left-pad","host":"registry.npmjs.org","status":200,"request_size":10,"response_size":500}`,
+		`{"timestamp":3,"url":"https://evil.example/payload.sh","status":200,"response_size":64}`,
+		`{"timestamp":4,"method":"POST","url":"https://evil.example/exfil","host":"evil.example","request_size":2048}`,
+		``,
+		`not json`,
+		`{"timestamp":5,"method":"GET"}`,
+	}, "\n")
+
+	activity, err := NewProxyAggregator().ProcessReader(strings.NewReader(log))
+	require.NoError(t, err)
+	assert.Equal(t, 4, activity.TotalRequests, "blank, malformed and URL-less lines are skipped")
+	assert.Equal(t, map[string]int{"registry.npmjs.org": 2, "evil.example": 2}, activity.Hosts)
+
+	npm := activity.Requests["GET https://registry.npmjs.org/left-pad"]
+	require.NotNil(t, npm, "methods are upper-cased")
+	assert.Equal(t, 2, npm.Count)
+	assert.Equal(t, int64(20), npm.BytesSent)
+	assert.Equal(t, int64(1000), npm.BytesReceived)
+
+	payload := activity.Requests["GET https://evil.example/payload.sh"]
+	require.NotNil(t, payload, "missing method defaults to GET")
+	assert.Equal(t, "evil.example", payload.Host, "missing host is taken from the URL")
+
+	exfil := activity.Requests["POST https://evil.example/exfil"]
+	require.NotNil(t, exfil)
+	assert.Equal(t, int64(2048), exfil.BytesSent)
+}
+
+func TestProxyAggregatorOversizedLines(t *testing.T) {
+	pa := NewProxyAggregator()
+	pa.SetMaxLineSize(64)
+	log := `{"url":"https://a.example/` + strings.Repeat("x", 100) + `"}` + "\n" + `{"url":"https://b.example/"}` + "\n"
+	activity, err := pa.ProcessReader(strings.NewReader(log))
+	require.NoError(t, err)
+	assert.Equal(t, 1, activity.TotalRequests)
+	assert.Equal(t, 1, pa.OversizedLines())
+}
+
+func TestDedupHTTPActivity(t *testing.T) {
+	target := &HTTPActivity{
+		TotalRequests: 3,
+		Hosts:         map[string]int{"registry.npmjs.org": 2, "evil.example": 1},
+		Requests: map[string]*HTTPRequestSummary{
+			"GET https://registry.npmjs.org/a": {Method: "GET", URL: "https://registry.npmjs.org/a", Host: "registry.npmjs.org", Count: 2},
+			"GET https://evil.example/x":       {Method: "GET", URL: "https://evil.example/x", Host: "evil.example", Count: 1},
+		},
+	}
+	baseline := &HTTPActivity{Hosts: map[string]int{"registry.npmjs.org": 5}}
+
+	deduped := dedupHTTPActivity(target, baseline)
+	require.NotNil(t, deduped)
+	assert.Equal(t, 1, deduped.TotalRequests)
+	assert.Equal(t, map[string]int{"evil.example": 1}, deduped.Hosts)
+	assert.Contains(t, deduped.Requests, "GET https://evil.example/x")
+
+	assert.Same(t, target, dedupHTTPActivity(target, nil), "no baseline keeps everything")
+	assert.Nil(t, dedupHTTPActivity(nil, baseline))
+	baseline.Hosts["evil.example"] = 1
+	assert.Nil(t, dedupHTTPActivity(target, baseline), "nothing anomalous left")
+}
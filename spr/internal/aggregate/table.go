@@ -0,0 +1,142 @@
+package aggregate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// Format is an output format of the aggregate CLI
+type Format string
+
+const (
+	FormatJSON    Format = "json"
+	FormatCSV     Format = "csv"
+	FormatParquet Format = "parquet"
+)
+
+// ParseFormat validates an output format name; empty means JSON
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(s); f {
+	case "":
+		return FormatJSON, nil
+	case FormatJSON, FormatCSV, FormatParquet:
+		return f, nil
+	}
+	return "", fmt.Errorf("unknown format %q (want json, csv or parquet)", s)
+}
+
+// Row categories: what Row.Key names
+const (
+	CategorySyscall       = "syscall"
+	CategoryFileAccess    = "file_access"
+	CategoryFileWrite     = "file_write"
+	CategoryFileCreate    = "file_create"
+	CategoryCommand       = "command"
+	CategoryCommandLine   = "command_line"
+	CategoryEnviron       = "environ"
+	CategorySecret        = "secret"
+	CategoryIP            = "ip"
+	CategoryDNS           = "dns"
+	CategoryURL           = "url"
+	CategoryBytesSent     = "bytes_sent"
+	CategoryBytesReceived = "bytes_received"
+	CategoryHTTPHost      = "http_host" // Per run: the proxy can't attribute requests to processes
+)
+
+// RowColumns are the column names of a Row, in order
+var RowColumns = []string{"collection", "process", "category", "key", "count"}
+
+// Row is one counter of per-process stats in long form, so the stats of many
+// packages stack into a single table for spreadsheets and data warehouses
+type Row struct {
+	Collection string
+	Process    string // Empty for per-run counters
+	Category   string // One of the Category* values
+	Key        string // Syscall, path, command, variable, address, domain or URL
+	Count      int64
+}
+
+// Rows flattens per-process stats into rows, ordered by process, category
+// and key
+func (s *PerProcessStats) Rows() []Row {
+	return tableRows(s.Collection, s.PerProcess, s.HTTPActivity)
+}
+
+// Rows flattens a diff into rows, ordered by process, category and key
+func (d *DedupedProcessStats) Rows() []Row {
+	return tableRows(d.Collection, d.PerProcess, d.HTTPActivity)
+}
+
+func tableRows(collection string, perProcess map[string]*ProcessSummary, http *HTTPActivity) []Row {
+	var rows []Row
+	add := func(process, category string, counts map[string]int) {
+		for key, n := range counts {
+			rows = append(rows, Row{Collection: collection, Process: process, Category: category, Key: key, Count: int64(n)})
+		}
+	}
+
+	for process, proc := range perProcess {
+		add(process, CategorySyscall, proc.SyscallProfile)
+		add(process, CategoryFileAccess, proc.FileAccess)
+		add(process, CategoryFileWrite, proc.FileWrites)
+		add(process, CategoryFileCreate, proc.FileCreates)
+		add(process, CategoryCommand, proc.ExecutedCommands)
+		add(process, CategoryCommandLine, proc.CommandLines)
+		if proc.EnvAccess != nil {
+			add(process, CategoryEnviron, proc.EnvAccess.Environ)
+			add(process, CategorySecret, proc.EnvAccess.Secrets)
+		}
+		add(process, CategoryIP, proc.NetworkActivity.IPs)
+		add(process, CategoryDNS, proc.NetworkActivity.DNSRecords)
+		add(process, CategoryURL, proc.NetworkActivity.URLs)
+		for dest, t := range proc.NetworkActivity.Transfers {
+			rows = append(rows,
+				Row{Collection: collection, Process: process, Category: CategoryBytesSent, Key: dest, Count: t.BytesSent},
+				Row{Collection: collection, Process: process, Category: CategoryBytesReceived, Key: dest, Count: t.BytesReceived})
+		}
+	}
+	if http != nil {
+		add("", CategoryHTTPHost, http.Hosts)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		if a.Process != b.Process {
+			return a.Process < b.Process
+		}
+		if a.Category != b.Category {
+			return a.Category < b.Category
+		}
+		return a.Key < b.Key
+	})
+	return rows
+}
+
+// WriteCSV writes rows as CSV with a RowColumns header
+func WriteCSV(w io.Writer, rows []Row) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(RowColumns); err != nil {
+		return err
+	}
+	for _, r := range rows {
+		if err := cw.Write([]string{r.Collection, r.Process, r.Category, r.Key, strconv.FormatInt(r.Count, 10)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteRows writes rows in a tabular format (CSV or Parquet)
+func WriteRows(w io.Writer, format Format, rows []Row) error {
+	switch format {
+	case FormatCSV:
+		return WriteCSV(w, rows)
+	case FormatParquet:
+		return WriteParquet(w, rows)
+	}
+	return fmt.Errorf("format %q is not tabular", format)
+}
@@ -0,0 +1,82 @@
+package aggregate
+
+import (
+	"bytes"
+	"encoding/csv"
+	"io"
+	"testing"
+
+	"github.com/parquet-go/parquet-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func tableStats() *DedupedProcessStats {
+	proc := newProcessSummary()
+	proc.SyscallProfile["connect"] = 3
+	proc.FileAccess["/etc/passwd"] = 1
+	proc.ExecutedCommands["/usr/bin/curl"] = 1
+	proc.NetworkActivity.Transfers = map[string]Transfer{"1.2.3.4:443": {BytesSent: 4096, BytesReceived: 10}}
+	return &DedupedProcessStats{
+		Collection:   "evil@1.0.0",
+		PerProcess:   map[string]*ProcessSummary{"npm>node>sh": proc},
+		HTTPActivity: &HTTPActivity{Hosts: map[string]int{"evil.example": 2}},
+	}
+}
+
+func TestRowsCSV(t *testing.T) {
+	rows := tableStats().Rows()
+	assert.Equal(t, []Row{
+		{"evil@1.0.0", "", CategoryHTTPHost, "evil.example", 2},
+		{"evil@1.0.0", "npm>node>sh", CategoryBytesReceived, "1.2.3.4:443", 10},
+		{"evil@1.0.0", "npm>node>sh", CategoryBytesSent, "1.2.3.4:443", 4096},
+		{"evil@1.0.0", "npm>node>sh", CategoryCommand, "/usr/bin/curl", 1},
+		{"evil@1.0.0", "npm>node>sh", CategoryFileAccess, "/etc/passwd", 1},
+		{"evil@1.0.0", "npm>node>sh", CategorySyscall, "connect", 3},
+	}, rows)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteRows(&buf, FormatCSV, rows))
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, len(rows)+1)
+	assert.Equal(t, RowColumns, records[0])
+	assert.Equal(t, []string{"evil@1.0.0", "npm>node>sh", "bytes_sent", "1.2.3.4:443", "4096"}, records[3])
+
+	_, err = ParseFormat("xlsx")
+	assert.Error(t, err)
+}
+
+func TestWriteParquet(t *testing.T) {
+	rows := tableStats().Rows()
+	var buf bytes.Buffer
+	require.NoError(t, WriteRows(&buf, FormatParquet, rows))
+	data := buf.Bytes()
+	require.Equal(t, "PAR1", string(data[:4]))
+	require.Equal(t, "PAR1", string(data[len(data)-4:]))
+
+	file, err := parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+	assert.Equal(t, int64(len(rows)), file.NumRows())
+	var names []string
+	for _, field := range file.Schema().Fields() {
+		names = append(names, field.Name())
+	}
+	assert.Equal(t, RowColumns, names)
+
+	reader := parquet.NewGenericReader[parquetRow](file)
+	defer reader.Close()
+	got := make([]parquetRow, len(rows)+1)
+	n, err := reader.Read(got)
+	require.ErrorIs(t, err, io.EOF)
+	require.Equal(t, len(rows), n)
+	for i, r := range rows {
+		assert.Equal(t, parquetRow(r), got[i])
+	}
+
+	buf.Reset()
+	require.NoError(t, WriteParquet(&buf, nil))
+	file, err = parquet.OpenFile(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err, "no rows still makes a valid file")
+	assert.Zero(t, file.NumRows())
+}
@@ -0,0 +1,204 @@
+package aggregate
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Transfer is the data exchanged with one destination
+type Transfer struct {
+	BytesSent     int64 `json:"bytes_sent"`
+	BytesReceived int64 `json:"bytes_received"`
+}
+
+// Dedup keeps a transfer to a destination the baseline also talked to only
+// if the target sent transferRatio times as much and transferMinDelta bytes
+// more: uploading a home directory, not a slightly larger request
+const (
+	transferRatio    = 4
+	transferMinDelta = 64 << 10
+)
+
+// socketKey identifies a socket by owning process and descriptor
+type socketKey struct {
+	pid int
+	fd  int
+}
+
+// trafficCounter accounts bytes per destination. Tracee's net_packet_ipv4
+// and net_packet_ipv6 events see every packet, so they are preferred when
+// traced; otherwise the return values of sendto, sendmsg, recvfrom and
+// recvmsg are counted, against the destination the socket connected to
+// unless the call names one. Neither is traced by default.
+type trafficCounter struct {
+	packets map[string]Transfer
+	socket  map[string]Transfer
+}
+
+func newTrafficCounter() *trafficCounter {
+	return &trafficCounter{packets: make(map[string]Transfer), socket: make(map[string]Transfer)}
+}
+
+// isTrafficEvent reports whether the event carries transferred bytes
+func isTrafficEvent(name string) bool {
+	switch name {
+	case "net_packet_ipv4", "net_packet_ipv6", "sendto", "sendmsg", "recvfrom", "recvmsg":
+		return true
+	}
+	return false
+}
+
+// record counts the bytes of a traffic event; sockets maps connected sockets
+// to their destination
+func (t *trafficCounter) record(event *TraceeEvent, sockets map[socketKey]string) {
+	switch event.EventName {
+	case "net_packet_ipv4", "net_packet_ipv6":
+		var meta struct {
+			SrcIP     string          `json:"src_ip"`
+			DstIP     string          `json:"dst_ip"`
+			SrcPort   int             `json:"src_port"`
+			DstPort   int             `json:"dst_port"`
+			PacketLen int64           `json:"packet_len"`
+			Direction json.RawMessage `json:"direction"`
+		}
+		if !eventArg(event, "metadata", &meta) || meta.PacketLen <= 0 {
+			return
+		}
+		// Tracee encodes the direction as a number (1 ingress, 2 egress) or,
+		// in some versions, by name
+		switch strings.Trim(string(meta.Direction), `"`) {
+		case "2", "egress":
+			add(t.packets, endpoint(meta.DstIP, meta.DstPort), meta.PacketLen, 0)
+		case "1", "ingress":
+			add(t.packets, endpoint(meta.SrcIP, meta.SrcPort), 0, meta.PacketLen)
+		}
+	case "sendto", "sendmsg", "recvfrom", "recvmsg":
+		if event.ReturnValue <= 0 {
+			return
+		}
+		var dest string
+		for _, name := range []string{"dest_addr", "src_addr"} {
+			var raw json.RawMessage
+			if eventArg(event, name, &raw) {
+				if key, ok := sockaddrKey(raw); ok {
+					dest = key
+				}
+			}
+		}
+		if dest == "" {
+			var fd int
+			if !eventArg(event, "sockfd", &fd) {
+				return
+			}
+			if dest = sockets[socketKey{event.ProcessID, fd}]; dest == "" {
+				return
+			}
+		}
+		if strings.HasPrefix(event.EventName, "send") {
+			add(t.socket, dest, event.ReturnValue, 0)
+		} else {
+			add(t.socket, dest, 0, event.ReturnValue)
+		}
+	}
+}
+
+// transfers returns the bytes per destination from the best source traced
+func (t *trafficCounter) transfers() map[string]Transfer {
+	if len(t.packets) > 0 {
+		return t.packets
+	}
+	return t.socket
+}
+
+// recordSocket remembers where a connect event's socket is connected to
+func recordSocket(sockets map[socketKey]string, event *TraceeEvent, dest string) {
+	var fd int
+	if eventArg(event, "sockfd", &fd) {
+		sockets[socketKey{event.ProcessID, fd}] = dest
+	}
+}
+
+// eventArg decodes the named argument of an event into v
+func eventArg(event *TraceeEvent, name string, v any) bool {
+	for _, arg := range event.Args {
+		if arg.Name == name {
+			return json.Unmarshal(arg.Value, v) == nil
+		}
+	}
+	return false
+}
+
+// sockaddrKey formats an AF_INET/AF_INET6 socket address as ip:port, the
+// form connect destinations are counted under
+func sockaddrKey(raw json.RawMessage) (string, bool) {
+	var addr struct {
+		Family   string `json:"sa_family"`
+		SinAddr  string `json:"sin_addr"`
+		SinPort  string `json:"sin_port"`
+		Sin6Addr string `json:"sin6_addr"`
+		Sin6Port string `json:"sin6_port"`
+	}
+	if err := json.Unmarshal(raw, &addr); err != nil {
+		return "", false
+	}
+	ip, port := addr.SinAddr, addr.SinPort
+	if ip == "" {
+		ip, port = addr.Sin6Addr, addr.Sin6Port
+	}
+	if ip == "" {
+		return "", false
+	}
+	if port == "" || port == "0" {
+		return ip, true
+	}
+	return fmt.Sprintf("%s:%s", ip, port), true
+}
+
+// endpoint formats an address as ip:port, like connect destinations
+func endpoint(ip string, port int) string {
+	if port == 0 {
+		return ip
+	}
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+func add(transfers map[string]Transfer, dest string, sent, received int64) {
+	t := transfers[dest]
+	t.BytesSent += sent
+	t.BytesReceived += received
+	transfers[dest] = t
+}
+
+// mergeTransfers combines src into dst with merge applied per field,
+// allocating dst if needed
+func mergeTransfers(dst, src map[string]Transfer, merge func(a, b int64) int64) map[string]Transfer {
+	if len(src) == 0 {
+		return dst
+	}
+	if dst == nil {
+		dst = make(map[string]Transfer, len(src))
+	}
+	for dest, t := range src {
+		d := dst[dest]
+		dst[dest] = Transfer{BytesSent: merge(d.BytesSent, t.BytesSent), BytesReceived: merge(d.BytesReceived, t.BytesReceived)}
+	}
+	return dst
+}
+
+// dedupTransfers returns the target transfers to destinations missing from
+// the baseline, or uploading far more to one it has
+func dedupTransfers(target, baseline map[string]Transfer) map[string]Transfer {
+	var kept map[string]Transfer
+	for dest, t := range target {
+		base, exists := baseline[dest]
+		if exists && (t.BytesSent < base.BytesSent*transferRatio || t.BytesSent-base.BytesSent <= transferMinDelta) {
+			continue
+		}
+		if kept == nil {
+			kept = make(map[string]Transfer)
+		}
+		kept[dest] = t
+	}
+	return kept
+}
@@ -0,0 +1,123 @@
+package aggregate
+
+import (
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Host classes of a URLIndicator
+const (
+	HostLoopback  = "loopback"
+	HostPrivateIP = "private-ip"
+	HostPublicIP  = "public-ip"
+	HostIDN       = "idn" // Internationalized domain, possible homograph
+	HostDomain    = "domain"
+)
+
+// urlPattern matches URLs embedded in command lines, e.g. the argument of
+// curl/wget or a string inside a node -e payload
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?|ftps?|wss?)://[^\s'"<>` + "`" + `\\]+`)
+
+// URLIndicator is a URL found in the command line of an executed process.
+// Short-lived processes (curl | sh) can exit before their connect or DNS
+// events are captured, so the command line may be the only trace of where
+// they reached out to.
+type URLIndicator struct {
+	URL       string   `json:"url"`
+	Host      string   `json:"host"` // Normalized, without port
+	Class     string   `json:"class"`
+	Processes []string `json:"processes"`
+	Count     int      `json:"count"`
+	// Observed is set when the host also shows up in the run's DNS queries or
+	// connections; false means the request left no other network trace
+	Observed bool `json:"observed"`
+}
+
+// ExtractURLs returns the URLs embedded in s
+func ExtractURLs(s string) []string {
+	matches := urlPattern.FindAllString(s, -1)
+	urls := matches[:0]
+	for _, m := range matches {
+		// Punctuation closing a sentence or an expression isn't part of the URL
+		m = strings.TrimRight(m, ".,;:!?)]}")
+		if _, err := url.Parse(m); err == nil {
+			urls = append(urls, m)
+		}
+	}
+	return urls
+}
+
+// ClassifyHost returns the host class of a URL host (without port)
+func ClassifyHost(host string) string {
+	if addr, err := netip.ParseAddr(strings.Trim(host, "[]")); err == nil {
+		switch {
+		case addr.IsLoopback():
+			return HostLoopback
+		case addr.IsPrivate(), addr.IsLinkLocalUnicast(), addr.IsUnspecified():
+			return HostPrivateIP
+		}
+		return HostPublicIP
+	}
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return HostLoopback
+	}
+	if _, idn := UnicodeDomain(host); idn {
+		return HostIDN
+	}
+	return HostDomain
+}
+
+// BuildURLIndicators lists the URLs recorded for processes, one entry per
+// URL, sorted by URL. Hosts are checked against the DNS queries and
+// connections of observed, which should be the full (non-deduped) run so a
+// host the baseline also resolved still counts as observed.
+func BuildURLIndicators(processes, observed map[string]*ProcessSummary) []URLIndicator {
+	byURL := make(map[string]*URLIndicator)
+	for procName, proc := range processes {
+		for rawURL, count := range proc.NetworkActivity.URLs {
+			ind, ok := byURL[rawURL]
+			if !ok {
+				ind = &URLIndicator{URL: rawURL}
+				if u, err := url.Parse(rawURL); err == nil {
+					ind.Host = NormalizeDomain(u.Hostname())
+				}
+				ind.Class = ClassifyHost(ind.Host)
+				byURL[rawURL] = ind
+			}
+			ind.Processes = append(ind.Processes, procName)
+			ind.Count += count
+		}
+	}
+	if len(byURL) == 0 {
+		return nil
+	}
+
+	domains := make(map[string]struct{})
+	ips := make(map[string]struct{})
+	for _, proc := range observed {
+		for name := range proc.NetworkActivity.DNSRecords {
+			domains[NormalizeDomain(name)] = struct{}{}
+		}
+		for addr := range proc.NetworkActivity.IPs {
+			if host, _, err := net.SplitHostPort(addr); err == nil {
+				addr = host
+			}
+			ips[addr] = struct{}{}
+		}
+	}
+
+	indicators := make([]URLIndicator, 0, len(byURL))
+	for _, ind := range byURL {
+		_, isDomain := domains[ind.Host]
+		_, isIP := ips[strings.Trim(ind.Host, "[]")]
+		ind.Observed = isDomain || isIP
+		sort.Strings(ind.Processes)
+		indicators = append(indicators, *ind)
+	}
+	sort.Slice(indicators, func(i, j int) bool { return indicators[i].URL < indicators[j].URL })
+	return indicators
+}
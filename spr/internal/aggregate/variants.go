@@ -0,0 +1,109 @@
+package aggregate
+
+import "sort"
+
+// ConditionalBehavior is activity observed under exactly one environment
+// variant (e.g. only with CI env set, or only under one locale) across the
+// variants compared. Behavior gated on the environment is a strong evasion
+// or targeting indicator.
+type ConditionalBehavior struct {
+	Variant string `json:"variant"` // Only variant the behavior appeared under
+	Kind    string `json:"kind"`    // "process", "file", "file_write", "command", "ip", "dns" or "url"
+	Value   string `json:"value"`
+	Process string `json:"process"` // Process that exhibited it (first seen)
+	Count   int    `json:"count"`
+}
+
+// behaviorKey identifies a behavior independent of the process performing it,
+// since the same action may be carried out by e.g. node in one run and sh in another
+type behaviorKey struct {
+	kind  string
+	value string
+}
+
+// CompareVariants diffs the per-process activity of runs of the same package
+// under different environments and returns behavior that appears under only
+// one of them. Syscall counts are ignored as they jitter between runs.
+// At least two variants are needed; results are sorted for stable output.
+func CompareVariants(variants map[string]map[string]*ProcessSummary) []ConditionalBehavior {
+	if len(variants) < 2 {
+		return nil
+	}
+
+	// Which variants each behavior was seen under, and where
+	seenIn := make(map[behaviorKey]map[string]*ConditionalBehavior)
+	record := func(variant, process, kind, value string, count int) {
+		key := behaviorKey{kind: kind, value: value}
+		byVariant, ok := seenIn[key]
+		if !ok {
+			byVariant = make(map[string]*ConditionalBehavior)
+			seenIn[key] = byVariant
+		}
+		if b, ok := byVariant[variant]; ok {
+			b.Count += count
+			return
+		}
+		byVariant[variant] = &ConditionalBehavior{
+			Variant: variant,
+			Kind:    kind,
+			Value:   value,
+			Process: process,
+			Count:   count,
+		}
+	}
+
+	for variant, perProcess := range variants {
+		forEachBehavior(perProcess, func(process, kind, value string, count int) {
+			record(variant, process, kind, value, count)
+		})
+	}
+
+	var conditional []ConditionalBehavior
+	for _, byVariant := range seenIn {
+		if len(byVariant) != 1 {
+			continue
+		}
+		for _, b := range byVariant {
+			conditional = append(conditional, *b)
+		}
+	}
+
+	sort.Slice(conditional, func(i, j int) bool {
+		a, b := conditional[i], conditional[j]
+		if a.Variant != b.Variant {
+			return a.Variant < b.Variant
+		}
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Value < b.Value
+	})
+
+	return conditional
+}
+
+// forEachBehavior calls fn with every behavior of the processes, keyed as
+// ConditionalBehavior.Kind and Value. Syscalls are left out.
+func forEachBehavior(perProcess map[string]*ProcessSummary, fn func(process, kind, value string, count int)) {
+	for procName, proc := range perProcess {
+		fn(procName, "process", procName, 1)
+		for file, count := range proc.FileAccess {
+			fn(procName, "file", file, count)
+		}
+		for file, count := range proc.FileWrites {
+			fn(procName, "file_write", file, count)
+		}
+		for cmd, count := range proc.ExecutedCommands {
+			fn(procName, "command", cmd, count)
+		}
+		for ip, count := range proc.NetworkActivity.IPs {
+			fn(procName, "ip", ip, count)
+		}
+		for domain, count := range proc.NetworkActivity.DNSRecords {
+			fn(procName, "dns", domain, count)
+		}
+		for u, count := range proc.NetworkActivity.URLs {
+			fn(procName, "url", u, count)
+		}
+	}
+}
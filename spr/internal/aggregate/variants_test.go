@@ -0,0 +1,59 @@
+package aggregate
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func summary(files map[string]int, dns map[string]int) *ProcessSummary {
+	return &ProcessSummary{
+		FileAccess:       files,
+		ExecutedCommands: map[string]int{},
+		NetworkActivity:  NetworkActivity{IPs: map[string]int{}, DNSRecords: dns},
+	}
+}
+
+func TestCompareVariants(t *testing.T) {
+	variants := map[string]map[string]*ProcessSummary{
+		"no-ci": {
+			"node": summary(map[string]int{"/app/index.js": 1}, map[string]int{}),
+		},
+		"ci": {
+			"node": summary(map[string]int{"/app/index.js": 2, "/root/.aws/credentials": 1}, map[string]int{}),
+			"sh":   summary(map[string]int{}, map[string]int{"exfil.example.com": 3}),
+		},
+	}
+
+	conditional := CompareVariants(variants)
+
+	assert.Equal(t, []ConditionalBehavior{
+		{Variant: "ci", Kind: "dns", Value: "exfil.example.com", Process: "sh", Count: 3},
+		{Variant: "ci", Kind: "file", Value: "/root/.aws/credentials", Process: "node", Count: 1},
+		{Variant: "ci", Kind: "process", Value: "sh", Process: "sh", Count: 1},
+	}, conditional)
+}
+
+func TestCompareVariantsNeedsTwo(t *testing.T) {
+	assert.Nil(t, CompareVariants(map[string]map[string]*ProcessSummary{
+		"ci": {"node": summary(map[string]int{"/etc/passwd": 1}, map[string]int{})},
+	}))
+}
+
+func TestCompareVariantsSingleCell(t *testing.T) {
+	// Locale x CI matrix where the payload only fires under ru-ru with CI set.
+	// Every other cell, including ru-ru without CI and en-us with CI, is clean.
+	variants := make(map[string]map[string]*ProcessSummary)
+	for _, locale := range []string{"en-us", "ru-ru", "zh-cn"} {
+		for _, ci := range []string{"no-ci", "ci"} {
+			variants[locale+"_"+ci] = map[string]*ProcessSummary{
+				"node": summary(map[string]int{"/app/index.js": 1}, map[string]int{}),
+			}
+		}
+	}
+	variants["ru-ru_ci"]["node"] = summary(map[string]int{"/app/index.js": 1, "/root/.aws/credentials": 1}, map[string]int{})
+
+	assert.Equal(t, []ConditionalBehavior{
+		{Variant: "ru-ru_ci", Kind: "file", Value: "/root/.aws/credentials", Process: "node", Count: 1},
+	}, CompareVariants(variants))
+}
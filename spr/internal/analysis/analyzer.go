@@ -2,17 +2,23 @@ package analysis
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
+	"runtime/debug"
 	"strings"
 	"sync"
 
 	"charm.land/fantasy"
-	"charm.land/fantasy/providers/openai"
 	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/acheong08/hackeurope-spr/internal/version"
 )
 
 const systemPrompt = `You are a security analyst specializing in software supply chain security. Your task is to analyze behavioral data from npm package installations and determine if the package exhibits malicious behavior.
@@ -24,12 +30,22 @@ You are analyzing "deduped" behavioral data, which means:
 - Common npm operations have been filtered out
 
 WHAT TO LOOK FOR:
-1. Network activity to suspicious IPs or domains (especially crypto pools, C2 servers)
+1. Network activity to suspicious IPs or domains (especially crypto pools, C2 servers, and internationalized
+   domains marked IDN whose rendered form imitates a well-known domain)
 2. File system access to sensitive locations (/etc, ~/.ssh, browser data, crypto wallets)
 3. Execution of system commands (especially encoded/obfuscated commands)
 4. Syscall patterns indicating process injection or privilege escalation
 5. Unusual process spawning patterns
-6. Access to environment variables containing secrets
+6. Access to environment variables containing secrets: reads of /proc/self/environ, and commands passed
+   NPM_TOKEN, GITHUB_TOKEN, AWS_* or similar credentials (Environment Access)
+7. HTTP(S) requests whose method, URL or upload size suggest data exfiltration rather than telemetry, and
+   destinations receiving far more bytes than they send back (uploads, not health checks)
+8. Behavior that only appears under specific locale/timezone environment variants (geo-targeted payloads)
+9. Behavior that differs between the "ci" variant (CI=true, GITHUB_ACTIONS=true, fake AWS credentials) and
+   the "no-ci" control — packages that go quiet in CI are evading analysis, packages that only act in CI
+   are targeting pipeline secrets
+10. URLs in executed command lines (curl/wget targets, node -e payloads), especially raw public IPs and URLs
+   with no matching DNS query or connection — short-lived downloaders often exit before those are captured
 
 JUDGMENT CRITERIA:
 - Consider the package's stated purpose vs its behavior
@@ -39,6 +55,21 @@ JUDGMENT CRITERIA:
 
 Provide a thorough justification explaining your reasoning.`
 
+// maxAgentSteps bounds the tool-calling round trips of one analysis
+const maxAgentSteps = 16
+
+// ErrNoAssessment is returned when the agent stops, e.g. at maxAgentSteps,
+// without calling submit_assessment. The package is left unanalyzed rather
+// than recorded as safe.
+var ErrNoAssessment = errors.New("agent did not submit an assessment")
+
+// PromptHash identifies the prompts behind an analysis, the system prompt
+// and the translation prompt, as a hex SHA-256
+func PromptHash() string {
+	sum := sha256.Sum256([]byte(systemPrompt + "\x00" + localizePrompt))
+	return hex.EncodeToString(sum[:])
+}
+
 // LogCallback is an optional function for forwarding log messages (e.g. to WebSocket).
 // level is one of "info", "success", "warning", "error".
 type LogCallback func(message, level string)
@@ -47,52 +78,134 @@ type LogCallback func(message, level string)
 type Analyzer struct {
 	model     fantasy.LanguageModel
 	semaphore chan struct{} // Limits concurrent analysis
+	rules     []Rule        // Deterministic pre-classifier, run before the LLM
+	sources   []Source      // Backends the LLM can query with tools
 	logCb     LogCallback
-}
+	streamCb  StreamCallback
+	logger    *slog.Logger
 
-// NewAnalyzer creates a new analyzer with the specified concurrency limit
-func NewAnalyzer(apiKey string, concurrencyLimit int) (*Analyzer, error) {
-	if apiKey == "" {
-		return nil, fmt.Errorf("API key is required for AI analysis")
-	}
+	modelName string // For usage reporting
+	pricing   Pricing
+	priced    bool    // Whether pricing is known for the model
+	maxBudget float64 // USD; zero means unlimited
 
-	provider, err := openai.New(
-		openai.WithBaseURL("https://cope.duti.dev"),
-		openai.WithAPIKey(apiKey),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OpenAI provider: %w", err)
-	}
+	// Longest list of a process's activity in the prompt before it is
+	// sampled — zero uses DefaultMaxIndicators
+	maxIndicators int
+
+	// Language justifications are rendered in — empty leaves them in English
+	language     string
+	translations map[string]string // English justification → translation
+
+	mu    sync.Mutex
+	usage Usage // Accumulated over all LLM calls
+}
 
-	ctx := context.Background()
-	model, err := provider.LanguageModel(ctx, "gpt-5-mini")
+// NewAnalyzer creates a new analyzer for the configured AI provider with the
+// specified concurrency limit
+func NewAnalyzer(cfg ProviderConfig, concurrencyLimit int) (*Analyzer, error) {
+	model, err := cfg.languageModel(context.Background())
 	if err != nil {
-		return nil, fmt.Errorf("failed to create language model: %w", err)
+		return nil, err
 	}
+	resolved, _ := cfg.Resolve()
+	pricing, priced := PricingFor(resolved.Provider, resolved.Model)
 
 	return &Analyzer{
 		model:     model,
 		semaphore: make(chan struct{}, concurrencyLimit),
+		rules:     DefaultRules,
+		sources:   []Source{DiffSource{}},
+		logger:    slog.Default(),
+		modelName: resolved.Model,
+		pricing:   pricing,
+		priced:    priced,
+
+		translations: make(map[string]string),
 	}, nil
 }
 
+// NewRulesAnalyzer creates an analyzer that decides with the deterministic
+// rules alone and never calls an LLM: packages the rules don't flag are
+// saved as not malicious, with whatever matched as indicators
+func NewRulesAnalyzer(concurrencyLimit int) *Analyzer {
+	return &Analyzer{
+		semaphore:    make(chan struct{}, concurrencyLimit),
+		rules:        DefaultRules,
+		logger:       slog.Default(),
+		translations: make(map[string]string),
+	}
+}
+
+// SetMaxBudget caps the estimated cost of LLM calls in USD; zero disables the
+// cap. Once spent, the remaining packages are not sent to the LLM. Requests
+// already in flight still complete, so the total may overshoot slightly.
+func (a *Analyzer) SetMaxBudget(usd float64) {
+	a.maxBudget = usd
+}
+
+// SetMaxIndicators caps each list of a process's activity in the prompt, e.g.
+// its file accesses; longer lists are sampled, keeping every sensitive path,
+// and the prompt says so. Zero restores DefaultMaxIndicators.
+func (a *Analyzer) SetMaxIndicators(n int) {
+	a.maxIndicators = n
+}
+
+// Usage returns the token usage and estimated cost of the LLM calls so far
+func (a *Analyzer) Usage() Usage {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.usage
+}
+
+// recordUsage accounts for an LLM call and returns its usage
+func (a *Analyzer) recordUsage(u fantasy.Usage) Usage {
+	usage := Usage{
+		PromptTokens:     u.InputTokens,
+		CompletionTokens: u.OutputTokens,
+		CostUSD:          a.pricing.Cost(u.InputTokens, u.OutputTokens),
+	}
+	a.mu.Lock()
+	a.usage.Add(usage)
+	a.mu.Unlock()
+	return usage
+}
+
+// budgetSpent reports whether the budget leaves no room for another call
+func (a *Analyzer) budgetSpent() bool {
+	if a.maxBudget <= 0 {
+		return false
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.usage.CostUSD >= a.maxBudget
+}
+
+// SetRules replaces the deterministic rules run before the LLM; nil sends
+// every package with anomalous behavior to the LLM
+func (a *Analyzer) SetRules(rules []Rule) {
+	a.rules = rules
+}
+
+// SetSources replaces the backends the LLM can query with tools, by default
+// just the DiffSource; nil leaves it with the prompt alone
+func (a *Analyzer) SetSources(sources ...Source) {
+	a.sources = sources
+}
+
 // SetLogCallback sets an optional callback for forwarding log messages.
 func (a *Analyzer) SetLogCallback(cb LogCallback) {
 	a.logCb = cb
 }
 
-// log prints to console and optionally forwards to the log callback.
-func (a *Analyzer) log(message, level string) {
-	prefix := "[INFO]"
-	switch level {
-	case "success":
-		prefix = "[SUCCESS]"
-	case "warning":
-		prefix = "[WARN]"
-	case "error":
-		prefix = "[ERROR]"
-	}
-	log.Printf("%s %s", prefix, message)
+// SetLogger sets the structured logger, typically one already tagged with a run ID.
+func (a *Analyzer) SetLogger(logger *slog.Logger) {
+	a.logger = logger
+}
+
+// log writes a structured log line and optionally forwards to the log callback.
+func (a *Analyzer) log(message, level string, attrs ...any) {
+	logging.Log(a.logger, message, level, append(attrs, logging.KeyStage, "analysis")...)
 	if a.logCb != nil {
 		a.logCb(message, level)
 	}
@@ -105,6 +218,9 @@ func (a *Analyzer) AnalyzePackages(ctx context.Context, packages []PackageInfo)
 	}
 
 	a.log(fmt.Sprintf("Starting AI security analysis for %d packages (max %d concurrent)", len(packages), cap(a.semaphore)), "info")
+	if a.model != nil && a.maxBudget > 0 && !a.priced {
+		a.log(fmt.Sprintf("No pricing known for model %s, the AI budget can't be enforced", a.modelName), "warning")
+	}
 
 	var wg sync.WaitGroup
 	errChan := make(chan error, len(packages))
@@ -130,7 +246,7 @@ func (a *Analyzer) AnalyzePackages(ctx context.Context, packages []PackageInfo)
 				return
 			}
 
-			err := a.analyzePackage(ctx, p)
+			err := a.analyzeRecovered(ctx, p)
 			<-a.semaphore // Release semaphore
 
 			if err != nil {
@@ -145,13 +261,22 @@ func (a *Analyzer) AnalyzePackages(ctx context.Context, packages []PackageInfo)
 
 	// Check for any errors (fail fast)
 	var errs []error
+	skipped := 0
 	for err := range errChan {
+		if errors.Is(err, ErrBudgetExceeded) {
+			skipped++
+			continue
+		}
 		errs = append(errs, err)
 	}
 
 	if len(errs) > 0 {
 		return errs[0] // Return first error (fail fast)
 	}
+	if skipped > 0 {
+		a.log(fmt.Sprintf("AI budget of $%.2f spent (%s); %d packages left unanalyzed", a.maxBudget, a.Usage(), skipped), "warning")
+		return fmt.Errorf("%w: %d packages left unanalyzed", ErrBudgetExceeded, skipped)
+	}
 
 	a.log(fmt.Sprintf("Completed AI security analysis for %d packages", len(packages)), "success")
 	return nil
@@ -162,6 +287,68 @@ type PackageInfo struct {
 	Name      string
 	Version   string
 	OutputDir string // Directory containing diff.json
+	// Signals are registry facts merged into the diff before the rules run;
+	// nil when they weren't looked up
+	Signals *aggregate.PackageSignals
+	// Static is the static scan of the package's tarball, shown to the model
+	// and merged into the assessment for reviewers; nil when not scanned
+	Static *static.Report
+	// Image is set for a container image whose entrypoint was traced, see
+	// orchestrator.SetImageMode; Name and Version are its repository and tag
+	Image bool
+	// Action is set for a GitHub Action run with canary secrets, see
+	// orchestrator.SetActionMode; Name and Version are owner/action and ref
+	Action bool
+}
+
+// imagePromptNote frames the behavioral data of a container image, which
+// npm's install/import tests and baseline don't apply to
+const imagePromptNote = `
+
+=== CONTAINER IMAGE ===
+This is a container image, not an npm package. The activity above was traced while the image's own entrypoint
+ran for a bounded time; unless a baseline image was given, nothing was subtracted, so expected work of the
+image's stated purpose (a web server binding, a tool reading its config) shows too. Flag what goes beyond that
+purpose: fetching and running payloads, reading credentials, mining, or contacting hosts unrelated to the image.
+`
+
+// actionPromptNote frames the behavioral data of a GitHub Action
+var actionPromptNote = `
+
+=== GITHUB ACTION ===
+This is a GitHub Action, not an npm package. The activity above was traced while the action ran as a workflow
+step would, with its declared inputs, a CI environment and canary secrets: GITHUB_TOKEN, ACTIONS_RUNTIME_TOKEN,
+cloud and registry credentials whose values contain "` + CanaryPrefix + `". Unless a baseline was given, nothing was
+subtracted. Actions legitimately call the GitHub API, download their tools and write to the workspace; flag
+sending a canary value anywhere, reading secrets the action has no use for, runner persistence (crontab,
+systemd, ~/.bashrc), and contacting hosts unrelated to the action's stated purpose.
+`
+
+// subject names what is analyzed in the prompt
+func (p PackageInfo) subject() string {
+	if p.Action {
+		return "GitHub Action: " + p.Name + "@" + p.Version
+	}
+	if p.Image && strings.HasPrefix(p.Version, "sha256:") {
+		return "container image: " + p.Name + "@" + p.Version
+	}
+	if p.Image {
+		return "container image: " + p.Name + ":" + p.Version
+	}
+	return "npm package: " + p.Name + "@" + p.Version
+}
+
+// analyzeRecovered runs analyzePackage, turning a panic (e.g. on a malformed
+// diff) into that package's error rather than crashing the process
+func (a *Analyzer) analyzeRecovered(ctx context.Context, pkg PackageInfo) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			a.log(fmt.Sprintf("Recovered from panic analyzing %s@%s: %v", pkg.Name, pkg.Version, r), "error",
+				logging.KeyPackageID, pkg.Name+"@"+pkg.Version, logging.KeyStack, string(debug.Stack()))
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return a.analyzePackage(ctx, pkg)
 }
 
 // analyzePackage performs AI analysis on a single package
@@ -169,7 +356,7 @@ func (a *Analyzer) analyzePackage(ctx context.Context, pkg PackageInfo) error {
 	// Check if analysis already exists (caching)
 	analysisPath := filepath.Join(pkg.OutputDir, "ai-analysis.json")
 	if _, err := os.Stat(analysisPath); err == nil {
-		a.log(fmt.Sprintf("Using cached analysis for %s@%s", pkg.Name, pkg.Version), "info")
+		a.log(fmt.Sprintf("Using cached analysis for %s@%s", pkg.Name, pkg.Version), "info", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
 		return nil
 	}
 
@@ -186,21 +373,51 @@ func (a *Analyzer) analyzePackage(ctx context.Context, pkg PackageInfo) error {
 		return fmt.Errorf("failed to parse diff.json: %w", err)
 	}
 
+	if pkg.Signals != nil {
+		deduped.Signals = pkg.Signals
+	}
+
 	// Skip analysis if no anomalous behavior
-	if len(deduped.PerProcess) == 0 {
-		a.log(fmt.Sprintf("No anomalous behavior for %s@%s, skipping analysis", pkg.Name, pkg.Version), "info")
+	cpuBurn := deduped.ResourceUsage != nil && deduped.ResourceUsage.CPUBurn
+	if len(deduped.PerProcess) == 0 && deduped.HTTPActivity == nil && !cpuBurn && !hasVariantAnomalies(&deduped) {
+		a.log(fmt.Sprintf("No anomalous behavior for %s@%s, skipping analysis", pkg.Name, pkg.Version), "info", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
 		assessment := SecurityAssessment{
 			IsMalicious:   false,
 			Confidence:    1.0,
 			Justification: "No anomalous behavior detected. All activity matched baseline patterns.",
 		}
-		return a.saveAnalysis(pkg.OutputDir, assessment)
+		return a.saveAnalysis(ctx, pkg, assessment, nil, nil)
+	}
+
+	// Clear-cut cases are decided by the rules alone, reproducibly and
+	// without an API call; the rest go to the LLM with the matches attached
+	rules := ClassifyRules(&deduped, a.rules)
+	if rules.Decisive() {
+		assessment := rules.Assessment()
+		a.log(fmt.Sprintf("Flagged %s@%s as MALICIOUS by rules (score: %.2f)", pkg.Name, pkg.Version, rules.Score), "warning", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
+		return a.saveAnalysis(ctx, pkg, assessment, rules.Matches, nil)
+	}
+	if a.model == nil {
+		a.log(fmt.Sprintf("Cleared %s@%s by rules alone (score: %.2f)", pkg.Name, pkg.Version, rules.Score), "info", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
+		return a.saveAnalysis(ctx, pkg, rules.Cleared(), rules.Matches, nil)
+	}
+
+	if a.budgetSpent() {
+		return fmt.Errorf("%w before %s@%s", ErrBudgetExceeded, pkg.Name, pkg.Version)
 	}
 
 	// Format diff data for the prompt
-	prompt := formatAnalysisPrompt(pkg.Name, pkg.Version, &deduped)
+	prompt := formatAnalysisPrompt(pkg.subject(), &deduped, rules, a.maxIndicators)
+	prompt += formatStaticReport(pkg.Static)
+	if pkg.Image {
+		prompt += imagePromptNote
+	}
+	if pkg.Action {
+		prompt += actionPromptNote
+	}
 
 	report := SecurityAssessment{}
+	submitted := false
 	// Tool
 	submitReportTool := fantasy.NewAgentTool(
 		"submit_assessment",
@@ -210,81 +427,156 @@ func (a *Analyzer) analyzePackage(ctx context.Context, pkg PackageInfo) error {
 			_ fantasy.ToolCall,
 		) (fantasy.ToolResponse, error) {
 			report = input
+			submitted = true
 			return fantasy.ToolResponse{
 				Content: "Command received",
 			}, nil
 		})
 
+	// A source that can't serve this package only costs the agent its tools
+	tools := []fantasy.AgentTool{submitReportTool}
+	for _, source := range a.sources {
+		sourceTools, err := source.Tools(ctx, pkg)
+		if err != nil {
+			a.log(fmt.Sprintf("Analysis source %s unavailable for %s@%s: %v", source.Name(), pkg.Name, pkg.Version, err), "warning", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
+			continue
+		}
+		tools = append(tools, sourceTools...)
+	}
+	if len(tools) > 1 {
+		prompt += "\nBefore submitting, you can use the other tools to drill into the raw data where the summary above is ambiguous."
+	}
+
 	// Call the agent
-	agent := fantasy.NewAgent(a.model, fantasy.WithSystemPrompt(systemPrompt), fantasy.WithTools(submitReportTool))
-	_, err = agent.Generate(ctx, fantasy.AgentCall{
-		Prompt: prompt,
-	})
+	agent := fantasy.NewAgent(a.model,
+		fantasy.WithSystemPrompt(systemPrompt),
+		fantasy.WithTools(tools...),
+		fantasy.WithStopConditions(fantasy.HasToolCall("submit_assessment"), fantasy.StepCountIs(maxAgentSteps)),
+	)
+	var result *fantasy.AgentResult
+	if a.streamCb != nil {
+		stream := &packageStream{cb: a.streamCb, pkg: pkg}
+		result, err = agent.Stream(ctx, stream.agentStreamCall(prompt))
+		stream.flush()
+	} else {
+		result, err = agent.Generate(ctx, fantasy.AgentCall{
+			Prompt: prompt,
+		})
+	}
 	if err != nil {
 		return fmt.Errorf("agent generation failed: %w", err)
 	}
+	usage := a.recordUsage(result.TotalUsage)
+
+	// Without a submitted assessment the zero value would read as SAFE
+	if !submitted {
+		return fmt.Errorf("%w for %s@%s after %d steps", ErrNoAssessment, pkg.Name, pkg.Version, len(result.Steps))
+	}
 
 	// Save the analysis
-	if err := a.saveAnalysis(pkg.OutputDir, report); err != nil {
+	if err := a.saveAnalysis(ctx, pkg, report, rules.Matches, &usage); err != nil {
 		return fmt.Errorf("failed to save analysis: %w", err)
 	}
 
 	if report.IsMalicious {
-		a.log(fmt.Sprintf("Flagged %s@%s as MALICIOUS (confidence: %.2f)", pkg.Name, pkg.Version, report.Confidence), "warning")
+		a.log(fmt.Sprintf("Flagged %s@%s as MALICIOUS (confidence: %.2f, %s)", pkg.Name, pkg.Version, report.Confidence, usage), "warning", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
 	} else {
-		a.log(fmt.Sprintf("Analyzed %s@%s — SAFE (confidence: %.2f)", pkg.Name, pkg.Version, report.Confidence), "success")
+		a.log(fmt.Sprintf("Analyzed %s@%s — SAFE (confidence: %.2f, %s)", pkg.Name, pkg.Version, report.Confidence, usage), "success", logging.KeyPackageID, pkg.Name+"@"+pkg.Version)
 	}
 
 	return nil
 }
 
-// formatAnalysisPrompt creates a detailed prompt from the deduped stats
-func formatAnalysisPrompt(name, version string, stats *aggregate.DedupedProcessStats) string {
+// formatAnalysisPrompt creates a detailed prompt about subject, e.g. "npm
+// package: left-pad@1.3.0", from the deduped stats and any rule matches that
+// fell short of flagging the package. Lists longer than maxIndicators are
+// sampled (zero uses DefaultMaxIndicators).
+func formatAnalysisPrompt(subject string, stats *aggregate.DedupedProcessStats, rules RuleResult, maxIndicators int) string {
 	var sb strings.Builder
+	sampler := newIndicatorSampler(maxIndicators)
 
-	sb.WriteString(fmt.Sprintf("Analyze the security of npm package: %s@%s\n\n", name, version))
+	sb.WriteString(fmt.Sprintf("Analyze the security of %s\n\n", subject))
 	sb.WriteString("DEDUPED BEHAVIORAL DATA (anomalous activity only):\n")
 	sb.WriteString(fmt.Sprintf("Total unique processes: %d\n", stats.CountProcesses))
 	sb.WriteString(fmt.Sprintf("Filtered from baseline: %d processes, %d files, %d commands, %d syscalls\n\n",
 		stats.RemovedProcesses, stats.RemovedFiles, stats.RemovedCommands, stats.RemovedSyscalls))
 
-	for procName, proc := range stats.PerProcess {
-		sb.WriteString(fmt.Sprintf("\n=== PROCESS: %s ===\n", procName))
+	if signals := stats.Signals; signals != nil {
+		sb.WriteString("PACKAGE SIGNALS:\n")
+		sb.WriteString(fmt.Sprintf("  - Weekly npm downloads: %d\n", signals.WeeklyDownloads))
+		sb.WriteString(fmt.Sprintf("  - Runs install scripts: %t\n\n", signals.InstallScript))
+	}
 
-		if len(proc.SyscallProfile) > 0 {
-			sb.WriteString("\nSyscalls:\n")
-			for syscall, count := range proc.SyscallProfile {
-				sb.WriteString(fmt.Sprintf("  - %s: %d calls\n", syscall, count))
-			}
+	if usage := stats.ResourceUsage; usage != nil {
+		sb.WriteString("SANDBOX RESOURCE USAGE:\n")
+		sb.WriteString(fmt.Sprintf("  - CPU time: %.1fs user, %.1fs system over %.1fs wall (utilization %.2f cores)\n",
+			usage.CPUUserSeconds, usage.CPUSystemSeconds, usage.WallSeconds, usage.CPUUtilization))
+		sb.WriteString(fmt.Sprintf("  - Peak memory: %d bytes\n", usage.MemoryPeakBytes))
+		sb.WriteString(fmt.Sprintf("  - Disk writes: %d bytes\n", usage.DiskWriteBytes))
+		if usage.CPUBurn {
+			sb.WriteString("  - WARNING: sustained CPU burn consistent with cryptomining\n")
 		}
+		sb.WriteString("\n")
+	}
 
-		if len(proc.FileAccess) > 0 {
-			sb.WriteString("\nFile Access:\n")
-			for file, count := range proc.FileAccess {
-				sb.WriteString(fmt.Sprintf("  - %s: %d accesses\n", file, count))
-			}
+	writeProcesses(&sb, stats.PerProcess, sampler)
+
+	if stats.HTTPActivity != nil && len(stats.HTTPActivity.Requests) > 0 {
+		sb.WriteString("\n=== HTTP(S) REQUESTS (captured by intercepting proxy) ===\n")
+		for _, req := range stats.HTTPActivity.Requests {
+			sb.WriteString(fmt.Sprintf("  - %s %s: %d requests, %d bytes sent, %d bytes received\n",
+				req.Method, req.URL, req.Count, req.BytesSent, req.BytesReceived))
 		}
+	}
 
-		if len(proc.ExecutedCommands) > 0 {
-			sb.WriteString("\nExecuted Commands:\n")
-			for cmd, count := range proc.ExecutedCommands {
-				sb.WriteString(fmt.Sprintf("  - %s: %d executions\n", cmd, count))
+	if len(stats.URLIndicators) > 0 {
+		sb.WriteString("\n=== URLS IN EXECUTED COMMAND LINES ===\n")
+		for _, ind := range stats.URLIndicators {
+			observed := "no DNS/connection seen"
+			if ind.Observed {
+				observed = "host contacted"
 			}
+			sb.WriteString(fmt.Sprintf("  - %s [%s, %s] in %s: %d times\n",
+				ind.URL, ind.Class, observed, strings.Join(ind.Processes, ", "), ind.Count))
 		}
+	}
 
-		if len(proc.NetworkActivity.IPs) > 0 {
-			sb.WriteString("\nNetwork Connections:\n")
-			for ip, count := range proc.NetworkActivity.IPs {
-				sb.WriteString(fmt.Sprintf("  - %s: %d connections\n", ip, count))
+	if len(stats.Intel) > 0 {
+		sb.WriteString("\n=== THREAT INTEL ON CONTACTED IPS AND DOMAINS ===\n")
+		for _, f := range stats.Intel {
+			var context []string
+			if f.ASN != 0 {
+				context = append(context, fmt.Sprintf("AS%d %s (%s)", f.ASN, f.ASOrg, f.Country))
+			}
+			if len(f.Verdicts) > 0 {
+				context = append(context, "listed as: "+strings.Join(f.Verdicts, ", "))
 			}
+			sb.WriteString(fmt.Sprintf("  - %s %s: %s\n", f.Kind, f.Indicator, strings.Join(context, "; ")))
 		}
+	}
 
-		if len(proc.NetworkActivity.DNSRecords) > 0 {
-			sb.WriteString("\nDNS Lookups:\n")
-			for domain, count := range proc.NetworkActivity.DNSRecords {
-				sb.WriteString(fmt.Sprintf("  - %s: %d lookups\n", domain, count))
-			}
+	if len(stats.EnvironmentConditional) > 0 {
+		sb.WriteString("\n\n##### ENVIRONMENT-CONDITIONAL BEHAVIOR (seen under only one variant — evasion indicator) #####\n")
+		for _, b := range stats.EnvironmentConditional {
+			sb.WriteString(fmt.Sprintf("  - [%s] %s %s (process %s, %d times)\n", b.Variant, b.Kind, b.Value, b.Process, b.Count))
+		}
+	}
+
+	writePhaseView(&sb, stats.Phases)
+
+	for variantName, variant := range stats.Variants {
+		if len(variant.PerProcess) == 0 {
+			continue
 		}
+		sb.WriteString(fmt.Sprintf("\n\n##### ENVIRONMENT VARIANT: %s (install + import rerun) #####\n", variantName))
+		writeProcesses(&sb, variant.PerProcess, sampler)
+	}
+
+	sb.WriteString(formatRuleMatches(rules))
+
+	if sampler.sampled > 0 {
+		sb.WriteString(fmt.Sprintf("\n\nNOTE: %d activity lists exceeded %d entries and were sampled (see the [SAMPLED] lines). "+
+			"Sensitive paths are always listed; absence of an entry from a sampled list is not evidence it did not occur.", sampler.sampled, sampler.max))
 	}
 
 	sb.WriteString("\n\nUse the submit_assessment tool to provide your security assessment.")
@@ -292,11 +584,148 @@ func formatAnalysisPrompt(name, version string, stats *aggregate.DedupedProcessS
 	return sb.String()
 }
 
-// saveAnalysis saves the assessment to ai-analysis.json
-func (a *Analyzer) saveAnalysis(outputDir string, assessment SecurityAssessment) error {
-	analysisPath := filepath.Join(outputDir, "ai-analysis.json")
+// hasVariantAnomalies reports whether any env matrix variant showed anomalous behavior
+func hasVariantAnomalies(stats *aggregate.DedupedProcessStats) bool {
+	if len(stats.EnvironmentConditional) > 0 {
+		return true
+	}
+	for _, variant := range stats.Variants {
+		if len(variant.PerProcess) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// maxPhaseRows caps the indicators listed by test phase in the prompt
+const maxPhaseRows = 60
+
+// writePhaseView formats the diff's activity side by side per test phase,
+// indicators seen in only one phase first
+func writePhaseView(sb *strings.Builder, view *aggregate.PhaseView) {
+	if view == nil || len(view.Phases) < 2 {
+		return
+	}
+	sb.WriteString(fmt.Sprintf("\n\n##### BEHAVIOR BY TEST PHASE (%s) #####\n", strings.Join(view.Phases, " | ")))
+	written := 0
+	for _, single := range []bool{true, false} {
+		for _, row := range view.Rows {
+			if (row.Only() != "") != single {
+				continue
+			}
+			if written == maxPhaseRows {
+				sb.WriteString(fmt.Sprintf("  ... %d more\n", len(view.Rows)-written))
+				return
+			}
+			counts := make([]string, len(view.Phases))
+			for i, phase := range view.Phases {
+				counts[i] = "-"
+				if n, ok := row.Counts[phase]; ok {
+					counts[i] = fmt.Sprint(n)
+				}
+			}
+			sb.WriteString(fmt.Sprintf("  - %s %s: %s", row.Kind, row.Value, strings.Join(counts, " | ")))
+			if only := row.Only(); only != "" {
+				sb.WriteString(fmt.Sprintf(" [%s only]", only))
+			}
+			sb.WriteString("\n")
+			written++
+		}
+	}
+}
+
+// writeProcesses formats per-process deduped activity for the prompt, each
+// list sampled down to the sampler's cap
+func writeProcesses(sb *strings.Builder, perProcess map[string]*aggregate.ProcessSummary, sampler *indicatorSampler) {
+	for procName, proc := range perProcess {
+		sb.WriteString(fmt.Sprintf("\n=== PROCESS: %s ===\n", procName))
+
+		sampler.writeList(sb, "Syscalls", "calls", proc.SyscallProfile, sampleRule{}, func(syscall string, count int) string {
+			return fmt.Sprintf("  - %s: %d calls\n", syscall, count)
+		})
+		sampler.writeList(sb, "File Access", "accesses", proc.FileAccess, fileRule, func(file string, count int) string {
+			return fmt.Sprintf("  - %s: %d accesses\n", file, count)
+		})
+		sampler.writeList(sb, "Files Opened for Writing", "writes", proc.FileWrites, fileRule, func(file string, count int) string {
+			return fmt.Sprintf("  - %s: %d writes\n", file, count)
+		})
+		sampler.writeList(sb, "Files Created", "creates", proc.FileCreates, fileRule, func(file string, count int) string {
+			return fmt.Sprintf("  - %s: %d creates\n", file, count)
+		})
+		sampler.writeList(sb, "Executed Commands", "executions", proc.ExecutedCommands, commandRule, func(cmd string, count int) string {
+			return fmt.Sprintf("  - %s: %d executions\n", cmd, count)
+		})
+		sampler.writeList(sb, "Command Lines", "executions", proc.CommandLines, cmdlineRule, func(cmdline string, count int) string {
+			return fmt.Sprintf("  - %q: %d executions\n", cmdline, count)
+		})
+
+		if env := proc.EnvAccess; env != nil {
+			sb.WriteString("\nEnvironment Access:\n")
+			for path, count := range env.Environ {
+				sb.WriteString(fmt.Sprintf("  - read %s: %d times\n", path, count))
+			}
+			for name, count := range env.Secrets {
+				sb.WriteString(fmt.Sprintf("  - secret %s passed to commands: %d executions\n", name, count))
+			}
+		}
 
-	jsonBytes, err := json.MarshalIndent(assessment, "", "  ")
+		sampler.writeList(sb, "Network Connections", "connections", proc.NetworkActivity.IPs, sampleRule{}, func(ip string, count int) string {
+			return fmt.Sprintf("  - %s: %d connections\n", ip, count)
+		})
+
+		transferred := make(map[string]int, len(proc.NetworkActivity.Transfers))
+		for dest, t := range proc.NetworkActivity.Transfers {
+			transferred[dest] = int(t.BytesSent + t.BytesReceived)
+		}
+		sampler.writeList(sb, "Data Transferred", "bytes", transferred, sampleRule{}, func(dest string, _ int) string {
+			t := proc.NetworkActivity.Transfers[dest]
+			return fmt.Sprintf("  - %s: %d bytes sent, %d bytes received\n", dest, t.BytesSent, t.BytesReceived)
+		})
+
+		sampler.writeList(sb, "DNS Lookups", "lookups", proc.NetworkActivity.DNSRecords, domainRule, func(domain string, count int) string {
+			if unicode, idn := aggregate.UnicodeDomain(domain); idn {
+				return fmt.Sprintf("  - %s (IDN, renders as %q): %d lookups\n", domain, unicode, count)
+			}
+			return fmt.Sprintf("  - %s: %d lookups\n", domain, count)
+		})
+	}
+}
+
+// saveAnalysis saves the assessment to pkg's ai-analysis.json, along with the
+// rule matches and the usage of the LLM call, if any. The justification is
+// localized first, see SetLanguage.
+func (a *Analyzer) saveAnalysis(ctx context.Context, pkg PackageInfo, assessment SecurityAssessment, rules []RuleMatch, usage *Usage) error {
+	analysisPath := filepath.Join(pkg.OutputDir, "ai-analysis.json")
+
+	original := a.localize(ctx, pkg, &assessment)
+	language := ""
+	if original != "" {
+		language = a.language
+	}
+
+	// The generator, rule matches, static scan, usage and English original
+	// are added here rather than to SecurityAssessment, which doubles as the
+	// model's tool schema
+	var staticScore float64
+	var staticFindings []static.Finding
+	var staticEvidence []static.FileEvidence
+	var installScripts []static.InstallScript
+	if pkg.Static != nil {
+		staticScore, staticFindings, staticEvidence = pkg.Static.Score, pkg.Static.Findings, pkg.Static.Evidence
+		installScripts = pkg.Static.InstallScripts
+	}
+	jsonBytes, err := json.MarshalIndent(struct {
+		SecurityAssessment
+		Language              string                 `json:"language,omitempty"`
+		OriginalJustification string                 `json:"original_justification,omitempty"`
+		Rules                 []RuleMatch            `json:"rules,omitempty"`
+		StaticScore           float64                `json:"static_score,omitempty"`
+		StaticFindings        []static.Finding       `json:"static_findings,omitempty"`
+		Evidence              []static.FileEvidence  `json:"evidence,omitempty"`
+		InstallScripts        []static.InstallScript `json:"install_scripts,omitempty"`
+		Usage                 *Usage                 `json:"usage,omitempty"`
+		Generator             *version.Info          `json:"generator"`
+	}{assessment, language, original, rules, staticScore, staticFindings, staticEvidence, installScripts, usage, version.Stamp()}, "", "  ")
 	if err != nil {
 		return fmt.Errorf("failed to marshal assessment: %w", err)
 	}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// brokenModel panics on every call, as a buggy provider would
+type brokenModel struct{ fantasy.LanguageModel }
+
+func TestAnalyzePackagesRecoversPanic(t *testing.T) {
+	dir := t.TempDir()
+	diff := `{"per_process":{"node":{"executed_commands":{"curl":1}}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "diff.json"), []byte(diff), 0o644))
+
+	a := &Analyzer{model: brokenModel{}, semaphore: make(chan struct{}, 1), logger: slog.Default()}
+	err := a.AnalyzePackages(context.Background(), []PackageInfo{{Name: "left-pad", Version: "1.0.0", OutputDir: dir}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "left-pad@1.0.0")
+	assert.Contains(t, err.Error(), "panic")
+}
+
+// scriptedModel answers every step with the same content
+type scriptedModel struct {
+	fantasy.LanguageModel
+	content fantasy.ResponseContent
+	calls   int
+}
+
+func (m *scriptedModel) Generate(context.Context, fantasy.Call) (*fantasy.Response, error) {
+	m.calls++
+	reason := fantasy.FinishReasonStop
+	for _, c := range m.content {
+		if c.GetType() == fantasy.ContentTypeToolCall {
+			reason = fantasy.FinishReasonToolCalls
+		}
+	}
+	return &fantasy.Response{Content: m.content, FinishReason: reason}, nil
+}
+
+// noopSource offers a drill-down tool that finds nothing
+type noopSource struct{}
+
+func (noopSource) Name() string { return "noop" }
+
+func (noopSource) Tools(context.Context, PackageInfo) ([]fantasy.AgentTool, error) {
+	return []fantasy.AgentTool{fantasy.NewAgentTool("noop", "Finds nothing", func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+		return fantasy.NewTextResponse("nothing found"), nil
+	})}, nil
+}
+
+func TestAnalysisRequiresSubmittedAssessment(t *testing.T) {
+	diff := `{"per_process":{"node":{"executed_commands":{"curl":1}}}}`
+	tests := []struct {
+		name    string
+		content fantasy.ResponseContent
+		calls   int
+	}{
+		{"answers in prose", fantasy.ResponseContent{fantasy.TextContent{Text: "Looks fine to me."}}, 1},
+		{"drills down until the step limit", fantasy.ResponseContent{fantasy.ToolCallContent{ToolCallID: "1", ToolName: "noop", Input: `{}`}}, maxAgentSteps},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			require.NoError(t, os.WriteFile(filepath.Join(dir, "diff.json"), []byte(diff), 0o644))
+
+			model := &scriptedModel{content: tt.content}
+			a := &Analyzer{model: model, semaphore: make(chan struct{}, 1), logger: slog.Default()}
+			a.SetSources(noopSource{})
+			err := a.AnalyzePackages(context.Background(), []PackageInfo{{Name: "left-pad", Version: "1.0.0", OutputDir: dir}})
+			require.ErrorIs(t, err, ErrNoAssessment)
+			assert.Equal(t, tt.calls, model.calls)
+			assert.NoFileExists(t, filepath.Join(dir, "ai-analysis.json"), "no verdict is recorded")
+		})
+	}
+}
+
+func TestAnalysisSavesFileEvidence(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "diff.json"), []byte(`{}`), 0o644))
+
+	evidence := []static.FileEvidence{{Path: "postinstall.js", SHA256: "ab12", Size: 42, Reasons: []string{"eval of decoded string"}, Line: 3, Excerpt: "eval(atob('...'))\n"}}
+	report := &static.Report{
+		Score:    0.6,
+		Findings: []static.Finding{{Kind: static.KindObfuscated, Path: "postinstall.js", Detail: "eval of decoded string"}},
+		Evidence: evidence,
+	}
+	a := NewRulesAnalyzer(1)
+	require.NoError(t, a.AnalyzePackages(context.Background(), []PackageInfo{{Name: "evil", Version: "1.0.0", OutputDir: dir, Static: report}}))
+
+	data, err := os.ReadFile(filepath.Join(dir, "ai-analysis.json"))
+	require.NoError(t, err)
+	var saved struct {
+		StaticScore    float64               `json:"static_score"`
+		StaticFindings []static.Finding      `json:"static_findings"`
+		Evidence       []static.FileEvidence `json:"evidence"`
+	}
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.Equal(t, evidence, saved.Evidence)
+	assert.Equal(t, 0.6, saved.StaticScore)
+	assert.Equal(t, report.Findings, saved.StaticFindings)
+
+	prompt := formatStaticReport(report)
+	assert.Contains(t, prompt, "static risk score 0.60")
+	assert.Contains(t, prompt, "[obfuscated_code] postinstall.js: eval of decoded string")
+	assert.Contains(t, prompt, "postinstall.js (sha256 ab12, 42 bytes): eval of decoded string, from line 3")
+	assert.Empty(t, formatStaticReport(nil))
+}
+
+func TestPackageInfoSubject(t *testing.T) {
+	assert.Equal(t, "npm package: left-pad@1.3.0", PackageInfo{Name: "left-pad", Version: "1.3.0"}.subject())
+	assert.Equal(t, "container image: nginx:1.25", PackageInfo{Name: "nginx", Version: "1.25", Image: true}.subject())
+	assert.Equal(t, "container image: ghcr.io/owner/tool@sha256:ab", PackageInfo{Name: "ghcr.io/owner/tool", Version: "sha256:ab", Image: true}.subject())
+	assert.Equal(t, "GitHub Action: actions/setup-node@v4", PackageInfo{Name: "actions/setup-node", Version: "v4", Action: true}.subject())
+}
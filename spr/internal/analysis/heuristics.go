@@ -0,0 +1,421 @@
+package analysis
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// RuleMaliciousScore is the combined rule score at or above which a package
+// is flagged without consulting the LLM. Anything lower, including diffs no
+// rule matched, is ambiguous and escalated.
+const RuleMaliciousScore = 0.9
+
+// Rule is a deterministic check over a diff. Score is how strongly a match
+// alone indicates malice, from 0 to 1.
+type Rule struct {
+	Name        string
+	Description string
+	Score       float64
+	// Match returns the evidence the rule matched on, nil if it didn't
+	Match func(stats *aggregate.DedupedProcessStats) []string
+}
+
+// RuleMatch is a rule that matched a diff and the evidence it matched on
+type RuleMatch struct {
+	Rule     string   `json:"rule"`
+	Score    float64  `json:"score"`
+	Evidence []string `json:"evidence"`
+}
+
+// RuleResult is the outcome of running the rules over a diff
+type RuleResult struct {
+	Score   float64     // Combined score: 1 - Π(1 - score) over the matches
+	Matches []RuleMatch // In rule order
+}
+
+// Decisive reports whether the rules alone are enough to flag the package
+func (r RuleResult) Decisive() bool {
+	return r.Score >= RuleMaliciousScore
+}
+
+// Assessment turns a decisive result into the assessment saved for the package
+func (r RuleResult) Assessment() SecurityAssessment {
+	names := make([]string, 0, len(r.Matches))
+	var indicators []string
+	for _, m := range r.Matches {
+		names = append(names, m.Rule)
+		for _, e := range m.Evidence {
+			indicators = append(indicators, fmt.Sprintf("%s: %s", m.Rule, e))
+		}
+	}
+	return SecurityAssessment{
+		IsMalicious:   true,
+		Confidence:    r.Score,
+		Justification: fmt.Sprintf("Flagged by deterministic rules (%s) without LLM review.", strings.Join(names, ", ")),
+		Indicators:    indicators,
+	}
+}
+
+// Cleared turns a result that isn't decisive into the assessment saved when
+// no LLM reviews the package, see NewRulesAnalyzer
+func (r RuleResult) Cleared() SecurityAssessment {
+	assessment := SecurityAssessment{
+		Confidence:    math.Round((1-r.Score)*1e6) / 1e6,
+		Justification: "No deterministic rule matched; not reviewed by an LLM (rules-only analysis).",
+	}
+	if len(r.Matches) > 0 {
+		names := make([]string, 0, len(r.Matches))
+		for _, m := range r.Matches {
+			names = append(names, m.Rule)
+			for _, e := range m.Evidence {
+				assessment.Indicators = append(assessment.Indicators, fmt.Sprintf("%s: %s", m.Rule, e))
+			}
+		}
+		assessment.Justification = fmt.Sprintf("Rules matched (%s) below the flagging threshold (score %.2f); not reviewed by an LLM (rules-only analysis).", strings.Join(names, ", "), r.Score)
+	}
+	return assessment
+}
+
+// Mining pool domains and stratum endpoints
+var miningPools = []string{
+	"stratum+tcp://", "stratum+ssl://", "stratum2+tcp://",
+	"minexmr.com", "supportxmr.com", "moneroocean.stream", "nanopool.org",
+	"2miners.com", "f2pool.com", "hashvault.pro", "c3pool.com", "minergate.com",
+	"xmrpool.eu", "herominers.com", "unmineable.com", "nicehash.com",
+}
+
+// Miner binaries, matched against process names and executed paths
+var minerBinaries = []string{"xmrig", "xmr-stak", "cpuminer", "minerd", "nbminer", "lolminer"}
+
+// LowPopularityDownloads is the weekly download count under which a package
+// counts as very low popularity: typosquats and freshly published payloads
+// rarely get past it before they are taken down
+const LowPopularityDownloads = 100
+
+// Credential files no package has a reason to read
+var sensitiveFiles = []string{
+	"/etc/shadow", "/etc/gshadow", "/etc/sudoers",
+	"/.ssh/id_rsa", "/.ssh/id_ed25519", "/.ssh/id_ecdsa", "/.ssh/id_dsa",
+	"/.aws/credentials", "/.docker/config.json", "/.kube/config",
+}
+
+// CanaryPrefix marks the fake secrets the GitHub Action workflow hands the
+// action under test (GITHUB_TOKEN, cloud and registry credentials). Nothing
+// legitimate puts them in a command line, DNS query or request URL.
+const CanaryPrefix = "sprcanary"
+
+var (
+	// base64 decoding piped into a shell, or a decoded payload eval'd
+	base64ShellPattern = regexp.MustCompile(`(?i)base64\s+(?:-d|--decode|-D)\b[^|;&]*\|\s*(?:sudo\s+)?(?:ba|z|da|k)?sh\b|(?:eval|exec)\s*[("$]+\s*(?:echo|printf)[^)]*\|\s*base64\s+(?:-d|--decode|-D)`)
+	// A download piped straight into a shell
+	pipeToShellPattern = regexp.MustCompile(`(?i)\b(?:curl|wget)\b[^|;&]*\|\s*(?:sudo\s+)?(?:ba|z|da|k)?sh\b`)
+	// URLs in a command line
+	commandURLPattern = regexp.MustCompile(`(?i)\b[a-z][a-z0-9+.-]*://[^\s'"]+`)
+)
+
+// DefaultRules are the rules the analyzer runs before escalating to the LLM
+var DefaultRules = []Rule{
+	{
+		Name:        "mining-pool",
+		Description: "Contacts a cryptocurrency mining pool or runs a known miner",
+		Score:       0.95,
+		Match:       matchMining,
+	},
+	{
+		Name:        "credential-file-read",
+		Description: "Reads /etc/shadow, SSH private keys or cloud credentials",
+		Score:       0.9,
+		Match:       matchSensitiveFiles,
+	},
+	{
+		Name:        "base64-shell",
+		Description: "Decodes a base64 payload into a shell",
+		Score:       0.95,
+		Match:       matchCommandLines(base64ShellPattern),
+	},
+	{
+		Name:        "pipe-to-shell",
+		Description: "Pipes a download (curl/wget) into a shell",
+		Score:       0.9,
+		Match:       matchCommandLines(pipeToShellPattern),
+	},
+	{
+		Name:        "cpu-burn",
+		Description: "Sustained CPU burn consistent with cryptomining",
+		Score:       0.5,
+		Match: func(stats *aggregate.DedupedProcessStats) []string {
+			if stats.ResourceUsage != nil && stats.ResourceUsage.CPUBurn {
+				return []string{fmt.Sprintf("CPU utilization %.2f cores", stats.ResourceUsage.CPUUtilization)}
+			}
+			return nil
+		},
+	},
+	{
+		Name:        "obscure-install-network",
+		Description: "Very low popularity package whose install script talks to the network",
+		Score:       0.6,
+		Match:       matchObscureInstallNetwork,
+	},
+	{
+		Name:        "canary-secret-leak",
+		Description: "Passes a canary secret of the sandbox on in a command line, DNS query or request URL",
+		Score:       0.95,
+		Match:       matchCanaries,
+	},
+	{
+		Name:        "raw-ip-download",
+		Description: "Command line fetches a URL on a public IP address",
+		Score:       0.4,
+		Match: func(stats *aggregate.DedupedProcessStats) []string {
+			var evidence []string
+			for _, ind := range stats.URLIndicators {
+				if ind.Class == aggregate.HostPublicIP {
+					evidence = append(evidence, ind.URL)
+				}
+			}
+			return evidence
+		},
+	},
+}
+
+// RulesetHash identifies a rule set and the flagging threshold as a hex
+// SHA-256. Match functions can't be hashed, so it covers their names,
+// scores and descriptions along with the patterns and lists the default
+// rules match against; the spr build identifies the code itself.
+func RulesetHash(rules []Rule) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "threshold %g\n", RuleMaliciousScore)
+	fmt.Fprintf(h, "low popularity %d\n", LowPopularityDownloads)
+	for _, r := range rules {
+		fmt.Fprintf(h, "rule %s %g %s\n", r.Name, r.Score, r.Description)
+	}
+	for _, list := range [][]string{miningPools, minerBinaries, sensitiveFiles, {CanaryPrefix}} {
+		fmt.Fprintf(h, "list %s\n", strings.Join(list, ","))
+	}
+	for _, pattern := range []*regexp.Regexp{base64ShellPattern, pipeToShellPattern, commandURLPattern} {
+		fmt.Fprintf(h, "pattern %s\n", pattern)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ClassifyRules runs rules over a diff, including its environment variants
+func ClassifyRules(stats *aggregate.DedupedProcessStats, rules []Rule) RuleResult {
+	var result RuleResult
+	clean := 1.0
+	for _, rule := range rules {
+		evidence := dedupeSorted(matchWithVariants(stats, rule.Match))
+		if len(evidence) == 0 {
+			continue
+		}
+		result.Matches = append(result.Matches, RuleMatch{Rule: rule.Name, Score: rule.Score, Evidence: evidence})
+		clean *= 1 - rule.Score
+	}
+	// Round away float noise so identical diffs always produce identical files
+	result.Score = math.Round((1-clean)*1e6) / 1e6
+	return result
+}
+
+// matchWithVariants applies match to the diff and every environment variant
+func matchWithVariants(stats *aggregate.DedupedProcessStats, match func(*aggregate.DedupedProcessStats) []string) []string {
+	evidence := match(stats)
+	for name, variant := range stats.Variants {
+		for _, e := range match(variant) {
+			evidence = append(evidence, fmt.Sprintf("%s (variant %s)", e, name))
+		}
+	}
+	return evidence
+}
+
+func matchMining(stats *aggregate.DedupedProcessStats) []string {
+	var evidence []string
+	isPool := func(s string) bool { return containsAny(strings.ToLower(s), miningPools) }
+	for procName, proc := range stats.PerProcess {
+		if containsAny(strings.ToLower(aggregate.ProcessNameFromKey(procName)), minerBinaries) {
+			evidence = append(evidence, "process "+procName)
+		}
+		for cmd := range proc.ExecutedCommands {
+			if containsAny(strings.ToLower(cmd), minerBinaries) {
+				evidence = append(evidence, "executed "+cmd)
+			}
+		}
+		for cmdline := range proc.CommandLines {
+			if isPool(cmdline) {
+				evidence = append(evidence, "command line "+cmdline)
+			}
+		}
+		for domain := range proc.NetworkActivity.DNSRecords {
+			if isPool(domain) {
+				evidence = append(evidence, "DNS lookup "+domain)
+			}
+		}
+		for u := range proc.NetworkActivity.URLs {
+			if isPool(u) {
+				evidence = append(evidence, "URL "+u)
+			}
+		}
+	}
+	if stats.HTTPActivity != nil {
+		for host := range stats.HTTPActivity.Hosts {
+			if isPool(host) {
+				evidence = append(evidence, "HTTP request to "+host)
+			}
+		}
+	}
+	return evidence
+}
+
+func matchSensitiveFiles(stats *aggregate.DedupedProcessStats) []string {
+	var evidence []string
+	for procName, proc := range stats.PerProcess {
+		for file := range proc.FileAccess {
+			if containsAny(file, sensitiveFiles) {
+				verb := "read"
+				if proc.FileWrites[file] > 0 {
+					verb = "written"
+				}
+				evidence = append(evidence, fmt.Sprintf("%s %s by %s", file, verb, procName))
+			}
+		}
+	}
+	return evidence
+}
+
+// matchObscureInstallNetwork combines three signals that are each common on
+// their own: a package almost nobody downloads, that runs code on install,
+// and whose install contacted the network
+func matchObscureInstallNetwork(stats *aggregate.DedupedProcessStats) []string {
+	signals := stats.Signals
+	if signals == nil || !signals.InstallScript || signals.WeeklyDownloads >= LowPopularityDownloads {
+		return nil
+	}
+
+	var hosts []string
+	for _, proc := range stats.PerProcess {
+		for domain := range proc.NetworkActivity.DNSRecords {
+			hosts = append(hosts, domain)
+		}
+		for ip := range proc.NetworkActivity.IPs {
+			hosts = append(hosts, ip)
+		}
+		for dest := range proc.NetworkActivity.Transfers {
+			hosts = append(hosts, dest)
+		}
+	}
+	if stats.HTTPActivity != nil {
+		for host := range stats.HTTPActivity.Hosts {
+			hosts = append(hosts, host)
+		}
+	}
+	if len(hosts) == 0 {
+		return nil
+	}
+	return []string{fmt.Sprintf("%d weekly downloads, install script contacted %s", signals.WeeklyDownloads, strings.Join(dedupeSorted(hosts), ", "))}
+}
+
+// matchCanaries finds canary secrets (see CanaryPrefix) sent anywhere but
+// GitHub, where an action's token legitimately goes: command lines passing
+// one alongside a URL of another host, DNS queries carrying one, and
+// requests with one in their URL
+func matchCanaries(stats *aggregate.DedupedProcessStats) []string {
+	var evidence []string
+	for _, proc := range stats.PerProcess {
+		for cmdline := range proc.CommandLines {
+			if !strings.Contains(cmdline, CanaryPrefix) {
+				continue
+			}
+			for _, url := range commandURLPattern.FindAllString(cmdline, -1) {
+				if !isGitHubURL(url) {
+					evidence = append(evidence, "command: "+cmdline)
+					break
+				}
+			}
+		}
+		for domain := range proc.NetworkActivity.DNSRecords {
+			if strings.Contains(strings.ToLower(domain), CanaryPrefix) {
+				evidence = append(evidence, "DNS query: "+domain)
+			}
+		}
+	}
+	if stats.HTTPActivity != nil {
+		for _, req := range stats.HTTPActivity.Requests {
+			if strings.Contains(req.URL, CanaryPrefix) && !isGitHubURL(req.URL) {
+				evidence = append(evidence, fmt.Sprintf("request: %s %s", req.Method, req.URL))
+			}
+		}
+	}
+	return evidence
+}
+
+// isGitHubURL reports whether url is on github.com or one of its API and
+// content hosts
+func isGitHubURL(url string) bool {
+	host, ok := strings.CutPrefix(url, "https://")
+	if !ok {
+		return false
+	}
+	host, _, _ = strings.Cut(host, "/")
+	host = strings.ToLower(host)
+	for _, domain := range []string{"github.com", "githubusercontent.com"} {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCommandLines returns a matcher for command lines matching pattern
+func matchCommandLines(pattern *regexp.Regexp) func(*aggregate.DedupedProcessStats) []string {
+	return func(stats *aggregate.DedupedProcessStats) []string {
+		var evidence []string
+		for _, proc := range stats.PerProcess {
+			for cmdline := range proc.CommandLines {
+				if pattern.MatchString(cmdline) {
+					evidence = append(evidence, cmdline)
+				}
+			}
+		}
+		return evidence
+	}
+}
+
+func containsAny(s string, substrs []string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeSorted sorts s and drops duplicates, so results don't depend on map
+// iteration order
+func dedupeSorted(s []string) []string {
+	sort.Strings(s)
+	out := s[:0]
+	for i, v := range s {
+		if i == 0 || v != s[i-1] {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// formatRuleMatches describes non-decisive rule matches for the LLM prompt
+func formatRuleMatches(result RuleResult) string {
+	if len(result.Matches) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("\n\n##### DETERMINISTIC RULE MATCHES (combined score %.2f, below the %.2f auto-flag threshold) #####\n", result.Score, RuleMaliciousScore))
+	for _, m := range result.Matches {
+		sb.WriteString(fmt.Sprintf("  - %s (score %.2f): %s\n", m.Rule, m.Score, strings.Join(m.Evidence, "; ")))
+	}
+	return sb.String()
+}
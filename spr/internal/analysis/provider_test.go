@@ -0,0 +1,54 @@
+package analysis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderConfigResolve(t *testing.T) {
+	cfg, err := ProviderConfig{APIKey: "key"}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, ProviderOpenAI, cfg.Provider)
+	assert.Equal(t, "https://cope.duti.dev", cfg.BaseURL)
+	assert.Equal(t, "gpt-5-mini", cfg.Model)
+
+	cfg, err = ProviderConfig{Provider: "Anthropic", APIKey: "key", Model: "claude-haiku-4-5"}.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, ProviderAnthropic, cfg.Provider)
+	assert.Equal(t, "https://api.anthropic.com/v1", cfg.BaseURL)
+	assert.Equal(t, "claude-haiku-4-5", cfg.Model)
+
+	// Local servers run without a key
+	ollama := ProviderConfig{Provider: ProviderOllama}
+	assert.True(t, ollama.Enabled())
+	cfg, err = ollama.Resolve()
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:11434/v1", cfg.BaseURL)
+
+	assert.False(t, ProviderConfig{Provider: ProviderAnthropic}.Enabled())
+	_, err = ProviderConfig{Provider: ProviderAnthropic}.Resolve()
+	assert.Error(t, err)
+
+	_, err = ProviderConfig{Provider: ProviderCompat}.Resolve()
+	assert.ErrorContains(t, err, "base URL and a model")
+	_, err = ProviderConfig{Provider: ProviderCompat, BaseURL: "http://localhost:8000/v1", Model: "qwen"}.Resolve()
+	assert.NoError(t, err)
+
+	_, err = ProviderConfig{Provider: "gemini", APIKey: "key"}.Resolve()
+	assert.ErrorContains(t, err, "unknown AI provider")
+}
+
+func TestNewAnalyzerProviders(t *testing.T) {
+	for _, cfg := range []ProviderConfig{
+		{APIKey: "key"},
+		{Provider: ProviderAnthropic, APIKey: "key"},
+		{Provider: ProviderOllama},
+	} {
+		model, err := cfg.languageModel(context.Background())
+		require.NoError(t, err, cfg.Provider)
+		assert.NotNil(t, model)
+	}
+}
@@ -0,0 +1,185 @@
+package analysis
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// DefaultMaxIndicators caps each list of a process's activity in the prompt,
+// e.g. its file accesses. A package walking node_modules opens thousands of
+// files; listing them all costs tokens and buries what matters.
+const DefaultMaxIndicators = 200
+
+// sampleRule says how a list is reduced to the cap
+type sampleRule struct {
+	// keep selects entries listed regardless of the cap; keepLabel names them
+	keep      func(string) bool
+	keepLabel string
+
+	// group spreads the rest of the sample across groups, e.g. directories,
+	// so one busy group doesn't crowd out the others; groupLabel names them
+	group      func(string) string
+	groupLabel string
+}
+
+var (
+	fileRule    = sampleRule{keep: aggregate.IsSensitivePath, keepLabel: "sensitive paths", group: path.Dir, groupLabel: "directories"}
+	commandRule = sampleRule{group: path.Dir, groupLabel: "directories"}
+	cmdlineRule = sampleRule{group: commandName, groupLabel: "programs"}
+	domainRule  = sampleRule{group: parentDomain, groupLabel: "domains"}
+)
+
+// indicatorSampler writes lists of indicators, sampling those longer than max
+type indicatorSampler struct {
+	max     int
+	sampled int // Lists sampled so far
+}
+
+func newIndicatorSampler(max int) *indicatorSampler {
+	if max <= 0 {
+		max = DefaultMaxIndicators
+	}
+	return &indicatorSampler{max: max}
+}
+
+// writeList writes a titled list, busiest entries first, formatting each with
+// line. Lists over the cap are sampled per rule and end with a note saying
+// what was left out, so the model knows it sees part of the data; unit is
+// what the counts count, e.g. "accesses".
+func (s *indicatorSampler) writeList(sb *strings.Builder, title, unit string, entries map[string]int, rule sampleRule, line func(key string, count int) string) {
+	if len(entries) == 0 {
+		return
+	}
+	sb.WriteString("\n" + title + ":\n")
+
+	keys := sortedByCount(entries)
+	if len(keys) <= s.max {
+		for _, key := range keys {
+			sb.WriteString(line(key, entries[key]))
+		}
+		return
+	}
+
+	shown, kept, groups := s.sample(keys, rule)
+	for _, key := range shown {
+		sb.WriteString(line(key, entries[key]))
+	}
+	s.sampled++
+
+	omitted, omittedCount := len(keys)-len(shown), 0
+	listed := make(map[string]bool, len(shown))
+	for _, key := range shown {
+		listed[key] = true
+	}
+	for _, key := range keys {
+		if !listed[key] {
+			omittedCount += entries[key]
+		}
+	}
+
+	var how []string
+	if kept > 0 {
+		how = append(how, fmt.Sprintf("all %d %s", kept, rule.keepLabel))
+	}
+	if rest := len(shown) - kept; rest > 0 {
+		spread := "the busiest"
+		if groups > 1 {
+			spread = fmt.Sprintf("spread across %d %s", groups, rule.groupLabel)
+		}
+		how = append(how, fmt.Sprintf("%d others %s", rest, spread))
+	}
+	sb.WriteString(fmt.Sprintf("  [SAMPLED: showing %d of %d entries (%s); %d entries with %d %s omitted]\n",
+		len(shown), len(keys), strings.Join(how, ", "), omitted, omittedCount, unit))
+}
+
+// sample picks up to max of keys (sorted busiest first): every entry rule
+// keeps, then the rest round-robin across their groups, busiest groups and
+// entries first. It returns the sample, how many entries were kept by rule
+// and how many groups the rest came from.
+func (s *indicatorSampler) sample(keys []string, rule sampleRule) (shown []string, kept, groups int) {
+	var rest []string
+	for _, key := range keys {
+		if rule.keep != nil && rule.keep(key) {
+			shown = append(shown, key)
+		} else {
+			rest = append(rest, key)
+		}
+	}
+	kept = len(shown)
+	budget := s.max - kept
+	if budget <= 0 {
+		return shown, kept, 0
+	}
+
+	group := rule.group
+	if group == nil {
+		group = func(string) string { return "" }
+	}
+	// Keys are busiest first, so groups are ordered by their busiest entry
+	byGroup := make(map[string][]string)
+	var order []string
+	for _, key := range rest {
+		g := group(key)
+		if _, ok := byGroup[g]; !ok {
+			order = append(order, g)
+		}
+		byGroup[g] = append(byGroup[g], key)
+	}
+
+	used := make(map[string]bool)
+	for round := 0; budget > 0; round++ {
+		picked := false
+		for _, g := range order {
+			if budget == 0 {
+				break
+			}
+			if members := byGroup[g]; round < len(members) {
+				shown = append(shown, members[round])
+				used[g] = true
+				budget--
+				picked = true
+			}
+		}
+		if !picked {
+			break
+		}
+	}
+	return shown, kept, len(used)
+}
+
+// sortedByCount returns the keys of entries by descending count, then key
+func sortedByCount(entries map[string]int) []string {
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if entries[keys[i]] != entries[keys[j]] {
+			return entries[keys[i]] > entries[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+// commandName returns the program a command line runs
+func commandName(cmdline string) string {
+	if fields := strings.Fields(cmdline); len(fields) > 0 {
+		return path.Base(fields[0])
+	}
+	return ""
+}
+
+// parentDomain returns the last two labels of a domain, e.g. example.com
+// for a.b.example.com
+func parentDomain(domain string) string {
+	labels := strings.Split(strings.TrimSuffix(domain, "."), ".")
+	if len(labels) <= 2 {
+		return domain
+	}
+	return strings.Join(labels[len(labels)-2:], ".")
+}
@@ -0,0 +1,86 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteListSamples(t *testing.T) {
+	entries := map[string]int{
+		"/etc/passwd":          1,
+		"/root/.ssh/id_rsa":    1,
+		"/root/.npmrc":         1,
+		"/usr/lib/libc.so.6":   50,
+		"/proc/self/status":    40,
+		"/tmp/build/output.js": 30,
+	}
+	for i := range 5000 {
+		entries[fmt.Sprintf("/app/node_modules/pkg%d/index.js", i%100)+fmt.Sprint(i)] = 2
+	}
+
+	s := newIndicatorSampler(50)
+	var sb strings.Builder
+	s.writeList(&sb, "File Access", "accesses", entries, fileRule, func(key string, count int) string {
+		return fmt.Sprintf("  - %s (%d)\n", key, count)
+	})
+	out := sb.String()
+
+	assert.Equal(t, 1, s.sampled)
+	assert.Equal(t, 50+3, strings.Count(out, "\n"), "title, 50 entries and the note")
+	for _, path := range []string{"/etc/passwd", "/root/.ssh/id_rsa", "/root/.npmrc"} {
+		assert.Contains(t, out, path)
+	}
+	for _, dir := range []string{"/usr/lib/", "/proc/self/", "/tmp/build/", "/app/node_modules/pkg0/", "/app/node_modules/pkg1/"} {
+		assert.Contains(t, out, dir)
+	}
+	assert.Contains(t, out, "[SAMPLED: showing 50 of 5006 entries (all 3 sensitive paths, 47 others spread across 47 directories); 4956 entries with 9912 accesses omitted]")
+}
+
+func TestWriteListUnderCap(t *testing.T) {
+	s := newIndicatorSampler(0)
+	var sb strings.Builder
+	s.writeList(&sb, "DNS Lookups", "lookups", map[string]int{"a.example.com": 1, "registry.npmjs.org": 3}, domainRule, func(key string, count int) string {
+		return fmt.Sprintf("  - %s (%d)\n", key, count)
+	})
+
+	assert.Equal(t, "\nDNS Lookups:\n  - registry.npmjs.org (3)\n  - a.example.com (1)\n", sb.String())
+	assert.Zero(t, s.sampled)
+	assert.Equal(t, DefaultMaxIndicators, s.max)
+}
+
+func TestFormatAnalysisPromptNotesSampling(t *testing.T) {
+	stats := &aggregate.DedupedProcessStats{PerProcess: map[string]*aggregate.ProcessSummary{
+		"node": process(func(p *aggregate.ProcessSummary) {
+			for i := range 30 {
+				p.FileAccess[fmt.Sprintf("/app/file%d.js", i)] = 1
+			}
+		}),
+	}}
+
+	prompt := formatAnalysisPrompt("npm package: pkg@1.0.0", stats, RuleResult{}, 10)
+	assert.Contains(t, prompt, "[SAMPLED: showing 10 of 30 entries")
+	assert.Contains(t, prompt, "NOTE:")
+
+	prompt = formatAnalysisPrompt("npm package: pkg@1.0.0", stats, RuleResult{}, 100)
+	assert.NotContains(t, prompt, "SAMPLED")
+}
+
+func TestFormatAnalysisPromptResourceUsage(t *testing.T) {
+	stats := &aggregate.DedupedProcessStats{ResourceUsage: &aggregate.ResourceUsage{
+		CPUUserSeconds: 70, CPUSystemSeconds: 2, WallSeconds: 40, MemoryPeakBytes: 1024, DiskWriteBytes: 2048,
+	}}
+	stats.ResourceUsage.ComputeIndicators()
+
+	prompt := formatAnalysisPrompt("npm package: pkg@1.0.0", stats, RuleResult{}, 10)
+	assert.Contains(t, prompt, "CPU time: 70.0s user, 2.0s system over 40.0s wall (utilization 1.80 cores)")
+	assert.Contains(t, prompt, "Peak memory: 1024 bytes")
+	assert.Contains(t, prompt, "WARNING: sustained CPU burn")
+
+	stats.ResourceUsage = &aggregate.ResourceUsage{CPUUserSeconds: 1, WallSeconds: 40}
+	stats.ResourceUsage.ComputeIndicators()
+	assert.NotContains(t, formatAnalysisPrompt("npm package: pkg@1.0.0", stats, RuleResult{}, 10), "CPU burn")
+}
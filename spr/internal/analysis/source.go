@@ -0,0 +1,299 @@
+package analysis
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"charm.land/fantasy"
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// Analysis sources the agent can query
+const (
+	SourceDiff  = "diff"  // The package's diff.json, see DiffSource
+	SourceStats = "stats" // The trace stats service, see StatsSource
+)
+
+// DefaultSources is used when no sources are configured
+const DefaultSources = SourceDiff
+
+// Source is a backend the analysis agent queries through tools, on top of the
+// diff summarized in its prompt
+type Source interface {
+	// Name identifies the source in logs
+	Name() string
+	// Tools returns the tools querying the source about pkg. On error the
+	// source is left out of that package's analysis.
+	Tools(ctx context.Context, pkg PackageInfo) ([]fantasy.AgentTool, error)
+}
+
+// ParseSources parses a comma-separated list of source names. traceAPI is the
+// base URL of the stats service, required by SourceStats, and traceToken its
+// bearer token, if it requires one.
+func ParseSources(spec, traceAPI, traceToken string) ([]Source, error) {
+	var sources []Source
+	for _, name := range strings.Split(spec, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case SourceDiff:
+			sources = append(sources, DiffSource{})
+		case SourceStats:
+			if traceAPI == "" {
+				return nil, fmt.Errorf("the %s analysis source needs a trace API URL", SourceStats)
+			}
+			sources = append(sources, NewStatsSource(traceAPI, traceToken))
+		case "":
+		default:
+			return nil, fmt.Errorf("unknown analysis source %q (expected %s or %s)", name, SourceDiff, SourceStats)
+		}
+	}
+	return sources, nil
+}
+
+// DiffSource serves drill-downs into a package's diff.json, so the agent can
+// inspect the raw values behind the prompt's summary
+type DiffSource struct{}
+
+func (DiffSource) Name() string { return SourceDiff }
+
+type processQuery struct {
+	Process string `json:"process" description:"Process key as returned by list_processes"`
+	Variant string `json:"variant,omitempty" description:"Environment variant name; empty for the main run"`
+}
+
+func (DiffSource) Tools(_ context.Context, pkg PackageInfo) ([]fantasy.AgentTool, error) {
+	data, err := os.ReadFile(filepath.Join(pkg.OutputDir, "diff.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read diff.json: %w", err)
+	}
+	var deduped aggregate.DedupedProcessStats
+	if err := json.Unmarshal(data, &deduped); err != nil {
+		return nil, fmt.Errorf("failed to parse diff.json: %w", err)
+	}
+
+	listProcesses := fantasy.NewAgentTool(
+		"list_processes",
+		"List the processes with anomalous activity in the diff, per environment variant",
+		func(context.Context, struct{}, fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			listing := map[string][]string{"": sortedKeys(deduped.PerProcess)}
+			for name, variant := range deduped.Variants {
+				listing[name] = sortedKeys(variant.PerProcess)
+			}
+			return jsonResponse(listing), nil
+		})
+
+	getProcess := fantasy.NewAgentTool(
+		"get_process_activity",
+		"Get the full anomalous activity of one process from the diff as JSON",
+		func(_ context.Context, input processQuery, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			stats := &deduped
+			if input.Variant != "" {
+				if stats = deduped.Variants[input.Variant]; stats == nil {
+					return fantasy.NewTextErrorResponse(fmt.Sprintf("no variant %q", input.Variant)), nil
+				}
+			}
+			proc, ok := stats.PerProcess[input.Process]
+			if !ok {
+				return fantasy.NewTextErrorResponse(fmt.Sprintf("no process %q", input.Process)), nil
+			}
+			return jsonResponse(proc), nil
+		})
+
+	return []fantasy.AgentTool{listProcesses, getProcess}, nil
+}
+
+// StatsSource queries the trace stats service (cmd/statsd), which holds
+// every event of a trace rather than the diff's per-process summary. Traces
+// are uploaded to it on first use, one collection per package.
+type StatsSource struct {
+	BaseURL    string
+	Token      string // Bearer token; empty sends none
+	HTTPClient *http.Client
+}
+
+// NewStatsSource creates a source for the stats service at baseURL
+func NewStatsSource(baseURL, token string) *StatsSource {
+	return &StatsSource{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// do sends a request to the stats service with its credentials
+func (s *StatsSource) do(req *http.Request) (*http.Response, error) {
+	if s.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+s.Token)
+	}
+	return s.HTTPClient.Do(req)
+}
+
+func (s *StatsSource) Name() string { return SourceStats }
+
+// CollectionName returns the stats service collection holding the trace of a
+// package, see aggregate.CollectionName
+func CollectionName(name, version string) string {
+	return aggregate.CollectionName(name, version)
+}
+
+type activityQuery struct {
+	Value  string `json:"value" description:"Exact value to look up"`
+	Limit  int    `json:"limit,omitempty" description:"Maximum number of events to return (default 50)"`
+	Offset int    `json:"offset,omitempty" description:"Number of events to skip"`
+}
+
+type pageQuery struct {
+	Limit  int `json:"limit,omitempty" description:"Maximum number of processes to return (default all)"`
+	Offset int `json:"offset,omitempty" description:"Number of processes to skip"`
+}
+
+func (s *StatsSource) Tools(ctx context.Context, pkg PackageInfo) ([]fantasy.AgentTool, error) {
+	collection := CollectionName(pkg.Name, pkg.Version)
+	if err := s.ensureUploaded(ctx, collection, filepath.Join(pkg.OutputDir, "behavior.jsonl")); err != nil {
+		return nil, err
+	}
+
+	getStats := fantasy.NewAgentTool(
+		"get_trace_stats",
+		"Get aggregated stats and risk flags of the full, non-deduped trace",
+		func(ctx context.Context, _ struct{}, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return s.query(ctx, "/stats/"+collection, nil), nil
+		})
+
+	getProcessStats := fantasy.NewAgentTool(
+		"get_trace_process_stats",
+		"Get per-process stats of the full, non-deduped trace",
+		func(ctx context.Context, input pageQuery, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+			return s.query(ctx, "/stats_per_process/"+collection, url.Values{
+				"limit":  {fmt.Sprint(input.Limit)},
+				"offset": {fmt.Sprint(input.Offset)},
+			}), nil
+		})
+
+	// One drill-down tool per kind of event /specific can filter on
+	tools := []fantasy.AgentTool{getStats, getProcessStats}
+	for _, kind := range []struct{ param, tool, desc string }{
+		{"dns", "get_dns_activity", "Get the raw trace events of DNS requests for a domain"},
+		{"command", "get_command_activity", "Get the raw trace events of executions of a command"},
+		{"file", "get_file_activity", "Get the raw trace events of accesses to a file path"},
+	} {
+		tools = append(tools, fantasy.NewAgentTool(kind.tool, kind.desc,
+			func(ctx context.Context, input activityQuery, _ fantasy.ToolCall) (fantasy.ToolResponse, error) {
+				params := url.Values{kind.param: {input.Value}, "offset": {fmt.Sprint(input.Offset)}}
+				if input.Limit > 0 {
+					params.Set("limit", fmt.Sprint(input.Limit))
+				}
+				return s.query(ctx, "/specific/"+collection, params), nil
+			}))
+	}
+	return tools, nil
+}
+
+// ensureUploaded uploads a trace to collection unless the service already
+// has it
+func (s *StatsSource) ensureUploaded(ctx context.Context, collection, tracePath string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.BaseURL+"/stats/"+collection, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return fmt.Errorf("trace API unreachable: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("trace API returned %s", resp.Status)
+	}
+
+	trace, err := os.Open(tracePath)
+	if err != nil {
+		return fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer trace.Close()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "behavior.jsonl")
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, trace); err != nil {
+		return fmt.Errorf("failed to read trace: %w", err)
+	}
+	if err := form.Close(); err != nil {
+		return err
+	}
+
+	uploadURL := s.BaseURL + "/upload-tracee?" + url.Values{"collection_name": {collection}}.Encode()
+	req, err = http.NewRequestWithContext(ctx, http.MethodPost, uploadURL, &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	resp, err = s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to upload trace: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("failed to upload trace: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// query GETs a stats service endpoint. Failures are returned to the model
+// as error responses rather than aborting the analysis.
+func (s *StatsSource) query(ctx context.Context, path string, params url.Values) fantasy.ToolResponse {
+	endpoint := s.BaseURL + path
+	if len(params) > 0 {
+		endpoint += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error())
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("trace API unreachable: %v", err))
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("failed to read response: %v", err))
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fantasy.NewTextErrorResponse(fmt.Sprintf("trace API returned %s: %s", resp.Status, strings.TrimSpace(string(body))))
+	}
+	return fantasy.NewTextResponse(string(body))
+}
+
+func jsonResponse(v any) fantasy.ToolResponse {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fantasy.NewTextErrorResponse(err.Error())
+	}
+	return fantasy.NewTextResponse(string(data))
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
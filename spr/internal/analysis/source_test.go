@@ -0,0 +1,113 @@
+package analysis
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func runTool(t *testing.T, tools []fantasy.AgentTool, name, input string) fantasy.ToolResponse {
+	t.Helper()
+	for _, tool := range tools {
+		if tool.Info().Name == name {
+			resp, err := tool.Run(context.Background(), fantasy.ToolCall{Name: name, Input: input})
+			require.NoError(t, err)
+			return resp
+		}
+	}
+	t.Fatalf("no tool %s", name)
+	return fantasy.ToolResponse{}
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources(DefaultSources, "", "")
+	require.NoError(t, err)
+	require.Len(t, sources, 1)
+	assert.Equal(t, SourceDiff, sources[0].Name())
+
+	sources, err = ParseSources("diff, stats", "http://localhost:8001/", "secret")
+	require.NoError(t, err)
+	require.Len(t, sources, 2)
+	assert.Equal(t, "http://localhost:8001", sources[1].(*StatsSource).BaseURL)
+	assert.Equal(t, "secret", sources[1].(*StatsSource).Token)
+
+	_, err = ParseSources("stats", "", "")
+	assert.Error(t, err)
+	_, err = ParseSources("mongo", "", "")
+	assert.Error(t, err)
+}
+
+func TestCollectionName(t *testing.T) {
+	assert.Equal(t, "pkg__scope_left_pad_1_0_0", CollectionName("@scope/left-pad", "1.0.0"))
+}
+
+func TestDiffSource(t *testing.T) {
+	dir := t.TempDir()
+	diff := `{"per_process":{"node":{"executed_commands":{"curl":1}}},"variants":{"ru":{"per_process":{"sh":{}}}}}`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "diff.json"), []byte(diff), 0o644))
+
+	tools, err := DiffSource{}.Tools(context.Background(), PackageInfo{Name: "pkg", Version: "1.0.0", OutputDir: dir})
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"":["node"],"ru":["sh"]}`, runTool(t, tools, "list_processes", `{}`).Content)
+	assert.Contains(t, runTool(t, tools, "get_process_activity", `{"process":"node"}`).Content, "curl")
+	assert.True(t, runTool(t, tools, "get_process_activity", `{"process":"node","variant":"ru"}`).IsError)
+
+	_, err = DiffSource{}.Tools(context.Background(), PackageInfo{OutputDir: t.TempDir()})
+	assert.Error(t, err)
+}
+
+func TestStatsSource(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "behavior.jsonl"), []byte("{\"eventName\":\"execve\"}\n"), 0o644))
+	pkg := PackageInfo{Name: "left-pad", Version: "1.0.0", OutputDir: dir}
+	collection := CollectionName(pkg.Name, pkg.Version)
+
+	uploaded := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/upload-tracee":
+			assert.Equal(t, collection, r.URL.Query().Get("collection_name"))
+			file, _, err := r.FormFile("file")
+			require.NoError(t, err)
+			data, _ := io.ReadAll(file)
+			uploaded = string(data)
+			io.WriteString(w, `{"status":"success"}`)
+		case r.URL.Path == "/stats/"+collection && uploaded != "":
+			io.WriteString(w, `{"risk_flags":[]}`)
+		case r.URL.Path == "/specific/"+collection:
+			io.WriteString(w, `{"command":"`+r.URL.Query().Get("command")+`"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	source := NewStatsSource(server.URL, "secret")
+	tools, err := source.Tools(context.Background(), pkg)
+	require.NoError(t, err)
+	assert.Contains(t, uploaded, "execve")
+
+	assert.JSONEq(t, `{"risk_flags":[]}`, runTool(t, tools, "get_trace_stats", `{}`).Content)
+	assert.JSONEq(t, `{"command":"curl"}`, runTool(t, tools, "get_command_activity", `{"value":"curl"}`).Content)
+	assert.True(t, runTool(t, tools, "get_trace_process_stats", `{}`).IsError)
+
+	// Already uploaded traces aren't uploaded again
+	uploaded = "sentinel"
+	_, err = source.Tools(context.Background(), pkg)
+	require.NoError(t, err)
+	assert.Equal(t, "sentinel", uploaded)
+
+	// Without a trace the source is unavailable
+	_, err = source.Tools(context.Background(), PackageInfo{Name: "other", Version: "1.0.0", OutputDir: t.TempDir()})
+	assert.Error(t, err)
+}
@@ -0,0 +1,65 @@
+package analysis
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/static"
+)
+
+// formatStaticReport describes the static scan of the tarball for the prompt:
+// its findings, the flagged files with excerpts, then the install scripts
+func formatStaticReport(report *static.Report) string {
+	if report == nil {
+		return ""
+	}
+	var sb strings.Builder
+	if len(report.Findings) > 0 {
+		sb.WriteString(fmt.Sprintf("\n\n=== STATIC ANALYSIS OF THE TARBALL (static risk score %.2f) ===\n", report.Score))
+		for _, f := range report.Findings {
+			where := ""
+			if f.Path != "" {
+				where = f.Path + ": "
+			}
+			sb.WriteString(fmt.Sprintf("  - [%s] %s%s\n", f.Kind, where, f.Detail))
+		}
+		for _, e := range report.Evidence {
+			sb.WriteString(fmt.Sprintf("\n--- %s (sha256 %s, %d bytes): %s, from line %d ---\n",
+				e.Path, e.SHA256, e.Size, strings.Join(e.Reasons, ", "), e.Line))
+			sb.WriteString(e.Excerpt)
+		}
+	}
+	sb.WriteString(formatInstallScripts(report.InstallScripts))
+	return sb.String()
+}
+
+// formatInstallScripts shows what npm runs when the package is installed:
+// each install script and the content of the package files it runs
+func formatInstallScripts(scripts []static.InstallScript) string {
+	if len(scripts) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("\n\n=== INSTALL SCRIPTS (run by npm install, in this order) ===\n")
+	for _, script := range scripts {
+		note := ""
+		if script.Implicit {
+			note = " (npm's default for a package with a binding.gyp)"
+		}
+		sb.WriteString(fmt.Sprintf("  %s: %s%s\n", script.Hook, script.Command, note))
+	}
+	for _, script := range scripts {
+		for _, f := range script.Files {
+			shown := ""
+			if f.Truncated {
+				shown = fmt.Sprintf(", first %d bytes shown", len(f.Content))
+			}
+			sb.WriteString(fmt.Sprintf("\n--- %s, run by %s (sha256 %s, %d bytes%s) ---\n", f.Path, script.Hook, f.SHA256, f.Size, shown))
+			sb.WriteString(f.Content)
+			if !strings.HasSuffix(f.Content, "\n") {
+				sb.WriteByte('\n')
+			}
+		}
+	}
+	return sb.String()
+}
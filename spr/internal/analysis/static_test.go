@@ -0,0 +1,26 @@
+package analysis
+
+import (
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFormatInstallScripts(t *testing.T) {
+	report := &static.Report{InstallScripts: []static.InstallScript{
+		{Hook: "install", Command: "node-gyp rebuild", Implicit: true},
+		{Hook: "postinstall", Command: "node setup.js", Files: []static.ScriptFile{
+			{Path: "setup.js", SHA256: "ab12", Size: 40000, Content: "require('https').get(url)", Truncated: true},
+		}},
+	}}
+
+	prompt := formatStaticReport(report)
+	assert.NotContains(t, prompt, "STATIC ANALYSIS OF THE TARBALL", "no findings")
+	assert.Contains(t, prompt, "=== INSTALL SCRIPTS (run by npm install, in this order) ===\n"+
+		"  install: node-gyp rebuild (npm's default for a package with a binding.gyp)\n"+
+		"  postinstall: node setup.js\n")
+	assert.Contains(t, prompt, "--- setup.js, run by postinstall (sha256 ab12, 40000 bytes, first 25 bytes shown) ---\nrequire('https').get(url)\n")
+
+	assert.Empty(t, formatStaticReport(&static.Report{}))
+}
@@ -0,0 +1,103 @@
+package analysis
+
+import (
+	"strings"
+	"sync"
+
+	"charm.land/fantasy"
+)
+
+// Kinds of stream events
+const (
+	StreamReasoning = "reasoning" // The model's reasoning, where the provider exposes it
+	StreamText      = "text"      // Response text
+	StreamToolCall  = "tool_call" // A completed tool call; Text holds its JSON input
+)
+
+// streamFlushSize is how much delta text is buffered before it is sent
+// without waiting for a line break
+const streamFlushSize = 200
+
+// StreamEvent is a piece of an LLM analysis in progress
+type StreamEvent struct {
+	Name    string
+	Version string
+	Kind    string
+	Text    string
+	Tool    string // Tool name, for tool calls
+}
+
+// StreamCallback is an optional function receiving the output of LLM
+// analyses as it is generated. Deltas are coalesced into whole lines where
+// possible. It is called from the analysis goroutines, one per package.
+type StreamCallback func(event StreamEvent)
+
+// SetStreamCallback streams reasoning, text and tool calls of LLM analyses
+// to cb as they are generated
+func (a *Analyzer) SetStreamCallback(cb StreamCallback) {
+	a.streamCb = cb
+}
+
+// packageStream coalesces the deltas of one package's analysis
+type packageStream struct {
+	cb   StreamCallback
+	pkg  PackageInfo
+	mu   sync.Mutex
+	kind string
+	buf  strings.Builder
+}
+
+func (s *packageStream) delta(kind, text string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if kind != s.kind {
+		s.flushLocked()
+		s.kind = kind
+	}
+	s.buf.WriteString(text)
+	if strings.Contains(text, "\n") || s.buf.Len() >= streamFlushSize {
+		s.flushLocked()
+	}
+}
+
+func (s *packageStream) flush() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.flushLocked()
+}
+
+func (s *packageStream) flushLocked() {
+	text := strings.TrimSpace(s.buf.String())
+	s.buf.Reset()
+	if text != "" {
+		s.cb(StreamEvent{Name: s.pkg.Name, Version: s.pkg.Version, Kind: s.kind, Text: text})
+	}
+}
+
+// agentStreamCall returns a streaming agent call forwarding to s
+func (s *packageStream) agentStreamCall(prompt string) fantasy.AgentStreamCall {
+	return fantasy.AgentStreamCall{
+		Prompt: prompt,
+		OnReasoningDelta: func(_, text string) error {
+			s.delta(StreamReasoning, text)
+			return nil
+		},
+		OnReasoningEnd: func(string, fantasy.ReasoningContent) error {
+			s.flush()
+			return nil
+		},
+		OnTextDelta: func(_, text string) error {
+			s.delta(StreamText, text)
+			return nil
+		},
+		OnTextEnd: func(string) error {
+			s.flush()
+			return nil
+		},
+		OnToolCall: func(call fantasy.ToolCallContent) error {
+			s.flush()
+			s.cb(StreamEvent{Name: s.pkg.Name, Version: s.pkg.Version, Kind: StreamToolCall, Text: call.Input, Tool: call.ToolName})
+			return nil
+		},
+	}
+}
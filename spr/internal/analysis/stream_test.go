@@ -0,0 +1,35 @@
+package analysis
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageStream(t *testing.T) {
+	var events []StreamEvent
+	s := &packageStream{
+		cb:  func(e StreamEvent) { events = append(events, e) },
+		pkg: PackageInfo{Name: "left-pad", Version: "1.3.0"},
+	}
+	call := s.agentStreamCall("prompt")
+	assert.Equal(t, "prompt", call.Prompt)
+
+	// Deltas are held back until a line ends, the kind changes or a part ends
+	require.NoError(t, call.OnReasoningDelta("r", "The postinstall "))
+	assert.Empty(t, events)
+	require.NoError(t, call.OnReasoningDelta("r", "script curls a raw IP.\nIt then"))
+	require.NoError(t, call.OnReasoningDelta("r", " pipes it to sh"))
+	require.NoError(t, call.OnTextDelta("t", "Submitting"))
+	require.NoError(t, call.OnTextEnd("t"))
+	require.NoError(t, call.OnToolCall(fantasy.ToolCallContent{ToolName: "submit_assessment", Input: `{"is_malicious":true}`}))
+
+	assert.Equal(t, []StreamEvent{
+		{Name: "left-pad", Version: "1.3.0", Kind: StreamReasoning, Text: "The postinstall script curls a raw IP.\nIt then"},
+		{Name: "left-pad", Version: "1.3.0", Kind: StreamReasoning, Text: "pipes it to sh"},
+		{Name: "left-pad", Version: "1.3.0", Kind: StreamText, Text: "Submitting"},
+		{Name: "left-pad", Version: "1.3.0", Kind: StreamToolCall, Text: `{"is_malicious":true}`, Tool: "submit_assessment"},
+	}, events)
+}
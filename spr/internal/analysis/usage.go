@@ -0,0 +1,77 @@
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrBudgetExceeded is returned when AI analysis stops because the configured
+// budget was spent
+var ErrBudgetExceeded = errors.New("AI analysis budget exceeded")
+
+// Usage is the token usage and estimated cost of AI analysis
+type Usage struct {
+	PromptTokens     int64 `json:"prompt_tokens"`
+	CompletionTokens int64 `json:"completion_tokens"`
+	// CostUSD is estimated from list prices; zero for models without known
+	// pricing
+	CostUSD float64 `json:"cost_usd"`
+}
+
+// Add accumulates u2 into u
+func (u *Usage) Add(u2 Usage) {
+	u.PromptTokens += u2.PromptTokens
+	u.CompletionTokens += u2.CompletionTokens
+	u.CostUSD += u2.CostUSD
+}
+
+func (u Usage) String() string {
+	return fmt.Sprintf("%d prompt + %d completion tokens, estimated $%.4f", u.PromptTokens, u.CompletionTokens, u.CostUSD)
+}
+
+// Pricing is the price of a model in USD per million tokens
+type Pricing struct {
+	Prompt     float64
+	Completion float64
+}
+
+// Cost estimates the cost of a request in USD
+func (p Pricing) Cost(promptTokens, completionTokens int64) float64 {
+	return (float64(promptTokens)*p.Prompt + float64(completionTokens)*p.Completion) / 1e6
+}
+
+// modelPricing holds list prices of common models. Dated snapshots
+// (gpt-5-mini-2025-08-07) match by prefix.
+var modelPricing = map[string]Pricing{
+	"gpt-5":             {1.25, 10},
+	"gpt-5-mini":        {0.25, 2},
+	"gpt-5-nano":        {0.05, 0.4},
+	"gpt-4.1":           {2, 8},
+	"gpt-4.1-mini":      {0.4, 1.6},
+	"gpt-4.1-nano":      {0.1, 0.4},
+	"gpt-4o":            {2.5, 10},
+	"gpt-4o-mini":       {0.15, 0.6},
+	"claude-opus-4-1":   {15, 75},
+	"claude-sonnet-4-5": {3, 15},
+	"claude-sonnet-4":   {3, 15},
+	"claude-haiku-4-5":  {1, 5},
+}
+
+// PricingFor returns the pricing of a provider's model, and whether it is
+// known. Models served by a local Ollama server are free.
+func PricingFor(provider, model string) (Pricing, bool) {
+	if strings.ToLower(provider) == ProviderOllama {
+		return Pricing{}, true
+	}
+	best := ""
+	for name := range modelPricing {
+		if (model == name || strings.HasPrefix(model, name+"-")) && len(name) > len(best) {
+			best = name
+		}
+	}
+	if best == "" {
+		return Pricing{}, false
+	}
+	return modelPricing[best], true
+}
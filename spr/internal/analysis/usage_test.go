@@ -0,0 +1,41 @@
+package analysis
+
+import (
+	"testing"
+
+	"charm.land/fantasy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPricingFor(t *testing.T) {
+	p, ok := PricingFor(ProviderOpenAI, "gpt-5-mini")
+	assert.True(t, ok)
+	assert.Equal(t, Pricing{0.25, 2}, p)
+
+	// Dated snapshots match the longest known prefix
+	p, ok = PricingFor(ProviderOpenAI, "gpt-5-mini-2025-08-07")
+	assert.True(t, ok)
+	assert.Equal(t, Pricing{0.25, 2}, p)
+
+	p, ok = PricingFor(ProviderOllama, "llama3.1")
+	assert.True(t, ok)
+	assert.Zero(t, p)
+
+	_, ok = PricingFor(ProviderCompat, "my-finetune")
+	assert.False(t, ok)
+}
+
+func TestAnalyzerBudget(t *testing.T) {
+	a := &Analyzer{pricing: Pricing{Prompt: 1, Completion: 10}, priced: true}
+	assert.False(t, a.budgetSpent(), "no budget means unlimited")
+
+	a.SetMaxBudget(0.02)
+	usage := a.recordUsage(fantasy.Usage{InputTokens: 5000, OutputTokens: 1000})
+	assert.Equal(t, Usage{PromptTokens: 5000, CompletionTokens: 1000, CostUSD: 0.015}, usage)
+	assert.False(t, a.budgetSpent())
+
+	a.recordUsage(fantasy.Usage{InputTokens: 5000, OutputTokens: 1000})
+	assert.True(t, a.budgetSpent())
+	assert.Equal(t, int64(10000), a.Usage().PromptTokens)
+	assert.InDelta(t, 0.03, a.Usage().CostUSD, 1e-9)
+}
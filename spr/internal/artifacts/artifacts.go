@@ -0,0 +1,47 @@
+// Package artifacts ships analysis artifacts to durable storage so that
+// deployments don't depend on a persistent local output directory
+package artifacts
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// Files are the per-package artifacts shipped to a sink
+var Files = []string{"behavior.jsonl", "diff.json", "ai-analysis.json"}
+
+// Sink stores artifacts under a slash-separated key
+type Sink interface {
+	// Put stores body under key. body is read from its current offset to
+	// the end and may be read more than once (e.g. to sign it).
+	Put(ctx context.Context, key string, body io.ReadSeeker, contentType string) error
+	// String describes the sink for logs, e.g. "s3://bucket/prefix"
+	String() string
+}
+
+// PutFile streams a local file to the sink
+func PutFile(ctx context.Context, sink Sink, key, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := sink.Put(ctx, key, f, contentType(filePath)); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", key, err)
+	}
+	return nil
+}
+
+func contentType(filePath string) string {
+	switch path.Ext(filePath) {
+	case ".json":
+		return "application/json"
+	case ".jsonl":
+		return "application/x-ndjson"
+	}
+	return "application/octet-stream"
+}
@@ -0,0 +1,300 @@
+// Package breaker keeps the pipeline from hammering a service that is down.
+// Each external dependency (GitHub, the registries, npm, the LLM provider)
+// has one process-wide circuit breaker: after consecutive failures it opens
+// and calls fail at once with the cause, and once a cooldown has passed a
+// single probe is let through (half-open) to decide whether to close it
+// again. The breakers' states make up the service's health.
+package breaker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dependencies guarded by a breaker
+const (
+	GitHub   = "github"
+	Registry = "registry" // Staging and safe registries (Gitea by default)
+	Npm      = "npm"      // Public npm registry and API
+	LLM      = "llm"      // AI provider
+)
+
+// Breaker states
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half-open"
+)
+
+// Defaults for breakers created before SetDefaults is called
+const (
+	DefaultFailures = 5
+	DefaultCooldown = 30 * time.Second
+)
+
+// ErrOpen is wrapped by the errors of calls rejected by an open breaker
+var ErrOpen = errors.New("circuit open")
+
+// Breaker trips after consecutive failures of one dependency
+type Breaker struct {
+	name string
+	now  func() time.Time
+
+	mu       sync.Mutex
+	failures int           // Consecutive failures to trip at; zero never trips
+	cooldown time.Duration // How long an open breaker rejects calls before a probe
+	state    string
+	streak   int // Consecutive failures so far
+	lastErr  error
+	since    time.Time // When the state last changed
+	probing  bool      // A half-open probe is in flight
+}
+
+// Status is a breaker's state, as reported in health checks
+type Status struct {
+	Name      string    `json:"name"`
+	State     string    `json:"state"`
+	Failures  int       `json:"consecutive_failures"`
+	LastError string    `json:"last_error,omitempty"`
+	Since     time.Time `json:"since"`
+}
+
+// New creates a closed breaker tripping after failures consecutive failures
+// and probing again after cooldown. Zero failures never trips.
+func New(name string, failures int, cooldown time.Duration) *Breaker {
+	return &Breaker{name: name, now: time.Now, failures: failures, cooldown: cooldown, state: StateClosed, since: time.Now()}
+}
+
+// Allow reports whether a call may go ahead, returning an error wrapping
+// ErrOpen with the cause when the breaker is open. An open breaker past its
+// cooldown lets one probe through; every allowed call must be followed by
+// Record.
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case StateOpen:
+		retry := b.since.Add(b.cooldown)
+		if b.now().Before(retry) {
+			return b.openError(retry)
+		}
+		b.setState(StateHalfOpen)
+		b.probing = true
+		return nil
+	case StateHalfOpen:
+		if b.probing {
+			return b.openError(time.Time{})
+		}
+		b.probing = true
+	}
+	return nil
+}
+
+// Record reports the outcome of an allowed call, nil for success
+func (b *Breaker) Record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		b.streak = 0
+		if b.state != StateClosed {
+			b.setState(StateClosed)
+		}
+		return
+	}
+	b.streak++
+	b.lastErr = err
+	if b.state == StateHalfOpen || (b.failures > 0 && b.streak >= b.failures && b.state == StateClosed) {
+		b.setState(StateOpen)
+	}
+}
+
+// release ends an allowed call without an outcome
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// Status returns the breaker's current state
+func (b *Breaker) Status() Status {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := Status{Name: b.name, State: b.state, Failures: b.streak, Since: b.since}
+	if b.lastErr != nil {
+		s.LastError = b.lastErr.Error()
+	}
+	return s
+}
+
+func (b *Breaker) setState(state string) {
+	b.state = state
+	b.since = b.now()
+}
+
+func (b *Breaker) openError(retry time.Time) error {
+	msg := fmt.Sprintf("%s unavailable: %v after %d consecutive failures", b.name, ErrOpen, b.streak)
+	if b.lastErr != nil {
+		msg += fmt.Sprintf(", last: %v", b.lastErr)
+	}
+	if !retry.IsZero() {
+		msg += fmt.Sprintf("; retrying in %s", retry.Sub(b.now()).Round(time.Second))
+	}
+	return &openError{msg: msg}
+}
+
+type openError struct{ msg string }
+
+func (e *openError) Error() string { return e.msg }
+func (e *openError) Unwrap() error { return ErrOpen }
+
+var (
+	registryMu sync.Mutex
+	breakers   = make(map[string]*Breaker)
+	failures   = DefaultFailures
+	cooldown   = DefaultCooldown
+)
+
+// SetDefaults sets the failures and cooldown of every breaker, including
+// those already created. Zero failures disables tripping.
+func SetDefaults(n int, d time.Duration) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	failures, cooldown = n, d
+	for _, b := range breakers {
+		b.mu.Lock()
+		b.failures, b.cooldown = n, d
+		b.mu.Unlock()
+	}
+}
+
+// Get returns the process-wide breaker of a dependency, creating it
+func Get(name string) *Breaker {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	b, ok := breakers[name]
+	if !ok {
+		b = New(name, failures, cooldown)
+		breakers[name] = b
+	}
+	return b
+}
+
+// Statuses returns the state of every breaker created so far, by name
+func Statuses() []Status {
+	registryMu.Lock()
+	all := make([]*Breaker, 0, len(breakers))
+	for _, b := range breakers {
+		all = append(all, b)
+	}
+	registryMu.Unlock()
+
+	statuses := make([]Status, len(all))
+	for i, b := range all {
+		statuses[i] = b.Status()
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Health is the aggregated state of the breakers
+type Health struct {
+	Status       string   `json:"status"` // "ok", or "degraded" while any breaker isn't closed
+	Dependencies []Status `json:"dependencies,omitempty"`
+}
+
+// CurrentHealth aggregates the state of every breaker
+func CurrentHealth() Health {
+	h := Health{Status: "ok", Dependencies: Statuses()}
+	for _, s := range h.Dependencies {
+		if s.State != StateClosed {
+			h.Status = "degraded"
+		}
+	}
+	return h
+}
+
+// Transport guards the requests of an HTTP client with the breaker of
+// dependency, or of npm or GitHub for requests to their hosts, so a client
+// talking to several services only trips the failing one; an empty
+// dependency guards only those. Transport errors and 5xx responses count as
+// failures; a nil next uses http.DefaultTransport.
+func Transport(dependency string, next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &transport{dependency: dependency, next: next}
+}
+
+// Client returns an HTTP client whose requests go through Transport
+func Client(dependency string, timeout time.Duration) *http.Client {
+	return &http.Client{Timeout: timeout, Transport: Transport(dependency, nil)}
+}
+
+type transport struct {
+	dependency string
+	next       http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	dependency := DependencyOf(req.URL.Hostname(), t.dependency)
+	if dependency == "" {
+		return t.next.RoundTrip(req)
+	}
+	b := Get(dependency)
+	if err := b.Allow(); err != nil {
+		return nil, err
+	}
+	resp, err := t.next.RoundTrip(req)
+	switch {
+	case err != nil && errors.Is(req.Context().Err(), context.Canceled):
+		// Cancelled by the caller, which says nothing about the service
+		b.release()
+	case err != nil:
+		b.Record(err)
+	case resp.StatusCode >= 500:
+		b.Record(fmt.Errorf("HTTP %d from %s", resp.StatusCode, req.URL.Host))
+	default:
+		b.Record(nil)
+	}
+	return resp, err
+}
+
+// DependencyOf returns the dependency a host belongs to: npm or GitHub for
+// their hosts, fallback for any other
+func DependencyOf(host, fallback string) string {
+	host = strings.ToLower(host)
+	switch {
+	case host == "npmjs.org" || strings.HasSuffix(host, ".npmjs.org"):
+		return Npm
+	case host == "github.com" || strings.HasSuffix(host, ".github.com"):
+		return GitHub
+	}
+	return fallback
+}
+
+// Probe sends a GET to url through the breaker of dependency, for preflight
+// checks: any response below 500 counts as the service being up
+func Probe(ctx context.Context, dependency, url string) error {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := Client(dependency, 0).Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package breaker
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBreaker(t *testing.T) {
+	now := time.Now()
+	b := New("gitea", 2, time.Minute)
+	b.now = func() time.Time { return now }
+
+	require.NoError(t, b.Allow())
+	b.Record(errors.New("connection refused"))
+	require.NoError(t, b.Allow(), "one failure doesn't trip")
+	b.Record(errors.New("connection refused"))
+	assert.Equal(t, StateOpen, b.Status().State)
+
+	err := b.Allow()
+	require.ErrorIs(t, err, ErrOpen)
+	assert.Contains(t, err.Error(), "gitea unavailable")
+	assert.Contains(t, err.Error(), "after 2 consecutive failures, last: connection refused")
+	assert.Contains(t, err.Error(), "retrying in 1m0s")
+
+	// Past the cooldown a single probe goes through
+	now = now.Add(time.Minute)
+	require.NoError(t, b.Allow())
+	assert.Equal(t, StateHalfOpen, b.Status().State)
+	assert.ErrorIs(t, b.Allow(), ErrOpen, "only one probe at a time")
+
+	// A failed probe opens it again, a successful one closes it
+	b.Record(errors.New("HTTP 503"))
+	assert.Equal(t, StateOpen, b.Status().State)
+	now = now.Add(time.Minute)
+	require.NoError(t, b.Allow())
+	b.Record(nil)
+	status := b.Status()
+	assert.Equal(t, StateClosed, status.State)
+	assert.Zero(t, status.Failures)
+	assert.Equal(t, "HTTP 503", status.LastError)
+}
+
+func TestBreakerReleaseKeepsState(t *testing.T) {
+	now := time.Now()
+	b := New("llm", 1, time.Second)
+	b.now = func() time.Time { return now }
+	require.NoError(t, b.Allow())
+	b.Record(errors.New("timeout"))
+
+	now = now.Add(time.Second)
+	require.NoError(t, b.Allow())
+	b.release()
+	assert.Equal(t, StateHalfOpen, b.Status().State, "a cancelled probe says nothing")
+	assert.NoError(t, b.Allow(), "the next call probes instead")
+}
+
+func TestBreakerNeverTrips(t *testing.T) {
+	b := New("npm", 0, time.Second)
+	for range 10 {
+		require.NoError(t, b.Allow())
+		b.Record(errors.New("down"))
+	}
+	assert.Equal(t, StateClosed, b.Status().State)
+}
+
+func TestDependencyOf(t *testing.T) {
+	assert.Equal(t, Npm, DependencyOf("registry.npmjs.org", Registry))
+	assert.Equal(t, Npm, DependencyOf("npmjs.org", ""))
+	assert.Equal(t, GitHub, DependencyOf("API.GitHub.com", ""))
+	assert.Equal(t, Registry, DependencyOf("git.example.com", Registry))
+	assert.Equal(t, "", DependencyOf("npmjs.org.evil.com", ""))
+}
+
+func TestTransport(t *testing.T) {
+	healthy := false
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	const name = "test-transport"
+	client := Client(name, 5*time.Second)
+	for range DefaultFailures {
+		resp, err := client.Get(srv.URL)
+		require.NoError(t, err, "5xx responses are returned to the caller")
+		resp.Body.Close()
+	}
+	_, err := client.Get(srv.URL)
+	require.ErrorIs(t, err, ErrOpen)
+	assert.Contains(t, err.Error(), "HTTP 502")
+
+	health := CurrentHealth()
+	assert.Equal(t, "degraded", health.Status)
+	assert.Contains(t, health.Dependencies, Get(name).Status())
+
+	// Probes go through the same breaker
+	healthy = true
+	assert.ErrorIs(t, Probe(context.Background(), name, srv.URL), ErrOpen)
+	b := Get(name)
+	b.mu.Lock()
+	b.since = b.since.Add(-DefaultCooldown)
+	b.mu.Unlock()
+	require.NoError(t, Probe(context.Background(), name, srv.URL))
+	assert.Equal(t, StateClosed, Get(name).Status().State)
+}
@@ -0,0 +1,355 @@
+// Package confusion finds dependency confusion targets: dependencies that look
+// private, by their scope or by resolving from a private registry, and that
+// could be substituted by a package of the same name on the public registry.
+//
+// A private-looking package must resolve from a configured private registry,
+// and if its name also exists publicly, the public package must share a
+// publisher with the private one. Anything else is a likely confusion attack,
+// or an unclaimed name waiting for one.
+package confusion
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Kinds of finding
+const (
+	// KindResolvedPublicly is a private package installed from the public
+	// registry: the confusion has already happened
+	KindResolvedPublicly = "resolved-publicly"
+	// KindUnknownRegistry is a private package resolved from a registry that
+	// isn't configured as private
+	KindUnknownRegistry = "unknown-registry"
+	// KindPublicConflict is a private package whose name is also published
+	// publicly, by someone else
+	KindPublicConflict = "public-conflict"
+)
+
+// concurrency bounds the registry lookups in flight
+const concurrency = 8
+
+// Result is the check of one private-looking package
+type Result struct {
+	Package  string `json:"package"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Reason   string `json:"reason"` // Why it looks private
+	Resolved string `json:"resolved,omitempty"`
+	// Whether the name exists on the public registry, and who publishes it
+	// there and on the private one
+	OnPublic          bool     `json:"on_public"`
+	PublicLatest      string   `json:"public_latest,omitempty"`
+	PublicPublishers  []string `json:"public_publishers,omitempty"`
+	PrivatePublishers []string `json:"private_publishers,omitempty"`
+	Kind              string   `json:"kind,omitempty"` // Empty when nothing is wrong
+	Error             string   `json:"error,omitempty"`
+}
+
+// Checker checks dependencies against the private scopes and registries
+type Checker struct {
+	PublicURL  string
+	Scopes     []string          // Private scopes, e.g. "@acme"
+	Registries []string          // Private registry URLs
+	Tokens     map[string]string // Auth token by registry, as "//host/path/"
+	Token      string            // Auth token for private registries without one in Tokens
+	HTTPClient *http.Client
+}
+
+// NewChecker creates a checker for the given private scopes and registries,
+// comparing against the public registry at publicURL
+func NewChecker(publicURL string, scopes, registries []string, token string) *Checker {
+	c := &Checker{
+		PublicURL:  strings.TrimSuffix(publicURL, "/"),
+		Tokens:     make(map[string]string),
+		Token:      token,
+		HTTPClient: breaker.Client("", 30*time.Second), // Private registries aren't guarded
+	}
+	for _, scope := range scopes {
+		c.addScope(scope)
+	}
+	for _, registry := range registries {
+		c.addRegistry(registry)
+	}
+	return c
+}
+
+// UseNpmrc adds the scoped and default registries of an .npmrc, other than
+// the public one, as private, with their tokens
+func (c *Checker) UseNpmrc(rc *Npmrc) {
+	for scope, registry := range rc.Scopes {
+		if c.addRegistry(registry) {
+			c.addScope(scope)
+		}
+	}
+	c.addRegistry(rc.Registry)
+	for registry, token := range rc.Tokens {
+		c.Tokens[registry] = token
+	}
+}
+
+// Enabled reports whether anything is configured as private
+func (c *Checker) Enabled() bool {
+	return c != nil && len(c.Scopes)+len(c.Registries) > 0
+}
+
+func (c *Checker) addScope(scope string) {
+	scope = strings.TrimSpace(scope)
+	if scope == "" {
+		return
+	}
+	if !strings.HasPrefix(scope, "@") {
+		scope = "@" + scope
+	}
+	if !slices.Contains(c.Scopes, scope) {
+		c.Scopes = append(c.Scopes, scope)
+	}
+}
+
+// addRegistry adds a private registry, reporting whether it is one
+func (c *Checker) addRegistry(registry string) bool {
+	registry = strings.TrimSpace(registry)
+	key := registryKey(registry)
+	if key == "" || key == registryKey(c.PublicURL) {
+		return false
+	}
+	if !slices.ContainsFunc(c.Registries, func(r string) bool { return registryKey(r) == key }) {
+		c.Registries = append(c.Registries, strings.TrimSuffix(registry, "/"))
+	}
+	return true
+}
+
+// Check checks the packages in nodes that look private. Packages resolved
+// from the public registry outside the private scopes are skipped, as are
+// git, file and link dependencies.
+func (c *Checker) Check(ctx context.Context, nodes []*models.PackageNode) []Result {
+	var candidates []*models.PackageNode
+	for _, node := range nodes {
+		if c.inScope(node.Name) || (isRegistryTarball(node.ResolvedURL) && !c.isPublic(node.ResolvedURL)) {
+			candidates = append(candidates, node)
+		}
+	}
+
+	results := make([]Result, len(candidates))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+	for i, node := range candidates {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			results[i] = c.check(ctx, node)
+		}()
+	}
+	wg.Wait()
+
+	// Packages resolved from an unconfigured registry that turn out to be
+	// public are mirrored, not private
+	checked := results[:0]
+	for _, r := range results {
+		if r.Reason != "" {
+			checked = append(checked, r)
+		}
+	}
+	sort.Slice(checked, func(i, j int) bool { return checked[i].Package < checked[j].Package })
+	return checked
+}
+
+// check checks one candidate package
+func (c *Checker) check(ctx context.Context, node *models.PackageNode) Result {
+	r := Result{Package: node.ID, Name: node.Name, Version: node.Version, Resolved: node.ResolvedURL}
+	private := c.privateRegistry(node.ResolvedURL)
+
+	public, err := c.fetch(ctx, c.PublicURL, node.Name, "")
+	if err != nil {
+		r.Error = err.Error()
+	}
+	r.OnPublic = public != nil
+	if public != nil {
+		r.PublicLatest = public.DistTags["latest"]
+		r.PublicPublishers = public.publishers()
+	}
+
+	switch {
+	case c.inScope(node.Name):
+		r.Reason = "internal scope " + scopeOf(node.Name)
+	case private != "":
+		r.Reason = "resolved from private registry " + registryKey(private)
+	case !r.OnPublic && err == nil:
+		r.Reason = "not on the public registry"
+	default:
+		return Result{}
+	}
+
+	switch {
+	case node.ResolvedURL != "" && c.isPublic(node.ResolvedURL):
+		r.Kind = KindResolvedPublicly
+		return r
+	case private == "" && node.ResolvedURL != "":
+		r.Kind = KindUnknownRegistry
+		return r
+	case !r.OnPublic:
+		return r
+	}
+
+	// Public too: fine only if the same people publish both
+	if private == "" && len(c.Registries) > 0 {
+		private = c.Registries[0]
+	}
+	if private != "" {
+		own, err := c.fetch(ctx, private, node.Name, c.tokenFor(private))
+		if err != nil {
+			r.Error = err.Error()
+		}
+		if own != nil {
+			r.PrivatePublishers = own.publishers()
+		}
+	}
+	if len(r.PrivatePublishers) == 0 && !c.inScope(node.Name) {
+		// Can't tell a mirror of a public package from a shadowed one
+		return r
+	}
+	if !overlaps(r.PublicPublishers, r.PrivatePublishers) {
+		r.Kind = KindPublicConflict
+	}
+	return r
+}
+
+// packument is the part of a registry's package document naming publishers
+type packument struct {
+	DistTags    map[string]string `json:"dist-tags"`
+	Maintainers []struct {
+		Name string `json:"name"`
+	} `json:"maintainers"`
+	Versions map[string]struct {
+		NPMUser struct {
+			Name string `json:"name"`
+		} `json:"_npmUser"`
+	} `json:"versions"`
+}
+
+// publishers returns the maintainers and version publishers, lowercased
+func (p *packument) publishers() []string {
+	seen := make(map[string]bool)
+	for _, m := range p.Maintainers {
+		seen[strings.ToLower(m.Name)] = m.Name != ""
+	}
+	for _, v := range p.Versions {
+		seen[strings.ToLower(v.NPMUser.Name)] = v.NPMUser.Name != ""
+	}
+	var names []string
+	for name, ok := range seen {
+		if ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// fetch returns a package's document from a registry, or nil when the
+// registry doesn't have it
+func (c *Checker) fetch(ctx context.Context, registry, name, token string) (*packument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(registry, "/")+"/"+naming.URLPath(name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up %s: %w", name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to look up %s on %s: status %d", name, registryKey(registry), resp.StatusCode)
+	}
+	var p packument
+	if err := json.NewDecoder(resp.Body).Decode(&p); err != nil {
+		return nil, fmt.Errorf("failed to parse %s from %s: %w", name, registryKey(registry), err)
+	}
+	return &p, nil
+}
+
+func (c *Checker) inScope(name string) bool {
+	return slices.Contains(c.Scopes, scopeOf(name))
+}
+
+func (c *Checker) isPublic(resolved string) bool {
+	return strings.HasPrefix(registryKey(resolved), registryKey(c.PublicURL))
+}
+
+// privateRegistry returns the configured private registry a tarball URL is
+// on, or ""
+func (c *Checker) privateRegistry(resolved string) string {
+	key := registryKey(resolved)
+	for _, registry := range c.Registries {
+		if strings.HasPrefix(key, registryKey(registry)) {
+			return registry
+		}
+	}
+	return ""
+}
+
+// tokenFor returns the auth token for a registry: the .npmrc token with the
+// longest matching prefix, else Token
+func (c *Checker) tokenFor(registry string) string {
+	key := registryKey(registry)
+	token, best := c.Token, 0
+	for prefix, t := range c.Tokens {
+		if p := strings.TrimSuffix(prefix, "/") + "/"; strings.HasPrefix(key, p) && len(p) > best {
+			token, best = t, len(p)
+		}
+	}
+	return token
+}
+
+// registryKey returns a URL as "//host/path/", the form .npmrc keys
+// registries by, or "" if it isn't an http(s) URL
+func registryKey(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ""
+	}
+	return "//" + u.Host + strings.TrimSuffix(u.Path, "/") + "/"
+}
+
+// isRegistryTarball reports whether a resolved URL is a registry tarball
+// (.../-/name-1.0.0.tgz) rather than a git, file or arbitrary URL dependency
+func isRegistryTarball(resolved string) bool {
+	return registryKey(resolved) != "" && strings.Contains(resolved, "/-/")
+}
+
+func scopeOf(name string) string {
+	if scope, _, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(scope, "@") {
+		return scope
+	}
+	return ""
+}
+
+func overlaps(a, b []string) bool {
+	for _, name := range a {
+		if slices.Contains(b, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,111 @@
+package confusion
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// registry serves package documents by URL path, as name -> maintainer
+func registry(t *testing.T, packages map[string]string, token string) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" && r.Header.Get("Authorization") != "Bearer "+token {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		maintainer, ok := packages[r.URL.EscapedPath()[1:]]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"dist-tags":{"latest":"99.0.0"},"maintainers":[{"name":"` + maintainer + `"}]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func node(name, version, resolved string) *models.PackageNode {
+	return &models.PackageNode{Package: models.Package{ID: name + "@" + version, Name: name, Version: version}, ResolvedURL: resolved}
+}
+
+func TestCheck(t *testing.T) {
+	public := registry(t, map[string]string{
+		"@acme%2fshadowed": "attacker",
+		"@acme%2fowned":    "acme-bot",
+		"mirrored":         "someone",
+		"lodash":           "jdalton",
+	}, "")
+	private := registry(t, map[string]string{
+		"@acme%2fshadowed": "acme-bot",
+		"@acme%2fowned":    "acme-bot",
+		"@acme%2fsafe":     "acme-bot",
+		"mirrored":         "someone",
+		"internal-tool":    "acme-bot",
+	}, "secret")
+
+	checker := NewChecker(public.URL, []string{"acme"}, []string{private.URL + "/"}, "secret")
+	tarball := func(base, name string) string { return base + "/" + name + "/-/x-1.0.0.tgz" }
+	results := checker.Check(t.Context(), []*models.PackageNode{
+		node("@acme/shadowed", "1.0.0", tarball(private.URL, "@acme/shadowed")),
+		node("@acme/owned", "1.0.0", tarball(private.URL, "@acme/owned")),
+		node("@acme/safe", "1.0.0", tarball(private.URL, "@acme/safe")),
+		node("@acme/hijacked", "1.0.0", tarball(public.URL, "@acme/hijacked")),
+		node("mirrored", "1.0.0", tarball(private.URL, "mirrored")),
+		node("internal-tool", "1.0.0", tarball("http://other.example", "internal-tool")),
+		node("lodash", "4.17.21", tarball(public.URL, "lodash")),
+		node("gitdep", "1.0.0", "git+ssh://git@github.com/acme/gitdep.git"),
+	})
+
+	kinds := make(map[string]string)
+	byName := make(map[string]Result)
+	for _, r := range results {
+		kinds[r.Name] = r.Kind
+		byName[r.Name] = r
+	}
+	assert.Equal(t, map[string]string{
+		"@acme/shadowed": KindPublicConflict,
+		"@acme/owned":    "",
+		"@acme/safe":     "",
+		"@acme/hijacked": KindResolvedPublicly,
+		"mirrored":       "",
+		"internal-tool":  KindUnknownRegistry,
+	}, kinds)
+
+	shadowed := byName["@acme/shadowed"]
+	assert.Equal(t, []string{"attacker"}, shadowed.PublicPublishers)
+	assert.Equal(t, []string{"acme-bot"}, shadowed.PrivatePublishers)
+	assert.Equal(t, "99.0.0", shadowed.PublicLatest)
+	assert.Equal(t, "not on the public registry", byName["internal-tool"].Reason)
+}
+
+func TestCheckerUseNpmrc(t *testing.T) {
+	t.Setenv("ACME_TOKEN", "from-env")
+	path := filepath.Join(t.TempDir(), ".npmrc")
+	require.NoError(t, os.WriteFile(path, []byte(`registry=https://registry.npmjs.org/
+@acme:registry=https://npm.acme.dev/
+@public:registry=https://registry.npmjs.org/
+# a comment
+//npm.acme.dev/:_authToken=${ACME_TOKEN}
+`), 0o644))
+	rc, err := LoadNpmrc(path)
+	require.NoError(t, err)
+
+	checker := NewChecker("https://registry.npmjs.org", nil, nil, "fallback")
+	assert.False(t, checker.Enabled())
+	checker.UseNpmrc(rc)
+	assert.True(t, checker.Enabled())
+	assert.Equal(t, []string{"@acme"}, checker.Scopes)
+	assert.Equal(t, []string{"https://npm.acme.dev"}, checker.Registries)
+	assert.Equal(t, "from-env", checker.tokenFor("https://npm.acme.dev/@acme%2flib"))
+	assert.Equal(t, "fallback", checker.tokenFor("https://other.example"))
+
+	rc, err = LoadNpmrc(filepath.Join(t.TempDir(), ".npmrc"))
+	require.NoError(t, err)
+	assert.Empty(t, rc.Scopes)
+}
@@ -0,0 +1,52 @@
+package confusion
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Npmrc is the registry configuration in an .npmrc file
+type Npmrc struct {
+	Registry string            // Default registry, if set
+	Scopes   map[string]string // Registry by scope, e.g. "@acme" -> "https://npm.acme.dev/"
+	Tokens   map[string]string // Auth token by registry, as "//host/path/"
+}
+
+// LoadNpmrc reads the registry settings of an .npmrc file, expanding
+// ${VAR} references to the environment as npm does. A missing file yields
+// an empty configuration.
+func LoadNpmrc(path string) (*Npmrc, error) {
+	rc := &Npmrc{Scopes: make(map[string]string), Tokens: make(map[string]string)}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return rc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		value = os.ExpandEnv(strings.Trim(strings.TrimSpace(value), `"'`))
+		switch {
+		case key == "registry":
+			rc.Registry = value
+		case strings.HasPrefix(key, "@") && strings.HasSuffix(key, ":registry"):
+			rc.Scopes[strings.TrimSuffix(key, ":registry")] = value
+		case strings.HasPrefix(key, "//") && strings.HasSuffix(key, ":_authToken"):
+			rc.Tokens[strings.TrimSuffix(key, ":_authToken")] = value
+		}
+	}
+	return rc, scanner.Err()
+}
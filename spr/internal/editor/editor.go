@@ -0,0 +1,307 @@
+// Package editor turns spr verdicts into diagnostics on the dependency lines
+// of a package.json buffer, for editor extensions to render inline. Serve
+// speaks a line-delimited JSON protocol over stdin/stdout; the diagnostics
+// follow the Language Server Protocol's shape so extensions can pass them
+// straight to their editor.
+package editor
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/parser"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Diagnostic severities, as in the Language Server Protocol
+const (
+	SeverityError       = 1
+	SeverityWarning     = 2
+	SeverityInformation = 3
+	SeverityHint        = 4
+)
+
+// VerdictUnknown is the code of dependencies spr has no verdict for
+const VerdictUnknown = "unknown"
+
+// Range is the span of a dependency entry
+type Range struct {
+	Start parser.Position `json:"start"`
+	End   parser.Position `json:"end"`
+}
+
+// Diagnostic annotates one dependency entry
+type Diagnostic struct {
+	Range    Range   `json:"range"`
+	Severity int     `json:"severity"`
+	Source   string  `json:"source"` // Always "spr"
+	Code     string  `json:"code"`   // The verdict, or VerdictUnknown
+	Message  string  `json:"message"`
+	Data     Finding `json:"data"`
+}
+
+// Finding is the machine-readable part of a diagnostic
+type Finding struct {
+	Package  string  `json:"package"`
+	Version  string  `json:"version"`
+	Verdict  string  `json:"verdict"`
+	Score    float64 `json:"score,omitempty"`    // Confidence of the AI verdict
+	Advisory string  `json:"advisory,omitempty"` // Path or URL of the drafted advisory
+}
+
+// Verdict is what a Lookup knows about a package version
+type Verdict struct {
+	Verdict  string // One of the models.Verdict* values
+	Score    float64
+	Summary  string // Justification of the verdict, if available
+	Advisory string
+}
+
+// Lookup returns the verdicts it knows among packages, keyed by
+// name@version; packages without one are left out
+type Lookup func(ctx context.Context, packages []models.Package) (map[string]Verdict, error)
+
+// Chain consults each lookup in turn for the packages the previous ones had
+// no verdict for
+func Chain(lookups ...Lookup) Lookup {
+	return func(ctx context.Context, packages []models.Package) (map[string]Verdict, error) {
+		verdicts := make(map[string]Verdict)
+		for _, lookup := range lookups {
+			var missing []models.Package
+			for _, pkg := range packages {
+				if _, ok := verdicts[pkg.Name+"@"+pkg.Version]; !ok {
+					missing = append(missing, pkg)
+				}
+			}
+			if len(missing) == 0 {
+				break
+			}
+			found, err := lookup(ctx, missing)
+			if err != nil {
+				return nil, err
+			}
+			for key, v := range found {
+				verdicts[key] = v
+			}
+		}
+		return verdicts, nil
+	}
+}
+
+// exactVersion matches specs that pin a single version
+var exactVersion = regexp.MustCompile(`^=?v?\d+\.\d+\.\d+(-[0-9A-Za-z.-]+)?(\+[0-9A-Za-z.-]+)?$`)
+
+// Diagnose returns a diagnostic for every dependency of packageJSON whose
+// version is known: installed according to lockfile (package-lock.json v3,
+// optional) or pinned exactly. Ranges without a lockfile can't be resolved
+// and get none.
+func Diagnose(ctx context.Context, packageJSON, lockfile []byte, lookup Lookup) ([]Diagnostic, error) {
+	entries, err := parser.LocateDependencies(packageJSON)
+	if err != nil {
+		return nil, err
+	}
+	var installs map[string]models.Package
+	if len(lockfile) > 0 {
+		if installs, err = parser.DirectInstalls(lockfile); err != nil {
+			return nil, err
+		}
+	}
+
+	resolved := make([]*models.Package, len(entries))
+	var packages []models.Package
+	for i, entry := range entries {
+		pkg, ok := installs[entry.Name]
+		if !ok {
+			name, spec := entry.Name, entry.Spec
+			if real, version, isAlias := parser.ParseAlias(spec); isAlias {
+				name, spec = real, version
+			}
+			if !exactVersion.MatchString(spec) {
+				continue
+			}
+			version := strings.TrimLeft(spec, "=v")
+			pkg = models.Package{ID: name + "@" + version, Name: name, Version: version}
+		}
+		resolved[i] = &pkg
+		packages = append(packages, pkg)
+	}
+
+	verdicts, err := lookup(ctx, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []Diagnostic
+	for i, entry := range entries {
+		pkg := resolved[i]
+		if pkg == nil {
+			continue
+		}
+		d := Diagnostic{
+			Range:  Range{Start: entry.Start, End: entry.End},
+			Source: "spr",
+			Data:   Finding{Package: pkg.Name, Version: pkg.Version, Verdict: VerdictUnknown},
+		}
+		id := pkg.Name + "@" + pkg.Version
+		v, known := verdicts[id]
+		switch {
+		case !known:
+			d.Severity = SeverityHint
+			d.Message = fmt.Sprintf("%s has not been analyzed by spr", id)
+		case v.Verdict == models.VerdictMalicious:
+			d.Severity = SeverityError
+			d.Message = fmt.Sprintf("%s was flagged as malicious by spr (confidence %.0f%%)", id, v.Score*100)
+		case v.Verdict == models.VerdictClean:
+			d.Severity = SeverityInformation
+			d.Message = fmt.Sprintf("%s was analyzed by spr: no behavior beyond the baseline", id)
+		default:
+			d.Severity = SeverityInformation
+			d.Message = fmt.Sprintf("%s was analyzed by spr: not malicious (confidence %.0f%%)", id, v.Score*100)
+		}
+		if known {
+			if v.Summary != "" {
+				d.Message += ": " + v.Summary
+			}
+			d.Data.Verdict, d.Data.Score, d.Data.Advisory = v.Verdict, v.Score, v.Advisory
+		}
+		d.Code = d.Data.Verdict
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics, nil
+}
+
+// Request asks for the diagnostics of a package.json buffer. Without a
+// lockfile, the package-lock.json next to a file:// URI is used if present.
+type Request struct {
+	ID       json.RawMessage `json:"id,omitempty"` // Echoed in the response
+	URI      string          `json:"uri,omitempty"`
+	Text     string          `json:"text"`
+	Lockfile string          `json:"lockfile,omitempty"`
+}
+
+// Response carries the diagnostics of a request, or why there are none
+type Response struct {
+	ID          json.RawMessage `json:"id,omitempty"`
+	URI         string          `json:"uri,omitempty"`
+	Diagnostics []Diagnostic    `json:"diagnostics"`
+	Error       string          `json:"error,omitempty"`
+}
+
+// maxRequest bounds a request line
+const maxRequest = 16 << 20
+
+// Serve answers requests read one per line from r with a response per line
+// on w, until r is exhausted or ctx is cancelled
+func Serve(ctx context.Context, r io.Reader, w io.Writer, lookup Lookup) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRequest)
+	enc := json.NewEncoder(w)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var req Request
+		resp := Response{Diagnostics: []Diagnostic{}}
+		if err := json.Unmarshal(line, &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp.ID, resp.URI = req.ID, req.URI
+			if diagnostics, err := Diagnose(ctx, []byte(req.Text), requestLockfile(req), lookup); err != nil {
+				resp.Error = err.Error()
+			} else if diagnostics != nil {
+				resp.Diagnostics = diagnostics
+			}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// requestLockfile returns the lockfile sent with a request, or the one on
+// disk next to its package.json
+func requestLockfile(req Request) []byte {
+	if req.Lockfile != "" {
+		return []byte(req.Lockfile)
+	}
+	u, err := url.Parse(req.URI)
+	if err != nil || u.Scheme != "file" {
+		return nil
+	}
+	data, err := os.ReadFile(filepath.Join(filepath.Dir(filepath.FromSlash(u.Path)), "package-lock.json"))
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// RemoteLookup queries the public bulk verdict API of an spr server at
+// baseURL, which only knows packages published as safe
+func RemoteLookup(baseURL string) Lookup {
+	client := &http.Client{Timeout: 30 * time.Second}
+	endpoint := strings.TrimSuffix(baseURL, "/") + "/api/verdicts/bulk"
+	return func(ctx context.Context, packages []models.Package) (map[string]Verdict, error) {
+		if len(packages) == 0 {
+			return nil, nil
+		}
+		query := struct {
+			Packages []string `json:"packages"`
+		}{}
+		for _, pkg := range packages {
+			query.Packages = append(query.Packages, pkg.Name+"@"+pkg.Version)
+		}
+		body, err := json.Marshal(query)
+		if err != nil {
+			return nil, err
+		}
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("verdict API: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("verdict API returned %s", resp.Status)
+		}
+
+		var result struct {
+			Verdicts []struct {
+				Package    string  `json:"package"`
+				Version    string  `json:"version"`
+				Verdict    string  `json:"verdict"`
+				Confidence float64 `json:"confidence"`
+			} `json:"verdicts"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+			return nil, fmt.Errorf("verdict API: %w", err)
+		}
+		verdicts := make(map[string]Verdict)
+		for _, v := range result.Verdicts {
+			if v.Verdict != VerdictUnknown {
+				verdicts[v.Package+"@"+v.Version] = Verdict{Verdict: v.Verdict, Score: v.Confidence}
+			}
+		}
+		return verdicts, nil
+	}
+}
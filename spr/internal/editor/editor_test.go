@@ -0,0 +1,178 @@
+package editor
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const packageJSON = `{
+  "name": "app",
+  "dependencies": {
+    "left-pad": "^1.3.0",
+    "evil": "2.0.0",
+    "fresh": "1.0.0",
+    "ranged": "~3.0.0"
+  },
+  "devDependencies": {
+    "alias": "npm:real@4.0.0"
+  }
+}`
+
+const lockfile = `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app"},
+    "node_modules/left-pad": {"version": "1.3.0"}
+  }
+}`
+
+// staticLookup serves fixed verdicts, recording what it was asked
+func staticLookup(verdicts map[string]Verdict, asked *[]string) Lookup {
+	return func(ctx context.Context, packages []models.Package) (map[string]Verdict, error) {
+		found := make(map[string]Verdict)
+		for _, pkg := range packages {
+			key := pkg.Name + "@" + pkg.Version
+			if asked != nil {
+				*asked = append(*asked, key)
+			}
+			if v, ok := verdicts[key]; ok {
+				found[key] = v
+			}
+		}
+		return found, nil
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	lookup := staticLookup(map[string]Verdict{
+		"left-pad@1.3.0": {Verdict: models.VerdictClean},
+		"evil@2.0.0":     {Verdict: models.VerdictMalicious, Score: 0.97, Summary: "exfiltrates ~/.npmrc", Advisory: "/out/evil@2.0.0/advisory.md"},
+		"real@4.0.0":     {Verdict: models.VerdictSafe, Score: 0.8},
+	}, nil)
+
+	diagnostics, err := Diagnose(context.Background(), []byte(packageJSON), []byte(lockfile), lookup)
+	require.NoError(t, err)
+	require.Len(t, diagnostics, 4, "the unresolved range gets no diagnostic")
+
+	byName := make(map[string]Diagnostic)
+	for _, d := range diagnostics {
+		byName[d.Data.Package] = d
+	}
+
+	leftPad := byName["left-pad"]
+	assert.Equal(t, SeverityInformation, leftPad.Severity)
+	assert.Equal(t, models.VerdictClean, leftPad.Code)
+	assert.Equal(t, "1.3.0", leftPad.Data.Version, "resolved from the lockfile")
+	assert.Equal(t, 3, leftPad.Range.Start.Line)
+
+	evil := byName["evil"]
+	assert.Equal(t, SeverityError, evil.Severity)
+	assert.Equal(t, models.VerdictMalicious, evil.Code)
+	assert.Contains(t, evil.Message, "97%")
+	assert.Contains(t, evil.Message, "exfiltrates ~/.npmrc")
+	assert.Equal(t, 0.97, evil.Data.Score)
+	assert.Equal(t, "/out/evil@2.0.0/advisory.md", evil.Data.Advisory)
+	assert.Equal(t, "spr", evil.Source)
+
+	fresh := byName["fresh"]
+	assert.Equal(t, SeverityHint, fresh.Severity)
+	assert.Equal(t, VerdictUnknown, fresh.Code)
+
+	real := byName["real"]
+	assert.Equal(t, SeverityInformation, real.Severity, "aliases are looked up by the real package")
+	assert.Equal(t, 9, real.Range.Start.Line)
+}
+
+func TestChain(t *testing.T) {
+	var asked []string
+	first := staticLookup(map[string]Verdict{"a@1.0.0": {Verdict: models.VerdictSafe}}, nil)
+	second := staticLookup(map[string]Verdict{"b@1.0.0": {Verdict: models.VerdictMalicious}}, &asked)
+
+	verdicts, err := Chain(first, second)(context.Background(), []models.Package{
+		{Name: "a", Version: "1.0.0"},
+		{Name: "b", Version: "1.0.0"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, verdicts, 2)
+	assert.Equal(t, []string{"b@1.0.0"}, asked, "later lookups are only asked about misses")
+}
+
+func TestServe(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte(lockfile), 0o644))
+
+	requests := []Request{
+		{ID: json.RawMessage(`1`), URI: "file://" + filepath.ToSlash(filepath.Join(dir, "package.json")), Text: packageJSON},
+		{ID: json.RawMessage(`"two"`), Text: "not json"},
+	}
+	var in bytes.Buffer
+	for _, req := range requests {
+		require.NoError(t, json.NewEncoder(&in).Encode(req))
+	}
+	in.WriteString("{broken\n")
+
+	lookup := staticLookup(map[string]Verdict{"left-pad@1.3.0": {Verdict: models.VerdictClean}}, nil)
+	var out bytes.Buffer
+	require.NoError(t, Serve(context.Background(), &in, &out, lookup))
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	require.Len(t, lines, 3)
+
+	var resp Response
+	require.NoError(t, json.Unmarshal([]byte(lines[0]), &resp))
+	assert.JSONEq(t, `1`, string(resp.ID))
+	assert.Empty(t, resp.Error)
+	var leftPad *Diagnostic
+	for i, d := range resp.Diagnostics {
+		if d.Data.Package == "left-pad" {
+			leftPad = &resp.Diagnostics[i]
+		}
+	}
+	require.NotNil(t, leftPad, "the lockfile next to the file:// URI resolves the range")
+	assert.Equal(t, models.VerdictClean, leftPad.Code)
+
+	resp = Response{}
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &resp))
+	assert.JSONEq(t, `"two"`, string(resp.ID))
+	assert.NotEmpty(t, resp.Error)
+	assert.Empty(t, resp.Diagnostics)
+
+	resp = Response{}
+	require.NoError(t, json.Unmarshal([]byte(lines[2]), &resp))
+	assert.Contains(t, resp.Error, "invalid request")
+}
+
+func TestRemoteLookup(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/api/verdicts/bulk", r.URL.Path)
+		var query struct {
+			Packages []string `json:"packages"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&query))
+		assert.Equal(t, []string{"left-pad@1.3.0", "fresh@1.0.0"}, query.Packages)
+		w.Write([]byte(`{"verdicts": [
+			{"package": "left-pad", "version": "1.3.0", "verdict": "safe", "confidence": 0.9},
+			{"package": "fresh", "version": "1.0.0", "verdict": "unknown"}
+		]}`))
+	}))
+	defer srv.Close()
+
+	verdicts, err := RemoteLookup(srv.URL+"/")(context.Background(), []models.Package{
+		{Name: "left-pad", Version: "1.3.0"},
+		{Name: "fresh", Version: "1.0.0"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Verdict{"left-pad@1.3.0": {Verdict: models.VerdictSafe, Score: 0.9}}, verdicts)
+}
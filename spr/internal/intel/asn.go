@@ -0,0 +1,95 @@
+package intel
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// asnRange is one announced address range of an ip2asn database
+type asnRange struct {
+	start, end netip.Addr
+	asn        int
+	country    string
+	org        string
+}
+
+// asnTable maps addresses to the network announcing them
+type asnTable struct {
+	ranges []asnRange // Sorted by start, non-overlapping
+}
+
+// loadASNTable reads an ip2asn database (https://iptoasn.com): one range per
+// line, tab-separated as range_start, range_end, AS_number, country_code and
+// AS_description. Files ending in .gz are decompressed.
+func loadASNTable(path string) (*asnTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ASN database: %w", err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress ASN database: %w", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return parseASNTable(r)
+}
+
+func parseASNTable(r io.Reader) (*asnTable, error) {
+	t := &asnTable{}
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) < 5 {
+			continue
+		}
+		asn, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("ASN database line %d: invalid AS number %q", n, fields[2])
+		}
+		if asn == 0 {
+			continue // "Not routed"
+		}
+		start, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("ASN database line %d: %w", n, err)
+		}
+		end, err := netip.ParseAddr(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("ASN database line %d: %w", n, err)
+		}
+		t.ranges = append(t.ranges, asnRange{start: start, end: end, asn: asn, country: fields[3], org: fields[4]})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ASN database: %w", err)
+	}
+
+	sort.Slice(t.ranges, func(i, j int) bool { return t.ranges[i].start.Less(t.ranges[j].start) })
+	return t, nil
+}
+
+// lookup returns the range containing addr
+func (t *asnTable) lookup(addr netip.Addr) (asnRange, bool) {
+	// Last range starting at or before addr
+	i := sort.Search(len(t.ranges), func(i int) bool { return addr.Less(t.ranges[i].start) }) - 1
+	if i < 0 {
+		return asnRange{}, false
+	}
+	r := t.ranges[i]
+	if r.start.BitLen() != addr.BitLen() || r.end.Less(addr) {
+		return asnRange{}, false
+	}
+	return r, true
+}
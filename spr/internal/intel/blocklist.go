@@ -0,0 +1,133 @@
+package intel
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/netip"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// blocklist is a feed of IPs, ranges, domains and ASNs sharing one verdict
+type blocklist struct {
+	verdict  string
+	addrs    map[netip.Addr]bool
+	prefixes []netip.Prefix
+	domains  map[string]bool // Matched with their subdomains
+	asns     map[int]bool
+}
+
+// loadBlocklist reads a feed. Each line holds one entry: an IP, a CIDR range,
+// a domain, a URL (its host is listed), an AS number such as "AS64500", or a
+// hosts-file line ("0.0.0.0 evil.example"). Quoted CSV lines, as in URLhaus
+// exports, are searched for their URL field. # and ; start comments.
+func loadBlocklist(path, verdict string) (*blocklist, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blocklist: %w", err)
+	}
+	defer f.Close()
+	return parseBlocklist(f, verdict)
+}
+
+func parseBlocklist(r io.Reader, verdict string) (*blocklist, error) {
+	b := &blocklist{
+		verdict: verdict,
+		addrs:   make(map[netip.Addr]bool),
+		domains: make(map[string]bool),
+		asns:    make(map[int]bool),
+	}
+	sc := bufio.NewScanner(r)
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if line[0] == '"' {
+			b.addCSV(line)
+			continue
+		}
+		fields := strings.Fields(line)
+		entry := fields[0]
+		if len(fields) > 1 && (entry == "0.0.0.0" || entry == "127.0.0.1" || entry == "::") {
+			entry = fields[1]
+		}
+		b.add(entry)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read blocklist: %w", err)
+	}
+	return b, nil
+}
+
+// addCSV adds the host of the first URL field of a CSV line
+func (b *blocklist) addCSV(line string) {
+	fields, err := csv.NewReader(strings.NewReader(line)).Read()
+	if err != nil {
+		return
+	}
+	for _, field := range fields {
+		if u, err := url.Parse(field); err == nil && u.Scheme != "" && u.Host != "" {
+			b.add(field)
+			return
+		}
+	}
+}
+
+func (b *blocklist) add(entry string) {
+	if u, err := url.Parse(entry); err == nil && u.Scheme != "" && u.Host != "" {
+		entry = u.Hostname()
+	}
+	if prefix, err := netip.ParsePrefix(entry); err == nil {
+		b.prefixes = append(b.prefixes, prefix.Masked())
+		return
+	}
+	if addr, err := netip.ParseAddr(entry); err == nil {
+		b.addrs[addr.Unmap()] = true
+		return
+	}
+	if upper := strings.ToUpper(entry); strings.HasPrefix(upper, "AS") {
+		if asn, err := strconv.Atoi(upper[2:]); err == nil {
+			b.asns[asn] = true
+			return
+		}
+	}
+	if domain := aggregate.NormalizeDomain(strings.TrimPrefix(strings.TrimPrefix(entry, "*"), ".")); domain != "" {
+		b.domains[domain] = true
+	}
+}
+
+// matchAddr reports whether an address, or the AS announcing it, is listed
+func (b *blocklist) matchAddr(addr netip.Addr, asn int) bool {
+	if b.addrs[addr] || (asn != 0 && b.asns[asn]) {
+		return true
+	}
+	for _, prefix := range b.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchDomain reports whether a domain or one of its parents is listed
+func (b *blocklist) matchDomain(domain string) bool {
+	for domain != "" {
+		if b.domains[domain] {
+			return true
+		}
+		_, parent, ok := strings.Cut(domain, ".")
+		if !ok {
+			return false
+		}
+		domain = parent
+	}
+	return false
+}
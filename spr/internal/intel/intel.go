@@ -0,0 +1,197 @@
+// Package intel enriches diffs with threat intelligence about the IPs and
+// domains packages contacted: the network (ASN, country) announcing each IP
+// and the verdicts of local blocklists such as sinkhole lists, URLhaus
+// exports or residential proxy ASNs
+package intel
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"gopkg.in/yaml.v3"
+)
+
+// Config lists the intel sources. It is loaded from YAML (or JSON):
+//
+//	asn_db: ip2asn-combined.tsv.gz    # https://iptoasn.com, optional
+//	blocklists:
+//	  - path: sinkholes.txt
+//	    verdict: known sinkhole
+//	  - path: urlhaus.csv             # URLhaus CSV export
+//	    verdict: malware distribution (URLhaus)
+//	  - path: residential-asns.txt    # AS64500, AS64501, ...
+//	    verdict: residential proxy ASN
+//
+// Relative paths are resolved against the config file's directory.
+type Config struct {
+	ASNDatabase string          `yaml:"asn_db" json:"asn_db"`
+	Blocklists  []BlocklistFeed `yaml:"blocklists" json:"blocklists"`
+}
+
+// BlocklistFeed is a local feed and the verdict for what it lists
+type BlocklistFeed struct {
+	Path    string `yaml:"path" json:"path"`
+	Verdict string `yaml:"verdict" json:"verdict"`
+}
+
+// Enricher looks up intel about IPs and domains. A nil Enricher enriches
+// nothing.
+type Enricher struct {
+	asn        *asnTable
+	blocklists []*blocklist
+}
+
+// Load reads an intel config and the sources it lists
+func Load(path string) (*Enricher, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read intel config: %w", err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse intel config: %w", err)
+	}
+	return New(cfg, filepath.Dir(path))
+}
+
+// New loads the sources of cfg, resolving relative paths against dir
+func New(cfg Config, dir string) (*Enricher, error) {
+	resolve := func(p string) string {
+		if p == "" || filepath.IsAbs(p) {
+			return p
+		}
+		return filepath.Join(dir, p)
+	}
+
+	e := &Enricher{}
+	if cfg.ASNDatabase != "" {
+		table, err := loadASNTable(resolve(cfg.ASNDatabase))
+		if err != nil {
+			return nil, err
+		}
+		e.asn = table
+	}
+	for i, feed := range cfg.Blocklists {
+		if feed.Path == "" || feed.Verdict == "" {
+			return nil, fmt.Errorf("blocklist %d needs a path and a verdict", i+1)
+		}
+		list, err := loadBlocklist(resolve(feed.Path), feed.Verdict)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", feed.Path, err)
+		}
+		e.blocklists = append(e.blocklists, list)
+	}
+	return e, nil
+}
+
+// Lookup returns what is known about an IP (optionally with a port) or a
+// domain, nil if nothing is
+func (e *Enricher) Lookup(indicator string) *aggregate.IntelFinding {
+	if e == nil {
+		return nil
+	}
+	if addr, ok := parseAddr(indicator); ok {
+		return e.lookupAddr(addr)
+	}
+	if host, _, err := net.SplitHostPort(indicator); err == nil {
+		indicator = host
+	}
+	return e.lookupDomain(aggregate.NormalizeDomain(indicator))
+}
+
+func (e *Enricher) lookupAddr(addr netip.Addr) *aggregate.IntelFinding {
+	finding := &aggregate.IntelFinding{Indicator: addr.String(), Kind: "ip"}
+	if e.asn != nil {
+		if r, ok := e.asn.lookup(addr); ok {
+			finding.ASN, finding.ASOrg, finding.Country = r.asn, r.org, r.country
+		}
+	}
+	for _, list := range e.blocklists {
+		if list.matchAddr(addr, finding.ASN) {
+			finding.Verdicts = append(finding.Verdicts, list.verdict)
+		}
+	}
+	if finding.ASN == 0 && len(finding.Verdicts) == 0 {
+		return nil
+	}
+	return finding
+}
+
+func (e *Enricher) lookupDomain(domain string) *aggregate.IntelFinding {
+	finding := &aggregate.IntelFinding{Indicator: domain, Kind: "domain"}
+	for _, list := range e.blocklists {
+		if list.matchDomain(domain) {
+			finding.Verdicts = append(finding.Verdicts, list.verdict)
+		}
+	}
+	if len(finding.Verdicts) == 0 {
+		return nil
+	}
+	return finding
+}
+
+// Enrich sets the diff's Intel to what is known about the IPs, domains and
+// URL hosts its processes and proxy-captured requests contacted, its
+// variants' included, sorted by indicator
+func (e *Enricher) Enrich(d *aggregate.DedupedProcessStats) {
+	if e == nil || d == nil {
+		return
+	}
+	indicators := make(map[string]bool)
+	collectIndicators(d, indicators)
+
+	d.Intel = nil
+	seen := make(map[string]bool)
+	for indicator := range indicators {
+		finding := e.Lookup(indicator)
+		if finding == nil || seen[finding.Indicator] {
+			continue
+		}
+		seen[finding.Indicator] = true
+		d.Intel = append(d.Intel, *finding)
+	}
+	sort.Slice(d.Intel, func(i, j int) bool { return d.Intel[i].Indicator < d.Intel[j].Indicator })
+}
+
+func collectIndicators(d *aggregate.DedupedProcessStats, indicators map[string]bool) {
+	for _, proc := range d.PerProcess {
+		for ip := range proc.NetworkActivity.IPs {
+			indicators[ip] = true
+		}
+		for domain := range proc.NetworkActivity.DNSRecords {
+			indicators[domain] = true
+		}
+		for u := range proc.NetworkActivity.URLs {
+			if parsed, err := url.Parse(u); err == nil && parsed.Hostname() != "" {
+				indicators[parsed.Hostname()] = true
+			}
+		}
+	}
+	if d.HTTPActivity != nil {
+		for host := range d.HTTPActivity.Hosts {
+			indicators[host] = true
+		}
+	}
+	for _, variant := range d.Variants {
+		collectIndicators(variant, indicators)
+	}
+}
+
+// parseAddr parses an IP with or without a port, as connect destinations
+// and proxy hosts are recorded
+func parseAddr(s string) (netip.Addr, bool) {
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap(), true
+	}
+	if addr, err := netip.ParseAddr(strings.Trim(s, "[]")); err == nil {
+		return addr.Unmap(), true
+	}
+	return netip.Addr{}, false
+}
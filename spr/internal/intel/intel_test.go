@@ -0,0 +1,115 @@
+package intel
+
+import (
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testASNTable = "1.0.0.0\t1.0.0.255\t13335\tUS\tCLOUDFLARENET\n" +
+	"5.6.0.0\t5.6.255.255\t64500\tNL\tEXAMPLE-RESIDENTIAL\n" +
+	"9.9.9.0\t9.9.9.255\t0\tNone\tNot routed\n" +
+	"2001:db8::\t2001:db8::ffff\t64501\tDE\tEXAMPLE-V6\n"
+
+func TestASNTableLookup(t *testing.T) {
+	table, err := parseASNTable(strings.NewReader(testASNTable))
+	require.NoError(t, err)
+
+	r, ok := table.lookup(netip.MustParseAddr("1.0.0.1"))
+	require.True(t, ok)
+	assert.Equal(t, 13335, r.asn)
+	assert.Equal(t, "CLOUDFLARENET", r.org)
+	assert.Equal(t, "US", r.country)
+
+	r, ok = table.lookup(netip.MustParseAddr("2001:db8::1"))
+	require.True(t, ok)
+	assert.Equal(t, 64501, r.asn)
+
+	for _, addr := range []string{"1.0.1.0", "9.9.9.9", "0.0.0.1", "::1"} {
+		_, ok := table.lookup(netip.MustParseAddr(addr))
+		assert.False(t, ok, addr)
+	}
+}
+
+func TestBlocklistFormats(t *testing.T) {
+	feed := `# sinkholes
+203.0.113.7
+198.51.100.0/24
+evil.example
+*.cdn.bad.example
+http://payload.example/stage2.sh
+AS64500
+0.0.0.0 hosts.example ; hosts-file line
+"123","2026-10-01 12:00:00","http://urlhaus.example:8080/bin","online","malware_download"
+`
+	list, err := parseBlocklist(strings.NewReader(feed), "bad")
+	require.NoError(t, err)
+
+	assert.True(t, list.matchAddr(netip.MustParseAddr("203.0.113.7"), 0))
+	assert.True(t, list.matchAddr(netip.MustParseAddr("198.51.100.42"), 0))
+	assert.True(t, list.matchAddr(netip.MustParseAddr("192.0.2.1"), 64500), "listed ASN")
+	assert.False(t, list.matchAddr(netip.MustParseAddr("192.0.2.1"), 13335))
+
+	for _, domain := range []string{"evil.example", "a.evil.example", "x.cdn.bad.example", "payload.example", "hosts.example", "urlhaus.example"} {
+		assert.True(t, list.matchDomain(domain), domain)
+	}
+	for _, domain := range []string{"notevil.example", "bad.example", "example"} {
+		assert.False(t, list.matchDomain(domain), domain)
+	}
+}
+
+func TestEnrich(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "asn.tsv"), []byte(testASNTable), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sinkholes.txt"), []byte("evil.example\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "residential.txt"), []byte("AS64500\n"), 0o644))
+	config := `asn_db: asn.tsv
+blocklists:
+  - path: sinkholes.txt
+    verdict: known sinkhole
+  - path: residential.txt
+    verdict: residential proxy ASN
+`
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "intel.yaml"), []byte(config), 0o644))
+
+	e, err := Load(filepath.Join(dir, "intel.yaml"))
+	require.NoError(t, err)
+
+	d := &aggregate.DedupedProcessStats{
+		PerProcess: map[string]*aggregate.ProcessSummary{
+			"node": {NetworkActivity: aggregate.NetworkActivity{
+				IPs:        map[string]int{"5.6.7.8:443": 2, "192.0.2.1:80": 1},
+				DNSRecords: map[string]int{"c2.evil.example": 1, "registry.npmjs.org": 1},
+				URLs:       map[string]int{"http://evil.example/x": 1},
+			}},
+		},
+		HTTPActivity: &aggregate.HTTPActivity{Hosts: map[string]int{"1.0.0.1:443": 1}},
+	}
+	e.Enrich(d)
+
+	assert.Equal(t, []aggregate.IntelFinding{
+		{Indicator: "1.0.0.1", Kind: "ip", ASN: 13335, ASOrg: "CLOUDFLARENET", Country: "US"},
+		{Indicator: "5.6.7.8", Kind: "ip", ASN: 64500, ASOrg: "EXAMPLE-RESIDENTIAL", Country: "NL", Verdicts: []string{"residential proxy ASN"}},
+		{Indicator: "c2.evil.example", Kind: "domain", Verdicts: []string{"known sinkhole"}},
+		{Indicator: "evil.example", Kind: "domain", Verdicts: []string{"known sinkhole"}},
+	}, d.Intel)
+}
+
+func TestNilEnricher(t *testing.T) {
+	var e *Enricher
+	d := &aggregate.DedupedProcessStats{}
+	e.Enrich(d)
+	assert.Nil(t, d.Intel)
+	assert.Nil(t, e.Lookup("evil.example"))
+}
+
+func TestNewRequiresVerdict(t *testing.T) {
+	_, err := New(Config{Blocklists: []BlocklistFeed{{Path: "list.txt"}}}, t.TempDir())
+	assert.Error(t, err)
+}
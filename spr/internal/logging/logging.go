@@ -0,0 +1,64 @@
+package logging
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// Attribute keys shared across components so log lines can be correlated
+const (
+	KeyRunID     = "run_id"
+	KeyPackageID = "package_id"
+	KeyStage     = "stage"
+	KeyStack     = "stack" // Goroutine stack of a recovered panic
+)
+
+// Setup installs the default slog logger. format is "json" for
+// machine-ingestible output (Loki/ELK) or anything else for plain text.
+// Output from the standard log package is routed through the same handler.
+func Setup(format string) {
+	slog.SetDefault(New(os.Stderr, format))
+}
+
+// New creates a logger writing to w in the given format ("json" or "text")
+func New(w io.Writer, format string) *slog.Logger {
+	opts := &slog.HandlerOptions{Level: slog.LevelInfo}
+	if strings.EqualFold(format, "json") {
+		return slog.New(slog.NewJSONHandler(w, opts))
+	}
+	return slog.New(slog.NewTextHandler(w, opts))
+}
+
+// NewRunID returns a short random identifier used to correlate all log
+// lines belonging to a single analysis run
+func NewRunID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Log writes message at the slog level corresponding to one of the
+// frontend log levels ("info", "success", "warning", "error").
+// "success" is logged at Info with result=success so it stays filterable.
+func Log(logger *slog.Logger, message, level string, attrs ...any) {
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	switch level {
+	case "success":
+		logger.Info(message, append(attrs, "result", "success")...)
+	case "warning":
+		logger.Warn(message, attrs...)
+	case "error":
+		logger.Error(message, attrs...)
+	default:
+		logger.Info(message, attrs...)
+	}
+}
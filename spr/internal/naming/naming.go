@@ -0,0 +1,70 @@
+// Package naming converts npm package names between the forms they take in
+// registry URLs, on disk and in messages. Scoped names ("@scope/name") can't
+// be used as-is in either of the first two, and every package that builds a
+// path or URL from a name goes through here so the forms always agree.
+// Container images and GitHub Actions are named by their repository, e.g.
+// "ghcr.io/owner/tool" or "actions/setup-node", and go through here too.
+package naming
+
+import "strings"
+
+// split returns the scope (without '@') and bare name of a scoped package
+func split(name string) (scope, bare string, ok bool) {
+	if !strings.HasPrefix(name, "@") {
+		return "", "", false
+	}
+	return strings.Cut(name[1:], "/")
+}
+
+// URLPath returns the name as a single registry URL path segment, e.g.
+// "@types/node" -> "@types%2fnode", as npm and Gitea expect
+func URLPath(name string) string {
+	if scope, bare, ok := split(name); ok {
+		return "@" + scope + "%2f" + bare
+	}
+	return name
+}
+
+// fileSafeEscapes escapes '_' and '/' in FileSafe names as '_' and a hex
+// code, so that every '_' in a FileSafe name starts an escape except the
+// "__" between scope and name, and FromFileSafe can always undo it
+var (
+	fileSafeEscapes   = strings.NewReplacer("_", "_5f", "/", "_2f")
+	fileSafeUnescapes = strings.NewReplacer("_5f", "_", "_2f", "/")
+)
+
+// FileSafe returns the name as a single path element, e.g. "@types/node" ->
+// "types__node". Also used wherever '@' and '/' aren't allowed, like test
+// package names. Underscores are escaped so names containing them stay
+// distinct, e.g. "string_decoder" -> "string_5fdecoder", and slashes of
+// image repositories become "_2f", e.g. "ghcr.io/owner/tool" ->
+// "ghcr.io_2fowner_2ftool".
+func FileSafe(name string) string {
+	if scope, bare, ok := split(name); ok {
+		return fileSafeEscapes.Replace(scope) + "__" + fileSafeEscapes.Replace(bare)
+	}
+	return fileSafeEscapes.Replace(name)
+}
+
+// FromFileSafe reverses FileSafe
+func FromFileSafe(safe string) string {
+	if scope, bare, ok := strings.Cut(safe, "__"); ok {
+		return "@" + fileSafeUnescapes.Replace(scope) + "/" + fileSafeUnescapes.Replace(bare)
+	}
+	return fileSafeUnescapes.Replace(safe)
+}
+
+// DirName returns the directory holding the results of a package version,
+// e.g. "types__node@20.0.0"
+func DirName(name, version string) string {
+	return FileSafe(name) + "@" + version
+}
+
+// Display returns the name and version as shown to users, e.g.
+// "@types/node@20.0.0", or just the name without a version
+func Display(name, version string) string {
+	if version == "" {
+		return name
+	}
+	return name + "@" + version
+}
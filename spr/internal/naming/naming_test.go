@@ -0,0 +1,39 @@
+package naming
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestForms(t *testing.T) {
+	tests := []struct {
+		name, url, file string
+	}{
+		{"lodash", "lodash", "lodash"},
+		{"@types/node", "@types%2fnode", "types__node"},
+		{"@sveltejs/kit", "@sveltejs%2fkit", "sveltejs__kit"},
+		{"string_decoder", "string_decoder", "string_5fdecoder"},
+		// Names that collided when '_' wasn't escaped
+		{"a__b", "a__b", "a_5f_5fb"},
+		{"@a/b__c", "@a%2fb__c", "a__b_5f_5fc"},
+		{"@a__b/c", "@a__b%2fc", "a_5f_5fb__c"},
+		{"@a_/b", "@a_%2fb", "a_5f__b"},
+		{"@a/_b", "@a%2f_b", "a___5fb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.url, URLPath(tt.name))
+			assert.Equal(t, tt.file, FileSafe(tt.name))
+			assert.Equal(t, tt.name, FromFileSafe(tt.file))
+		})
+	}
+}
+
+func TestDirNameAndDisplay(t *testing.T) {
+	assert.Equal(t, "types__node@20.0.0", DirName("@types/node", "20.0.0"))
+	assert.Equal(t, "@types/node@20.0.0", Display("@types/node", "20.0.0"))
+	assert.Equal(t, "@types/node", Display("@types/node", ""))
+	assert.Equal(t, "ghcr.io_2fowner_2ftool@1.0", DirName("ghcr.io/owner/tool", "1.0"))
+	assert.Equal(t, "ghcr.io/owner/tool", FromFileSafe(FileSafe("ghcr.io/owner/tool")))
+}
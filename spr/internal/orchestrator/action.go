@@ -0,0 +1,68 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ActionInput is the workflow input carrying the action reference in action
+// mode, see SetActionMode
+const ActionInput = "action"
+
+// DefaultActionWorkflowFile checks out an action and runs it under Tracee
+// with canary secrets
+const DefaultActionWorkflowFile = "analyze-action.yml"
+
+var (
+	actionOwner  = regexp.MustCompile(`^[A-Za-z0-9](?:[A-Za-z0-9-]{0,38})$`)
+	actionPath   = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+	actionGitRef = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,254}$`)
+)
+
+// ParseActionRef parses a GitHub Action reference as written in uses:,
+// e.g. "actions/setup-node@v4" or "owner/repo/path@<sha>", into the package
+// it is analyzed as: owner/repo[/path] as name and the ref as version. The
+// ref is required, and can't contain '/' since it names the results
+// directory; pin such branches by commit SHA.
+func ParseActionRef(ref string) (models.Package, error) {
+	name, version, ok := strings.Cut(strings.TrimSpace(ref), "@")
+	if !ok || version == "" {
+		return models.Package{}, fmt.Errorf("action %q has no ref, expected owner/action@ref", ref)
+	}
+	if !actionGitRef.MatchString(version) || strings.Contains(version, "..") {
+		return models.Package{}, fmt.Errorf("invalid action ref %q", version)
+	}
+	parts := strings.Split(name, "/")
+	if len(parts) < 2 || !actionOwner.MatchString(parts[0]) {
+		return models.Package{}, fmt.Errorf("invalid action %q, expected owner/action@ref", ref)
+	}
+	for _, part := range parts[1:] {
+		if !actionPath.MatchString(part) || part == "." || part == ".." {
+			return models.Package{}, fmt.Errorf("invalid action %q, expected owner/action@ref", ref)
+		}
+	}
+	return models.Package{ID: name + "@" + version, Name: name, Version: version}, nil
+}
+
+// ActionRef returns the action reference of a package parsed by
+// ParseActionRef
+func ActionRef(pkg models.Package) string {
+	return pkg.Name + "@" + pkg.Version
+}
+
+// SetActionMode analyzes GitHub Actions instead of npm packages: each
+// package is an action (see ParseActionRef) the workflow checks out and runs
+// in a sandbox with canary secrets (see analysis.CanaryPrefix), given as
+// ActionInput.
+// As in image mode, the npm test settings aren't sent and without a
+// baseline every traced activity is kept in the diff.
+func (o *Orchestrator) SetActionMode(enabled bool) {
+	o.actionMode = enabled
+	if enabled && o.baseline == nil {
+		o.baseline = &aggregate.PerProcessStats{Collection: "empty"}
+	}
+}
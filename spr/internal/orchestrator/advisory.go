@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/acheong08/hackeurope-spr/internal/advisory"
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// writeAdvisories drafts an advisory (advisory.md and advisory.osv.json) in
+// the output directory of every package flagged as malicious. Runs after
+// suggestRemediations, whose replacement it recommends, and before quarantine
+// so the drafts are archived with the rest of the evidence.
+func (o *Orchestrator) writeAdvisories(packages []models.Package, outputDir string) {
+	for _, pkg := range packages {
+		assessment, err := loadAssessment(outputDir, pkg)
+		if err != nil || assessment == nil || !assessment.IsMalicious {
+			continue
+		}
+
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+
+		var diff *aggregate.DedupedProcessStats
+		if data, err := os.ReadFile(filepath.Join(pkgDir, "diff.json")); err == nil {
+			diff = &aggregate.DedupedProcessStats{}
+			if err := json.Unmarshal(data, diff); err != nil {
+				o.logMsg(fmt.Sprintf("Failed to parse diff.json, advisory will lack IOCs: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "advisory")...)
+				diff = nil
+			}
+		}
+
+		draft := advisory.New(pkg.Name, pkg.Version, assessment, diff)
+		if data, err := os.ReadFile(filepath.Join(pkgDir, RemediationFile)); err == nil {
+			var rem Remediation
+			if json.Unmarshal(data, &rem) == nil {
+				draft.Replacement = rem.Replacement
+			}
+		}
+		if err := draft.Write(pkgDir); err != nil {
+			o.logMsg(fmt.Sprintf("Failed to write advisory for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "advisory")...)
+			continue
+		}
+		o.annotate(pkg, models.AnnotationVulns, []string{draft.OSV().ID})
+		o.logMsg(fmt.Sprintf("Drafted advisory for %s@%s in %s", pkg.Name, pkg.Version, pkgDir), "info", pkgAttrs(pkg.Name, pkg.Version, "advisory")...)
+	}
+}
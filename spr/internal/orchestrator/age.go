@@ -0,0 +1,245 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// AgeFile records the package age gate of a run in its output directory
+const AgeFile = "age.json"
+
+// Graph annotations of the package age gate
+const (
+	AnnotationPublishedAt = "published_at" // string, RFC 3339 upstream publish time
+	AnnotationAgeGate     = "age_gate"     // string, the AgeAction applied to a too-new version
+)
+
+// Actions the age gate takes on versions younger than the minimum age
+const (
+	// AgeBlock blocks promotion of the whole tree, like a malicious verdict
+	AgeBlock = "block"
+	// AgeQuarantine withholds only the too-new versions from the safe
+	// registry; a later run promotes them once they are old enough
+	AgeQuarantine = "quarantine"
+)
+
+// ageConcurrency bounds the package documents fetched in flight
+const ageConcurrency = 8
+
+// AgePolicy gates dependency versions published less than MinAge ago, since
+// most compromised npm releases are caught and unpublished within days
+type AgePolicy struct {
+	MinAge time.Duration
+	Action string // AgeBlock or AgeQuarantine
+}
+
+// ParseAgePolicy parses a minimum age, as a Go duration or a number of days
+// such as "3d", and the action to take on younger versions. An empty minAge
+// disables the gate and returns nil.
+func ParseAgePolicy(minAge, action string) (*AgePolicy, error) {
+	minAge = strings.TrimSpace(minAge)
+	if minAge == "" || minAge == "0" {
+		return nil, nil
+	}
+
+	var d time.Duration
+	if days, ok := strings.CutSuffix(minAge, "d"); ok {
+		n, err := strconv.ParseFloat(days, 64)
+		if err != nil {
+			return nil, fmt.Errorf("minimum package age %q: invalid number of days", minAge)
+		}
+		d = time.Duration(n * float64(24*time.Hour))
+	} else {
+		var err error
+		if d, err = time.ParseDuration(minAge); err != nil {
+			return nil, fmt.Errorf("minimum package age %q: want a duration like 72h or 3d", minAge)
+		}
+	}
+	if d <= 0 {
+		return nil, fmt.Errorf("minimum package age %q: must be positive", minAge)
+	}
+
+	switch action {
+	case "":
+		action = AgeBlock
+	case AgeBlock, AgeQuarantine:
+	default:
+		return nil, fmt.Errorf("package age action %q: want %s or %s", action, AgeBlock, AgeQuarantine)
+	}
+	return &AgePolicy{MinAge: d, Action: action}, nil
+}
+
+// AgeRecord is the age of one package version of the tree
+type AgeRecord struct {
+	Package     string    `json:"package"`
+	Version     string    `json:"version"`
+	PublishedAt time.Time `json:"published_at,omitzero"`
+	Age         string    `json:"age,omitempty"`
+	Action      string    `json:"action,omitempty"` // Set when the version is younger than the minimum age
+	Error       string    `json:"error,omitempty"`
+}
+
+// AgeReport is the content of AgeFile
+type AgeReport struct {
+	CheckedAt time.Time   `json:"checked_at"`
+	MinAge    string      `json:"min_age"`
+	Action    string      `json:"action"`
+	Packages  []AgeRecord `json:"packages"`
+}
+
+// SetAgePolicy gates the whole dependency tree on how long ago each version
+// was published upstream before promotion. Versions younger than the policy's
+// minimum age are logged, annotated on the graph and, depending on its
+// action, block promotion or are withheld from it. Nil disables the gate.
+func (o *Orchestrator) SetAgePolicy(policy *AgePolicy) {
+	o.agePolicy = policy
+}
+
+// TooNew returns the versions the last run found younger than the minimum
+// package age
+func (o *Orchestrator) TooNew() []AgeRecord {
+	return o.tooNew
+}
+
+// checkAge reads the publish time of every version of the graph from the
+// npm registry, gates the ones under the minimum age, and records the check
+// in AgeFile. Versions whose publish time can't be read are reported but not
+// gated.
+func (o *Orchestrator) checkAge(ctx context.Context, outputDir string) {
+	o.tooNew = nil
+	if o.agePolicy == nil || o.graph == nil {
+		return
+	}
+	if o.offline {
+		o.logMsg("Offline: skipping the package age gate", "warning", logging.KeyStage, "age")
+		return
+	}
+
+	versions := make(map[string][]*models.PackageNode)
+	for id, node := range o.graph.Nodes {
+		if o.graph.RootPackage != nil && id == o.graph.RootPackage.ID {
+			continue
+		}
+		if registry.IsNonNpmDep(node.ResolvedURL) {
+			continue // Git and URL dependencies have no registry publish time
+		}
+		versions[node.Name] = append(versions[node.Name], node)
+	}
+
+	// One package document per name
+	times := make(map[string]map[string]time.Time, len(versions))
+	errs := make(map[string]error)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, ageConcurrency)
+	for name := range versions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			published, err := registry.FetchPublishTimes(ctx, quarantineHTTPClient, npmRegistryURL, name)
+			mu.Lock()
+			defer mu.Unlock()
+			times[name], errs[name] = published, err
+		}()
+	}
+	wg.Wait()
+
+	now := time.Now().UTC()
+	report := AgeReport{CheckedAt: now, MinAge: o.agePolicy.MinAge.String(), Action: o.agePolicy.Action}
+	for name, nodes := range versions {
+		for _, node := range nodes {
+			record := AgeRecord{Package: name, Version: node.Version}
+			attrs := []any{logging.KeyPackageID, node.ID, logging.KeyStage, "age"}
+			published, ok := times[name][node.Version]
+			switch {
+			case errs[name] != nil:
+				record.Error = errs[name].Error()
+			case !ok:
+				record.Error = "version has no publish time"
+			}
+			if record.Error != "" {
+				o.logMsg(fmt.Sprintf("%s: publish time unknown, not age gated: %s", node.ID, record.Error), "warning", attrs...)
+				report.Packages = append(report.Packages, record)
+				continue
+			}
+
+			age := now.Sub(published)
+			record.PublishedAt = published
+			record.Age = age.Round(time.Minute).String()
+			o.graph.Annotate(node.ID, AnnotationPublishedAt, published.Format(time.RFC3339))
+			if age < o.agePolicy.MinAge {
+				record.Action = o.agePolicy.Action
+				o.tooNew = append(o.tooNew, record)
+				o.graph.Annotate(node.ID, AnnotationAgeGate, record.Action)
+				o.logMsg(fmt.Sprintf("%s published %s ago, under the minimum age of %s (%s)", node.ID, record.Age, o.agePolicy.MinAge, record.Action), "warning", attrs...)
+			}
+			report.Packages = append(report.Packages, record)
+		}
+	}
+	sort.Slice(report.Packages, func(i, j int) bool {
+		a, b := report.Packages[i], report.Packages[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Version < b.Version
+	})
+	sort.Slice(o.tooNew, func(i, j int) bool {
+		return o.tooNew[i].Package+"@"+o.tooNew[i].Version < o.tooNew[j].Package+"@"+o.tooNew[j].Version
+	})
+	o.logMsg(fmt.Sprintf("%d package versions age checked, %d under the minimum age of %s", len(report.Packages), len(o.tooNew), o.agePolicy.MinAge), "info", logging.KeyStage, "age")
+
+	if err := writeAgeReport(outputDir, &report); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write %s: %v", AgeFile, err), "warning", logging.KeyStage, "age")
+	}
+}
+
+// promotableGraph returns the graph to promote: the whole graph, less the
+// versions the age gate quarantined
+func (o *Orchestrator) promotableGraph() *models.DependencyGraph {
+	held := make(map[string]bool)
+	for _, record := range o.tooNew {
+		if record.Action == AgeQuarantine {
+			held[record.Package+"@"+record.Version] = true
+		}
+	}
+	if len(held) == 0 {
+		return o.graph
+	}
+
+	graph := &models.DependencyGraph{RootPackage: o.graph.RootPackage, Nodes: make(map[string]*models.PackageNode, len(o.graph.Nodes))}
+	for id, node := range o.graph.Nodes {
+		if !held[id] {
+			graph.Nodes[id] = node
+		}
+	}
+	return graph
+}
+
+// writeAgeReport writes AgeFile to outputDir
+func writeAgeReport(outputDir string, report *AgeReport) error {
+	if outputDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, AgeFile), data, 0o644)
+}
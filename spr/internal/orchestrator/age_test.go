@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAgePolicy(t *testing.T) {
+	policy, err := ParseAgePolicy("3d", "")
+	require.NoError(t, err)
+	assert.Equal(t, &AgePolicy{MinAge: 72 * time.Hour, Action: AgeBlock}, policy)
+
+	policy, err = ParseAgePolicy("36h", AgeQuarantine)
+	require.NoError(t, err)
+	assert.Equal(t, &AgePolicy{MinAge: 36 * time.Hour, Action: AgeQuarantine}, policy)
+
+	policy, err = ParseAgePolicy("", AgeBlock)
+	require.NoError(t, err)
+	assert.Nil(t, policy)
+
+	for _, bad := range [][2]string{{"soon", ""}, {"xd", ""}, {"-1h", ""}, {"1d", "warn"}} {
+		_, err := ParseAgePolicy(bad[0], bad[1])
+		assert.Error(t, err, bad)
+	}
+}
+
+func TestCheckAge(t *testing.T) {
+	now := time.Now().UTC()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/left-pad":
+			json.NewEncoder(w).Encode(map[string]any{"time": map[string]string{
+				"created":  now.AddDate(-5, 0, 0).Format(time.RFC3339),
+				"modified": now.Format(time.RFC3339),
+				"1.3.0":    now.AddDate(-5, 0, 0).Format(time.RFC3339),
+			}})
+		case "/@acme%2flib":
+			json.NewEncoder(w).Encode(map[string]any{"time": map[string]string{
+				"2.0.0": now.Add(-2 * time.Hour).Format(time.RFC3339),
+			}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	orig := npmRegistryURL
+	npmRegistryURL = srv.URL
+	defer func() { npmRegistryURL = orig }()
+
+	graph := models.NewDependencyGraph()
+	for _, pkg := range []models.Package{
+		{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"},
+		{ID: "@acme/lib@2.0.0", Name: "@acme/lib", Version: "2.0.0"},
+		{ID: "unpublished@1.0.0", Name: "unpublished", Version: "1.0.0"},
+	} {
+		graph.AddNode(&models.PackageNode{Package: pkg})
+	}
+
+	safe := registry.NewUploader("http://safe.invalid", "secure", "token")
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", safe, graph)
+	o.SetAgePolicy(&AgePolicy{MinAge: 72 * time.Hour, Action: AgeQuarantine})
+
+	outputDir := t.TempDir()
+	o.checkAge(t.Context(), outputDir)
+	require.Len(t, o.TooNew(), 1)
+	assert.Equal(t, "@acme/lib", o.TooNew()[0].Package)
+	assert.Equal(t, AgeQuarantine, graph.Nodes["@acme/lib@2.0.0"].StringAnnotation(AnnotationAgeGate))
+	assert.Empty(t, graph.Nodes["left-pad@1.3.0"].StringAnnotation(AnnotationAgeGate))
+	assert.NotEmpty(t, graph.Nodes["left-pad@1.3.0"].StringAnnotation(AnnotationPublishedAt))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, AgeFile))
+	require.NoError(t, err)
+	var report AgeReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	require.Len(t, report.Packages, 3)
+	assert.Equal(t, "unpublished", report.Packages[2].Package)
+	assert.NotEmpty(t, report.Packages[2].Error)
+
+	// Quarantined versions are left out of the promoted graph only
+	promoted := o.promotableGraph()
+	assert.Len(t, promoted.Nodes, 2)
+	assert.NotContains(t, promoted.Nodes, "@acme/lib@2.0.0")
+	assert.Len(t, graph.Nodes, 3)
+
+	// Blocking holds back the whole tree before anything is uploaded to the
+	// (unreachable) safe registry
+	o.SetAgePolicy(&AgePolicy{MinAge: 72 * time.Hour, Action: AgeBlock})
+	o.checkAge(t.Context(), outputDir)
+	assert.Same(t, graph, o.promotableGraph())
+	assert.NoError(t, o.promoteToSafeRegistry(t.Context(), nil, outputDir))
+}
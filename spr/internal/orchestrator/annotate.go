@@ -0,0 +1,47 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// annotate attaches a finding to the graph node of pkg, if the orchestrator
+// has a graph and the package is in it
+func (o *Orchestrator) annotate(pkg models.Package, key string, value any) {
+	if o.graph == nil {
+		return
+	}
+	o.graph.Annotate(pkg.Name+"@"+pkg.Version, key, value)
+}
+
+// annotateVerdicts records the verdict of every analyzed package, and the AI
+// confidence behind it, on the dependency graph
+func (o *Orchestrator) annotateVerdicts(packages []models.Package, outputDir string) {
+	if o.graph == nil {
+		return
+	}
+
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for graph annotations: %v", err), "warning", logging.KeyStage, "annotate")
+		return
+	}
+	for _, pkg := range packages {
+		assessment, analyzed := verdicts[pkg.Name+"@"+pkg.Version]
+		switch {
+		case !analyzed:
+			o.annotate(pkg, models.AnnotationVerdict, models.VerdictNotAnalyzed)
+			continue
+		case assessment == nil:
+			o.annotate(pkg, models.AnnotationVerdict, models.VerdictClean)
+			continue
+		case assessment.IsMalicious:
+			o.annotate(pkg, models.AnnotationVerdict, models.VerdictMalicious)
+		default:
+			o.annotate(pkg, models.AnnotationVerdict, models.VerdictSafe)
+		}
+		o.annotate(pkg, models.AnnotationScore, assessment.Confidence)
+	}
+}
@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnnotateVerdicts(t *testing.T) {
+	evil := models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"}
+	clean := models.Package{ID: "@scope/clean@2.0.0", Name: "@scope/clean", Version: "2.0.0"}
+	skipped := models.Package{ID: "skipped@1.0.0", Name: "skipped", Version: "1.0.0"}
+
+	graph := models.NewDependencyGraph()
+	for _, pkg := range []models.Package{evil, clean, skipped} {
+		graph.AddNode(&models.PackageNode{Package: pkg})
+	}
+
+	outputDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(outputDir, "evil@1.0.0"), 0o755))
+	require.NoError(t, os.MkdirAll(filepath.Join(outputDir, "scope__clean@2.0.0"), 0o755))
+	data, err := json.Marshal(map[string]any{"is_malicious": true, "confidence": 0.9, "justification": "exfiltrates env"})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "evil@1.0.0", "ai-analysis.json"), data, 0o644))
+
+	o := &Orchestrator{graph: graph}
+	o.annotateVerdicts([]models.Package{evil, clean, skipped}, outputDir)
+
+	node := graph.Nodes[evil.ID]
+	assert.Equal(t, models.VerdictMalicious, node.StringAnnotation(models.AnnotationVerdict))
+	score, ok := node.FloatAnnotation(models.AnnotationScore)
+	assert.True(t, ok)
+	assert.Equal(t, 0.9, score)
+
+	assert.Equal(t, models.VerdictClean, graph.Nodes[clean.ID].StringAnnotation(models.AnnotationVerdict))
+	_, ok = graph.Nodes[clean.ID].Annotation(models.AnnotationScore)
+	assert.False(t, ok)
+	assert.Equal(t, models.VerdictNotAnalyzed, graph.Nodes[skipped.ID].StringAnnotation(models.AnnotationVerdict))
+
+	// Annotations survive a JSON round trip, as sent to clients
+	data, err = json.Marshal(node)
+	require.NoError(t, err)
+	var decoded models.PackageNode
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, models.VerdictMalicious, decoded.StringAnnotation(models.AnnotationVerdict))
+
+	decoded.Annotate(models.AnnotationVulns, []string{"SPR-DRAFT-evil-1.0.0"})
+	data, err = json.Marshal(&decoded)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, []string{"SPR-DRAFT-evil-1.0.0"}, decoded.StringsAnnotation(models.AnnotationVulns))
+}
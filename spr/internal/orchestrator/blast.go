@@ -0,0 +1,145 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// BlastRadiusFile is written next to the analysis of every flagged package
+const BlastRadiusFile = "blast-radius.json"
+
+// AnnotationBlastRadius is the graph annotation holding a flagged package's
+// *BlastRadius
+const AnnotationBlastRadius = "blast_radius"
+
+// Remediation priorities, most urgent first
+const (
+	PriorityCritical = "critical" // Ships to production and runs code on install
+	PriorityHigh     = "high"     // Ships to production
+	PriorityMedium   = "medium"   // Dev-only, but runs code on every install
+	PriorityLow      = "low"      // Dev-only, runs only when imported
+)
+
+var priorityRank = map[string]int{PriorityCritical: 0, PriorityHigh: 1, PriorityMedium: 2, PriorityLow: 3}
+
+// BlastRadius describes how far a flagged package reaches into the project
+type BlastRadius struct {
+	Package          string   `json:"package"`
+	Priority         string   `json:"priority"`
+	Dependents       int      `json:"dependents"` // Packages depending on it, transitively
+	DirectDependents []string `json:"direct_dependents,omitempty"`
+	Direct           bool     `json:"direct"`                     // A direct dependency of the project
+	Production       bool     `json:"production"`                 // Reachable from dependencies, not only devDependencies
+	InstallScript    bool     `json:"install_script"`             // Runs install scripts by default
+	WeeklyDownloads  *int64   `json:"weekly_downloads,omitempty"` // Nil when not looked up
+	Path             []string `json:"path,omitempty"`             // Shortest chain from the project to the package
+}
+
+// ComputeBlastRadius measures the reach of pkg in graph. It returns nil when
+// the package is not in the graph.
+func ComputeBlastRadius(graph *models.DependencyGraph, pkg models.Package) *BlastRadius {
+	id := pkg.Name + "@" + pkg.Version
+	node, ok := graph.Nodes[id]
+	if !ok {
+		return nil
+	}
+
+	direct, all := graph.Dependents(id)
+	br := &BlastRadius{
+		Package:          id,
+		Dependents:       len(all),
+		DirectDependents: direct,
+		Production:       !node.Dev,
+		InstallScript:    node.HasInstallScript,
+		Path:             graph.PathFromRoot(id),
+	}
+	if downloads, ok := node.FloatAnnotation(models.AnnotationDownloads); ok {
+		n := int64(downloads)
+		br.WeeklyDownloads = &n
+	}
+	for _, dep := range graph.GetDirectDependencies() {
+		if dep.ID == id {
+			br.Direct = true
+			break
+		}
+	}
+
+	switch {
+	case br.Production && br.InstallScript:
+		br.Priority = PriorityCritical
+	case br.Production:
+		br.Priority = PriorityHigh
+	case br.InstallScript:
+		br.Priority = PriorityMedium
+	default:
+		br.Priority = PriorityLow
+	}
+	return br
+}
+
+// reportBlastRadius computes the blast radius of every package flagged as
+// malicious, writes it to the package's output directory, attaches it to the
+// graph and logs the packages in remediation order. Failures are logged, not
+// returned.
+func (o *Orchestrator) reportBlastRadius(packages []models.Package, outputDir string) {
+	if o.graph == nil {
+		return
+	}
+
+	var radii []*BlastRadius
+	for _, pkg := range packages {
+		assessment, err := loadAssessment(outputDir, pkg)
+		if err != nil || assessment == nil || !assessment.IsMalicious {
+			continue
+		}
+		br := ComputeBlastRadius(o.graph, pkg)
+		if br == nil {
+			continue
+		}
+		radii = append(radii, br)
+		o.annotate(pkg, AnnotationBlastRadius, br)
+
+		data, err := json.MarshalIndent(br, "", "  ")
+		if err != nil {
+			continue
+		}
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+		if err := os.WriteFile(filepath.Join(pkgDir, BlastRadiusFile), data, 0o644); err != nil {
+			o.logMsg(fmt.Sprintf("Failed to write blast radius for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "blast-radius")...)
+		}
+	}
+	if len(radii) == 0 {
+		return
+	}
+
+	sort.SliceStable(radii, func(i, j int) bool {
+		if radii[i].Priority != radii[j].Priority {
+			return priorityRank[radii[i].Priority] < priorityRank[radii[j].Priority]
+		}
+		return radii[i].Dependents > radii[j].Dependents
+	})
+
+	o.logMsg(fmt.Sprintf("Blast radius of %d flagged packages, in remediation order:", len(radii)), "warning", logging.KeyStage, "blast-radius")
+	for _, br := range radii {
+		scope := "dev-only"
+		if br.Production {
+			scope = "production"
+		}
+		scripts := ""
+		if br.InstallScript {
+			scripts = ", runs install scripts"
+		}
+		if br.WeeklyDownloads != nil {
+			scripts += fmt.Sprintf(", %d weekly downloads", *br.WeeklyDownloads)
+		}
+		o.logMsg(fmt.Sprintf("  [%s] %s: %s, %d dependents%s, via %s", br.Priority, br.Package, scope, br.Dependents, scripts, strings.Join(br.Path, " > ")), "warning", logging.KeyPackageID, br.Package, logging.KeyStage, "blast-radius")
+	}
+}
@@ -0,0 +1,96 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blastGraph builds app -> {web, jest}, web -> {evil, util}, jest -> {util},
+// util -> {evil}, with jest dev-only and evil running install scripts
+func blastGraph() *models.DependencyGraph {
+	graph := models.NewDependencyGraph()
+	root := &models.PackageNode{
+		Package:      models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"},
+		Dependencies: map[string]string{"web": "^1.0.0", "jest": "^29.0.0"},
+	}
+	graph.AddNode(root)
+	graph.RootPackage = &root.Package
+	graph.AddNode(&models.PackageNode{
+		Package:      models.Package{ID: "web@1.0.0", Name: "web", Version: "1.0.0"},
+		Dependencies: map[string]string{"evil": "^1.0.0", "util": "^1.0.0"},
+	})
+	graph.AddNode(&models.PackageNode{
+		Package:      models.Package{ID: "jest@29.0.0", Name: "jest", Version: "29.0.0"},
+		Dependencies: map[string]string{"util": "^1.0.0"},
+		Dev:          true,
+	})
+	graph.AddNode(&models.PackageNode{
+		Package:      models.Package{ID: "util@1.0.0", Name: "util", Version: "1.0.0"},
+		Dependencies: map[string]string{"evil": "^1.0.0"},
+	})
+	graph.AddNode(&models.PackageNode{
+		Package:          models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"},
+		HasInstallScript: true,
+	})
+	return graph
+}
+
+func TestComputeBlastRadius(t *testing.T) {
+	graph := blastGraph()
+
+	br := ComputeBlastRadius(graph, models.Package{Name: "evil", Version: "1.0.0"})
+	require.NotNil(t, br)
+	assert.Equal(t, PriorityCritical, br.Priority)
+	assert.Equal(t, []string{"util@1.0.0", "web@1.0.0"}, br.DirectDependents)
+	assert.Equal(t, 3, br.Dependents)
+	assert.False(t, br.Direct)
+	assert.True(t, br.Production)
+	assert.True(t, br.InstallScript)
+	assert.Equal(t, []string{"app@1.0.0", "web@1.0.0", "evil@1.0.0"}, br.Path)
+
+	br = ComputeBlastRadius(graph, models.Package{Name: "jest", Version: "29.0.0"})
+	require.NotNil(t, br)
+	assert.Equal(t, PriorityLow, br.Priority)
+	assert.Zero(t, br.Dependents)
+	assert.True(t, br.Direct)
+
+	assert.Nil(t, ComputeBlastRadius(graph, models.Package{Name: "missing", Version: "1.0.0"}))
+}
+
+func TestReportBlastRadius(t *testing.T) {
+	graph := blastGraph()
+	evil := models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"}
+	util := models.Package{ID: "util@1.0.0", Name: "util", Version: "1.0.0"}
+
+	outputDir := t.TempDir()
+	for _, dir := range []string{"evil@1.0.0", "util@1.0.0"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(outputDir, dir), 0o755))
+	}
+	data, err := json.Marshal(map[string]any{"is_malicious": true, "confidence": 0.9})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "evil@1.0.0", "ai-analysis.json"), data, 0o644))
+
+	o := &Orchestrator{graph: graph}
+	o.reportBlastRadius([]models.Package{evil, util}, outputDir)
+
+	data, err = os.ReadFile(filepath.Join(outputDir, "evil@1.0.0", BlastRadiusFile))
+	require.NoError(t, err)
+	var br BlastRadius
+	require.NoError(t, json.Unmarshal(data, &br))
+	assert.Equal(t, PriorityCritical, br.Priority)
+	assert.Equal(t, 3, br.Dependents)
+
+	_, ok := graph.Nodes[evil.ID].Annotation(AnnotationBlastRadius)
+	assert.True(t, ok)
+
+	// Packages that weren't flagged get no report
+	assert.NoFileExists(t, filepath.Join(outputDir, "util@1.0.0", BlastRadiusFile))
+	_, ok = graph.Nodes[util.ID].Annotation(AnnotationBlastRadius)
+	assert.False(t, ok)
+}
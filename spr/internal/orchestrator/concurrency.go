@@ -0,0 +1,76 @@
+package orchestrator
+
+import "fmt"
+
+// StageConcurrency limits how much work each pipeline stage does at once.
+// The stages have different bottlenecks: uploads and workflows wait on the
+// network and GitHub runners, aggregation is CPU and disk bound, and AI
+// analysis is bound by the provider's rate limits.
+type StageConcurrency struct {
+	// Upload is the number of concurrent registry uploads. The orchestrator
+	// doesn't upload; callers apply it to their registry.Uploader.
+	Upload int
+	// Workflow is the number of packages with a workflow run in flight
+	Workflow int
+	// Aggregate is the number of artifact copies and baseline diffs running
+	// at once. Workers wait for a free slot before moving on to their next
+	// package, so a backlog here holds back new workflow runs.
+	Aggregate int
+	// AI is the number of concurrent LLM calls
+	AI int
+}
+
+// DefaultStageConcurrency is used for limits left at zero
+var DefaultStageConcurrency = StageConcurrency{
+	Upload:    10,
+	Workflow:  5,
+	Aggregate: 4,
+	AI:        5,
+}
+
+// WithDefaults returns c with zero limits replaced by the defaults
+func (c StageConcurrency) WithDefaults() StageConcurrency {
+	if c.Upload == 0 {
+		c.Upload = DefaultStageConcurrency.Upload
+	}
+	if c.Workflow == 0 {
+		c.Workflow = DefaultStageConcurrency.Workflow
+	}
+	if c.Aggregate == 0 {
+		c.Aggregate = DefaultStageConcurrency.Aggregate
+	}
+	if c.AI == 0 {
+		c.AI = DefaultStageConcurrency.AI
+	}
+	return c
+}
+
+// Validate rejects negative limits
+func (c StageConcurrency) Validate() error {
+	for _, limit := range []struct {
+		stage string
+		n     int
+	}{{"upload", c.Upload}, {"workflow", c.Workflow}, {"aggregate", c.Aggregate}, {"ai", c.AI}} {
+		if limit.n < 0 {
+			return fmt.Errorf("%s concurrency must not be negative, got %d", limit.stage, limit.n)
+		}
+	}
+	return nil
+}
+
+// SetStageConcurrency sets the per-stage concurrency limits; zero limits use
+// the defaults. The workflow limit replaces the concurrency passed to
+// NewOrchestrator.
+func (o *Orchestrator) SetStageConcurrency(c StageConcurrency) {
+	if c.Workflow == 0 {
+		c.Workflow = o.concurrency
+	}
+	o.stages = c.WithDefaults()
+	o.concurrency = o.stages.Workflow
+}
+
+// acquireAggregate waits for a free aggregation slot and returns its release
+func (o *Orchestrator) acquireAggregate() func() {
+	o.aggregateSem <- struct{}{}
+	return func() { <-o.aggregateSem }
+}
@@ -0,0 +1,47 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStageConcurrency(t *testing.T) {
+	assert.Equal(t, StageConcurrency{Upload: 2, Workflow: 5, Aggregate: 4, AI: 1}, StageConcurrency{Upload: 2, AI: 1}.WithDefaults())
+	assert.NoError(t, StageConcurrency{}.Validate())
+	assert.ErrorContains(t, StageConcurrency{Aggregate: -1}.Validate(), "aggregate")
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 3, time.Minute, nil, "", "", nil, nil)
+	o.SetStageConcurrency(StageConcurrency{Aggregate: 1})
+	// The constructor's workflow concurrency is kept unless overridden
+	assert.Equal(t, 3, o.concurrency)
+	assert.Equal(t, StageConcurrency{Upload: 10, Workflow: 3, Aggregate: 1, AI: 5}, o.stages)
+
+	o.SetStageConcurrency(StageConcurrency{Workflow: 8})
+	assert.Equal(t, 8, o.concurrency)
+}
+
+func TestAcquireAggregateBackpressure(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 2, time.Minute, nil, "", "", nil, nil)
+	o.aggregateSem = make(chan struct{}, 1)
+
+	release := o.acquireAggregate()
+	acquired := make(chan struct{})
+	go func() {
+		o.acquireAggregate()()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("second aggregation started while the only slot was taken")
+	case <-time.After(50 * time.Millisecond):
+	}
+	release()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("slot was not handed over after release")
+	}
+}
@@ -0,0 +1,115 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/confusion"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ConfusionFile records the dependency confusion check of a run in its output
+// directory
+const ConfusionFile = "confusion.json"
+
+// AnnotationConfusion is the graph annotation holding the kind of a likely
+// dependency confusion finding (confusion.Kind*)
+const AnnotationConfusion = "confusion"
+
+// ConfusionReport is the content of ConfusionFile: every private-looking
+// package checked, findings or not
+type ConfusionReport struct {
+	CheckedAt  time.Time          `json:"checked_at"`
+	Scopes     []string           `json:"scopes,omitempty"`
+	Registries []string           `json:"registries,omitempty"`
+	Packages   []confusion.Result `json:"packages"`
+}
+
+// SetConfusionCheck checks the whole dependency tree for dependency confusion
+// targets before analysis. Findings are logged, annotated on the graph, and
+// block promotion to the safe registry. Nil disables the check.
+func (o *Orchestrator) SetConfusionCheck(checker *confusion.Checker) {
+	o.confusionChecker = checker
+}
+
+// ConfusionFindings returns the likely dependency confusion targets found by
+// the last run
+func (o *Orchestrator) ConfusionFindings() []confusion.Result {
+	return o.confusionFindings
+}
+
+// checkConfusion runs the dependency confusion check over the graph and
+// records it in ConfusionFile
+func (o *Orchestrator) checkConfusion(ctx context.Context, outputDir string) {
+	o.confusionFindings = nil
+	if !o.confusionChecker.Enabled() || o.graph == nil {
+		return
+	}
+	if o.offline {
+		o.logMsg("Offline: skipping dependency confusion checks", "warning", logging.KeyStage, "confusion")
+		return
+	}
+
+	nodes := make([]*models.PackageNode, 0, len(o.graph.Nodes))
+	for id, node := range o.graph.Nodes {
+		if o.graph.RootPackage == nil || id != o.graph.RootPackage.ID {
+			nodes = append(nodes, node)
+		}
+	}
+	report := ConfusionReport{
+		CheckedAt:  time.Now().UTC(),
+		Scopes:     o.confusionChecker.Scopes,
+		Registries: o.confusionChecker.Registries,
+		Packages:   o.confusionChecker.Check(ctx, nodes),
+	}
+	for _, result := range report.Packages {
+		attrs := []any{logging.KeyPackageID, result.Package, logging.KeyStage, "confusion"}
+		if result.Kind == "" {
+			if result.Error != "" {
+				o.logMsg(fmt.Sprintf("%s: dependency confusion check incomplete: %s", result.Package, result.Error), "warning", attrs...)
+			}
+			continue
+		}
+		o.confusionFindings = append(o.confusionFindings, result)
+		o.graph.Annotate(result.Package, AnnotationConfusion, result.Kind)
+		o.logMsg(fmt.Sprintf("Likely dependency confusion: %s (%s): %s", result.Package, result.Reason, describeConfusion(result)), "error", attrs...)
+	}
+	o.logMsg(fmt.Sprintf("%d private-looking packages checked for dependency confusion, %d findings", len(report.Packages), len(o.confusionFindings)), "info", logging.KeyStage, "confusion")
+
+	if err := writeConfusionReport(outputDir, &report); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write %s: %v", ConfusionFile, err), "warning", logging.KeyStage, "confusion")
+	}
+}
+
+// describeConfusion explains a finding
+func describeConfusion(r confusion.Result) string {
+	switch r.Kind {
+	case confusion.KindResolvedPublicly:
+		return "installed from the public registry (" + r.Resolved + ")"
+	case confusion.KindUnknownRegistry:
+		return "resolved from a registry not configured as private (" + r.Resolved + ")"
+	case confusion.KindPublicConflict:
+		return fmt.Sprintf("also published publicly by %v (latest %s), privately by %v", r.PublicPublishers, r.PublicLatest, r.PrivatePublishers)
+	}
+	return r.Kind
+}
+
+// writeConfusionReport writes ConfusionFile to outputDir
+func writeConfusionReport(outputDir string, report *ConfusionReport) error {
+	if outputDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, ConfusionFile), data, 0o644)
+}
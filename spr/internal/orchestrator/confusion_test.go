@@ -0,0 +1,61 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/confusion"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckConfusion(t *testing.T) {
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() == "/@acme%2flib" {
+			w.Write([]byte(`{"maintainers":[{"name":"attacker"}]}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer public.Close()
+
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{
+		Package:     models.Package{ID: "@acme/lib@99.0.0", Name: "@acme/lib", Version: "99.0.0"},
+		ResolvedURL: public.URL + "/@acme/lib/-/lib-99.0.0.tgz",
+	})
+	graph.AddNode(&models.PackageNode{
+		Package:     models.Package{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"},
+		ResolvedURL: public.URL + "/left-pad/-/left-pad-1.3.0.tgz",
+	})
+
+	safe := registry.NewUploader("http://safe.invalid", "secure", "token")
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", safe, graph)
+	o.SetConfusionCheck(confusion.NewChecker(public.URL, []string{"@acme"}, nil, ""))
+
+	outputDir := t.TempDir()
+	o.checkConfusion(t.Context(), outputDir)
+	require.Len(t, o.ConfusionFindings(), 1)
+	assert.Equal(t, confusion.KindResolvedPublicly, o.ConfusionFindings()[0].Kind)
+	assert.Equal(t, confusion.KindResolvedPublicly, graph.Nodes["@acme/lib@99.0.0"].StringAnnotation(AnnotationConfusion))
+	assert.Empty(t, graph.Nodes["left-pad@1.3.0"].StringAnnotation(AnnotationConfusion))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, ConfusionFile))
+	require.NoError(t, err)
+	var report ConfusionReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, []string{"@acme"}, report.Scopes)
+	require.Len(t, report.Packages, 1)
+	assert.Equal(t, []string{"attacker"}, report.Packages[0].PublicPublishers)
+
+	// Findings block promotion before anything is uploaded to the
+	// (unreachable) safe registry
+	assert.NoError(t, o.promoteToSafeRegistry(t.Context(), nil, outputDir))
+}
@@ -0,0 +1,202 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ErrInsufficientDisk is returned by RunPackages when the preflight check
+// estimates that the run won't fit on disk
+var ErrInsufficientDisk = errors.New("insufficient disk space")
+
+// errDiskCheckUnsupported is returned by freeBytes where free space can't be
+// queried
+var errDiskCheckUnsupported = errors.New("free space check not supported on this platform")
+
+// defaultPackageFootprint is the estimated disk use of one package's
+// artifacts until previous results give a better estimate
+const defaultPackageFootprint = 32 << 20
+
+// traceFiles are the raw traces that are only needed to compute diff.json
+var traceFiles = []string{"behavior.jsonl", "proxy.jsonl"}
+
+// SetSkipDiskCheck disables the free-space preflight check of RunPackages
+func (o *Orchestrator) SetSkipDiskCheck(skip bool) {
+	o.skipDiskCheck = skip
+}
+
+// SetKeepTraces keeps behavior.jsonl and proxy.jsonl after diffing. By default
+// they are deleted from the output directory and the cache once diff.json
+// exists, after artifacts are exported; cached results are then reused from
+// diff.json alone, but can't be re-diffed against a new baseline.
+func (o *Orchestrator) SetKeepTraces(keep bool) {
+	o.keepTraces = keep
+}
+
+// preflightDiskSpace estimates the disk space the run needs and fails if the
+// temp or output directory has less free space. Downloads wait in tempDir
+// only until they are copied, so it needs room for the packages in flight;
+// outputDir needs room for every package that isn't cached.
+func (o *Orchestrator) preflightDiskSpace(packages []models.Package, tempDir, outputDir string) error {
+	// Offline runs download nothing
+	if o.skipDiskCheck || o.offline {
+		return nil
+	}
+
+	uncached := 0
+	for _, pkg := range packages {
+		if !hasCachedResult(pkg) {
+			uncached++
+		}
+	}
+	if uncached == 0 {
+		return nil
+	}
+
+	footprint := estimatePackageFootprint(outputDir, "analysis-results")
+	inFlight := min(uncached, o.stages.Workflow+o.stages.Aggregate)
+	for _, need := range []struct {
+		dir   string
+		bytes uint64
+	}{
+		{tempDir, uint64(inFlight) * footprint},
+		{outputDir, uint64(uncached) * footprint},
+	} {
+		if need.dir == "" {
+			continue
+		}
+		free, err := freeBytes(need.dir)
+		if err != nil {
+			o.logMsg(fmt.Sprintf("Skipping disk space check of %s: %v", need.dir, err), "warning", logging.KeyStage, "preflight")
+			continue
+		}
+		if free < need.bytes {
+			return fmt.Errorf("%w: %s has %s free, %d uncached packages need an estimated %s", ErrInsufficientDisk, need.dir, formatBytes(free), uncached, formatBytes(need.bytes))
+		}
+	}
+	o.logMsg(fmt.Sprintf("Disk space check passed: %d uncached packages, estimated %s each", uncached, formatBytes(footprint)), "info", logging.KeyStage, "preflight")
+	return nil
+}
+
+// hasCachedResult reports whether a package can be served from the
+// analysis-results cache, from its trace or, once pruned, its diff
+func hasCachedResult(pkg models.Package) bool {
+	dir := filepath.Join("analysis-results", naming.DirName(pkg.Name, pkg.Version))
+	for _, name := range []string{"behavior.jsonl", "diff.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// estimatePackageFootprint averages the size of the package directories with
+// a trace in dirs, falling back to defaultPackageFootprint
+func estimatePackageFootprint(dirs ...string) uint64 {
+	var total, count uint64
+	seen := make(map[string]bool)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			pkgDir := filepath.Join(dir, entry.Name())
+			abs, _ := filepath.Abs(pkgDir)
+			if !entry.IsDir() || seen[abs] {
+				continue
+			}
+			// Pruned directories no longer show what a download takes
+			if _, err := os.Stat(filepath.Join(pkgDir, "behavior.jsonl")); err != nil {
+				continue
+			}
+			seen[abs] = true
+			total += dirSize(pkgDir)
+			count++
+		}
+	}
+	if count == 0 {
+		return defaultPackageFootprint
+	}
+	return total / count
+}
+
+func dirSize(dir string) uint64 {
+	var size uint64
+	_ = filepath.WalkDir(dir, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += uint64(info.Size())
+		}
+		return nil
+	})
+	return size
+}
+
+// pruneTraces deletes the raw traces of packages whose diff.json exists, in
+// outputDir and the analysis-results cache, including those of environment
+// variants. Failures are logged, not returned.
+func (o *Orchestrator) pruneTraces(packages []models.Package, outputDir string) {
+	if o.keepTraces {
+		return
+	}
+
+	var freed uint64
+	for _, pkg := range packages {
+		pkgKey := naming.DirName(pkg.Name, pkg.Version)
+		for _, root := range []string{outputDir, "analysis-results"} {
+			pkgDir := filepath.Join(root, pkgKey)
+			if _, err := os.Stat(filepath.Join(pkgDir, "diff.json")); err != nil {
+				continue
+			}
+			dirs := []string{pkgDir}
+			if entries, err := os.ReadDir(filepath.Join(pkgDir, "variants")); err == nil {
+				for _, entry := range entries {
+					if entry.IsDir() {
+						dirs = append(dirs, filepath.Join(pkgDir, "variants", entry.Name()))
+					}
+				}
+			}
+			for _, dir := range dirs {
+				for _, name := range traceFiles {
+					path := filepath.Join(dir, name)
+					info, err := os.Stat(path)
+					if err != nil {
+						continue
+					}
+					if err := os.Remove(path); err != nil {
+						o.logMsg(fmt.Sprintf("Failed to delete %s: %v", path, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cleanup")...)
+						continue
+					}
+					freed += uint64(info.Size())
+				}
+			}
+		}
+	}
+	if freed > 0 {
+		o.logMsg(fmt.Sprintf("Deleted diffed traces, freeing %s", formatBytes(freed)), "info", logging.KeyStage, "cleanup")
+	}
+}
+
+// formatBytes renders a byte count with a binary unit
+func formatBytes(n uint64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := uint64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,7 @@
+//go:build !unix
+
+package orchestrator
+
+func freeBytes(string) (uint64, error) {
+	return 0, errDiskCheckUnsupported
+}
@@ -0,0 +1,122 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestPruneTraces(t *testing.T) {
+	t.Chdir(t.TempDir())
+	outputDir := t.TempDir()
+	diffed := models.Package{Name: "@scope/diffed", Version: "1.0.0"}
+	undiffed := models.Package{Name: "undiffed", Version: "1.0.0"}
+	files := map[string]string{
+		"scope__diffed@1.0.0/behavior.jsonl":                "{}\n",
+		"scope__diffed@1.0.0/proxy.jsonl":                   "{}\n",
+		"scope__diffed@1.0.0/diff.json":                     "{}",
+		"scope__diffed@1.0.0/variants/ru/behavior.jsonl":    "{}\n",
+		"scope__diffed@1.0.0/variants/ru/variant-diff.json": "{}",
+		"undiffed@1.0.0/behavior.jsonl":                     "{}\n",
+	}
+	writeFiles(t, outputDir, files)
+	writeFiles(t, "analysis-results", files)
+
+	t.Run("keep traces", func(t *testing.T) {
+		o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+		o.SetKeepTraces(true)
+		o.pruneTraces([]models.Package{diffed, undiffed}, outputDir)
+		assert.FileExists(t, filepath.Join(outputDir, "scope__diffed@1.0.0", "behavior.jsonl"))
+	})
+
+	t.Run("prune", func(t *testing.T) {
+		o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+		o.pruneTraces([]models.Package{diffed, undiffed}, outputDir)
+		for _, root := range []string{outputDir, "analysis-results"} {
+			pkgDir := filepath.Join(root, "scope__diffed@1.0.0")
+			assert.NoFileExists(t, filepath.Join(pkgDir, "behavior.jsonl"))
+			assert.NoFileExists(t, filepath.Join(pkgDir, "proxy.jsonl"))
+			assert.NoFileExists(t, filepath.Join(pkgDir, "variants", "ru", "behavior.jsonl"))
+			assert.FileExists(t, filepath.Join(pkgDir, "diff.json"))
+			assert.FileExists(t, filepath.Join(pkgDir, "variants", "ru", "variant-diff.json"))
+			// Without a diff the trace is still needed
+			assert.FileExists(t, filepath.Join(root, "undiffed@1.0.0", "behavior.jsonl"))
+		}
+	})
+}
+
+func TestEstimatePackageFootprint(t *testing.T) {
+	dir := t.TempDir()
+	assert.Equal(t, uint64(defaultPackageFootprint), estimatePackageFootprint(dir, filepath.Join(dir, "missing")))
+
+	writeFiles(t, dir, map[string]string{
+		"a@1.0.0/behavior.jsonl": "0123456789",
+		"a@1.0.0/diff.json":      "0123456789",
+		"b@1.0.0/behavior.jsonl": "0123456789",
+		// Pruned, so not representative of a download
+		"c@1.0.0/diff.json": "0",
+	})
+	assert.Equal(t, uint64(15), estimatePackageFootprint(dir, dir))
+}
+
+func TestPreflightDiskSpace(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cached := models.Package{Name: "cached", Version: "1.0.0"}
+	writeFiles(t, "analysis-results", map[string]string{"cached@1.0.0/diff.json": "{}"})
+	missing := filepath.Join(t.TempDir(), "missing")
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	// Nothing to download, so the unreadable directory isn't checked
+	require.NoError(t, o.preflightDiskSpace([]models.Package{cached}, missing, missing))
+
+	// An unreadable directory only skips the check
+	uncached := models.Package{Name: "uncached", Version: "1.0.0"}
+	require.NoError(t, o.preflightDiskSpace([]models.Package{cached, uncached}, missing, missing))
+
+	o.SetSkipDiskCheck(true)
+	require.NoError(t, o.preflightDiskSpace([]models.Package{uncached}, missing, missing))
+}
+
+func TestRunPackagesPrunedCache(t *testing.T) {
+	// A cache pruned down to diff.json is served without a workflow run
+	t.Chdir(t.TempDir())
+	pkg := models.Package{Name: "pruned", Version: "1.0.0"}
+	writeFiles(t, "analysis-results", map[string]string{
+		"pruned@1.0.0/diff.json":        "{}",
+		"pruned@1.0.0/ai-analysis.json": `{"is_malicious":false,"confidence":0.9}`,
+	})
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+		return nil, errors.New("GitHub unreachable")
+	})}
+	outputDir := t.TempDir()
+	results, err := o.RunPackages(context.Background(), []models.Package{pkg}, t.TempDir(), outputDir)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Success)
+	assert.FileExists(t, filepath.Join(outputDir, "pruned@1.0.0", "diff.json"))
+}
+
+func TestFormatBytes(t *testing.T) {
+	assert.Equal(t, "512 B", formatBytes(512))
+	assert.Equal(t, "1.5 KiB", formatBytes(1536))
+	assert.Equal(t, "32.0 MiB", formatBytes(defaultPackageFootprint))
+}
@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// DispatchIDInput is the workflow input carrying a unique ID per dispatch.
+// workflow_dispatch answers 204 without the run it created, so the workflow
+// puts the ID in its run-name and the run is found by listing recent runs.
+const DispatchIDInput = "dispatch_id"
+
+// How long to look for a dispatched run before giving up; runs take a few
+// seconds to show up in the list
+var (
+	dispatchLookupInterval = 3 * time.Second
+	dispatchLookupTimeout  = 2 * time.Minute
+)
+
+// newDispatchID returns a random ID to correlate a dispatch with its run
+func newDispatchID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// workflowRunList is the response of the list workflow runs endpoint
+type workflowRunList struct {
+	WorkflowRuns []struct {
+		WorkflowRun
+		DisplayTitle string `json:"display_title"`
+	} `json:"workflow_runs"`
+}
+
+// findDispatchedRun polls the recent workflow_dispatch runs of workflowFile
+// for the one whose run-name contains dispatchID. since is when it was
+// dispatched; a minute of clock skew is allowed.
+func (c *GitHubClient) findDispatchedRun(ctx context.Context, workflowFile, dispatchID string, since time.Time) (*WorkflowRunResponse, error) {
+	query := url.Values{
+		"event":    {"workflow_dispatch"},
+		"created":  {">=" + since.Add(-time.Minute).UTC().Format(time.RFC3339)},
+		"per_page": {"100"},
+	}
+	runsURL := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/workflows/%s/runs?%s",
+		c.Owner, c.Repo, workflowFile, query.Encode())
+
+	deadline := time.Now().Add(dispatchLookupTimeout)
+	for {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, runsURL, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+		resp, err := c.do(c.HTTPClient, req)
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read response body: %w", err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			return nil, statusError(resp, body)
+		}
+
+		var list workflowRunList
+		if err := json.Unmarshal(body, &list); err != nil {
+			return nil, fmt.Errorf("failed to parse response: %w", err)
+		}
+		for _, run := range list.WorkflowRuns {
+			if strings.Contains(run.DisplayTitle, dispatchID) {
+				return &WorkflowRunResponse{RunID: run.ID, HTMLURL: run.HTMLURL}, nil
+			}
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("no run found for dispatch %s after %s; does the workflow put inputs.%s in its run-name?", dispatchID, dispatchLookupTimeout, DispatchIDInput)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(dispatchLookupInterval):
+		}
+	}
+}
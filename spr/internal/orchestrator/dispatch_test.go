@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerWorkflowFindsDispatchedRun(t *testing.T) {
+	dispatchLookupInterval = time.Millisecond
+	t.Cleanup(func() { dispatchLookupInterval = 3 * time.Second })
+
+	var lists int
+	client := NewGitHubClient("token", "owner", "repo")
+	client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		respond := func(status int, body string) (*http.Response, error) {
+			return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+		}
+		switch {
+		case r.Method == http.MethodPost:
+			return respond(http.StatusNoContent, "")
+		case r.URL.Path == "/repos/owner/repo/actions/workflows/analyze.yml/runs":
+			assert.Equal(t, "workflow_dispatch", r.URL.Query().Get("event"))
+			assert.True(t, strings.HasPrefix(r.URL.Query().Get("created"), ">="))
+			lists++
+			if lists == 1 {
+				// Not listed yet
+				return respond(http.StatusOK, `{"workflow_runs":[{"id":1,"display_title":"Analyze lodash@4.17.21 [other]"}]}`)
+			}
+			return respond(http.StatusOK, `{"workflow_runs":[
+				{"id":1,"display_title":"Analyze lodash@4.17.21 [other]"},
+				{"id":42,"display_title":"Analyze left-pad@1.3.0 [abc123]","html_url":"https://github.com/owner/repo/actions/runs/42"}]}`)
+		}
+		t.Fatalf("unexpected request %s %s", r.Method, r.URL)
+		return nil, nil
+	})}
+
+	run, err := client.TriggerWorkflow(t.Context(), "analyze.yml", map[string]string{"package": "left-pad", DispatchIDInput: "abc123"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), run.RunID)
+	assert.Equal(t, "https://github.com/owner/repo/actions/runs/42", run.HTMLURL)
+	assert.Equal(t, 2, lists)
+
+	_, err = client.TriggerWorkflow(t.Context(), "analyze.yml", map[string]string{"package": "left-pad"})
+	assert.ErrorContains(t, err, DispatchIDInput)
+}
@@ -0,0 +1,163 @@
+package orchestrator
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+)
+
+// DefaultDriftThreshold is the share of traced activity a baseline is
+// expected to remove; runs below it count towards a stale baseline
+const DefaultDriftThreshold = 0.5
+
+// DefaultDriftRuns is how many consecutive runs below the threshold mark a
+// baseline stale
+const DefaultDriftRuns = 3
+
+// DriftRecord is one run in the baseline drift history
+type DriftRecord struct {
+	Time     time.Time `json:"time"`
+	RunID    string    `json:"run_id,omitempty"`
+	Baseline string    `json:"baseline"` // SHA-256 of the baseline file
+	Diffs    int       `json:"diffs"`    // Diffs generated in the run
+	Removed  float64   `json:"removed"`  // Mean aggregate.RemovedShare over them
+}
+
+// SetBaselineDrift tracks how much of the traced activity the baseline
+// removes, appending one DriftRecord per run to the JSON lines file at path.
+// When the last runs runs of the same baseline all removed less than
+// threshold, the baseline is reported stale, as happens once npm or Node in
+// the runner image no longer match it. An empty path disables tracking.
+func (o *Orchestrator) SetBaselineDrift(path string, threshold float64, runs int) {
+	o.driftPath = path
+	o.driftThreshold = threshold
+	o.driftRuns = runs
+}
+
+// observeRemoved records the share of activity the baseline removed from a
+// diff generated in this run
+func (o *Orchestrator) observeRemoved(share float64) {
+	o.driftMu.Lock()
+	defer o.driftMu.Unlock()
+	o.driftShares = append(o.driftShares, share)
+}
+
+// checkBaselineDrift appends this run's baseline effectiveness to the drift
+// history and warns when the baseline looks stale. Runs that generated no
+// diffs, e.g. fully cached ones, aren't recorded. Failures are logged, not
+// returned.
+func (o *Orchestrator) checkBaselineDrift() {
+	o.driftMu.Lock()
+	shares := o.driftShares
+	o.driftShares = nil
+	o.driftMu.Unlock()
+	if o.driftPath == "" || o.baselinePath == "" || len(shares) == 0 {
+		return
+	}
+
+	data, err := os.ReadFile(o.baselinePath)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to hash baseline %s for drift tracking: %v", o.baselinePath, err), "warning", logging.KeyStage, "baseline-drift")
+		return
+	}
+	sum := sha256.Sum256(data)
+	record := DriftRecord{
+		Time:     time.Now().UTC(),
+		RunID:    o.resultsLogRunID,
+		Baseline: hex.EncodeToString(sum[:]),
+		Diffs:    len(shares),
+	}
+	for _, share := range shares {
+		record.Removed += share / float64(len(shares))
+	}
+
+	if err := appendDriftRecord(o.driftPath, record); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to append to baseline drift history %s: %v", o.driftPath, err), "warning", logging.KeyStage, "baseline-drift")
+		return
+	}
+	o.logMsg(fmt.Sprintf("Baseline removed %.0f%% of traced activity across %d diffs", record.Removed*100, record.Diffs), "info", logging.KeyStage, "baseline-drift")
+
+	history, err := LoadDriftHistory(o.driftPath)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to read baseline drift history %s: %v", o.driftPath, err), "warning", logging.KeyStage, "baseline-drift")
+		return
+	}
+	threshold, runs := o.driftThreshold, o.driftRuns
+	if threshold <= 0 {
+		threshold = DefaultDriftThreshold
+	}
+	if runs <= 0 {
+		runs = DefaultDriftRuns
+	}
+	if BaselineStale(history, record.Baseline, threshold, runs) {
+		o.logMsg(fmt.Sprintf("Baseline %s looks stale: it removed less than %.0f%% of traced activity in each of the last %d runs, so diffs are mostly runner noise. Regenerate it from fresh known-safe runs (e.g. after npm or Node upgrades in the runner image) with aggregate-cli -samples.",
+			o.baselinePath, threshold*100, runs), "warning", logging.KeyStage, "baseline-drift")
+	}
+}
+
+// BaselineStale reports whether the last runs records of the given baseline
+// in history all removed less than threshold. Records of other baselines
+// are ignored, so a regenerated baseline starts afresh.
+func BaselineStale(history []DriftRecord, baseline string, threshold float64, runs int) bool {
+	seen := 0
+	for i := len(history) - 1; i >= 0 && seen < runs; i-- {
+		if history[i].Baseline != baseline {
+			continue
+		}
+		if history[i].Removed >= threshold {
+			return false
+		}
+		seen++
+	}
+	return runs > 0 && seen == runs
+}
+
+// LoadDriftHistory reads the drift history at path, oldest first. A missing
+// file is an empty history.
+func LoadDriftHistory(path string) ([]DriftRecord, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var history []DriftRecord
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record DriftRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		history = append(history, record)
+	}
+	return history, scanner.Err()
+}
+
+// appendDriftRecord appends record to the drift history at path
+func appendDriftRecord(path string, record DriftRecord) error {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaselineStale(t *testing.T) {
+	history := []DriftRecord{
+		{Baseline: "old", Removed: 0.1},
+		{Baseline: "a", Removed: 0.9},
+		{Baseline: "a", Removed: 0.3},
+		{Baseline: "old", Removed: 0.1},
+		{Baseline: "a", Removed: 0.2},
+	}
+	assert.False(t, BaselineStale(history, "a", 0.5, 3), "an effective run among the last three")
+	assert.True(t, BaselineStale(history, "a", 0.5, 2))
+	assert.False(t, BaselineStale(history, "new", 0.5, 1), "no history for a regenerated baseline")
+	assert.True(t, BaselineStale(history, "old", 0.5, 2))
+}
+
+func TestCheckBaselineDrift(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"baseline.json": `{"collection":"safe","per_process":{}}`})
+	historyPath := filepath.Join(dir, "drift.jsonl")
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, filepath.Join(dir, "baseline.json"), "", nil, nil)
+	o.SetResultsLog("", "run-1")
+	o.SetBaselineDrift(historyPath, 0.5, 2)
+
+	// Runs without fresh diffs aren't recorded
+	o.checkBaselineDrift()
+	history, err := LoadDriftHistory(historyPath)
+	require.NoError(t, err)
+	assert.Empty(t, history)
+
+	o.observeRemoved(0.2)
+	o.observeRemoved(0.4)
+	o.checkBaselineDrift()
+	o.observeRemoved(0.1)
+	o.checkBaselineDrift()
+
+	history, err = LoadDriftHistory(historyPath)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.Equal(t, "run-1", history[0].RunID)
+	assert.Equal(t, 2, history[0].Diffs)
+	assert.InDelta(t, 0.3, history[0].Removed, 1e-9)
+	assert.Len(t, history[0].Baseline, 64)
+	assert.True(t, BaselineStale(history, history[0].Baseline, 0.5, 2))
+}
@@ -0,0 +1,60 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/acheong08/hackeurope-spr/internal/artifacts"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// SetArtifactSink ships each package's behavior.jsonl, diff.json and
+// ai-analysis.json to sink under <runID>/<name>@<version>/ once the run
+// completes. Unless keepLocal is set, local copies are removed after a
+// successful upload. A nil sink disables export.
+func (o *Orchestrator) SetArtifactSink(sink artifacts.Sink, runID string, keepLocal bool) {
+	o.artifactSink = sink
+	o.artifactRunID = runID
+	o.keepLocalArtifacts = keepLocal
+}
+
+// exportArtifacts uploads the artifacts of every package to the artifact
+// sink. Failures are logged and leave the local copy in place.
+func (o *Orchestrator) exportArtifacts(ctx context.Context, packages []models.Package, outputDir string) {
+	if o.artifactSink == nil {
+		return
+	}
+
+	uploaded := 0
+	for _, pkg := range packages {
+		pkgKey := naming.DirName(pkg.Name, pkg.Version)
+		pkgDir := filepath.Join(outputDir, pkgKey)
+
+		for _, name := range artifacts.Files {
+			filePath := filepath.Join(pkgDir, name)
+			if _, err := os.Stat(filePath); err != nil {
+				continue
+			}
+
+			key := path.Join(o.artifactRunID, pkgKey, name)
+			if err := artifacts.PutFile(ctx, o.artifactSink, key, filePath); err != nil {
+				o.logMsg(fmt.Sprintf("Failed to export %s for %s@%s: %v", name, pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "export")...)
+				continue
+			}
+			uploaded++
+
+			if !o.keepLocalArtifacts {
+				if err := os.Remove(filePath); err != nil {
+					o.logMsg(fmt.Sprintf("Failed to remove local %s for %s@%s: %v", name, pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "export")...)
+				}
+			}
+		}
+	}
+
+	o.logMsg(fmt.Sprintf("Exported %d artifacts to %s", uploaded, o.artifactSink), "info", logging.KeyStage, "export")
+}
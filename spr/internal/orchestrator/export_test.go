@@ -0,0 +1,78 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type memorySink struct {
+	objects map[string]string
+	fail    bool
+}
+
+func (s *memorySink) Put(_ context.Context, key string, body io.ReadSeeker, _ string) error {
+	if s.fail {
+		return errors.New("unavailable")
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	s.objects[key] = string(data)
+	return nil
+}
+
+func (s *memorySink) String() string { return "memory" }
+
+func TestExportArtifacts(t *testing.T) {
+	outputDir := t.TempDir()
+	pkgDir := filepath.Join(outputDir, "scope__pkg@1.0.0")
+	require.NoError(t, os.MkdirAll(pkgDir, 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "behavior.jsonl"), []byte("{}\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "diff.json"), []byte("{}"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "proxy.jsonl"), []byte("{}\n"), 0o644))
+
+	packages := []models.Package{{Name: "@scope/pkg", Version: "1.0.0"}}
+
+	t.Run("keep local", func(t *testing.T) {
+		sink := &memorySink{objects: map[string]string{}}
+		o := &Orchestrator{}
+		o.SetArtifactSink(sink, "run1", true)
+		o.exportArtifacts(t.Context(), packages, outputDir)
+
+		assert.Equal(t, map[string]string{
+			"run1/scope__pkg@1.0.0/behavior.jsonl": "{}\n",
+			"run1/scope__pkg@1.0.0/diff.json":      "{}",
+		}, sink.objects)
+		assert.FileExists(t, filepath.Join(pkgDir, "diff.json"))
+	})
+
+	t.Run("failed upload keeps local copy", func(t *testing.T) {
+		o := &Orchestrator{}
+		o.SetArtifactSink(&memorySink{fail: true}, "run2", false)
+		o.exportArtifacts(t.Context(), packages, outputDir)
+
+		assert.FileExists(t, filepath.Join(pkgDir, "diff.json"))
+	})
+
+	t.Run("remote only", func(t *testing.T) {
+		sink := &memorySink{objects: map[string]string{}}
+		o := &Orchestrator{}
+		o.SetArtifactSink(sink, "run3", false)
+		o.exportArtifacts(t.Context(), packages, outputDir)
+
+		assert.Len(t, sink.objects, 2)
+		assert.NoFileExists(t, filepath.Join(pkgDir, "behavior.jsonl"))
+		assert.NoFileExists(t, filepath.Join(pkgDir, "diff.json"))
+		// Only the exported artifacts are removed
+		assert.FileExists(t, filepath.Join(pkgDir, "proxy.jsonl"))
+	})
+}
@@ -4,18 +4,37 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
 )
 
+// ErrRateLimited is returned when GitHub rejects a request because the
+// API rate limit has been exhausted
+var ErrRateLimited = errors.New("GitHub API rate limit exceeded")
+
+// RateLimit holds the most recently observed GitHub API rate-limit state
+type RateLimit struct {
+	Limit     int
+	Remaining int
+	Reset     time.Time
+}
+
 // GitHubClient provides access to GitHub Actions API
 type GitHubClient struct {
 	Token      string
 	Owner      string
 	Repo       string
 	HTTPClient *http.Client
+
+	mu        sync.Mutex
+	rateLimit *RateLimit // nil until the first response is seen
 }
 
 // NewGitHubClient creates a new GitHub API client
@@ -24,8 +43,86 @@ func NewGitHubClient(token, owner, repo string) *GitHubClient {
 		Token:      token,
 		Owner:      owner,
 		Repo:       repo,
-		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		HTTPClient: breaker.Client(breaker.GitHub, 30*time.Second),
+	}
+}
+
+// do executes a request and records the rate-limit headers from the response
+func (c *GitHubClient) do(client *http.Client, req *http.Request) (*http.Response, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	c.recordRateLimit(resp.Header)
+	return resp, nil
+}
+
+// recordRateLimit updates the tracked rate limit from X-RateLimit-* headers
+func (c *GitHubClient) recordRateLimit(header http.Header) {
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return // Header absent (e.g. artifact storage redirect)
+	}
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	resetUnix, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rateLimit = &RateLimit{
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     time.Unix(resetUnix, 0),
+	}
+}
+
+// RateLimit returns the last observed rate-limit state.
+// The boolean is false if no GitHub response has been seen yet.
+func (c *GitHubClient) RateLimit() (RateLimit, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rateLimit == nil {
+		return RateLimit{}, false
+	}
+	return *c.rateLimit, true
+}
+
+// RateLimitError is a request rejected by a rate limit. It matches
+// ErrRateLimited with errors.Is.
+type RateLimitError struct {
+	StatusCode int
+	Body       string
+	// How long GitHub asked to wait (Retry-After), zero when it didn't say.
+	// The primary limit is waited out with the X-RateLimit-Reset instead.
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("%v (status %d): %s", ErrRateLimited, e.StatusCode, e.Body)
+}
+
+func (e *RateLimitError) Unwrap() error {
+	return ErrRateLimited
+}
+
+// statusError builds an error for an unexpected response status, a
+// *RateLimitError when GitHub signals that the rate limit is exhausted
+func statusError(resp *http.Response, body []byte) error {
+	if (resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests) &&
+		(resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != "") {
+		return &RateLimitError{StatusCode: resp.StatusCode, Body: string(body), RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"))}
 	}
+	return fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+}
+
+// parseRetryAfter parses a Retry-After header, in seconds or an HTTP date
+func parseRetryAfter(v string) time.Duration {
+	if seconds, err := strconv.Atoi(v); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
 }
 
 // WorkflowRunResponse represents the response from triggering a workflow
@@ -45,13 +142,14 @@ type WorkflowRun struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
-// TriggerWorkflow dispatches a workflow run
+// TriggerWorkflow dispatches a workflow run. When GitHub doesn't return the
+// run it created, the run is looked up by the DispatchIDInput input.
 func (c *GitHubClient) TriggerWorkflow(ctx context.Context, workflowFile string, inputs map[string]string) (*WorkflowRunResponse, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/workflows/%s/dispatches",
 		c.Owner, c.Repo, workflowFile)
 
 	payload := map[string]interface{}{
-		"ref":                "main",
+		"ref":                dispatchRef,
 		"inputs":             inputs,
 		"return_run_details": true,
 	}
@@ -71,7 +169,8 @@ func (c *GitHubClient) TriggerWorkflow(ctx context.Context, workflowFile string,
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 	req.Header.Set("Content-Type", "application/json")
 
-	resp, err := c.HTTPClient.Do(req)
+	dispatched := time.Now()
+	resp, err := c.do(c.HTTPClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -83,12 +182,17 @@ func (c *GitHubClient) TriggerWorkflow(ctx context.Context, workflowFile string,
 	}
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusNoContent {
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, statusError(resp, body)
 	}
 
 	if resp.StatusCode == http.StatusNoContent {
-		// Workflow triggered but no run details returned
-		return nil, fmt.Errorf("API returned 204 - run details not available. Ensure GitHub API supports return_run_details")
+		// Workflow triggered but no run details returned; find the run by
+		// its dispatch ID instead
+		dispatchID := inputs[DispatchIDInput]
+		if dispatchID == "" {
+			return nil, fmt.Errorf("API returned 204 - run details not available and no %s input to find the run by", DispatchIDInput)
+		}
+		return c.findDispatchedRun(ctx, workflowFile, dispatchID, dispatched)
 	}
 
 	var run WorkflowRunResponse
@@ -117,7 +221,7 @@ func (c *GitHubClient) GetWorkflowRun(ctx context.Context, runID int64) (*Workfl
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(c.HTTPClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -128,7 +232,7 @@ func (c *GitHubClient) GetWorkflowRun(ctx context.Context, runID int64) (*Workfl
 		if readErr != nil {
 			return nil, fmt.Errorf("unexpected status %d (failed to read body: %v)", resp.StatusCode, readErr)
 		}
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var run WorkflowRun
@@ -149,6 +253,38 @@ type Artifact struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
+// CancelWorkflowRun requests cancellation of a workflow run. GitHub answers
+// 409 if the run already finished, which is not treated as an error.
+func (c *GitHubClient) CancelWorkflowRun(ctx context.Context, runID int64) error {
+	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runs/%d/cancel",
+		c.Owner, c.Repo, runID)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.do(c.HTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusAccepted && resp.StatusCode != http.StatusConflict {
+		body, readErr := io.ReadAll(resp.Body)
+		if readErr != nil {
+			return fmt.Errorf("unexpected status %d (failed to read body: %v)", resp.StatusCode, readErr)
+		}
+		return statusError(resp, body)
+	}
+
+	return nil
+}
+
 // ListArtifacts returns all artifacts for a workflow run
 func (c *GitHubClient) ListArtifacts(ctx context.Context, runID int64) ([]Artifact, error) {
 	url := fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/runs/%d/artifacts",
@@ -163,7 +299,7 @@ func (c *GitHubClient) ListArtifacts(ctx context.Context, runID int64) ([]Artifa
 	req.Header.Set("Authorization", "Bearer "+c.Token)
 	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
 
-	resp, err := c.HTTPClient.Do(req)
+	resp, err := c.do(c.HTTPClient, req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -174,7 +310,7 @@ func (c *GitHubClient) ListArtifacts(ctx context.Context, runID int64) ([]Artifa
 		if readErr != nil {
 			return nil, fmt.Errorf("unexpected status %d (failed to read body: %v)", resp.StatusCode, readErr)
 		}
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, statusError(resp, body)
 	}
 
 	var result struct {
@@ -204,13 +340,14 @@ func (c *GitHubClient) DownloadArtifact(ctx context.Context, artifactID int64) (
 
 	// Disable redirect following to get the redirect URL
 	client := &http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:   30 * time.Second,
+		Transport: breaker.Transport(breaker.GitHub, nil),
 		CheckRedirect: func(req *http.Request, via []*http.Request) error {
 			return http.ErrUseLastResponse
 		},
 	}
 
-	resp, err := client.Do(req)
+	resp, err := c.do(client, req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
 	}
@@ -242,7 +379,7 @@ func (c *GitHubClient) DownloadArtifact(ctx context.Context, artifactID int64) (
 		if readErr != nil {
 			return nil, fmt.Errorf("unexpected status %d (failed to read body: %v)", resp.StatusCode, readErr)
 		}
-		return nil, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, string(body))
+		return nil, statusError(resp, body)
 	}
 
 	data, err := io.ReadAll(resp.Body)
@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordRateLimit(t *testing.T) {
+	tests := []struct {
+		name   string
+		header map[string]string
+		want   *RateLimit
+	}{
+		{
+			name:   "full headers",
+			header: map[string]string{"X-RateLimit-Limit": "5000", "X-RateLimit-Remaining": "42", "X-RateLimit-Reset": "1700000000"},
+			want:   &RateLimit{Limit: 5000, Remaining: 42, Reset: time.Unix(1700000000, 0)},
+		},
+		{
+			name:   "remaining only",
+			header: map[string]string{"X-RateLimit-Remaining": "0"},
+			want:   &RateLimit{Reset: time.Unix(0, 0)},
+		},
+		{
+			name:   "no headers",
+			header: map[string]string{},
+		},
+		{
+			name:   "malformed remaining",
+			header: map[string]string{"X-RateLimit-Remaining": "lots", "X-RateLimit-Limit": "5000"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewGitHubClient("token", "owner", "repo")
+			header := http.Header{}
+			for k, v := range tt.header {
+				header.Set(k, v)
+			}
+			c.recordRateLimit(header)
+			limit, ok := c.RateLimit()
+			if tt.want == nil {
+				assert.False(t, ok)
+				return
+			}
+			require.True(t, ok)
+			assert.Equal(t, *tt.want, limit)
+		})
+	}
+}
+
+func TestStatusError(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		header      map[string]string
+		rateLimited bool
+	}{
+		{"exhausted primary limit", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0"}, true},
+		{"secondary limit", http.StatusForbidden, map[string]string{"Retry-After": "60"}, true},
+		{"too many requests", http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}, true},
+		{"forbidden with budget left", http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "10"}, false},
+		{"too many requests without headers", http.StatusTooManyRequests, nil, false},
+		{"server error", http.StatusBadGateway, map[string]string{"X-RateLimit-Remaining": "0"}, false},
+		{"not found", http.StatusNotFound, nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{StatusCode: tt.status, Header: http.Header{}}
+			for k, v := range tt.header {
+				resp.Header.Set(k, v)
+			}
+			err := statusError(resp, []byte("body"))
+			require.Error(t, err)
+			if tt.rateLimited {
+				assert.ErrorIs(t, err, ErrRateLimited)
+			} else {
+				assert.NotErrorIs(t, err, ErrRateLimited)
+			}
+			assert.Contains(t, err.Error(), "body")
+		})
+	}
+}
+
+func TestPollWorkflowCompletionRateLimitPause(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.client.rateLimit = &RateLimit{Limit: 5000, Remaining: 0, Reset: time.Now().Add(200 * time.Millisecond)}
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		header := http.Header{}
+		header.Set("X-RateLimit-Remaining", "5000")
+		return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(`{"id":7,"status":"completed","conclusion":"success"}`))}, nil
+	})}
+
+	// The pause outlasts the timeout, but doesn't count towards it
+	run, err := o.pollWorkflowCompletion(t.Context(), 7, 100*time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, "completed", run.Status)
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, 60*time.Second, parseRetryAfter("60"))
+	assert.Zero(t, parseRetryAfter(""))
+	assert.Zero(t, parseRetryAfter("soon"))
+	assert.Zero(t, parseRetryAfter(time.Now().Add(-time.Hour).UTC().Format(http.TimeFormat)))
+	assert.InDelta(t, time.Minute, parseRetryAfter(time.Now().Add(time.Minute).UTC().Format(http.TimeFormat)), float64(2*time.Second))
+}
+
+func TestDownloadArtifactsRetriesRateLimit(t *testing.T) {
+	newOrchestrator := func(limited int) (*Orchestrator, *int) {
+		requests := 0
+		o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+		o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+			requests++
+			header := http.Header{}
+			if requests <= limited {
+				// Secondary rate limit
+				header.Set("Retry-After", "0")
+				return &http.Response{StatusCode: http.StatusForbidden, Header: header, Body: io.NopCloser(strings.NewReader("slow down"))}, nil
+			}
+			return &http.Response{StatusCode: http.StatusOK, Header: header, Body: io.NopCloser(strings.NewReader(`{"total_count":0,"artifacts":[]}`))}, nil
+		})}
+		return o, &requests
+	}
+
+	o, requests := newOrchestrator(2)
+	artifacts, err := o.downloadArtifacts(t.Context(), 7, models.Package{Name: "pkg", Version: "1.0.0"}, t.TempDir())
+	require.NoError(t, err)
+	assert.Empty(t, artifacts)
+	assert.Equal(t, 3, *requests)
+
+	o, requests = newOrchestrator(rateLimitRetries + 1)
+	_, err = o.downloadArtifacts(t.Context(), 7, models.Package{Name: "pkg", Version: "1.0.0"}, t.TempDir())
+	assert.ErrorIs(t, err, ErrRateLimited)
+	assert.Equal(t, rateLimitRetries+1, *requests)
+}
@@ -0,0 +1,93 @@
+package orchestrator
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ImageInput is the workflow input carrying the image reference in image
+// mode, see SetImageMode
+const ImageInput = "image"
+
+// DefaultImageWorkflowFile runs an image's entrypoint under Tracee
+const DefaultImageWorkflowFile = "analyze-image.yml"
+
+// imageRepository matches the repository of an image reference: an optional
+// registry host (with port), then lowercase path components
+var imageRepository = regexp.MustCompile(`^(?:[a-zA-Z0-9.-]+(?::[0-9]+)?/)?[a-z0-9]+(?:[._-]+[a-z0-9]+)*(?:/[a-z0-9]+(?:[._-]+[a-z0-9]+)*)*$`)
+
+var (
+	imageTag    = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.-]{0,127}$`)
+	imageDigest = regexp.MustCompile(`^sha256:[a-f0-9]{64}$`)
+)
+
+// ParseImageRef parses a Docker image reference, e.g. "nginx:1.25",
+// "ghcr.io/owner/tool@sha256:…", into the package it is analyzed as: the
+// repository as name and the tag or digest as version. A reference with
+// neither is "latest". A digest pins the image, so it wins over a tag.
+func ParseImageRef(ref string) (models.Package, error) {
+	repo, version := strings.TrimSpace(ref), ""
+	if r, digest, ok := strings.Cut(repo, "@"); ok {
+		if !imageDigest.MatchString(digest) {
+			return models.Package{}, fmt.Errorf("invalid image digest %q", digest)
+		}
+		repo, version = r, digest
+	}
+	// A colon after the last slash separates the tag; one before it is a
+	// registry port
+	if i := strings.LastIndex(repo, ":"); i > strings.LastIndex(repo, "/") {
+		tag := repo[i+1:]
+		if !imageTag.MatchString(tag) {
+			return models.Package{}, fmt.Errorf("invalid image tag %q", tag)
+		}
+		repo = repo[:i]
+		if version == "" {
+			version = tag
+		}
+	}
+	if !imageRepository.MatchString(repo) {
+		return models.Package{}, fmt.Errorf("invalid image reference %q", ref)
+	}
+	if version == "" {
+		version = "latest"
+	}
+	return models.Package{ID: repo + "@" + version, Name: repo, Version: version}, nil
+}
+
+// ImageRef returns the image reference of a package parsed by ParseImageRef
+func ImageRef(pkg models.Package) string {
+	if strings.HasPrefix(pkg.Version, "sha256:") {
+		return pkg.Name + "@" + pkg.Version
+	}
+	return pkg.Name + ":" + pkg.Version
+}
+
+// SetImageMode analyzes container images instead of npm packages: each
+// package is an image (see ParseImageRef) whose entrypoint the workflow runs
+// under Tracee, given as ImageInput. The npm test settings (TLS
+// interception, observation, environment matrix, tests) aren't sent, and
+// without a baseline every traced activity is kept in the diff, so
+// aggregation, rules and AI analysis still run.
+func (o *Orchestrator) SetImageMode(enabled bool) {
+	o.imageMode = enabled
+	if enabled && o.baseline == nil {
+		o.baseline = &aggregate.PerProcessStats{Collection: "empty"}
+	}
+}
+
+// targetInputs returns the inputs of a dispatch analyzing something other
+// than an npm package: the extra inputs, and ref as input. The npm test
+// settings don't apply.
+func (o *Orchestrator) targetInputs(input, ref string) map[string]string {
+	inputs := make(map[string]string, len(o.workflowInputs)+2)
+	for key, value := range o.workflowInputs {
+		inputs[key] = value
+	}
+	inputs[input] = ref
+	inputs[DispatchIDInput] = newDispatchID()
+	return inputs
+}
@@ -0,0 +1,102 @@
+package orchestrator
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseImageRef(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("ab", 32)
+	tests := []struct {
+		ref, name, version string
+	}{
+		{"nginx", "nginx", "latest"},
+		{"nginx:1.25", "nginx", "1.25"},
+		{"library/node:20-alpine", "library/node", "20-alpine"},
+		{"ghcr.io/owner/tool:v1.2.3", "ghcr.io/owner/tool", "v1.2.3"},
+		{"localhost:5000/tool", "localhost:5000/tool", "latest"},
+		{"localhost:5000/tool:dev", "localhost:5000/tool", "dev"},
+		{"ghcr.io/owner/tool@" + digest, "ghcr.io/owner/tool", digest},
+		{"ghcr.io/owner/tool:v1@" + digest, "ghcr.io/owner/tool", digest},
+	}
+	for _, tt := range tests {
+		pkg, err := ParseImageRef(tt.ref)
+		if assert.NoError(t, err, tt.ref) {
+			assert.Equal(t, tt.name, pkg.Name, tt.ref)
+			assert.Equal(t, tt.version, pkg.Version, tt.ref)
+		}
+	}
+
+	for _, ref := range []string{"", "Nginx", "nginx:", "nginx@sha256:abc", "nginx:bad tag", "-nginx", "a//b"} {
+		_, err := ParseImageRef(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestImageRef(t *testing.T) {
+	for _, ref := range []string{"nginx:1.25", "ghcr.io/owner/tool@sha256:" + strings.Repeat("0", 64)} {
+		pkg, err := ParseImageRef(ref)
+		require.NoError(t, err)
+		assert.Equal(t, ref, ImageRef(pkg))
+	}
+}
+
+func TestImageModeInputs(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", DefaultImageWorkflowFile, 1, time.Minute, nil, "", "", nil, nil)
+	o.SetInterceptTLS(true)
+	o.SetWorkflowInputs(map[string]string{"run_seconds": "60"})
+	o.SetImageMode(true)
+	require.NotNil(t, o.baseline, "an empty baseline keeps diffs and AI analysis running")
+
+	pkg, err := ParseImageRef("nginx:1.25")
+	require.NoError(t, err)
+	inputs := o.targetInputs(ImageInput, ImageRef(pkg))
+	assert.Equal(t, "nginx:1.25", inputs[ImageInput])
+	assert.Equal(t, "60", inputs["run_seconds"])
+	assert.NotEmpty(t, inputs[DispatchIDInput])
+	assert.NotContains(t, inputs, "package")
+	assert.NotContains(t, inputs, "intercept_tls")
+
+	_, err = ParseWorkflowInputs("image=alpine")
+	assert.Error(t, err, "image is set by spr")
+}
+
+func TestParseActionRef(t *testing.T) {
+	tests := []struct {
+		ref, name, version string
+	}{
+		{"actions/setup-node@v4", "actions/setup-node", "v4"},
+		{"owner/repo/sub/dir@v1.2.3", "owner/repo/sub/dir", "v1.2.3"},
+		{"owner/repo@0123456789abcdef0123456789abcdef01234567", "owner/repo", "0123456789abcdef0123456789abcdef01234567"},
+	}
+	for _, tt := range tests {
+		pkg, err := ParseActionRef(tt.ref)
+		if assert.NoError(t, err, tt.ref) {
+			assert.Equal(t, tt.name, pkg.Name, tt.ref)
+			assert.Equal(t, tt.version, pkg.Version, tt.ref)
+			assert.Equal(t, tt.ref, ActionRef(pkg))
+		}
+	}
+
+	for _, ref := range []string{"", "actions/checkout", "actions@v4", "actions/checkout@", "actions/checkout@releases/v1", "owner/../x@v1", "-owner/x@v1", "owner/x@v1 ; rm"} {
+		_, err := ParseActionRef(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestActionModeInputs(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", DefaultActionWorkflowFile, 1, time.Minute, nil, "", "", nil, nil)
+	o.SetActionMode(true)
+	require.NotNil(t, o.baseline)
+
+	inputs := o.targetInputs(ActionInput, "actions/setup-node@v4")
+	assert.Equal(t, "actions/setup-node@v4", inputs[ActionInput])
+	assert.NotContains(t, inputs, "package")
+
+	_, err := ParseWorkflowInputs("action=x/y@v1")
+	assert.Error(t, err, "action is set by spr")
+}
@@ -0,0 +1,29 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// SetIngestDir sets where the server stores traces uploaded by self-hosted
+// runners, one <run id>/behavior.jsonl per workflow run (runners use
+// $GITHUB_RUN_ID as the job ID). A run's ingested trace is collected with its
+// GitHub artifacts; empty disables it.
+func (o *Orchestrator) SetIngestDir(dir string) {
+	o.ingestDir = dir
+}
+
+// ingestedArtifact returns the directory holding the trace uploaded for
+// runID, if its upload completed. It is consumed like a downloaded artifact
+// and removed once copied to the output.
+func (o *Orchestrator) ingestedArtifact(runID int64) (string, bool) {
+	if o.ingestDir == "" {
+		return "", false
+	}
+	dir := filepath.Join(o.ingestDir, strconv.FormatInt(runID, 10))
+	if _, err := os.Stat(filepath.Join(dir, "behavior.jsonl")); err != nil {
+		return "", false
+	}
+	return dir, true
+}
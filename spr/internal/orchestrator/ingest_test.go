@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunPackagesIngestedTrace(t *testing.T) {
+	t.Chdir(t.TempDir())
+	ingestDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(ingestDir, "42"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(ingestDir, "42", "behavior.jsonl"), []byte("{\"eventName\":\"execve\"}\n"), 0o644))
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var body string
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/dispatches"):
+			body = `{"workflow_run_id":42}`
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/42"):
+			body = `{"id":42,"status":"completed","conclusion":"success"}`
+		case strings.HasSuffix(r.URL.Path, "/actions/runs/42/artifacts"):
+			// A self-hosted run uploads nothing to GitHub
+			body = `{"total_count":0,"artifacts":[]}`
+		default:
+			return &http.Response{StatusCode: http.StatusNotFound, Header: http.Header{}, Body: io.NopCloser(strings.NewReader("{}"))}, nil
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+	o.SetIngestDir(ingestDir)
+
+	outputDir := t.TempDir()
+	results, err := o.RunPackages(context.Background(), []models.Package{{Name: "hosted", Version: "1.0.0"}}, t.TempDir(), outputDir)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.Equal(t, int64(42), results[0].RunID)
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "hosted@1.0.0", "behavior.jsonl"))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"eventName\":\"execve\"}\n", string(data))
+	// Consumed like a downloaded artifact
+	assert.NoDirExists(t, filepath.Join(ingestDir, "42"))
+}
+
+func TestIngestedArtifact(t *testing.T) {
+	o := NewOrchestrator("", "", "", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	_, ok := o.ingestedArtifact(1)
+	assert.False(t, ok, "disabled without an ingest dir")
+
+	dir := t.TempDir()
+	o.SetIngestDir(dir)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "1"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "1", "behavior.jsonl.part"), []byte("{}\n"), 0o644))
+	_, ok = o.ingestedArtifact(1)
+	assert.False(t, ok, "an unfinished upload is ignored")
+
+	require.NoError(t, os.Rename(filepath.Join(dir, "1", "behavior.jsonl.part"), filepath.Join(dir, "1", "behavior.jsonl")))
+	got, ok := o.ingestedArtifact(1)
+	assert.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "1"), got)
+}
@@ -0,0 +1,231 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/tester"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"gopkg.in/yaml.v3"
+)
+
+// managedInputs are the workflow inputs the orchestrator sets itself from
+// its own settings; extra inputs can't override them
+var managedInputs = map[string]bool{
+	"package":         true,
+	"version":         true,
+	DispatchIDInput:   true,
+	"intercept_tls":   true,
+	"observe_minutes": true,
+	"clock_skew":      true,
+	"env_matrix":      true,
+	"tests":           true,
+	SeedInput:         true,
+	ImageInput:        true,
+	ActionInput:       true,
+}
+
+// MaxObserveMinutes is the longest observation window, leaving the
+// workflow's 120 minute observation step time for its setup
+const MaxObserveMinutes = 110
+
+// clockSkewPattern matches the faketime specs the workflow accepts: a
+// relative offset such as "+30d" or an absolute "@2030-01-01 00:00:00",
+// optionally followed by a speed-up ("x10") or per-call increment ("i2")
+var clockSkewPattern = regexp.MustCompile(`^([+-][0-9]+(\.[0-9]+)?[smhdy]?|@[0-9]{4}-[0-9]{2}-[0-9]{2} [0-9]{2}:[0-9]{2}:[0-9]{2})( [xi][0-9]+(\.[0-9]+)?)?$`)
+
+// ValidateObservation checks the observation settings of SetObservation
+// before they are dispatched. The clock skew ends up in a faketime command
+// line in the runner, so anything but a plain faketime spec is rejected.
+func ValidateObservation(minutes int, clockSkew string) error {
+	if minutes < 0 || minutes > MaxObserveMinutes {
+		return fmt.Errorf("observation window of %d minutes is outside 0-%d", minutes, MaxObserveMinutes)
+	}
+	if minutes > 0 && clockSkew != "" && !clockSkewPattern.MatchString(clockSkew) {
+		return fmt.Errorf("invalid clock skew %q, expected a faketime offset such as \"+30d x10\"", clockSkew)
+	}
+	return nil
+}
+
+// packageInputs returns the inputs of a dispatch analyzing the npm package
+// pkg: the extra inputs, then those set from the orchestrator's settings
+func (o *Orchestrator) packageInputs(pkg models.Package) map[string]string {
+	inputs := make(map[string]string, len(o.workflowInputs)+3)
+	for key, value := range o.workflowInputs {
+		inputs[key] = value
+	}
+	inputs["package"] = pkg.Name
+	inputs["version"] = pkg.Version
+	inputs[DispatchIDInput] = newDispatchID()
+	if o.interceptTLS {
+		inputs["intercept_tls"] = "true"
+	}
+	if o.observeMinutes > 0 {
+		inputs["observe_minutes"] = strconv.Itoa(o.observeMinutes)
+		if o.clockSkew != "" {
+			inputs["clock_skew"] = o.clockSkew
+		}
+	}
+	if len(o.envMatrix) > 0 {
+		inputs["env_matrix"] = encodeEnvMatrix(o.envMatrix)
+	}
+	if tester.TestsLabel(o.tests) != "" {
+		inputs["tests"] = strings.Join(o.tests, ",")
+	}
+	if o.seed != 0 && o.seedInput {
+		inputs[SeedInput] = strconv.FormatInt(o.seed, 10)
+	}
+	return inputs
+}
+
+// ParseWorkflowInputs parses extra workflow inputs of the form
+// "node_version=20,trace_seconds=120"
+func ParseWorkflowInputs(spec string) (map[string]string, error) {
+	inputs := make(map[string]string)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(entry, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid input %q, expected key=value", entry)
+		}
+		if managedInputs[key] {
+			return nil, fmt.Errorf("input %q is set by spr itself", key)
+		}
+		inputs[key] = strings.TrimSpace(value)
+	}
+	return inputs, nil
+}
+
+// SetWorkflowInputs sets extra inputs passed to every workflow dispatch.
+// They are checked against the inputs the workflow declares before any run
+// is triggered.
+func (o *Orchestrator) SetWorkflowInputs(inputs map[string]string) {
+	o.workflowInputs = inputs
+}
+
+// validateWorkflowInputs fails when an extra input isn't declared by the
+// workflow, which GitHub would otherwise reject on every dispatch, and
+// checks whether the workflow takes a seed. If the workflow can't be read,
+// dispatches go ahead unseeded and GitHub has the final say.
+func (o *Orchestrator) validateWorkflowInputs(ctx context.Context) error {
+	if (len(o.workflowInputs) == 0 && o.seed == 0) || o.offline {
+		return nil
+	}
+	declared, err := o.client.WorkflowInputs(ctx, o.workflowFile)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Could not read the inputs of %s, not validating extra inputs or seeding runs: %v", o.workflowFile, err), "warning", logging.KeyStage, "workflow")
+		return nil
+	}
+	o.seedInput = declared[SeedInput]
+	if o.seed != 0 && !o.seedInput {
+		o.logMsg(fmt.Sprintf("Workflow %s doesn't declare a %s input, runs are not seeded", o.workflowFile, SeedInput), "warning", logging.KeyStage, "workflow")
+	}
+
+	var unknown []string
+	for key := range o.workflowInputs {
+		if !declared[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+	names := make([]string, 0, len(declared))
+	for key := range declared {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return fmt.Errorf("workflow %s doesn't declare inputs %s (declared: %s)", o.workflowFile, strings.Join(unknown, ", "), strings.Join(names, ", "))
+}
+
+// WorkflowInputs returns the names of the workflow_dispatch inputs a workflow
+// declares, read from its file on the default branch
+func (c *GitHubClient) WorkflowInputs(ctx context.Context, workflowFile string) (map[string]bool, error) {
+	var workflow struct {
+		Path string `json:"path"`
+	}
+	if err := c.getJSON(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/actions/workflows/%s", c.Owner, c.Repo, workflowFile), &workflow); err != nil {
+		return nil, fmt.Errorf("failed to get workflow: %w", err)
+	}
+
+	var file struct {
+		Content string `json:"content"`
+	}
+	if err := c.getJSON(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s", c.Owner, c.Repo, (&url.URL{Path: workflow.Path}).EscapedPath()), &file); err != nil {
+		return nil, fmt.Errorf("failed to get workflow file: %w", err)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.ReplaceAll(file.Content, "\n", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode workflow file: %w", err)
+	}
+	return parseDispatchInputs(data)
+}
+
+// parseDispatchInputs returns the workflow_dispatch input names of a
+// workflow definition
+func parseDispatchInputs(data []byte) (map[string]bool, error) {
+	var def struct {
+		On yaml.Node `yaml:"on"`
+	}
+	if err := yaml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow: %w", err)
+	}
+
+	// on: may be a string, a list of events or a map of event to config;
+	// only the last can declare inputs
+	var on map[string]struct {
+		Inputs map[string]any `yaml:"inputs"`
+	}
+	inputs := make(map[string]bool)
+	if def.On.Kind != yaml.MappingNode {
+		return inputs, nil
+	}
+	if err := def.On.Decode(&on); err != nil {
+		return nil, fmt.Errorf("failed to parse workflow triggers: %w", err)
+	}
+	for key := range on["workflow_dispatch"].Inputs {
+		inputs[key] = true
+	}
+	return inputs, nil
+}
+
+// getJSON GETs a GitHub API URL and decodes the response into v
+func (c *GitHubClient) getJSON(ctx context.Context, url string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := c.do(c.HTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return statusError(resp, body)
+	}
+	return json.Unmarshal(body, v)
+}
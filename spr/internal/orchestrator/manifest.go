@@ -0,0 +1,195 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ManifestFile is the name of the run manifest written to the output directory
+const ManifestFile = "run.json"
+
+// Stage is a package's progress through the pipeline. Stages are ordered,
+// so a package at a later stage has completed every earlier one.
+type Stage string
+
+const (
+	StagePending    Stage = "pending"
+	StageUploaded   Stage = "uploaded"
+	StageTriggered  Stage = "triggered"
+	StageDownloaded Stage = "downloaded"
+	StageAnalyzed   Stage = "analyzed"
+	StagePromoted   Stage = "promoted"
+)
+
+var stageOrder = map[Stage]int{
+	StagePending:    0,
+	StageUploaded:   1,
+	StageTriggered:  2,
+	StageDownloaded: 3,
+	StageAnalyzed:   4,
+	StagePromoted:   5,
+}
+
+// PackageState is the recorded progress of a single package
+type PackageState struct {
+	Name          string    `json:"name"`
+	Version       string    `json:"version"`
+	Stage         Stage     `json:"stage"`
+	WorkflowRunID int64     `json:"workflow_run_id,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// Manifest records per-package pipeline state in <outputDir>/run.json so an
+// interrupted `spr check` can resume where it left off, including re-attaching
+// to workflow runs that were still in flight. A nil *Manifest is valid and
+// records nothing.
+type Manifest struct {
+	mu   sync.Mutex
+	path string
+
+	StartedAt time.Time                `json:"started_at"`
+	UpdatedAt time.Time                `json:"updated_at"`
+	Packages  map[string]*PackageState `json:"packages"`            // keyed by name@version
+	Generator *version.Info            `json:"generator,omitempty"` // Build that last wrote the manifest
+}
+
+// LoadManifest loads the run manifest from outputDir, returning an empty
+// manifest if none exists yet
+func LoadManifest(outputDir string) (*Manifest, error) {
+	m := &Manifest{
+		path:     filepath.Join(outputDir, ManifestFile),
+		Packages: make(map[string]*PackageState),
+	}
+
+	data, err := os.ReadFile(m.path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			m.StartedAt = time.Now()
+			return m, nil
+		}
+		return nil, fmt.Errorf("failed to read run manifest: %w", err)
+	}
+
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("failed to parse run manifest: %w", err)
+	}
+	if m.Packages == nil {
+		m.Packages = make(map[string]*PackageState)
+	}
+	return m, nil
+}
+
+func manifestKey(name, version string) string {
+	return name + "@" + version
+}
+
+// Stage returns the recorded stage of a package (StagePending if unknown)
+func (m *Manifest) Stage(pkg models.Package) Stage {
+	if m == nil {
+		return StagePending
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.Packages[manifestKey(pkg.Name, pkg.Version)]; ok {
+		return state.Stage
+	}
+	return StagePending
+}
+
+// Reached reports whether a package has completed the given stage
+func (m *Manifest) Reached(pkg models.Package, stage Stage) bool {
+	return stageOrder[m.Stage(pkg)] >= stageOrder[stage]
+}
+
+// WorkflowRunID returns the workflow run recorded for a package, or 0
+func (m *Manifest) WorkflowRunID(pkg models.Package) int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if state, ok := m.Packages[manifestKey(pkg.Name, pkg.Version)]; ok {
+		return state.WorkflowRunID
+	}
+	return 0
+}
+
+// Advance moves a package to stage and saves the manifest. Stages never move
+// backwards; runID is recorded when non-zero.
+func (m *Manifest) Advance(pkg models.Package, stage Stage, runID int64) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := manifestKey(pkg.Name, pkg.Version)
+	state, ok := m.Packages[key]
+	if !ok {
+		state = &PackageState{Name: pkg.Name, Version: pkg.Version, Stage: StagePending}
+		m.Packages[key] = state
+	}
+	if stageOrder[stage] > stageOrder[state.Stage] {
+		state.Stage = stage
+	}
+	if runID != 0 {
+		state.WorkflowRunID = runID
+	}
+	state.UpdatedAt = time.Now()
+
+	return m.save()
+}
+
+// Reset moves a package back to stage, dropping any recorded workflow run.
+// Used when a resumed run ID turns out to be unusable.
+func (m *Manifest) Reset(pkg models.Package, stage Stage) error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.Packages[manifestKey(pkg.Name, pkg.Version)]
+	if !ok {
+		return nil
+	}
+	state.Stage = stage
+	state.WorkflowRunID = 0
+	state.UpdatedAt = time.Now()
+
+	return m.save()
+}
+
+// save writes the manifest atomically. Caller must hold m.mu.
+func (m *Manifest) save() error {
+	m.UpdatedAt = time.Now()
+	m.Generator = version.Stamp()
+
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal run manifest: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(m.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create manifest directory: %w", err)
+	}
+
+	// Write to a temp file and rename so a crash mid-write can't corrupt it
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return fmt.Errorf("failed to write run manifest: %w", err)
+	}
+	return nil
+}
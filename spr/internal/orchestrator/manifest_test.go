@@ -0,0 +1,42 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestManifestResume(t *testing.T) {
+	dir := t.TempDir()
+	pkg := models.Package{Name: "lodash", Version: "4.17.21"}
+
+	m, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, StagePending, m.Stage(pkg))
+
+	require.NoError(t, m.Advance(pkg, StageTriggered, 42))
+	// Stages never move backwards
+	require.NoError(t, m.Advance(pkg, StageUploaded, 0))
+
+	reloaded, err := LoadManifest(dir)
+	require.NoError(t, err)
+	assert.Equal(t, StageTriggered, reloaded.Stage(pkg))
+	assert.Equal(t, int64(42), reloaded.WorkflowRunID(pkg))
+	assert.True(t, reloaded.Reached(pkg, StageUploaded))
+	assert.False(t, reloaded.Reached(pkg, StageDownloaded))
+
+	require.NoError(t, reloaded.Reset(pkg, StageUploaded))
+	assert.Equal(t, StageUploaded, reloaded.Stage(pkg))
+	assert.Zero(t, reloaded.WorkflowRunID(pkg))
+}
+
+func TestNilManifest(t *testing.T) {
+	var m *Manifest
+	pkg := models.Package{Name: "lodash", Version: "4.17.21"}
+
+	assert.NoError(t, m.Advance(pkg, StageDownloaded, 1))
+	assert.Equal(t, StagePending, m.Stage(pkg))
+	assert.False(t, m.Reached(pkg, StageUploaded))
+}
@@ -5,9 +5,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
@@ -15,11 +16,54 @@ import (
 
 	"github.com/acheong08/hackeurope-spr/internal/aggregate"
 	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/artifacts"
+	"github.com/acheong08/hackeurope-spr/internal/confusion"
+	"github.com/acheong08/hackeurope-spr/internal/intel"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/popularity"
+	"github.com/acheong08/hackeurope-spr/internal/provenance"
 	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/internal/scoring"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/acheong08/hackeurope-spr/internal/telemetry"
 	"github.com/acheong08/hackeurope-spr/internal/tester"
+	"github.com/acheong08/hackeurope-spr/internal/ticketing"
+	"github.com/acheong08/hackeurope-spr/internal/typosquat"
+	"github.com/acheong08/hackeurope-spr/internal/version"
 	"github.com/acheong08/hackeurope-spr/pkg/models"
 )
 
+// rateLimitReserve is the number of GitHub API requests kept in reserve.
+// Workers pause until the rate-limit window resets once the remaining budget
+// drops below this, rather than failing mid-run.
+const rateLimitReserve = 50
+
+// rateLimitRetries is how often a request rejected by a rate limit is retried
+// by withRateLimit before the package fails
+const rateLimitRetries = 5
+
+// ErrCancelled is returned by RunPackages when the caller cancels the context.
+// The returned results still cover every package that finished beforehand.
+var ErrCancelled = errors.New("analysis cancelled")
+
+// ErrNotCached is the package error in offline mode for packages without
+// cached results
+var ErrNotCached = errors.New("no cached results")
+
+// cachedEvidence are the cached files besides the trace, diff and verdict
+// copied to the output on a cache hit
+var cachedEvidence = []string{"proxy.jsonl", "resources.json"}
+
+// ErrPartialFailure is returned by RunPackages in keep-going mode when some
+// packages failed; the remaining packages were still analyzed.
+var ErrPartialFailure = errors.New("analysis failed for some packages")
+
+// errFailFast is the cancellation cause used when one package fails and the
+// rest of the run is abandoned, as opposed to the caller cancelling
+var errFailFast = errors.New("cancelled due to previous error")
+
 // ProgressCallback is called when a package's artifacts are successfully copied
 type ProgressCallback func(pkgName, pkgVersion string, artifactCount int)
 
@@ -31,12 +75,152 @@ type Orchestrator struct {
 	client       *GitHubClient
 	workflowFile string
 	concurrency  int
+	stages       StageConcurrency // Per-stage limits; Workflow mirrors concurrency
+	aggregateSem chan struct{}    // Aggregation slots, created per run
 	timeout      time.Duration
 	progressCb   ProgressCallback
 	logCb        LogCallback
+	streamCb     analysis.StreamCallback
+	logger       *slog.Logger
 	baselinePath string
 	baseline     *aggregate.PerProcessStats
-	apiKey       string // API key for AI analysis
+	keyMode      aggregate.KeyMode // How processes are keyed in diffs
+	maxLine      int               // Longest trace line aggregated; 0 means aggregate.DefaultMaxLineSize
+	syscalls     aggregate.SyscallThreshold
+	access       aggregate.AccessThreshold
+	allowlist    *aggregate.Allowlist
+	intel        *intel.Enricher // Threat intel embedded in diffs
+	apiKey       string          // API key for AI analysis
+	interceptTLS bool            // Route sandbox traffic through the TLS-intercepting proxy
+
+	// AI provider, base URL and model — empty values use the provider defaults
+	aiProvider analysis.ProviderConfig
+	// Estimated cost cap for AI analysis in USD — zero means unlimited
+	maxAIBudget float64
+	aiUsage     analysis.Usage
+	// Longest activity list in AI prompts before sampling — zero uses the default
+	maxPromptIndicators int
+	// Backends the analysis agent queries with tools — nil uses the analyzer's default
+	analysisSources []analysis.Source
+	// Language of justifications — empty leaves them in English
+	analysisLanguage string
+	// Decide with the deterministic rules alone, never calling the LLM
+	rulesOnly bool
+
+	// Hash-chained results log — empty path disables it
+	resultsLog      string
+	resultsLogRunID string
+
+	// Baseline drift history — empty path disables it
+	driftPath      string
+	driftThreshold float64
+	driftRuns      int
+	driftMu        sync.Mutex
+	driftShares    []float64 // aggregate.RemovedShare of each diff in the run
+
+	// Opt-in anonymous telemetry — nil disables it
+	telemetry *telemetry.Client
+
+	// Detection forwarding to a SIEM — nil disables it
+	siem *siem.Client
+
+	// Tickets for flagged packages — nil disables them
+	tickets *ticketing.Filer
+
+	// Packages whose artifact copy panicked in the current run
+	copyMu       sync.Mutex
+	copyFailures map[models.Package]error
+
+	// Disk space preflight and cleanup of diffed traces
+	skipDiskCheck bool
+	keepTraces    bool
+
+	// Long-duration observation mode — zero observeMinutes disables it
+	observeMinutes int
+	clockSkew      string // faketime spec, e.g. "+30d x10"
+
+	// Environment matrix (locale/timezone/...) — each variant reruns the tests
+	envMatrix []EnvVariant
+
+	// Extra workflow_dispatch inputs passed to every run
+	workflowInputs map[string]string
+
+	// Seed for the sandbox's Math.random — zero, or a workflow without the
+	// seed input, leaves runs unseeded
+	seed      int64
+	seedInput bool
+
+	// Behavioral tests each run is limited to — nil runs all of them
+	tests []string
+
+	// Container images or GitHub Actions instead of npm packages, see
+	// SetImageMode and SetActionMode
+	imageMode  bool
+	actionMode bool
+
+	// Wakes run polling on workflow_run webhooks — nil polls every 15s
+	runWaker *RunWaker
+
+	// Traces uploaded by self-hosted runners, per run ID — empty disables it
+	ingestDir string
+
+	// Run manifest for resuming interrupted runs — nil disables it
+	manifest *Manifest
+
+	// Evidence quarantine for flagged packages — empty disables it
+	quarantineDir string
+
+	// Publishers whose provenance lets packages skip dynamic analysis — an
+	// empty policy disables provenance checks. trusted holds the packages
+	// the current run skipped.
+	trustedPublishers *provenance.Policy
+	skipTrusted       bool
+	trusted           map[models.Package]ProvenanceRecord
+
+	// Dependency confusion check of the whole tree — nil disables it.
+	// Findings block promotion.
+	confusionChecker  *confusion.Checker
+	confusionFindings []confusion.Result
+
+	// Typosquatting check of every dependency name — nil disables it.
+	// Findings are flagged, not blocked.
+	typosquatChecker  *typosquat.Checker
+	typosquatFindings []typosquat.Finding
+
+	// Minimum upstream age of every version of the tree — nil disables the
+	// gate. tooNew holds the versions the current run found younger.
+	agePolicy *AgePolicy
+	tooNew    []AgeRecord
+
+	// Static scan of the tarballs before behavioral analysis: tarballDir
+	// holds those the uploader kept, staticReports the scans of this run
+	// and staticFlagged those with findings
+	tarballDir    string
+	staticReports map[models.Package]*static.Report
+	staticFlagged []StaticRecord
+
+	// Combined risk score of each package scored in the current run
+	riskScores map[models.Package]scoring.Score
+
+	// Weekly download counts weighed by the rules — nil disables lookups
+	popularity *popularity.Client
+
+	// Per-package timeout overrides (name@version or name) and how often a
+	// failed or timed-out workflow is re-triggered
+	packageTimeouts map[string]time.Duration
+	retries         int
+
+	// Keep analyzing the remaining packages when one fails instead of
+	// cancelling the run
+	keepGoing bool
+
+	// Only use cached results, never trigger workflows
+	offline bool
+
+	// Durable artifact storage (S3/MinIO) — nil disables export
+	artifactSink       artifacts.Sink
+	artifactRunID      string
+	keepLocalArtifacts bool
 
 	// Safe registry — nil means promotion is disabled
 	safeUploader *registry.Uploader
@@ -60,12 +244,17 @@ func NewOrchestrator(token, owner, repo, workflowFile string, concurrency int, t
 		client:       NewGitHubClient(token, owner, repo),
 		workflowFile: workflowFile,
 		concurrency:  concurrency,
+		stages:       StageConcurrency{Workflow: concurrency}.WithDefaults(),
 		timeout:      timeout,
 		progressCb:   progressCb,
 		baselinePath: baselinePath,
 		apiKey:       apiKey,
 		safeUploader: safeUploader,
 		graph:        graph,
+		keyMode:      aggregate.KeyName,
+		syscalls:     aggregate.DefaultSyscallThreshold,
+		access:       aggregate.DefaultAccessThreshold,
+		logger:       slog.Default(),
 	}
 
 	// Load baseline if provided
@@ -86,33 +275,214 @@ func (o *Orchestrator) SetLogCallback(cb LogCallback) {
 	o.logCb = cb
 }
 
-// logMsg prints to console and optionally forwards via the log callback.
-func (o *Orchestrator) logMsg(message, level string) {
-	prefix := "[INFO]"
-	switch level {
-	case "success":
-		prefix = "[SUCCESS]"
-	case "warning":
-		prefix = "[WARN]"
-	case "error":
-		prefix = "[ERROR]"
+// SetAnalysisStreamCallback sets an optional callback receiving the output of
+// AI analyses as it is generated
+func (o *Orchestrator) SetAnalysisStreamCallback(cb analysis.StreamCallback) {
+	o.streamCb = cb
+}
+
+// SetInterceptTLS enables capture of HTTP(S) payload metadata through the
+// workflow's TLS-intercepting proxy.
+func (o *Orchestrator) SetInterceptTLS(enabled bool) {
+	o.interceptTLS = enabled
+}
+
+// SetObservation enables the long-duration observation test variant, keeping
+// the sandbox alive for the given number of minutes after import under a
+// faketime clock skew. The workflow poll timeout is extended accordingly.
+func (o *Orchestrator) SetObservation(minutes int, clockSkew string) {
+	o.observeMinutes = minutes
+	o.clockSkew = clockSkew
+}
+
+// SetTests limits every workflow run to the given behavioral tests (see
+// tester.ParseTests); nil runs all of them
+func (o *Orchestrator) SetTests(tests []string) {
+	o.tests = tests
+}
+
+// SetEnvMatrix sets the environment variants the install and import tests are
+// rerun under. Results are merged into each package's diff.json.
+func (o *Orchestrator) SetEnvMatrix(variants []EnvVariant) {
+	o.envMatrix = variants
+}
+
+// SetAIProvider selects the AI provider, base URL and model used for analysis.
+// Empty values fall back to the defaults in the analysis package; keyless
+// providers such as Ollama run without an API key.
+func (o *Orchestrator) SetAIProvider(provider, baseURL, model string) {
+	o.aiProvider = analysis.ProviderConfig{Provider: provider, BaseURL: baseURL, Model: model}
+}
+
+// SetAnalysisSources selects the backends the analysis agent can query with
+// tools, see analysis.ParseSources. Nil keeps the analyzer's default.
+func (o *Orchestrator) SetAnalysisSources(sources []analysis.Source) {
+	o.analysisSources = sources
+}
+
+// SetAnalysisLanguage renders justifications in language for review boards
+// that don't work in English, see analysis.Analyzer.SetLanguage
+func (o *Orchestrator) SetAnalysisLanguage(language string) {
+	o.analysisLanguage = language
+}
+
+// SetMaxAIBudget caps the estimated cost of AI analysis per run in USD; once
+// spent, the remaining packages are left unanalyzed and the run fails. Zero
+// disables the cap.
+func (o *Orchestrator) SetMaxAIBudget(usd float64) {
+	o.maxAIBudget = usd
+}
+
+// SetMaxPromptIndicators caps each list of a process's activity in AI
+// prompts; longer lists are sampled, see analysis.Analyzer.SetMaxIndicators.
+// Zero uses analysis.DefaultMaxIndicators.
+func (o *Orchestrator) SetMaxPromptIndicators(n int) {
+	o.maxPromptIndicators = n
+}
+
+// SetRulesOnly analyzes diffs with the deterministic rules alone, even when
+// an AI provider is configured: packages they don't flag are cleared without
+// LLM review, see analysis.NewRulesAnalyzer
+func (o *Orchestrator) SetRulesOnly(enabled bool) {
+	o.rulesOnly = enabled
+}
+
+// AIUsage returns the token usage and estimated cost of AI analysis so far
+func (o *Orchestrator) AIUsage() analysis.Usage {
+	return o.aiUsage
+}
+
+// aiConfig returns the AI provider configuration including the API key
+func (o *Orchestrator) aiConfig() analysis.ProviderConfig {
+	cfg := o.aiProvider
+	cfg.APIKey = o.apiKey
+	return cfg
+}
+
+// SetProcessKeyMode sets how processes are keyed when aggregating behavior
+// traces; baselines keyed differently are matched by bare process name
+func (o *Orchestrator) SetProcessKeyMode(mode aggregate.KeyMode) {
+	o.keyMode = mode
+}
+
+// SetMaxLineSize sets the longest behavior.jsonl or proxy.jsonl line
+// aggregated, in bytes. Longer lines are skipped with a warning.
+func (o *Orchestrator) SetMaxLineSize(n int) {
+	o.maxLine = n
+}
+
+// SetSyscallThreshold sets how far a syscall count must rise above the
+// baseline before it is kept in a diff
+func (o *Orchestrator) SetSyscallThreshold(t aggregate.SyscallThreshold) {
+	o.syscalls = t
+}
+
+// SetAccessThreshold sets how much more often than in the baseline a file or
+// command must be used before it is kept in a diff despite the baseline
+// having it
+func (o *Orchestrator) SetAccessThreshold(t aggregate.AccessThreshold) {
+	o.access = t
+}
+
+// SetAllowlist suppresses known-benign activity left in diffs after baseline
+// subtraction; nil disables it
+func (o *Orchestrator) SetAllowlist(a *aggregate.Allowlist) {
+	o.allowlist = a
+}
+
+// SetIntel embeds threat intel about the IPs and domains each diff contacted
+// in the diff; nil disables it
+func (o *Orchestrator) SetIntel(e *intel.Enricher) {
+	o.intel = e
+}
+
+// SetKeepGoing disables fail-fast: when a package fails, the others are still
+// analyzed, and RunPackages returns ErrPartialFailure once the successful
+// ones have been through AI analysis. Nothing is promoted to the safe
+// registry in that case.
+func (o *Orchestrator) SetKeepGoing(enabled bool) {
+	o.keepGoing = enabled
+}
+
+// SetOffline restricts analysis to results cached in analysis-results:
+// packages without a cached behavior.jsonl or diff.json fail with ErrNotCached instead of
+// triggering a workflow, and SBOM licenses are not fetched. Diffs and AI
+// analyses missing from the cache are still computed locally.
+func (o *Orchestrator) SetOffline(enabled bool) {
+	o.offline = enabled
+}
+
+// SetManifest enables resumable runs: package progress is recorded in the
+// manifest and packages it shows as already triggered or downloaded are
+// picked up where they left off.
+func (o *Orchestrator) SetManifest(m *Manifest) {
+	o.manifest = m
+}
+
+// advance records package progress in the run manifest, if one is set
+func (o *Orchestrator) advance(pkg models.Package, stage Stage, runID int64) {
+	if err := o.manifest.Advance(pkg, stage, runID); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to update run manifest for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "manifest")...)
+	}
+}
+
+// SetLogger sets the structured logger, typically one already tagged with a run ID.
+func (o *Orchestrator) SetLogger(logger *slog.Logger) {
+	o.logger = logger
+}
+
+// logMsg writes a structured log line and optionally forwards via the log callback.
+// attrs are slog key/value pairs (see pkgAttrs); lines without attrs are
+// tagged with the generic "orchestrator" stage.
+func (o *Orchestrator) logMsg(message, level string, attrs ...any) {
+	if len(attrs) == 0 {
+		attrs = []any{logging.KeyStage, "orchestrator"}
 	}
-	log.Printf("%s %s", prefix, message)
+	logging.Log(o.logger, message, level, attrs...)
 	if o.logCb != nil {
 		o.logCb(message, level)
 	}
 }
 
+// pkgAttrs returns log attributes identifying a package and the stage it is in
+func pkgAttrs(name, version, stage string) []any {
+	return []any{logging.KeyPackageID, name + "@" + version, logging.KeyStage, stage}
+}
+
 // RunPackages triggers workflows for all packages and collects results
 func (o *Orchestrator) RunPackages(ctx context.Context, packages []models.Package, tempDir string, outputDir string) ([]PackageResult, error) {
 	if len(packages) == 0 {
 		return nil, fmt.Errorf("no packages to analyze")
 	}
 
-	o.logMsg(fmt.Sprintf("Starting analysis of %d packages (max %d concurrent)", len(packages), o.concurrency), "info")
+	o.logMsg(fmt.Sprintf("Starting analysis of %d packages (max %d concurrent workflows, %d aggregations)", len(packages), o.concurrency, o.stages.Aggregate), "info")
+	start := time.Now()
+	report := telemetry.NewReport()
+	report.Packages = len(packages)
+	o.aggregateSem = make(chan struct{}, o.stages.Aggregate)
+	o.copyFailures = make(map[models.Package]error)
+
+	if err := o.preflightDiskSpace(packages, tempDir, outputDir); err != nil {
+		return nil, err
+	}
+	if err := ValidateObservation(o.observeMinutes, o.clockSkew); err != nil {
+		return nil, err
+	}
+	if err := o.validateWorkflowInputs(ctx); err != nil {
+		return nil, err
+	}
+
+	o.checkTyposquats(outputDir)
+	o.checkConfusion(ctx, outputDir)
+	o.checkAge(ctx, outputDir)
+	// Packages built by a trusted publisher skip the workflows entirely
+	packages = o.checkProvenance(ctx, packages, outputDir)
+	o.checkStatic(ctx, packages, outputDir)
 
 	// Create a cancellable context for early termination
-	ctx, cancel := context.WithCancel(ctx)
+	parentCtx := ctx
+	ctx, cancelCause := context.WithCancelCause(ctx)
+	cancel := func() { cancelCause(errFailFast) }
 	defer cancel()
 
 	// Create channels for work distribution and result collection
@@ -154,7 +524,7 @@ func (o *Orchestrator) RunPackages(ctx context.Context, packages []models.Packag
 		completed++
 		if result.Error != nil {
 			failed++
-			if !hasFailure {
+			if !hasFailure && !o.keepGoing {
 				hasFailure = true
 				// Cancel context on first failure (fail-fast)
 				cancel()
@@ -162,39 +532,141 @@ func (o *Orchestrator) RunPackages(ctx context.Context, packages []models.Packag
 		}
 		results = append(results, result)
 		if result.Error != nil {
-			o.logMsg(fmt.Sprintf("[%d/%d] %s@%s — FAILED: %v", completed, len(packages), result.Package.Name, result.Package.Version, result.Error), "error")
+			o.logMsg(fmt.Sprintf("[%d/%d] %s@%s — FAILED: %v", completed, len(packages), result.Package.Name, result.Package.Version, result.Error), "error", pkgAttrs(result.Package.Name, result.Package.Version, "workflow")...)
 		} else {
-			o.logMsg(fmt.Sprintf("[%d/%d] %s@%s — SUCCESS (%d artifacts)", completed, len(packages), result.Package.Name, result.Package.Version, len(result.Artifacts)), "success")
+			o.logMsg(fmt.Sprintf("[%d/%d] %s@%s — SUCCESS (%d artifacts)", completed, len(packages), result.Package.Name, result.Package.Version, len(result.Artifacts)), "success", pkgAttrs(result.Package.Name, result.Package.Version, "workflow")...)
+		}
+	}
+
+	// Caller cancelled: wait for in-progress copies so finished packages are
+	// available as partial results, but skip AI analysis and promotion
+	if parentCtx.Err() != nil {
+		copyWg.Wait()
+		var finished []models.Package
+		for _, result := range results {
+			if result.Success {
+				finished = append(finished, result.Package)
+			}
+		}
+		o.persistToCache(finished, outputDir)
+		o.logMsg(fmt.Sprintf("Analysis cancelled: %d/%d packages finished", len(finished), len(packages)), "warning")
+		return results, ErrCancelled
+	}
+
+	// Wait for all artifact copy goroutines to complete; a package whose copy
+	// panicked fails like one whose workflow did
+	o.logMsg("Waiting for artifact copies to complete...", "info")
+	copyWg.Wait()
+	o.logMsg("All artifacts copied successfully", "success")
+	for i := range results {
+		if err := o.copyFailures[results[i].Package]; err != nil && results[i].Error == nil {
+			results[i].Success = false
+			results[i].Error = err
+			failed++
 		}
 	}
 
 	// Check if we had any failures
+	failedPkgs := make(map[models.Package]error)
 	for _, result := range results {
-		if result.Error != nil {
+		if result.Error == nil {
+			continue
+		}
+		if !o.keepGoing {
+			report.Failed = failed
+			o.sendTelemetry(report, start)
 			return results, fmt.Errorf("analysis failed for %s@%s: %w", result.Package.Name, result.Package.Version, result.Error)
 		}
+		failedPkgs[result.Package] = result.Error
 	}
 
 	o.logMsg(fmt.Sprintf("Completed analysis: %d/%d packages successful", len(packages)-failed, len(packages)), "info")
+	report.Failed = failed
+
+	// Keep going: the rest of the pipeline only sees packages that finished
+	total := len(packages)
+	if len(failedPkgs) > 0 {
+		var succeeded []models.Package
+		for _, pkg := range packages {
+			if _, ok := failedPkgs[pkg]; !ok {
+				succeeded = append(succeeded, pkg)
+			}
+		}
+		packages = succeeded
+	}
 
-	// Wait for all artifact copy goroutines to complete
-	o.logMsg("Waiting for artifact copies to complete...", "info")
-	copyWg.Wait()
-	o.logMsg("All artifacts copied successfully", "success")
+	report.Time(telemetry.StageWorkflows, time.Since(start))
 
-	// Run AI security analysis if API key is provided
-	if o.apiKey != "" && o.baseline != nil {
-		if err := o.runAIAnalysis(ctx, packages, outputDir); err != nil {
+	// Run AI security analysis if a provider is configured
+	if (o.rulesOnly || o.aiConfig().Enabled()) && o.baseline != nil {
+		aiStart := time.Now()
+		err := o.runAIAnalysis(ctx, packages, outputDir)
+		report.Time(telemetry.StageAIAnalysis, time.Since(aiStart))
+		if err != nil {
+			o.sendTelemetry(report, start)
 			return results, fmt.Errorf("AI analysis failed: %w", err)
 		}
 	}
+	reportingStart := time.Now()
 
 	// Persist results to analysis-results/ cache so subsequent runs can skip workflows
 	o.persistToCache(packages, outputDir)
 
-	// Promote full dependency tree to safe registry if all packages passed
-	if err := o.promoteToSafeRegistry(ctx, packages, outputDir); err != nil {
-		return results, fmt.Errorf("safe registry promotion failed: %w", err)
+	// Record verdicts on the graph for exports and clients
+	o.annotateVerdicts(packages, outputDir)
+
+	// Rank flagged packages by reach, look for clean replacements, draft
+	// advisories for them, then archive them with the rest of the evidence
+	// before anything else can go wrong
+	o.reportBlastRadius(packages, outputDir)
+	o.suggestRemediations(ctx, packages, outputDir)
+	o.writeAdvisories(packages, outputDir)
+	o.quarantineFlagged(ctx, packages, outputDir)
+
+	// One number per package for CI gates, from everything found so far
+	o.scorePackages(packages, outputDir)
+
+	// Commit the verdicts to the tamper-evident log before anything acts on them
+	o.appendResultsLog(packages, outputDir)
+	o.checkBaselineDrift()
+	o.tallyVerdicts(report, packages, outputDir)
+	o.forwardToSIEM(ctx, packages, outputDir)
+	o.fileTickets(ctx, packages, outputDir)
+
+	// Export the dependency tree with verdicts as CycloneDX and SPDX SBOMs
+	o.writeSBOM(ctx, packages, outputDir)
+
+	// Promote full dependency tree to safe registry if all packages passed.
+	// A failed package leaves part of the tree unvetted, so nothing is promoted.
+	var promoteErr error
+	if len(failedPkgs) == 0 {
+		promoteErr = o.promoteToSafeRegistry(ctx, packages, outputDir)
+	} else if o.safeUploader != nil {
+		o.logMsg(fmt.Sprintf("Skipping safe registry promotion: %d packages failed analysis", len(failedPkgs)), "warning", logging.KeyStage, "promote")
+	}
+
+	// Ship artifacts to durable storage last, as the steps above read them
+	// from outputDir; a failed promotion shouldn't lose them
+	o.exportArtifacts(ctx, packages, outputDir)
+
+	// Raw traces are the bulk of the output and aren't needed once diffed
+	o.pruneTraces(packages, outputDir)
+
+	report.Time(telemetry.StageReporting, time.Since(reportingStart))
+	o.sendTelemetry(report, start)
+
+	if promoteErr != nil {
+		return results, fmt.Errorf("safe registry promotion failed: %w", promoteErr)
+	}
+
+	if len(failedPkgs) > 0 {
+		o.logMsg(fmt.Sprintf("%d/%d packages failed:", len(failedPkgs), total), "error")
+		for _, result := range results {
+			if result.Error != nil {
+				o.logMsg(fmt.Sprintf("  %s@%s: %v", result.Package.Name, result.Package.Version, result.Error), "error", pkgAttrs(result.Package.Name, result.Package.Version, "workflow")...)
+			}
+		}
+		return results, fmt.Errorf("%w: %d/%d packages failed", ErrPartialFailure, len(failedPkgs), total)
 	}
 
 	return results, nil
@@ -209,14 +681,14 @@ func (o *Orchestrator) worker(ctx context.Context, cancel context.CancelFunc, wo
 			resultChan <- PackageResult{
 				Package: pkg,
 				Success: false,
-				Error:   fmt.Errorf("cancelled due to previous error"),
+				Error:   context.Cause(ctx),
 			}
 			continue
 		default:
 		}
 
 		semaphore <- struct{}{} // Acquire
-		result := o.analyzePackage(ctx, pkg, tempDir, outputDir, copyWg)
+		result := o.runPackage(ctx, pkg, tempDir, outputDir, copyWg)
 		<-semaphore // Release
 
 		resultChan <- result
@@ -230,39 +702,35 @@ func (o *Orchestrator) analyzePackage(ctx context.Context, pkg models.Package, t
 	}
 
 	// 1. Check for cached behavior.jsonl file
-	normalizedPkgName := tester.NormalizePackageName(pkg.Name)
-	cacheDir := filepath.Join("analysis-results", fmt.Sprintf("%s@%s", normalizedPkgName, pkg.Version))
+	cacheDir := filepath.Join("analysis-results", naming.DirName(pkg.Name, pkg.Version))
 	cachedBehaviorPath := filepath.Join(cacheDir, "behavior.jsonl")
 
 	if _, err := os.Stat(cachedBehaviorPath); err == nil {
 		// Cached file exists, use it instead of running workflow
-		o.logMsg(fmt.Sprintf("Using cached behavior.jsonl for %s@%s", pkg.Name, pkg.Version), "info")
+		o.logMsg(fmt.Sprintf("Using cached behavior.jsonl for %s@%s", pkg.Name, pkg.Version), "info", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
 
 		// Copy cached file to tempDir for processing
-		artifactDir := filepath.Join(tempDir, fmt.Sprintf("%s@%s", normalizedPkgName, pkg.Version))
+		artifactDir := filepath.Join(tempDir, naming.DirName(pkg.Name, pkg.Version))
 		if err := os.MkdirAll(artifactDir, 0o755); err != nil {
 			result.Error = fmt.Errorf("failed to create artifact directory: %w", err)
 			return result
 		}
 
 		// Copy behavior.jsonl to artifact directory
-		data, err := os.ReadFile(cachedBehaviorPath)
-		if err != nil {
-			result.Error = fmt.Errorf("failed to read cached behavior.jsonl: %w", err)
-			return result
-		}
-
 		destPath := filepath.Join(artifactDir, "behavior.jsonl")
-		if err := os.WriteFile(destPath, data, 0o644); err != nil {
-			result.Error = fmt.Errorf("failed to write cached behavior.jsonl: %w", err)
+		if err := copyFile(cachedBehaviorPath, destPath); err != nil {
+			result.Error = fmt.Errorf("failed to copy cached behavior.jsonl: %w", err)
 			return result
 		}
 
 		// Generate diff.json if it doesn't exist in cache and baseline is available
 		if o.baseline != nil {
 			if _, err := os.Stat(filepath.Join(cacheDir, "diff.json")); os.IsNotExist(err) {
-				if err := o.generateDiff(cachedBehaviorPath); err != nil {
-					o.logMsg(fmt.Sprintf("Failed to generate diff for cached %s@%s: %v", pkg.Name, pkg.Version, err), "warning")
+				release := o.acquireAggregate()
+				err := o.generateDiff(cachedBehaviorPath)
+				release()
+				if err != nil {
+					o.logMsg(fmt.Sprintf("Failed to generate diff for cached %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
 				}
 			}
 		}
@@ -272,31 +740,54 @@ func (o *Orchestrator) analyzePackage(ctx context.Context, pkg models.Package, t
 		if diffData, err := os.ReadFile(cachedDiffPath); err == nil {
 			diffDestPath := filepath.Join(artifactDir, "diff.json")
 			if err := os.WriteFile(diffDestPath, diffData, 0o644); err != nil {
-				o.logMsg(fmt.Sprintf("Failed to copy cached diff.json: %v", err), "warning")
+				o.logMsg(fmt.Sprintf("Failed to copy cached diff.json: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
 			}
 		}
 
 		// Copy cached files to outputDir so AI analysis and emitPackageResults can find them
 		if outputDir != "" {
-			pkgOutputDir := filepath.Join(outputDir, fmt.Sprintf("%s@%s", normalizedPkgName, pkg.Version))
+			pkgOutputDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
 			if err := os.MkdirAll(pkgOutputDir, 0o755); err != nil {
-				o.logMsg(fmt.Sprintf("Failed to create output directory for cached %s@%s: %v", pkg.Name, pkg.Version, err), "warning")
+				o.logMsg(fmt.Sprintf("Failed to create output directory for cached %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
 			} else {
 				// Copy behavior.jsonl
-				if err := os.WriteFile(filepath.Join(pkgOutputDir, "behavior.jsonl"), data, 0o644); err != nil {
-					o.logMsg(fmt.Sprintf("Failed to copy cached behavior.jsonl to output: %v", err), "warning")
+				if err := copyFile(cachedBehaviorPath, filepath.Join(pkgOutputDir, "behavior.jsonl")); err != nil {
+					o.logMsg(fmt.Sprintf("Failed to copy cached behavior.jsonl to output: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
+				}
+				// Copy its index, if one was written
+				cachedIndexPath := aggregate.IndexPath(cachedBehaviorPath)
+				if _, err := os.Stat(cachedIndexPath); err == nil {
+					if err := copyFile(cachedIndexPath, filepath.Join(pkgOutputDir, "behavior.index.json")); err != nil {
+						o.logMsg(fmt.Sprintf("Failed to copy cached behavior.index.json to output: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
+					}
 				}
 				// Copy diff.json if it exists
 				if diffData, err := os.ReadFile(cachedDiffPath); err == nil {
 					if err := os.WriteFile(filepath.Join(pkgOutputDir, "diff.json"), diffData, 0o644); err != nil {
-						o.logMsg(fmt.Sprintf("Failed to copy cached diff.json to output: %v", err), "warning")
+						o.logMsg(fmt.Sprintf("Failed to copy cached diff.json to output: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
 					}
 				}
 				// Copy ai-analysis.json if it exists in cache
 				cachedAIPath := filepath.Join(cacheDir, "ai-analysis.json")
 				if aiData, err := os.ReadFile(cachedAIPath); err == nil {
 					if err := os.WriteFile(filepath.Join(pkgOutputDir, "ai-analysis.json"), aiData, 0o644); err != nil {
-						o.logMsg(fmt.Sprintf("Failed to copy cached ai-analysis.json to output: %v", err), "warning")
+						o.logMsg(fmt.Sprintf("Failed to copy cached ai-analysis.json to output: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
+					}
+				}
+				// Copy the network and resource evidence and env matrix
+				// traces, so reports on cached packages keep them
+				for _, name := range cachedEvidence {
+					cachedPath := filepath.Join(cacheDir, name)
+					if _, err := os.Stat(cachedPath); err != nil {
+						continue
+					}
+					if err := copyFile(cachedPath, filepath.Join(pkgOutputDir, name)); err != nil {
+						o.logMsg(fmt.Sprintf("Failed to copy cached %s to output: %v", name, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
+					}
+				}
+				if _, err := os.Stat(filepath.Join(cacheDir, "variants")); err == nil {
+					if err := copyDir(filepath.Join(cacheDir, "variants"), filepath.Join(pkgOutputDir, "variants")); err != nil {
+						o.logMsg(fmt.Sprintf("Failed to copy cached env variants to output: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
 					}
 				}
 
@@ -308,93 +799,135 @@ func (o *Orchestrator) analyzePackage(ctx context.Context, pkg models.Package, t
 		}
 
 		artifacts := []string{artifactDir}
+		o.advance(pkg, StageDownloaded, 0)
 
 		result.Success = true
 		result.Artifacts = artifacts
 		return result
 	}
 
-	// 2. Trigger workflow (no cache found)
-	inputs := map[string]string{
-		"package": pkg.Name,
-		"version": pkg.Version,
-	}
+	// Traces are deleted once diffed (see SetKeepTraces); the cached diff is
+	// still enough to skip the workflow
+	if _, err := os.Stat(filepath.Join(cacheDir, "diff.json")); err == nil {
+		o.logMsg(fmt.Sprintf("Using cached diff.json for %s@%s (trace pruned)", pkg.Name, pkg.Version), "info", pkgAttrs(pkg.Name, pkg.Version, "cache")...)
+		if outputDir != "" {
+			pkgOutputDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+			if err := copyDir(cacheDir, pkgOutputDir); err != nil {
+				result.Error = fmt.Errorf("failed to copy cached results: %w", err)
+				return result
+			}
+			if o.progressCb != nil {
+				o.progressCb(pkg.Name, pkg.Version, 1)
+			}
+		}
+		o.advance(pkg, StageDownloaded, 0)
 
-	triggerResp, err := o.client.TriggerWorkflow(ctx, o.workflowFile, inputs)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to trigger workflow: %w", err)
+		result.Success = true
+		result.Artifacts = []string{cacheDir}
 		return result
 	}
 
-	result.RunID = triggerResp.RunID
-	o.logMsg(fmt.Sprintf("Triggered workflow for %s@%s (run ID: %d)", pkg.Name, pkg.Version, triggerResp.RunID), "info")
+	// 2. Resume from the run manifest if a previous run already downloaded the artifacts
+	if outputDir != "" && o.manifest.Reached(pkg, StageDownloaded) {
+		pkgOutputDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+		behaviorPath := filepath.Join(pkgOutputDir, "behavior.jsonl")
+		if _, err := os.Stat(behaviorPath); err == nil {
+			o.logMsg(fmt.Sprintf("Resuming %s@%s: artifacts already downloaded", pkg.Name, pkg.Version), "info", pkgAttrs(pkg.Name, pkg.Version, "manifest")...)
+			release := o.acquireAggregate()
+			err := o.generateDiff(behaviorPath)
+			release()
+			if err != nil {
+				o.logMsg(fmt.Sprintf("Failed to generate diff for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "manifest")...)
+			}
+			if o.progressCb != nil {
+				o.progressCb(pkg.Name, pkg.Version, 1)
+			}
 
-	// 3. Poll for completion
-	run, err := o.pollWorkflowCompletion(ctx, triggerResp.RunID)
-	if err != nil {
-		result.Error = fmt.Errorf("failed to wait for completion: %w", err)
+			result.Success = true
+			result.RunID = o.manifest.WorkflowRunID(pkg)
+			result.Artifacts = []string{pkgOutputDir}
+			return result
+		}
+	}
+
+	if o.offline {
+		result.Error = fmt.Errorf("%w (offline)", ErrNotCached)
 		return result
 	}
 
-	// 4. Check conclusion
-	if run.Conclusion != "success" {
-		result.Error = fmt.Errorf("workflow failed with conclusion: %s", run.Conclusion)
+	// 3-5. Trigger (or re-attach to) a workflow run, wait for it and check its
+	// conclusion, re-triggering runs that fail or time out up to o.retries times
+	run, err := o.runWorkflow(ctx, pkg, &result)
+	if err != nil {
+		result.Error = err
 		return result
 	}
 
-	// 5. Download artifacts
+	// 6. Download artifacts
 	artifacts, err := o.downloadArtifacts(ctx, run.ID, pkg, tempDir)
 	if err != nil {
 		result.Error = fmt.Errorf("failed to download artifacts: %w", err)
 		return result
 	}
+	// Self-hosted runners upload their trace to the server instead. Listed
+	// last, so it takes precedence over a behavior.jsonl in the artifacts.
+	if dir, ok := o.ingestedArtifact(run.ID); ok {
+		o.logMsg(fmt.Sprintf("Using ingested trace for %s@%s from %s", pkg.Name, pkg.Version, dir), "info", pkgAttrs(pkg.Name, pkg.Version, "download")...)
+		artifacts = append(artifacts, dir)
+	}
 
-	// 5. Copy artifacts to output directory immediately (non-blocking, with context cancellation)
+	// 7. Copy artifacts to output directory in the background (with context
+	// cancellation). Waiting for an aggregation slot first keeps this worker,
+	// and so the next workflow run, back while aggregation is behind.
 	if len(artifacts) > 0 && outputDir != "" {
+		release := o.acquireAggregate()
 		copyWg.Add(1)
 		go func(ctx context.Context, artifactPaths []string, pkgName, pkgVersion string) {
 			defer copyWg.Done()
+			defer release()
+			defer o.recoverCopy(models.Package{Name: pkgName, Version: pkgVersion})
 
-			// Check if context is cancelled before starting
-			select {
-			case <-ctx.Done():
-				o.logMsg(fmt.Sprintf("Skipping artifact copy for %s@%s: context cancelled", pkgName, pkgVersion), "warning")
+			// Skip the copy if the run was abandoned after a failure. A caller
+			// cancellation still copies finished downloads as partial results.
+			if errors.Is(context.Cause(ctx), errFailFast) {
+				o.logMsg(fmt.Sprintf("Skipping artifact copy for %s@%s: context cancelled", pkgName, pkgVersion), "warning", pkgAttrs(pkgName, pkgVersion, "download")...)
 				return
-			default:
 			}
 
-			normalizedPkgName := tester.NormalizePackageName(pkgName)
-			pkgOutputDir := filepath.Join(outputDir, fmt.Sprintf("%s@%s", normalizedPkgName, pkgVersion))
+			pkgOutputDir := filepath.Join(outputDir, naming.DirName(pkgName, pkgVersion))
 			if err := os.MkdirAll(pkgOutputDir, 0o755); err != nil {
-				o.logMsg(fmt.Sprintf("Failed to create output directory for %s@%s: %v", pkgName, pkgVersion, err), "warning")
+				o.logMsg(fmt.Sprintf("Failed to create output directory for %s@%s: %v", pkgName, pkgVersion, err), "warning", pkgAttrs(pkgName, pkgVersion, "download")...)
 				return
 			}
 
 			for _, artifactPath := range artifactPaths {
-				// Check context before each file copy
-				select {
-				case <-ctx.Done():
-					o.logMsg(fmt.Sprintf("Aborting artifact copy for %s@%s: context cancelled", pkgName, pkgVersion), "warning")
+				// Check for fail-fast abort before each file copy
+				if errors.Is(context.Cause(ctx), errFailFast) {
+					o.logMsg(fmt.Sprintf("Aborting artifact copy for %s@%s: context cancelled", pkgName, pkgVersion), "warning", pkgAttrs(pkgName, pkgVersion, "download")...)
 					return
-				default:
-					// Copy contents of artifact directory directly into pkgOutputDir (flatten structure)
-					if err := copyDirContents(artifactPath, pkgOutputDir); err != nil {
-						o.logMsg(fmt.Sprintf("Failed to copy artifact %s: %v", artifactPath, err), "warning")
-					}
+				}
+				// Copy contents of artifact directory directly into pkgOutputDir
+				// (flatten structure), then free the temp copy
+				if err := copyDirContents(artifactPath, pkgOutputDir); err != nil {
+					o.logMsg(fmt.Sprintf("Failed to copy artifact %s: %v", artifactPath, err), "warning", pkgAttrs(pkgName, pkgVersion, "download")...)
+				} else if err := os.RemoveAll(artifactPath); err != nil {
+					o.logMsg(fmt.Sprintf("Failed to delete downloaded artifact %s: %v", artifactPath, err), "warning", pkgAttrs(pkgName, pkgVersion, "cleanup")...)
 				}
 			}
-			o.logMsg(fmt.Sprintf("Copied %d artifacts for %s@%s to output", len(artifactPaths), pkgName, pkgVersion), "info")
+			o.logMsg(fmt.Sprintf("Copied %d artifacts for %s@%s to output", len(artifactPaths), pkgName, pkgVersion), "info", pkgAttrs(pkgName, pkgVersion, "download")...)
 
 			// Generate diff.json if baseline is available
 			if o.baseline != nil {
 				behaviorPath := filepath.Join(pkgOutputDir, "behavior.jsonl")
 				if _, err := os.Stat(behaviorPath); err == nil {
 					if err := o.generateDiff(behaviorPath); err != nil {
-						o.logMsg(fmt.Sprintf("Failed to generate diff for %s@%s: %v", pkgName, pkgVersion, err), "warning")
+						o.logMsg(fmt.Sprintf("Failed to generate diff for %s@%s: %v", pkgName, pkgVersion, err), "warning", pkgAttrs(pkgName, pkgVersion, "download")...)
 					}
 				}
 			}
 
+			o.advance(models.Package{Name: pkgName, Version: pkgVersion}, StageDownloaded, 0)
+
 			// Notify via callback if provided (sends to WebSocket)
 			if o.progressCb != nil {
 				o.progressCb(pkgName, pkgVersion, len(artifactPaths))
@@ -407,51 +940,208 @@ func (o *Orchestrator) analyzePackage(ctx context.Context, pkg models.Package, t
 	return result
 }
 
-// pollWorkflowCompletion polls the workflow status until completed or timeout
-func (o *Orchestrator) pollWorkflowCompletion(ctx context.Context, runID int64) (*WorkflowRun, error) {
-	ctx, cancel := context.WithTimeout(ctx, o.timeout)
-	defer cancel()
+// runWorkflow re-attaches to a workflow run left in flight by a previous run,
+// or triggers a new one, and waits for it to succeed. Runs that fail or time
+// out are re-triggered up to o.retries times; a timed-out run is cancelled
+// first so it doesn't keep a runner busy. result.RunID is set to the last run.
+func (o *Orchestrator) runWorkflow(ctx context.Context, pkg models.Package, result *PackageResult) (*WorkflowRun, error) {
+	timeout := o.timeoutFor(pkg)
+
+	for attempt := 0; ; attempt++ {
+		runID := o.manifest.WorkflowRunID(pkg)
+		resumed := attempt == 0 && runID != 0 && o.manifest.Stage(pkg) == StageTriggered
+		if resumed {
+			o.logMsg(fmt.Sprintf("Resuming %s@%s: re-attaching to workflow run %d", pkg.Name, pkg.Version, runID), "info", append(pkgAttrs(pkg.Name, pkg.Version, "manifest"), "workflow_run_id", runID)...)
+		} else {
+			var inputs map[string]string
+			switch {
+			case o.imageMode:
+				inputs = o.targetInputs(ImageInput, ImageRef(pkg))
+			case o.actionMode:
+				inputs = o.targetInputs(ActionInput, ActionRef(pkg))
+			default:
+				inputs = o.packageInputs(pkg)
+			}
+
+			var triggerResp *WorkflowRunResponse
+			err := o.withRateLimit(ctx, func() (err error) {
+				triggerResp, err = o.client.TriggerWorkflow(ctx, o.workflowFile, inputs)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to trigger workflow: %w", err)
+			}
+
+			runID = triggerResp.RunID
+			o.advance(pkg, StageTriggered, runID)
+			o.logMsg(fmt.Sprintf("Triggered workflow for %s@%s (run ID: %d)", pkg.Name, pkg.Version, runID), "info", pkgAttrs(pkg.Name, pkg.Version, "workflow")...)
+		}
+		result.RunID = runID
+
+		run, err := o.pollWorkflowCompletion(ctx, runID, timeout)
+		if err != nil {
+			if ctx.Err() != nil {
+				if !errors.Is(context.Cause(ctx), errFailFast) {
+					// Caller cancelled the analysis: don't leave the run burning runner minutes
+					o.cancelWorkflowRun(pkg, runID)
+				}
+				return nil, fmt.Errorf("failed to wait for completion: %w", err)
+			}
+			err = fmt.Errorf("failed to wait for completion: %w", err)
+			if resumed {
+				// The recorded run is gone or unreachable; trigger afresh next time
+				o.manifest.Reset(pkg, StageUploaded)
+			} else if errors.Is(err, errWorkflowTimeout) && attempt < o.retries {
+				o.cancelWorkflowRun(pkg, runID)
+			}
+		} else if run.Conclusion != "success" {
+			// Don't re-attach to a failed run on the next attempt
+			o.manifest.Reset(pkg, StageUploaded)
+			err = fmt.Errorf("workflow failed with conclusion: %s", run.Conclusion)
+		} else {
+			return run, nil
+		}
+
+		if attempt >= o.retries {
+			return nil, err
+		}
+		o.logMsg(fmt.Sprintf("Workflow for %s@%s failed (%v), re-triggering (retry %d/%d)", pkg.Name, pkg.Version, err, attempt+1, o.retries), "warning", append(pkgAttrs(pkg.Name, pkg.Version, "workflow"), "workflow_run_id", runID)...)
+	}
+}
 
-	const pollInterval = 15 * time.Second
+// pollWorkflowCompletion polls the workflow status until completed or timeout
+func (o *Orchestrator) pollWorkflowCompletion(ctx context.Context, runID int64, timeout time.Duration) (*WorkflowRun, error) {
+	// The observation window and env matrix reruns happen inside the workflow,
+	// so allow for them on top of the normal timeout (roughly a minute per variant)
+	timeout += time.Duration(o.observeMinutes+len(o.envMatrix)) * time.Minute
+	// Tracked by hand rather than with a context deadline, so that pauses
+	// for the rate limit extend it instead of eating into the run's time
+	deadline := time.Now().Add(timeout)
+
+	const defaultPollInterval = 15 * time.Second
+	pollInterval := defaultPollInterval
+	var completed <-chan struct{}
+	if o.runWaker != nil {
+		var stop func()
+		completed, stop = o.runWaker.Wait(runID)
+		defer stop()
+		pollInterval = webhookPollInterval
+	}
 	attempt := 0
 
 	for {
-		select {
-		case <-ctx.Done():
-			if ctx.Err() == context.Canceled {
-				return nil, fmt.Errorf("workflow polling cancelled")
-			}
-			return nil, fmt.Errorf("timeout waiting for workflow completion")
-		default:
+		if ctx.Err() == context.Canceled {
+			return nil, fmt.Errorf("workflow polling cancelled")
+		}
+		if ctx.Err() != nil || !time.Now().Before(deadline) {
+			return nil, errWorkflowTimeout
 		}
 
 		attempt++
-		o.logMsg(fmt.Sprintf("Polling workflow run %d (attempt %d)", runID, attempt), "info")
+		o.logMsg(fmt.Sprintf("Polling workflow run %d (attempt %d)", runID, attempt), "info", logging.KeyStage, "workflow", "workflow_run_id", runID)
 
-		run, err := o.client.GetWorkflowRun(ctx, runID)
+		paused, err := o.waitForRateLimit(ctx)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get workflow status: %w", err)
+			return nil, err
 		}
+		deadline = deadline.Add(paused)
 
-		if run.Status == "completed" {
+		run, err := o.client.GetWorkflowRun(ctx, runID)
+		if errors.Is(err, ErrRateLimited) {
+			// Budget exhausted by another consumer of the token; retry next interval
+			o.logMsg(fmt.Sprintf("Rate limited while polling run %d, will retry", runID), "warning", logging.KeyStage, "workflow", "workflow_run_id", runID)
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to get workflow status: %w", err)
+		} else if run.Status == "completed" {
 			return run, nil
 		}
 
 		select {
 		case <-ctx.Done():
-			if ctx.Err() == context.Canceled {
-				return nil, fmt.Errorf("workflow polling cancelled")
-			}
-			return nil, fmt.Errorf("timeout waiting for workflow completion")
-		case <-time.After(pollInterval):
+			// Checked at the top of the loop
+		case <-time.After(min(pollInterval, time.Until(deadline))):
 			// Continue polling
+		case <-completed:
+			// Completion webhook received; poll now for the conclusion, and
+			// at the usual pace should the API lag behind the webhook
+			o.logMsg(fmt.Sprintf("Workflow run %d reported completed by webhook", runID), "info", logging.KeyStage, "workflow", "workflow_run_id", runID)
+			completed = nil
+			pollInterval = defaultPollInterval
 		}
 	}
 }
 
+// cancelWorkflowRun asks GitHub to stop a run abandoned by a cancelled
+// analysis. Uses a fresh context since the analysis context is already done.
+func (o *Orchestrator) cancelWorkflowRun(pkg models.Package, runID int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	attrs := append(pkgAttrs(pkg.Name, pkg.Version, "workflow"), "workflow_run_id", runID)
+	if err := o.client.CancelWorkflowRun(ctx, runID); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to cancel workflow run %d for %s@%s: %v", runID, pkg.Name, pkg.Version, err), "warning", attrs...)
+		return
+	}
+	o.manifest.Reset(pkg, StageUploaded)
+	o.logMsg(fmt.Sprintf("Cancelled workflow run %d for %s@%s", runID, pkg.Name, pkg.Version), "info", attrs...)
+}
+
+// withRateLimit calls fn once the rate limit allows, retrying it up to
+// rateLimitRetries times while GitHub rejects it with a rate limit. Retries
+// wait for Retry-After, or for the primary limit to reset.
+func (o *Orchestrator) withRateLimit(ctx context.Context, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if _, err := o.waitForRateLimit(ctx); err != nil {
+			return err
+		}
+		err := fn()
+		var limited *RateLimitError
+		if !errors.As(err, &limited) || attempt >= rateLimitRetries {
+			return err
+		}
+
+		o.logMsg(fmt.Sprintf("Rate limited by GitHub (status %d), retrying in %s (retry %d/%d)", limited.StatusCode, limited.RetryAfter.Round(time.Second), attempt+1, rateLimitRetries), "warning")
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled while waiting for rate limit: %w", err)
+		case <-time.After(limited.RetryAfter):
+		}
+	}
+}
+
+// waitForRateLimit pauses the calling worker while the GitHub API budget is
+// below rateLimitReserve, resuming once the rate-limit window resets. It
+// returns how long it paused.
+func (o *Orchestrator) waitForRateLimit(ctx context.Context) (time.Duration, error) {
+	limit, ok := o.client.RateLimit()
+	if !ok || limit.Remaining >= rateLimitReserve {
+		return 0, nil
+	}
+
+	wait := time.Until(limit.Reset)
+	if wait <= 0 {
+		return 0, nil
+	}
+	// Small buffer so we don't resume right on the boundary
+	wait += time.Second
+
+	o.logMsg(fmt.Sprintf("GitHub API rate limit low (%d/%d remaining), pausing for %s", limit.Remaining, limit.Limit, wait.Round(time.Second)), "warning")
+
+	select {
+	case <-ctx.Done():
+		return 0, fmt.Errorf("cancelled while waiting for rate limit reset")
+	case <-time.After(wait):
+		return wait, nil
+	}
+}
+
 // downloadArtifacts downloads and extracts all artifacts for a run
 func (o *Orchestrator) downloadArtifacts(ctx context.Context, runID int64, pkg models.Package, tempDir string) ([]string, error) {
-	artifacts, err := o.client.ListArtifacts(ctx, runID)
+	var artifacts []Artifact
+	err := o.withRateLimit(ctx, func() (err error) {
+		artifacts, err = o.client.ListArtifacts(ctx, runID)
+		return err
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to list artifacts: %w", err)
 	}
@@ -463,7 +1153,11 @@ func (o *Orchestrator) downloadArtifacts(ctx context.Context, runID int64, pkg m
 			continue
 		}
 
-		data, err := o.client.DownloadArtifact(ctx, artifact.ID)
+		var data []byte
+		err := o.withRateLimit(ctx, func() (err error) {
+			data, err = o.client.DownloadArtifact(ctx, artifact.ID)
+			return err
+		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to download artifact %s: %w", artifact.Name, err)
 		}
@@ -500,13 +1194,73 @@ func (o *Orchestrator) generateDiff(behaviorPath string) error {
 
 	// Process behavior.jsonl
 	aggregator := aggregate.NewProcessAggregator()
+	aggregator.SetKeyMode(o.keyMode)
+	aggregator.SetMaxLineSize(o.maxLine)
+	aggregator.EnableIndex()
 	result, err := aggregator.ProcessFile(behaviorPath, filepath.Base(filepath.Dir(behaviorPath)))
 	if err != nil {
 		return fmt.Errorf("failed to process behavior.jsonl: %w", err)
 	}
+	o.warnOversized(behaviorPath, aggregator.OversizedLines())
+
+	// Index the trace for drill-down (spr events) while it was read anyway
+	if err := aggregate.WriteEventIndex(aggregate.IndexPath(behaviorPath), aggregator.Index()); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to index %s: %v", behaviorPath, err), "warning", logging.KeyStage, "aggregate")
+	}
+
+	// Attach proxy-captured HTTP activity if the run used TLS interception
+	proxyPath := filepath.Join(filepath.Dir(behaviorPath), "proxy.jsonl")
+	if _, err := os.Stat(proxyPath); err == nil {
+		proxy := aggregate.NewProxyAggregator()
+		proxy.SetMaxLineSize(o.maxLine)
+		httpActivity, err := proxy.ProcessFile(proxyPath)
+		if err != nil {
+			return fmt.Errorf("failed to process proxy.jsonl: %w", err)
+		}
+		o.warnOversized(proxyPath, proxy.OversizedLines())
+		result.HTTPActivity = httpActivity
+	}
 
 	// Apply deduplication
-	deduped := aggregate.Dedup(result, o.baseline)
+	deduped := aggregate.DedupWithThresholds(result, o.baseline, o.syscalls, o.access)
+	if share, ok := aggregate.RemovedShare(result, deduped); ok {
+		o.observeRemoved(share)
+	}
+	o.allowlist.Apply(deduped)
+
+	// Attach sandbox resource usage (not deduped — it's a per-run measurement)
+	resourcesPath := filepath.Join(filepath.Dir(behaviorPath), "resources.json")
+	if _, err := os.Stat(resourcesPath); err == nil {
+		usage, err := aggregate.LoadResourceUsage(resourcesPath)
+		if err != nil {
+			return fmt.Errorf("failed to load resources.json: %w", err)
+		}
+		deduped.ResourceUsage = usage
+	}
+
+	// Merge in per-variant diffs from the environment matrix, if it ran
+	variants, err := o.diffVariants(filepath.Dir(behaviorPath))
+	if err != nil {
+		return fmt.Errorf("failed to diff env variants: %w", err)
+	}
+	deduped.Variants = variants
+
+	// Flag behavior that only shows up under one environment (evasion/targeting)
+	if len(variants) >= 2 {
+		perVariant := make(map[string]map[string]*aggregate.ProcessSummary, len(variants))
+		for name, variant := range variants {
+			perVariant[name] = variant.PerProcess
+		}
+		deduped.EnvironmentConditional = aggregate.CompareVariants(perVariant)
+	}
+
+	// Context on where the package connected to, variants included
+	o.intel.Enrich(deduped)
+
+	deduped.Generator = version.Stamp()
+	if tester.TestsLabel(o.tests) != "" {
+		deduped.Tests = o.tests
+	}
 
 	// Marshal to JSON
 	jsonBytes, err := json.MarshalIndent(deduped, "", "  ")
@@ -533,7 +1287,7 @@ func extractZip(data []byte, destDir string) error {
 		// Security check: prevent zip slip - validate BEFORE joining
 		// filepath.IsLocal checks: not empty, not absolute, no .., no reserved names
 		if !filepath.IsLocal(file.Name) {
-			log.Printf("    [Worker] Warning: skipping dangerous path in zip: %s\n", file.Name)
+			slog.Warn("Skipping dangerous path in zip", "path", file.Name)
 			continue
 		}
 
@@ -541,7 +1295,7 @@ func extractZip(data []byte, destDir string) error {
 
 		// Double-check the resolved path is within destination
 		if !isSubPath(path, destDir) {
-			log.Printf("    [Worker] Warning: skipping path that escapes destination: %s\n", file.Name)
+			slog.Warn("Skipping zip path that escapes destination", "path", file.Name)
 			continue
 		}
 
@@ -586,6 +1340,33 @@ func isSubPath(path, base string) bool {
 	return !filepath.IsAbs(rel) && rel != ".." && !filepath.HasPrefix(rel, "..")
 }
 
+// warnOversized logs the lines of a trace skipped for exceeding the line
+// size limit, as events the diff is missing
+func (o *Orchestrator) warnOversized(path string, lines int) {
+	if lines > 0 {
+		o.logMsg(fmt.Sprintf("Skipped %d lines of %s longer than the max line size", lines, path), "warning", logging.KeyStage, "aggregate")
+	}
+}
+
+// copyFile streams src to dst, so traces of any size are copied without
+// holding them in memory
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
 // copyDir recursively copies a directory
 func copyDir(src, dst string) error {
 	entries, err := os.ReadDir(src)
@@ -605,14 +1386,8 @@ func copyDir(src, dst string) error {
 			if err := copyDir(srcPath, dstPath); err != nil {
 				return err
 			}
-		} else {
-			data, err := os.ReadFile(srcPath)
-			if err != nil {
-				return err
-			}
-			if err := os.WriteFile(dstPath, data, 0o644); err != nil {
-				return err
-			}
+		} else if err := copyFile(srcPath, dstPath); err != nil {
+			return err
 		}
 	}
 
@@ -638,14 +1413,8 @@ func copyDirContents(src, dst string) error {
 			if err := copyDir(srcPath, dstPath); err != nil {
 				return err
 			}
-		} else {
-			data, err := os.ReadFile(srcPath)
-			if err != nil {
-				return err
-			}
-			if err := os.WriteFile(dstPath, data, 0o644); err != nil {
-				return err
-			}
+		} else if err := copyFile(srcPath, dstPath); err != nil {
+			return err
 		}
 	}
 
@@ -657,11 +1426,10 @@ func copyDirContents(src, dst string) error {
 // runs can skip the GitHub Actions workflow for these packages.
 func (o *Orchestrator) persistToCache(packages []models.Package, outputDir string) {
 	cacheRoot := "analysis-results"
-	filesToCache := []string{"behavior.jsonl", "diff.json", "ai-analysis.json"}
+	filesToCache := []string{"behavior.jsonl", "behavior.index.json", "proxy.jsonl", "resources.json", "diff.json", "ai-analysis.json"}
 
 	for _, pkg := range packages {
-		normalizedName := tester.NormalizePackageName(pkg.Name)
-		pkgKey := fmt.Sprintf("%s@%s", normalizedName, pkg.Version)
+		pkgKey := naming.DirName(pkg.Name, pkg.Version)
 		srcDir := filepath.Join(outputDir, pkgKey)
 		dstDir := filepath.Join(cacheRoot, pkgKey)
 
@@ -677,7 +1445,7 @@ func (o *Orchestrator) persistToCache(packages []models.Package, outputDir strin
 		}
 
 		if err := os.MkdirAll(dstDir, 0o755); err != nil {
-			o.logMsg(fmt.Sprintf("Failed to create cache directory for %s: %v", pkgKey, err), "warning")
+			o.logMsg(fmt.Sprintf("Failed to create cache directory for %s: %v", pkgKey, err), "warning", logging.KeyStage, "cache")
 			continue
 		}
 
@@ -685,36 +1453,54 @@ func (o *Orchestrator) persistToCache(packages []models.Package, outputDir strin
 			srcPath := filepath.Join(srcDir, fileName)
 			dstPath := filepath.Join(dstDir, fileName)
 
-			data, err := os.ReadFile(srcPath)
+			src, err := os.Stat(srcPath)
 			if err != nil {
 				continue // File doesn't exist in output — skip silently
 			}
 
 			// Skip if destination already exists and is same size
-			if info, err := os.Stat(dstPath); err == nil && info.Size() == int64(len(data)) {
+			if info, err := os.Stat(dstPath); err == nil && info.Size() == src.Size() {
 				continue
 			}
 
-			if err := os.WriteFile(dstPath, data, 0o644); err != nil {
-				o.logMsg(fmt.Sprintf("Failed to cache %s for %s: %v", fileName, pkgKey, err), "warning")
+			if err := copyFile(srcPath, dstPath); err != nil {
+				o.logMsg(fmt.Sprintf("Failed to cache %s for %s: %v", fileName, pkgKey, err), "warning", logging.KeyStage, "cache")
+			}
+		}
+
+		// Env matrix traces live in a subdirectory per variant
+		if _, err := os.Stat(filepath.Join(srcDir, "variants")); err == nil {
+			if err := copyDir(filepath.Join(srcDir, "variants"), filepath.Join(dstDir, "variants")); err != nil {
+				o.logMsg(fmt.Sprintf("Failed to cache env variants for %s: %v", pkgKey, err), "warning", logging.KeyStage, "cache")
 			}
 		}
 	}
 
-	o.logMsg("Persisted analysis results to cache", "info")
+	o.logMsg("Persisted analysis results to cache", "info", logging.KeyStage, "cache")
 }
 
 // runAIAnalysis runs AI security analysis on all packages with diffs
 func (o *Orchestrator) runAIAnalysis(ctx context.Context, packages []models.Package, outputDir string) error {
-	if o.apiKey == "" {
+	var analyzer *analysis.Analyzer
+	switch {
+	case o.rulesOnly:
+		analyzer = analysis.NewRulesAnalyzer(o.stages.AI)
+	case o.aiConfig().Enabled():
+		var err error
+		if analyzer, err = analysis.NewAnalyzer(o.aiConfig(), o.stages.AI); err != nil {
+			return fmt.Errorf("failed to create analyzer: %w", err)
+		}
+	default:
 		return nil
 	}
 
-	// Create analyzer with concurrency limit of 5
-	analyzer, err := analysis.NewAnalyzer(o.apiKey, 5)
-	if err != nil {
-		return fmt.Errorf("failed to create analyzer: %w", err)
+	analyzer.SetLogger(o.logger)
+	analyzer.SetMaxBudget(o.maxAIBudget)
+	analyzer.SetMaxIndicators(o.maxPromptIndicators)
+	if o.analysisSources != nil {
+		analyzer.SetSources(o.analysisSources...)
 	}
+	analyzer.SetLanguage(o.analysisLanguage)
 
 	// Chain log callback so analyzer logs go to WebSocket too
 	if o.logCb != nil {
@@ -722,12 +1508,16 @@ func (o *Orchestrator) runAIAnalysis(ctx context.Context, packages []models.Pack
 			o.logCb(message, level)
 		})
 	}
+	if o.streamCb != nil {
+		analyzer.SetStreamCallback(o.streamCb)
+	}
 
 	// Build list of packages to analyze
+	signals := o.packageSignals(ctx, packages)
 	var packagesToAnalyze []analysis.PackageInfo
+	var analyzed []models.Package
 	for _, pkg := range packages {
-		normalizedName := tester.NormalizePackageName(pkg.Name)
-		pkgOutputDir := filepath.Join(outputDir, fmt.Sprintf("%s@%s", normalizedName, pkg.Version))
+		pkgOutputDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
 		diffPath := filepath.Join(pkgOutputDir, "diff.json")
 
 		// Check if diff.json exists
@@ -736,20 +1526,41 @@ func (o *Orchestrator) runAIAnalysis(ctx context.Context, packages []models.Pack
 				Name:      pkg.Name,
 				Version:   pkg.Version,
 				OutputDir: pkgOutputDir,
+				Signals:   signals[pkg],
+				Image:     o.imageMode,
+				Action:    o.actionMode,
 			})
+			analyzed = append(analyzed, pkg)
 		}
 	}
 
+	// Static scans from before the behavioral analysis, see checkStatic
+	for i := range packagesToAnalyze {
+		packagesToAnalyze[i].Static = o.staticReports[analyzed[i]]
+	}
+
 	if len(packagesToAnalyze) == 0 {
-		o.logMsg("No packages with diff.json found for AI analysis", "info")
+		o.logMsg("No packages with diff.json found for AI analysis", "info", logging.KeyStage, "analysis")
 		return nil
 	}
 
-	o.logMsg(fmt.Sprintf("Running AI security analysis on %d packages...", len(packagesToAnalyze)), "info")
-	if err := analyzer.AnalyzePackages(ctx, packagesToAnalyze); err != nil {
+	o.logMsg(fmt.Sprintf("Running AI security analysis on %d packages...", len(packagesToAnalyze)), "info", logging.KeyStage, "analysis")
+	err := analyzer.AnalyzePackages(ctx, packagesToAnalyze)
+	usage := analyzer.Usage()
+	o.aiUsage.Add(usage)
+	if usage.PromptTokens > 0 || usage.CompletionTokens > 0 {
+		o.logMsg(fmt.Sprintf("AI analysis usage: %s", usage), "info", logging.KeyStage, "analysis")
+	}
+	if err != nil {
 		return err
 	}
 
+	for _, pkg := range packagesToAnalyze {
+		if _, err := os.Stat(filepath.Join(pkg.OutputDir, "ai-analysis.json")); err == nil {
+			o.advance(models.Package{Name: pkg.Name, Version: pkg.Version}, StageAnalyzed, 0)
+		}
+	}
+
 	return nil
 }
 
@@ -761,53 +1572,79 @@ func (o *Orchestrator) promoteToSafeRegistry(ctx context.Context, packages []mod
 		return nil
 	}
 
-	o.logMsg("Checking AI analysis results before promoting to safe registry...", "info")
+	// Nothing analyzed (every package trusted) proves nothing was promoted
+	alreadyPromoted := len(packages) > 0
+	for _, pkg := range packages {
+		if !o.manifest.Reached(pkg, StagePromoted) {
+			alreadyPromoted = false
+			break
+		}
+	}
+	if alreadyPromoted && o.manifest != nil {
+		o.logMsg("Dependency tree already promoted by a previous run, skipping", "info", logging.KeyStage, "promote")
+		return nil
+	}
+
+	o.logMsg("Checking AI analysis results before promoting to safe registry...", "info", logging.KeyStage, "promote")
 
 	var blocked []string
+	for _, finding := range o.confusionFindings {
+		blocked = append(blocked, fmt.Sprintf("%s: likely dependency confusion (%s)", finding.Package, finding.Kind))
+	}
+	for _, record := range o.tooNew {
+		if record.Action == AgeBlock {
+			blocked = append(blocked, fmt.Sprintf("%s@%s: published %s ago, under the minimum age of %s", record.Package, record.Version, record.Age, o.agePolicy.MinAge))
+		}
+	}
 
 	for _, pkg := range packages {
-		normalizedName := tester.NormalizePackageName(pkg.Name)
-		aiPath := filepath.Join(outputDir, fmt.Sprintf("%s@%s", normalizedName, pkg.Version), "ai-analysis.json")
-
-		data, err := os.ReadFile(aiPath)
+		assessment, err := loadAssessment(outputDir, pkg)
 		if err != nil {
-			if os.IsNotExist(err) {
-				// No analysis file → no anomalies detected → treat as safe
-				o.logMsg(fmt.Sprintf("%s@%s: no AI analysis (clean diff), treating as safe", pkg.Name, pkg.Version), "info")
-				continue
-			}
-			return fmt.Errorf("failed to read ai-analysis.json for %s@%s: %w", pkg.Name, pkg.Version, err)
+			return err
 		}
-
-		var assessment analysis.SecurityAssessment
-		if err := json.Unmarshal(data, &assessment); err != nil {
-			return fmt.Errorf("failed to parse ai-analysis.json for %s@%s: %w", pkg.Name, pkg.Version, err)
+		if assessment == nil {
+			// No analysis file → no anomalies detected → treat as safe
+			o.logMsg(fmt.Sprintf("%s@%s: no AI analysis (clean diff), treating as safe", pkg.Name, pkg.Version), "info", pkgAttrs(pkg.Name, pkg.Version, "promote")...)
+			continue
 		}
 
 		if assessment.IsMalicious {
 			blocked = append(blocked, fmt.Sprintf("%s@%s (confidence=%.2f): %s",
 				pkg.Name, pkg.Version, assessment.Confidence, assessment.Justification))
-			o.logMsg(fmt.Sprintf("BLOCKED %s@%s — %s", pkg.Name, pkg.Version, assessment.Justification), "error")
+			o.logMsg(fmt.Sprintf("BLOCKED %s@%s — %s", pkg.Name, pkg.Version, assessment.Justification), "error", pkgAttrs(pkg.Name, pkg.Version, "promote")...)
 		} else {
-			o.logMsg(fmt.Sprintf("%s@%s: safe (confidence=%.2f)", pkg.Name, pkg.Version, assessment.Confidence), "success")
+			o.logMsg(fmt.Sprintf("%s@%s: safe (confidence=%.2f)", pkg.Name, pkg.Version, assessment.Confidence), "success", pkgAttrs(pkg.Name, pkg.Version, "promote")...)
 		}
 	}
 
 	if len(blocked) > 0 {
-		o.logMsg(fmt.Sprintf("Promotion skipped — %d package(s) flagged as malicious:", len(blocked)), "warning")
+		o.logMsg(fmt.Sprintf("Promotion skipped — %d package(s) flagged:", len(blocked)), "warning", logging.KeyStage, "promote")
 		for _, b := range blocked {
-			o.logMsg(fmt.Sprintf("  - %s", b), "warning")
+			o.logMsg(fmt.Sprintf("  - %s", b), "warning", logging.KeyStage, "promote")
 		}
 		// Don't return an error — let the caller continue so it can
 		// emit results (e.g. red nodes in the frontend).
 		return nil
 	}
 
-	o.logMsg("All packages passed analysis — promoting full dependency tree to safe registry...", "success")
-	if err := o.safeUploader.UploadGraph(ctx, o.graph); err != nil {
+	graph := o.promotableGraph()
+	if held := len(o.graph.Nodes) - len(graph.Nodes); held > 0 {
+		o.logMsg(fmt.Sprintf("All packages passed analysis — promoting dependency tree to safe registry, %d version(s) under the minimum age withheld...", held), "success", logging.KeyStage, "promote")
+	} else {
+		o.logMsg("All packages passed analysis — promoting full dependency tree to safe registry...", "success", logging.KeyStage, "promote")
+	}
+	uploads, err := o.safeUploader.UploadMissing(ctx, graph)
+	if err != nil {
 		return fmt.Errorf("failed to promote packages to safe registry: %w", err)
 	}
+	o.logMsg(fmt.Sprintf("Promoted %d new packages, %d were already in the safe registry", uploads.Uploaded, uploads.Skipped), "info", logging.KeyStage, "promote")
+	if err := o.verifyPromotion(ctx, graph, outputDir, uploads); err != nil {
+		return err
+	}
 
-	o.logMsg("Successfully promoted dependency tree to safe registry", "success")
+	for _, pkg := range packages {
+		o.advance(pkg, StagePromoted, 0)
+	}
+	o.logMsg("Successfully promoted dependency tree to safe registry", "success", logging.KeyStage, "promote")
 	return nil
 }
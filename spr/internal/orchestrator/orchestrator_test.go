@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) { return f(r) }
+
+func TestRunPackagesKeepGoing(t *testing.T) {
+	// cached@1.0.0 is served from the analysis-results cache; broken@1.0.0
+	// needs a workflow run, which fails
+	t.Chdir(t.TempDir())
+	cached := models.Package{Name: "cached", Version: "1.0.0"}
+	broken := models.Package{Name: "broken", Version: "1.0.0"}
+	require.NoError(t, os.MkdirAll(filepath.Join("analysis-results", "cached@1.0.0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join("analysis-results", "cached@1.0.0", "behavior.jsonl"), []byte("{}\n"), 0o644))
+
+	newOrchestrator := func() *Orchestrator {
+		o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+		o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(*http.Request) (*http.Response, error) {
+			return nil, errors.New("GitHub unreachable")
+		})}
+		return o
+	}
+
+	t.Run("fail fast", func(t *testing.T) {
+		o := newOrchestrator()
+		results, err := o.RunPackages(context.Background(), []models.Package{broken, cached}, t.TempDir(), t.TempDir())
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, ErrPartialFailure)
+		assert.Contains(t, err.Error(), "broken@1.0.0")
+		assert.Len(t, results, 2)
+	})
+
+	t.Run("keep going", func(t *testing.T) {
+		o := newOrchestrator()
+		o.SetKeepGoing(true)
+		outputDir := t.TempDir()
+		results, err := o.RunPackages(context.Background(), []models.Package{broken, cached}, t.TempDir(), outputDir)
+		require.ErrorIs(t, err, ErrPartialFailure)
+		assert.Contains(t, err.Error(), "1/2 packages failed")
+
+		require.Len(t, results, 2)
+		for _, result := range results {
+			if result.Package == broken {
+				assert.Error(t, result.Error)
+			} else {
+				assert.NoError(t, result.Error)
+			}
+		}
+		assert.FileExists(t, filepath.Join(outputDir, "cached@1.0.0", "behavior.jsonl"))
+	})
+}
+
+func TestRunPackagesOffline(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cached := models.Package{Name: "cached", Version: "1.0.0"}
+	uncached := models.Package{Name: "uncached", Version: "1.0.0"}
+	require.NoError(t, os.MkdirAll(filepath.Join("analysis-results", "cached@1.0.0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join("analysis-results", "cached@1.0.0", "behavior.jsonl"), []byte("{}\n"), 0o644))
+
+	o := NewOrchestrator("", "", "", "analyze.yml", 2, time.Minute, nil, "", "", nil, nil)
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		t.Errorf("unexpected request to %s", r.URL)
+		return nil, errors.New("offline")
+	})}
+	o.SetOffline(true)
+	o.SetKeepGoing(true)
+
+	outputDir := t.TempDir()
+	results, err := o.RunPackages(context.Background(), []models.Package{cached, uncached}, t.TempDir(), outputDir)
+	require.ErrorIs(t, err, ErrPartialFailure)
+	require.Len(t, results, 2)
+	for _, result := range results {
+		if result.Package == uncached {
+			assert.ErrorIs(t, result.Error, ErrNotCached)
+		} else {
+			assert.NoError(t, result.Error)
+		}
+	}
+	assert.FileExists(t, filepath.Join(outputDir, "cached@1.0.0", "behavior.jsonl"))
+}
+
+func TestRunPackagesCachedEvidence(t *testing.T) {
+	t.Chdir(t.TempDir())
+	cached := models.Package{Name: "cached", Version: "1.0.0"}
+	writeFiles(t, "analysis-results", map[string]string{
+		"cached@1.0.0/behavior.jsonl":                        "{}\n",
+		"cached@1.0.0/proxy.jsonl":                           `{"url":"https://evil.example/"}` + "\n",
+		"cached@1.0.0/resources.json":                        "{}",
+		"cached@1.0.0/variants/tz-Asia-Tokyo/behavior.jsonl": "{}\n",
+	})
+
+	o := NewOrchestrator("", "", "", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.SetOffline(true)
+	outputDir := t.TempDir()
+	_, err := o.RunPackages(context.Background(), []models.Package{cached}, t.TempDir(), outputDir)
+	require.NoError(t, err)
+
+	pkgDir := filepath.Join(outputDir, "cached@1.0.0")
+	assert.FileExists(t, filepath.Join(pkgDir, "proxy.jsonl"))
+	assert.FileExists(t, filepath.Join(pkgDir, "resources.json"))
+	assert.FileExists(t, filepath.Join(pkgDir, "variants", "tz-Asia-Tokyo", "behavior.jsonl"))
+}
@@ -0,0 +1,80 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/popularity"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// popularityConcurrency bounds the download counts fetched in flight
+const popularityConcurrency = 8
+
+// SetPopularity looks up the weekly npm downloads of every analyzed package.
+// The counts are weighed by the rules together with install scripts and
+// network activity, annotated on the graph and shown in reports. Nil disables
+// the lookups.
+func (o *Orchestrator) SetPopularity(client *popularity.Client) {
+	o.popularity = client
+}
+
+// packageSignals looks up the download counts of packages and returns the
+// signals the rules weigh for each package whose count is known. Lookup
+// failures are logged; those packages are analyzed without signals.
+func (o *Orchestrator) packageSignals(ctx context.Context, packages []models.Package) map[models.Package]*aggregate.PackageSignals {
+	if o.popularity == nil || len(packages) == 0 {
+		return nil
+	}
+	if o.offline {
+		o.logMsg("Offline: skipping download count lookups", "warning", logging.KeyStage, "popularity")
+		return nil
+	}
+
+	signals := make(map[models.Package]*aggregate.PackageSignals, len(packages))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, popularityConcurrency)
+	for _, pkg := range packages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			downloads, err := o.popularity.WeeklyDownloads(ctx, pkg.Name)
+			if err != nil {
+				if !errors.Is(err, popularity.ErrNotFound) {
+					o.logMsg(fmt.Sprintf("Download count of %s unknown: %v", pkg.Name, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "popularity")...)
+				}
+				return
+			}
+			mu.Lock()
+			signals[pkg] = &aggregate.PackageSignals{WeeklyDownloads: downloads, InstallScript: o.hasInstallScript(pkg)}
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	for pkg, s := range signals {
+		o.annotate(pkg, models.AnnotationDownloads, int(s.WeeklyDownloads))
+	}
+	if err := o.popularity.Save(); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to cache download counts: %v", err), "warning", logging.KeyStage, "popularity")
+	}
+	o.logMsg(fmt.Sprintf("Download counts known for %d of %d packages", len(signals), len(packages)), "info", logging.KeyStage, "popularity")
+	return signals
+}
+
+// hasInstallScript reports whether the lockfile says pkg runs install scripts
+func (o *Orchestrator) hasInstallScript(pkg models.Package) bool {
+	if o.graph == nil {
+		return false
+	}
+	node, ok := o.graph.Nodes[pkg.Name+"@"+pkg.Version]
+	return ok && node.HasInstallScript
+}
@@ -0,0 +1,45 @@
+package orchestrator
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/popularity"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPackageSignals(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/downloads/point/last-week/evil-pkg" {
+			w.Write([]byte(`{"downloads":7}`))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	evil := models.Package{Name: "evil-pkg", Version: "1.0.0"}
+	private := models.Package{Name: "internal-only", Version: "2.0.0"}
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "evil-pkg@1.0.0", Name: "evil-pkg", Version: "1.0.0"}, HasInstallScript: true})
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "internal-only@2.0.0", Name: "internal-only", Version: "2.0.0"}})
+
+	client := popularity.NewClient(filepath.Join(t.TempDir(), "downloads.json"))
+	client.APIURL = srv.URL
+	o := &Orchestrator{graph: graph}
+	o.SetPopularity(client)
+
+	signals := o.packageSignals(t.Context(), []models.Package{evil, private})
+	require.Len(t, signals, 1)
+	assert.Equal(t, &aggregate.PackageSignals{WeeklyDownloads: 7, InstallScript: true}, signals[evil])
+
+	br := ComputeBlastRadius(graph, evil)
+	require.NotNil(t, br.WeeklyDownloads)
+	assert.Equal(t, int64(7), *br.WeeklyDownloads)
+	assert.Nil(t, ComputeBlastRadius(graph, private).WeeklyDownloads)
+}
@@ -0,0 +1,64 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// PromotionReportFile is written to the output directory after promoting the
+// dependency tree to the safe registry
+const PromotionReportFile = "promotion.json"
+
+// PromotionReport records what a promotion uploaded and whether every
+// promoted version can actually be installed from the safe registry
+type PromotionReport struct {
+	Registry      string                  `json:"registry"`
+	Packages      int                     `json:"packages"`
+	Uploaded      int                     `json:"uploaded"`
+	Skipped       int                     `json:"skipped"` // Already in the registry
+	Verified      int                     `json:"verified"`
+	Verifications []registry.Verification `json:"verifications"`
+}
+
+// verifyPromotion reads every package of the promoted graph back from the safe
+// registry and writes the promotion report. It fails when any version is
+// missing, unservable or doesn't match its integrity, as a publish answered
+// with 409 counts as success and can hide a partial promotion.
+func (o *Orchestrator) verifyPromotion(ctx context.Context, graph *models.DependencyGraph, outputDir string, uploads registry.UploadSummary) error {
+	o.logMsg("Verifying promoted packages can be resolved from the safe registry...", "info", logging.KeyStage, "promote")
+
+	verifications := o.safeUploader.VerifyGraph(ctx, graph)
+	report := PromotionReport{
+		Registry:      o.safeUploader.BaseURL + "/" + o.safeUploader.Owner,
+		Packages:      len(verifications),
+		Uploaded:      uploads.Uploaded,
+		Skipped:       uploads.Skipped,
+		Verifications: verifications,
+	}
+	for _, v := range verifications {
+		if v.OK() {
+			report.Verified++
+			continue
+		}
+		o.logMsg(fmt.Sprintf("Promotion of %s not verified: %s", v.ID, v.Error), "error", logging.KeyStage, "promote", logging.KeyPackageID, v.ID)
+	}
+
+	if data, err := json.MarshalIndent(report, "", "  "); err == nil {
+		if err := os.WriteFile(filepath.Join(outputDir, PromotionReportFile), data, 0o644); err != nil {
+			o.logMsg(fmt.Sprintf("Failed to write promotion report: %v", err), "warning", logging.KeyStage, "promote")
+		}
+	}
+
+	if failed := report.Packages - report.Verified; failed > 0 {
+		return fmt.Errorf("%d/%d promoted packages could not be verified in the safe registry", failed, report.Packages)
+	}
+	o.logMsg(fmt.Sprintf("Verified %d promoted packages in the safe registry", report.Verified), "success", logging.KeyStage, "promote")
+	return nil
+}
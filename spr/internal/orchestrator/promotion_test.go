@@ -0,0 +1,55 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyPromotion(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/packages/secure/npm/left-pad":
+			json.NewEncoder(w).Encode(map[string]any{"versions": map[string]any{
+				"1.3.0": map[string]any{"dist": map[string]any{"tarball": srv.URL + "/left-pad-1.3.0.tgz"}},
+			}})
+		case "/left-pad-1.3.0.tgz":
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"}})
+	safe := registry.NewUploader(srv.URL, "secure", "token")
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", safe, graph)
+
+	dir := t.TempDir()
+	require.NoError(t, o.verifyPromotion(t.Context(), o.graph, dir, registry.UploadSummary{Skipped: 1}))
+
+	// A version the registry never took (e.g. a publish answered with 409)
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "is-odd@3.0.1", Name: "is-odd", Version: "3.0.1"}})
+	assert.ErrorContains(t, o.verifyPromotion(t.Context(), o.graph, dir, registry.UploadSummary{Skipped: 1}), "1/2 promoted packages")
+
+	data, err := os.ReadFile(filepath.Join(dir, PromotionReportFile))
+	require.NoError(t, err)
+	var report PromotionReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, srv.URL+"/secure", report.Registry)
+	assert.Equal(t, 2, report.Packages)
+	assert.Equal(t, 1, report.Verified)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, "is-odd@3.0.1", report.Verifications[0].ID)
+	assert.False(t, report.Verifications[0].OK())
+}
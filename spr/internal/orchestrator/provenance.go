@@ -0,0 +1,148 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/provenance"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ProvenanceFile records the provenance checks of a run in its output
+// directory
+const ProvenanceFile = "provenance.json"
+
+// TrustedViaProvenance is the decision recorded for packages that skipped
+// dynamic analysis because a trusted publisher built them
+const TrustedViaProvenance = "trusted via provenance"
+
+// provenanceConcurrency bounds the attestation fetches in flight
+const provenanceConcurrency = 8
+
+// ProvenanceRecord is the outcome of checking one package's provenance
+type ProvenanceRecord struct {
+	Package   string                `json:"package"`
+	Version   string                `json:"version"`
+	Publisher *provenance.Publisher `json:"publisher,omitempty"`  // Nil when the provenance didn't verify
+	TrustedBy string                `json:"trusted_by,omitempty"` // The trusted publisher pattern it matched
+	Decision  string                `json:"decision"`             // TrustedViaProvenance, or "analyzed"
+	Error     string                `json:"error,omitempty"`
+}
+
+// ProvenanceReport is the content of ProvenanceFile
+type ProvenanceReport struct {
+	CheckedAt time.Time          `json:"checked_at"`
+	Packages  []ProvenanceRecord `json:"packages"`
+}
+
+// SetTrustedPublishers checks the provenance of every package before
+// analysis. Packages whose provenance verifies against a publisher the policy
+// trusts skip dynamic analysis when skip is set, and are reported as
+// TrustedViaProvenance; otherwise they are analyzed as usual, with the check
+// recorded. An empty policy disables the check.
+func (o *Orchestrator) SetTrustedPublishers(policy *provenance.Policy, skip bool) {
+	o.trustedPublishers = policy
+	o.skipTrusted = skip
+}
+
+// TrustedPackages returns the packages the last run skipped for trusted
+// provenance
+func (o *Orchestrator) TrustedPackages() map[models.Package]ProvenanceRecord {
+	return o.trusted
+}
+
+// checkProvenance verifies the provenance of each package against the
+// trusted publishers, records the outcomes in ProvenanceFile, and returns the
+// packages still to analyze. Every verified publisher is annotated on the
+// graph; packages whose check fails are analyzed, never trusted.
+func (o *Orchestrator) checkProvenance(ctx context.Context, packages []models.Package, outputDir string) []models.Package {
+	o.trusted = make(map[models.Package]ProvenanceRecord)
+	if o.trustedPublishers.Empty() {
+		return packages
+	}
+	if o.offline {
+		o.logMsg("Offline: skipping provenance checks, all packages are analyzed", "warning", logging.KeyStage, "provenance")
+		return packages
+	}
+
+	verifier := provenance.NewVerifier(npmRegistryURL)
+	report := ProvenanceReport{CheckedAt: time.Now().UTC(), Packages: make([]ProvenanceRecord, len(packages))}
+	publishers := make([]*provenance.Publisher, len(packages))
+	errs := make([]error, len(packages))
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, provenanceConcurrency)
+	for i, pkg := range packages {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			publishers[i], errs[i] = verifier.Verify(ctx, pkg.Name, pkg.Version, o.integrity(pkg))
+		}()
+	}
+	wg.Wait()
+
+	var remaining []models.Package
+	for i, pkg := range packages {
+		record := ProvenanceRecord{Package: pkg.Name, Version: pkg.Version, Decision: "analyzed"}
+		if err := errs[i]; err != nil {
+			record.Error = err.Error()
+			if !errors.Is(err, provenance.ErrNoProvenance) {
+				o.logMsg(fmt.Sprintf("Provenance of %s@%s did not verify: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "provenance")...)
+			}
+		} else {
+			record.Publisher = publishers[i]
+			record.TrustedBy, _ = o.trustedPublishers.Trusts(*publishers[i])
+			o.annotate(pkg, models.AnnotationProvenance, publishers[i].String())
+		}
+
+		if record.TrustedBy != "" && o.skipTrusted {
+			record.Decision = TrustedViaProvenance
+			o.trusted[pkg] = record
+			o.annotate(pkg, models.AnnotationVerdict, models.VerdictTrusted)
+			o.logMsg(fmt.Sprintf("%s@%s %s (%s), skipping dynamic analysis", pkg.Name, pkg.Version, TrustedViaProvenance, record.Publisher), "success", pkgAttrs(pkg.Name, pkg.Version, "provenance")...)
+		} else {
+			remaining = append(remaining, pkg)
+		}
+		report.Packages[i] = record
+	}
+	o.logMsg(fmt.Sprintf("%d of %d packages %s", len(o.trusted), len(packages), TrustedViaProvenance), "info", logging.KeyStage, "provenance")
+
+	if err := writeProvenanceReport(outputDir, &report); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write %s: %v", ProvenanceFile, err), "warning", logging.KeyStage, "provenance")
+	}
+	return remaining
+}
+
+// integrity returns the lockfile SRI hash of a package, if the graph has one
+func (o *Orchestrator) integrity(pkg models.Package) string {
+	if o.graph == nil {
+		return ""
+	}
+	if node, ok := o.graph.Nodes[pkg.Name+"@"+pkg.Version]; ok {
+		return node.Integrity
+	}
+	return ""
+}
+
+// writeProvenanceReport writes ProvenanceFile to outputDir
+func writeProvenanceReport(outputDir string, report *ProvenanceReport) error {
+	if outputDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, ProvenanceFile), data, 0o644)
+}
@@ -0,0 +1,119 @@
+package orchestrator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/provenance"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signedAttestations returns an attestations response for pkg, built and
+// signed by the release workflow of github.com/acme/lib
+func signedAttestations(t *testing.T, pkg models.Package, tarball []byte) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	san, err := url.Parse("https://github.com/acme/lib/.github/workflows/release.yml@refs/heads/main")
+	require.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour), URIs: []*url.URL{san}}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	sum := sha512.Sum512(tarball)
+	payload, err := json.Marshal(map[string]any{
+		"subject": []any{map[string]any{"name": "pkg:npm/" + pkg.Name + "@" + pkg.Version, "digest": map[string]string{"sha512": hex.EncodeToString(sum[:])}}},
+	})
+	require.NoError(t, err)
+	payloadType := "application/vnd.in-toto+json"
+	hash := sha256.Sum256(fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]any{"attestations": []any{map[string]any{
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"bundle": map[string]any{
+			"verificationMaterial": map[string]any{"certificate": map[string]string{"rawBytes": base64.StdEncoding.EncodeToString(der)}},
+			"dsseEnvelope": map[string]any{
+				"payload":     base64.StdEncoding.EncodeToString(payload),
+				"payloadType": payloadType,
+				"signatures":  []any{map[string]string{"sig": base64.StdEncoding.EncodeToString(sig)}},
+			},
+		},
+	}}})
+	require.NoError(t, err)
+	return data
+}
+
+func TestCheckProvenance(t *testing.T) {
+	tarball := []byte("lib tarball")
+	sum := sha512.Sum512(tarball)
+	trusted := models.Package{ID: "lib@1.0.0", Name: "lib", Version: "1.0.0"}
+	unsigned := models.Package{ID: "other@2.0.0", Name: "other", Version: "2.0.0"}
+
+	attestations := signedAttestations(t, trusted, tarball)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/-/npm/v1/attestations/lib@1.0.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(attestations)
+	}))
+	defer srv.Close()
+	oldRegistry := npmRegistryURL
+	npmRegistryURL = srv.URL
+	defer func() { npmRegistryURL = oldRegistry }()
+
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{Package: trusted, Integrity: "sha512-" + base64.StdEncoding.EncodeToString(sum[:])})
+	graph.AddNode(&models.PackageNode{Package: unsigned, Integrity: "sha512-" + base64.StdEncoding.EncodeToString(sum[:])})
+	policy, err := provenance.ParsePolicy("github.com/acme")
+	require.NoError(t, err)
+	packages := []models.Package{trusted, unsigned}
+
+	// Recorded only: everything is still analyzed
+	o := &Orchestrator{graph: graph}
+	o.SetTrustedPublishers(policy, false)
+	assert.Equal(t, packages, o.checkProvenance(t.Context(), packages, ""))
+	assert.Empty(t, o.TrustedPackages())
+	assert.Equal(t, "github.com/acme/lib/.github/workflows/release.yml@refs/heads/main", graph.Nodes[trusted.ID].StringAnnotation(models.AnnotationProvenance))
+
+	outputDir := t.TempDir()
+	o.SetTrustedPublishers(policy, true)
+	assert.Equal(t, []models.Package{unsigned}, o.checkProvenance(t.Context(), packages, outputDir))
+	require.Contains(t, o.TrustedPackages(), trusted)
+	assert.Equal(t, "github.com/acme", o.TrustedPackages()[trusted].TrustedBy)
+	assert.Equal(t, models.VerdictTrusted, graph.Nodes[trusted.ID].StringAnnotation(models.AnnotationVerdict))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, ProvenanceFile))
+	require.NoError(t, err)
+	var report ProvenanceReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	require.Len(t, report.Packages, 2)
+	assert.Equal(t, TrustedViaProvenance, report.Packages[0].Decision)
+	assert.Equal(t, "analyzed", report.Packages[1].Decision)
+	assert.Equal(t, provenance.ErrNoProvenance.Error(), report.Packages[1].Error)
+
+	// An untrusting policy checks nothing
+	o.SetTrustedPublishers(nil, true)
+	assert.Equal(t, packages, o.checkProvenance(t.Context(), packages, t.TempDir()))
+	assert.Empty(t, o.TrustedPackages())
+}
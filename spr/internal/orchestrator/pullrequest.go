@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// FilePullRequest describes a pull request changing a single file
+type FilePullRequest struct {
+	Path          string // File path in the repository
+	Content       []byte // New file content
+	Branch        string // Head branch, created from Base
+	Base          string // Base branch; empty uses the repository's default branch
+	CommitMessage string
+	Title         string
+	Body          string
+}
+
+// pullRequestResponse is the subset of a created pull request that is used
+type pullRequestResponse struct {
+	HTMLURL string `json:"html_url"`
+}
+
+// OpenFilePullRequest commits a single file change to a new branch of the
+// client's repository and opens a pull request for it, returning its URL
+func (c *GitHubClient) OpenFilePullRequest(ctx context.Context, pr FilePullRequest) (string, error) {
+	repoURL := fmt.Sprintf("https://api.github.com/repos/%s/%s", c.Owner, c.Repo)
+
+	base := pr.Base
+	if base == "" {
+		var repo struct {
+			DefaultBranch string `json:"default_branch"`
+		}
+		if err := c.apiJSON(ctx, http.MethodGet, repoURL, nil, &repo); err != nil {
+			return "", fmt.Errorf("failed to get repository: %w", err)
+		}
+		base = repo.DefaultBranch
+	}
+
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	if err := c.apiJSON(ctx, http.MethodGet, repoURL+"/git/ref/heads/"+escapeRef(base), nil, &ref); err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", base, err)
+	}
+	if err := c.apiJSON(ctx, http.MethodPost, repoURL+"/git/refs", map[string]string{
+		"ref": "refs/heads/" + pr.Branch,
+		"sha": ref.Object.SHA,
+	}, nil); err != nil {
+		return "", fmt.Errorf("failed to create branch %s: %w", pr.Branch, err)
+	}
+
+	// Updating an existing file requires its blob SHA
+	contentsURL := repoURL + "/contents/" + escapeRef(pr.Path)
+	var existing struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.apiJSON(ctx, http.MethodGet, contentsURL+"?ref="+url.QueryEscape(base), nil, &existing); err != nil {
+		return "", fmt.Errorf("failed to get %s: %w", pr.Path, err)
+	}
+	if err := c.apiJSON(ctx, http.MethodPut, contentsURL, map[string]string{
+		"message": pr.CommitMessage,
+		"content": base64.StdEncoding.EncodeToString(pr.Content),
+		"sha":     existing.SHA,
+		"branch":  pr.Branch,
+	}, nil); err != nil {
+		return "", fmt.Errorf("failed to commit %s: %w", pr.Path, err)
+	}
+
+	var created pullRequestResponse
+	if err := c.apiJSON(ctx, http.MethodPost, repoURL+"/pulls", map[string]string{
+		"title": pr.Title,
+		"body":  pr.Body,
+		"head":  pr.Branch,
+		"base":  base,
+	}, &created); err != nil {
+		return "", fmt.Errorf("failed to open pull request: %w", err)
+	}
+	return created.HTMLURL, nil
+}
+
+// apiJSON sends a GitHub API request with an optional JSON payload and
+// decodes a successful response into out, if non-nil
+func (c *GitHubClient) apiJSON(ctx context.Context, method, rawURL string, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return fmt.Errorf("failed to marshal request: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, body)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.do(c.HTTPClient, req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return statusError(resp, respBody)
+	}
+	if out != nil {
+		if err := json.Unmarshal(respBody, out); err != nil {
+			return fmt.Errorf("failed to parse response: %w", err)
+		}
+	}
+	return nil
+}
+
+// escapeRef escapes each segment of a slash-separated branch name or path
+func escapeRef(ref string) string {
+	segments := strings.Split(ref, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
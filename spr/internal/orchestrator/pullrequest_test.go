@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenFilePullRequest(t *testing.T) {
+	var calls []string
+	var committed map[string]string
+	client := NewGitHubClient("token", "owner", "repo")
+	client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls = append(calls, r.Method+" "+r.URL.RequestURI())
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+		body := "{}"
+		status := http.StatusOK
+		switch r.Method + " " + r.URL.Path {
+		case "GET /repos/owner/repo":
+			body = `{"default_branch":"main"}`
+		case "GET /repos/owner/repo/git/ref/heads/main":
+			body = `{"object":{"sha":"base-sha"}}`
+		case "POST /repos/owner/repo/git/refs":
+			status = http.StatusCreated
+		case "GET /repos/owner/repo/contents/app/package.json":
+			body = `{"sha":"blob-sha"}`
+		case "PUT /repos/owner/repo/contents/app/package.json":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&committed))
+		case "POST /repos/owner/repo/pulls":
+			status = http.StatusCreated
+			body = `{"html_url":"https://github.com/owner/repo/pull/1"}`
+		default:
+			status = http.StatusNotFound
+		}
+		return &http.Response{StatusCode: status, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(body))}, nil
+	})}
+
+	url, err := client.OpenFilePullRequest(context.Background(), FilePullRequest{
+		Path:          "app/package.json",
+		Content:       []byte(`{"overrides":{}}`),
+		Branch:        "spr-fix",
+		CommitMessage: "Pin",
+		Title:         "Pin",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "https://github.com/owner/repo/pull/1", url)
+	assert.Equal(t, []string{
+		"GET /repos/owner/repo",
+		"GET /repos/owner/repo/git/ref/heads/main",
+		"POST /repos/owner/repo/git/refs",
+		"GET /repos/owner/repo/contents/app/package.json?ref=main",
+		"PUT /repos/owner/repo/contents/app/package.json",
+		"POST /repos/owner/repo/pulls",
+	}, calls)
+	assert.Equal(t, "blob-sha", committed["sha"])
+	assert.Equal(t, "spr-fix", committed["branch"])
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte(`{"overrides":{}}`)), committed["content"])
+}
+
+func TestOpenFilePullRequestError(t *testing.T) {
+	client := NewGitHubClient("token", "owner", "repo")
+	client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusForbidden, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"message":"denied"}`))}, nil
+	})}
+
+	_, err := client.OpenFilePullRequest(context.Background(), FilePullRequest{Path: "package.json", Branch: "b", Base: "main"})
+	assert.ErrorContains(t, err, "failed to resolve main")
+}
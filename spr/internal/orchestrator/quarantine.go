@@ -0,0 +1,334 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// EvidenceFile is the name of the record written into each quarantine entry
+const EvidenceFile = "evidence.json"
+
+// npmRegistryURL is where metadata snapshots are taken from (overridden in tests)
+var npmRegistryURL = "https://registry.npmjs.org"
+
+var quarantineHTTPClient = breaker.Client(breaker.Npm, 60*time.Second)
+
+// EvidenceRecord describes a quarantine entry: what was captured, from where,
+// and the hashes of every file so tampering can be detected later
+type EvidenceRecord struct {
+	Package       string    `json:"package"`
+	Version       string    `json:"version"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+	TarballURL    string    `json:"tarball_url"`
+	// Integrity is the lockfile SRI hash; IntegrityVerified reports whether
+	// the archived tarball matches it
+	Integrity         string                       `json:"integrity,omitempty"`
+	IntegrityVerified bool                         `json:"integrity_verified"`
+	Assessment        *analysis.SecurityAssessment `json:"assessment,omitempty"`
+	Files             []EvidenceHash               `json:"files"`
+	// Errors lists evidence that could not be captured, e.g. a tarball that
+	// was already unpublished upstream
+	Errors    []string      `json:"errors,omitempty"`
+	Generator *version.Info `json:"generator,omitempty"`
+}
+
+// EvidenceHash holds the hashes of one quarantined file
+type EvidenceHash struct {
+	Path   string `json:"path"` // Relative to the quarantine entry
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+	SHA512 string `json:"sha512"`
+}
+
+// SetQuarantineDir enables evidence quarantine: packages flagged as malicious
+// have their tarball, registry metadata and analysis artifacts archived
+// read-only under dir. An empty dir disables it.
+func (o *Orchestrator) SetQuarantineDir(dir string) {
+	o.quarantineDir = dir
+}
+
+// loadAssessment reads a package's ai-analysis.json. It returns nil without
+// an error when the package has no analysis (clean diff).
+func loadAssessment(outputDir string, pkg models.Package) (*analysis.SecurityAssessment, error) {
+	aiPath := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version), "ai-analysis.json")
+
+	data, err := os.ReadFile(aiPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read ai-analysis.json for %s@%s: %w", pkg.Name, pkg.Version, err)
+	}
+
+	var assessment analysis.SecurityAssessment
+	if err := json.Unmarshal(data, &assessment); err != nil {
+		return nil, fmt.Errorf("failed to parse ai-analysis.json for %s@%s: %w", pkg.Name, pkg.Version, err)
+	}
+	return &assessment, nil
+}
+
+// quarantineFlagged archives evidence for every package flagged as malicious.
+// Failures are logged rather than returned so they never block the run.
+func (o *Orchestrator) quarantineFlagged(ctx context.Context, packages []models.Package, outputDir string) {
+	if o.quarantineDir == "" {
+		return
+	}
+
+	for _, pkg := range packages {
+		assessment, err := loadAssessment(outputDir, pkg)
+		if err != nil {
+			o.logMsg(fmt.Sprintf("Skipping quarantine check: %v", err), "warning", pkgAttrs(pkg.Name, pkg.Version, "quarantine")...)
+			continue
+		}
+		if assessment == nil || !assessment.IsMalicious {
+			continue
+		}
+
+		dir, err := o.quarantinePackage(ctx, pkg, outputDir, assessment)
+		if err != nil {
+			o.logMsg(fmt.Sprintf("Failed to quarantine %s@%s: %v", pkg.Name, pkg.Version, err), "error", pkgAttrs(pkg.Name, pkg.Version, "quarantine")...)
+			continue
+		}
+		if dir == "" {
+			o.logMsg(fmt.Sprintf("%s@%s already quarantined", pkg.Name, pkg.Version), "info", pkgAttrs(pkg.Name, pkg.Version, "quarantine")...)
+			continue
+		}
+		o.logMsg(fmt.Sprintf("Quarantined evidence for %s@%s in %s", pkg.Name, pkg.Version, dir), "success", pkgAttrs(pkg.Name, pkg.Version, "quarantine")...)
+	}
+}
+
+// quarantinePackage captures the package's tarball, full registry metadata
+// and analysis artifacts into <quarantineDir>/<name>@<version>. Entries are
+// write-once: evidence is assembled in a staging directory, renamed into
+// place, then made read-only, and an existing entry is never touched (the
+// returned dir is empty in that case).
+func (o *Orchestrator) quarantinePackage(ctx context.Context, pkg models.Package, outputDir string, assessment *analysis.SecurityAssessment) (string, error) {
+	pkgKey := naming.DirName(pkg.Name, pkg.Version)
+	finalDir := filepath.Join(o.quarantineDir, pkgKey)
+	if _, err := os.Stat(finalDir); err == nil {
+		return "", nil
+	}
+
+	if err := os.MkdirAll(o.quarantineDir, 0o755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	staging, err := os.MkdirTemp(o.quarantineDir, ".staging-"+pkgKey+"-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create staging directory: %w", err)
+	}
+	defer os.RemoveAll(staging) // No-op once renamed into place
+
+	node := &models.PackageNode{Package: pkg}
+	if o.graph != nil {
+		if n, ok := o.graph.Nodes[pkg.Name+"@"+pkg.Version]; ok {
+			node = n
+		}
+	}
+
+	record := EvidenceRecord{
+		Package:       pkg.Name,
+		Version:       pkg.Version,
+		QuarantinedAt: time.Now().UTC(),
+		TarballURL:    registry.TarballURL(node),
+		Integrity:     node.Integrity,
+		Assessment:    assessment,
+		Generator:     version.Stamp(),
+	}
+
+	// Exact tarball as published, checked against the lockfile integrity
+	tarball, err := fetchEvidence(ctx, record.TarballURL)
+	if err != nil {
+		record.Errors = append(record.Errors, fmt.Sprintf("tarball: %v", err))
+	} else {
+		if err := os.WriteFile(filepath.Join(staging, path.Base(record.TarballURL)), tarball, 0o644); err != nil {
+			return "", err
+		}
+		if record.Integrity != "" {
+			record.IntegrityVerified, err = verifyIntegrity(tarball, record.Integrity)
+			if err != nil {
+				record.Errors = append(record.Errors, fmt.Sprintf("integrity: %v", err))
+			}
+		}
+	}
+
+	// Full packument rather than the single version, so maintainers, publish
+	// times and dist-tags are preserved too
+	metadata, err := fetchEvidence(ctx, npmRegistryURL+"/"+naming.URLPath(pkg.Name))
+	if err != nil {
+		record.Errors = append(record.Errors, fmt.Sprintf("metadata: %v", err))
+	} else if err := os.WriteFile(filepath.Join(staging, "metadata.json"), metadata, 0o644); err != nil {
+		return "", err
+	}
+
+	// Behavior traces, diff and AI analysis
+	pkgOutputDir := filepath.Join(outputDir, pkgKey)
+	if _, err := os.Stat(pkgOutputDir); err == nil {
+		if err := copyDir(pkgOutputDir, filepath.Join(staging, "artifacts")); err != nil {
+			return "", fmt.Errorf("failed to copy artifacts: %w", err)
+		}
+	}
+
+	record.Files, err = hashEvidence(staging)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash evidence: %w", err)
+	}
+	if err := writeEvidenceRecord(staging, &record); err != nil {
+		return "", err
+	}
+
+	if err := os.Rename(staging, finalDir); err != nil {
+		if _, statErr := os.Stat(finalDir); statErr == nil {
+			return "", nil // Lost a race with a concurrent run
+		}
+		return "", fmt.Errorf("failed to move evidence into place: %w", err)
+	}
+	if err := makeReadOnly(finalDir); err != nil {
+		return finalDir, fmt.Errorf("failed to make evidence read-only: %w", err)
+	}
+
+	return finalDir, nil
+}
+
+// fetchEvidence downloads a URL verbatim
+func fetchEvidence(ctx context.Context, rawURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := quarantineHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", rawURL, resp.StatusCode)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// verifyIntegrity checks data against an SRI string as found in lockfiles
+// (e.g. "sha512-<base64>"). Any one matching hash is sufficient.
+func verifyIntegrity(data []byte, sri string) (bool, error) {
+	supported := false
+	for _, entry := range strings.Fields(sri) {
+		algo, digest, ok := strings.Cut(entry, "-")
+		if !ok {
+			continue
+		}
+
+		var sum []byte
+		switch algo {
+		case "sha512":
+			s := sha512.Sum512(data)
+			sum = s[:]
+		case "sha256":
+			s := sha256.Sum256(data)
+			sum = s[:]
+		case "sha1":
+			s := sha1.Sum(data)
+			sum = s[:]
+		default:
+			continue
+		}
+
+		supported = true
+		if base64.StdEncoding.EncodeToString(sum) == digest {
+			return true, nil
+		}
+	}
+
+	if !supported {
+		return false, fmt.Errorf("no supported hash in %q", sri)
+	}
+	return false, nil
+}
+
+// hashEvidence hashes every file under dir, sorted by path
+func hashEvidence(dir string) ([]EvidenceHash, error) {
+	var hashes []EvidenceHash
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		// Streamed: evidence includes the full behavior trace
+		f, err := os.Open(p)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		s256, s512 := sha256.New(), sha512.New()
+		size, err := io.Copy(io.MultiWriter(s256, s512), f)
+		if err != nil {
+			return err
+		}
+
+		hashes = append(hashes, EvidenceHash{
+			Path:   filepath.ToSlash(rel),
+			Size:   size,
+			SHA256: hex.EncodeToString(s256.Sum(nil)),
+			SHA512: hex.EncodeToString(s512.Sum(nil)),
+		})
+		return nil
+	})
+	return hashes, err
+}
+
+// writeEvidenceRecord writes evidence.json plus a SHA256SUMS file that can be
+// checked with `sha256sum -c`
+func writeEvidenceRecord(dir string, record *EvidenceRecord) error {
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal evidence record: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, EvidenceFile), data, 0o644); err != nil {
+		return err
+	}
+
+	var sums bytes.Buffer
+	for _, f := range record.Files {
+		fmt.Fprintf(&sums, "%s  %s\n", f.SHA256, f.Path)
+	}
+	return os.WriteFile(filepath.Join(dir, "SHA256SUMS"), sums.Bytes(), 0o644)
+}
+
+// makeReadOnly strips write permission from everything under dir
+func makeReadOnly(dir string) error {
+	return filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return os.Chmod(p, 0o555)
+		}
+		return os.Chmod(p, 0o444)
+	})
+}
@@ -0,0 +1,112 @@
+package orchestrator
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyIntegrity(t *testing.T) {
+	data := []byte("tarball")
+	sum := sha512.Sum512(data)
+	sri := "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+
+	ok, err := verifyIntegrity(data, sri)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = verifyIntegrity([]byte("tampered"), sri)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	_, err = verifyIntegrity(data, "md5-abc")
+	assert.Error(t, err)
+}
+
+func TestQuarantinePackage(t *testing.T) {
+	tarball := []byte("evil tarball")
+	sum := sha512.Sum512(tarball)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/evil/-/evil-1.0.0.tgz":
+			w.Write(tarball)
+		case "/evil":
+			w.Write([]byte(`{"name":"evil"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	oldRegistry := npmRegistryURL
+	npmRegistryURL = srv.URL
+	defer func() { npmRegistryURL = oldRegistry }()
+
+	pkg := models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"}
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{
+		Package:     pkg,
+		ResolvedURL: srv.URL + "/evil/-/evil-1.0.0.tgz",
+		Integrity:   "sha512-" + base64.StdEncoding.EncodeToString(sum[:]),
+	})
+
+	outputDir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(outputDir, "evil@1.0.0"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(outputDir, "evil@1.0.0", "diff.json"), []byte(`{}`), 0o644))
+
+	quarantineDir := t.TempDir()
+	t.Cleanup(func() {
+		// Restore write permission so the temp dir can be removed
+		filepath.WalkDir(quarantineDir, func(p string, d fs.DirEntry, err error) error {
+			if err == nil {
+				os.Chmod(p, 0o755)
+			}
+			return nil
+		})
+	})
+
+	o := &Orchestrator{graph: graph, quarantineDir: quarantineDir}
+	assessment := &analysis.SecurityAssessment{IsMalicious: true, Confidence: 0.9}
+
+	dir, err := o.quarantinePackage(t.Context(), pkg, outputDir, assessment)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(quarantineDir, "evil@1.0.0"), dir)
+
+	data, err := os.ReadFile(filepath.Join(dir, EvidenceFile))
+	require.NoError(t, err)
+	var record EvidenceRecord
+	require.NoError(t, json.Unmarshal(data, &record))
+
+	assert.True(t, record.IntegrityVerified)
+	assert.Empty(t, record.Errors)
+	var paths []string
+	for _, f := range record.Files {
+		paths = append(paths, f.Path)
+	}
+	assert.Equal(t, []string{"artifacts/diff.json", "evil-1.0.0.tgz", "metadata.json"}, paths)
+
+	archived, err := os.ReadFile(filepath.Join(dir, "evil-1.0.0.tgz"))
+	require.NoError(t, err)
+	assert.Equal(t, tarball, archived)
+
+	info, err := os.Stat(filepath.Join(dir, "evil-1.0.0.tgz"))
+	require.NoError(t, err)
+	assert.Equal(t, fs.FileMode(0o444), info.Mode().Perm())
+
+	// Existing entries are never overwritten
+	dir, err = o.quarantinePackage(t.Context(), pkg, outputDir, assessment)
+	require.NoError(t, err)
+	assert.Empty(t, dir)
+}
@@ -0,0 +1,52 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ErrPanic is the package error when analyzing a package panicked, e.g. on a
+// malformed artifact. The stack is logged and the run treats the package like
+// any other failure.
+var ErrPanic = errors.New("panic")
+
+// recoverPackage turns a panic in the calling goroutine into *errp, logging
+// its stack. It must be deferred directly.
+func (o *Orchestrator) recoverPackage(pkg models.Package, stage string, errp *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	*errp = fmt.Errorf("%w during %s: %v", ErrPanic, stage, r)
+	o.logMsg(fmt.Sprintf("Recovered from panic in %s of %s@%s: %v", stage, pkg.Name, pkg.Version, r), "error",
+		append(pkgAttrs(pkg.Name, pkg.Version, stage), logging.KeyStack, string(debug.Stack()))...)
+}
+
+// runPackage analyzes a package in a worker, converting a panic into the
+// package's failure so it can't take down the other workers
+func (o *Orchestrator) runPackage(ctx context.Context, pkg models.Package, tempDir string, outputDir string, copyWg *sync.WaitGroup) (result PackageResult) {
+	result.Package = pkg
+	defer o.recoverPackage(pkg, "workflow", &result.Error)
+	return o.analyzePackage(ctx, pkg, tempDir, outputDir, copyWg)
+}
+
+// recoverCopy records a panic in the artifact copy of pkg as its failure,
+// picked up by RunPackages once the copies are done. It must be deferred
+// directly.
+func (o *Orchestrator) recoverCopy(pkg models.Package) {
+	r := recover()
+	if r == nil {
+		return
+	}
+	o.logMsg(fmt.Sprintf("Recovered from panic copying artifacts of %s@%s: %v", pkg.Name, pkg.Version, r), "error",
+		append(pkgAttrs(pkg.Name, pkg.Version, "download"), logging.KeyStack, string(debug.Stack()))...)
+	o.copyMu.Lock()
+	o.copyFailures[pkg] = fmt.Errorf("%w during artifact copy: %v", ErrPanic, r)
+	o.copyMu.Unlock()
+}
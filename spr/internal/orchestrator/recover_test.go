@@ -0,0 +1,41 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecoverPackage(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	pkg := models.Package{Name: "left-pad", Version: "1.0.0"}
+
+	var err error
+	func() {
+		defer o.recoverPackage(pkg, "workflow", &err)
+		var artifacts []string
+		_ = artifacts[1]
+	}()
+	assert.ErrorIs(t, err, ErrPanic)
+	assert.Contains(t, err.Error(), "index out of range")
+
+	// Without a panic the error is left alone
+	err = nil
+	func() { defer o.recoverPackage(pkg, "workflow", &err) }()
+	assert.NoError(t, err)
+}
+
+func TestRecoverCopy(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.copyFailures = make(map[models.Package]error)
+	pkg := models.Package{Name: "left-pad", Version: "1.0.0"}
+
+	func() {
+		defer o.recoverCopy(pkg)
+		panic("malformed artifact")
+	}()
+	assert.ErrorIs(t, o.copyFailures[pkg], ErrPanic)
+	assert.Contains(t, o.copyFailures[pkg].Error(), "malformed artifact")
+}
@@ -0,0 +1,274 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// RemediationFile is written next to the analysis of every flagged package
+const RemediationFile = "remediation.json"
+
+// maxRemediationCandidates caps how many unanalyzed versions are suggested
+// for analysis
+const maxRemediationCandidates = 3
+
+var remediationHTTPClient = breaker.Client(breaker.Npm, 30*time.Second)
+
+// Remediation suggests a replacement for a flagged package version
+type Remediation struct {
+	Package string `json:"package"`
+	// Replacement is the nearest sibling version with a clean verdict, empty
+	// if none has been analyzed yet
+	Replacement string `json:"replacement,omitempty"`
+	// Candidates are the nearest sibling versions without a verdict, to
+	// analyze next (spr check on a project pinning them) when there is no
+	// replacement
+	Candidates []string `json:"candidates,omitempty"`
+	// Pin is the command pinning a direct dependency to the replacement
+	Pin string `json:"pin,omitempty"`
+	// Overrides is a package.json overrides snippet forcing the replacement
+	// for transitive dependencies too
+	Overrides map[string]string `json:"overrides,omitempty"`
+}
+
+// suggestRemediations looks for sibling versions of every flagged package
+// with a clean verdict in outputDir or the analysis-results cache, writes
+// the suggestion to the package's output directory and logs it. The
+// registry is not queried offline; only cached versions are considered
+// then. Failures are logged, not returned.
+func (o *Orchestrator) suggestRemediations(ctx context.Context, packages []models.Package, outputDir string) {
+	direct := make(map[string]bool)
+	if o.graph != nil {
+		for _, dep := range o.graph.GetDirectDependencies() {
+			direct[dep.ID] = true
+		}
+	}
+
+	for _, pkg := range packages {
+		assessment, err := loadAssessment(outputDir, pkg)
+		if err != nil || assessment == nil || !assessment.IsMalicious {
+			continue
+		}
+
+		rem, err := FindRemediation(ctx, pkg, outputDir, direct[pkg.Name+"@"+pkg.Version], o.offline)
+		if err != nil {
+			o.logMsg(fmt.Sprintf("Failed to list versions of %s, suggesting cached ones only: %v", pkg.Name, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "remediation")...)
+		}
+
+		data, err := json.MarshalIndent(rem, "", "  ")
+		if err != nil {
+			continue
+		}
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+		if err := os.WriteFile(filepath.Join(pkgDir, RemediationFile), data, 0o644); err != nil {
+			o.logMsg(fmt.Sprintf("Failed to write remediation for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "remediation")...)
+		}
+
+		switch {
+		case rem.Replacement != "":
+			snippet, _ := json.Marshal(map[string]any{"overrides": rem.Overrides})
+			msg := fmt.Sprintf("Remediation for %s@%s: replace with %s@%s (clean verdict); add %s to package.json", pkg.Name, pkg.Version, pkg.Name, rem.Replacement, snippet)
+			if rem.Pin != "" {
+				msg += " or run: " + rem.Pin
+			}
+			o.logMsg(msg, "info", pkgAttrs(pkg.Name, pkg.Version, "remediation")...)
+		case len(rem.Candidates) > 0:
+			o.logMsg(fmt.Sprintf("Remediation for %s@%s: no analyzed clean version yet; analyze %s next", pkg.Name, pkg.Version, strings.Join(rem.Candidates, ", ")), "info", pkgAttrs(pkg.Name, pkg.Version, "remediation")...)
+		default:
+			o.logMsg(fmt.Sprintf("Remediation for %s@%s: no other version available, remove the dependency", pkg.Name, pkg.Version), "warning", pkgAttrs(pkg.Name, pkg.Version, "remediation")...)
+		}
+	}
+}
+
+// FindRemediation looks for the nearest sibling version of pkg with a clean
+// verdict in outputDir or the analysis-results cache. Versions are listed from
+// the registry unless offline; if that fails, the cached versions are used
+// and the error is returned along with the remediation.
+func FindRemediation(ctx context.Context, pkg models.Package, outputDir string, direct, offline bool) (*Remediation, error) {
+	var versions []string
+	var fetchErr error
+	if !offline {
+		versions, fetchErr = fetchVersions(ctx, pkg.Name)
+	}
+	if offline || fetchErr != nil {
+		versions = cachedVersions(pkg.Name, outputDir, "analysis-results")
+	}
+
+	rem := buildRemediation(pkg, versions, direct, func(version string) (bool, bool) {
+		sibling := models.Package{Name: pkg.Name, Version: version}
+		for _, dir := range []string{outputDir, "analysis-results"} {
+			if clean, analyzed := cachedVerdict(dir, sibling); analyzed {
+				return clean, true
+			}
+		}
+		return false, false
+	})
+	return rem, fetchErr
+}
+
+// buildRemediation picks the nearest version with a clean verdict among
+// versions, or failing that the nearest unanalyzed ones. verdict reports
+// whether a version is clean and whether it was analyzed at all.
+func buildRemediation(pkg models.Package, versions []string, direct bool, verdict func(version string) (clean, analyzed bool)) *Remediation {
+	rem := &Remediation{Package: pkg.Name + "@" + pkg.Version}
+	for _, version := range rankSiblingVersions(pkg.Version, versions) {
+		clean, analyzed := verdict(version)
+		if !analyzed {
+			if len(rem.Candidates) < maxRemediationCandidates {
+				rem.Candidates = append(rem.Candidates, version)
+			}
+			continue
+		}
+		if clean {
+			rem.Replacement = version
+			break
+		}
+	}
+
+	if rem.Replacement != "" {
+		// A replacement makes analyzing further versions unnecessary
+		rem.Candidates = nil
+		rem.Overrides = map[string]string{pkg.Name: rem.Replacement}
+		if direct {
+			rem.Pin = fmt.Sprintf("npm install --save-exact %s@%s", pkg.Name, rem.Replacement)
+		}
+	}
+	return rem
+}
+
+// rankSiblingVersions orders the release versions other than flagged by how
+// good a replacement they would make: newer ones in the same major first
+// (closest first, as the least disruptive upgrade), then older ones in the
+// same major (closest first), then other majors by distance
+func rankSiblingVersions(flagged string, versions []string) []string {
+	major := semverMajor(flagged)
+	rank := func(v string) int {
+		newer := registry.CompareSemver(v, flagged) > 0
+		switch {
+		case semverMajor(v) == major && newer:
+			return 0
+		case semverMajor(v) == major:
+			return 1
+		case newer:
+			return 2
+		default:
+			return 3
+		}
+	}
+
+	var siblings []string
+	for _, v := range versions {
+		if v != flagged && !strings.Contains(v, "-") && semverMajor(v) != "" {
+			siblings = append(siblings, v)
+		}
+	}
+	sort.SliceStable(siblings, func(i, j int) bool {
+		ri, rj := rank(siblings[i]), rank(siblings[j])
+		if ri != rj {
+			return ri < rj
+		}
+		// Closest first: ascending above the flagged version, descending below
+		if ri%2 == 0 {
+			return registry.CompareSemver(siblings[i], siblings[j]) < 0
+		}
+		return registry.CompareSemver(siblings[i], siblings[j]) > 0
+	})
+	return siblings
+}
+
+func semverMajor(version string) string {
+	major, _, ok := strings.Cut(version, ".")
+	if !ok {
+		return ""
+	}
+	return major
+}
+
+// cachedVerdict reports whether pkg has a clean verdict in dir, and whether it
+// was analyzed there at all. A diff without an AI analysis means nothing
+// anomalous was found.
+func cachedVerdict(dir string, pkg models.Package) (clean, analyzed bool) {
+	assessment, err := loadAssessment(dir, pkg)
+	if err != nil {
+		return false, false
+	}
+	if assessment != nil {
+		return !assessment.IsMalicious, true
+	}
+	pkgDir := filepath.Join(dir, naming.DirName(pkg.Name, pkg.Version))
+	if _, err := os.Stat(filepath.Join(pkgDir, "diff.json")); err == nil {
+		return true, true
+	}
+	return false, false
+}
+
+// cachedVersions lists the versions of a package with results in any of dirs
+func cachedVersions(name string, dirs ...string) []string {
+	prefix := naming.FileSafe(name) + "@"
+	seen := make(map[string]bool)
+	var versions []string
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			version, ok := strings.CutPrefix(entry.Name(), prefix)
+			if ok && entry.IsDir() && !seen[version] {
+				seen[version] = true
+				versions = append(versions, version)
+			}
+		}
+	}
+	return versions
+}
+
+// fetchVersions lists the published, non-deprecated versions of a package
+func fetchVersions(ctx context.Context, name string) ([]string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, npmRegistryURL+"/"+naming.URLPath(name), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// The abbreviated packument is enough and much smaller
+	req.Header.Set("Accept", "application/vnd.npm.install-v1+json")
+
+	resp, err := remediationHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: status %d", req.URL, resp.StatusCode)
+	}
+
+	var doc struct {
+		Versions map[string]struct {
+			Deprecated any `json:"deprecated"`
+		} `json:"versions"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode package metadata: %w", err)
+	}
+
+	versions := make([]string, 0, len(doc.Versions))
+	for version, meta := range doc.Versions {
+		if deprecated, _ := meta.Deprecated.(string); deprecated != "" {
+			continue
+		}
+		versions = append(versions, version)
+	}
+	return versions, nil
+}
@@ -0,0 +1,90 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRankSiblingVersions(t *testing.T) {
+	versions := []string{"0.9.0", "1.0.0", "1.1.0", "1.2.0", "1.2.1", "1.3.0-beta.1", "1.3.0", "2.0.0", "3.0.0"}
+	assert.Equal(t, []string{"1.2.1", "1.3.0", "1.1.0", "1.0.0", "2.0.0", "3.0.0", "0.9.0"}, rankSiblingVersions("1.2.0", versions))
+}
+
+func TestBuildRemediation(t *testing.T) {
+	pkg := models.Package{Name: "evil", Version: "1.2.0"}
+	versions := []string{"1.0.0", "1.1.0", "1.2.0", "1.2.1", "1.3.0"}
+
+	verdicts := map[string]bool{"1.2.1": false, "1.1.0": true}
+	rem := buildRemediation(pkg, versions, true, func(v string) (bool, bool) {
+		clean, analyzed := verdicts[v]
+		return clean, analyzed
+	})
+	assert.Equal(t, "1.1.0", rem.Replacement)
+	assert.Empty(t, rem.Candidates)
+	assert.Equal(t, map[string]string{"evil": "1.1.0"}, rem.Overrides)
+	assert.Equal(t, "npm install --save-exact evil@1.1.0", rem.Pin)
+
+	// Nothing analyzed yet: suggest what to analyze, nearest first
+	rem = buildRemediation(pkg, versions, false, func(string) (bool, bool) { return false, false })
+	assert.Empty(t, rem.Replacement)
+	assert.Equal(t, []string{"1.2.1", "1.3.0", "1.1.0"}, rem.Candidates)
+	assert.Empty(t, rem.Pin)
+}
+
+func TestSuggestRemediations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/@scope/evil" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"versions":{"1.0.0":{},"1.1.0":{"deprecated":"broken"},"1.2.0":{},"1.3.0":{}}}`))
+	}))
+	defer srv.Close()
+
+	oldRegistry := npmRegistryURL
+	npmRegistryURL = srv.URL
+	defer func() { npmRegistryURL = oldRegistry }()
+
+	// Sibling verdicts come from the analysis-results cache in the cwd
+	t.Chdir(t.TempDir())
+	writeFile := func(path, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+	writeFile(filepath.Join("analysis-results", "scope__evil@1.3.0", "ai-analysis.json"), `{"is_malicious": true}`)
+	writeFile(filepath.Join("analysis-results", "scope__evil@1.0.0", "diff.json"), `{}`)
+
+	outputDir := "out"
+	writeFile(filepath.Join(outputDir, "scope__evil@1.2.0", "ai-analysis.json"), `{"is_malicious": true}`)
+
+	pkg := models.Package{ID: "@scope/evil@1.2.0", Name: "@scope/evil", Version: "1.2.0"}
+	o := &Orchestrator{}
+	o.suggestRemediations(context.Background(), []models.Package{pkg}, outputDir)
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "scope__evil@1.2.0", RemediationFile))
+	require.NoError(t, err)
+	var rem Remediation
+	require.NoError(t, json.Unmarshal(data, &rem))
+	assert.Equal(t, "1.0.0", rem.Replacement)
+	assert.Equal(t, map[string]string{"@scope/evil": "1.0.0"}, rem.Overrides)
+
+	// Offline, only cached versions are considered
+	require.NoError(t, os.RemoveAll(filepath.Join("analysis-results", "scope__evil@1.0.0")))
+	o.offline = true
+	o.suggestRemediations(context.Background(), []models.Package{pkg}, outputDir)
+	data, err = os.ReadFile(filepath.Join(outputDir, "scope__evil@1.2.0", RemediationFile))
+	require.NoError(t, err)
+	rem = Remediation{}
+	require.NoError(t, json.Unmarshal(data, &rem))
+	assert.Empty(t, rem.Replacement)
+	assert.Empty(t, rem.Candidates)
+}
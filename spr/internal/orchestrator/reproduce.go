@@ -0,0 +1,201 @@
+package orchestrator
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+)
+
+// ReproductionFile is the name of the reproducibility manifest written to the
+// output directory
+const ReproductionFile = "reproduce.json"
+
+// SeedInput is the workflow input carrying the run's seed. Workflows that
+// don't declare it run unseeded.
+const SeedInput = "seed"
+
+// dispatchRef is the branch workflows are dispatched on
+const dispatchRef = "main"
+
+// templatesPath is where the workflow's checkout keeps the test templates
+const templatesPath = "spr/templates"
+
+// Reproduction records everything that affects the results of a run, so
+// `spr reproduce` can repeat it with identical settings and name the inputs
+// that changed in between
+type Reproduction struct {
+	Seed      int64           `json:"seed"`
+	CreatedAt time.Time       `json:"created_at"`
+	Generator *version.Info   `json:"generator"`
+	Settings  json.RawMessage `json:"settings"` // The check settings, as recorded by the CLI
+	Inputs    ReproInputs     `json:"inputs"`
+}
+
+// ReproInputs are the content hashes and versions of the run's inputs. File
+// hashes are hex SHA-256; empty means the input wasn't used.
+type ReproInputs struct {
+	Lockfile     string            `json:"lockfile,omitempty"`
+	PackageJSON  string            `json:"package_json,omitempty"`
+	Dependencies string            `json:"dependencies"` // Hash of the sorted name@version list analyzed
+	Baseline     string            `json:"baseline,omitempty"`
+	Allowlist    string            `json:"allowlist,omitempty"`
+	Workflow     string            `json:"workflow"`
+	WorkflowSHA  string            `json:"workflow_sha,omitempty"` // Commit dispatched runs check out; empty offline
+	Templates    map[string]string `json:"templates,omitempty"`    // Git tree SHA per test template at that commit
+	AIModel      string            `json:"ai_model,omitempty"`     // provider/model; empty without LLM review
+	Prompt       string            `json:"prompt,omitempty"`
+	Ruleset      string            `json:"ruleset"`
+}
+
+// InputChange is an input that differs between two runs
+type InputChange struct {
+	Input string
+	Was   string
+	Now   string
+}
+
+// LoadReproduction reads a reproducibility manifest
+func LoadReproduction(path string) (*Reproduction, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read reproducibility manifest: %w", err)
+	}
+	var r Reproduction
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse reproducibility manifest: %w", err)
+	}
+	return &r, nil
+}
+
+// Save writes the manifest to outputDir
+func (r *Reproduction) Save(outputDir string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal reproducibility manifest: %w", err)
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return fmt.Errorf("failed to create output directory: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(outputDir, ReproductionFile), data, 0o644); err != nil {
+		return fmt.Errorf("failed to write reproducibility manifest: %w", err)
+	}
+	return nil
+}
+
+// Changes lists the inputs of r that differ from those of an earlier run,
+// including the build of spr that recorded them
+func (r *Reproduction) Changes(earlier *Reproduction) []InputChange {
+	var changes []InputChange
+	compare := func(input, was, now string) {
+		if was != now {
+			changes = append(changes, InputChange{Input: input, Was: was, Now: now})
+		}
+	}
+	a, b := earlier.Inputs, r.Inputs
+	compare("lockfile", a.Lockfile, b.Lockfile)
+	compare("package_json", a.PackageJSON, b.PackageJSON)
+	compare("dependencies", a.Dependencies, b.Dependencies)
+	compare("baseline", a.Baseline, b.Baseline)
+	compare("allowlist", a.Allowlist, b.Allowlist)
+	compare("workflow", a.Workflow, b.Workflow)
+	// Offline runs don't look the workflow up, so neither side is known
+	if a.WorkflowSHA != "" && b.WorkflowSHA != "" {
+		compare("workflow_sha", a.WorkflowSHA, b.WorkflowSHA)
+		names := make(map[string]bool)
+		for name := range a.Templates {
+			names[name] = true
+		}
+		for name := range b.Templates {
+			names[name] = true
+		}
+		sorted := make([]string, 0, len(names))
+		for name := range names {
+			sorted = append(sorted, name)
+		}
+		sort.Strings(sorted)
+		for _, name := range sorted {
+			compare("templates/"+name, a.Templates[name], b.Templates[name])
+		}
+	}
+	compare("ai_model", a.AIModel, b.AIModel)
+	compare("prompt", a.Prompt, b.Prompt)
+	compare("ruleset", a.Ruleset, b.Ruleset)
+	if earlier.Generator != nil && r.Generator != nil {
+		compare("spr", earlier.Generator.String(), r.Generator.String())
+	}
+	return changes
+}
+
+// HashFile returns the hex SHA-256 of a file's contents, or "" for an empty
+// path
+func HashFile(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashStrings returns the hex SHA-256 of a list of strings, sorted so order
+// doesn't matter
+func HashStrings(values []string) string {
+	sorted := append([]string(nil), values...)
+	sort.Strings(sorted)
+	sum := sha256.Sum256([]byte(strings.Join(sorted, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// WorkflowRevision returns the commit workflows dispatched now check out, and
+// the git tree SHA of each test template at that commit. The workflow file
+// and templates both come from that checkout.
+func (c *GitHubClient) WorkflowRevision(ctx context.Context) (string, map[string]string, error) {
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.getJSON(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/commits/%s", c.Owner, c.Repo, dispatchRef), &commit); err != nil {
+		return "", nil, fmt.Errorf("failed to get %s commit: %w", dispatchRef, err)
+	}
+
+	var entries []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+	}
+	query := url.Values{"ref": {commit.SHA}}
+	if err := c.getJSON(ctx, fmt.Sprintf("https://api.github.com/repos/%s/%s/contents/%s?%s", c.Owner, c.Repo, templatesPath, query.Encode()), &entries); err != nil {
+		return "", nil, fmt.Errorf("failed to list test templates: %w", err)
+	}
+	templates := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.Type == "dir" {
+			templates[entry.Name] = entry.SHA
+		}
+	}
+	return commit.SHA, templates, nil
+}
+
+// SetSeed sets the seed passed to workflows that declare SeedInput, which
+// seeds Math.random in the sandbox so repeated runs draw the same numbers.
+// Zero leaves runs unseeded.
+func (o *Orchestrator) SetSeed(seed int64) {
+	o.seed = seed
+}
@@ -0,0 +1,116 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReproductionRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	r := &Reproduction{
+		Seed:      42,
+		Generator: &version.Info{Version: "v1.0.0"},
+		Settings:  json.RawMessage(`{"scope":"direct"}`),
+		Inputs:    ReproInputs{Dependencies: HashStrings([]string{"b@1.0.0", "a@1.0.0"}), Ruleset: "r"},
+	}
+	require.NoError(t, r.Save(dir))
+
+	loaded, err := LoadReproduction(filepath.Join(dir, ReproductionFile))
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), loaded.Seed)
+	assert.JSONEq(t, `{"scope":"direct"}`, string(loaded.Settings))
+	assert.Equal(t, HashStrings([]string{"a@1.0.0", "b@1.0.0"}), loaded.Inputs.Dependencies, "order doesn't matter")
+	assert.Empty(t, loaded.Changes(r))
+}
+
+func TestReproductionChanges(t *testing.T) {
+	earlier := &Reproduction{
+		Generator: &version.Info{Version: "v1.0.0"},
+		Inputs: ReproInputs{
+			Lockfile:    "aaa",
+			Baseline:    "bbb",
+			Workflow:    "analyze.yml",
+			WorkflowSHA: "c1",
+			Templates:   map[string]string{"import-test": "t1", "install-test": "t2"},
+			Ruleset:     "r1",
+		},
+	}
+	now := &Reproduction{
+		Generator: &version.Info{Version: "v1.1.0"},
+		Inputs: ReproInputs{
+			Lockfile:    "aaa",
+			Baseline:    "bbb2",
+			Workflow:    "analyze.yml",
+			WorkflowSHA: "c2",
+			Templates:   map[string]string{"import-test": "t1", "install-test": "t3", "cli-test": "t4"},
+			AIModel:     "openai/gpt-5-mini",
+			Ruleset:     "r1",
+		},
+	}
+	assert.Equal(t, []InputChange{
+		{Input: "baseline", Was: "bbb", Now: "bbb2"},
+		{Input: "workflow_sha", Was: "c1", Now: "c2"},
+		{Input: "templates/cli-test", Now: "t4"},
+		{Input: "templates/install-test", Was: "t2", Now: "t3"},
+		{Input: "ai_model", Now: "openai/gpt-5-mini"},
+		{Input: "spr", Was: "spr v1.0.0", Now: "spr v1.1.0"},
+	}, now.Changes(earlier))
+
+	// An offline run knows no workflow revision to compare
+	now.Inputs.WorkflowSHA, now.Inputs.Templates = "", nil
+	for _, change := range now.Changes(earlier) {
+		assert.NotContains(t, change.Input, "workflow_sha")
+		assert.NotContains(t, change.Input, "templates/")
+	}
+}
+
+func TestHashFile(t *testing.T) {
+	hash, err := HashFile("")
+	require.NoError(t, err)
+	assert.Empty(t, hash)
+
+	path := filepath.Join(t.TempDir(), "package-lock.json")
+	require.NoError(t, os.WriteFile(path, []byte("abc"), 0o644))
+	hash, err = HashFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", hash)
+
+	_, err = HashFile(filepath.Join(t.TempDir(), "missing"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestWorkflowRevision(t *testing.T) {
+	client := NewGitHubClient("token", "owner", "repo")
+	client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		var body any
+		switch r.URL.Path {
+		case "/repos/owner/repo/commits/main":
+			body = map[string]string{"sha": "c0ffee"}
+		case "/repos/owner/repo/contents/spr/templates":
+			assert.Equal(t, "c0ffee", r.URL.Query().Get("ref"))
+			body = []map[string]string{
+				{"name": "import-test", "type": "dir", "sha": "t1"},
+				{"name": "install-test", "type": "dir", "sha": "t2"},
+				{"name": "README.md", "type": "file", "sha": "f1"},
+			}
+		default:
+			t.Fatalf("unexpected request %s", r.URL)
+		}
+		data, _ := json.Marshal(body)
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(string(data)))}, nil
+	})}
+
+	sha, templates, err := client.WorkflowRevision(t.Context())
+	require.NoError(t, err)
+	assert.Equal(t, "c0ffee", sha)
+	assert.Equal(t, map[string]string{"import-test": "t1", "install-test": "t2"}, templates)
+}
@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/resultlog"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// resultLogFiles are the artifacts whose hashes are recorded with a verdict
+var resultLogFiles = []string{"diff.json", "ai-analysis.json"}
+
+// ResultRecord is the record of one package verdict in the results log
+type ResultRecord struct {
+	Time       time.Time `json:"time"`
+	RunID      string    `json:"run_id,omitempty"`
+	Package    string    `json:"package"`
+	Version    string    `json:"version"`
+	Verdict    string    `json:"verdict"` // One of the models.Verdict* values
+	Confidence float64   `json:"confidence,omitempty"`
+	// Artifacts maps the files the verdict is based on to their SHA-256
+	Artifacts map[string]string `json:"artifacts,omitempty"`
+	Generator *version.Info     `json:"generator,omitempty"`
+}
+
+// SetResultsLog appends the verdict of every analyzed package to the
+// hash-chained log at path (see resultlog), tagged with runID. An empty path
+// disables it.
+func (o *Orchestrator) SetResultsLog(path, runID string) {
+	o.resultsLog = path
+	o.resultsLogRunID = runID
+}
+
+// appendResultsLog records the verdicts of packages in the results log.
+// Failures are logged, not returned.
+func (o *Orchestrator) appendResultsLog(packages []models.Package, outputDir string) {
+	if o.resultsLog == "" {
+		return
+	}
+
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for the results log: %v", err), "warning", logging.KeyStage, "results-log")
+		return
+	}
+
+	now := time.Now().UTC()
+	var records []any
+	for _, pkg := range packages {
+		assessment, analyzed := verdicts[pkg.Name+"@"+pkg.Version]
+		if !analyzed {
+			continue
+		}
+		record := ResultRecord{
+			Time:      now,
+			RunID:     o.resultsLogRunID,
+			Package:   pkg.Name,
+			Version:   pkg.Version,
+			Verdict:   models.VerdictClean,
+			Artifacts: make(map[string]string),
+			Generator: version.Stamp(),
+		}
+		if assessment != nil {
+			record.Verdict = models.VerdictSafe
+			if assessment.IsMalicious {
+				record.Verdict = models.VerdictMalicious
+			}
+			record.Confidence = assessment.Confidence
+		}
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+		for _, name := range resultLogFiles {
+			data, err := os.ReadFile(filepath.Join(pkgDir, name))
+			if err != nil {
+				continue
+			}
+			sum := sha256.Sum256(data)
+			record.Artifacts[name] = hex.EncodeToString(sum[:])
+		}
+		records = append(records, record)
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	head, err := resultlog.Append(o.resultsLog, records...)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to append to results log %s: %v", o.resultsLog, err), "error", logging.KeyStage, "results-log")
+		return
+	}
+	o.logMsg(fmt.Sprintf("Recorded %d verdicts in %s (head %s)", len(records), o.resultsLog, head), "info", logging.KeyStage, "results-log")
+}
@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/resultlog"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAppendResultsLog(t *testing.T) {
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"evil@1.0.0/diff.json":        "{}",
+		"evil@1.0.0/ai-analysis.json": `{"is_malicious":true,"confidence":0.9}`,
+		"clean@1.0.0/diff.json":       "{}",
+	})
+	packages := []models.Package{
+		{Name: "evil", Version: "1.0.0"},
+		{Name: "clean", Version: "1.0.0"},
+		{Name: "missing", Version: "1.0.0"},
+	}
+	logPath := filepath.Join(t.TempDir(), "results.log")
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	o.SetResultsLog(logPath, "run-1")
+	o.appendResultsLog(packages, outputDir)
+
+	result, err := resultlog.Verify(logPath)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Entries)
+
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	var records []ResultRecord
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		var entry resultlog.Entry
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		var record ResultRecord
+		require.NoError(t, json.Unmarshal(entry.Record, &record))
+		records = append(records, record)
+	}
+	require.Len(t, records, 2)
+	assert.Equal(t, "evil", records[0].Package)
+	assert.Equal(t, models.VerdictMalicious, records[0].Verdict)
+	assert.Equal(t, 0.9, records[0].Confidence)
+	assert.Equal(t, "run-1", records[0].RunID)
+	assert.Len(t, records[0].Artifacts, 2)
+	assert.Equal(t, models.VerdictClean, records[1].Verdict)
+	assert.Equal(t, "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", records[1].Artifacts["diff.json"])
+}
@@ -0,0 +1,76 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/sbom"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// SBOM files written to the output directory after analysis
+const (
+	SBOMFile     = "sbom.cdx.json"
+	SPDXSBOMFile = "sbom.spdx.json"
+)
+
+// LoadVerdicts collects the AI assessments of the packages that have results
+// in outputDir, keyed by name@version. Packages that were analyzed but have
+// no ai-analysis.json (clean diff) map to nil; packages without results are
+// left out.
+func LoadVerdicts(outputDir string, packages []models.Package) (map[string]*analysis.SecurityAssessment, error) {
+	verdicts := make(map[string]*analysis.SecurityAssessment)
+	for _, pkg := range packages {
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+		if _, err := os.Stat(pkgDir); err != nil {
+			continue
+		}
+
+		assessment, err := loadAssessment(outputDir, pkg)
+		if err != nil {
+			return nil, err
+		}
+		verdicts[pkg.Name+"@"+pkg.Version] = assessment
+	}
+	return verdicts, nil
+}
+
+// writeSBOM exports the dependency graph with per-package verdicts as
+// CycloneDX and SPDX SBOMs in outputDir. Failures are logged, not returned.
+func (o *Orchestrator) writeSBOM(ctx context.Context, packages []models.Package, outputDir string) {
+	if o.graph == nil {
+		return
+	}
+
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for SBOM: %v", err), "warning", logging.KeyStage, "sbom")
+		return
+	}
+
+	cdxPath := filepath.Join(outputDir, SBOMFile)
+	if err := sbom.NewCycloneDX(o.graph, verdicts).Write(cdxPath); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write CycloneDX SBOM: %v", err), "warning", logging.KeyStage, "sbom")
+	} else {
+		o.logMsg(fmt.Sprintf("Wrote CycloneDX SBOM to %s", cdxPath), "info", logging.KeyStage, "sbom")
+	}
+
+	var licenses map[string]string
+	if !o.offline {
+		licenses = sbom.FetchLicenses(ctx, o.graph, 10)
+	}
+	for id, license := range licenses {
+		o.graph.Annotate(id, models.AnnotationLicense, license)
+	}
+	spdxPath := filepath.Join(outputDir, SPDXSBOMFile)
+	if err := sbom.NewSPDX(o.graph, verdicts, licenses).Write(spdxPath); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write SPDX SBOM: %v", err), "warning", logging.KeyStage, "sbom")
+	} else {
+		o.logMsg(fmt.Sprintf("Wrote SPDX SBOM to %s (%d/%d licenses resolved)", spdxPath, len(licenses), len(o.graph.Nodes)), "info", logging.KeyStage, "sbom")
+	}
+}
@@ -0,0 +1,86 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/scoring"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// RiskFile holds, in a package's output directory, its combined risk score
+// and the factors behind it
+const RiskFile = "risk.json"
+
+// RiskScores returns the combined risk score of every package scored in the
+// last run
+func (o *Orchestrator) RiskScores() map[models.Package]scoring.Score {
+	return o.riskScores
+}
+
+// scorePackages combines the verdict, diff, static scan and graph findings
+// of every analyzed package into its risk score (see scoring), writing it to
+// RiskFile and annotating it as models.AnnotationRisk. Runs after the stages
+// that annotate findings. Failures are logged, not returned.
+func (o *Orchestrator) scorePackages(packages []models.Package, outputDir string) {
+	o.riskScores = make(map[models.Package]scoring.Score, len(packages))
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for risk scores: %v", err), "warning", logging.KeyStage, "scoring")
+		return
+	}
+
+	for _, pkg := range packages {
+		signals := o.riskSignals(pkg, outputDir)
+		assessment, analyzed := verdicts[pkg.Name+"@"+pkg.Version]
+		signals.Verdict, signals.Confidence = verdictOf(assessment, analyzed)
+
+		score := scoring.Compute(signals)
+		o.riskScores[pkg] = score
+		o.annotate(pkg, models.AnnotationRisk, score.Score)
+		if !analyzed || outputDir == "" {
+			continue
+		}
+		data, err := json.MarshalIndent(score, "", "  ")
+		if err == nil {
+			err = os.WriteFile(filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version), RiskFile), data, 0o644)
+		}
+		if err != nil {
+			o.logMsg(fmt.Sprintf("Failed to write %s of %s@%s: %v", RiskFile, pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "scoring")...)
+		}
+	}
+}
+
+// riskSignals gathers the findings about pkg other than its verdict: the
+// risk flags of its diff, its static scan and what earlier stages annotated
+func (o *Orchestrator) riskSignals(pkg models.Package, outputDir string) scoring.Signals {
+	var signals scoring.Signals
+	var diff struct {
+		RiskFlags []string `json:"risk_flags"`
+	}
+	if data, err := os.ReadFile(filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version), "diff.json")); err == nil {
+		if json.Unmarshal(data, &diff) == nil {
+			signals.RiskFlags = diff.RiskFlags
+		}
+	}
+	if report := o.staticReports[pkg]; report != nil {
+		signals.StaticRisk = report.Score
+	}
+	if o.graph == nil {
+		return signals
+	}
+	node, ok := o.graph.Nodes[pkg.Name+"@"+pkg.Version]
+	if !ok {
+		return signals
+	}
+	signals.Vulns = node.StringsAnnotation(models.AnnotationVulns)
+	signals.Typosquat = node.StringAnnotation(AnnotationTyposquat)
+	signals.Confusion = node.StringAnnotation(AnnotationConfusion)
+	signals.AgeGate = node.StringAnnotation(AnnotationAgeGate)
+	signals.Provenance = node.StringAnnotation(models.AnnotationProvenance)
+	return signals
+}
@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/scoring"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScorePackages(t *testing.T) {
+	evil := models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"}
+	squat := models.Package{ID: "lodahs@1.0.0", Name: "lodahs", Version: "1.0.0"}
+	skipped := models.Package{ID: "skipped@1.0.0", Name: "skipped", Version: "1.0.0"}
+	graph := models.NewDependencyGraph()
+	for _, pkg := range []models.Package{evil, squat, skipped} {
+		graph.AddNode(&models.PackageNode{Package: pkg})
+	}
+	graph.Annotate("lodahs@1.0.0", AnnotationTyposquat, "lodash")
+
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"evil@1.0.0/ai-analysis.json": `{"is_malicious":true,"confidence":0.9}`,
+		"evil@1.0.0/diff.json":        `{"risk_flags":["reverse_shell"]}`,
+		"lodahs@1.0.0/diff.json":      "{}",
+	})
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, graph)
+	o.scorePackages([]models.Package{evil, squat, skipped}, outputDir)
+
+	scores := o.RiskScores()
+	assert.Equal(t, 100, scores[evil].Score)
+	assert.Equal(t, 50, scores[squat].Score)
+	assert.Equal(t, 15, scores[skipped].Score)
+	risk, ok := graph.Nodes["evil@1.0.0"].FloatAnnotation(models.AnnotationRisk)
+	require.True(t, ok)
+	assert.Equal(t, 100.0, risk)
+
+	data, err := os.ReadFile(filepath.Join(outputDir, "evil@1.0.0", RiskFile))
+	require.NoError(t, err)
+	var written scoring.Score
+	require.NoError(t, json.Unmarshal(data, &written))
+	assert.Equal(t, scores[evil], written)
+	assert.NoFileExists(t, filepath.Join(outputDir, "skipped@1.0.0", RiskFile))
+}
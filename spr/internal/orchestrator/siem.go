@@ -0,0 +1,63 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// SetSIEM forwards an event per flagged package of each run to a SIEM
+// collector at endpoint, in format and authenticated with token if set. An
+// empty endpoint disables it.
+func (o *Orchestrator) SetSIEM(endpoint string, format siem.Format, token string) {
+	o.siem = nil
+	if endpoint != "" {
+		o.siem = siem.NewClient(endpoint, format, token)
+	}
+}
+
+// forwardToSIEM sends the flagged packages among packages to the SIEM.
+// Failures are logged, not returned.
+func (o *Orchestrator) forwardToSIEM(ctx context.Context, packages []models.Package, outputDir string) {
+	if o.siem == nil {
+		return
+	}
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for the SIEM: %v", err), "warning", logging.KeyStage, "siem")
+		return
+	}
+
+	now := time.Now().UTC()
+	var events []siem.Event
+	for _, pkg := range packages {
+		assessment := verdicts[pkg.Name+"@"+pkg.Version]
+		if assessment == nil || !assessment.IsMalicious {
+			continue
+		}
+		events = append(events, siem.Event{
+			Time:          now,
+			RunID:         o.resultsLogRunID,
+			Package:       pkg.Name,
+			Version:       pkg.Version,
+			Confidence:    assessment.Confidence,
+			Justification: assessment.Justification,
+			Indicators:    assessment.Indicators,
+		})
+	}
+	if len(events) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := o.siem.Send(ctx, events); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to forward detections to the SIEM: %v", err), "warning", logging.KeyStage, "siem")
+		return
+	}
+	o.logMsg(fmt.Sprintf("Forwarded %d detections to the SIEM as %s", len(events), o.siem.Format), "info", logging.KeyStage, "siem")
+}
@@ -0,0 +1,209 @@
+package orchestrator
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// staticConcurrency bounds the tarballs scanned at once
+const staticConcurrency = 8
+
+// StaticFile records the static scan of a run's tarballs in its output
+// directory
+const StaticFile = "static.json"
+
+// InstallScriptsFile holds, in a package's output directory, the scripts npm
+// runs when installing the package and the files they run
+const InstallScriptsFile = "install-scripts.json"
+
+// AnnotationInstallScripts is the graph annotation listing the install hooks
+// (preinstall, install, postinstall) of a package that has any
+const AnnotationInstallScripts = "install_scripts"
+
+// AnnotationStaticRisk is the graph annotation holding the static risk score
+// of a package with static findings
+const AnnotationStaticRisk = "static_risk"
+
+// StaticRecord is the static scan of one package
+type StaticRecord struct {
+	Package string `json:"package"`
+	*static.Report
+}
+
+// StaticScanReport is the content of StaticFile
+type StaticScanReport struct {
+	ScannedAt time.Time      `json:"scanned_at"`
+	Scanned   int            `json:"scanned"` // Tarballs scanned
+	Flagged   []StaticRecord `json:"flagged"` // Packages with findings, riskiest first
+}
+
+// SetTarballDir reads tarballs from dir, where the registry uploader kept
+// them (see registry.Uploader.SetTarballDir), before downloading them for
+// the static scan. Empty always downloads.
+func (o *Orchestrator) SetTarballDir(dir string) {
+	o.tarballDir = dir
+}
+
+// StaticFlagged returns the packages the static scan of the last run found
+// risks in, riskiest first
+func (o *Orchestrator) StaticFlagged() []StaticRecord {
+	return o.staticFlagged
+}
+
+// checkStatic scans the tarball of every package before its behavioral
+// analysis. Packages with findings are logged and annotated with their
+// static risk score, and the scan is recorded in StaticFile; the reports
+// are merged into the packages' assessments by runAIAnalysis.
+func (o *Orchestrator) checkStatic(ctx context.Context, packages []models.Package, outputDir string) {
+	o.staticReports, o.staticFlagged = nil, nil
+	if o.graph == nil || len(packages) == 0 {
+		return
+	}
+	if o.offline {
+		o.logMsg("Offline: skipping static analysis of tarballs", "warning", logging.KeyStage, "static")
+		return
+	}
+
+	o.staticReports = o.scanTarballs(ctx, packages)
+	report := StaticScanReport{ScannedAt: time.Now().UTC(), Scanned: len(o.staticReports)}
+	for pkg, scan := range o.staticReports {
+		o.recordInstallScripts(pkg, scan.InstallScripts, outputDir)
+		if len(scan.Findings) == 0 {
+			continue
+		}
+		o.annotate(pkg, AnnotationStaticRisk, scan.Score)
+		o.logMsg(fmt.Sprintf("Static analysis of %s@%s: risk score %.2f (%s)", pkg.Name, pkg.Version, scan.Score, strings.Join(scan.Kinds(), ", ")), "warning", pkgAttrs(pkg.Name, pkg.Version, "static")...)
+		report.Flagged = append(report.Flagged, StaticRecord{Package: pkg.Name + "@" + pkg.Version, Report: scan})
+	}
+	sort.Slice(report.Flagged, func(i, j int) bool {
+		a, b := report.Flagged[i], report.Flagged[j]
+		if a.Score != b.Score {
+			return a.Score > b.Score
+		}
+		return a.Package < b.Package
+	})
+	o.staticFlagged = report.Flagged
+	o.logMsg(fmt.Sprintf("%d tarballs scanned statically, %d with findings", report.Scanned, len(report.Flagged)), "info", logging.KeyStage, "static")
+
+	if err := writeStaticReport(outputDir, &report); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write %s: %v", StaticFile, err), "warning", logging.KeyStage, "static")
+	}
+}
+
+// recordInstallScripts annotates the install hooks of pkg and writes its
+// install scripts to InstallScriptsFile, so results show exactly what runs
+// at install time
+func (o *Orchestrator) recordInstallScripts(pkg models.Package, scripts []static.InstallScript, outputDir string) {
+	if len(scripts) == 0 {
+		return
+	}
+	hooks := make([]string, len(scripts))
+	for i, script := range scripts {
+		hooks[i] = script.Hook
+	}
+	o.annotate(pkg, AnnotationInstallScripts, hooks)
+	if outputDir == "" {
+		return
+	}
+
+	pkgOutputDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+	data, err := json.MarshalIndent(scripts, "", "  ")
+	if err == nil {
+		if err = os.MkdirAll(pkgOutputDir, 0o755); err == nil {
+			err = os.WriteFile(filepath.Join(pkgOutputDir, InstallScriptsFile), data, 0o644)
+		}
+	}
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write %s of %s@%s: %v", InstallScriptsFile, pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "static")...)
+	}
+}
+
+// scanTarballs scans the tarball of each package. Tarballs that can't be
+// read or fail their lockfile integrity are logged and those packages left
+// out.
+func (o *Orchestrator) scanTarballs(ctx context.Context, packages []models.Package) map[models.Package]*static.Report {
+	reports := make(map[models.Package]*static.Report)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, staticConcurrency)
+	for _, pkg := range packages {
+		node, ok := o.graph.Nodes[pkg.Name+"@"+pkg.Version]
+		if !ok {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			tarball, err := o.loadTarball(ctx, node)
+			if err != nil {
+				o.logMsg(fmt.Sprintf("Failed to fetch tarball of %s@%s for static analysis: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "static")...)
+				return
+			}
+			if tarball == nil {
+				return
+			}
+			if node.Integrity != "" {
+				if ok, err := verifyIntegrity(tarball, node.Integrity); err != nil || !ok {
+					o.logMsg(fmt.Sprintf("Tarball of %s@%s doesn't match its lockfile integrity, skipping static analysis", pkg.Name, pkg.Version), "warning", pkgAttrs(pkg.Name, pkg.Version, "static")...)
+					return
+				}
+			}
+			report, err := static.Scan(bytes.NewReader(tarball))
+			if err != nil {
+				o.logMsg(fmt.Sprintf("Static analysis of %s@%s failed: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "static")...)
+				return
+			}
+			mu.Lock()
+			reports[pkg] = report
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return reports
+}
+
+// loadTarball returns the tarball of node the uploader kept, or downloads it.
+// Packages not resolved from a registry, e.g. local ones, return nil.
+func (o *Orchestrator) loadTarball(ctx context.Context, node *models.PackageNode) ([]byte, error) {
+	if o.tarballDir != "" {
+		if data, err := os.ReadFile(registry.TarballPath(o.tarballDir, node.Name, node.Version)); err == nil {
+			return data, nil
+		}
+	}
+	if !strings.HasPrefix(node.ResolvedURL, "http") {
+		return nil, nil
+	}
+	return fetchEvidence(ctx, node.ResolvedURL)
+}
+
+// writeStaticReport writes StaticFile to outputDir
+func writeStaticReport(outputDir string, report *StaticScanReport) error {
+	if outputDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, StaticFile), data, 0o644)
+}
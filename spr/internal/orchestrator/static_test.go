@@ -0,0 +1,99 @@
+package orchestrator
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/registry"
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckStatic(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range []struct{ name, content string }{
+		{"package/package.json", `{"name":"evil-pkg","scripts":{"postinstall":"node postinstall.js"}}`},
+		{"package/postinstall.js", "eval(Buffer.from('Y29uc29sZS5sb2coMSk=', 'base64').toString());\n"},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content))}))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	tarball := buf.Bytes()
+	sum := sha512.Sum512(tarball)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(tarball)
+	}))
+	defer srv.Close()
+
+	evil := models.Package{ID: "evil-pkg@1.0.0", Name: "evil-pkg", Version: "1.0.0"}
+	tampered := models.Package{ID: "tampered@1.0.0", Name: "tampered", Version: "1.0.0"}
+	local := models.Package{ID: "local@1.0.0", Name: "local", Version: "1.0.0"}
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{Package: evil, ResolvedURL: srv.URL + "/evil-pkg-1.0.0.tgz", Integrity: "sha512-" + base64.StdEncoding.EncodeToString(sum[:])})
+	graph.AddNode(&models.PackageNode{Package: tampered, ResolvedURL: srv.URL + "/tampered-1.0.0.tgz", Integrity: "sha512-AAAA"})
+	graph.AddNode(&models.PackageNode{Package: local, ResolvedURL: "file:../local"})
+
+	// kept was left in the tarball directory by the uploader
+	kept := models.Package{ID: "kept@1.0.0", Name: "kept", Version: "1.0.0"}
+	graph.AddNode(&models.PackageNode{Package: kept, ResolvedURL: "http://127.0.0.1:1/kept-1.0.0.tgz"})
+	tarballDir := t.TempDir()
+	require.NoError(t, os.WriteFile(registry.TarballPath(tarballDir, kept.Name, kept.Version), tarball, 0o644))
+
+	o := &Orchestrator{graph: graph}
+	o.SetTarballDir(tarballDir)
+	outputDir := t.TempDir()
+	o.checkStatic(t.Context(), []models.Package{evil, tampered, local, kept}, outputDir)
+	require.Len(t, o.staticReports, 2)
+	require.Len(t, o.staticReports[evil].Evidence, 1)
+	assert.Equal(t, "postinstall.js", o.staticReports[evil].Evidence[0].Path)
+	assert.Equal(t, []string{"eval of decoded string"}, o.staticReports[evil].Evidence[0].Reasons)
+	assert.Equal(t, []string{static.KindObfuscated, static.KindInstallScript}, o.staticReports[evil].Kinds())
+	assert.Equal(t, o.staticReports[evil], o.staticReports[kept])
+
+	score, ok := graph.Nodes[evil.ID].FloatAnnotation(AnnotationStaticRisk)
+	assert.True(t, ok)
+	assert.Equal(t, 0.8, score)
+
+	data, err := os.ReadFile(filepath.Join(outputDir, StaticFile))
+	require.NoError(t, err)
+	var report StaticScanReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 2, report.Scanned)
+	require.Len(t, report.Flagged, 2)
+	assert.Equal(t, "evil-pkg@1.0.0", report.Flagged[0].Package)
+	assert.Equal(t, 0.8, report.Flagged[0].Score)
+	assert.Equal(t, o.StaticFlagged(), report.Flagged)
+
+	hooks := graph.Nodes[evil.ID].StringsAnnotation(AnnotationInstallScripts)
+	assert.Equal(t, []string{"postinstall"}, hooks)
+	data, err = os.ReadFile(filepath.Join(outputDir, naming.DirName(evil.Name, evil.Version), InstallScriptsFile))
+	require.NoError(t, err)
+	var scripts []static.InstallScript
+	require.NoError(t, json.Unmarshal(data, &scripts))
+	require.Len(t, scripts, 1)
+	assert.Equal(t, "node postinstall.js", scripts[0].Command)
+	require.Len(t, scripts[0].Files, 1)
+	assert.Contains(t, scripts[0].Files[0].Content, "eval(Buffer.from(")
+
+	o.offline = true
+	o.checkStatic(t.Context(), []models.Package{evil}, outputDir)
+	assert.Nil(t, o.staticReports)
+}
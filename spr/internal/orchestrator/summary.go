@@ -0,0 +1,124 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/scoring"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Summary is the machine-readable outcome of a run, for CI steps to parse
+// instead of the human-readable output
+type Summary struct {
+	RunID     string           `json:"run_id,omitempty"`
+	OutputDir string           `json:"output_dir"`
+	Passed    bool             `json:"passed"` // No package failed or was found malicious, and no gate tripped
+	Error     string           `json:"error,omitempty"`
+	MaxRisk   int              `json:"max_risk,omitempty"` // Risk gate, zero when disabled
+	Packages  []PackageSummary `json:"packages"`
+	Generator *version.Info    `json:"generator,omitempty"`
+}
+
+// PackageSummary is one package's outcome in a Summary
+type PackageSummary struct {
+	Package    string  `json:"package"`
+	Version    string  `json:"version"`
+	Verdict    string  `json:"verdict"` // One of the models.Verdict* values
+	Confidence float64 `json:"confidence,omitempty"`
+	Risk       int     `json:"risk"` // Combined risk score, 0-100, see scoring
+	// What the verdict and score are based on: the factors of the score,
+	// the diff's behavioral risk flags and the AI's indicators
+	Factors       []scoring.Factor `json:"factors,omitempty"`
+	RiskFlags     []string         `json:"risk_flags,omitempty"`
+	Indicators    []string         `json:"indicators,omitempty"`
+	Justification string           `json:"justification,omitempty"`
+	WorkflowRun   int64            `json:"workflow_run,omitempty"`
+	Error         string           `json:"error,omitempty"`
+	// Artifacts are the files in the package's output directory
+	Artifacts []string `json:"artifacts,omitempty"`
+}
+
+// NewSummary returns a passing summary of a run without packages, e.g. one
+// that stopped before analysis
+func NewSummary(outputDir string) *Summary {
+	return &Summary{OutputDir: outputDir, Passed: true, Packages: []PackageSummary{}, Generator: version.Stamp()}
+}
+
+// Summary summarizes the results of the last RunPackages, sorted by package.
+// Verdicts and risk flags are read back from outputDir, so packages without
+// results there are reported as not analyzed.
+func (o *Orchestrator) Summary(results []PackageResult, outputDir string) (*Summary, error) {
+	packages := make([]models.Package, len(results))
+	for i, result := range results {
+		packages[i] = result.Package
+	}
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := NewSummary(outputDir)
+	for _, result := range results {
+		pkg := result.Package
+		assessment, analyzed := verdicts[pkg.Name+"@"+pkg.Version]
+		ps := PackageSummary{
+			Package:     pkg.Name,
+			Version:     pkg.Version,
+			RiskFlags:   o.riskSignals(pkg, outputDir).RiskFlags,
+			WorkflowRun: result.RunID,
+		}
+		ps.Verdict, ps.Confidence = verdictOf(assessment, analyzed)
+		if ps.Verdict == models.VerdictMalicious {
+			summary.Passed = false
+		}
+		if assessment != nil {
+			ps.Indicators = assessment.Indicators
+			ps.Justification = assessment.Justification
+		}
+		if score, ok := o.riskScores[pkg]; ok {
+			ps.Risk, ps.Factors = score.Score, score.Factors
+		}
+		if result.Error != nil {
+			ps.Error = result.Error.Error()
+			summary.Passed = false
+		}
+		if analyzed {
+			pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+			entries, _ := os.ReadDir(pkgDir)
+			for _, entry := range entries {
+				if !entry.IsDir() {
+					ps.Artifacts = append(ps.Artifacts, filepath.Join(pkgDir, entry.Name()))
+				}
+			}
+		}
+		summary.Packages = append(summary.Packages, ps)
+	}
+	sort.Slice(summary.Packages, func(i, j int) bool {
+		a, b := summary.Packages[i], summary.Packages[j]
+		if a.Package != b.Package {
+			return a.Package < b.Package
+		}
+		return a.Version < b.Version
+	})
+	return summary, nil
+}
+
+// verdictOf returns the verdict and confidence of a package given its entry
+// in LoadVerdicts
+func verdictOf(assessment *analysis.SecurityAssessment, analyzed bool) (string, float64) {
+	switch {
+	case !analyzed:
+		return models.VerdictNotAnalyzed, 0
+	case assessment == nil:
+		return models.VerdictClean, 0
+	case assessment.IsMalicious:
+		return models.VerdictMalicious, assessment.Confidence
+	default:
+		return models.VerdictSafe, assessment.Confidence
+	}
+}
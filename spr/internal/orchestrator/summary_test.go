@@ -0,0 +1,90 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSummary(t *testing.T) {
+	evil := models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"}
+	clean := models.Package{ID: "clean@2.0.0", Name: "clean", Version: "2.0.0"}
+	failed := models.Package{ID: "failed@1.0.0", Name: "failed", Version: "1.0.0"}
+
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"evil@1.0.0/ai-analysis.json": `{"is_malicious":true,"confidence":0.9,"justification":"exfiltrates env","indicators":["reads NPM_TOKEN"]}`,
+		"evil@1.0.0/diff.json":        `{"risk_flags":["env_secret_access"]}`,
+		"clean@2.0.0/diff.json":       "{}",
+	})
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	packages := []models.Package{evil, clean, failed}
+	o.scorePackages(packages, outputDir)
+	summary, err := o.Summary([]PackageResult{
+		{Package: evil, Success: true, RunID: 42},
+		{Package: clean, Success: true},
+		{Package: failed, Error: errors.New("workflow timed out")},
+	}, outputDir)
+	require.NoError(t, err)
+
+	assert.False(t, summary.Passed, "a package failed")
+	assert.Equal(t, outputDir, summary.OutputDir)
+	require.Len(t, summary.Packages, 3)
+	assert.Equal(t, []string{"clean", "evil", "failed"}, []string{summary.Packages[0].Package, summary.Packages[1].Package, summary.Packages[2].Package})
+
+	cleanSummary := summary.Packages[0]
+	assert.Equal(t, models.VerdictClean, cleanSummary.Verdict)
+	assert.Zero(t, cleanSummary.Risk)
+	assert.Equal(t, []string{filepath.Join(outputDir, "clean@2.0.0", "diff.json"), filepath.Join(outputDir, "clean@2.0.0", RiskFile)}, cleanSummary.Artifacts)
+
+	evilSummary := summary.Packages[1]
+	assert.Equal(t, models.VerdictMalicious, evilSummary.Verdict)
+	assert.Equal(t, 0.9, evilSummary.Confidence)
+	assert.Equal(t, o.RiskScores()[evil].Score, evilSummary.Risk)
+	assert.NotEmpty(t, evilSummary.Factors)
+	assert.Equal(t, []string{"env_secret_access"}, evilSummary.RiskFlags)
+	assert.Equal(t, []string{"reads NPM_TOKEN"}, evilSummary.Indicators)
+	assert.Equal(t, "exfiltrates env", evilSummary.Justification)
+	assert.Equal(t, int64(42), evilSummary.WorkflowRun)
+	assert.Len(t, evilSummary.Artifacts, 3)
+
+	failedSummary := summary.Packages[2]
+	assert.Equal(t, models.VerdictNotAnalyzed, failedSummary.Verdict)
+	assert.Equal(t, "workflow timed out", failedSummary.Error)
+	assert.Empty(t, failedSummary.Artifacts)
+}
+
+func TestSummaryMaliciousFails(t *testing.T) {
+	evil := models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"}
+	clean := models.Package{ID: "clean@2.0.0", Name: "clean", Version: "2.0.0"}
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"evil@1.0.0/ai-analysis.json": `{"is_malicious":true,"confidence":0.2}`,
+		"clean@2.0.0/diff.json":       "{}",
+	})
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	summary, err := o.Summary([]PackageResult{{Package: clean, Success: true}}, outputDir)
+	require.NoError(t, err)
+	assert.True(t, summary.Passed)
+
+	// Every package ran fine, but one of them is malicious
+	summary, err = o.Summary([]PackageResult{{Package: evil, Success: true}, {Package: clean, Success: true}}, outputDir)
+	require.NoError(t, err)
+	assert.False(t, summary.Passed)
+}
+
+func TestNewSummary(t *testing.T) {
+	data, err := json.Marshal(NewSummary("out"))
+	require.NoError(t, err)
+	// An empty list rather than null, like summaries of runs with packages
+	assert.Contains(t, string(data), `"packages":[]`)
+	assert.Contains(t, string(data), `"passed":true`)
+}
@@ -0,0 +1,66 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/telemetry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// SetTelemetry opts in to reporting anonymous counts and stage timings of
+// each run to endpoint, see telemetry.Report. An empty endpoint disables it.
+func (o *Orchestrator) SetTelemetry(endpoint string) {
+	o.telemetry = nil
+	if endpoint != "" {
+		o.telemetry = telemetry.NewClient(endpoint)
+	}
+}
+
+// tallyVerdicts counts the verdicts of packages into report
+func (o *Orchestrator) tallyVerdicts(report *telemetry.Report, packages []models.Package, outputDir string) {
+	if o.telemetry == nil {
+		return
+	}
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for telemetry: %v", err), "warning", logging.KeyStage, "telemetry")
+		return
+	}
+	for _, pkg := range packages {
+		assessment, analyzed := verdicts[pkg.Name+"@"+pkg.Version]
+		switch {
+		case !analyzed:
+			report.Verdicts[models.VerdictNotAnalyzed]++
+		case assessment == nil:
+			report.Verdicts[models.VerdictClean]++
+		case assessment.IsMalicious:
+			report.Verdicts[models.VerdictMalicious]++
+		default:
+			report.Verdicts[models.VerdictSafe]++
+		}
+	}
+	if len(o.trusted) > 0 {
+		report.Verdicts[models.VerdictTrusted] = len(o.trusted)
+	}
+}
+
+// sendTelemetry posts report, timing the run from start. It doesn't use the
+// run's context so a report is sent even for runs cut short by a failure.
+// Failures are logged, not returned.
+func (o *Orchestrator) sendTelemetry(report *telemetry.Report, start time.Time) {
+	if o.telemetry == nil {
+		return
+	}
+	report.Time(telemetry.StageTotal, time.Since(start))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := o.telemetry.Send(ctx, report); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to send telemetry: %v", err), "warning", logging.KeyStage, "telemetry")
+		return
+	}
+	o.logMsg("Sent anonymous telemetry report", "info", logging.KeyStage, "telemetry")
+}
@@ -0,0 +1,58 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/telemetry"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTelemetry(t *testing.T) {
+	outputDir := t.TempDir()
+	writeFiles(t, outputDir, map[string]string{
+		"left-pad@1.0.0/diff.json":        "{}",
+		"left-pad@1.0.0/ai-analysis.json": `{"is_malicious":true,"confidence":0.9}`,
+		"is-odd@1.0.0/diff.json":          "{}",
+	})
+	packages := []models.Package{
+		{Name: "left-pad", Version: "1.0.0"},
+		{Name: "is-odd", Version: "1.0.0"},
+		{Name: "lodash", Version: "1.0.0"},
+	}
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	report := telemetry.NewReport()
+	report.Packages = len(packages)
+
+	// Disabled by default
+	o.tallyVerdicts(report, packages, outputDir)
+	o.sendTelemetry(report, time.Now())
+	assert.Empty(t, report.Verdicts)
+	assert.Nil(t, body)
+
+	o.SetTelemetry(server.URL)
+	o.tallyVerdicts(report, packages, outputDir)
+	o.sendTelemetry(report, time.Now())
+	require.NotNil(t, body)
+
+	var sent telemetry.Report
+	require.NoError(t, json.Unmarshal(body, &sent))
+	assert.Equal(t, map[string]int{models.VerdictMalicious: 1, models.VerdictClean: 1, models.VerdictNotAnalyzed: 1}, sent.Verdicts)
+	assert.Contains(t, sent.StageSeconds, telemetry.StageTotal)
+	for _, pkg := range packages {
+		assert.NotContains(t, string(body), pkg.Name)
+	}
+}
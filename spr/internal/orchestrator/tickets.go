@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/internal/ticketing"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// ticketEvidence are the analysis artifacts attached to tickets
+var ticketEvidence = []string{"diff.json", "ai-analysis.json"}
+
+// SetTicketing opens a ticket per flagged package of each run through filer,
+// unless one is already open for the same package@version. nil disables it.
+func (o *Orchestrator) SetTicketing(filer *ticketing.Filer) {
+	o.tickets = filer
+}
+
+// fileTickets files a ticket for each flagged package among packages.
+// Failures are logged, not returned.
+func (o *Orchestrator) fileTickets(ctx context.Context, packages []models.Package, outputDir string) {
+	if o.tickets == nil {
+		return
+	}
+	verdicts, err := LoadVerdicts(outputDir, packages)
+	if err != nil {
+		o.logMsg(fmt.Sprintf("Failed to load verdicts for ticketing: %v", err), "warning", logging.KeyStage, "tickets")
+		return
+	}
+
+	for _, pkg := range packages {
+		assessment := verdicts[pkg.Name+"@"+pkg.Version]
+		if assessment == nil || !assessment.IsMalicious {
+			continue
+		}
+
+		finding := ticketing.Finding{
+			Package:       pkg.Name,
+			Version:       pkg.Version,
+			Confidence:    assessment.Confidence,
+			Justification: assessment.Justification,
+			Indicators:    assessment.Indicators,
+			RunID:         o.resultsLogRunID,
+		}
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
+		for _, name := range ticketEvidence {
+			if data, err := os.ReadFile(filepath.Join(pkgDir, name)); err == nil {
+				finding.Evidence = append(finding.Evidence, ticketing.Attachment{Name: name, Data: data})
+			}
+		}
+
+		fileCtx, cancel := context.WithTimeout(ctx, time.Minute)
+		id, created, err := o.tickets.File(fileCtx, finding)
+		cancel()
+		switch {
+		case err != nil:
+			o.logMsg(fmt.Sprintf("Failed to file ticket for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", pkgAttrs(pkg.Name, pkg.Version, "tickets")...)
+		case created:
+			o.logMsg(fmt.Sprintf("Opened ticket %s for %s@%s", id, pkg.Name, pkg.Version), "success", pkgAttrs(pkg.Name, pkg.Version, "tickets")...)
+		default:
+			o.logMsg(fmt.Sprintf("Ticket %s is already open for %s@%s", id, pkg.Name, pkg.Version), "info", pkgAttrs(pkg.Name, pkg.Version, "tickets")...)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// errWorkflowTimeout is returned when a workflow run doesn't complete within
+// the package's timeout
+var errWorkflowTimeout = errors.New("timeout waiting for workflow completion")
+
+// ParsePackageTimeouts parses per-package timeout overrides of the form
+// "name=20m,@scope/pkg@1.2.3=45m". Keys are a package name (any version) or
+// name@version; values are Go durations or a bare number of minutes. An
+// empty spec returns no overrides.
+func ParsePackageTimeouts(spec string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		i := strings.LastIndex(entry, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("package timeout %q: expected name=duration", entry)
+		}
+		key, value := strings.TrimSpace(entry[:i]), strings.TrimSpace(entry[i+1:])
+
+		var d time.Duration
+		if minutes, err := strconv.Atoi(value); err == nil {
+			d = time.Duration(minutes) * time.Minute
+		} else if d, err = time.ParseDuration(value); err != nil {
+			return nil, fmt.Errorf("package timeout %q: invalid duration %q", entry, value)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("package timeout %q: duration must be positive", entry)
+		}
+		timeouts[key] = d
+	}
+	return timeouts, nil
+}
+
+// SetPackageTimeouts overrides the workflow timeout for individual packages,
+// keyed by name@version or name (see ParsePackageTimeouts)
+func (o *Orchestrator) SetPackageTimeouts(timeouts map[string]time.Duration) {
+	o.packageTimeouts = timeouts
+}
+
+// SetRetries sets how many times a workflow run that fails or times out is
+// re-triggered before the package is given up on
+func (o *Orchestrator) SetRetries(n int) {
+	if n < 0 {
+		n = 0
+	}
+	o.retries = n
+}
+
+// timeoutFor returns the workflow timeout of pkg: an exact name@version
+// override, then a name override, then the global timeout
+func (o *Orchestrator) timeoutFor(pkg models.Package) time.Duration {
+	if d, ok := o.packageTimeouts[pkg.Name+"@"+pkg.Version]; ok {
+		return d
+	}
+	if d, ok := o.packageTimeouts[pkg.Name]; ok {
+		return d
+	}
+	return o.timeout
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePackageTimeouts(t *testing.T) {
+	timeouts, err := ParsePackageTimeouts("esbuild=20m, @scope/pkg@1.2.3=45 ,sharp=1h30m")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{
+		"esbuild":          20 * time.Minute,
+		"@scope/pkg@1.2.3": 45 * time.Minute,
+		"sharp":            90 * time.Minute,
+	}, timeouts)
+
+	timeouts, err = ParsePackageTimeouts("")
+	require.NoError(t, err)
+	assert.Empty(t, timeouts)
+
+	for _, spec := range []string{"esbuild", "=20m", "esbuild=soon", "esbuild=0", "esbuild=-5m"} {
+		_, err := ParsePackageTimeouts(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestTimeoutFor(t *testing.T) {
+	o := &Orchestrator{timeout: 5 * time.Minute}
+	o.SetPackageTimeouts(map[string]time.Duration{
+		"esbuild":       20 * time.Minute,
+		"esbuild@0.1.0": 45 * time.Minute,
+	})
+
+	assert.Equal(t, 45*time.Minute, o.timeoutFor(models.Package{Name: "esbuild", Version: "0.1.0"}))
+	assert.Equal(t, 20*time.Minute, o.timeoutFor(models.Package{Name: "esbuild", Version: "0.2.0"}))
+	assert.Equal(t, 5*time.Minute, o.timeoutFor(models.Package{Name: "left-pad", Version: "1.3.0"}))
+
+	o.SetRetries(-1)
+	assert.Equal(t, 0, o.retries)
+}
@@ -0,0 +1,103 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/typosquat"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// TyposquatFile records the typosquatting check of a run in its output
+// directory
+const TyposquatFile = "typosquat.json"
+
+// AnnotationTyposquat is the graph annotation holding the popular package a
+// likely typosquat imitates
+const AnnotationTyposquat = "typosquat_of"
+
+// TyposquatReport is the content of TyposquatFile
+type TyposquatReport struct {
+	CheckedAt time.Time           `json:"checked_at"`
+	Checked   int                 `json:"checked"` // Dependencies compared
+	Findings  []typosquat.Finding `json:"findings"`
+}
+
+// SetTyposquatCheck compares every dependency name against popular packages
+// before any workflow is triggered. Likely typosquats are logged and
+// annotated on the graph, and still analyzed. Nil disables the check.
+func (o *Orchestrator) SetTyposquatCheck(checker *typosquat.Checker) {
+	o.typosquatChecker = checker
+}
+
+// TyposquatFindings returns the likely typosquats found by the last run
+func (o *Orchestrator) TyposquatFindings() []typosquat.Finding {
+	return o.typosquatFindings
+}
+
+// checkTyposquats runs the typosquatting check over the graph and records it
+// in TyposquatFile
+func (o *Orchestrator) checkTyposquats(outputDir string) {
+	o.typosquatFindings = nil
+	if o.typosquatChecker == nil || o.graph == nil {
+		return
+	}
+
+	nodes := make([]*models.PackageNode, 0, len(o.graph.Nodes))
+	for id, node := range o.graph.Nodes {
+		if o.graph.RootPackage == nil || id != o.graph.RootPackage.ID {
+			nodes = append(nodes, node)
+		}
+	}
+	report := TyposquatReport{
+		CheckedAt: time.Now().UTC(),
+		Checked:   len(nodes),
+		Findings:  o.typosquatChecker.Check(nodes),
+	}
+	for _, finding := range report.Findings {
+		o.graph.Annotate(finding.Package, AnnotationTyposquat, finding.Target)
+		o.logMsg(fmt.Sprintf("Likely typosquat: %s imitates %s (%s)", finding.Package, finding.Target, describeTyposquat(finding)), "error",
+			logging.KeyPackageID, finding.Package, logging.KeyStage, "typosquat")
+	}
+	o.typosquatFindings = report.Findings
+	o.logMsg(fmt.Sprintf("%d dependency names checked for typosquatting, %d findings", report.Checked, len(report.Findings)), "info", logging.KeyStage, "typosquat")
+
+	if err := writeTyposquatReport(outputDir, &report); err != nil {
+		o.logMsg(fmt.Sprintf("Failed to write %s: %v", TyposquatFile, err), "warning", logging.KeyStage, "typosquat")
+	}
+}
+
+// describeTyposquat explains a finding
+func describeTyposquat(f typosquat.Finding) string {
+	switch f.Kind {
+	case typosquat.KindHomoglyph:
+		return "look-alike characters"
+	case typosquat.KindSeparator:
+		return "different separators"
+	case typosquat.KindEditDistance:
+		if f.Distance == 1 {
+			return "one character off"
+		}
+		return fmt.Sprintf("%d characters off", f.Distance)
+	}
+	return f.Kind
+}
+
+// writeTyposquatReport writes TyposquatFile to outputDir
+func writeTyposquatReport(outputDir string, report *TyposquatReport) error {
+	if outputDir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(outputDir, 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(outputDir, TyposquatFile), data, 0o644)
+}
@@ -0,0 +1,39 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/typosquat"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckTyposquats(t *testing.T) {
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "axois@0.1.0", Name: "axois", Version: "0.1.0"}})
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "axios@1.7.0", Name: "axios", Version: "1.7.0"}})
+
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, graph)
+	outputDir := t.TempDir()
+	o.checkTyposquats(outputDir)
+	assert.Empty(t, o.TyposquatFindings(), "disabled without a checker")
+
+	o.SetTyposquatCheck(typosquat.NewChecker(nil))
+	o.checkTyposquats(outputDir)
+	require.Len(t, o.TyposquatFindings(), 1)
+	assert.Equal(t, "axios", graph.Nodes["axois@0.1.0"].StringAnnotation(AnnotationTyposquat))
+	assert.Empty(t, graph.Nodes["axios@1.7.0"].StringAnnotation(AnnotationTyposquat))
+
+	data, err := os.ReadFile(filepath.Join(outputDir, TyposquatFile))
+	require.NoError(t, err)
+	var report TyposquatReport
+	require.NoError(t, json.Unmarshal(data, &report))
+	assert.Equal(t, 2, report.Checked)
+	require.Len(t, report.Findings, 1)
+	assert.Equal(t, typosquat.KindEditDistance, report.Findings[0].Kind)
+}
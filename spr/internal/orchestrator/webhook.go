@@ -0,0 +1,88 @@
+package orchestrator
+
+import (
+	"sync"
+	"time"
+)
+
+// webhookPollInterval is the fallback poll interval when completions are
+// pushed by webhook, in case a delivery is lost
+const webhookPollInterval = 2 * time.Minute
+
+// maxCompletedRuns bounds how many completions are remembered for runs
+// nobody was waiting on yet
+const maxCompletedRuns = 1024
+
+// RunWaker wakes the orchestrators waiting on workflow runs when GitHub
+// reports them completed through a workflow_run webhook, instead of leaving
+// them to find out on their next poll. One waker is shared by all analyses.
+type RunWaker struct {
+	mu        sync.Mutex
+	waiters   map[int64][]chan struct{}
+	completed map[int64]bool
+	order     []int64 // Completed run IDs, oldest first
+}
+
+// NewRunWaker creates a waker with nobody waiting
+func NewRunWaker() *RunWaker {
+	return &RunWaker{
+		waiters:   make(map[int64][]chan struct{}),
+		completed: make(map[int64]bool),
+	}
+}
+
+// Notify records that a run completed and wakes everyone waiting on it. A
+// completion that arrives before anyone waits is remembered, so a run that
+// finishes quickly isn't missed.
+func (w *RunWaker) Notify(runID int64) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for _, ch := range w.waiters[runID] {
+		close(ch)
+	}
+	delete(w.waiters, runID)
+
+	if !w.completed[runID] {
+		w.completed[runID] = true
+		w.order = append(w.order, runID)
+		if len(w.order) > maxCompletedRuns {
+			delete(w.completed, w.order[0])
+			w.order = w.order[1:]
+		}
+	}
+}
+
+// Wait returns a channel closed once the run is reported completed, and a
+// function to stop waiting that must be called when done with it
+func (w *RunWaker) Wait(runID int64) (<-chan struct{}, func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	ch := make(chan struct{})
+	if w.completed[runID] {
+		close(ch)
+		return ch, func() {}
+	}
+	w.waiters[runID] = append(w.waiters[runID], ch)
+	return ch, func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		waiters := w.waiters[runID]
+		for i, c := range waiters {
+			if c == ch {
+				w.waiters[runID] = append(waiters[:i], waiters[i+1:]...)
+				break
+			}
+		}
+		if len(w.waiters[runID]) == 0 {
+			delete(w.waiters, runID)
+		}
+	}
+}
+
+// SetRunWaker lets workflow_run webhooks wake the wait for a run instead of
+// polling GitHub every 15 seconds; polling drops to a slow fallback
+func (o *Orchestrator) SetRunWaker(w *RunWaker) {
+	o.runWaker = w
+}
@@ -0,0 +1,70 @@
+package orchestrator
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunWaker(t *testing.T) {
+	w := NewRunWaker()
+
+	ch, stop := w.Wait(1)
+	select {
+	case <-ch:
+		t.Fatal("woken before completion")
+	default:
+	}
+	w.Notify(1)
+	<-ch
+	stop()
+
+	// A completion arriving before the wait isn't lost
+	w.Notify(2)
+	ch, stop = w.Wait(2)
+	<-ch
+	stop()
+
+	_, stop = w.Wait(3)
+	stop()
+	assert.Empty(t, w.waiters)
+}
+
+func TestPollWorkflowCompletionWokenByWebhook(t *testing.T) {
+	o := NewOrchestrator("token", "owner", "repo", "analyze.yml", 1, time.Minute, nil, "", "", nil, nil)
+	waker := NewRunWaker()
+	o.SetRunWaker(waker)
+
+	var calls atomic.Int32
+	polls := make(chan struct{}, 10)
+	o.client.HTTPClient = &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		polls <- struct{}{}
+		status := "in_progress"
+		if calls.Add(1) > 1 {
+			status = "completed"
+		}
+		return &http.Response{StatusCode: http.StatusOK, Header: http.Header{}, Body: io.NopCloser(strings.NewReader(`{"id":7,"status":"` + status + `","conclusion":"success"}`))}, nil
+	})}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := o.pollWorkflowCompletion(t.Context(), 7, time.Minute)
+		done <- err
+	}()
+	<-polls
+	waker.Notify(7)
+
+	// Without the webhook the next poll would be minutes away
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("poll not woken by webhook")
+	}
+}
@@ -0,0 +1,54 @@
+package parser
+
+import "strings"
+
+// aliasPrefix marks an npm alias dependency, e.g. "foo": "npm:bar@^1.2.3"
+const aliasPrefix = "npm:"
+
+// ParseAlias splits an npm alias spec ("npm:bar@^1.2.3", "npm:@scope/bar@1")
+// into the real package name and version range. A spec without a version
+// resolves the latest tag. ok is false for anything that isn't an alias.
+func ParseAlias(spec string) (name, version string, ok bool) {
+	target, found := strings.CutPrefix(spec, aliasPrefix)
+	if !found || target == "" {
+		return "", "", false
+	}
+
+	// The version separator is the first @ after any scope
+	at := strings.Index(target[1:], "@") + 1
+	if at == 0 {
+		return target, "latest", true
+	}
+	name, version = target[:at], target[at+1:]
+	if version == "" {
+		version = "latest"
+	}
+	return name, version, true
+}
+
+// resolveAliases returns deps with aliased entries keyed by the real package
+// name and version range, so dependency names match graph nodes. An alias
+// whose real name is also a direct entry keeps its alias key.
+func resolveAliases(deps map[string]string) map[string]string {
+	if deps == nil {
+		return nil
+	}
+	resolved := make(map[string]string, len(deps))
+	for name, spec := range deps {
+		if _, _, ok := ParseAlias(spec); !ok {
+			resolved[name] = spec
+		}
+	}
+	for name, spec := range deps {
+		real, version, ok := ParseAlias(spec)
+		if !ok {
+			continue
+		}
+		if _, taken := resolved[real]; taken {
+			resolved[name] = spec
+			continue
+		}
+		resolved[real] = version
+	}
+	return resolved
+}
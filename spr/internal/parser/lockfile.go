@@ -6,6 +6,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/acheong08/hackeurope-spr/pkg/models"
@@ -19,12 +20,14 @@ type PackageLockV3 struct {
 
 // PackageLockPackage represents a single package entry in lockfile
 type PackageLockPackage struct {
-	Version         string            `json:"version"`
-	Resolved        string            `json:"resolved"`
-	Integrity       string            `json:"integrity"`
-	Dependencies    map[string]string `json:"dependencies"`
-	DevDependencies map[string]string `json:"devDependencies"`
-	Dev             bool              `json:"dev"`
+	Name             string            `json:"name"` // Real package name of an alias (node_modules/<alias>)
+	Version          string            `json:"version"`
+	Resolved         string            `json:"resolved"`
+	Integrity        string            `json:"integrity"`
+	Dependencies     map[string]string `json:"dependencies"`
+	DevDependencies  map[string]string `json:"devDependencies"`
+	Dev              bool              `json:"dev"`
+	HasInstallScript bool              `json:"hasInstallScript"`
 }
 
 // LockfileManager handles generation and parsing of lockfiles
@@ -120,17 +123,14 @@ func (lm *LockfileManager) ParseLockfile(lockfilePath string, rootPackage *model
 		return nil, fmt.Errorf("failed to read lockfile: %w", err)
 	}
 
-	var lockfile PackageLockV3
-	if err := json.Unmarshal(data, &lockfile); err != nil {
-		return nil, fmt.Errorf("failed to parse lockfile: %w", err)
-	}
-
-	if lockfile.LockfileVersion != 3 {
-		return nil, fmt.Errorf("unsupported lockfile version: %d (expected 3)", lockfile.LockfileVersion)
+	lockfile, err := decodeLockfile(data)
+	if err != nil {
+		return nil, err
 	}
 
 	graph := models.NewDependencyGraph()
 	graph.RootPackage = rootPackage
+	pathIDs := map[string]string{"": rootPackage.ID} // Install path -> node ID
 
 	// First pass: collect all packages
 	for path, pkg := range lockfile.Packages {
@@ -144,6 +144,10 @@ func (lm *LockfileManager) ParseLockfile(lockfilePath string, rootPackage *model
 		if name == "" {
 			continue
 		}
+		if pkg.Name != "" {
+			// Aliased dependency: installed under the alias, published as pkg.Name
+			name = pkg.Name
+		}
 
 		node := &models.PackageNode{
 			Package: models.Package{
@@ -151,12 +155,19 @@ func (lm *LockfileManager) ParseLockfile(lockfilePath string, rootPackage *model
 				Name:    name,
 				Version: pkg.Version,
 			},
-			ResolvedURL:  pkg.Resolved,
-			Integrity:    pkg.Integrity,
-			Dependencies: pkg.Dependencies,
+			ResolvedURL:      pkg.Resolved,
+			Integrity:        pkg.Integrity,
+			Dependencies:     resolveAliases(pkg.Dependencies),
+			Dev:              pkg.Dev,
+			HasInstallScript: pkg.HasInstallScript,
+		}
+		if existing, ok := graph.Nodes[node.ID]; ok {
+			// Installed at several paths: it's dev-only if every copy is
+			node.Dev = node.Dev && existing.Dev
 		}
 
 		graph.AddNode(node)
+		pathIDs[path] = node.ID
 	}
 
 	// Second pass: extract root dependencies
@@ -173,14 +184,155 @@ func (lm *LockfileManager) ParseLockfile(lockfilePath string, rootPackage *model
 		// Add root node with its dependencies
 		rootNode := &models.PackageNode{
 			Package:      *rootPackage,
-			Dependencies: allRootDeps,
+			Dependencies: resolveAliases(allRootDeps),
 		}
 		graph.AddNode(rootNode)
 	}
 
+	// Third pass: resolve dependencies to the nodes actually installed
+	resolveDependencies(graph, lockfile, pathIDs)
+
 	return graph, nil
 }
 
+// decodeLockfile parses a version 3 package-lock.json
+func decodeLockfile(data []byte) (PackageLockV3, error) {
+	var lockfile PackageLockV3
+	if err := json.Unmarshal(data, &lockfile); err != nil {
+		return lockfile, fmt.Errorf("failed to parse lockfile: %w", err)
+	}
+	if lockfile.LockfileVersion != 3 {
+		return lockfile, fmt.Errorf("unsupported lockfile version: %d (expected 3)", lockfile.LockfileVersion)
+	}
+	return lockfile, nil
+}
+
+// LockfilePackages returns every package version a package-lock.json
+// installs, once each and sorted by ID. Unlike ParseLockfile it needs no
+// root package and builds no graph.
+func LockfilePackages(data []byte) ([]models.Package, error) {
+	lockfile, err := decodeLockfile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	var packages []models.Package
+	for path, pkg := range lockfile.Packages {
+		// The root (path "") is the project itself
+		name := extractPackageName(path)
+		if pkg.Name != "" && name != "" {
+			name = pkg.Name
+		}
+		// Links to local workspaces have no version
+		if name == "" || pkg.Version == "" {
+			continue
+		}
+		id := name + "@" + pkg.Version
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		packages = append(packages, models.Package{ID: id, Name: name, Version: pkg.Version})
+	}
+	sort.Slice(packages, func(i, j int) bool { return packages[i].ID < packages[j].ID })
+	return packages, nil
+}
+
+// DirectInstalls maps each dependency installed at the top of node_modules,
+// by the name it is declared under in package.json, to the package version
+// installed there (the real package for aliases)
+func DirectInstalls(data []byte) (map[string]models.Package, error) {
+	lockfile, err := decodeLockfile(data)
+	if err != nil {
+		return nil, err
+	}
+
+	installs := make(map[string]models.Package)
+	for path, pkg := range lockfile.Packages {
+		declared, ok := strings.CutPrefix(path, "node_modules/")
+		if !ok || strings.Contains(declared, "/node_modules/") || pkg.Version == "" {
+			continue
+		}
+		name := declared
+		if pkg.Name != "" {
+			name = pkg.Name
+		}
+		installs[declared] = models.Package{ID: name + "@" + pkg.Version, Name: name, Version: pkg.Version}
+	}
+	return installs, nil
+}
+
+// resolveDependencies sets the ResolvedDependencies of every node. As with
+// Node's module resolution, a dependency of the package installed at path
+// resolves to the closest node_modules/<name> at or above path. A package
+// installed at several paths depends on the union of what each copy resolves.
+func resolveDependencies(graph *models.DependencyGraph, lockfile PackageLockV3, pathIDs map[string]string) {
+	resolved := make(map[string]map[string]bool) // Node ID -> dependency IDs
+	for path, pkg := range lockfile.Packages {
+		id, ok := pathIDs[path]
+		if !ok {
+			continue
+		}
+		if resolved[id] == nil {
+			resolved[id] = make(map[string]bool)
+		}
+		deps := pkg.Dependencies
+		if path == "" {
+			deps = make(map[string]string)
+			for name, spec := range pkg.Dependencies {
+				deps[name] = spec
+			}
+			for name, spec := range pkg.DevDependencies {
+				deps[name] = spec
+			}
+		}
+		// Dependency keys are install names, so aliases resolve by path too
+		for name := range deps {
+			if depID, ok := lookupInstalled(pathIDs, path, name); ok {
+				resolved[id][depID] = true
+			}
+		}
+	}
+
+	for id, deps := range resolved {
+		node, ok := graph.Nodes[id]
+		if !ok {
+			continue
+		}
+		node.ResolvedDependencies = make([]string, 0, len(deps))
+		for depID := range deps {
+			node.ResolvedDependencies = append(node.ResolvedDependencies, depID)
+		}
+		sort.Strings(node.ResolvedDependencies)
+	}
+}
+
+// lookupInstalled finds the node ID of dependency name for the package at
+// path, walking up through the enclosing node_modules directories
+func lookupInstalled(pathIDs map[string]string, path, name string) (string, bool) {
+	dir := path
+	for {
+		candidate := "node_modules/" + name
+		if dir != "" {
+			candidate = dir + "/" + candidate
+		}
+		if id, ok := pathIDs[candidate]; ok {
+			return id, true
+		}
+		if dir == "" {
+			return "", false
+		}
+		// Up to the package whose node_modules contains dir, or the root
+		i := strings.LastIndex(dir, "node_modules/")
+		if i < 0 {
+			dir = ""
+			continue
+		}
+		dir = strings.TrimSuffix(dir[:i], "/")
+	}
+}
+
 // Cleanup removes the temporary directory
 func (lm *LockfileManager) Cleanup() error {
 	if lm.TempDir != "" {
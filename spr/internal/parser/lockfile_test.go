@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/acheong08/hackeurope-spr/pkg/models"
@@ -73,3 +75,170 @@ func TestExtractPackageName(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLockfileAliases(t *testing.T) {
+	lockfile := `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "version": "1.0.0",
+      "dependencies": {"cliui": "npm:@isaacs/cliui@^8.0.2", "left-pad": "^1.3.0"}
+    },
+    "node_modules/cliui": {
+      "name": "@isaacs/cliui",
+      "version": "8.0.2",
+      "resolved": "https://registry.npmjs.org/@isaacs/cliui/-/cliui-8.0.2.tgz",
+      "dependencies": {"string-width-cjs": "npm:string-width@^4.2.0"}
+    },
+    "node_modules/string-width-cjs": {
+      "name": "string-width",
+      "version": "4.2.3",
+      "resolved": "https://registry.npmjs.org/string-width/-/string-width-4.2.3.tgz"
+    },
+    "node_modules/left-pad": {
+      "version": "1.3.0",
+      "resolved": "https://registry.npmjs.org/left-pad/-/left-pad-1.3.0.tgz"
+    }
+  }
+}`
+	path := filepath.Join(t.TempDir(), "package-lock.json")
+	require.NoError(t, os.WriteFile(path, []byte(lockfile), 0o644))
+
+	root := &models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	graph, err := NewLockfileManager().ParseLockfile(path, root)
+	require.NoError(t, err)
+
+	// Aliased packages are recorded under their real names
+	assert.Contains(t, graph.Nodes, "@isaacs/cliui@8.0.2")
+	assert.Contains(t, graph.Nodes, "string-width@4.2.3")
+	assert.NotContains(t, graph.Nodes, "cliui@8.0.2")
+	assert.Equal(t, map[string]string{"string-width": "^4.2.0"}, graph.Nodes["@isaacs/cliui@8.0.2"].Dependencies)
+
+	var direct []string
+	for _, dep := range graph.GetDirectDependencies() {
+		direct = append(direct, dep.ID)
+	}
+	assert.ElementsMatch(t, []string{"@isaacs/cliui@8.0.2", "left-pad@1.3.0"}, direct)
+}
+
+func TestParseLockfileDevAndInstallScripts(t *testing.T) {
+	lockfile := `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "version": "1.0.0",
+      "dependencies": {"esbuild": "^0.20.0"},
+      "devDependencies": {"jest": "^29.0.0"}
+    },
+    "node_modules/esbuild": {"version": "0.20.2", "hasInstallScript": true},
+    "node_modules/jest": {"version": "29.7.0", "dev": true, "dependencies": {"ms": "^2.1.0"}},
+    "node_modules/jest/node_modules/ms": {"version": "2.1.3", "dev": true},
+    "node_modules/esbuild/node_modules/ms": {"version": "2.1.3"}
+  }
+}`
+	path := filepath.Join(t.TempDir(), "package-lock.json")
+	require.NoError(t, os.WriteFile(path, []byte(lockfile), 0o644))
+
+	root := &models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	graph, err := NewLockfileManager().ParseLockfile(path, root)
+	require.NoError(t, err)
+
+	assert.True(t, graph.Nodes["esbuild@0.20.2"].HasInstallScript)
+	assert.False(t, graph.Nodes["esbuild@0.20.2"].Dev)
+	assert.True(t, graph.Nodes["jest@29.7.0"].Dev)
+	// Installed for both a dev and a production dependency
+	assert.False(t, graph.Nodes["ms@2.1.3"].Dev)
+}
+
+func TestParseAlias(t *testing.T) {
+	tests := []struct {
+		spec, name, version string
+		ok                  bool
+	}{
+		{"npm:bar@^1.2.3", "bar", "^1.2.3", true},
+		{"npm:@scope/bar@1.0.0", "@scope/bar", "1.0.0", true},
+		{"npm:bar", "bar", "latest", true},
+		{"npm:@scope/bar", "@scope/bar", "latest", true},
+		{"^1.2.3", "", "", false},
+		{"github:user/repo", "", "", false},
+		{"npm:", "", "", false},
+	}
+	for _, tt := range tests {
+		name, version, ok := ParseAlias(tt.spec)
+		assert.Equal(t, tt.ok, ok, tt.spec)
+		assert.Equal(t, tt.name, name, tt.spec)
+		assert.Equal(t, tt.version, version, tt.spec)
+	}
+
+	// An alias shadowed by a direct dependency on the real name keeps its key
+	assert.Equal(t, map[string]string{"bar": "^2.0.0", "old-bar": "npm:bar@^1.0.0"},
+		resolveAliases(map[string]string{"bar": "^2.0.0", "old-bar": "npm:bar@^1.0.0"}))
+}
+
+func TestParseLockfileResolvedDependencies(t *testing.T) {
+	lockfile := `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {
+      "version": "1.0.0",
+      "dependencies": {"a": "^1.0.0", "debug": "^4.0.0", "cliui": "npm:@isaacs/cliui@^8.0.2"},
+      "devDependencies": {"b": "^1.0.0"}
+    },
+    "node_modules/a": {"version": "1.0.0", "dependencies": {"debug": "^2.0.0", "ms": "^2.1.0"}},
+    "node_modules/a/node_modules/debug": {"version": "2.6.9", "dependencies": {"ms": "2.0.0"}},
+    "node_modules/a/node_modules/debug/node_modules/ms": {"version": "2.0.0"},
+    "node_modules/b": {"version": "1.0.0", "dev": true, "dependencies": {"debug": "^4.0.0", "missing": "^1.0.0"}},
+    "node_modules/debug": {"version": "4.3.4", "dependencies": {"ms": "2.1.2"}},
+    "node_modules/ms": {"version": "2.1.2"},
+    "node_modules/cliui": {"name": "@isaacs/cliui", "version": "8.0.2"}
+  }
+}`
+	path := filepath.Join(t.TempDir(), "package-lock.json")
+	require.NoError(t, os.WriteFile(path, []byte(lockfile), 0o644))
+
+	root := &models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	graph, err := NewLockfileManager().ParseLockfile(path, root)
+	require.NoError(t, err)
+
+	// Nested copies shadow hoisted ones; the rest resolve up the tree
+	assert.Equal(t, []string{"@isaacs/cliui@8.0.2", "a@1.0.0", "b@1.0.0", "debug@4.3.4"}, graph.Nodes["app@1.0.0"].ResolvedDependencies)
+	assert.Equal(t, []string{"debug@2.6.9", "ms@2.1.2"}, graph.Nodes["a@1.0.0"].ResolvedDependencies)
+	assert.Equal(t, []string{"ms@2.0.0"}, graph.Nodes["debug@2.6.9"].ResolvedDependencies)
+	assert.Equal(t, []string{"ms@2.1.2"}, graph.Nodes["debug@4.3.4"].ResolvedDependencies)
+	assert.Equal(t, []string{"debug@4.3.4"}, graph.Nodes["b@1.0.0"].ResolvedDependencies)
+	assert.Empty(t, graph.Nodes["ms@2.0.0"].ResolvedDependencies)
+
+	// Traversal follows the resolved edges rather than every version by name
+	direct, _ := graph.Dependents("ms@2.0.0")
+	assert.Equal(t, []string{"debug@2.6.9"}, direct)
+	assert.Equal(t, []string{"app@1.0.0", "a@1.0.0", "debug@2.6.9", "ms@2.0.0"}, graph.PathFromRoot("ms@2.0.0"))
+}
+
+func TestLockfilePackages(t *testing.T) {
+	lockfile := `{
+  "lockfileVersion": 3,
+  "packages": {
+    "": {"name": "app", "version": "1.0.0"},
+    "node_modules/cliui": {"name": "@isaacs/cliui", "version": "8.0.2"},
+    "node_modules/left-pad": {"version": "1.3.0"},
+    "node_modules/a/node_modules/left-pad": {"version": "1.3.0"},
+    "node_modules/local": {"resolved": "packages/local", "link": true}
+  }
+}`
+	packages, err := LockfilePackages([]byte(lockfile))
+	require.NoError(t, err)
+	assert.Equal(t, []models.Package{
+		{ID: "@isaacs/cliui@8.0.2", Name: "@isaacs/cliui", Version: "8.0.2"},
+		{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"},
+	}, packages)
+
+	_, err = LockfilePackages([]byte(`{"lockfileVersion": 1}`))
+	assert.Error(t, err)
+
+	installs, err := DirectInstalls([]byte(lockfile))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]models.Package{
+		"cliui":    {ID: "@isaacs/cliui@8.0.2", Name: "@isaacs/cliui", Version: "8.0.2"},
+		"left-pad": {ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"},
+	}, installs)
+}
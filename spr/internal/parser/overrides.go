@@ -0,0 +1,172 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// Package.json fields that force dependency versions
+const (
+	OverridesField   = "overrides"   // npm and pnpm (>= 9 reads it too)
+	ResolutionsField = "resolutions" // yarn
+)
+
+// indentPattern finds the indentation of the first member of a JSON object
+var indentPattern = regexp.MustCompile(`\{\s*?\n([ \t]+)"`)
+
+// SetOverrides pins packages to versions in the overrides (npm) or
+// resolutions (yarn) field of the package.json at path. Existing pins for
+// other packages are kept, as are the order of fields and the file's
+// indentation.
+func SetOverrides(path, field string, pins map[string]string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read package.json: %w", err)
+	}
+
+	updated, err := setOverrides(data, field, pins)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, updated, 0o644)
+}
+
+func setOverrides(data []byte, field string, pins map[string]string) ([]byte, error) {
+	root, err := decodeOrderedObject(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse package.json: %w", err)
+	}
+
+	var overrides orderedObject
+	if raw, ok := root.get(field); ok {
+		if overrides, err = decodeOrderedObject(raw); err != nil {
+			return nil, fmt.Errorf("failed to parse %s in package.json: %w", field, err)
+		}
+	}
+
+	names := make([]string, 0, len(pins))
+	for name := range pins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, err := marshalNoEscape(pins[name])
+		if err != nil {
+			return nil, err
+		}
+		overrides.set(name, value)
+	}
+
+	encoded, err := overrides.encode()
+	if err != nil {
+		return nil, err
+	}
+	root.set(field, encoded)
+
+	compact, err := root.encode()
+	if err != nil {
+		return nil, err
+	}
+
+	indent := "  "
+	if m := indentPattern.FindSubmatch(data); m != nil {
+		indent = string(m[1])
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact, "", indent); err != nil {
+		return nil, err
+	}
+	if bytes.HasSuffix(bytes.TrimRight(data, " \t"), []byte("\n")) {
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), nil
+}
+
+// orderedObject is a JSON object that remembers the order of its members
+type orderedObject []objectMember
+
+type objectMember struct {
+	key   string
+	value json.RawMessage
+}
+
+func decodeOrderedObject(data []byte) (orderedObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil {
+		return nil, err
+	} else if tok != json.Delim('{') {
+		return nil, fmt.Errorf("expected an object")
+	}
+
+	var obj orderedObject
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		obj.set(key, value)
+	}
+	return obj, nil
+}
+
+func (o orderedObject) get(key string) (json.RawMessage, bool) {
+	for _, member := range o {
+		if member.key == key {
+			return member.value, true
+		}
+	}
+	return nil, false
+}
+
+func (o *orderedObject) set(key string, value json.RawMessage) {
+	for i := range *o {
+		if (*o)[i].key == key {
+			(*o)[i].value = value
+			return
+		}
+	}
+	*o = append(*o, objectMember{key, value})
+}
+
+// encode renders the object compactly, members in order
+func (o orderedObject) encode() (json.RawMessage, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := marshalNoEscape(member.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, member.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalNoEscape marshals v without escaping <, > and &, which are common in
+// version ranges
+func marshalNoEscape(v any) (json.RawMessage, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
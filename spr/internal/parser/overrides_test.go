@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetOverrides(t *testing.T) {
+	t.Run("adds field keeping order and indentation", func(t *testing.T) {
+		in := "{\n    \"name\": \"app\",\n    \"dependencies\": {\n        \"left-pad\": \"^1.0.0\"\n    }\n}\n"
+		out, err := setOverrides([]byte(in), OverridesField, map[string]string{"left-pad": "1.3.0"})
+		require.NoError(t, err)
+		assert.Equal(t, "{\n    \"name\": \"app\",\n    \"dependencies\": {\n        \"left-pad\": \"^1.0.0\"\n    },\n    \"overrides\": {\n        \"left-pad\": \"1.3.0\"\n    }\n}\n", string(out))
+	})
+
+	t.Run("merges with existing pins", func(t *testing.T) {
+		in := `{"resolutions":{"b":"2.0.0","a":">=1 <2"}}`
+		out, err := setOverrides([]byte(in), ResolutionsField, map[string]string{"a": "1.2.3", "@scope/c": "3.0.0"})
+		require.NoError(t, err)
+		// Existing members keep their place, new ones are appended sorted, and
+		// no trailing newline is added to a file without one
+		assert.Equal(t, "{\n  \"resolutions\": {\n    \"b\": \"2.0.0\",\n    \"a\": \"1.2.3\",\n    \"@scope/c\": \"3.0.0\"\n  }\n}", string(out))
+	})
+
+	t.Run("rejects non-object field", func(t *testing.T) {
+		_, err := setOverrides([]byte(`{"overrides":"nope"}`), OverridesField, map[string]string{"a": "1.0.0"})
+		assert.Error(t, err)
+	})
+
+	t.Run("writes file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "package.json")
+		require.NoError(t, os.WriteFile(path, []byte("{\n  \"name\": \"app\"\n}\n"), 0o644))
+		require.NoError(t, SetOverrides(path, OverridesField, map[string]string{"x": "<2.0.0"}))
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, "{\n  \"name\": \"app\",\n  \"overrides\": {\n    \"x\": \"<2.0.0\"\n  }\n}\n", string(data))
+	})
+}
@@ -0,0 +1,130 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// DependencySections are the package.json fields that declare dependencies
+var DependencySections = []string{"dependencies", "devDependencies", "optionalDependencies", "peerDependencies"}
+
+// Position is a zero-based line and character in a document. As in the
+// Language Server Protocol, characters count UTF-16 code units.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// DependencyEntry is a dependency declared in package.json and the span of
+// its "name": "spec" entry
+type DependencyEntry struct {
+	Section string
+	Name    string
+	Spec    string
+	Start   Position
+	End     Position
+}
+
+// LocateDependencies finds every dependency declaration in a package.json
+// buffer, in document order, so editors can annotate the lines they are on.
+// Unlike ParsePackageJSON it keeps duplicates and source positions.
+func LocateDependencies(data []byte) ([]DependencyEntry, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil, fmt.Errorf("failed to parse package.json: not a JSON object")
+	}
+
+	lines := lineOffsets(data)
+	var entries []DependencyEntry
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse package.json: %w", err)
+		}
+		section, _ := tok.(string)
+		if !slices.Contains(DependencySections, section) {
+			var skip json.RawMessage
+			if err := dec.Decode(&skip); err != nil {
+				return nil, fmt.Errorf("failed to parse package.json: %w", err)
+			}
+			continue
+		}
+
+		if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+			return nil, fmt.Errorf("failed to parse package.json: %s is not an object", section)
+		}
+		for dec.More() {
+			tok, err := dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse package.json: %w", err)
+			}
+			name, _ := tok.(string)
+			start := openingQuote(data, int(dec.InputOffset()))
+			var value json.RawMessage
+			if err := dec.Decode(&value); err != nil {
+				return nil, fmt.Errorf("failed to parse package.json: %w", err)
+			}
+			var spec string
+			json.Unmarshal(value, &spec)
+			entries = append(entries, DependencyEntry{
+				Section: section,
+				Name:    name,
+				Spec:    spec,
+				Start:   position(data, lines, start),
+				End:     position(data, lines, int(dec.InputOffset())),
+			})
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("failed to parse package.json: %w", err)
+		}
+	}
+	return entries, nil
+}
+
+// openingQuote returns the offset of the quote opening the string that ends
+// just before end
+func openingQuote(data []byte, end int) int {
+	for i := end - 2; i >= 0; i-- {
+		if data[i] != '"' {
+			continue
+		}
+		backslashes := 0
+		for j := i - 1; j >= 0 && data[j] == '\\'; j-- {
+			backslashes++
+		}
+		if backslashes%2 == 0 {
+			return i
+		}
+	}
+	return 0
+}
+
+// lineOffsets returns the offset at which each line starts
+func lineOffsets(data []byte) []int {
+	offsets := []int{0}
+	for i, b := range data {
+		if b == '\n' {
+			offsets = append(offsets, i+1)
+		}
+	}
+	return offsets
+}
+
+// position converts a byte offset into a line and UTF-16 character
+func position(data []byte, lines []int, offset int) Position {
+	line, _ := slices.BinarySearch(lines, offset+1)
+	line--
+	character := 0
+	for _, r := range string(data[lines[line]:offset]) {
+		if r == utf8.RuneError {
+			character++
+			continue
+		}
+		character += utf16.RuneLen(r)
+	}
+	return Position{Line: line, Character: character}
+}
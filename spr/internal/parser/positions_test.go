@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocateDependencies(t *testing.T) {
+	pkgJSON := `{
+  "name": "app",
+  "scripts": {"dependencies": "not a section"},
+  "dependencies": {
+    "left-pad": "^1.3.0",
+    "@scope/ünï": "npm:other@2"
+  },
+  "devDependencies": {"eslint": "9.0.0"}
+}`
+	entries, err := LocateDependencies([]byte(pkgJSON))
+	require.NoError(t, err)
+	assert.Equal(t, []DependencyEntry{
+		{Section: "dependencies", Name: "left-pad", Spec: "^1.3.0", Start: Position{4, 4}, End: Position{4, 24}},
+		{Section: "dependencies", Name: "@scope/ünï", Spec: "npm:other@2", Start: Position{5, 4}, End: Position{5, 31}},
+		{Section: "devDependencies", Name: "eslint", Spec: "9.0.0", Start: Position{7, 22}, End: Position{7, 39}},
+	}, entries)
+
+	_, err = LocateDependencies([]byte(`{"dependencies": []}`))
+	assert.Error(t, err)
+	_, err = LocateDependencies([]byte(`{"dependencies": {"a": `))
+	assert.Error(t, err)
+}
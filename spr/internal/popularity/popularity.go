@@ -0,0 +1,152 @@
+// Package popularity looks up how widely npm packages are used, as weekly
+// download counts from the npm downloads API, caching them between runs
+package popularity
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+)
+
+// DefaultAPIURL is the npm downloads API
+const DefaultAPIURL = "https://api.npmjs.org"
+
+// DefaultCacheFile is where counts are cached, next to the analysis results
+const DefaultCacheFile = "analysis-results/downloads.json"
+
+// DefaultTTL is how long a cached count is used before it is fetched again.
+// The API reports the last full week, so counts change at most daily.
+const DefaultTTL = 24 * time.Hour
+
+// ErrNotFound is returned for packages the downloads API doesn't know, such
+// as ones only published to a private registry
+var ErrNotFound = errors.New("package not found on the npm downloads API")
+
+// entry is one cached count
+type entry struct {
+	Downloads int64     `json:"downloads"`
+	FetchedAt time.Time `json:"fetched_at"`
+}
+
+// Client fetches weekly download counts. Counts are read from and written
+// back to CachePath; it is safe for concurrent use.
+type Client struct {
+	APIURL     string
+	CachePath  string // Empty keeps counts in memory only
+	TTL        time.Duration
+	HTTPClient *http.Client
+
+	mu     sync.Mutex
+	cache  map[string]entry
+	loaded bool
+	dirty  bool
+}
+
+// NewClient creates a client for the npm downloads API caching counts in
+// cachePath
+func NewClient(cachePath string) *Client {
+	return &Client{
+		APIURL:     DefaultAPIURL,
+		CachePath:  cachePath,
+		TTL:        DefaultTTL,
+		HTTPClient: breaker.Client(breaker.Npm, 30*time.Second),
+	}
+}
+
+// WeeklyDownloads returns how often a package was downloaded over the last
+// week, from the cache when the count is fresh
+func (c *Client) WeeklyDownloads(ctx context.Context, name string) (int64, error) {
+	c.mu.Lock()
+	c.load()
+	cached, ok := c.cache[name]
+	c.mu.Unlock()
+	if ok && time.Since(cached.FetchedAt) < c.TTL {
+		return cached.Downloads, nil
+	}
+
+	downloads, err := c.fetch(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	c.mu.Lock()
+	c.cache[name] = entry{Downloads: downloads, FetchedAt: time.Now().UTC()}
+	c.dirty = true
+	c.mu.Unlock()
+	return downloads, nil
+}
+
+// fetch asks the downloads API for the last week's count. Scoped names go in
+// the path as they are; the API doesn't accept them escaped.
+func (c *Client) fetch(ctx context.Context, name string) (int64, error) {
+	url := strings.TrimSuffix(c.APIURL, "/") + "/downloads/point/last-week/" + name
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch download count: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, ErrNotFound
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("failed to fetch download count: status %d", resp.StatusCode)
+	}
+	var point struct {
+		Downloads int64 `json:"downloads"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&point); err != nil {
+		return 0, fmt.Errorf("failed to decode download count: %w", err)
+	}
+	return point.Downloads, nil
+}
+
+// load reads the cache file once. A missing or unreadable cache starts
+// empty; counts are only an optimization. Callers hold mu.
+func (c *Client) load() {
+	if c.loaded {
+		return
+	}
+	c.loaded = true
+	c.cache = make(map[string]entry)
+	if c.CachePath == "" {
+		return
+	}
+	if data, err := os.ReadFile(c.CachePath); err == nil {
+		json.Unmarshal(data, &c.cache)
+	}
+}
+
+// Save writes the counts fetched since the cache was loaded back to
+// CachePath
+func (c *Client) Save() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dirty || c.CachePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(c.cache, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.CachePath), 0o755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(c.CachePath, data, 0o644); err != nil {
+		return err
+	}
+	c.dirty = false
+	return nil
+}
@@ -0,0 +1,58 @@
+package popularity
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeeklyDownloads(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+		switch r.URL.Path {
+		case "/downloads/point/last-week/@acme/lib":
+			w.Write([]byte(`{"downloads":12,"package":"@acme/lib"}`))
+		case "/downloads/point/last-week/lodash":
+			w.Write([]byte(`{"downloads":50000000,"package":"lodash"}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"package not found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	cache := filepath.Join(t.TempDir(), "analysis-results", "downloads.json")
+	client := NewClient(cache)
+	client.APIURL = srv.URL
+
+	n, err := client.WeeklyDownloads(t.Context(), "@acme/lib")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+	n, err = client.WeeklyDownloads(t.Context(), "lodash")
+	require.NoError(t, err)
+	assert.Equal(t, int64(50000000), n)
+	_, err = client.WeeklyDownloads(t.Context(), "internal-only")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Repeat lookups, in this run or the next, are served from the cache
+	_, err = client.WeeklyDownloads(t.Context(), "lodash")
+	require.NoError(t, err)
+	require.NoError(t, client.Save())
+	next := NewClient(cache)
+	next.APIURL = srv.URL
+	n, err = next.WeeklyDownloads(t.Context(), "@acme/lib")
+	require.NoError(t, err)
+	assert.Equal(t, int64(12), n)
+	assert.Equal(t, 3, hits)
+
+	// Stale counts are fetched again
+	next.TTL = 0
+	_, err = next.WeeklyDownloads(t.Context(), "@acme/lib")
+	require.NoError(t, err)
+	assert.Equal(t, 4, hits)
+}
@@ -0,0 +1,83 @@
+package provenance
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Policy is a list of trusted publishers. Each pattern is a publisher
+// subject, or a prefix of one ending at a path segment, with * matching
+// within a segment and an optional @ref glob:
+//
+//	github.com/acme                                     every workflow of an org
+//	github.com/acme/*/.github/workflows/release.yml     one workflow in any repo
+//	github.com/acme/lib@refs/tags/*                     tagged builds only
+type Policy struct {
+	patterns []string
+}
+
+// ParsePolicy parses a comma-separated list of trusted publishers. An empty
+// list trusts nobody.
+func ParsePolicy(spec string) (*Policy, error) {
+	p := &Policy{}
+	for _, pattern := range strings.Split(spec, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+		pattern = strings.TrimPrefix(pattern, "https://")
+		subject, ref, _ := strings.Cut(pattern, "@")
+		if strings.Count(subject, "/") < 1 {
+			return nil, fmt.Errorf("trusted publisher %q: want at least host/owner", pattern)
+		}
+		// Surface malformed globs now rather than as silent mismatches
+		if _, err := path.Match(subject, subject); err != nil {
+			return nil, fmt.Errorf("trusted publisher %q: %w", pattern, err)
+		}
+		if _, err := path.Match(ref, ref); err != nil {
+			return nil, fmt.Errorf("trusted publisher %q: %w", pattern, err)
+		}
+		p.patterns = append(p.patterns, pattern)
+	}
+	return p, nil
+}
+
+// Empty reports whether the policy trusts nobody
+func (p *Policy) Empty() bool {
+	return p == nil || len(p.patterns) == 0
+}
+
+// Trusts returns the first pattern matching the publisher
+func (p *Policy) Trusts(publisher Publisher) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	for _, pattern := range p.patterns {
+		if matchPublisher(pattern, publisher) {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// matchPublisher matches a publisher against one pattern
+func matchPublisher(pattern string, publisher Publisher) bool {
+	subject, ref, hasRef := strings.Cut(pattern, "@")
+	if hasRef {
+		if ok, _ := path.Match(ref, publisher.Ref); !ok {
+			return false
+		}
+	}
+	want := strings.Split(subject, "/")
+	got := strings.Split(publisher.Subject, "/")
+	if len(want) > len(got) {
+		return false
+	}
+	for i, segment := range want {
+		if ok, _ := path.Match(segment, got[i]); !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package provenance
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyTrusts(t *testing.T) {
+	publisher := Publisher{Subject: "github.com/acme/lib/.github/workflows/release.yml", Ref: "refs/tags/v1.0.0"}
+	tests := []struct {
+		spec string
+		want bool
+	}{
+		{"github.com/acme", true},
+		{"https://github.com/acme", true},
+		{"github.com/acm", false},
+		{"github.com/acme/*/.github/workflows/release.yml", true},
+		{"github.com/acme/lib/.github/workflows/ci.yml", false},
+		{"github.com/acme/lib@refs/tags/*", true},
+		{"github.com/acme/lib@refs/heads/main", false},
+		{"github.com/other,github.com/acme/lib", true},
+	}
+	for _, tt := range tests {
+		policy, err := ParsePolicy(tt.spec)
+		require.NoError(t, err, tt.spec)
+		_, trusted := policy.Trusts(publisher)
+		assert.Equal(t, tt.want, trusted, tt.spec)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	policy, err := ParsePolicy(" , ")
+	require.NoError(t, err)
+	assert.True(t, policy.Empty())
+
+	_, err = ParsePolicy("github.com")
+	assert.Error(t, err)
+	_, err = ParsePolicy("github.com/[acme")
+	assert.Error(t, err)
+}
@@ -0,0 +1,294 @@
+// Package provenance verifies npm provenance attestations and matches the
+// publisher they name against a list of trusted ones, so packages built by a
+// trusted CI workflow can skip dynamic analysis.
+//
+// An attestation is accepted when its DSSE signature verifies against the
+// signing certificate in its Sigstore bundle, its in-toto statement names
+// the package and the lockfile's sha512 of the tarball, and the workflow in
+// its SLSA predicate is the one the certificate was issued to. The Fulcio
+// chain and Rekor inclusion proof are not checked here: the registry checks
+// both when a version is published with provenance, and attestations are
+// fetched from it over TLS.
+package provenance
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+)
+
+// slsaPredicatePrefix prefixes the predicate types of SLSA provenance
+const slsaPredicatePrefix = "https://slsa.dev/provenance/"
+
+// ErrNoProvenance is returned for versions published without provenance
+var ErrNoProvenance = errors.New("no provenance attestation")
+
+// Publisher is the CI workflow that built and signed a package version
+type Publisher struct {
+	// Subject is the workflow, as host/owner/repo/path without scheme or
+	// ref, e.g. github.com/acme/lib/.github/workflows/release.yml
+	Subject string `json:"subject"`
+	Ref     string `json:"ref,omitempty"` // e.g. refs/tags/v1.2.3
+}
+
+func (p Publisher) String() string {
+	if p.Ref == "" {
+		return p.Subject
+	}
+	return p.Subject + "@" + p.Ref
+}
+
+// Verifier fetches and verifies attestations from an npm registry
+type Verifier struct {
+	RegistryURL string
+	HTTPClient  *http.Client
+}
+
+// NewVerifier creates a verifier for the registry at registryURL
+func NewVerifier(registryURL string) *Verifier {
+	return &Verifier{
+		RegistryURL: strings.TrimSuffix(registryURL, "/"),
+		HTTPClient:  breaker.Client(breaker.Npm, 30*time.Second),
+	}
+}
+
+// attestations is the registry's response for a version
+type attestations struct {
+	Attestations []struct {
+		PredicateType string `json:"predicateType"`
+		Bundle        bundle `json:"bundle"`
+	} `json:"attestations"`
+}
+
+// bundle is the part of a Sigstore bundle needed to check its signature
+type bundle struct {
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes string `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes string `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope struct {
+		Payload     string `json:"payload"`
+		PayloadType string `json:"payloadType"`
+		Signatures  []struct {
+			Sig string `json:"sig"`
+		} `json:"signatures"`
+	} `json:"dsseEnvelope"`
+}
+
+// statement is an in-toto statement carrying SLSA provenance
+type statement struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	Predicate struct {
+		BuildDefinition struct {
+			ExternalParameters struct {
+				Workflow *struct {
+					Ref        string `json:"ref"`
+					Repository string `json:"repository"`
+					Path       string `json:"path"`
+				} `json:"workflow"`
+			} `json:"externalParameters"`
+		} `json:"buildDefinition"`
+	} `json:"predicate"`
+}
+
+// Verify fetches the provenance of a version and returns its publisher.
+// integrity is the lockfile's SRI hash of the tarball, which the
+// attestation must name.
+func (v *Verifier) Verify(ctx context.Context, name, version, integrity string) (*Publisher, error) {
+	digest, err := sha512Hex(integrity)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := v.fetch(ctx, name, version)
+	if err != nil {
+		return nil, err
+	}
+	cert, err := b.certificate()
+	if err != nil {
+		return nil, err
+	}
+	payload, err := b.verifySignature(cert)
+	if err != nil {
+		return nil, err
+	}
+
+	var st statement
+	if err := json.Unmarshal(payload, &st); err != nil {
+		return nil, fmt.Errorf("failed to parse provenance statement: %w", err)
+	}
+	wantName := "pkg:npm/" + strings.Replace(name, "@", "%40", 1) + "@" + version
+	matched := false
+	for _, s := range st.Subject {
+		if s.Name == wantName && strings.EqualFold(s.Digest["sha512"], digest) {
+			matched = true
+			break
+		}
+	}
+	if !matched {
+		return nil, fmt.Errorf("provenance does not attest %s with the lockfile's integrity", wantName)
+	}
+
+	publisher, err := certPublisher(cert)
+	if err != nil {
+		return nil, err
+	}
+	if w := st.Predicate.BuildDefinition.ExternalParameters.Workflow; w != nil {
+		built := Publisher{Subject: trimScheme(w.Repository) + "/" + strings.TrimPrefix(w.Path, "/"), Ref: w.Ref}
+		if built != *publisher {
+			return nil, fmt.Errorf("provenance workflow %s differs from signing identity %s", built, publisher)
+		}
+	}
+	return publisher, nil
+}
+
+// fetch returns the SLSA provenance bundle of a version
+func (v *Verifier) fetch(ctx context.Context, name, version string) (*bundle, error) {
+	rawURL := fmt.Sprintf("%s/-/npm/v1/attestations/%s@%s", v.RegistryURL, url.PathEscape(name), url.PathEscape(version))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := v.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch attestations: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNoProvenance
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch attestations: status %d", resp.StatusCode)
+	}
+
+	var a attestations
+	if err := json.NewDecoder(resp.Body).Decode(&a); err != nil {
+		return nil, fmt.Errorf("failed to parse attestations: %w", err)
+	}
+	for _, att := range a.Attestations {
+		if strings.HasPrefix(att.PredicateType, slsaPredicatePrefix) {
+			return &att.Bundle, nil
+		}
+	}
+	return nil, ErrNoProvenance
+}
+
+// certificate returns the bundle's signing certificate
+func (b *bundle) certificate() (*x509.Certificate, error) {
+	var raw string
+	vm := b.VerificationMaterial
+	switch {
+	case vm.Certificate != nil:
+		raw = vm.Certificate.RawBytes
+	case vm.X509CertificateChain != nil && len(vm.X509CertificateChain.Certificates) > 0:
+		raw = vm.X509CertificateChain.Certificates[0].RawBytes
+	default:
+		return nil, errors.New("provenance bundle has no signing certificate")
+	}
+	der, err := base64.StdEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("invalid signing certificate: %w", err)
+	}
+	return cert, nil
+}
+
+// verifySignature checks the DSSE envelope against the certificate's key and
+// returns its payload
+func (b *bundle) verifySignature(cert *x509.Certificate) ([]byte, error) {
+	env := b.DSSEEnvelope
+	payload, err := base64.StdEncoding.DecodeString(env.Payload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provenance payload: %w", err)
+	}
+	if len(env.Signatures) == 0 {
+		return nil, errors.New("provenance envelope is unsigned")
+	}
+	sig, err := base64.StdEncoding.DecodeString(env.Signatures[0].Sig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provenance signature: %w", err)
+	}
+
+	// DSSE signs the pre-authentication encoding of type and payload
+	pae := fmt.Appendf(nil, "DSSEv1 %d %s %d ", len(env.PayloadType), env.PayloadType, len(payload))
+	pae = append(pae, payload...)
+	hash := sha256.Sum256(pae)
+
+	valid := false
+	switch key := cert.PublicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, hash[:], sig)
+	case *rsa.PublicKey:
+		valid = rsa.VerifyPKCS1v15(key, crypto.SHA256, hash[:], sig) == nil
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, pae, sig)
+	default:
+		return nil, fmt.Errorf("unsupported signing key type %T", cert.PublicKey)
+	}
+	if !valid {
+		return nil, errors.New("provenance signature does not verify")
+	}
+	return payload, nil
+}
+
+// certPublisher returns the workflow a Fulcio certificate was issued to,
+// from its URI SAN (https://github.com/owner/repo/.github/workflows/x.yml@ref)
+func certPublisher(cert *x509.Certificate) (*Publisher, error) {
+	for _, uri := range cert.URIs {
+		if uri.Scheme != "https" {
+			continue
+		}
+		identity := trimScheme(uri.String())
+		subject, ref, _ := strings.Cut(identity, "@")
+		return &Publisher{Subject: subject, Ref: ref}, nil
+	}
+	return nil, errors.New("signing certificate names no workflow")
+}
+
+// trimScheme strips the scheme from a URL and cleans its path, dropping the
+// slash GitLab doubles between the project and the CI file
+func trimScheme(s string) string {
+	return path.Clean(strings.TrimPrefix(strings.TrimPrefix(s, "git+"), "https://"))
+}
+
+// sha512Hex returns the hex sha512 digest in an SRI string
+func sha512Hex(integrity string) (string, error) {
+	for _, entry := range strings.Fields(integrity) {
+		if digest, ok := strings.CutPrefix(entry, "sha512-"); ok {
+			sum, err := base64.StdEncoding.DecodeString(digest)
+			if err != nil {
+				return "", fmt.Errorf("invalid integrity %q: %w", integrity, err)
+			}
+			return hex.EncodeToString(sum), nil
+		}
+	}
+	return "", fmt.Errorf("no sha512 integrity to check provenance against")
+}
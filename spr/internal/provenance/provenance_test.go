@@ -0,0 +1,135 @@
+package provenance
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIdentity = "https://github.com/acme/lib/.github/workflows/release.yml@refs/tags/v1.0.0"
+	testTarball  = "tarball"
+)
+
+// testAttestations returns the registry's attestations response for
+// @acme/lib@1.0.0, signed by a certificate issued to testIdentity. workflow
+// is the repository the predicate claims to be built from.
+func testAttestations(t *testing.T, workflow string) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	san, err := url.Parse(testIdentity)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		URIs:         []*url.URL{san},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	sum := sha512.Sum512([]byte(testTarball))
+	payload, err := json.Marshal(map[string]any{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"predicateType": "https://slsa.dev/provenance/v1",
+		"subject":       []any{map[string]any{"name": "pkg:npm/%40acme/lib@1.0.0", "digest": map[string]string{"sha512": hex.EncodeToString(sum[:])}}},
+		"predicate": map[string]any{"buildDefinition": map[string]any{"externalParameters": map[string]any{"workflow": map[string]string{
+			"ref":        "refs/tags/v1.0.0",
+			"repository": workflow,
+			"path":       ".github/workflows/release.yml",
+		}}}},
+	})
+	require.NoError(t, err)
+	payloadType := "application/vnd.in-toto+json"
+	hash := sha256.Sum256(fmt.Appendf(nil, "DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	data, err := json.Marshal(map[string]any{"attestations": []any{
+		map[string]any{"predicateType": "https://github.com/npm/attestation/tree/main/specs/publish/v0.1"},
+		map[string]any{"predicateType": "https://slsa.dev/provenance/v1", "bundle": map[string]any{
+			"verificationMaterial": map[string]any{"certificate": map[string]string{"rawBytes": base64.StdEncoding.EncodeToString(der)}},
+			"dsseEnvelope": map[string]any{
+				"payload":     base64.StdEncoding.EncodeToString(payload),
+				"payloadType": payloadType,
+				"signatures":  []any{map[string]string{"sig": base64.StdEncoding.EncodeToString(sig)}},
+			},
+		}},
+	}})
+	require.NoError(t, err)
+	return data
+}
+
+// testRegistry serves attestations for @acme/lib@1.0.0 only
+func testRegistry(t *testing.T, attestations []byte) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.EscapedPath() != "/-/npm/v1/attestations/@acme%2Flib@1.0.0" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(attestations)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func testIntegrity(data string) string {
+	sum := sha512.Sum512([]byte(data))
+	return "sha512-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestVerify(t *testing.T) {
+	srv := testRegistry(t, testAttestations(t, "https://github.com/acme/lib"))
+	publisher, err := NewVerifier(srv.URL).Verify(t.Context(), "@acme/lib", "1.0.0", testIntegrity(testTarball))
+	require.NoError(t, err)
+	assert.Equal(t, Publisher{Subject: "github.com/acme/lib/.github/workflows/release.yml", Ref: "refs/tags/v1.0.0"}, *publisher)
+}
+
+func TestVerifyRejects(t *testing.T) {
+	srv := testRegistry(t, testAttestations(t, "https://github.com/acme/lib"))
+	verifier := NewVerifier(srv.URL)
+
+	_, err := verifier.Verify(t.Context(), "@acme/lib", "1.0.0", testIntegrity("tampered"))
+	assert.ErrorContains(t, err, "lockfile's integrity")
+
+	_, err = verifier.Verify(t.Context(), "@acme/other", "1.0.0", testIntegrity(testTarball))
+	assert.ErrorIs(t, err, ErrNoProvenance)
+
+	_, err = verifier.Verify(t.Context(), "@acme/lib", "1.0.0", "sha1-abc")
+	assert.Error(t, err)
+
+	// A certificate for one workflow vouching for another's build
+	srv = testRegistry(t, testAttestations(t, "https://github.com/evil/lib"))
+	_, err = NewVerifier(srv.URL).Verify(t.Context(), "@acme/lib", "1.0.0", testIntegrity(testTarball))
+	assert.ErrorContains(t, err, "differs from signing identity")
+}
+
+func TestVerifyBadSignature(t *testing.T) {
+	var resp map[string][]map[string]any
+	require.NoError(t, json.Unmarshal(testAttestations(t, "https://github.com/acme/lib"), &resp))
+	envelope := resp["attestations"][1]["bundle"].(map[string]any)["dsseEnvelope"].(map[string]any)
+	envelope["payloadType"] = "application/json"
+	data, err := json.Marshal(resp)
+	require.NoError(t, err)
+
+	srv := testRegistry(t, data)
+	_, err = NewVerifier(srv.URL).Verify(t.Context(), "@acme/lib", "1.0.0", testIntegrity(testTarball))
+	assert.ErrorContains(t, err, "does not verify")
+}
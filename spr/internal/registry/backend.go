@@ -0,0 +1,128 @@
+package registry
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Type selects the registry API an Uploader talks to (REGISTRY_TYPE)
+type Type string
+
+const (
+	// TypeGitea is Gitea's package registry: /api/packages/{owner}/npm/{name}
+	TypeGitea Type = "gitea"
+	// TypeVerdaccio is a Verdaccio instance. Metadata reads skip uplinks so
+	// packages only present upstream are not mistaken for uploaded ones.
+	TypeVerdaccio Type = "verdaccio"
+	// TypeNpm is any CouchDB-style npm registry serving packages at /{name}
+	TypeNpm Type = "npm"
+)
+
+// ParseType parses a registry type name; empty selects TypeGitea
+func ParseType(s string) (Type, error) {
+	switch Type(strings.ToLower(s)) {
+	case "", TypeGitea:
+		return TypeGitea, nil
+	case TypeVerdaccio:
+		return TypeVerdaccio, nil
+	case TypeNpm:
+		return TypeNpm, nil
+	}
+	return "", fmt.Errorf("unknown registry type %q (expected gitea, verdaccio or npm)", s)
+}
+
+// RegistryBackend abstracts the URL layout and authentication of an npm
+// registry. Every backend speaks the npm publish protocol: GET the package
+// document to read versions and dist-tags, PUT it with the tarball attached
+// to publish.
+type RegistryBackend interface {
+	// Type returns which kind of registry this is
+	Type() Type
+	// MetadataURL returns the URL to GET a package document from
+	MetadataURL(name string) string
+	// PublishURL returns the URL to PUT a new package version to
+	PublishURL(name string) string
+	// TarballURL returns where the registry serves an uploaded tarball
+	TarballURL(name, fileName string) string
+	// Authorize adds credentials to a request
+	Authorize(req *http.Request)
+}
+
+// NewBackend creates the backend for a registry type. owner is only used by
+// Gitea, where packages are namespaced by user or organisation.
+func NewBackend(t Type, baseURL, owner, token string) RegistryBackend {
+	baseURL = strings.TrimSuffix(baseURL, "/")
+	switch t {
+	case TypeVerdaccio:
+		return &VerdaccioBackend{NpmBackend{BaseURL: baseURL, Token: token}}
+	case TypeNpm:
+		return &NpmBackend{BaseURL: baseURL, Token: token}
+	default:
+		return &GiteaBackend{BaseURL: baseURL, Owner: owner, Token: token}
+	}
+}
+
+// GiteaBackend publishes to a Gitea package registry
+type GiteaBackend struct {
+	BaseURL string
+	Owner   string
+	Token   string
+}
+
+func (b *GiteaBackend) Type() Type { return TypeGitea }
+
+func (b *GiteaBackend) MetadataURL(name string) string {
+	return fmt.Sprintf("%s/api/packages/%s/npm/%s", b.BaseURL, b.Owner, normalizePackageName(name))
+}
+
+func (b *GiteaBackend) PublishURL(name string) string {
+	return b.MetadataURL(name)
+}
+
+func (b *GiteaBackend) TarballURL(name, fileName string) string {
+	return b.MetadataURL(name) + "/-/" + fileName
+}
+
+func (b *GiteaBackend) Authorize(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+b.Token)
+}
+
+// NpmBackend publishes to a CouchDB-style npm registry
+type NpmBackend struct {
+	BaseURL string
+	Token   string
+}
+
+func (b *NpmBackend) Type() Type { return TypeNpm }
+
+func (b *NpmBackend) MetadataURL(name string) string {
+	return b.BaseURL + "/" + normalizePackageName(name)
+}
+
+func (b *NpmBackend) PublishURL(name string) string {
+	return b.BaseURL + "/" + normalizePackageName(name)
+}
+
+func (b *NpmBackend) TarballURL(name, fileName string) string {
+	return b.BaseURL + "/" + name + "/-/" + fileName
+}
+
+func (b *NpmBackend) Authorize(req *http.Request) {
+	if b.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+b.Token)
+	}
+}
+
+// VerdaccioBackend publishes to Verdaccio
+type VerdaccioBackend struct {
+	NpmBackend
+}
+
+func (b *VerdaccioBackend) Type() Type { return TypeVerdaccio }
+
+// MetadataURL asks for local metadata only; by default Verdaccio merges in
+// what its uplinks (usually npmjs) have
+func (b *VerdaccioBackend) MetadataURL(name string) string {
+	return b.NpmBackend.MetadataURL(name) + "?write=true"
+}
@@ -0,0 +1,87 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseType(t *testing.T) {
+	for in, want := range map[string]Type{"": TypeGitea, "gitea": TypeGitea, "Verdaccio": TypeVerdaccio, "npm": TypeNpm} {
+		got, err := ParseType(in)
+		require.NoError(t, err)
+		assert.Equal(t, want, got)
+	}
+	_, err := ParseType("nexus")
+	assert.Error(t, err)
+}
+
+func TestBackendURLs(t *testing.T) {
+	tests := []struct {
+		t        Type
+		metadata string
+		publish  string
+		tarball  string
+	}{
+		{
+			TypeGitea,
+			"https://git.example/api/packages/owner/npm/@scope%2fpkg",
+			"https://git.example/api/packages/owner/npm/@scope%2fpkg",
+			"https://git.example/api/packages/owner/npm/@scope%2fpkg/-/pkg-1.0.0.tgz",
+		},
+		{
+			TypeVerdaccio,
+			"https://git.example/@scope%2fpkg?write=true",
+			"https://git.example/@scope%2fpkg",
+			"https://git.example/@scope/pkg/-/pkg-1.0.0.tgz",
+		},
+		{
+			TypeNpm,
+			"https://git.example/@scope%2fpkg",
+			"https://git.example/@scope%2fpkg",
+			"https://git.example/@scope/pkg/-/pkg-1.0.0.tgz",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.t), func(t *testing.T) {
+			b := NewBackend(tt.t, "https://git.example/", "owner", "token")
+			assert.Equal(t, tt.t, b.Type())
+			assert.Equal(t, tt.metadata, b.MetadataURL("@scope/pkg"))
+			assert.Equal(t, tt.publish, b.PublishURL("@scope/pkg"))
+			assert.Equal(t, tt.tarball, b.TarballURL("@scope/pkg", "pkg-1.0.0.tgz"))
+		})
+	}
+}
+
+func TestUploadToVerdaccio(t *testing.T) {
+	var uploaded map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		require.Equal(t, "/left-pad", r.URL.Path)
+		switch r.Method {
+		case http.MethodGet:
+			assert.Equal(t, "true", r.URL.Query().Get("write"))
+			http.NotFound(w, r)
+		case http.MethodPut:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&uploaded))
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	uploader := NewUploader(srv.URL, "", "token")
+	uploader.SetBackend(NewBackend(TypeVerdaccio, srv.URL, "", "token"))
+
+	exists, err := uploader.PackageExists(t.Context(), "left-pad", "1.3.0")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	require.NoError(t, uploader.UploadPackageWithMetadata(t.Context(), "left-pad", "1.3.0", []byte("tarball"), map[string]interface{}{}))
+	manifest := uploaded["versions"].(map[string]interface{})["1.3.0"].(map[string]interface{})
+	assert.Equal(t, srv.URL+"/left-pad/-/left-pad-1.3.0.tgz", manifest["dist"].(map[string]interface{})["tarball"])
+}
@@ -0,0 +1,125 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LocalVersion returns the temporary version a local package is uploaded
+// under, e.g. 1.2.0-spr.20260102150405. It sorts below the real release so
+// it never becomes latest on a registry that already has that version.
+func LocalVersion(base string, t time.Time) string {
+	if base == "" {
+		base = "0.0.0"
+	}
+	core, _, _ := strings.Cut(base, "+")
+	core, _, _ = strings.Cut(core, "-")
+	return fmt.Sprintf("%s-spr.%s", core, t.UTC().Format("20060102150405"))
+}
+
+// UploadLocal packs an unpublished package directory with npm pack and
+// uploads it under version, returning the package name. Lifecycle scripts
+// (prepare, prepack) are not run, so build outputs must already exist.
+func (u *Uploader) UploadLocal(ctx context.Context, dir, version string) (string, error) {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s: %w", dir, err)
+	}
+
+	tempDir, err := os.MkdirTemp("", "spr-pack-*")
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	tarball, manifest, err := npmPack(ctx, absDir, tempDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to pack %s: %w", dir, err)
+	}
+	name, _ := manifest["name"].(string)
+	if name == "" {
+		return "", fmt.Errorf("package.json in %s has no name", dir)
+	}
+
+	// Installs verify the tarball's package.json against the requested version
+	tarball, err = setTarballVersion(tarball, version)
+	if err != nil {
+		return "", err
+	}
+	manifest["version"] = version
+
+	u.logMsg(fmt.Sprintf("Uploading local package %s@%s from %s", name, version, dir), "info")
+	if err := u.UploadPackageWithMetadata(ctx, name, version, tarball, manifest); err != nil {
+		return "", fmt.Errorf("failed to upload: %w", err)
+	}
+	return name, nil
+}
+
+// setTarballVersion rewrites the version in package/package.json of an npm
+// tarball, leaving every other entry untouched
+func setTarballVersion(tarball []byte, version string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tarball: %w", err)
+	}
+	defer gz.Close()
+
+	var out bytes.Buffer
+	gzw := gzip.NewWriter(&out)
+	tw := tar.NewWriter(gzw)
+	tr := tar.NewReader(gz)
+	found := false
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", hdr.Name, err)
+		}
+
+		if hdr.Name == "package/package.json" {
+			var manifest map[string]interface{}
+			if err := json.Unmarshal(data, &manifest); err != nil {
+				return nil, fmt.Errorf("failed to parse package.json: %w", err)
+			}
+			manifest["version"] = version
+			if data, err = json.MarshalIndent(manifest, "", "  "); err != nil {
+				return nil, fmt.Errorf("failed to marshal package.json: %w", err)
+			}
+			hdr.Size = int64(len(data))
+			found = true
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("failed to write tarball: %w", err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, fmt.Errorf("failed to write tarball: %w", err)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("package.json not found in tarball")
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write tarball: %w", err)
+	}
+	if err := gzw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write tarball: %w", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,65 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalVersion(t *testing.T) {
+	at := time.Date(2026, 1, 2, 15, 4, 5, 0, time.UTC)
+	assert.Equal(t, "1.2.0-spr.20260102150405", LocalVersion("1.2.0", at))
+	assert.Equal(t, "1.2.0-spr.20260102150405", LocalVersion("1.2.0-beta.1+build", at))
+	assert.Equal(t, "0.0.0-spr.20260102150405", LocalVersion("", at))
+	assert.Equal(t, -1, CompareSemver(LocalVersion("1.2.0", at), "1.2.0"))
+}
+
+func TestSetTarballVersion(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, entry := range []struct{ name, content string }{
+		{"package/package.json", `{"name":"my-lib","version":"1.2.0","main":"index.js"}`},
+		{"package/index.js", "module.exports = 1"},
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: entry.name, Mode: 0o644, Size: int64(len(entry.content))}))
+		_, err := tw.Write([]byte(entry.content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	rewritten, err := setTarballVersion(buf.Bytes(), "1.2.0-spr.1")
+	require.NoError(t, err)
+
+	manifest, err := readPackageJSON(rewritten)
+	require.NoError(t, err)
+	assert.Equal(t, "1.2.0-spr.1", manifest["version"])
+	assert.Equal(t, "index.js", manifest["main"])
+
+	// Other entries are carried over unchanged
+	gzr, err := gzip.NewReader(bytes.NewReader(rewritten))
+	require.NoError(t, err)
+	tr := tar.NewReader(gzr)
+	contents := make(map[string]string)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := io.ReadAll(tr)
+		require.NoError(t, err)
+		contents[hdr.Name] = string(data)
+	}
+	assert.Equal(t, "module.exports = 1", contents["package/index.js"])
+
+	_, err = setTarballVersion([]byte("not a tarball"), "1.0.0")
+	assert.Error(t, err)
+}
@@ -0,0 +1,222 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// hostedGitPrefixes maps npm's hosted git shorthands to clone URL prefixes
+var hostedGitPrefixes = map[string]string{
+	"github:":    "https://github.com/",
+	"gitlab:":    "https://gitlab.com/",
+	"bitbucket:": "https://bitbucket.org/",
+}
+
+// hostedGitHosts are hosts whose git+ssh URLs are cloned over HTTPS instead,
+// since lockfiles record ssh even for public repos and sandboxes carry no keys
+var hostedGitHosts = []string{"github.com", "gitlab.com", "bitbucket.org"}
+
+// gitTransports are the only URL schemes cloned. Others such as ext:: (runs
+// an arbitrary command) or file:// (reads the host filesystem) are rejected.
+var gitTransports = map[string]bool{"https": true, "ssh": true, "git": true}
+
+// SetAllowNonNpm lets UploadGraph handle git and URL dependencies by cloning
+// or downloading them, repacking with npm pack and uploading the result like
+// any npm package. When disabled (the default) such dependencies abort the
+// upload.
+func (u *Uploader) SetAllowNonNpm(enabled bool) {
+	u.allowNonNpm = enabled
+}
+
+// uploadNonNpmNode packs a git or URL dependency and uploads it under the name
+// and version recorded in the lockfile
+func (u *Uploader) uploadNonNpmNode(ctx context.Context, node *models.PackageNode) error {
+	tarball, manifest, err := u.packNonNpm(ctx, node.ResolvedURL)
+	if err != nil {
+		return fmt.Errorf("failed to pack %s: %w", node.ResolvedURL, err)
+	}
+
+	if name, _ := manifest["name"].(string); name != "" && name != node.Name {
+		u.logMsg(fmt.Sprintf("Packed %s declares name %q, uploading as %s", node.ResolvedURL, name, node.Name), "warning")
+	}
+	// The lockfile's name and version are what installs will ask for
+	manifest["name"] = node.Name
+	manifest["version"] = node.Version
+
+	if err := u.UploadPackageWithMetadata(ctx, node.Name, node.Version, tarball, manifest); err != nil {
+		return fmt.Errorf("failed to upload: %w", err)
+	}
+	return nil
+}
+
+// packNonNpm fetches a git or URL dependency and packs it with npm pack.
+// Lifecycle scripts are never run: prepare scripts of an unvetted git
+// dependency would otherwise execute on this host.
+func (u *Uploader) packNonNpm(ctx context.Context, resolved string) ([]byte, map[string]interface{}, error) {
+	tempDir, err := os.MkdirTemp("", "spr-pack-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	var source string
+	if repo, ref, ok := gitRemote(resolved); ok {
+		if err := checkGitRemote(repo, ref); err != nil {
+			return nil, nil, err
+		}
+		source = filepath.Join(tempDir, "src")
+		if err := runCommand(ctx, "", "git", "clone", "--quiet", "--", repo, source); err != nil {
+			return nil, nil, err
+		}
+		if ref != "" {
+			// A trailing -- makes git read ref as a revision, never a path
+			if err := runCommand(ctx, source, "git", "checkout", "--quiet", ref, "--"); err != nil {
+				return nil, nil, err
+			}
+		}
+	} else {
+		data, err := u.DownloadTarball(ctx, resolved)
+		if err != nil {
+			return nil, nil, err
+		}
+		source = filepath.Join(tempDir, "src.tgz")
+		if err := os.WriteFile(source, data, 0o644); err != nil {
+			return nil, nil, fmt.Errorf("failed to write tarball: %w", err)
+		}
+	}
+
+	return npmPack(ctx, source, tempDir)
+}
+
+// npmPack packs source (a directory or tarball) with npm pack inside
+// tempDir and returns the tarball and its package.json. Lifecycle scripts
+// are skipped.
+func npmPack(ctx context.Context, source, tempDir string) ([]byte, map[string]interface{}, error) {
+	outDir := filepath.Join(tempDir, "out")
+	if err := os.Mkdir(outDir, 0o755); err != nil {
+		return nil, nil, fmt.Errorf("failed to create pack directory: %w", err)
+	}
+	cmd := exec.CommandContext(ctx, "npm", "pack", source, "--ignore-scripts", "--json", "--pack-destination", outDir)
+	cmd.Dir = tempDir
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, nil, fmt.Errorf("npm pack failed: %w\nOutput: %s", err, exitErr.Stderr)
+		}
+		return nil, nil, fmt.Errorf("npm pack failed: %w", err)
+	}
+
+	var packed []struct {
+		Filename string `json:"filename"`
+	}
+	if err := json.Unmarshal(output, &packed); err != nil || len(packed) == 0 {
+		return nil, nil, fmt.Errorf("unexpected npm pack output: %s", output)
+	}
+
+	tarball, err := os.ReadFile(filepath.Join(outDir, filepath.Base(packed[0].Filename)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read packed tarball: %w", err)
+	}
+
+	manifest, err := readPackageJSON(tarball)
+	if err != nil {
+		return nil, nil, err
+	}
+	return tarball, manifest, nil
+}
+
+// gitRemote converts a lockfile resolved value for a git dependency into a
+// clone URL and committish. ok is false for anything that isn't git.
+func gitRemote(resolved string) (repo, ref string, ok bool) {
+	repo, ref, _ = strings.Cut(resolved, "#")
+
+	for shorthand, prefix := range hostedGitPrefixes {
+		if rest, found := strings.CutPrefix(repo, shorthand); found {
+			return prefix + strings.TrimSuffix(rest, ".git") + ".git", ref, true
+		}
+	}
+
+	rest, found := strings.CutPrefix(repo, "git+")
+	if !found {
+		if !strings.HasPrefix(repo, "git://") {
+			return "", "", false
+		}
+		rest = repo
+	}
+
+	for _, host := range hostedGitHosts {
+		if path, found := strings.CutPrefix(rest, "ssh://git@"+host+"/"); found {
+			return "https://" + host + "/" + path, ref, true
+		}
+	}
+	return rest, ref, true
+}
+
+// checkGitRemote rejects clone URLs and refs from a lockfile that git could
+// mistake for options, and transports other than gitTransports
+func checkGitRemote(repo, ref string) error {
+	if strings.HasPrefix(repo, "-") {
+		return fmt.Errorf("invalid git URL %q", repo)
+	}
+	if strings.HasPrefix(ref, "-") {
+		return fmt.Errorf("invalid git ref %q", ref)
+	}
+	u, err := url.Parse(repo)
+	if err != nil || u.Host == "" || !gitTransports[u.Scheme] {
+		return fmt.Errorf("unsupported git URL %q (only https, ssh and git transports are allowed)", repo)
+	}
+	return nil
+}
+
+// readPackageJSON extracts package/package.json from an npm tarball
+func readPackageJSON(tarball []byte) (map[string]interface{}, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(tarball))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tarball: %w", err)
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("package.json not found in tarball")
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball: %w", err)
+		}
+		if hdr.Name != "package/package.json" {
+			continue
+		}
+
+		var manifest map[string]interface{}
+		if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+			return nil, fmt.Errorf("failed to parse package.json: %w", err)
+		}
+		return manifest, nil
+	}
+}
+
+// runCommand runs a command, including its output in the error on failure
+func runCommand(ctx context.Context, dir, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s %s failed: %w\nOutput: %s", name, args[0], err, output)
+	}
+	return nil
+}
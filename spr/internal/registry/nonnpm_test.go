@@ -0,0 +1,114 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitRemote(t *testing.T) {
+	tests := []struct {
+		resolved string
+		repo     string
+		ref      string
+		ok       bool
+	}{
+		{"git+ssh://git@github.com/org/repo.git#0123abc", "https://github.com/org/repo.git", "0123abc", true},
+		{"git+https://gitlab.example.com/org/repo.git#v1.2.0", "https://gitlab.example.com/org/repo.git", "v1.2.0", true},
+		{"git+ssh://git@git.internal/org/repo.git", "ssh://git@git.internal/org/repo.git", "", true},
+		{"git://example.com/repo.git#main", "git://example.com/repo.git", "main", true},
+		{"github:org/repo#semver:^1.0.0", "https://github.com/org/repo.git", "semver:^1.0.0", true},
+		{"bitbucket:org/repo.git", "https://bitbucket.org/org/repo.git", "", true},
+		{"https://example.com/package.tgz", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resolved, func(t *testing.T) {
+			repo, ref, ok := gitRemote(tt.resolved)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.repo, repo)
+			assert.Equal(t, tt.ref, ref)
+		})
+	}
+}
+
+func TestCheckGitRemote(t *testing.T) {
+	tests := []struct {
+		resolved string
+		ok       bool
+	}{
+		{"git+ssh://git@github.com/org/repo.git#0123abc", true},
+		{"git+https://gitlab.example.com/org/repo.git#v1.2.0", true},
+		{"git+ssh://git@git.internal/org/repo.git", true},
+		{"git://example.com/repo.git#main", true},
+		{"github:org/repo#semver:^1.0.0", true},
+		{"git+--upload-pack=touch /tmp/pwned", false},
+		{"git+https://example.com/repo.git#--output=/tmp/pwned", false},
+		{"git+ext::sh -c touch% /tmp/pwned", false},
+		{"git+file:///etc/repo.git", false},
+		{"git+file://localhost/etc/repo.git", false},
+		{"git+/srv/repo.git", false},
+		{"git+git@github.com:org/repo.git", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.resolved, func(t *testing.T) {
+			repo, ref, ok := gitRemote(tt.resolved)
+			require.True(t, ok)
+			err := checkGitRemote(repo, ref)
+			if tt.ok {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPackNonNpmRejectsOptionInjection(t *testing.T) {
+	// Rejected before git ever runs
+	_, _, err := NewUploader("", "", "").packNonNpm(t.Context(), "git+--upload-pack=touch /tmp/pwned")
+	assert.ErrorContains(t, err, "invalid git URL")
+}
+
+func TestReadPackageJSON(t *testing.T) {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{
+		"package/index.js":     "module.exports = 1",
+		"package/package.json": `{"name":"private-lib","version":"1.0.0","bin":"cli.js"}`,
+	} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	manifest, err := readPackageJSON(buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, "private-lib", manifest["name"])
+	assert.Equal(t, "cli.js", manifest["bin"])
+
+	_, err = readPackageJSON([]byte("not a tarball"))
+	assert.Error(t, err)
+}
+
+func TestUploadGraphRejectsNonNpmByDefault(t *testing.T) {
+	graph := models.NewDependencyGraph()
+	graph.AddNode(&models.PackageNode{
+		Package:      models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"},
+		Dependencies: map[string]string{"private-lib": "github:org/repo"},
+	})
+
+	err := NewUploader("", "", "").UploadGraph(context.Background(), graph)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "github:org/repo")
+}
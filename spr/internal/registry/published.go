@@ -0,0 +1,64 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+)
+
+// npmRegistryURL is the upstream registry publish times are read from
+const npmRegistryURL = "https://registry.npmjs.org"
+
+// PublishTimes fetches when each version of a package was published to the
+// upstream npm registry
+func (u *Uploader) PublishTimes(ctx context.Context, name string) (map[string]time.Time, error) {
+	return FetchPublishTimes(ctx, u.HTTPClient, npmRegistryURL, name)
+}
+
+// FetchPublishTimes reads the time field of a package document on the npm
+// registry at registryURL, keyed by version. The created and modified
+// entries are left out.
+func FetchPublishTimes(ctx context.Context, client *http.Client, registryURL, name string) (map[string]time.Time, error) {
+	url := strings.TrimSuffix(registryURL, "/") + "/" + naming.URLPath(name)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	// The abbreviated install document has no time field
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch package document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch package document: status %d", resp.StatusCode)
+	}
+
+	var doc struct {
+		Time map[string]string `json:"time"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("failed to decode package document: %w", err)
+	}
+
+	times := make(map[string]time.Time, len(doc.Time))
+	for version, value := range doc.Time {
+		if version == "created" || version == "modified" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid publish time of %s@%s: %q", name, version, value)
+		}
+		times[version] = t
+	}
+	return times, nil
+}
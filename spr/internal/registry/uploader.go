@@ -9,19 +9,28 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"os"
+	"path/filepath"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/acheong08/hackeurope-spr/internal/breaker"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
 	"github.com/acheong08/hackeurope-spr/pkg/models"
 )
 
 // LogCallback is an optional function for forwarding log messages (e.g. to WebSocket).
 type LogCallback func(message, level string)
 
-// Uploader handles uploading packages to Gitea registry
+// ProgressCallback is an optional function receiving upload progress: the
+// packages of the graph done (uploaded or already present) out of total.
+type ProgressCallback func(done, total int)
+
+// Uploader handles uploading packages to an npm registry, Gitea's by default
 type Uploader struct {
 	BaseURL     string
 	Owner       string
@@ -29,9 +38,15 @@ type Uploader struct {
 	Concurrency int
 	HTTPClient  *http.Client
 	logCb       LogCallback
+	progressCb  ProgressCallback
+	logger      *slog.Logger
+	allowNonNpm bool // Pack and upload git/URL dependencies instead of rejecting them
+	backend     RegistryBackend
+	tarballDir  string // Keeps the downloaded tarballs, see SetTarballDir
 }
 
-// NewUploader creates a new registry uploader
+// NewUploader creates a new registry uploader for a Gitea registry; use
+// SetBackend to target another registry type
 func NewUploader(baseURL, owner, token string) *Uploader {
 	return &Uploader{
 		BaseURL:     strings.TrimSuffix(baseURL, "/"),
@@ -39,60 +54,137 @@ func NewUploader(baseURL, owner, token string) *Uploader {
 		Token:       token,
 		Concurrency: 10,
 		HTTPClient: &http.Client{
-			Timeout: 60 * time.Second,
+			Timeout:   60 * time.Second,
+			Transport: breaker.Transport(breaker.Registry, nil), // Tarball downloads go through npm's
 		},
+		logger:  slog.Default(),
+		backend: NewBackend(TypeGitea, baseURL, owner, token),
 	}
 }
 
+// SetBackend selects the registry API packages are uploaded through
+func (u *Uploader) SetBackend(b RegistryBackend) {
+	u.backend = b
+}
+
+// SetTarballDir keeps a copy of every tarball downloaded for upload in dir, at
+// TarballPath, so later stages such as static analysis needn't download it
+// again. Empty discards them.
+func (u *Uploader) SetTarballDir(dir string) {
+	u.tarballDir = dir
+}
+
+// TarballPath is where a tarball kept by SetTarballDir is stored in dir
+func TarballPath(dir, name, version string) string {
+	return filepath.Join(dir, naming.DirName(name, version)+".tgz")
+}
+
 // SetLogCallback sets an optional callback for forwarding log messages.
 func (u *Uploader) SetLogCallback(cb LogCallback) {
 	u.logCb = cb
 }
 
-// logMsg prints to console and optionally forwards via the log callback.
-func (u *Uploader) logMsg(message, level string) {
-	log.Printf("%s", message)
+// SetProgressCallback sets an optional callback told how many packages are
+// done after the existence check and after every upload. Calls are
+// serialized.
+func (u *Uploader) SetProgressCallback(cb ProgressCallback) {
+	u.progressCb = cb
+}
+
+// reportProgress forwards progress to the progress callback, if any
+func (u *Uploader) reportProgress(done, total int) {
+	if u.progressCb != nil {
+		u.progressCb(done, total)
+	}
+}
+
+// SetLogger sets the structured logger, typically one already tagged with a run ID.
+func (u *Uploader) SetLogger(logger *slog.Logger) {
+	u.logger = logger
+}
+
+// logMsg writes a structured log line and optionally forwards via the log callback.
+func (u *Uploader) logMsg(message, level string, attrs ...any) {
+	attrs = append(attrs, logging.KeyStage, "upload", "registry", u.BaseURL+"/"+u.Owner)
+	logging.Log(u.logger, message, level, attrs...)
 	if u.logCb != nil {
 		u.logCb(message, level)
 	}
 }
 
 // PackageExists checks if a specific package version already exists in the registry
-// Uses the npm registry protocol: GET /api/packages/{owner}/npm/{packageName}
+// Uses the npm registry protocol: GET the package document (for Gitea
+// /api/packages/{owner}/npm/{packageName})
 // Returns true only if the specific version exists
 func (u *Uploader) PackageExists(ctx context.Context, name, version string) (bool, error) {
-	// Normalize package name for URL
-	pkgPath := normalizePackageName(name)
-	url := fmt.Sprintf("%s/api/packages/%s/npm/%s", u.BaseURL, u.Owner, pkgPath)
+	existing, err := u.fetchPackument(ctx, name)
+	if err != nil {
+		return false, err
+	}
+	if existing == nil {
+		return false, nil
+	}
+	_, versionExists := existing.Versions[version]
+	return versionExists, nil
+}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+// packument is the subset of the registry's package document needed to merge
+// a new version into it
+type packument struct {
+	DistTags map[string]string          `json:"dist-tags"`
+	Versions map[string]json.RawMessage `json:"versions"`
+}
+
+// versions returns the versions of a package document, none if it is nil
+func (p *packument) versions() map[string]json.RawMessage {
+	if p == nil {
+		return nil
+	}
+	return p.Versions
+}
+
+// fetchPackument fetches the package document from the registry.
+// Returns nil without error when the package has never been uploaded.
+func (u *Uploader) fetchPackument(ctx context.Context, name string) (*packument, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.backend.MetadataURL(name), nil)
 	if err != nil {
-		return false, fmt.Errorf("failed to create request: %w", err)
+		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+u.Token)
+	u.backend.Authorize(req)
 
 	resp, err := u.HTTPClient.Do(req)
 	if err != nil {
-		return false, fmt.Errorf("failed to check package existence: %w", err)
+		return nil, fmt.Errorf("failed to check package existence: %w", err)
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode == http.StatusOK {
-		// Parse response to check if specific version exists
-		var pkgMetadata struct {
-			Versions map[string]interface{} `json:"versions"`
-		}
-		if err := json.NewDecoder(resp.Body).Decode(&pkgMetadata); err != nil {
-			return false, fmt.Errorf("failed to decode package metadata: %w", err)
-		}
-		_, versionExists := pkgMetadata.Versions[version]
-		return versionExists, nil
-	} else if resp.StatusCode == http.StatusNotFound {
-		return false, nil
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 
-	return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	var pkgMetadata packument
+	if err := json.NewDecoder(resp.Body).Decode(&pkgMetadata); err != nil {
+		return nil, fmt.Errorf("failed to decode package metadata: %w", err)
+	}
+	return &pkgMetadata, nil
+}
+
+// mergeDistTags keeps the registry's existing dist-tags and only points
+// latest at version when it is newer than the current latest, so uploading
+// an old version of a package never downgrades what installs resolve to
+func mergeDistTags(existing map[string]string, version string) map[string]string {
+	tags := make(map[string]string, len(existing)+1)
+	for tag, v := range existing {
+		tags[tag] = v
+	}
+	if latest, ok := tags["latest"]; !ok || CompareSemver(version, latest) > 0 {
+		tags["latest"] = version
+	}
+	return tags
 }
 
 // DownloadTarball downloads a package tarball from npm
@@ -123,13 +215,7 @@ func (u *Uploader) DownloadTarball(ctx context.Context, url string) ([]byte, err
 // FetchPackageMetadata fetches normalized package metadata from npm registry API
 // This returns properly structured metadata (bin as object, repository as object, etc.)
 func (u *Uploader) FetchPackageMetadata(ctx context.Context, name, version string) (map[string]interface{}, error) {
-	// Handle scoped packages in URL
-	urlName := name
-	if strings.HasPrefix(name, "@") {
-		urlName = strings.Replace(name, "/", "%2F", 1)
-	}
-
-	url := fmt.Sprintf("https://registry.npmjs.org/%s/%s", urlName, version)
+	url := fmt.Sprintf("https://registry.npmjs.org/%s/%s", naming.URLPath(name), version)
 
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
@@ -157,16 +243,23 @@ func (u *Uploader) FetchPackageMetadata(ctx context.Context, name, version strin
 	return metadata, nil
 }
 
-// UploadPackageWithMetadata uploads a package to the Gitea registry using npm protocol
+// UploadPackageWithMetadata uploads a package to the registry using npm protocol
 // Uses pre-fetched metadata from npm API (already normalized) instead of extracting from tarball
 func (u *Uploader) UploadPackageWithMetadata(ctx context.Context, name, version string, tarball []byte, apiMetadata map[string]interface{}) error {
-	// Normalize package name for URL path
-	pkgPath := normalizePackageName(name)
-
-	url := fmt.Sprintf("%s/api/packages/%s/npm/%s", u.BaseURL, u.Owner, pkgPath)
+	// Merge with the dist-tags already in the registry instead of blindly
+	// moving latest to the version being uploaded
+	existing, err := u.fetchPackument(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to fetch existing metadata: %w", err)
+	}
+	var existingTags map[string]string
+	if existing != nil {
+		existingTags = existing.DistTags
+	}
+	distTags := mergeDistTags(existingTags, version)
 
 	// Build the npm metadata JSON using API metadata (already normalized)
-	metadata, err := u.buildMetadataFromAPI(name, version, tarball, apiMetadata)
+	metadata, err := u.buildMetadataFromAPI(name, version, tarball, apiMetadata, distTags)
 	if err != nil {
 		return fmt.Errorf("failed to build metadata: %w", err)
 	}
@@ -176,12 +269,12 @@ func (u *Uploader) UploadPackageWithMetadata(ctx context.Context, name, version
 		return fmt.Errorf("failed to marshal metadata: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPut, url, bytes.NewReader(metadataJSON))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.backend.PublishURL(name), bytes.NewReader(metadataJSON))
 	if err != nil {
 		return fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+u.Token)
+	u.backend.Authorize(req)
 	req.Header.Set("Content-Type", "application/json")
 
 	resp, err := u.HTTPClient.Do(req)
@@ -283,7 +376,7 @@ func normalizeRepositoryField(apiMetadata map[string]interface{}) map[string]int
 
 // buildMetadataFromAPI constructs npm package metadata JSON using pre-fetched API metadata
 // The npm registry API already returns normalized fields (bin as object, repository as object, etc.)
-func (u *Uploader) buildMetadataFromAPI(name, version string, tarball []byte, apiMetadata map[string]interface{}) (map[string]interface{}, error) {
+func (u *Uploader) buildMetadataFromAPI(name, version string, tarball []byte, apiMetadata map[string]interface{}, distTags map[string]string) (map[string]interface{}, error) {
 	// Calculate hashes
 	hash512 := sha512.Sum512(tarball)
 	hash1 := sha1.Sum(tarball)
@@ -301,8 +394,7 @@ func (u *Uploader) buildMetadataFromAPI(name, version string, tarball []byte, ap
 	tarballFileName := fmt.Sprintf("%s-%s.tgz", tarballName, version)
 
 	// Construct tarball URL
-	tarballURL := fmt.Sprintf("%s/api/packages/%s/npm/%s/-/%s",
-		u.BaseURL, u.Owner, normalizePackageName(name), tarballFileName)
+	tarballURL := u.backend.TarballURL(name, tarballFileName)
 
 	// Build manifest with required fields
 	manifest := map[string]interface{}{
@@ -347,7 +439,7 @@ func (u *Uploader) buildMetadataFromAPI(name, version string, tarball []byte, ap
 	root := map[string]interface{}{
 		"_id":       name,
 		"name":      name,
-		"dist-tags": map[string]string{"latest": version},
+		"dist-tags": distTags,
 		"versions": map[string]interface{}{
 			version: manifest,
 		},
@@ -363,24 +455,51 @@ func (u *Uploader) buildMetadataFromAPI(name, version string, tarball []byte, ap
 	return root, nil
 }
 
+// UploadSummary counts the packages of an upload
+type UploadSummary struct {
+	Uploaded int `json:"uploaded"`
+	Skipped  int `json:"skipped"` // Already in the registry
+}
+
 // UploadGraph uploads all packages in the dependency graph
 func (u *Uploader) UploadGraph(ctx context.Context, graph *models.DependencyGraph) error {
-	// Filter out root package and collect all nodes
+	_, err := u.UploadMissing(ctx, graph)
+	return err
+}
+
+// UploadMissing uploads the packages of the dependency graph the registry
+// doesn't have yet. Existence is checked for the whole graph up front, one
+// package document per name, so a mostly unchanged tree only costs a round
+// of metadata reads.
+func (u *Uploader) UploadMissing(ctx context.Context, graph *models.DependencyGraph) (UploadSummary, error) {
+	// Filter out root package and collect all nodes, dependencies first so
+	// they are published before the packages that install them
 	var nodes []*models.PackageNode
-	for _, node := range graph.Nodes {
-		if graph.RootPackage != nil && node.ID == graph.RootPackage.ID {
+	for _, id := range graph.TopologicalOrder() {
+		if graph.RootPackage != nil && id == graph.RootPackage.ID {
 			continue // Skip root package
 		}
-		nodes = append(nodes, node)
+		nodes = append(nodes, graph.Nodes[id])
 	}
 
 	// Check for non-npm dependencies
 	nonNpmDeps := u.extractNonNpmDeps(nodes)
-	if len(nonNpmDeps) > 0 {
-		return fmt.Errorf("unsupported non-npm dependencies found: %v. These dependency types are not yet supported", nonNpmDeps)
+	if len(nonNpmDeps) > 0 && !u.allowNonNpm {
+		return UploadSummary{}, fmt.Errorf("unsupported non-npm dependencies found: %v. Enable non-npm dependency support to pack and upload them", nonNpmDeps)
+	}
+
+	missing, err := u.missingNodes(ctx, nodes)
+	if err != nil {
+		return UploadSummary{}, err
+	}
+	summary := UploadSummary{Skipped: len(nodes) - len(missing)}
+	u.reportProgress(summary.Skipped, len(nodes))
+	if len(missing) == 0 {
+		u.logMsg(fmt.Sprintf("All %d packages already in %s registry, nothing to upload", len(nodes), u.backend.Type()), "success")
+		return summary, nil
 	}
 
-	u.logMsg(fmt.Sprintf("Uploading %d packages to Gitea registry...", len(nodes)), "info")
+	u.logMsg(fmt.Sprintf("Uploading %d packages to %s registry (%d already present)...", len(missing), u.backend.Type(), summary.Skipped), "info")
 
 	// Upload npm packages with worker pool
 	var wg sync.WaitGroup
@@ -390,7 +509,7 @@ func (u *Uploader) UploadGraph(ctx context.Context, graph *models.DependencyGrap
 	var mu sync.Mutex
 	var stopChan = make(chan struct{})
 
-	for _, node := range nodes {
+	for _, node := range missing {
 		wg.Add(1)
 		go func(n *models.PackageNode) {
 			defer wg.Done()
@@ -416,7 +535,8 @@ func (u *Uploader) UploadGraph(ctx context.Context, graph *models.DependencyGrap
 
 			mu.Lock()
 			processedCount++
-			u.logMsg(fmt.Sprintf("[%d/%d] Uploaded: %s@%s", processedCount, len(nodes), n.Name, n.Version), "info")
+			u.logMsg(fmt.Sprintf("[%d/%d] Uploaded: %s@%s", processedCount, len(missing), n.Name, n.Version), "info", logging.KeyPackageID, n.ID)
+			u.reportProgress(summary.Skipped+processedCount, len(nodes))
 			mu.Unlock()
 		}(node)
 	}
@@ -425,23 +545,70 @@ func (u *Uploader) UploadGraph(ctx context.Context, graph *models.DependencyGrap
 	close(errChan)
 
 	// Check if any error occurred
+	summary.Uploaded = processedCount
 	if err := <-errChan; err != nil {
-		return err
+		return summary, err
 	}
 
-	u.logMsg(fmt.Sprintf("Successfully uploaded %d packages", len(nodes)), "success")
-	return nil
+	u.logMsg(fmt.Sprintf("Successfully uploaded %d packages, skipped %d already present", summary.Uploaded, summary.Skipped), "success")
+	return summary, nil
 }
 
-// uploadNode uploads a single package node
-func (u *Uploader) uploadNode(ctx context.Context, node *models.PackageNode) error {
-	// Check if already exists
-	exists, err := u.PackageExists(ctx, node.Name, node.Version)
-	if err != nil {
-		return fmt.Errorf("failed to check existence: %w", err)
+// missingNodes returns the nodes whose versions the registry doesn't have,
+// in their original order
+func (u *Uploader) missingNodes(ctx context.Context, nodes []*models.PackageNode) ([]*models.PackageNode, error) {
+	var names []string
+	versions := make(map[string]map[string]bool)
+	for _, node := range nodes {
+		if versions[node.Name] == nil {
+			versions[node.Name] = make(map[string]bool)
+			names = append(names, node.Name)
+		}
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var firstErr error
+	semaphore := make(chan struct{}, max(u.Concurrency, 1))
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			existing, err := u.fetchPackument(ctx, name)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("failed to check existence of %s: %w", name, err)
+				}
+				return
+			}
+			for version := range existing.versions() {
+				versions[name][version] = true
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+
+	var missing []*models.PackageNode
+	for _, node := range nodes {
+		if !versions[node.Name][node.Version] {
+			missing = append(missing, node)
+		}
 	}
-	if exists {
-		return nil // Skip existing packages
+	return missing, nil
+}
+
+// uploadNode uploads a single package node missing from the registry
+func (u *Uploader) uploadNode(ctx context.Context, node *models.PackageNode) error {
+	if u.allowNonNpm && IsNonNpmDep(node.ResolvedURL) {
+		return u.uploadNonNpmNode(ctx, node)
 	}
 
 	// Fetch normalized metadata from npm registry API
@@ -451,17 +618,12 @@ func (u *Uploader) uploadNode(ctx context.Context, node *models.PackageNode) err
 		return fmt.Errorf("failed to fetch metadata for %s@%s: %w", node.Name, node.Version, err)
 	}
 
-	// Get tarball URL - construct from npm registry if not provided
-	tarballURL := node.ResolvedURL
-	if tarballURL == "" {
-		tarballURL = constructNpmTarballURL(node.Name, node.Version)
-	}
-
 	// Download tarball
-	tarball, err := u.DownloadTarball(ctx, tarballURL)
+	tarball, err := u.DownloadTarball(ctx, TarballURL(node))
 	if err != nil {
 		return fmt.Errorf("failed to download: %w", err)
 	}
+	u.keepTarball(node, tarball)
 
 	// Upload to registry with API metadata (already normalized)
 	if err := u.UploadPackageWithMetadata(ctx, node.Name, node.Version, tarball, metadata); err != nil {
@@ -471,6 +633,17 @@ func (u *Uploader) uploadNode(ctx context.Context, node *models.PackageNode) err
 	return nil
 }
 
+// keepTarball saves a downloaded tarball to the tarball directory, if any.
+// A failure only costs a later download, so it is logged, not returned.
+func (u *Uploader) keepTarball(node *models.PackageNode, tarball []byte) {
+	if u.tarballDir == "" {
+		return
+	}
+	if err := os.WriteFile(TarballPath(u.tarballDir, node.Name, node.Version), tarball, 0o644); err != nil {
+		u.logMsg(fmt.Sprintf("Failed to keep tarball of %s: %v", node.ID, err), "warning", logging.KeyPackageID, node.ID)
+	}
+}
+
 // extractNonNpmDeps extracts non-npm dependency URLs from nodes
 func (u *Uploader) extractNonNpmDeps(nodes []*models.PackageNode) []string {
 	var urls []string
@@ -478,7 +651,7 @@ func (u *Uploader) extractNonNpmDeps(nodes []*models.PackageNode) []string {
 
 	for _, node := range nodes {
 		for _, depURL := range node.Dependencies {
-			if isNonNpmDep(depURL) && !seen[depURL] {
+			if IsNonNpmDep(depURL) && !seen[depURL] {
 				urls = append(urls, depURL)
 				seen[depURL] = true
 			}
@@ -488,8 +661,8 @@ func (u *Uploader) extractNonNpmDeps(nodes []*models.PackageNode) []string {
 	return urls
 }
 
-// isNonNpmDep checks if a dependency URL is not from npm registry
-func isNonNpmDep(url string) bool {
+// IsNonNpmDep checks if a dependency URL is not from npm registry
+func IsNonNpmDep(url string) bool {
 	return strings.HasPrefix(url, "git+") ||
 		strings.HasPrefix(url, "github:") ||
 		strings.HasPrefix(url, "gitlab:") ||
@@ -500,14 +673,16 @@ func isNonNpmDep(url string) bool {
 
 // normalizePackageName normalizes a package name for URL
 func normalizePackageName(name string) string {
-	// Replace @scope/name with @scope%2fname
-	if strings.HasPrefix(name, "@") {
-		parts := strings.SplitN(name, "/", 2)
-		if len(parts) == 2 {
-			return parts[0] + "%2f" + parts[1]
-		}
+	return naming.URLPath(name)
+}
+
+// TarballURL returns the tarball URL for a package node, constructing the npm
+// registry URL when the lockfile didn't record a resolved one
+func TarballURL(node *models.PackageNode) string {
+	if node.ResolvedURL != "" {
+		return node.ResolvedURL
 	}
-	return name
+	return constructNpmTarballURL(node.Name, node.Version)
 }
 
 // constructNpmTarballURL constructs the npm registry tarball URL for a package
@@ -2,6 +2,10 @@ package registry
 
 import (
 	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
 	"github.com/acheong08/hackeurope-spr/pkg/models"
@@ -42,7 +46,7 @@ func TestIsNonNpmDep(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.url, func(t *testing.T) {
-			result := isNonNpmDep(tt.url)
+			result := IsNonNpmDep(tt.url)
 			assert.Equal(t, tt.expected, result)
 		})
 	}
@@ -141,3 +145,118 @@ func TestConstructNpmTarballURL(t *testing.T) {
 		})
 	}
 }
+
+func TestCompareSemver(t *testing.T) {
+	tests := []struct {
+		a, b     string
+		expected int
+	}{
+		{"1.0.0", "1.0.0", 0},
+		{"1.0.1", "1.0.0", 1},
+		{"1.10.0", "1.9.0", 1},
+		{"2.0.0", "10.0.0", -1},
+		{"1.0.0-rc.1", "1.0.0", -1},
+		{"1.0.0-alpha", "1.0.0-alpha.1", -1},
+		{"1.0.0-alpha.2", "1.0.0-alpha.10", -1},
+		{"1.0.0-1", "1.0.0-alpha", -1},
+		{"1.0.0+build.5", "1.0.0", 0},
+		{"not-a-version", "0.0.1", -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.a+"_"+tt.b, func(t *testing.T) {
+			assert.Equal(t, tt.expected, CompareSemver(tt.a, tt.b))
+			assert.Equal(t, -tt.expected, CompareSemver(tt.b, tt.a))
+		})
+	}
+}
+
+func TestMergeDistTags(t *testing.T) {
+	assert.Equal(t, map[string]string{"latest": "1.0.0"}, mergeDistTags(nil, "1.0.0"))
+
+	existing := map[string]string{"latest": "2.0.0", "next": "3.0.0-beta.1"}
+	assert.Equal(t, existing, mergeDistTags(existing, "1.5.0"))
+	assert.Equal(t, map[string]string{"latest": "2.1.0", "next": "3.0.0-beta.1"}, mergeDistTags(existing, "2.1.0"))
+	assert.Equal(t, "2.0.0", existing["latest"], "existing tags must not be mutated")
+}
+
+func TestUploadPackageWithMetadataKeepsNewerLatest(t *testing.T) {
+	var uploaded map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/packages/owner/npm/lodash", r.URL.EscapedPath())
+		switch r.Method {
+		case http.MethodGet:
+			w.Write([]byte(`{"dist-tags":{"latest":"4.17.21","legacy":"3.10.1"},"versions":{"4.17.21":{},"3.10.1":{}}}`))
+		case http.MethodPut:
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&uploaded))
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	uploader := NewUploader(srv.URL, "owner", "token")
+	err := uploader.UploadPackageWithMetadata(t.Context(), "lodash", "4.17.20", []byte("tarball"), map[string]interface{}{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]interface{}{"latest": "4.17.21", "legacy": "3.10.1"}, uploaded["dist-tags"])
+	assert.Contains(t, uploaded["versions"], "4.17.20")
+}
+
+func TestUploadMissing(t *testing.T) {
+	var gets int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, http.MethodGet, r.Method, "nothing should be published")
+		gets++
+		switch r.URL.Path {
+		case "/api/packages/owner/npm/left-pad":
+			json.NewEncoder(w).Encode(map[string]any{"versions": map[string]any{"1.3.0": map[string]any{}, "1.2.0": map[string]any{}}})
+		case "/api/packages/owner/npm/is-odd":
+			json.NewEncoder(w).Encode(map[string]any{"versions": map[string]any{"3.0.1": map[string]any{}}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	graph := models.NewDependencyGraph()
+	for _, node := range []*models.PackageNode{
+		{Package: models.Package{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"}},
+		{Package: models.Package{ID: "left-pad@1.2.0", Name: "left-pad", Version: "1.2.0"}},
+		{Package: models.Package{ID: "is-odd@3.0.1", Name: "is-odd", Version: "3.0.1"}},
+	} {
+		graph.AddNode(node)
+	}
+
+	uploader := NewUploader(srv.URL, "owner", "token")
+	var progress [][2]int
+	uploader.SetProgressCallback(func(done, total int) {
+		progress = append(progress, [2]int{done, total})
+	})
+	summary, err := uploader.UploadMissing(t.Context(), graph)
+	require.NoError(t, err)
+	assert.Equal(t, UploadSummary{Skipped: 3}, summary)
+	assert.Equal(t, [][2]int{{3, 3}}, progress, "packages already present count as done")
+	assert.Equal(t, 2, gets, "one package document per name")
+
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "is-even@1.0.0", Name: "is-even", Version: "1.0.0"}})
+	graph.AddNode(&models.PackageNode{Package: models.Package{ID: "left-pad@1.1.0", Name: "left-pad", Version: "1.1.0"}})
+	missing, err := uploader.missingNodes(t.Context(), []*models.PackageNode{graph.Nodes["left-pad@1.1.0"], graph.Nodes["is-odd@3.0.1"], graph.Nodes["is-even@1.0.0"]})
+	require.NoError(t, err)
+	require.Len(t, missing, 2)
+	assert.Equal(t, "left-pad@1.1.0", missing[0].ID)
+	assert.Equal(t, "is-even@1.0.0", missing[1].ID)
+}
+
+func TestKeepTarball(t *testing.T) {
+	node := &models.PackageNode{Package: models.Package{ID: "@scope/pkg@1.0.0", Name: "@scope/pkg", Version: "1.0.0"}}
+
+	uploader := NewUploader("", "", "")
+	uploader.keepTarball(node, []byte("tarball")) // No directory, discarded
+
+	dir := t.TempDir()
+	uploader.SetTarballDir(dir)
+	uploader.keepTarball(node, []byte("tarball"))
+	data, err := os.ReadFile(TarballPath(dir, node.Name, node.Version))
+	require.NoError(t, err)
+	assert.Equal(t, "tarball", string(data))
+}
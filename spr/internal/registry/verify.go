@@ -0,0 +1,136 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Verification is the outcome of reading one uploaded version back from the
+// registry. A publish answered with 409 Conflict counts as success, so
+// without this a version that never made it in goes unnoticed.
+type Verification struct {
+	ID         string `json:"id"`
+	Resolvable bool   `json:"resolvable"` // Listed in the package document
+	Tarball    bool   `json:"tarball"`    // Tarball answers a HEAD request
+	// False when the registry's dist.integrity contradicts the lockfile's
+	Integrity bool   `json:"integrity"`
+	Error     string `json:"error,omitempty"`
+}
+
+// OK reports whether the version can be installed from the registry
+func (v Verification) OK() bool {
+	return v.Resolvable && v.Tarball && v.Integrity
+}
+
+// versionDist is the dist section of a version in the package document
+type versionDist struct {
+	Dist struct {
+		Tarball   string `json:"tarball"`
+		Integrity string `json:"integrity"`
+	} `json:"dist"`
+}
+
+// VerifyNode checks that a node's version is listed by the registry, that its
+// tarball is served, and that its integrity matches the lockfile's
+func (u *Uploader) VerifyNode(ctx context.Context, node *models.PackageNode) Verification {
+	v := Verification{ID: node.ID}
+
+	existing, err := u.fetchPackument(ctx, node.Name)
+	if err != nil {
+		v.Error = err.Error()
+		return v
+	}
+	raw, ok := existing.versions()[node.Version]
+	if !ok {
+		v.Error = "version not found in registry"
+		return v
+	}
+	v.Resolvable = true
+
+	var dist versionDist
+	if err := json.Unmarshal(raw, &dist); err != nil || dist.Dist.Tarball == "" {
+		v.Error = "version has no tarball"
+		return v
+	}
+	v.Integrity = integrityMatches(node.Integrity, dist.Dist.Integrity)
+	if !v.Integrity {
+		v.Error = fmt.Sprintf("integrity mismatch: registry has %s", dist.Dist.Integrity)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, dist.Dist.Tarball, nil)
+	if err != nil {
+		v.Error = fmt.Sprintf("invalid tarball URL: %v", err)
+		return v
+	}
+	u.backend.Authorize(req)
+	resp, err := u.HTTPClient.Do(req)
+	if err != nil {
+		v.Error = fmt.Sprintf("tarball unreachable: %v", err)
+		return v
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		v.Error = fmt.Sprintf("tarball returned status %d", resp.StatusCode)
+		return v
+	}
+	v.Tarball = true
+	return v
+}
+
+// VerifyGraph verifies every package of the graph but the root, sorted by ID
+func (u *Uploader) VerifyGraph(ctx context.Context, graph *models.DependencyGraph) []Verification {
+	var nodes []*models.PackageNode
+	for _, node := range graph.Nodes {
+		if graph.RootPackage != nil && node.ID == graph.RootPackage.ID {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	results := make([]Verification, len(nodes))
+	var wg sync.WaitGroup
+	semaphore := make(chan struct{}, max(u.Concurrency, 1))
+	for i, node := range nodes {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+			results[i] = u.VerifyNode(ctx, node)
+		}()
+	}
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].ID < results[j].ID })
+	return results
+}
+
+// integrityMatches compares two SRI strings ("sha512-... sha1-..."). They
+// match unless they share a hash algorithm with different digests; without a
+// common algorithm (e.g. an old sha1 lockfile entry against the sha512 the
+// uploader computes) there is nothing to contradict.
+func integrityMatches(expected, actual string) bool {
+	digests := make(map[string]string)
+	for _, sri := range strings.Fields(actual) {
+		if algo, digest, ok := strings.Cut(sri, "-"); ok {
+			digests[algo] = digest
+		}
+	}
+	for _, sri := range strings.Fields(expected) {
+		algo, digest, ok := strings.Cut(sri, "-")
+		if !ok {
+			continue
+		}
+		if got, ok := digests[algo]; ok && got != digest {
+			return false
+		}
+	}
+	return true
+}
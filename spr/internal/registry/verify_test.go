@@ -0,0 +1,67 @@
+package registry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIntegrityMatches(t *testing.T) {
+	assert.True(t, integrityMatches("sha512-abc", "sha512-abc"))
+	assert.False(t, integrityMatches("sha512-abc", "sha512-xyz"))
+	assert.True(t, integrityMatches("sha1-abc", "sha512-xyz"))
+	assert.True(t, integrityMatches("", "sha512-xyz"))
+	assert.False(t, integrityMatches("sha1-old sha512-abc", "sha512-xyz"))
+}
+
+func TestVerifyGraph(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/packages/owner/npm/left-pad":
+			json.NewEncoder(w).Encode(map[string]any{"versions": map[string]any{
+				"1.3.0": map[string]any{"dist": map[string]any{
+					"tarball":   srv.URL + "/api/packages/owner/npm/left-pad/-/left-pad-1.3.0.tgz",
+					"integrity": "sha512-good",
+				}},
+				"1.2.0": map[string]any{"dist": map[string]any{
+					"tarball":   srv.URL + "/missing.tgz",
+					"integrity": "sha512-good",
+				}},
+			}})
+		case "/api/packages/owner/npm/left-pad/-/left-pad-1.3.0.tgz":
+			assert.Equal(t, http.MethodHead, r.Method)
+			assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	graph := models.NewDependencyGraph()
+	graph.RootPackage = &models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	for _, node := range []*models.PackageNode{
+		{Package: *graph.RootPackage},
+		{Package: models.Package{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"}, Integrity: "sha512-good"},
+		{Package: models.Package{ID: "left-pad@1.2.0", Name: "left-pad", Version: "1.2.0"}},
+		{Package: models.Package{ID: "left-pad@1.1.0", Name: "left-pad", Version: "1.1.0"}},
+		{Package: models.Package{ID: "is-odd@3.0.1", Name: "is-odd", Version: "3.0.1"}},
+	} {
+		graph.AddNode(node)
+	}
+
+	u := NewUploader(srv.URL, "owner", "token")
+	results := u.VerifyGraph(t.Context(), graph)
+	assert.Equal(t, []Verification{
+		{ID: "is-odd@3.0.1", Error: "version not found in registry"},
+		{ID: "left-pad@1.1.0", Error: "version not found in registry"},
+		{ID: "left-pad@1.2.0", Resolvable: true, Integrity: true, Error: "tarball returned status 404"},
+		{ID: "left-pad@1.3.0", Resolvable: true, Tarball: true, Integrity: true},
+	}, results)
+	assert.True(t, results[3].OK())
+	assert.False(t, results[2].OK())
+}
@@ -0,0 +1,165 @@
+// Package resultlog keeps an append-only, hash-chained log of pipeline
+// results. Each entry commits to the hash of the one before it, so editing,
+// reordering or deleting any entry but the last breaks the chain; comparing
+// the head hash with a copy kept elsewhere also catches a truncated tail.
+package resultlog
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// ErrTampered is returned by Verify when the chain is broken
+var ErrTampered = errors.New("results log tampered")
+
+// GenesisHash is the previous hash of the first entry
+var GenesisHash = strings.Repeat("0", sha256.Size*2)
+
+// Entry is one line of the log
+type Entry struct {
+	Seq      int             `json:"seq"` // 1-based
+	PrevHash string          `json:"prev_hash"`
+	Record   json.RawMessage `json:"record"`
+	Hash     string          `json:"hash"`
+}
+
+// computeHash hashes an entry's position, predecessor and record bytes
+func (e Entry) computeHash() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d\n%s\n", e.Seq, e.PrevHash)
+	h.Write(e.Record)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// appendMu serializes appends within the process; the log supports a single
+// writing process at a time
+var appendMu sync.Mutex
+
+// Append chains records onto the log at path, creating it if needed, and
+// returns the new head hash. Records are stored as their JSON encoding.
+func Append(path string, records ...any) (string, error) {
+	appendMu.Lock()
+	defer appendMu.Unlock()
+
+	last, err := lastEntry(path)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	for _, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode record: %w", err)
+		}
+		entry := Entry{Seq: last.Seq + 1, PrevHash: last.Hash, Record: data}
+		entry.Hash = entry.computeHash()
+		line, err := json.Marshal(entry)
+		if err != nil {
+			return "", fmt.Errorf("failed to encode entry: %w", err)
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+		last = entry
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open results log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		return "", fmt.Errorf("failed to append to results log: %w", err)
+	}
+	if err := f.Sync(); err != nil {
+		return "", fmt.Errorf("failed to sync results log: %w", err)
+	}
+	return last.Hash, nil
+}
+
+// lastEntry returns the last entry of the log, or a zero entry chaining to
+// GenesisHash when it is empty or doesn't exist
+func lastEntry(path string) (Entry, error) {
+	last := Entry{Hash: GenesisHash}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return last, nil
+	}
+	if err != nil {
+		return last, fmt.Errorf("failed to read results log: %w", err)
+	}
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return last, nil
+	}
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	if err := json.Unmarshal(data, &last); err != nil {
+		return last, fmt.Errorf("%w: last entry unreadable, refusing to extend it: %v", ErrTampered, err)
+	}
+	return last, nil
+}
+
+// Result summarizes a verified log
+type Result struct {
+	Entries int
+	Head    string // Hash of the last entry, GenesisHash when empty
+}
+
+// Verify checks every entry of the log at path against its predecessor. A
+// broken chain returns ErrTampered naming the first bad line.
+func Verify(path string) (Result, error) {
+	return walk(path, func(Entry) {})
+}
+
+// Read verifies the log at path like Verify and returns its entries
+func Read(path string) ([]Entry, error) {
+	var entries []Entry
+	if _, err := walk(path, func(e Entry) { entries = append(entries, e) }); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// walk verifies the log at path, calling fn with each entry that chains
+func walk(path string, fn func(Entry)) (Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to open results log: %w", err)
+	}
+	defer f.Close()
+
+	result := Result{Head: GenesisHash}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return result, fmt.Errorf("%w: line %d: unreadable entry: %v", ErrTampered, line, err)
+		}
+		switch {
+		case entry.Seq != result.Entries+1:
+			return result, fmt.Errorf("%w: line %d: sequence %d, expected %d", ErrTampered, line, entry.Seq, result.Entries+1)
+		case entry.PrevHash != result.Head:
+			return result, fmt.Errorf("%w: line %d: previous hash doesn't match line %d", ErrTampered, line, line-1)
+		case entry.computeHash() != entry.Hash:
+			return result, fmt.Errorf("%w: line %d: record doesn't match its hash", ErrTampered, line)
+		}
+		fn(entry)
+		result.Entries++
+		result.Head = entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return result, fmt.Errorf("failed to read results log: %w", err)
+	}
+	return result, nil
+}
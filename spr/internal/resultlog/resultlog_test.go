@@ -0,0 +1,81 @@
+package resultlog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type record struct {
+	Package string `json:"package"`
+	Verdict string `json:"verdict"`
+}
+
+func writeLog(t *testing.T) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "results.log")
+	_, err := Append(path, record{"a", "safe"}, record{"b", "malicious"})
+	require.NoError(t, err)
+	head, err := Append(path, record{"c", "clean"})
+	require.NoError(t, err)
+
+	result, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, 3, result.Entries)
+	assert.Equal(t, head, result.Head)
+	return path
+}
+
+func TestVerifyEmpty(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.log")
+	require.NoError(t, os.WriteFile(path, nil, 0o644))
+	result, err := Verify(path)
+	require.NoError(t, err)
+	assert.Equal(t, Result{Head: GenesisHash}, result)
+}
+
+func TestVerifyTampered(t *testing.T) {
+	for name, tamper := range map[string]func(lines []string) []string{
+		"edited verdict": func(lines []string) []string {
+			lines[1] = strings.Replace(lines[1], "malicious", "safe", 1)
+			return lines
+		},
+		"deleted entry": func(lines []string) []string {
+			return append(lines[:1], lines[2:]...)
+		},
+		"reordered entries": func(lines []string) []string {
+			lines[0], lines[1] = lines[1], lines[0]
+			return lines
+		},
+		"garbage": func(lines []string) []string {
+			return append(lines, "not json")
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			path := writeLog(t)
+			data, err := os.ReadFile(path)
+			require.NoError(t, err)
+			lines := tamper(strings.Split(strings.TrimSpace(string(data)), "\n"))
+			require.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o644))
+
+			_, err = Verify(path)
+			assert.ErrorIs(t, err, ErrTampered)
+		})
+	}
+}
+
+func TestAppendRefusesBrokenTail(t *testing.T) {
+	path := writeLog(t)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	require.NoError(t, err)
+	_, err = f.WriteString("{truncated\n")
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	_, err = Append(path, record{"d", "safe"})
+	assert.ErrorIs(t, err, ErrTampered)
+}
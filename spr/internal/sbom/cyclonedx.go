@@ -0,0 +1,230 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// CycloneDXSpecVersion is the CycloneDX specification version produced
+const CycloneDXSpecVersion = "1.5"
+
+// BOM is a CycloneDX bill of materials
+type BOM struct {
+	BOMFormat       string          `json:"bomFormat"`
+	SpecVersion     string          `json:"specVersion"`
+	SerialNumber    string          `json:"serialNumber"`
+	Version         int             `json:"version"`
+	Metadata        Metadata        `json:"metadata"`
+	Components      []Component     `json:"components"`
+	Dependencies    []Dependency    `json:"dependencies,omitempty"`
+	Vulnerabilities []Vulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// Metadata describes the BOM itself and the project it was generated for
+type Metadata struct {
+	Timestamp string     `json:"timestamp"`
+	Tools     Tools      `json:"tools"`
+	Component *Component `json:"component,omitempty"`
+}
+
+// Tools lists the tools that generated the BOM
+type Tools struct {
+	Components []Component `json:"components"`
+}
+
+// Component is a single package
+type Component struct {
+	Type               string              `json:"type"`
+	BOMRef             string              `json:"bom-ref,omitempty"`
+	Group              string              `json:"group,omitempty"`
+	Name               string              `json:"name"`
+	Version            string              `json:"version,omitempty"`
+	PURL               string              `json:"purl,omitempty"`
+	Hashes             []Hash              `json:"hashes,omitempty"`
+	ExternalReferences []ExternalReference `json:"externalReferences,omitempty"`
+	Properties         []Property          `json:"properties,omitempty"`
+}
+
+// Hash is a component digest
+type Hash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"` // Hex encoded
+}
+
+// ExternalReference points at where a component came from
+type ExternalReference struct {
+	Type string `json:"type"`
+	URL  string `json:"url"`
+}
+
+// Property is a free-form name/value annotation
+type Property struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Dependency lists the components a component directly depends on
+type Dependency struct {
+	Ref       string   `json:"ref"`
+	DependsOn []string `json:"dependsOn,omitempty"`
+}
+
+// Vulnerability records a package flagged as malicious by the analysis
+type Vulnerability struct {
+	BOMRef      string   `json:"bom-ref,omitempty"`
+	ID          string   `json:"id"`
+	Source      Source   `json:"source"`
+	Ratings     []Rating `json:"ratings,omitempty"`
+	CWEs        []int    `json:"cwes,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Detail      string   `json:"detail,omitempty"`
+	Affects     []Affect `json:"affects"`
+}
+
+// Source identifies who reported a vulnerability
+type Source struct {
+	Name string `json:"name"`
+}
+
+// Rating is a vulnerability severity
+type Rating struct {
+	Source   *Source `json:"source,omitempty"`
+	Severity string  `json:"severity"`
+	Method   string  `json:"method,omitempty"`
+}
+
+// Affect references a component a vulnerability applies to
+type Affect struct {
+	Ref string `json:"ref"`
+}
+
+// cweEmbeddedMaliciousCode is CWE-506, used for packages flagged as malicious
+const cweEmbeddedMaliciousCode = 506
+
+// cycloneDXAlgorithms maps SRI hash prefixes to CycloneDX algorithm names
+var cycloneDXAlgorithms = map[string]string{
+	"sha1":   "SHA-1",
+	"sha256": "SHA-256",
+	"sha384": "SHA-384",
+	"sha512": "SHA-512",
+}
+
+// NewCycloneDX builds a CycloneDX BOM from a dependency graph, recording
+// verdicts as component properties and flagged packages as vulnerabilities
+func NewCycloneDX(graph *models.DependencyGraph, verdicts Verdicts) *BOM {
+	bom := &BOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  CycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: Metadata{
+			Timestamp: time.Now().UTC().Format(time.RFC3339),
+			Tools: Tools{Components: []Component{
+				{Type: "application", Name: "spr", Version: version.Get().Version},
+			}},
+		},
+	}
+
+	layout := layoutGraph(graph)
+	for _, id := range layout.ids {
+		node := graph.Nodes[id]
+		ref := purl(node.Name, node.Version)
+
+		var dependsOn []string
+		for _, depID := range layout.deps[id] {
+			dep := graph.Nodes[depID]
+			dependsOn = append(dependsOn, purl(dep.Name, dep.Version))
+		}
+		bom.Dependencies = append(bom.Dependencies, Dependency{Ref: ref, DependsOn: dependsOn})
+
+		if id == layout.rootID {
+			root := newComponent(node)
+			root.Type = "application"
+			bom.Metadata.Component = &root
+			continue
+		}
+
+		component := newComponent(node)
+		verdict, assessment := verdicts.verdict(node)
+		component.Properties = verdictProperties(verdict, assessment)
+		bom.Components = append(bom.Components, component)
+
+		if verdict == VerdictMalicious {
+			bom.Vulnerabilities = append(bom.Vulnerabilities, newVulnerability(node, ref, assessment))
+		}
+	}
+
+	return bom
+}
+
+// Write writes the BOM as indented JSON
+func (b *BOM) Write(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SBOM: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// newComponent converts a graph node into a library component
+func newComponent(node *models.PackageNode) Component {
+	c := Component{
+		Type:    "library",
+		BOMRef:  purl(node.Name, node.Version),
+		Name:    node.Name,
+		Version: node.Version,
+		PURL:    purl(node.Name, node.Version),
+		Hashes:  cycloneDXHashes(node.Integrity),
+	}
+	if scope, name, ok := strings.Cut(node.Name, "/"); ok && strings.HasPrefix(scope, "@") {
+		c.Group = scope
+		c.Name = name
+	}
+	if node.ResolvedURL != "" {
+		c.ExternalReferences = []ExternalReference{{Type: "distribution", URL: node.ResolvedURL}}
+	}
+	return c
+}
+
+// verdictProperties records the analysis verdict on a component
+func verdictProperties(verdict string, assessment *analysis.SecurityAssessment) []Property {
+	props := []Property{{Name: "spr:verdict", Value: verdict}}
+	if assessment != nil {
+		props = append(props, Property{Name: "spr:confidence", Value: fmt.Sprintf("%.2f", assessment.Confidence)})
+	}
+	return props
+}
+
+// newVulnerability describes a flagged package as a CWE-506 finding
+func newVulnerability(node *models.PackageNode, ref string, assessment *analysis.SecurityAssessment) Vulnerability {
+	source := Source{Name: "spr"}
+	return Vulnerability{
+		BOMRef:      "spr:" + node.ID,
+		ID:          "SPR-" + node.ID,
+		Source:      source,
+		Ratings:     []Rating{{Source: &source, Severity: "critical", Method: "other"}},
+		CWEs:        []int{cweEmbeddedMaliciousCode},
+		Description: assessment.Justification,
+		Detail:      strings.Join(assessment.Indicators, "\n"),
+		Affects:     []Affect{{Ref: ref}},
+	}
+}
+
+// cycloneDXHashes converts a lockfile SRI string into CycloneDX hashes,
+// skipping unknown algorithms
+func cycloneDXHashes(sri string) []Hash {
+	var hashes []Hash
+	for _, d := range sriDigests(sri) {
+		if alg, ok := cycloneDXAlgorithms[d.algo]; ok {
+			hashes = append(hashes, Hash{Alg: alg, Content: d.hex})
+		}
+	}
+	return hashes
+}
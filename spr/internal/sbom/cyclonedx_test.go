@@ -0,0 +1,70 @@
+package sbom
+
+import (
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testGraph() *models.DependencyGraph {
+	graph := models.NewDependencyGraph()
+	graph.RootPackage = &models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	graph.AddNode(&models.PackageNode{
+		Package:      *graph.RootPackage,
+		Dependencies: map[string]string{"@types/node": "^20.0.0", "evil": "1.0.0"},
+	})
+	graph.AddNode(&models.PackageNode{
+		Package:     models.Package{ID: "@types/node@20.0.0", Name: "@types/node", Version: "20.0.0"},
+		ResolvedURL: "https://registry.npmjs.org/@types/node/-/node-20.0.0.tgz",
+		Integrity:   "sha512-AAECAw==",
+	})
+	graph.AddNode(&models.PackageNode{
+		Package: models.Package{ID: "evil@1.0.0", Name: "evil", Version: "1.0.0"},
+	})
+	return graph
+}
+
+func TestNewCycloneDX(t *testing.T) {
+	verdicts := Verdicts{
+		"@types/node@20.0.0": nil,
+		"evil@1.0.0":         {IsMalicious: true, Confidence: 0.95, Justification: "exfiltrates env", Indicators: []string{"dns: evil.example"}},
+	}
+
+	bom := NewCycloneDX(testGraph(), verdicts)
+
+	assert.Equal(t, "CycloneDX", bom.BOMFormat)
+	assert.Regexp(t, `^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, bom.SerialNumber)
+	require.NotNil(t, bom.Metadata.Component)
+	assert.Equal(t, "app", bom.Metadata.Component.Name)
+
+	require.Len(t, bom.Components, 2)
+	types := bom.Components[0]
+	assert.Equal(t, "@types", types.Group)
+	assert.Equal(t, "node", types.Name)
+	assert.Equal(t, "pkg:npm/%40types/node@20.0.0", types.PURL)
+	assert.Equal(t, []Hash{{Alg: "SHA-512", Content: "00010203"}}, types.Hashes)
+	assert.Equal(t, "https://registry.npmjs.org/@types/node/-/node-20.0.0.tgz", types.ExternalReferences[0].URL)
+	assert.Equal(t, []Property{{Name: "spr:verdict", Value: VerdictClean}}, types.Properties)
+
+	evil := bom.Components[1]
+	assert.Contains(t, evil.Properties, Property{Name: "spr:verdict", Value: VerdictMalicious})
+
+	require.Len(t, bom.Vulnerabilities, 1)
+	assert.Equal(t, "exfiltrates env", bom.Vulnerabilities[0].Description)
+	assert.Equal(t, []Affect{{Ref: "pkg:npm/evil@1.0.0"}}, bom.Vulnerabilities[0].Affects)
+
+	assert.Contains(t, bom.Dependencies, Dependency{
+		Ref:       "pkg:npm/app@1.0.0",
+		DependsOn: []string{"pkg:npm/%40types/node@20.0.0", "pkg:npm/evil@1.0.0"},
+	})
+}
+
+func TestNewCycloneDXNotAnalyzed(t *testing.T) {
+	bom := NewCycloneDX(testGraph(), nil)
+	for _, c := range bom.Components {
+		assert.Equal(t, []Property{{Name: "spr:verdict", Value: VerdictNotAnalyzed}}, c.Properties)
+	}
+	assert.Empty(t, bom.Vulnerabilities)
+}
@@ -0,0 +1,117 @@
+package sbom
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/naming"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// npmRegistryURL is where package metadata is fetched from (overridden in tests)
+var npmRegistryURL = "https://registry.npmjs.org"
+
+var licenseHTTPClient = &http.Client{Timeout: 30 * time.Second}
+
+// FetchLicenses looks up the declared license of every package in the graph
+// from its npm metadata, keyed by name@version. Packages whose metadata can't
+// be fetched or that declare no license are left out.
+func FetchLicenses(ctx context.Context, graph *models.DependencyGraph, concurrency int) map[string]string {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		licenses = make(map[string]string)
+		sem      = make(chan struct{}, concurrency)
+	)
+
+	for id, node := range graph.Nodes {
+		if graph.RootPackage != nil && id == graph.RootPackage.ID {
+			continue
+		}
+
+		wg.Add(1)
+		go func(id string, node *models.PackageNode) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			license, err := fetchLicense(ctx, node.Name, node.Version)
+			if err != nil || license == "" {
+				return
+			}
+			mu.Lock()
+			licenses[id] = license
+			mu.Unlock()
+		}(id, node)
+	}
+
+	wg.Wait()
+	return licenses
+}
+
+// fetchLicense reads the license of one package version from npm
+func fetchLicense(ctx context.Context, name, version string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/%s/%s", npmRegistryURL, naming.URLPath(name), url.PathEscape(version)), nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := licenseHTTPClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to fetch metadata: status %d", resp.StatusCode)
+	}
+
+	var metadata map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return "", fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	return licenseFromMetadata(metadata), nil
+}
+
+// licenseFromMetadata extracts the license from package metadata, handling
+// the legacy object ({"type": "MIT"}) and "licenses" array forms
+func licenseFromMetadata(metadata map[string]any) string {
+	switch license := metadata["license"].(type) {
+	case string:
+		return license
+	case map[string]any:
+		if t, ok := license["type"].(string); ok {
+			return t
+		}
+	}
+
+	if list, ok := metadata["licenses"].([]any); ok {
+		var types []string
+		for _, entry := range list {
+			if obj, ok := entry.(map[string]any); ok {
+				if t, ok := obj["type"].(string); ok {
+					types = append(types, t)
+				}
+			}
+		}
+		if len(types) > 1 {
+			return "(" + strings.Join(types, " OR ") + ")"
+		}
+		if len(types) == 1 {
+			return types[0]
+		}
+	}
+
+	return ""
+}
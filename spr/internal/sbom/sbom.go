@@ -0,0 +1,134 @@
+// Package sbom exports a dependency graph as a software bill of materials,
+// in CycloneDX or SPDX format, annotated with spr's per-package analysis
+// verdicts
+package sbom
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Verdict values recorded on each package
+const (
+	VerdictMalicious   = models.VerdictMalicious
+	VerdictSafe        = models.VerdictSafe
+	VerdictClean       = models.VerdictClean // Analyzed, no behavior beyond the baseline
+	VerdictNotAnalyzed = models.VerdictNotAnalyzed
+	VerdictTrusted     = models.VerdictTrusted
+)
+
+// Verdicts holds analysis results keyed by name@version: a package present
+// with a nil assessment was analyzed and showed nothing beyond the baseline,
+// while absent packages were not analyzed
+type Verdicts map[string]*analysis.SecurityAssessment
+
+// verdict returns the verdict for a package and its assessment, if any.
+// Packages skipped for trusted provenance are annotated as such on the graph.
+func (v Verdicts) verdict(node *models.PackageNode) (string, *analysis.SecurityAssessment) {
+	assessment, analyzed := v[node.ID]
+	switch {
+	case !analyzed && node.StringAnnotation(models.AnnotationVerdict) == VerdictTrusted:
+		return VerdictTrusted, nil
+	case !analyzed:
+		return VerdictNotAnalyzed, nil
+	case assessment == nil:
+		return VerdictClean, nil
+	case assessment.IsMalicious:
+		return VerdictMalicious, assessment
+	default:
+		return VerdictSafe, assessment
+	}
+}
+
+// digest is one hash from a lockfile SRI string
+type digest struct {
+	algo string // SRI algorithm name, e.g. "sha512"
+	hex  string
+}
+
+// sriDigests decodes a lockfile SRI string ("sha512-<base64> ...") into
+// hex digests, skipping malformed entries
+func sriDigests(sri string) []digest {
+	var digests []digest
+	for _, entry := range strings.Fields(sri) {
+		algo, b64, ok := strings.Cut(entry, "-")
+		if !ok {
+			continue
+		}
+		raw, err := base64.StdEncoding.DecodeString(b64)
+		if err != nil {
+			continue
+		}
+		digests = append(digests, digest{algo: algo, hex: hex.EncodeToString(raw)})
+	}
+	return digests
+}
+
+// purl returns the package URL for an npm package, e.g.
+// pkg:npm/%40types/node@20.0.0
+func purl(name, version string) string {
+	if scope, rest, ok := strings.Cut(name, "/"); ok && strings.HasPrefix(scope, "@") {
+		// PathEscape leaves '@' alone but purl requires it encoded
+		name = "%40" + url.PathEscape(scope[1:]) + "/" + url.PathEscape(rest)
+	} else {
+		name = url.PathEscape(name)
+	}
+	return fmt.Sprintf("pkg:npm/%s@%s", name, url.PathEscape(version))
+}
+
+// graphLayout is the graph in a stable order with dependency ranges resolved
+// to node IDs by name, the same way the graph resolves direct dependencies
+type graphLayout struct {
+	ids    []string // Sorted node IDs
+	rootID string
+	deps   map[string][]string // Node ID -> sorted dependency node IDs
+}
+
+func layoutGraph(graph *models.DependencyGraph) *graphLayout {
+	l := &graphLayout{deps: make(map[string][]string)}
+	if graph.RootPackage != nil {
+		l.rootID = graph.RootPackage.ID
+	}
+
+	for id := range graph.Nodes {
+		l.ids = append(l.ids, id)
+	}
+	sort.Strings(l.ids)
+
+	idByName := make(map[string]string)
+	for _, id := range l.ids {
+		if id != l.rootID {
+			idByName[graph.Nodes[id].Name] = id
+		}
+	}
+
+	for _, id := range l.ids {
+		var deps []string
+		for depName := range graph.Nodes[id].Dependencies {
+			if depID, ok := idByName[depName]; ok {
+				deps = append(deps, depID)
+			}
+		}
+		sort.Strings(deps)
+		l.deps[id] = deps
+	}
+
+	return l
+}
+
+// newUUID returns a random RFC 4122 version 4 UUID
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
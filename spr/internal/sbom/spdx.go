@@ -0,0 +1,221 @@
+package sbom
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// SPDXVersion is the SPDX specification version produced
+const SPDXVersion = "SPDX-2.3"
+
+// noAssertion is SPDX's marker for information that wasn't determined
+const noAssertion = "NOASSERTION"
+
+// SPDXDocument is an SPDX 2.3 document in its JSON serialization
+type SPDXDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      SPDXCreationInfo   `json:"creationInfo"`
+	DocumentDescribes []string           `json:"documentDescribes,omitempty"`
+	Packages          []SPDXPackage      `json:"packages"`
+	Relationships     []SPDXRelationship `json:"relationships,omitempty"`
+}
+
+// SPDXCreationInfo records when and by what the document was created
+type SPDXCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+// SPDXPackage is a single package
+type SPDXPackage struct {
+	Name             string           `json:"name"`
+	SPDXID           string           `json:"SPDXID"`
+	VersionInfo      string           `json:"versionInfo,omitempty"`
+	DownloadLocation string           `json:"downloadLocation"`
+	FilesAnalyzed    bool             `json:"filesAnalyzed"`
+	Checksums        []SPDXChecksum   `json:"checksums,omitempty"`
+	LicenseConcluded string           `json:"licenseConcluded"`
+	LicenseDeclared  string           `json:"licenseDeclared"`
+	LicenseComments  string           `json:"licenseComments,omitempty"`
+	CopyrightText    string           `json:"copyrightText"`
+	ExternalRefs     []SPDXExternal   `json:"externalRefs,omitempty"`
+	Annotations      []SPDXAnnotation `json:"annotations,omitempty"`
+}
+
+// SPDXChecksum is a package digest
+type SPDXChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"` // Hex encoded
+}
+
+// SPDXExternal references a package in another system, e.g. by purl
+type SPDXExternal struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+// SPDXAnnotation carries the spr verdict for a package
+type SPDXAnnotation struct {
+	AnnotationType string `json:"annotationType"`
+	Annotator      string `json:"annotator"`
+	AnnotationDate string `json:"annotationDate"`
+	Comment        string `json:"comment"`
+}
+
+// SPDXRelationship links two elements, e.g. a package and its dependency
+type SPDXRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+// spdxAlgorithms maps SRI hash prefixes to SPDX checksum algorithm names
+var spdxAlgorithms = map[string]string{
+	"sha1":   "SHA1",
+	"sha256": "SHA256",
+	"sha384": "SHA384",
+	"sha512": "SHA512",
+}
+
+// spdxIDUnsafe matches characters not allowed in SPDX identifiers
+var spdxIDUnsafe = regexp.MustCompile(`[^A-Za-z0-9.-]+`)
+
+// spdxLicenseExpression loosely matches a valid SPDX license expression such
+// as "MIT" or "(MIT OR Apache-2.0)"; anything else is not asserted
+var spdxLicenseExpression = regexp.MustCompile(`^\(?[A-Za-z0-9.+-]+( +(AND|OR|WITH) +\(?[A-Za-z0-9.+-]+\)?)*\)?$`)
+
+// NewSPDX builds an SPDX 2.3 document from a dependency graph. licenses holds
+// declared licenses keyed by name@version (see FetchLicenses) and may be nil,
+// in which case license annotations on the graph are used; verdicts are
+// recorded as package annotations.
+func NewSPDX(graph *models.DependencyGraph, verdicts Verdicts, licenses map[string]string) *SPDXDocument {
+	now := time.Now().UTC().Format(time.RFC3339)
+
+	name := "spr-sbom"
+	if graph.RootPackage != nil && graph.RootPackage.Name != "" {
+		name = graph.RootPackage.Name
+	}
+
+	doc := &SPDXDocument{
+		SPDXVersion:       SPDXVersion,
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              name,
+		DocumentNamespace: fmt.Sprintf("https://spdx.org/spdxdocs/spr-%s-%s", spdxIDUnsafe.ReplaceAllString(name, "-"), newUUID()),
+		CreationInfo: SPDXCreationInfo{
+			Created:  now,
+			Creators: []string{"Tool: spr-" + version.Get().Version},
+		},
+	}
+
+	layout := layoutGraph(graph)
+	for _, id := range layout.ids {
+		node := graph.Nodes[id]
+		license, ok := licenses[id]
+		if !ok {
+			license = node.StringAnnotation(models.AnnotationLicense)
+		}
+		pkg := newSPDXPackage(node, license)
+
+		if id == layout.rootID {
+			doc.DocumentDescribes = append(doc.DocumentDescribes, pkg.SPDXID)
+			doc.Relationships = append(doc.Relationships, SPDXRelationship{
+				SPDXElementID:      doc.SPDXID,
+				RelationshipType:   "DESCRIBES",
+				RelatedSPDXElement: pkg.SPDXID,
+			})
+		} else {
+			verdict, assessment := verdicts.verdict(node)
+			comment := "spr verdict: " + verdict
+			if assessment != nil {
+				comment += fmt.Sprintf(" (confidence %.2f)", assessment.Confidence)
+				if assessment.Justification != "" {
+					comment += ": " + assessment.Justification
+				}
+			}
+			pkg.Annotations = []SPDXAnnotation{{
+				AnnotationType: "REVIEW",
+				Annotator:      "Tool: spr",
+				AnnotationDate: now,
+				Comment:        comment,
+			}}
+		}
+
+		for _, depID := range layout.deps[id] {
+			doc.Relationships = append(doc.Relationships, SPDXRelationship{
+				SPDXElementID:      pkg.SPDXID,
+				RelationshipType:   "DEPENDS_ON",
+				RelatedSPDXElement: spdxID(depID),
+			})
+		}
+
+		doc.Packages = append(doc.Packages, pkg)
+	}
+
+	return doc
+}
+
+// Write writes the document as indented JSON
+func (d *SPDXDocument) Write(path string) error {
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal SPDX document: %w", err)
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// newSPDXPackage converts a graph node into an SPDX package
+func newSPDXPackage(node *models.PackageNode, license string) SPDXPackage {
+	pkg := SPDXPackage{
+		Name:             node.Name,
+		SPDXID:           spdxID(node.ID),
+		VersionInfo:      node.Version,
+		DownloadLocation: noAssertion,
+		LicenseConcluded: noAssertion,
+		LicenseDeclared:  noAssertion,
+		CopyrightText:    noAssertion,
+		ExternalRefs: []SPDXExternal{{
+			ReferenceCategory: "PACKAGE-MANAGER",
+			ReferenceType:     "purl",
+			ReferenceLocator:  purl(node.Name, node.Version),
+		}},
+	}
+
+	if node.ResolvedURL != "" {
+		pkg.DownloadLocation = node.ResolvedURL
+	}
+
+	for _, d := range sriDigests(node.Integrity) {
+		if alg, ok := spdxAlgorithms[d.algo]; ok {
+			pkg.Checksums = append(pkg.Checksums, SPDXChecksum{Algorithm: alg, ChecksumValue: d.hex})
+		}
+	}
+
+	if license != "" {
+		if spdxLicenseExpression.MatchString(license) {
+			pkg.LicenseDeclared = license
+		} else {
+			// e.g. "SEE LICENSE IN LICENSE.md"
+			pkg.LicenseComments = "Declared in package.json as: " + license
+		}
+	}
+
+	return pkg
+}
+
+// spdxID derives a valid SPDX element ID from a node ID (name@version)
+func spdxID(id string) string {
+	return "SPDXRef-Package-" + strings.Trim(spdxIDUnsafe.ReplaceAllString(id, "-"), "-")
+}
@@ -0,0 +1,84 @@
+package sbom
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewSPDX(t *testing.T) {
+	verdicts := Verdicts{
+		"evil@1.0.0": {IsMalicious: true, Confidence: 0.95, Justification: "exfiltrates env"},
+	}
+	licenses := map[string]string{
+		"@types/node@20.0.0": "MIT",
+		"evil@1.0.0":         "SEE LICENSE IN LICENSE.md",
+	}
+
+	doc := NewSPDX(testGraph(), verdicts, licenses)
+
+	assert.Equal(t, "SPDX-2.3", doc.SPDXVersion)
+	assert.Equal(t, []string{"SPDXRef-Package-app-1.0.0"}, doc.DocumentDescribes)
+	require.Len(t, doc.Packages, 3)
+
+	types := doc.Packages[0]
+	assert.Equal(t, "SPDXRef-Package-types-node-20.0.0", types.SPDXID)
+	assert.Equal(t, "MIT", types.LicenseDeclared)
+	assert.Equal(t, []SPDXChecksum{{Algorithm: "SHA512", ChecksumValue: "00010203"}}, types.Checksums)
+	assert.Equal(t, "https://registry.npmjs.org/@types/node/-/node-20.0.0.tgz", types.DownloadLocation)
+	assert.Equal(t, "spr verdict: not-analyzed", types.Annotations[0].Comment)
+
+	evil := doc.Packages[2]
+	assert.Equal(t, noAssertion, evil.LicenseDeclared)
+	assert.Contains(t, evil.LicenseComments, "SEE LICENSE IN")
+	assert.Equal(t, noAssertion, evil.DownloadLocation)
+	assert.Equal(t, "spr verdict: malicious (confidence 0.95): exfiltrates env", evil.Annotations[0].Comment)
+
+	assert.Contains(t, doc.Relationships, SPDXRelationship{
+		SPDXElementID:      "SPDXRef-Package-app-1.0.0",
+		RelationshipType:   "DEPENDS_ON",
+		RelatedSPDXElement: "SPDXRef-Package-evil-1.0.0",
+	})
+}
+
+func TestNewSPDXLicenseAnnotations(t *testing.T) {
+	graph := testGraph()
+	graph.Annotate("@types/node@20.0.0", models.AnnotationLicense, "MIT")
+
+	doc := NewSPDX(graph, nil, nil)
+	require.Len(t, doc.Packages, 3)
+	assert.Equal(t, "MIT", doc.Packages[0].LicenseDeclared)
+	assert.Equal(t, noAssertion, doc.Packages[2].LicenseDeclared)
+}
+
+func TestLicenseFromMetadata(t *testing.T) {
+	assert.Equal(t, "MIT", licenseFromMetadata(map[string]any{"license": "MIT"}))
+	assert.Equal(t, "ISC", licenseFromMetadata(map[string]any{"license": map[string]any{"type": "ISC"}}))
+	assert.Equal(t, "(MIT OR Apache-2.0)", licenseFromMetadata(map[string]any{
+		"licenses": []any{map[string]any{"type": "MIT"}, map[string]any{"type": "Apache-2.0"}},
+	}))
+	assert.Empty(t, licenseFromMetadata(map[string]any{}))
+}
+
+func TestFetchLicenses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/@types%2fnode/20.0.0":
+			w.Write([]byte(`{"license":"MIT"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	old := npmRegistryURL
+	npmRegistryURL = srv.URL
+	defer func() { npmRegistryURL = old }()
+
+	licenses := FetchLicenses(t.Context(), testGraph(), 2)
+	assert.Equal(t, map[string]string{"@types/node@20.0.0": "MIT"}, licenses)
+}
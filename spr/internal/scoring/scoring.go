@@ -0,0 +1,137 @@
+// Package scoring combines what the pipeline found out about a package, its
+// behavioral diff, static scan, AI verdict, vulnerabilities and registry
+// metadata, into one 0-100 risk score, so CI gates can fail on a single
+// threshold instead of weighing every finding themselves.
+//
+// Each signal is turned into a risk between 0 and 1, and the risks combine
+// as independent evidence, 1 - (1-r1)(1-r2)..., so one strong signal is
+// enough for a high score and several weak ones add up without reaching it.
+// A malicious verdict always scores 100. Trusted provenance halves the score
+// of a package that wasn't flagged.
+package scoring
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Signals a score is made of, named in Factor.Signal
+const (
+	SignalVerdict    = "verdict"
+	SignalBehavior   = "behavior" // One factor per risk flag of the diff
+	SignalStatic     = "static"
+	SignalVulns      = "vulns"
+	SignalTyposquat  = "typosquat"
+	SignalConfusion  = "confusion"
+	SignalAge        = "age"
+	SignalProvenance = "provenance"
+)
+
+// Signals is what is known about a package. Zero values mean nothing was
+// found, except an empty Verdict, which means the package wasn't analyzed.
+type Signals struct {
+	Verdict    string   // One of the models.Verdict* values
+	Confidence float64  // AI confidence in Verdict, 0-1
+	RiskFlags  []string // Behavioral risk flags of the diff, see aggregate.FlagRisks
+	StaticRisk float64  // Static scan score, 0-1, see static.Report
+	Vulns      []string // Advisory or vulnerability IDs
+	Typosquat  string   // Popular package the name imitates
+	Confusion  string   // Kind of dependency confusion finding
+	AgeGate    string   // Age policy action applied to a too-new version
+	Provenance string   // Trusted publisher the tarball was built by
+}
+
+// Factor is one signal's part in a score
+type Factor struct {
+	Signal string  `json:"signal"`
+	Risk   float64 `json:"risk"` // 0-1 before combining; 0 for provenance, which scales the rest
+	Detail string  `json:"detail,omitempty"`
+}
+
+// Score is the combined risk of a package
+type Score struct {
+	Score   int      `json:"score"`             // 0 (nothing found) to 100
+	Factors []Factor `json:"factors,omitempty"` // Riskiest first
+}
+
+// Risks of the behavioral risk flags; flags missing here weigh
+// defaultFlagRisk
+var flagRisks = map[string]float64{
+	aggregate.RiskReverseShell:        0.8,
+	aggregate.RiskCryptoMiner:         0.7,
+	aggregate.RiskSensitiveFileWrite:  0.5,
+	aggregate.RiskEnvSecretAccess:     0.4,
+	aggregate.RiskSensitiveFileAccess: 0.35,
+	aggregate.RiskShellSpawned:        0.15,
+	aggregate.RiskNetworkActivity:     0.15,
+	aggregate.RiskProcfsAccess:        0.1,
+}
+
+const (
+	defaultFlagRisk  = 0.1
+	notAnalyzedRisk  = 0.15 // Unknown behavior isn't no risk
+	safeDoubtRisk    = 0.3  // Risk of a safe verdict made with no confidence
+	maliciousRisk    = 1    // Decisive whatever the confidence, so every risk gate fails it
+	staticWeight     = 0.8  // The static scan flags, the behavior proves
+	vulnsRisk        = 0.9
+	typosquatRisk    = 0.5
+	confusionRisk    = 0.6
+	ageGateRisk      = 0.2
+	provenanceFactor = 0.5
+)
+
+// Compute scores a package
+func Compute(s Signals) Score {
+	var factors []Factor
+	add := func(signal string, risk float64, detail string) {
+		if risk > 0 {
+			factors = append(factors, Factor{Signal: signal, Risk: math.Min(risk, 1), Detail: detail})
+		}
+	}
+
+	switch s.Verdict {
+	case models.VerdictMalicious:
+		add(SignalVerdict, maliciousRisk, fmt.Sprintf("malicious (confidence %.2f)", s.Confidence))
+	case models.VerdictSafe:
+		add(SignalVerdict, safeDoubtRisk*(1-s.Confidence), fmt.Sprintf("safe (confidence %.2f)", s.Confidence))
+	case "", models.VerdictNotAnalyzed:
+		add(SignalVerdict, notAnalyzedRisk, "not analyzed")
+	}
+	for _, flag := range s.RiskFlags {
+		risk, ok := flagRisks[flag]
+		if !ok {
+			risk = defaultFlagRisk
+		}
+		add(SignalBehavior, risk, flag)
+	}
+	add(SignalStatic, staticWeight*s.StaticRisk, fmt.Sprintf("static risk %.2f", s.StaticRisk))
+	if len(s.Vulns) > 0 {
+		add(SignalVulns, vulnsRisk, strings.Join(s.Vulns, ", "))
+	}
+	if s.Typosquat != "" {
+		add(SignalTyposquat, typosquatRisk, "imitates "+s.Typosquat)
+	}
+	if s.Confusion != "" {
+		add(SignalConfusion, confusionRisk, s.Confusion)
+	}
+	if s.AgeGate != "" {
+		add(SignalAge, ageGateRisk, "too new: "+s.AgeGate)
+	}
+
+	none := 1.0
+	for _, f := range factors {
+		none *= 1 - f.Risk
+	}
+	risk := 1 - none
+	sort.SliceStable(factors, func(i, j int) bool { return factors[i].Risk > factors[j].Risk })
+	if s.Provenance != "" && s.Verdict != models.VerdictMalicious {
+		risk *= provenanceFactor
+		factors = append(factors, Factor{Signal: SignalProvenance, Detail: "built by " + s.Provenance + ", score halved"})
+	}
+	return Score{Score: int(math.Round(risk * 100)), Factors: factors}
+}
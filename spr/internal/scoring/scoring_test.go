@@ -0,0 +1,49 @@
+package scoring
+
+import (
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompute(t *testing.T) {
+	assert.Equal(t, 0, Compute(Signals{Verdict: models.VerdictClean}).Score)
+	assert.Equal(t, 15, Compute(Signals{}).Score, "not analyzed isn't no risk")
+	assert.Equal(t, 3, Compute(Signals{Verdict: models.VerdictSafe, Confidence: 0.9}).Score)
+
+	malicious := Compute(Signals{Verdict: models.VerdictMalicious, Confidence: 0.95, RiskFlags: []string{aggregate.RiskEnvSecretAccess}})
+	assert.Equal(t, 100, malicious.Score)
+	require.Len(t, malicious.Factors, 2)
+	assert.Equal(t, SignalVerdict, malicious.Factors[0].Signal, "riskiest first")
+
+	// Weak signals add up without one alone deciding
+	weak := Signals{Verdict: models.VerdictClean, StaticRisk: 0.25, AgeGate: "warn"}
+	assert.Equal(t, 36, Compute(weak).Score)
+	weak.RiskFlags = []string{aggregate.RiskNetworkActivity, "new_flag"}
+	assert.Equal(t, 51, Compute(weak).Score)
+}
+
+func TestComputeProvenance(t *testing.T) {
+	signals := Signals{Verdict: models.VerdictClean, Typosquat: "lodash", Provenance: "github.com/acme/lib"}
+	score := Compute(signals)
+	assert.Equal(t, 25, score.Score)
+	assert.Equal(t, SignalProvenance, score.Factors[len(score.Factors)-1].Signal)
+
+	// Provenance doesn't vouch for a package caught misbehaving
+	signals.Verdict, signals.Confidence = models.VerdictMalicious, 0.9
+	assert.Equal(t, 100, Compute(signals).Score)
+}
+
+func TestComputeMaliciousFailsGate(t *testing.T) {
+	// -max-risk fails packages scoring above it, 1-99 being the gates that
+	// can fail anything
+	for _, confidence := range []float64{0, 0.1, 0.5, 0.95} {
+		score := Compute(Signals{Verdict: models.VerdictMalicious, Confidence: confidence, Provenance: "github.com/acme/lib"})
+		for maxRisk := 1; maxRisk < 100; maxRisk++ {
+			require.Greater(t, score.Score, maxRisk, "confidence %.2f passes -max-risk %d", confidence, maxRisk)
+		}
+	}
+}
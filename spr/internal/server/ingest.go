@@ -0,0 +1,212 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// IngestRoute is where self-hosted runners upload a job's behavior.jsonl
+const IngestRoute = "/api/jobs/{id}/artifacts"
+
+// IngestedTraceFile is the name an uploaded trace is stored under, in a
+// directory per job
+const IngestedTraceFile = "behavior.jsonl"
+
+// maxIngestChunk bounds the size of a single uploaded chunk
+const maxIngestChunk = 64 << 20
+
+// jobIDPattern limits job IDs to names that are safe as a directory
+var jobIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// ingestStatus is the response to every chunk: the bytes received so far
+// and whether the upload is complete
+type ingestStatus struct {
+	Received int64 `json:"received"`
+	Complete bool  `json:"complete"`
+}
+
+// ArtifactIngestHandler accepts behavior.jsonl uploads from runners that
+// don't share a filesystem with the server, authenticated with a bearer
+// token. A trace is uploaded as one or more POSTs to IngestRoute, each
+// appending a chunk at ?offset= (the bytes received so far; bodies may use
+// chunked transfer encoding). The last one sets ?complete=true, which moves
+// the trace to <dir>/<job id>/behavior.jsonl. A chunk at the wrong offset
+// gets a 409 with the bytes received, so an interrupted upload can resume.
+func ArtifactIngestHandler(token, dir string) http.Handler {
+	locks := newJobLocks()
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		bearer, _ := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		id := r.PathValue("id")
+		if !jobIDPattern.MatchString(id) {
+			http.Error(w, "invalid job id", http.StatusBadRequest)
+			return
+		}
+		var offset int64
+		if v := r.URL.Query().Get("offset"); v != "" {
+			n, err := strconv.ParseInt(v, 10, 64)
+			if err != nil || n < 0 {
+				http.Error(w, "invalid offset", http.StatusBadRequest)
+				return
+			}
+			offset = n
+		}
+		complete := r.URL.Query().Get("complete") == "true"
+
+		unlock := locks.lock(id)
+		defer unlock()
+
+		status, err := appendChunk(filepath.Join(dir, id), offset, http.MaxBytesReader(w, r.Body, maxIngestChunk), complete)
+		var mismatch *offsetMismatchError
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, "chunk too large", http.StatusRequestEntityTooLarge)
+			return
+		case errors.As(err, &mismatch):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusConflict)
+			json.NewEncoder(w).Encode(ingestStatus{Received: mismatch.received, Complete: mismatch.complete})
+			return
+		case err != nil:
+			slog.Error("Artifact ingest failed", "job_id", id, "error", err)
+			http.Error(w, "failed to store chunk", http.StatusInternalServerError)
+			return
+		}
+		if status.Complete {
+			slog.Info("Artifact ingested", "job_id", id, "bytes", status.Received)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+// SetIngestDir sets where ArtifactIngestHandler stores uploaded traces, so
+// the orchestrator collects a run's trace with its artifacts
+func (p *Pipeline) SetIngestDir(dir string) {
+	p.ingestDir = dir
+}
+
+// jobLocks serializes the chunks of each job's upload. An entry lives only
+// while a request for the job holds or waits for it, so finished jobs don't
+// accumulate.
+type jobLocks struct {
+	mu   sync.Mutex
+	jobs map[string]*jobLock
+}
+
+type jobLock struct {
+	sync.Mutex
+	refs int // Requests holding or waiting for the lock
+}
+
+func newJobLocks() *jobLocks {
+	return &jobLocks{jobs: make(map[string]*jobLock)}
+}
+
+// lock locks the job and returns the function unlocking it
+func (l *jobLocks) lock(id string) func() {
+	l.mu.Lock()
+	m, ok := l.jobs[id]
+	if !ok {
+		m = &jobLock{}
+		l.jobs[id] = m
+	}
+	m.refs++
+	l.mu.Unlock()
+
+	m.Lock()
+	return func() {
+		m.Unlock()
+		l.mu.Lock()
+		if m.refs--; m.refs == 0 {
+			delete(l.jobs, id)
+		}
+		l.mu.Unlock()
+	}
+}
+
+// len returns the number of jobs with an entry
+func (l *jobLocks) len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.jobs)
+}
+
+// offsetMismatchError is returned for a chunk that doesn't start where the
+// previous one ended, or that arrives after the upload completed
+type offsetMismatchError struct {
+	received int64
+	complete bool
+}
+
+func (e *offsetMismatchError) Error() string {
+	return fmt.Sprintf("expected offset %d", e.received)
+}
+
+// appendChunk writes a chunk at offset to the job's partial trace in jobDir,
+// and moves it into place when complete is set. Completed traces are
+// immutable.
+func appendChunk(jobDir string, offset int64, body io.Reader, complete bool) (ingestStatus, error) {
+	final := filepath.Join(jobDir, IngestedTraceFile)
+	if info, err := os.Stat(final); err == nil {
+		return ingestStatus{}, &offsetMismatchError{received: info.Size(), complete: true}
+	}
+	if err := os.MkdirAll(jobDir, 0755); err != nil {
+		return ingestStatus{}, fmt.Errorf("failed to create job directory: %w", err)
+	}
+
+	partial := final + ".part"
+	f, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return ingestStatus{}, fmt.Errorf("failed to open trace: %w", err)
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return ingestStatus{}, fmt.Errorf("failed to stat trace: %w", err)
+	}
+	if info.Size() != offset {
+		return ingestStatus{}, &offsetMismatchError{received: info.Size()}
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return ingestStatus{}, fmt.Errorf("failed to seek trace: %w", err)
+	}
+	n, err := io.Copy(f, body)
+	if err != nil {
+		// Drop the partial chunk so the runner can resend it from offset
+		f.Truncate(offset)
+		return ingestStatus{}, fmt.Errorf("failed to write chunk: %w", err)
+	}
+	received := offset + n
+	if !complete {
+		return ingestStatus{Received: received}, nil
+	}
+
+	if err := f.Close(); err != nil {
+		return ingestStatus{}, fmt.Errorf("failed to close trace: %w", err)
+	}
+	if err := os.Rename(partial, final); err != nil {
+		return ingestStatus{}, fmt.Errorf("failed to finalize trace: %w", err)
+	}
+	return ingestStatus{Received: received, Complete: true}, nil
+}
@@ -0,0 +1,70 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArtifactIngestHandler(t *testing.T) {
+	dir := t.TempDir()
+	mux := http.NewServeMux()
+	mux.Handle(IngestRoute, ArtifactIngestHandler("token", dir))
+
+	upload := func(id, query, token, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/jobs/"+id+"/artifacts"+query, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusUnauthorized, upload("job-1", "", "wrong", "{}\n").Code)
+	assert.Equal(t, http.StatusBadRequest, upload(".hidden", "", "token", "{}\n").Code)
+
+	rec := upload("job-1", "?offset=0", "token", "{\"a\":1}\n")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"received":8,"complete":false}`, rec.Body.String())
+
+	// A resent or skipped chunk reports where to resume
+	rec = upload("job-1", "?offset=0", "token", "{\"a\":1}\n")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.JSONEq(t, `{"received":8,"complete":false}`, rec.Body.String())
+
+	rec = upload("job-1", "?offset=8&complete=true", "token", "{\"b\":2}\n")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{"received":16,"complete":true}`, rec.Body.String())
+
+	data, err := os.ReadFile(filepath.Join(dir, "job-1", IngestedTraceFile))
+	require.NoError(t, err)
+	assert.Equal(t, "{\"a\":1}\n{\"b\":2}\n", string(data))
+	assert.NoFileExists(t, filepath.Join(dir, "job-1", IngestedTraceFile+".part"))
+
+	// Completed traces can't be appended to
+	assert.Equal(t, http.StatusConflict, upload("job-1", "?offset=16", "token", "{}\n").Code)
+}
+
+func TestJobLocksEvicted(t *testing.T) {
+	locks := newJobLocks()
+	unlock := locks.lock("job-1")
+	assert.Equal(t, 1, locks.len())
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		locks.lock("job-1")()
+	}()
+	// Evicted once the last holder or waiter is done
+	unlock()
+	<-done
+	assert.Equal(t, 0, locks.len())
+
+	locks.lock("job-2")()
+	assert.Equal(t, 0, locks.len())
+}
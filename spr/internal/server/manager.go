@@ -0,0 +1,156 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+var (
+	// ErrTooManyAnalyses is returned when a client or the server as a whole is
+	// already running the maximum number of concurrent analyses
+	ErrTooManyAnalyses = errors.New("too many concurrent analyses")
+	// ErrDuplicateAnalysisID is returned when a client reuses one of its own
+	// in-flight analysis IDs
+	ErrDuplicateAnalysisID = errors.New("analysis ID already in use")
+)
+
+// AnalysisManager tracks in-flight analyses across all connections and
+// enforces per-client and server-wide concurrency limits
+type AnalysisManager struct {
+	mu           sync.Mutex
+	maxPerClient int
+	maxTotal     int
+	analyses     map[analysisKey]*managedAnalysis
+}
+
+// analysisKey scopes analysis IDs to the connection that started them. IDs
+// are chosen by clients, so two connections may pick the same one and must
+// not be able to see or cancel each other's analyses through it.
+type analysisKey struct {
+	owner any // Connection that started it
+	id    string
+}
+
+type managedAnalysis struct {
+	cancel context.CancelFunc
+}
+
+// NewAnalysisManager creates a manager. A limit of zero or less means unlimited.
+func NewAnalysisManager(maxPerClient, maxTotal int) *AnalysisManager {
+	return &AnalysisManager{
+		maxPerClient: maxPerClient,
+		maxTotal:     maxTotal,
+		analyses:     make(map[analysisKey]*managedAnalysis),
+	}
+}
+
+// Start registers a new analysis for owner and returns its context. The
+// caller must call Finish with the same owner and ID once the analysis returns.
+func (m *AnalysisManager) Start(owner any, id string) (context.Context, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := analysisKey{owner: owner, id: id}
+	if _, exists := m.analyses[key]; exists {
+		return nil, ErrDuplicateAnalysisID
+	}
+	if m.maxTotal > 0 && len(m.analyses) >= m.maxTotal {
+		return nil, fmt.Errorf("%w: server limit of %d reached", ErrTooManyAnalyses, m.maxTotal)
+	}
+	if m.maxPerClient > 0 && m.countLocked(owner) >= m.maxPerClient {
+		return nil, fmt.Errorf("%w: limit of %d per connection reached", ErrTooManyAnalyses, m.maxPerClient)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m.analyses[key] = &managedAnalysis{cancel: cancel}
+	return ctx, nil
+}
+
+// Finish releases an analysis slot
+func (m *AnalysisManager) Finish(owner any, id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	key := analysisKey{owner: owner, id: id}
+	if a, ok := m.analyses[key]; ok {
+		a.cancel()
+		delete(m.analyses, key)
+	}
+}
+
+// Cancel cancels one analysis belonging to owner, reporting whether it existed
+func (m *AnalysisManager) Cancel(owner any, id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	a, ok := m.analyses[analysisKey{owner: owner, id: id}]
+	if !ok {
+		return false
+	}
+	a.cancel()
+	return true
+}
+
+// CancelAll cancels every analysis belonging to owner and returns how many
+// were cancelled. Used when a connection closes or sends a bare cancel.
+func (m *AnalysisManager) CancelAll(owner any) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	n := 0
+	for key, a := range m.analyses {
+		if key.owner == owner {
+			a.cancel()
+			n++
+		}
+	}
+	return n
+}
+
+// Count returns the number of in-flight analyses belonging to owner
+func (m *AnalysisManager) Count(owner any) int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.countLocked(owner)
+}
+
+func (m *AnalysisManager) countLocked(owner any) int {
+	n := 0
+	for key := range m.analyses {
+		if key.owner == owner {
+			n++
+		}
+	}
+	return n
+}
+
+// analysisSender tags every message with an analysis ID so clients running
+// several analyses over one connection can demultiplex them
+type analysisSender struct {
+	ProgressSender
+	id string
+}
+
+// WithAnalysisID wraps sender so all messages carry the given analysis ID
+func WithAnalysisID(sender ProgressSender, id string) ProgressSender {
+	return &analysisSender{ProgressSender: sender, id: id}
+}
+
+func (s *analysisSender) SendMessage(msg Message) {
+	msg.AnalysisID = s.id
+	s.ProgressSender.SendMessage(msg)
+}
+
+func (s *analysisSender) SendLog(message, level string) {
+	s.SendMessage(NewLogMessage(message, level))
+}
+
+func (s *analysisSender) SendProgress(percent int, stage, message string) {
+	s.SendMessage(NewProgressMessage(percent, stage, message))
+}
+
+func (s *analysisSender) SendError(message string, err error) {
+	s.SendMessage(NewErrorMessage(message, err))
+}
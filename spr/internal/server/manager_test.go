@@ -0,0 +1,75 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisManagerLimits(t *testing.T) {
+	m := NewAnalysisManager(2, 3)
+	alice, bob := "alice", "bob"
+
+	_, err := m.Start(alice, "a1")
+	require.NoError(t, err)
+	_, err = m.Start(alice, "a1")
+	assert.ErrorIs(t, err, ErrDuplicateAnalysisID)
+	_, err = m.Start(alice, "a2")
+	require.NoError(t, err)
+	_, err = m.Start(alice, "a3")
+	assert.ErrorIs(t, err, ErrTooManyAnalyses, "per-client limit")
+
+	_, err = m.Start(bob, "b1")
+	require.NoError(t, err)
+	_, err = m.Start(bob, "b2")
+	assert.ErrorIs(t, err, ErrTooManyAnalyses, "server-wide limit")
+
+	m.Finish(alice, "a1")
+	_, err = m.Start(bob, "b2")
+	require.NoError(t, err)
+	assert.Equal(t, 2, m.Count(bob))
+}
+
+func TestAnalysisManagerCancel(t *testing.T) {
+	m := NewAnalysisManager(0, 0)
+	alice, bob := "alice", "bob"
+
+	ctx1, err := m.Start(alice, "a1")
+	require.NoError(t, err)
+	ctx2, err := m.Start(alice, "a2")
+	require.NoError(t, err)
+	ctx3, err := m.Start(bob, "b1")
+	require.NoError(t, err)
+
+	// Can't cancel another connection's analysis
+	assert.False(t, m.Cancel(bob, "a1"))
+	assert.NoError(t, ctx1.Err())
+
+	assert.True(t, m.Cancel(alice, "a1"))
+	assert.Error(t, ctx1.Err())
+	assert.NoError(t, ctx2.Err())
+
+	assert.Equal(t, 2, m.CancelAll(alice))
+	assert.Error(t, ctx2.Err())
+	assert.NoError(t, ctx3.Err())
+}
+
+func TestAnalysisManagerScopesIDs(t *testing.T) {
+	m := NewAnalysisManager(0, 0)
+	alice, bob := "alice", "bob"
+
+	// IDs are client-chosen, so another connection may reuse one
+	ctxA, err := m.Start(alice, "job")
+	require.NoError(t, err)
+	ctxB, err := m.Start(bob, "job")
+	require.NoError(t, err)
+
+	assert.True(t, m.Cancel(bob, "job"))
+	assert.Error(t, ctxB.Err())
+	assert.NoError(t, ctxA.Err(), "cancelling bob's job leaves alice's running")
+
+	m.Finish(bob, "job")
+	assert.Equal(t, 1, m.Count(alice))
+	assert.Equal(t, 0, m.Count(bob))
+}
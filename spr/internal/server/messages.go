@@ -6,6 +6,7 @@ import (
 
 	"github.com/acheong08/hackeurope-spr/internal/aggregate"
 	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/static"
 	"github.com/acheong08/hackeurope-spr/pkg/models"
 )
 
@@ -15,15 +16,20 @@ type MessageType string
 const (
 	// Client -> Server
 	TypeAnalyze MessageType = "analyze" // Client sends package.json to analyze
+	TypeCancel  MessageType = "cancel"  // Client aborts an in-flight analysis (or all of them)
 	TypePing    MessageType = "ping"    // Keep-alive
 
 	// Server -> Client
+	TypeStarted               MessageType = "started"                 // Analysis accepted; carries its analysis_id
 	TypeDAG                   MessageType = "dag"                     // Dependency graph data
 	TypeProgress              MessageType = "progress"                // Progress updates
 	TypeLog                   MessageType = "log"                     // Log messages for terminal
 	TypePackageStatus         MessageType = "package_status"          // Individual package status update
 	TypePackageBehavioralData MessageType = "package_behavioral_data" // Per-package deduped diff data
 	TypePackageAnalysis       MessageType = "package_analysis"        // Per-package AI security assessment
+	TypePackageAnalysisStream MessageType = "package_analysis_stream" // AI reasoning, text and tool calls as they are generated
+	TypePackageAnnotations    MessageType = "package_annotations"     // Per-package graph annotations (verdict, license, ...)
+	TypePackageInstallScripts MessageType = "package_install_scripts" // Per-package scripts npm runs at install time
 	TypeComplete              MessageType = "complete"                // Analysis complete
 	TypeError                 MessageType = "error"                   // Error message
 )
@@ -32,6 +38,10 @@ const (
 type Message struct {
 	Type    MessageType     `json:"type"`
 	Payload json.RawMessage `json:"payload"`
+	// AnalysisID identifies which of a connection's concurrent analyses a
+	// message belongs to. Optional on analyze (server assigns one if empty);
+	// on cancel, empty cancels all of the connection's analyses.
+	AnalysisID string `json:"analysis_id,omitempty"`
 }
 
 // AnalyzePayload sent by client to start analysis
@@ -43,6 +53,7 @@ type AnalyzePayload struct {
 type DAGPayload struct {
 	RootPackage *models.Package       `json:"root_package"`
 	Nodes       []*models.PackageNode `json:"nodes"`
+	Edges       []models.Edge         `json:"edges"` // Dependencies resolved to node IDs, see DependencyGraph.Edges
 	EdgeCount   int                   `json:"edge_count"`
 }
 
@@ -64,14 +75,15 @@ type PackageStatusPayload struct {
 	PackageID string `json:"package_id"` // "name@version"
 	Name      string `json:"name"`
 	Version   string `json:"version"`
-	Status    string `json:"status"`   // "pending", "uploading", "analyzing", "complete", "failed"
+	Status    string `json:"status"`   // "pending", "uploading", "analyzing", "complete", "failed", "cancelled"
 	Progress  int    `json:"progress"` // 0-100 for this package
 }
 
 // CompletePayload sent when analysis is done
 type CompletePayload struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message"`
+	Cancelled bool   `json:"cancelled,omitempty"` // Aborted by a cancel message; results sent so far are partial
 }
 
 // ErrorPayload for error messages
@@ -82,11 +94,21 @@ type ErrorPayload struct {
 
 // Helper functions to create messages
 
-func NewDAGMessage(root *models.Package, nodes []*models.PackageNode, edgeCount int) Message {
+// NewStartedMessage acknowledges an analyze request with the analysis ID that
+// all of its subsequent messages will carry
+func NewStartedMessage(analysisID string) Message {
+	return Message{Type: TypeStarted, Payload: json.RawMessage("{}"), AnalysisID: analysisID}
+}
+
+func NewDAGMessage(root *models.Package, nodes []*models.PackageNode, edges []models.Edge) Message {
+	if edges == nil {
+		edges = []models.Edge{}
+	}
 	payload := DAGPayload{
 		RootPackage: root,
 		Nodes:       nodes,
-		EdgeCount:   edgeCount,
+		Edges:       edges,
+		EdgeCount:   len(edges),
 	}
 	payloadBytes, _ := json.Marshal(payload)
 	return Message{Type: TypeDAG, Payload: payloadBytes}
@@ -132,6 +154,17 @@ func NewCompleteMessage(success bool, message string) Message {
 	return Message{Type: TypeComplete, Payload: payloadBytes}
 }
 
+// NewCancelledMessage signals that the analysis was aborted by the client
+func NewCancelledMessage(message string) Message {
+	payload := CompletePayload{
+		Success:   false,
+		Message:   message,
+		Cancelled: true,
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	return Message{Type: TypeComplete, Payload: payloadBytes}
+}
+
 func NewErrorMessage(message string, err error) Message {
 	errMsg := message
 	if err != nil {
@@ -167,6 +200,17 @@ type PackageAnalysisPayload struct {
 	Assessment *analysis.SecurityAssessment `json:"assessment"`
 }
 
+// PackageAnalysisStreamPayload carries a piece of a package's AI analysis in
+// progress: reasoning or response text (usually a line), or a tool call
+type PackageAnalysisStreamPayload struct {
+	PackageID string `json:"package_id"`
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"` // "reasoning", "text" or "tool_call"
+	Text      string `json:"text"` // Tool input JSON for tool calls
+	Tool      string `json:"tool,omitempty"`
+}
+
 func NewPackageBehavioralDataMessage(pkgID, name, version string, data *aggregate.DedupedProcessStats) Message {
 	payload := PackageBehavioralDataPayload{
 		PackageID: pkgID,
@@ -178,6 +222,59 @@ func NewPackageBehavioralDataMessage(pkgID, name, version string, data *aggregat
 	return Message{Type: TypePackageBehavioralData, Payload: payloadBytes}
 }
 
+// PackageAnnotationsPayload carries the findings pipeline stages attached to
+// a package's graph node
+type PackageAnnotationsPayload struct {
+	PackageID   string         `json:"package_id"`
+	Name        string         `json:"name"`
+	Version     string         `json:"version"`
+	Annotations map[string]any `json:"annotations"`
+}
+
+func NewPackageAnnotationsMessage(node *models.PackageNode) Message {
+	payload := PackageAnnotationsPayload{
+		PackageID:   node.ID,
+		Name:        node.Name,
+		Version:     node.Version,
+		Annotations: node.Annotations,
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	return Message{Type: TypePackageAnnotations, Payload: payloadBytes}
+}
+
+// PackageInstallScriptsPayload carries the scripts npm runs when installing
+// a package, with the content of the files they run
+type PackageInstallScriptsPayload struct {
+	PackageID string                 `json:"package_id"`
+	Name      string                 `json:"name"`
+	Version   string                 `json:"version"`
+	Scripts   []static.InstallScript `json:"scripts"`
+}
+
+func NewPackageInstallScriptsMessage(pkgID, name, version string, scripts []static.InstallScript) Message {
+	payload := PackageInstallScriptsPayload{
+		PackageID: pkgID,
+		Name:      name,
+		Version:   version,
+		Scripts:   scripts,
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	return Message{Type: TypePackageInstallScripts, Payload: payloadBytes}
+}
+
+func NewPackageAnalysisStreamMessage(event analysis.StreamEvent) Message {
+	payload := PackageAnalysisStreamPayload{
+		PackageID: event.Name + "@" + event.Version,
+		Name:      event.Name,
+		Version:   event.Version,
+		Kind:      event.Kind,
+		Text:      event.Text,
+		Tool:      event.Tool,
+	}
+	payloadBytes, _ := json.Marshal(payload)
+	return Message{Type: TypePackageAnalysisStream, Payload: payloadBytes}
+}
+
 func NewPackageAnalysisMessage(pkgID, name, version string, assessment *analysis.SecurityAssessment) Message {
 	payload := PackageAnalysisPayload{
 		PackageID:  pkgID,
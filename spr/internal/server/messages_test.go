@@ -0,0 +1,52 @@
+package server
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDAGMessageEdges(t *testing.T) {
+	graph := models.NewDependencyGraph()
+	graph.RootPackage = &models.Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	for _, node := range []*models.PackageNode{
+		{Package: *graph.RootPackage, Dependencies: map[string]string{"a": "^1.0.0", "b": "2.0.0", "missing": "1.0.0"}},
+		{Package: models.Package{ID: "a@1.2.0", Name: "a", Version: "1.2.0"}, Dependencies: map[string]string{"b": "^1.0.0"}},
+		{Package: models.Package{ID: "b@1.0.0", Name: "b", Version: "1.0.0"}},
+		{Package: models.Package{ID: "b@2.0.0", Name: "b", Version: "2.0.0"}},
+	} {
+		graph.AddNode(node)
+	}
+
+	msg := NewDAGMessage(graph.RootPackage, nil, graph.Edges())
+	var payload DAGPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+
+	// Exact versions resolve to one node, ranges to every version of the name
+	assert.Equal(t, []models.Edge{
+		{From: "a@1.2.0", To: "b@1.0.0"},
+		{From: "a@1.2.0", To: "b@2.0.0"},
+		{From: "app@1.0.0", To: "a@1.2.0"},
+		{From: "app@1.0.0", To: "b@2.0.0"},
+	}, payload.Edges)
+	assert.Equal(t, 4, payload.EdgeCount)
+
+	// An empty graph still sends an array
+	msg = NewDAGMessage(nil, nil, nil)
+	assert.Contains(t, string(msg.Payload), `"edges":[]`)
+}
+
+func TestNewPackageInstallScriptsMessage(t *testing.T) {
+	scripts := []static.InstallScript{{Hook: "postinstall", Command: "node setup.js", Files: []static.ScriptFile{{Path: "setup.js", Content: "run()\n"}}}}
+	msg := NewPackageInstallScriptsMessage("evil@1.0.0", "evil", "1.0.0", scripts)
+	assert.Equal(t, TypePackageInstallScripts, msg.Type)
+
+	var payload PackageInstallScriptsPayload
+	require.NoError(t, json.Unmarshal(msg.Payload, &payload))
+	assert.Equal(t, "evil@1.0.0", payload.PackageID)
+	assert.Equal(t, scripts, payload.Scripts)
+}
@@ -3,18 +3,25 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"time"
 
 	"github.com/acheong08/hackeurope-spr/internal/aggregate"
 	"github.com/acheong08/hackeurope-spr/internal/analysis"
+	"github.com/acheong08/hackeurope-spr/internal/artifacts"
+	"github.com/acheong08/hackeurope-spr/internal/intel"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+	"github.com/acheong08/hackeurope-spr/internal/naming"
 	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
 	"github.com/acheong08/hackeurope-spr/internal/parser"
 	"github.com/acheong08/hackeurope-spr/internal/registry"
-	"github.com/acheong08/hackeurope-spr/internal/tester"
+	"github.com/acheong08/hackeurope-spr/internal/siem"
+	"github.com/acheong08/hackeurope-spr/internal/static"
+	"github.com/acheong08/hackeurope-spr/internal/ticketing"
 	"github.com/acheong08/hackeurope-spr/pkg/models"
 )
 
@@ -29,11 +36,13 @@ type ProgressSender interface {
 // Pipeline wraps the CLI analysis logic for WebSocket use
 type Pipeline struct {
 	// Unsafe (staging) registry settings
+	registryType  registry.Type
 	registryURL   string
 	registryToken string
 	registryOwner string
 
 	// Safe (approved) registry settings — promotion skipped when token is empty
+	safeRegistryType  registry.Type
 	safeRegistryURL   string
 	safeRegistryToken string
 	safeRegistryOwner string
@@ -44,14 +53,59 @@ type Pipeline struct {
 	repoName    string
 
 	// Analysis settings
-	baselinePath string
-	apiKey       string // API key for AI analysis
+	baselinePath  string
+	apiKey        string // API key for AI analysis
+	aiProvider    string // AI provider, base URL and model — empty uses the defaults
+	aiBaseURL     string
+	aiModel       string
+	quarantineDir string // Evidence archive for flagged packages — empty disables it
+	resultsLog    string // Hash-chained verdict log — empty disables it
+	drift         driftSettings
+	telemetryURL  string // Opt-in anonymous run stats — empty disables it
+	keyMode       aggregate.KeyMode
+	allowNonNpm   bool // Pack and upload git/URL dependencies instead of aborting
+	syscalls      aggregate.SyscallThreshold
+	access        aggregate.AccessThreshold
+	allowlist     *aggregate.Allowlist
+	intel         *intel.Enricher
+	artifactSink  artifacts.Sink // Durable artifact storage — nil disables export
+
+	// Per-stage concurrency limits — zero values use the defaults
+	stages orchestrator.StageConcurrency
+
+	// Backends the analysis agent queries with tools — nil uses the default
+	sources []analysis.Source
+	// Language of justifications — empty leaves them in English
+	language string
+
+	// Per-package workflow timeout overrides and re-triggers of failed runs
+	packageTimeouts map[string]time.Duration
+	retries         int
+	// Extra workflow_dispatch inputs for every run
+	workflowInputs map[string]string
+	// Behavioral tests each run is limited to — nil runs all of them
+	tests []string
+	// Wakes workflow polling on workflow_run webhooks — nil polls
+	runWaker *orchestrator.RunWaker
+	// Traces uploaded to the ingest route by self-hosted runners — empty
+	// disables them
+	ingestDir string
+	// SIEM collector flagged packages are forwarded to — empty URL disables it
+	siemURL    string
+	siemFormat siem.Format
+	siemToken  string
+	// Ticket filer for flagged packages — nil disables it
+	tickets *ticketing.Filer
 
 	// Progress sender
 	sender ProgressSender
 
 	// Temp directory for this analysis
 	tempDir string
+
+	// Correlation ID for this analysis run; every log line is tagged with it
+	runID  string
+	logger *slog.Logger
 }
 
 // NewPipeline creates a new pipeline instance
@@ -63,6 +117,7 @@ func NewPipeline(
 	apiKey string,
 	safeRegistryURL, safeRegistryToken, safeRegistryOwner string,
 ) *Pipeline {
+	runID := logging.NewRunID()
 	return &Pipeline{
 		registryURL:       registryURL,
 		registryToken:     registryToken,
@@ -76,25 +131,164 @@ func NewPipeline(
 		baselinePath:      baselinePath,
 		apiKey:            apiKey,
 		sender:            sender,
+		syscalls:          aggregate.DefaultSyscallThreshold,
+		access:            aggregate.DefaultAccessThreshold,
+		runID:             runID,
+		logger:            slog.Default().With(logging.KeyRunID, runID),
 	}
 }
 
-// log sends a log message both to the WebSocket client and to the console
-func (p *Pipeline) log(message, level string) {
+// SetSender replaces the progress sender, e.g. with one that tags messages
+// with an analysis ID
+func (p *Pipeline) SetSender(sender ProgressSender) {
+	p.sender = sender
+}
+
+// SetQuarantineDir sets where evidence for flagged packages is archived
+func (p *Pipeline) SetQuarantineDir(dir string) {
+	p.quarantineDir = dir
+}
+
+// SetResultsLog sets the hash-chained log verdicts are appended to
+func (p *Pipeline) SetResultsLog(path string) {
+	p.resultsLog = path
+}
+
+// driftSettings are the orchestrator's baseline drift tracking settings,
+// see orchestrator.SetBaselineDrift
+type driftSettings struct {
+	path      string
+	threshold float64
+	runs      int
+}
+
+// SetBaselineDrift records the baseline's effectiveness in each run to the
+// history at path, see orchestrator.SetBaselineDrift
+func (p *Pipeline) SetBaselineDrift(path string, threshold float64, runs int) {
+	p.drift = driftSettings{path: path, threshold: threshold, runs: runs}
+}
+
+// SetTelemetry opts in to posting anonymous run stats to endpoint
+func (p *Pipeline) SetTelemetry(endpoint string) {
+	p.telemetryURL = endpoint
+}
+
+// SetSIEM forwards flagged packages to a SIEM collector at endpoint
+func (p *Pipeline) SetSIEM(endpoint string, format siem.Format, token string) {
+	p.siemURL = endpoint
+	p.siemFormat = format
+	p.siemToken = token
+}
+
+// SetTicketing files a ticket per flagged package through filer; nil
+// disables it
+func (p *Pipeline) SetTicketing(filer *ticketing.Filer) {
+	p.tickets = filer
+}
+
+// SetProcessKeyMode sets how processes are keyed in behavioral diffs
+func (p *Pipeline) SetProcessKeyMode(mode aggregate.KeyMode) {
+	p.keyMode = mode
+}
+
+// SetSyscallThreshold sets how far a syscall count must rise above the
+// baseline before it is kept in a diff
+func (p *Pipeline) SetSyscallThreshold(t aggregate.SyscallThreshold) {
+	p.syscalls = t
+}
+
+// SetAccessThreshold sets how much more often than in the baseline a file or
+// command must be used before it is kept in a diff despite the baseline
+// having it
+func (p *Pipeline) SetAccessThreshold(t aggregate.AccessThreshold) {
+	p.access = t
+}
+
+// SetAllowlist suppresses known-benign activity left in diffs after baseline
+// subtraction; nil disables it
+func (p *Pipeline) SetAllowlist(a *aggregate.Allowlist) {
+	p.allowlist = a
+}
+
+// SetIntel embeds threat intel about contacted IPs and domains in diffs; nil
+// disables it
+func (p *Pipeline) SetIntel(e *intel.Enricher) {
+	p.intel = e
+}
+
+// SetRegistryTypes selects the API of the unsafe and safe registries
+// (Gitea when unset)
+func (p *Pipeline) SetRegistryTypes(unsafe, safe registry.Type) {
+	p.registryType = unsafe
+	p.safeRegistryType = safe
+}
+
+// SetAllowNonNpm enables packing and uploading git and URL dependencies
+func (p *Pipeline) SetAllowNonNpm(enabled bool) {
+	p.allowNonNpm = enabled
+}
+
+// SetArtifactSink uploads each package's artifacts to sink, keyed by run ID.
+// Local copies are kept since results are read back from them.
+func (p *Pipeline) SetArtifactSink(sink artifacts.Sink) {
+	p.artifactSink = sink
+}
+
+// SetWorkflowRetries overrides the workflow timeout of individual packages
+// and sets how often a failed or timed-out workflow is re-triggered
+func (p *Pipeline) SetWorkflowRetries(timeouts map[string]time.Duration, retries int) {
+	p.packageTimeouts = timeouts
+	p.retries = retries
+}
+
+// SetWorkflowInputs sets extra inputs passed to every workflow dispatch
+func (p *Pipeline) SetWorkflowInputs(inputs map[string]string) {
+	p.workflowInputs = inputs
+}
+
+// SetTests limits every workflow run to the given behavioral tests
+func (p *Pipeline) SetTests(tests []string) {
+	p.tests = tests
+}
+
+// SetAIProvider selects the AI provider, base URL and model used for analysis
+func (p *Pipeline) SetAIProvider(provider, baseURL, model string) {
+	p.aiProvider = provider
+	p.aiBaseURL = baseURL
+	p.aiModel = model
+}
+
+// SetAnalysisSources selects the backends the analysis agent can query with
+// tools: the package's diff.json, the trace stats service or both
+func (p *Pipeline) SetAnalysisSources(sources []analysis.Source) {
+	p.sources = sources
+}
+
+// SetAnalysisLanguage renders justifications in language rather than English
+func (p *Pipeline) SetAnalysisLanguage(language string) {
+	p.language = language
+}
+
+// SetStageConcurrency sets the upload, workflow, aggregation and AI
+// concurrency limits
+func (p *Pipeline) SetStageConcurrency(c orchestrator.StageConcurrency) {
+	p.stages = c
+}
+
+// RunID returns the correlation ID tagged on every log line of this pipeline
+func (p *Pipeline) RunID() string {
+	return p.runID
+}
+
+// log sends a log message both to the WebSocket client and to the structured logger
+func (p *Pipeline) log(message, level string, attrs ...any) {
 	// Send to WebSocket client
 	p.sender.SendLog(message, level)
 
-	// Also log to console with level indicator
-	prefix := "[INFO]"
-	switch level {
-	case "success":
-		prefix = "[SUCCESS]"
-	case "warning":
-		prefix = "[WARN]"
-	case "error":
-		prefix = "[ERROR]"
+	if len(attrs) == 0 {
+		attrs = []any{logging.KeyStage, "pipeline"}
 	}
-	log.Printf("%s %s", prefix, message)
+	logging.Log(p.logger, message, level, attrs...)
 }
 
 // logf is a formatted version of log
@@ -112,6 +306,9 @@ func (p *Pipeline) Run(ctx context.Context, packageJSONContent string) error {
 	}
 	p.tempDir = tempDir
 	defer os.RemoveAll(tempDir)
+	if err := os.Mkdir(p.tarballDir(), 0o755); err != nil {
+		return fmt.Errorf("failed to create tarball directory: %w", err)
+	}
 
 	p.log("Starting analysis...", "info")
 
@@ -158,12 +355,13 @@ func (p *Pipeline) Run(ctx context.Context, packageJSONContent string) error {
 
 	// Step 4: Aggregate data (80% - 90%)
 	p.sender.SendProgress(80, "aggregate", "Aggregating behavioral data...")
-	// TODO: Call Mongo aggregation service
+	// Traces were diffed, and uploaded to the trace stats service when it
+	// is an analysis source, as each package finished
 	p.sender.SendProgress(90, "aggregate", "Data aggregation complete")
 
 	// Step 5: Run agent (90% - 100%)
 	p.sender.SendProgress(90, "agent", "Running security analysis...")
-	// TODO: Call agent
+	// The agent ran per package as part of the workflows, see SetAnalysisSources
 	p.sender.SendProgress(100, "agent", "Analysis complete")
 
 	p.log("Analysis pipeline complete", "success")
@@ -220,65 +418,45 @@ func (p *Pipeline) sendDAG(graph *models.DependencyGraph) error {
 		nodes = append(nodes, node)
 	}
 
-	// Count edges (dependencies)
-	edgeCount := 0
-	for _, node := range graph.Nodes {
-		edgeCount += len(node.Dependencies)
-	}
-
-	msg := NewDAGMessage(graph.RootPackage, nodes, edgeCount)
+	edges := graph.Edges()
+	msg := NewDAGMessage(graph.RootPackage, nodes, edges)
 	p.sender.SendMessage(msg)
 
-	p.log(fmt.Sprintf("DAG sent: %d nodes, %d edges", len(nodes), edgeCount), "success")
+	p.log(fmt.Sprintf("DAG sent: %d nodes, %d edges", len(nodes), len(edges)), "success")
 	return nil
 }
 
+// tarballDir is where the uploader keeps the tarballs it downloads, for the
+// static scan; inside the run's temp directory, empty outside a run
+func (p *Pipeline) tarballDir() string {
+	if p.tempDir == "" {
+		return ""
+	}
+	return filepath.Join(p.tempDir, "tarballs")
+}
+
 // uploadPackages uploads the dependency graph to the registry
 func (p *Pipeline) uploadPackages(ctx context.Context, graph *models.DependencyGraph) error {
 	uploader := registry.NewUploader(p.registryURL, p.registryOwner, p.registryToken)
+	uploader.Concurrency = p.stages.WithDefaults().Upload
+	uploader.SetLogger(p.logger)
+	uploader.SetBackend(registry.NewBackend(p.registryType, p.registryURL, p.registryOwner, p.registryToken))
+	uploader.SetAllowNonNpm(p.allowNonNpm)
+	uploader.SetTarballDir(p.tarballDir())
 	uploader.SetLogCallback(func(message, level string) {
 		p.sender.SendLog(message, level)
 	})
 
-	// Track progress
-	totalPackages := len(graph.Nodes)
-	uploaded := 0
-
-	// Create a wrapper to track progress
-	errChan := make(chan error, 1)
-	go func() {
-		errChan <- uploader.UploadGraph(ctx, graph)
-	}()
-
-	// Poll progress (simple version)
-	ticker := time.NewTicker(500 * time.Millisecond)
-	defer ticker.Stop()
-
-	for {
-		select {
-		case err := <-errChan:
-			if err != nil {
-				return err
-			}
-			// Send final progress
-			percent := 20 + int(float64(totalPackages)/float64(totalPackages)*20)
-			p.sender.SendProgress(percent, "upload", fmt.Sprintf("Uploaded %d/%d packages", totalPackages, totalPackages))
-			return nil
-		case <-ticker.C:
-			// Update progress (approximate)
-			uploaded++
-			if uploaded > totalPackages {
-				uploaded = totalPackages
-			}
-			percent := 20 + int(float64(uploaded)/float64(totalPackages)*20)
-			if percent > 40 {
-				percent = 40
-			}
-			p.sender.SendProgress(percent, "upload", fmt.Sprintf("Uploading package %d/%d...", uploaded, totalPackages))
-		case <-ctx.Done():
-			return ctx.Err()
+	// Upload progress maps onto 20-40% of the run
+	uploader.SetProgressCallback(func(done, total int) {
+		percent := 40
+		if total > 0 {
+			percent = 20 + done*20/total
 		}
-	}
+		p.sender.SendProgress(percent, "upload", fmt.Sprintf("Uploaded %d/%d packages", done, total))
+	})
+
+	return uploader.UploadGraph(ctx, graph)
 }
 
 // runWorkflows triggers GitHub Actions workflows for packages, then emits
@@ -299,6 +477,10 @@ func (p *Pipeline) runWorkflows(ctx context.Context, packages []*models.PackageN
 	var safeUploader *registry.Uploader
 	if p.safeRegistryToken != "" {
 		safeUploader = registry.NewUploader(p.safeRegistryURL, p.safeRegistryOwner, p.safeRegistryToken)
+		safeUploader.Concurrency = p.stages.WithDefaults().Upload
+		safeUploader.SetLogger(p.logger)
+		safeUploader.SetBackend(registry.NewBackend(p.safeRegistryType, p.safeRegistryURL, p.safeRegistryOwner, p.safeRegistryToken))
+		safeUploader.SetAllowNonNpm(p.allowNonNpm)
 		safeUploader.SetLogCallback(func(message, level string) {
 			p.sender.SendLog(message, level)
 		})
@@ -310,7 +492,7 @@ func (p *Pipeline) runWorkflows(ctx context.Context, packages []*models.PackageN
 		p.repoOwner,
 		p.repoName,
 		"analyze-package.yml",
-		5,             // concurrency
+		p.stages.WithDefaults().Workflow,
 		5*time.Minute, // timeout
 		func(pkgName, pkgVersion string, artifactCount int) {
 			p.sender.SendLog(fmt.Sprintf("Downloaded %d artifacts for %s@%s", artifactCount, pkgName, pkgVersion), "success")
@@ -322,9 +504,38 @@ func (p *Pipeline) runWorkflows(ctx context.Context, packages []*models.PackageN
 	)
 
 	// Forward orchestrator + analyzer logs to WebSocket
+	orch.SetLogger(p.logger)
+	orch.SetAIProvider(p.aiProvider, p.aiBaseURL, p.aiModel)
+	orch.SetAnalysisSources(p.sources)
+	orch.SetAnalysisLanguage(p.language)
+	orch.SetStageConcurrency(p.stages)
+	orch.SetQuarantineDir(p.quarantineDir)
+	orch.SetResultsLog(p.resultsLog, p.runID)
+	orch.SetBaselineDrift(p.drift.path, p.drift.threshold, p.drift.runs)
+	orch.SetTelemetry(p.telemetryURL)
+	orch.SetSIEM(p.siemURL, p.siemFormat, p.siemToken)
+	orch.SetTicketing(p.tickets)
+	orch.SetProcessKeyMode(p.keyMode)
+	orch.SetSyscallThreshold(p.syscalls)
+	orch.SetAccessThreshold(p.access)
+	orch.SetAllowlist(p.allowlist)
+	orch.SetIntel(p.intel)
+	orch.SetTarballDir(p.tarballDir())
+	orch.SetPackageTimeouts(p.packageTimeouts)
+	orch.SetRetries(p.retries)
+	orch.SetWorkflowInputs(p.workflowInputs)
+	orch.SetTests(p.tests)
+	orch.SetRunWaker(p.runWaker)
+	orch.SetIngestDir(p.ingestDir)
+	if p.artifactSink != nil {
+		orch.SetArtifactSink(p.artifactSink, p.runID, true)
+	}
 	orch.SetLogCallback(func(message, level string) {
 		p.sender.SendLog(message, level)
 	})
+	orch.SetAnalysisStreamCallback(func(event analysis.StreamEvent) {
+		p.sender.SendMessage(NewPackageAnalysisStreamMessage(event))
+	})
 
 	// Mark all packages pending
 	for _, pkg := range packages {
@@ -346,10 +557,14 @@ func (p *Pipeline) runWorkflows(ctx context.Context, packages []*models.PackageN
 	}()
 
 	// Run workflows
-	_, err := orch.RunPackages(ctx, pkgs, p.tempDir, outputDir)
+	results, err := orch.RunPackages(ctx, pkgs, p.tempDir, outputDir)
 
 	close(completedChan)
 
+	if errors.Is(err, orchestrator.ErrCancelled) {
+		p.emitPartialResults(packages, results, outputDir)
+		return err
+	}
 	if err != nil {
 		return err
 	}
@@ -360,12 +575,11 @@ func (p *Pipeline) runWorkflows(ctx context.Context, packages []*models.PackageN
 	return nil
 }
 
-// emitPackageResults reads diff.json and ai-analysis.json for each package
-// and sends them over WebSocket. Sets package_status to "failed" for malicious packages.
+// emitPackageResults reads diff.json, ai-analysis.json and the install scripts
+// of each package and sends them over WebSocket. Sets package_status to "failed" for malicious packages.
 func (p *Pipeline) emitPackageResults(packages []*models.PackageNode, outputDir string) {
 	for _, pkg := range packages {
-		normalizedName := tester.NormalizePackageName(pkg.Name)
-		pkgDir := filepath.Join(outputDir, fmt.Sprintf("%s@%s", normalizedName, pkg.Version))
+		pkgDir := filepath.Join(outputDir, naming.DirName(pkg.Name, pkg.Version))
 
 		isMalicious := false
 
@@ -374,9 +588,11 @@ func (p *Pipeline) emitPackageResults(packages []*models.PackageNode, outputDir
 		if data, err := os.ReadFile(diffPath); err == nil {
 			var diff aggregate.DedupedProcessStats
 			if err := json.Unmarshal(data, &diff); err == nil {
+				// Flagged again so diffs from before risk flags carry them too
+				diff.FlagRisks()
 				p.sender.SendMessage(NewPackageBehavioralDataMessage(pkg.ID, pkg.Name, pkg.Version, &diff))
 			} else {
-				p.log(fmt.Sprintf("Failed to parse diff.json for %s@%s: %v", pkg.Name, pkg.Version, err), "warning")
+				p.log(fmt.Sprintf("Failed to parse diff.json for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", logging.KeyPackageID, pkg.ID, logging.KeyStage, "results")
 			}
 		}
 		// diff.json absence is normal (no anomalies) — no warning needed
@@ -389,16 +605,31 @@ func (p *Pipeline) emitPackageResults(packages []*models.PackageNode, outputDir
 				p.sender.SendMessage(NewPackageAnalysisMessage(pkg.ID, pkg.Name, pkg.Version, &assessment))
 				if assessment.IsMalicious {
 					isMalicious = true
-					p.log(fmt.Sprintf("SUSPICIOUS %s@%s — %s", pkg.Name, pkg.Version, assessment.Justification), "warning")
+					p.log(fmt.Sprintf("SUSPICIOUS %s@%s — %s", pkg.Name, pkg.Version, assessment.Justification), "warning", logging.KeyPackageID, pkg.ID, logging.KeyStage, "results")
 				} else {
-					p.log(fmt.Sprintf("SAFE %s@%s (confidence=%.0f%%)", pkg.Name, pkg.Version, assessment.Confidence*100), "success")
+					p.log(fmt.Sprintf("SAFE %s@%s (confidence=%.0f%%)", pkg.Name, pkg.Version, assessment.Confidence*100), "success", logging.KeyPackageID, pkg.ID, logging.KeyStage, "results")
 				}
 			} else {
-				p.log(fmt.Sprintf("Failed to parse ai-analysis.json for %s@%s: %v", pkg.Name, pkg.Version, err), "warning")
+				p.log(fmt.Sprintf("Failed to parse ai-analysis.json for %s@%s: %v", pkg.Name, pkg.Version, err), "warning", logging.KeyPackageID, pkg.ID, logging.KeyStage, "results")
 			}
 		}
 		// ai-analysis.json absence means no anomalies → safe
 
+		// --- Install scripts (install-scripts.json), from the static scan ---
+		if data, err := os.ReadFile(filepath.Join(pkgDir, orchestrator.InstallScriptsFile)); err == nil {
+			var scripts []static.InstallScript
+			if err := json.Unmarshal(data, &scripts); err == nil {
+				p.sender.SendMessage(NewPackageInstallScriptsMessage(pkg.ID, pkg.Name, pkg.Version, scripts))
+			} else {
+				p.log(fmt.Sprintf("Failed to parse %s for %s@%s: %v", orchestrator.InstallScriptsFile, pkg.Name, pkg.Version, err), "warning", logging.KeyPackageID, pkg.ID, logging.KeyStage, "results")
+			}
+		}
+
+		// Findings the orchestrator recorded on the graph node
+		if len(pkg.Annotations) > 0 {
+			p.sender.SendMessage(NewPackageAnnotationsMessage(pkg))
+		}
+
 		// Set node color in the DAG
 		status := "complete"
 		if isMalicious {
@@ -408,6 +639,29 @@ func (p *Pipeline) emitPackageResults(packages []*models.PackageNode, outputDir
 	}
 }
 
+// emitPartialResults sends results for the packages that finished before the
+// analysis was cancelled and marks the rest as cancelled
+func (p *Pipeline) emitPartialResults(packages []*models.PackageNode, results []orchestrator.PackageResult, outputDir string) {
+	finished := make(map[string]bool)
+	for _, result := range results {
+		if result.Success {
+			finished[result.Package.Name+"@"+result.Package.Version] = true
+		}
+	}
+
+	var done []*models.PackageNode
+	for _, pkg := range packages {
+		if finished[pkg.Name+"@"+pkg.Version] {
+			done = append(done, pkg)
+		} else {
+			p.sender.SendMessage(NewPackageStatusMessage(pkg.ID, pkg.Name, pkg.Version, "cancelled", 0))
+		}
+	}
+
+	p.log(fmt.Sprintf("Analysis cancelled — sending partial results for %d/%d packages", len(done), len(packages)), "warning")
+	p.emitPackageResults(done, outputDir)
+}
+
 // parsePackageJSON is a helper to parse package.json from string
 type packageJSON struct {
 	Name            string            `json:"name"`
@@ -0,0 +1,344 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/parser"
+	"github.com/acheong08/hackeurope-spr/internal/resultlog"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// VerdictRoute serves the public verdict of a package version as
+// /api/verdicts/<name>/<version>; scoped names keep their slash
+const VerdictRoute = "/api/verdicts/{path...}"
+
+// BulkVerdictRoute answers verdict queries for many packages in one POST
+const BulkVerdictRoute = "/api/verdicts/bulk"
+
+// VerdictUnknown is the verdict of packages without a public verdict in bulk
+// responses: unanalyzed, unpublished or flagged
+const VerdictUnknown = "unknown"
+
+// Limits on bulk queries
+const (
+	maxBulkBody     = 32 << 20 // Large enough for monorepo lockfiles
+	maxBulkPackages = 10000
+	// Concurrent safe registry checks per bulk query
+	bulkConcurrency = 8
+)
+
+// DefaultVerdictCacheTTL is how long verdicts and publication checks are
+// cached when no TTL is configured
+const DefaultVerdictCacheTTL = time.Minute
+
+// PublicVerdict is all the verdict API reveals about a package version
+type PublicVerdict struct {
+	Package    string    `json:"package"`
+	Version    string    `json:"version"`
+	Verdict    string    `json:"verdict"` // models.VerdictSafe or models.VerdictClean
+	Confidence float64   `json:"confidence,omitempty"`
+	AnalyzedAt time.Time `json:"analyzed_at,omitzero"`
+}
+
+// PublishedFunc reports whether a package version is in the safe registry
+type PublishedFunc func(ctx context.Context, name, version string) (bool, error)
+
+// indexedVerdict is a verdict with the hash of the log entry it came from,
+// which doubles as its ETag
+type indexedVerdict struct {
+	PublicVerdict
+	hash string
+}
+
+// publishCheck is a cached PublishedFunc answer
+type publishCheck struct {
+	published bool
+	at        time.Time
+}
+
+// VerdictIndex answers verdict lookups from the results log. The log is
+// re-read at most once per TTL, and only when it has changed; publication
+// checks are cached for the TTL too, so lookups stay cheap at high volume.
+type VerdictIndex struct {
+	logPath   string
+	ttl       time.Duration
+	published PublishedFunc
+
+	mu       sync.Mutex
+	loadedAt time.Time
+	modTime  time.Time
+	size     int64
+	verdicts map[string]indexedVerdict
+	checks   map[string]publishCheck
+}
+
+// NewVerdictIndex serves the latest verdict of each package version in the
+// results log at logPath. Only safe and clean verdicts are served, and when
+// published is set only for versions it confirms are in the safe registry.
+// A zero ttl uses DefaultVerdictCacheTTL.
+func NewVerdictIndex(logPath string, ttl time.Duration, published PublishedFunc) *VerdictIndex {
+	if ttl <= 0 {
+		ttl = DefaultVerdictCacheTTL
+	}
+	return &VerdictIndex{
+		logPath:   logPath,
+		ttl:       ttl,
+		published: published,
+		checks:    make(map[string]publishCheck),
+	}
+}
+
+// lookup returns the public verdict of name@version, or nil if there is none
+// to serve
+func (x *VerdictIndex) lookup(ctx context.Context, name, version string) (*indexedVerdict, error) {
+	key := name + "@" + version
+
+	x.mu.Lock()
+	if err := x.refresh(); err != nil {
+		x.mu.Unlock()
+		return nil, err
+	}
+	v, ok := x.verdicts[key]
+	check, checked := x.checks[key]
+	x.mu.Unlock()
+
+	if !ok || v.Verdict == models.VerdictMalicious {
+		return nil, nil
+	}
+	if x.published == nil {
+		return &v, nil
+	}
+	if !checked || time.Since(check.at) >= x.ttl {
+		// Checked outside the lock; concurrent misses may check twice
+		published, err := x.published(ctx, name, version)
+		if err != nil {
+			return nil, fmt.Errorf("failed to check the safe registry: %w", err)
+		}
+		check = publishCheck{published: published, at: time.Now()}
+		x.mu.Lock()
+		x.checks[key] = check
+		x.mu.Unlock()
+	}
+	if !check.published {
+		return nil, nil
+	}
+	return &v, nil
+}
+
+// refresh rebuilds the index if the TTL has passed and the log has changed
+// since it was read. Called with x.mu held.
+func (x *VerdictIndex) refresh() error {
+	if x.verdicts != nil && time.Since(x.loadedAt) < x.ttl {
+		return nil
+	}
+	info, err := os.Stat(x.logPath)
+	if errors.Is(err, os.ErrNotExist) {
+		// Nothing analyzed yet
+		x.verdicts, x.loadedAt = map[string]indexedVerdict{}, time.Now()
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if x.verdicts != nil && info.ModTime().Equal(x.modTime) && info.Size() == x.size {
+		x.loadedAt = time.Now()
+		return nil
+	}
+
+	// A tampered log isn't served from, not even its intact prefix
+	entries, err := resultlog.Read(x.logPath)
+	if err != nil {
+		x.verdicts = nil
+		return err
+	}
+	verdicts := make(map[string]indexedVerdict)
+	for _, entry := range entries {
+		var record struct {
+			Time       time.Time `json:"time"`
+			Package    string    `json:"package"`
+			Version    string    `json:"version"`
+			Verdict    string    `json:"verdict"`
+			Confidence float64   `json:"confidence"`
+		}
+		if err := json.Unmarshal(entry.Record, &record); err != nil || record.Package == "" {
+			continue
+		}
+		// Later runs supersede earlier verdicts
+		verdicts[record.Package+"@"+record.Version] = indexedVerdict{
+			PublicVerdict: PublicVerdict{
+				Package:    record.Package,
+				Version:    record.Version,
+				Verdict:    record.Verdict,
+				Confidence: record.Confidence,
+				AnalyzedAt: record.Time,
+			},
+			hash: entry.Hash,
+		}
+	}
+	x.verdicts, x.loadedAt = verdicts, time.Now()
+	x.modTime, x.size = info.ModTime(), info.Size()
+	return nil
+}
+
+// VerdictHandler serves GET VerdictRoute without authentication: the verdict
+// and confidence of a package version published as safe, or 404. Responses
+// are cacheable by browsers and proxies for the index TTL and carry the log
+// entry hash as their ETag.
+func VerdictHandler(index *VerdictIndex) http.Handler {
+	cacheControl := "public, max-age=" + strconv.Itoa(int(index.ttl.Seconds()))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		// Developer portals fetch this from the browser
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		path := r.PathValue("path")
+		i := strings.LastIndexByte(path, '/')
+		if i <= 0 || i == len(path)-1 {
+			http.Error(w, "expected /api/verdicts/<name>/<version>", http.StatusBadRequest)
+			return
+		}
+		name, version := path[:i], path[i+1:]
+
+		v, err := index.lookup(r.Context(), name, version)
+		if err != nil {
+			slog.Error("Verdict lookup failed", "package", name, "version", version, "error", err)
+			http.Error(w, "verdicts unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Cache-Control", cacheControl)
+		if v == nil {
+			http.Error(w, "no published verdict", http.StatusNotFound)
+			return
+		}
+
+		etag := `"` + v.hash + `"`
+		w.Header().Set("ETag", etag)
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(v.PublicVerdict)
+	})
+}
+
+// bulkResponse lists a verdict for every queried package, in query order
+type bulkResponse struct {
+	Verdicts []PublicVerdict `json:"verdicts"`
+	Known    int             `json:"known"`
+	Unknown  int             `json:"unknown"`
+}
+
+// BulkVerdictHandler serves POST BulkVerdictRoute without authentication.
+// The body is either a package-lock.json (v3) or a list of packages:
+//
+//	{"packages": ["left-pad@1.3.0", "@scope/name@2.0.0"]}
+//
+// Every package gets its public verdict, or VerdictUnknown when it has none,
+// so editor plugins and CI gates can check a whole tree in one round trip.
+func BulkVerdictHandler(index *VerdictIndex) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBulkBody))
+		if err != nil {
+			http.Error(w, "request body too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		packages, err := parseBulkQuery(data)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if len(packages) > maxBulkPackages {
+			http.Error(w, fmt.Sprintf("too many packages (%d, at most %d)", len(packages), maxBulkPackages), http.StatusRequestEntityTooLarge)
+			return
+		}
+
+		resp := bulkResponse{Verdicts: make([]PublicVerdict, len(packages))}
+		errs := make([]error, len(packages))
+		semaphore := make(chan struct{}, bulkConcurrency)
+		var wg sync.WaitGroup
+		for i, pkg := range packages {
+			wg.Add(1)
+			semaphore <- struct{}{}
+			go func() {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				v, err := index.lookup(r.Context(), pkg.Name, pkg.Version)
+				switch {
+				case err != nil:
+					errs[i] = err
+				case v != nil:
+					resp.Verdicts[i] = v.PublicVerdict
+				default:
+					resp.Verdicts[i] = PublicVerdict{Package: pkg.Name, Version: pkg.Version, Verdict: VerdictUnknown}
+				}
+			}()
+		}
+		wg.Wait()
+		if err := errors.Join(errs...); err != nil {
+			slog.Error("Bulk verdict lookup failed", "packages", len(packages), "error", err)
+			http.Error(w, "verdicts unavailable", http.StatusServiceUnavailable)
+			return
+		}
+
+		for _, v := range resp.Verdicts {
+			if v.Verdict == VerdictUnknown {
+				resp.Unknown++
+			} else {
+				resp.Known++
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// parseBulkQuery reads the packages of a bulk query: a lockfile, recognized
+// by its lockfileVersion, or a list of name@version specs
+func parseBulkQuery(data []byte) ([]models.Package, error) {
+	var query struct {
+		LockfileVersion *int            `json:"lockfileVersion"`
+		Packages        json.RawMessage `json:"packages"`
+	}
+	if err := json.Unmarshal(data, &query); err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if query.LockfileVersion != nil {
+		return parser.LockfilePackages(data)
+	}
+
+	var specs []string
+	if err := json.Unmarshal(query.Packages, &specs); err != nil {
+		return nil, fmt.Errorf(`expected a package-lock.json or {"packages": ["name@version", ...]}`)
+	}
+	packages := make([]models.Package, 0, len(specs))
+	for _, spec := range specs {
+		// The version follows the last @, so scoped names keep theirs
+		i := strings.LastIndexByte(spec, '@')
+		if i <= 0 || i == len(spec)-1 {
+			return nil, fmt.Errorf("invalid package %q (expected name@version)", spec)
+		}
+		packages = append(packages, models.Package{ID: spec, Name: spec[:i], Version: spec[i+1:]})
+	}
+	return packages, nil
+}
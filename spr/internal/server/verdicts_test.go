@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/acheong08/hackeurope-spr/internal/resultlog"
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerdictHandler(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "results.log")
+	analyzed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	_, err := resultlog.Append(logPath,
+		orchestrator.ResultRecord{Time: analyzed, RunID: "run-1", Package: "@acme/ui", Version: "1.0.0", Verdict: models.VerdictSafe, Confidence: 0.9, Artifacts: map[string]string{"diff.json": "abc"}},
+		orchestrator.ResultRecord{Time: analyzed, Package: "evil", Version: "1.0.0", Verdict: models.VerdictMalicious, Confidence: 0.95},
+		orchestrator.ResultRecord{Time: analyzed, Package: "left-pad", Version: "1.3.0", Verdict: models.VerdictClean},
+		orchestrator.ResultRecord{Time: analyzed, Package: "unpublished", Version: "2.0.0", Verdict: models.VerdictSafe},
+	)
+	require.NoError(t, err)
+
+	checks := 0
+	published := func(ctx context.Context, name, version string) (bool, error) {
+		checks++
+		return name != "unpublished", nil
+	}
+	mux := http.NewServeMux()
+	mux.Handle(VerdictRoute, VerdictHandler(NewVerdictIndex(logPath, time.Hour, published)))
+	get := func(path, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := get("/api/verdicts/@acme/ui/1.0.0", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=3600", rec.Header().Get("Cache-Control"))
+	assert.Equal(t, "*", rec.Header().Get("Access-Control-Allow-Origin"))
+	// Only the verdict and score, not run IDs or artifact hashes
+	assert.JSONEq(t, `{"package":"@acme/ui","version":"1.0.0","verdict":"safe","confidence":0.9,"analyzed_at":"2026-01-02T03:04:05Z"}`, rec.Body.String())
+
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+	assert.Equal(t, http.StatusNotModified, get("/api/verdicts/@acme/ui/1.0.0", etag).Code)
+	assert.Equal(t, http.StatusOK, get("/api/verdicts/%40acme%2Fui/1.0.0", "").Code)
+	assert.Equal(t, 1, checks, "publication checks are cached")
+
+	assert.Equal(t, http.StatusOK, get("/api/verdicts/left-pad/1.3.0", "").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/verdicts/evil/1.0.0", "").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/verdicts/unpublished/2.0.0", "").Code)
+	assert.Equal(t, http.StatusNotFound, get("/api/verdicts/left-pad/9.9.9", "").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/verdicts/left-pad", "").Code)
+}
+
+func TestVerdictIndexRefresh(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "results.log")
+	index := NewVerdictIndex(logPath, time.Nanosecond, nil)
+
+	// No log yet serves nothing rather than failing
+	v, err := index.lookup(context.Background(), "pkg", "1.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	_, err = resultlog.Append(logPath, orchestrator.ResultRecord{Package: "pkg", Version: "1.0.0", Verdict: models.VerdictSafe})
+	require.NoError(t, err)
+	v, err = index.lookup(context.Background(), "pkg", "1.0.0")
+	require.NoError(t, err)
+	require.NotNil(t, v)
+
+	// A later run supersedes the verdict
+	_, err = resultlog.Append(logPath, orchestrator.ResultRecord{Package: "pkg", Version: "1.0.0", Verdict: models.VerdictMalicious})
+	require.NoError(t, err)
+	v, err = index.lookup(context.Background(), "pkg", "1.0.0")
+	require.NoError(t, err)
+	assert.Nil(t, v)
+
+	// A tampered log isn't served from
+	data, err := os.ReadFile(logPath)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(logPath, append([]byte("x"), data...), 0o644))
+	_, err = index.lookup(context.Background(), "pkg", "1.0.0")
+	assert.ErrorIs(t, err, resultlog.ErrTampered)
+}
+
+func TestBulkVerdictHandler(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "results.log")
+	_, err := resultlog.Append(logPath,
+		orchestrator.ResultRecord{Package: "@isaacs/cliui", Version: "8.0.2", Verdict: models.VerdictSafe, Confidence: 0.8},
+		orchestrator.ResultRecord{Package: "left-pad", Version: "1.3.0", Verdict: models.VerdictClean},
+		orchestrator.ResultRecord{Package: "evil", Version: "1.0.0", Verdict: models.VerdictMalicious},
+	)
+	require.NoError(t, err)
+
+	mux := http.NewServeMux()
+	index := NewVerdictIndex(logPath, time.Hour, nil)
+	mux.Handle(VerdictRoute, VerdictHandler(index))
+	mux.Handle(BulkVerdictRoute, BulkVerdictHandler(index))
+	post := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, BulkVerdictRoute, strings.NewReader(body)))
+		return rec
+	}
+
+	rec := post(`{"packages": ["left-pad@1.3.0", "evil@1.0.0", "@isaacs/cliui@8.0.2", "new@0.1.0"]}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.JSONEq(t, `{
+		"verdicts": [
+			{"package": "left-pad", "version": "1.3.0", "verdict": "clean"},
+			{"package": "evil", "version": "1.0.0", "verdict": "unknown"},
+			{"package": "@isaacs/cliui", "version": "8.0.2", "verdict": "safe", "confidence": 0.8},
+			{"package": "new", "version": "0.1.0", "verdict": "unknown"}
+		],
+		"known": 2,
+		"unknown": 2
+	}`, rec.Body.String())
+
+	rec = post(`{
+		"lockfileVersion": 3,
+		"packages": {
+			"": {"name": "app", "version": "1.0.0"},
+			"node_modules/cliui": {"name": "@isaacs/cliui", "version": "8.0.2"},
+			"node_modules/left-pad": {"version": "1.3.0"}
+		}
+	}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+	var resp bulkResponse
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.Known)
+	assert.Zero(t, resp.Unknown)
+
+	assert.Equal(t, http.StatusBadRequest, post(`{"packages": ["left-pad"]}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`{"lockfileVersion": 1}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(`not json`).Code)
+
+	// The bulk route doesn't shadow single lookups of a package named bulk
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/verdicts/bulk/1.0.0", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
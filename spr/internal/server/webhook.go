@@ -0,0 +1,82 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+)
+
+// maxWebhookBody bounds the size of a webhook delivery
+const maxWebhookBody = 5 << 20
+
+// workflowRunEvent is the part of a workflow_run webhook payload we use
+type workflowRunEvent struct {
+	Action      string `json:"action"`
+	WorkflowRun struct {
+		ID         int64  `json:"id"`
+		Conclusion string `json:"conclusion"`
+	} `json:"workflow_run"`
+}
+
+// WorkflowWebhookHandler accepts GitHub workflow_run webhooks, signed with
+// secret, and wakes the analyses waiting on runs that completed. Other
+// events are acknowledged and ignored.
+func WorkflowWebhookHandler(secret string, waker *orchestrator.RunWaker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBody))
+		if err != nil {
+			http.Error(w, "failed to read body", http.StatusBadRequest)
+			return
+		}
+		if !validSignature(secret, body, r.Header.Get("X-Hub-Signature-256")) {
+			http.Error(w, "invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		if r.Header.Get("X-GitHub-Event") != "workflow_run" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		var event workflowRunEvent
+		if err := json.Unmarshal(body, &event); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+		if event.Action == "completed" && event.WorkflowRun.ID != 0 {
+			slog.Info("Workflow run completed", "workflow_run_id", event.WorkflowRun.ID, "conclusion", event.WorkflowRun.Conclusion)
+			waker.Notify(event.WorkflowRun.ID)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// validSignature checks a GitHub X-Hub-Signature-256 header
+func validSignature(secret string, body []byte, header string) bool {
+	signature, ok := strings.CutPrefix(header, "sha256=")
+	if !ok {
+		return false
+	}
+	got, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hmac.Equal(got, mac.Sum(nil))
+}
+
+// SetRunWaker lets workflow_run webhooks end the wait for workflow runs
+func (p *Pipeline) SetRunWaker(w *orchestrator.RunWaker) {
+	p.runWaker = w
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/internal/orchestrator"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkflowWebhookHandler(t *testing.T) {
+	waker := orchestrator.NewRunWaker()
+	handler := WorkflowWebhookHandler("secret", waker)
+
+	deliver := func(event, body, secret string) int {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/workflow", strings.NewReader(body))
+		req.Header.Set("X-GitHub-Event", event)
+		req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	completed := `{"action":"completed","workflow_run":{"id":42,"conclusion":"success"}}`
+	assert.Equal(t, http.StatusUnauthorized, deliver("workflow_run", completed, "wrong"))
+	assert.Equal(t, http.StatusNoContent, deliver("ping", `{}`, "secret"))
+	assert.Equal(t, http.StatusNoContent, deliver("workflow_run", `{"action":"in_progress","workflow_run":{"id":43}}`, "secret"))
+	assert.Equal(t, http.StatusNoContent, deliver("workflow_run", completed, "secret"))
+
+	woken := func(runID int64) bool {
+		ch, stop := waker.Wait(runID)
+		defer stop()
+		select {
+		case <-ch:
+			return true
+		default:
+			return false
+		}
+	}
+	assert.True(t, woken(42))
+	assert.False(t, woken(43))
+}
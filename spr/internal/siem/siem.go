@@ -0,0 +1,214 @@
+// Package siem forwards detections of flagged packages to a SIEM's HTTP
+// collector, as CEF, LEEF or JSON events, so they land in the alert pipeline
+// a SOC already watches.
+package siem
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/acheong08/hackeurope-spr/internal/version"
+)
+
+// Format is the wire format of forwarded events
+type Format string
+
+const (
+	FormatCEF  Format = "cef"  // ArcSight Common Event Format
+	FormatLEEF Format = "leef" // QRadar Log Event Extended Format 1.0
+	FormatJSON Format = "json" // One JSON object per line
+)
+
+// Vendor, product and event ID in CEF and LEEF headers
+const (
+	vendor  = "spr"
+	product = "spr"
+	eventID = "malicious-package"
+)
+
+// ParseFormat parses a format name, case-insensitively. Empty selects CEF.
+func ParseFormat(s string) (Format, error) {
+	switch f := Format(strings.ToLower(strings.TrimSpace(s))); f {
+	case "":
+		return FormatCEF, nil
+	case FormatCEF, FormatLEEF, FormatJSON:
+		return f, nil
+	default:
+		return "", fmt.Errorf("unknown SIEM format %q (expected cef, leef or json)", s)
+	}
+}
+
+// Event is a detection of one flagged package
+type Event struct {
+	Time          time.Time `json:"time"`
+	RunID         string    `json:"run_id,omitempty"`
+	Package       string    `json:"package"`
+	Version       string    `json:"version"`
+	Confidence    float64   `json:"confidence"`
+	Justification string    `json:"justification,omitempty"`
+	Indicators    []string  `json:"indicators,omitempty"`
+}
+
+// Severity maps confidence onto the 1-10 scale of CEF and LEEF
+func (e Event) Severity() int {
+	return min(max(int(math.Round(e.Confidence*10)), 1), 10)
+}
+
+// FormatEvent renders an event as a single line in the given format
+func FormatEvent(f Format, e Event) (string, error) {
+	switch f {
+	case FormatCEF:
+		return formatCEF(e), nil
+	case FormatLEEF:
+		return formatLEEF(e), nil
+	case FormatJSON:
+		data, err := json.Marshal(e)
+		return string(data), err
+	default:
+		return "", fmt.Errorf("unknown SIEM format %q", f)
+	}
+}
+
+// formatCEF renders CEF:Version|Vendor|Product|Version|ID|Name|Severity|Extension
+func formatCEF(e Event) string {
+	header := []string{
+		"CEF:0", cefHeader(vendor), cefHeader(product), cefHeader(version.Get().Version),
+		eventID, "Malicious npm package", strconv.Itoa(e.Severity()),
+	}
+	ext := [][2]string{
+		{"rt", strconv.FormatInt(e.Time.UnixMilli(), 10)},
+		{"cs1Label", "package"}, {"cs1", e.Package},
+		{"cs2Label", "version"}, {"cs2", e.Version},
+		{"cfp1Label", "confidence"}, {"cfp1", strconv.FormatFloat(e.Confidence, 'f', 2, 64)},
+		{"msg", e.Justification},
+	}
+	if len(e.Indicators) > 0 {
+		ext = append(ext, [2]string{"cs3Label", "indicators"}, [2]string{"cs3", strings.Join(e.Indicators, ", ")})
+	}
+	if e.RunID != "" {
+		ext = append(ext, [2]string{"cs4Label", "runId"}, [2]string{"cs4", e.RunID})
+	}
+
+	pairs := make([]string, 0, len(ext))
+	for _, kv := range ext {
+		pairs = append(pairs, kv[0]+"="+cefValue(kv[1]))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(pairs, " ")
+}
+
+// cefHeader escapes a CEF header field
+func cefHeader(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ").Replace(s)
+}
+
+// cefValue escapes a CEF extension value
+func cefValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`).Replace(s)
+}
+
+// formatLEEF renders LEEF:1.0|Vendor|Product|Version|EventID|attributes,
+// with tab-separated attributes
+func formatLEEF(e Event) string {
+	header := []string{
+		"LEEF:1.0", leefHeader(vendor), leefHeader(product), leefHeader(version.Get().Version), eventID,
+	}
+	attrs := [][2]string{
+		{"devTime", e.Time.UTC().Format("Jan 02 2006 15:04:05")},
+		{"devTimeFormat", "MMM dd yyyy HH:mm:ss"},
+		{"sev", strconv.Itoa(e.Severity())},
+		{"cat", "malicious-package"},
+		{"package", e.Package},
+		{"version", e.Version},
+		{"confidence", strconv.FormatFloat(e.Confidence, 'f', 2, 64)},
+		{"msg", e.Justification},
+	}
+	if len(e.Indicators) > 0 {
+		attrs = append(attrs, [2]string{"indicators", strings.Join(e.Indicators, ", ")})
+	}
+	if e.RunID != "" {
+		attrs = append(attrs, [2]string{"runId", e.RunID})
+	}
+
+	pairs := make([]string, 0, len(attrs))
+	for _, kv := range attrs {
+		pairs = append(pairs, kv[0]+"="+leefValue(kv[1]))
+	}
+	return strings.Join(header, "|") + "|" + strings.Join(pairs, "\t")
+}
+
+// leefHeader escapes a LEEF header field
+func leefHeader(s string) string {
+	return strings.NewReplacer(`|`, `\|`, "\n", " ", "\r", " ").Replace(s)
+}
+
+// leefValue strips the attribute delimiter and line breaks from a LEEF value
+func leefValue(s string) string {
+	return strings.NewReplacer("\t", " ", "\n", " ", "\r", " ").Replace(s)
+}
+
+// Client posts events to a SIEM HTTP collector
+type Client struct {
+	Endpoint   string
+	Format     Format
+	Token      string // Sent as a bearer token when set
+	HTTPClient *http.Client
+}
+
+// NewClient creates a client posting events in format to endpoint
+func NewClient(endpoint string, format Format, token string) *Client {
+	return &Client{
+		Endpoint:   endpoint,
+		Format:     format,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Send posts events in one request, one per line
+func (c *Client) Send(ctx context.Context, events []Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var body bytes.Buffer
+	for _, e := range events {
+		line, err := FormatEvent(c.Format, e)
+		if err != nil {
+			return fmt.Errorf("failed to format event: %w", err)
+		}
+		body.WriteString(line)
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.Endpoint, &body)
+	if err != nil {
+		return err
+	}
+	contentType := "text/plain; charset=utf-8"
+	if c.Format == FormatJSON {
+		contentType = "application/x-ndjson"
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "spr/"+version.Get().Version)
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send events: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("SIEM endpoint returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+package siem
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testEvent() Event {
+	return Event{
+		Time:          time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC),
+		RunID:         "run-1",
+		Package:       "evil|pkg",
+		Version:       "1.0.0",
+		Confidence:    0.93,
+		Justification: "postinstall runs curl a=b\nthen sh",
+		Indicators:    []string{"network", "shell"},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	f, err := ParseFormat("")
+	require.NoError(t, err)
+	assert.Equal(t, FormatCEF, f)
+
+	f, err = ParseFormat(" LEEF ")
+	require.NoError(t, err)
+	assert.Equal(t, FormatLEEF, f)
+
+	_, err = ParseFormat("syslog")
+	assert.Error(t, err)
+}
+
+func TestFormatCEF(t *testing.T) {
+	line, err := FormatEvent(FormatCEF, testEvent())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "CEF:0|spr|spr|"), line)
+	assert.Contains(t, line, "|malicious-package|Malicious npm package|9|")
+	assert.Contains(t, line, `cs1=evil|pkg`)
+	assert.Contains(t, line, `msg=postinstall runs curl a\=b\nthen sh`)
+	assert.Contains(t, line, "cs4=run-1")
+	assert.NotContains(t, line, "\n")
+}
+
+func TestFormatLEEF(t *testing.T) {
+	line, err := FormatEvent(FormatLEEF, testEvent())
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(line, "LEEF:1.0|spr|spr|"), line)
+	assert.Contains(t, line, "\tsev=9\t")
+	assert.Contains(t, line, "\tpackage=evil|pkg\t")
+	assert.Contains(t, line, "devTime=Mar 01 2026 12:30:00")
+	assert.NotContains(t, line, "\n")
+}
+
+func TestSeverity(t *testing.T) {
+	assert.Equal(t, 1, Event{Confidence: 0}.Severity())
+	assert.Equal(t, 10, Event{Confidence: 1.2}.Severity())
+}
+
+func TestClientSend(t *testing.T) {
+	var body, contentType, auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body, contentType, auth = string(data), r.Header.Get("Content-Type"), r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	events := []Event{testEvent(), testEvent()}
+	require.NoError(t, NewClient(server.URL, FormatJSON, "secret").Send(context.Background(), events))
+	assert.Equal(t, "application/x-ndjson", contentType)
+	assert.Equal(t, "Bearer secret", auth)
+	assert.Equal(t, 2, strings.Count(body, "\n"))
+	assert.Contains(t, body, `"package":"evil|pkg"`)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	err := NewClient(failing.URL, FormatCEF, "").Send(context.Background(), events)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "bad token")
+}
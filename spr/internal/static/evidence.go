@@ -0,0 +1,193 @@
+package static
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Bounds on what static analysis reads from a tarball and keeps as evidence
+const (
+	maxScannedFileBytes = 4 << 20 // Larger files are hashed but not scanned
+	maxEvidenceFiles    = 10
+	excerptLines        = 12   // Lines kept from the first flagged one on
+	excerptLineBytes    = 240  // Longer lines are cut, as minified code is
+	maxExcerptBytes     = 2048 // Cap on the excerpt as a whole
+	longLineBytes       = 5000 // An install script line this long is packed
+	obfuscatedNameCount = 10   // _0x identifiers before a file counts as obfuscated
+)
+
+// installScripts are the lifecycle scripts npm runs on install
+var installScripts = []string{"preinstall", "install", "postinstall"}
+
+// FileEvidence is a file of a package's tarball flagged by static analysis,
+// with a bounded excerpt of the offending code so reviewers needn't unpack
+// the tarball themselves
+type FileEvidence struct {
+	Path      string   `json:"path"` // Relative to the package root
+	SHA256    string   `json:"sha256"`
+	Size      int64    `json:"size"`
+	Reasons   []string `json:"reasons"`
+	Line      int      `json:"line"` // First flagged line, 1-based
+	Excerpt   string   `json:"excerpt"`
+	Truncated bool     `json:"truncated,omitempty"`
+}
+
+// staticCheck flags a line of a file. installOnly checks only apply to the
+// files install scripts run, where packed code has no business being.
+type staticCheck struct {
+	reason      string
+	installOnly bool
+	match       func(line string) bool
+}
+
+var (
+	evalDecodedPattern  = regexp.MustCompile(`\b(?:eval|Function)\s*\(\s*(?:atob|Buffer\.from|unescape|decodeURIComponent|String\.fromCharCode)\s*\(`)
+	hexEscapePattern    = regexp.MustCompile(`(?:\\x[0-9a-fA-F]{2}){24,}`)
+	obfuscatedNameRegex = regexp.MustCompile(`\b_0x[0-9a-f]{4,6}\b`)
+)
+
+var staticChecks = []staticCheck{
+	{reason: "eval of decoded string", match: evalDecodedPattern.MatchString},
+	{reason: "long hex-escaped string", match: hexEscapePattern.MatchString},
+	{reason: "obfuscator identifiers", match: func(line string) bool {
+		return len(obfuscatedNameRegex.FindAllStringIndex(line, obfuscatedNameCount)) >= obfuscatedNameCount
+	}},
+	{reason: "packed line in install script", installOnly: true, match: func(line string) bool {
+		return len(line) >= longLineBytes
+	}},
+}
+
+// scannedFile is a script read from a tarball: its first
+// maxScannedFileBytes, and the size and hash of the whole file
+type scannedFile struct {
+	head   []byte
+	size   int64
+	sha256 string
+}
+
+// isScript reports whether a file name looks like code npm or node would run
+func isScript(name string) bool {
+	switch path.Ext(name) {
+	case ".js", ".cjs", ".mjs", ".sh":
+		return true
+	}
+	return false
+}
+
+// installEntryPoints returns the files the manifest's install scripts run,
+// e.g. scripts/setup.js for "node ./scripts/setup.js --quiet"
+func installEntryPoints(manifest []byte) map[string]bool {
+	scripts := manifestScripts(manifest)
+	if scripts == nil {
+		return nil
+	}
+	entries := make(map[string]bool)
+	for _, script := range installScripts {
+		for _, file := range commandFiles(scripts[script]) {
+			entries[file] = true
+		}
+	}
+	return entries
+}
+
+// manifestScripts returns the scripts of a package.json, nil if it has none
+// or can't be parsed
+func manifestScripts(manifest []byte) map[string]string {
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if len(manifest) == 0 || json.Unmarshal(manifest, &pkg) != nil {
+		return nil
+	}
+	return pkg.Scripts
+}
+
+// commandFiles returns the scripts a command line runs, e.g. scripts/setup.js
+// for "node ./scripts/setup.js --quiet"
+func commandFiles(command string) []string {
+	var files []string
+	for _, field := range strings.FieldsFunc(command, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ';' || r == '&' || r == '|' || r == '"' || r == '\''
+	}) {
+		if isScript(field) && !slices.Contains(files, path.Clean(field)) {
+			files = append(files, path.Clean(field))
+		}
+	}
+	return files
+}
+
+// scanFile runs the static checks over a file, returning nil if none flagged it
+func scanFile(name string, data []byte, install bool) *FileEvidence {
+	var e *FileEvidence
+	var lines []string
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, maxScannedFileBytes)
+	for n := 1; sc.Scan(); n++ {
+		line := sc.Text()
+		if e != nil {
+			if len(lines) < excerptLines {
+				lines = append(lines, line)
+			}
+		}
+		for _, check := range staticChecks {
+			if check.installOnly && !install {
+				continue
+			}
+			if !check.match(line) {
+				continue
+			}
+			if e == nil {
+				e = &FileEvidence{Path: name, Line: n}
+				lines = append(lines, line)
+			}
+			if !slices.Contains(e.Reasons, check.reason) {
+				e.Reasons = append(e.Reasons, check.reason)
+			}
+		}
+	}
+	if e == nil {
+		return nil
+	}
+	e.Excerpt, e.Truncated = excerpt(lines)
+	if sc.Err() != nil {
+		e.Truncated = true
+	}
+	return e
+}
+
+// excerpt joins lines into a bounded snippet, cutting long lines and the
+// snippet as a whole, and reports whether anything was cut
+func excerpt(lines []string) (string, bool) {
+	var sb strings.Builder
+	truncated := false
+	for _, line := range lines {
+		if len(line) > excerptLineBytes {
+			line = line[:excerptLineBytes] + "…"
+			truncated = true
+		}
+		if sb.Len()+len(line)+1 > maxExcerptBytes {
+			return sb.String(), true
+		}
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+	return sb.String(), truncated
+}
+
+// limitedBuffer keeps the first n bytes written to it and discards the rest
+type limitedBuffer struct {
+	buf *bytes.Buffer
+	n   int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if room := l.n - l.buf.Len(); room > 0 {
+		l.buf.Write(p[:min(room, len(p))])
+	}
+	return len(p), nil
+}
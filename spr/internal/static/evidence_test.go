@@ -0,0 +1,73 @@
+package static
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func staticTarball(t *testing.T, files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: "package/" + name, Mode: 0o644, Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestScanEvidence(t *testing.T) {
+	postinstall := "const os = require('os');\n" +
+		"eval(atob('Y29uc3QgaHR0cHM9cmVxdWlyZSgiaHR0cHMiKQ=='));\n" +
+		strings.Repeat("x();\n", 20)
+	packed := "var a=" + strings.Repeat("1+", 3000) + "1;\n"
+	tarball := staticTarball(t, map[string]string{
+		"package.json":     `{"name":"evil","scripts":{"postinstall":"node ./postinstall.js && node setup/packed.js"}}`,
+		"postinstall.js":   postinstall,
+		"setup/packed.js":  packed,
+		"dist/lib.min.js":  packed, // Minified, but not run on install
+		"lib/strings.js":   "module.exports = '" + strings.Repeat(`\x41`, 30) + "';\n",
+		"lib/index.js":     "module.exports = require('./strings');\n",
+		"README.md":        "eval(atob('...'))",
+		"test/fixtures.sh": "echo ok\n",
+	})
+
+	report, err := Scan(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	evidence := report.Evidence
+	require.Len(t, evidence, 3)
+
+	// Install script entry points come first
+	assert.Equal(t, "postinstall.js", evidence[0].Path)
+	assert.Equal(t, "setup/packed.js", evidence[1].Path)
+	assert.Equal(t, "lib/strings.js", evidence[2].Path)
+
+	sum := sha256.Sum256([]byte(postinstall))
+	assert.Equal(t, hex.EncodeToString(sum[:]), evidence[0].SHA256)
+	assert.Equal(t, int64(len(postinstall)), evidence[0].Size)
+	assert.Equal(t, []string{"eval of decoded string"}, evidence[0].Reasons)
+	assert.Equal(t, 2, evidence[0].Line)
+	assert.True(t, strings.HasPrefix(evidence[0].Excerpt, "eval(atob("))
+	assert.Equal(t, excerptLines, strings.Count(evidence[0].Excerpt, "\n"))
+	assert.False(t, evidence[0].Truncated)
+
+	assert.Equal(t, []string{"packed line in install script"}, evidence[1].Reasons)
+	assert.True(t, evidence[1].Truncated)
+	assert.LessOrEqual(t, len(evidence[1].Excerpt), maxExcerptBytes)
+
+	assert.Equal(t, []string{"long hex-escaped string"}, evidence[2].Reasons)
+
+	_, err = Scan(strings.NewReader("not a tarball"))
+	assert.Error(t, err)
+}
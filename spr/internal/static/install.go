@@ -0,0 +1,61 @@
+package static
+
+import "strings"
+
+// maxInstallFileBytes bounds the content kept of a file an install script runs
+const maxInstallFileBytes = 16 << 10
+
+// InstallScript is a lifecycle script npm runs when the package is installed
+type InstallScript struct {
+	Hook    string `json:"hook"` // preinstall, install or postinstall
+	Command string `json:"command"`
+	// Implicit marks npm's default install of a package with a binding.gyp
+	// and no install script of its own: "node-gyp rebuild"
+	Implicit bool         `json:"implicit,omitempty"`
+	Files    []ScriptFile `json:"files,omitempty"` // Package files the command runs
+}
+
+// ScriptFile is a file of the package an install script runs, with its
+// content so reviewers see exactly what is executed
+type ScriptFile struct {
+	Path      string `json:"path"` // Relative to the package root
+	SHA256    string `json:"sha256"`
+	Size      int64  `json:"size"`
+	Content   string `json:"content"`
+	Truncated bool   `json:"truncated,omitempty"` // Content is the first maxInstallFileBytes
+}
+
+// extractInstallScripts returns the install scripts of the manifest in the
+// order npm runs them, each with the package files it runs. bindingGyp says
+// whether the package has a binding.gyp, which npm builds by default.
+func extractInstallScripts(manifest []byte, scripts map[string]scannedFile, bindingGyp bool) []InstallScript {
+	commands := manifestScripts(manifest)
+	implicit := bindingGyp && commands["install"] == "" && commands["preinstall"] == ""
+
+	var extracted []InstallScript
+	for _, hook := range installScripts {
+		script := InstallScript{Hook: hook, Command: commands[hook]}
+		if hook == "install" && implicit {
+			script.Command, script.Implicit = "node-gyp rebuild", true
+		}
+		if strings.TrimSpace(script.Command) == "" {
+			continue
+		}
+		for _, name := range commandFiles(script.Command) {
+			f, ok := scripts[name]
+			if !ok {
+				continue
+			}
+			content := f.head[:min(len(f.head), maxInstallFileBytes)]
+			script.Files = append(script.Files, ScriptFile{
+				Path:      name,
+				SHA256:    f.sha256,
+				Size:      f.size,
+				Content:   strings.ToValidUTF8(string(content), "�"),
+				Truncated: f.size > int64(len(content)),
+			})
+		}
+		extracted = append(extracted, script)
+	}
+	return extracted
+}
@@ -0,0 +1,54 @@
+package static
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractInstallScripts(t *testing.T) {
+	setup := "require('child_process').exec('id');\n"
+	large := strings.Repeat("// padding\n", 2000)
+	tarball := staticTarball(t, map[string]string{
+		"package.json":      `{"name":"pkg","scripts":{"postinstall":"node ./scripts/setup.js && node scripts/large.js","preinstall":"echo hi","test":"node test.js"}}`,
+		"scripts/setup.js":  setup,
+		"scripts/large.js":  large,
+		"test.js":           "assert(true)\n",
+		"binding.gyp":       "{}",
+		"scripts/unused.js": "unused()\n",
+	})
+
+	report, err := Scan(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	require.Len(t, report.InstallScripts, 2, "binding.gyp isn't built with a preinstall script")
+
+	pre, post := report.InstallScripts[0], report.InstallScripts[1]
+	assert.Equal(t, InstallScript{Hook: "preinstall", Command: "echo hi"}, pre)
+	assert.Equal(t, "postinstall", post.Hook)
+	require.Len(t, post.Files, 2)
+	assert.Equal(t, "scripts/setup.js", post.Files[0].Path)
+	assert.Equal(t, setup, post.Files[0].Content)
+	assert.False(t, post.Files[0].Truncated)
+	assert.Equal(t, "scripts/large.js", post.Files[1].Path)
+	assert.Len(t, post.Files[1].Content, maxInstallFileBytes)
+	assert.Equal(t, int64(len(large)), post.Files[1].Size)
+	assert.True(t, post.Files[1].Truncated)
+}
+
+func TestExtractImplicitInstall(t *testing.T) {
+	tarball := staticTarball(t, map[string]string{
+		"package.json": `{"name":"native"}`,
+		"binding.gyp":  "{}",
+	})
+	report, err := Scan(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	assert.Equal(t, []InstallScript{{Hook: "install", Command: "node-gyp rebuild", Implicit: true}}, report.InstallScripts)
+
+	tarball = staticTarball(t, map[string]string{"package.json": `{"name":"plain","scripts":{"test":"jest"}}`})
+	report, err = Scan(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	assert.Empty(t, report.InstallScripts)
+}
@@ -0,0 +1,317 @@
+// Package static scans npm tarballs before they run: obfuscated JavaScript,
+// payloads in install scripts, native binaries and packages shipping only
+// minified code. Findings are weighed into a static risk score that is
+// reported alongside the behavioral verdict.
+//
+// Nothing is executed; a tarball is read once, keeping the head of each
+// script for the checks and hashing the rest.
+package static
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of static findings
+const (
+	KindObfuscated    = "obfuscated_code"
+	KindInstallScript = "install_script_payload"
+	KindBinary        = "binary_blob"
+	KindMinifiedOnly  = "minified_only"
+)
+
+// Weights are how strongly each kind of finding indicates a malicious
+// package. They combine like rule scores: 1 - Π(1 - weight) over the kinds
+// found.
+var Weights = map[string]float64{
+	KindObfuscated:    0.6,
+	KindInstallScript: 0.5,
+	KindBinary:        0.4,
+	KindMinifiedOnly:  0.2,
+}
+
+// Bounds on the binary and minified checks
+const (
+	sniffBytes        = 512  // Read from every file to recognize binaries
+	minMinifiedBytes  = 2048 // Smaller scripts don't tell minified from terse
+	minifiedLineBytes = 500  // Average line length of minified code
+	maxBinaryFindings = 10
+	maxScriptExcerpt  = 160 // Install script commands quoted in findings
+)
+
+// installPayloads are inline commands that have no business in an install
+// script of a library
+var installPayloads = []struct {
+	reason  string
+	pattern *regexp.Regexp
+}{
+	{"runs inline node code", regexp.MustCompile(`\bnode\s+(?:-e|--eval|-p|--print)\b`)},
+	{"downloads a file", regexp.MustCompile(`(?i)\b(?:curl|wget|Invoke-WebRequest|iwr)\b`)},
+	{"pipes into a shell", regexp.MustCompile(`\|\s*(?:sudo\s+)?(?:ba|z|da|k)?sh\b`)},
+	{"decodes base64", regexp.MustCompile(`(?i)base64\s+(?:-d|--decode|-D)\b|\batob\s*\(|Buffer\.from\([^)]*base64`)},
+	{"runs PowerShell", regexp.MustCompile(`(?i)\bpowershell\b`)},
+}
+
+// Finding is a static risk found in a tarball
+type Finding struct {
+	Kind   string `json:"kind"`
+	Path   string `json:"path,omitempty"` // Empty for findings about the whole package
+	Detail string `json:"detail"`
+}
+
+// Report is the static scan of a tarball
+type Report struct {
+	Score    float64        `json:"score"` // 0 (nothing found) to 1
+	Findings []Finding      `json:"findings,omitempty"`
+	Evidence []FileEvidence `json:"evidence,omitempty"` // Flagged scripts with excerpts
+
+	// InstallScripts are what npm runs when the package is installed
+	InstallScripts []InstallScript `json:"install_scripts,omitempty"`
+}
+
+// Kinds returns the distinct kinds of the report's findings, in order
+func (r *Report) Kinds() []string {
+	if r == nil {
+		return nil
+	}
+	var kinds []string
+	seen := make(map[string]bool)
+	for _, f := range r.Findings {
+		if !seen[f.Kind] {
+			seen[f.Kind] = true
+			kinds = append(kinds, f.Kind)
+		}
+	}
+	return kinds
+}
+
+// Scan reads a gzipped npm tarball and reports its static risks
+func Scan(r io.Reader) (*Report, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open tarball: %w", err)
+	}
+	defer gz.Close()
+
+	scripts := make(map[string]scannedFile)
+	var manifest []byte
+	var binaries []Finding
+	bindingGyp := false
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tarball: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		// npm strips the first path component, whatever it is called
+		_, name, ok := strings.Cut(path.Clean(hdr.Name), "/")
+		if !ok {
+			continue
+		}
+		if name == "package.json" {
+			if manifest, err = io.ReadAll(io.LimitReader(tr, maxScannedFileBytes)); err != nil {
+				return nil, fmt.Errorf("failed to read package.json: %w", err)
+			}
+			continue
+		}
+		if name == "binding.gyp" {
+			bindingGyp = true
+		}
+
+		limit := sniffBytes
+		if isScript(name) {
+			limit = maxScannedFileBytes
+		}
+		h := sha256.New()
+		var head bytes.Buffer
+		size, err := io.Copy(io.MultiWriter(h, &limitedBuffer{&head, limit}), tr)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if kind := binaryKind(name, head.Bytes()); kind != "" {
+			binaries = append(binaries, Finding{Kind: KindBinary, Path: name, Detail: fmt.Sprintf("%s, %d bytes", kind, size)})
+			continue
+		}
+		if isScript(name) {
+			scripts[name] = scannedFile{head: head.Bytes(), size: size, sha256: hex.EncodeToString(h.Sum(nil))}
+		}
+	}
+
+	report := &Report{InstallScripts: extractInstallScripts(manifest, scripts, bindingGyp)}
+	entries := installEntryPoints(manifest)
+	report.Findings = append(report.Findings, installScriptFindings(manifest)...)
+	report.Evidence = scanScripts(scripts, entries)
+	for _, e := range report.Evidence {
+		report.Findings = append(report.Findings, evidenceFindings(e, entries[e.Path])...)
+	}
+
+	sort.Slice(binaries, func(i, j int) bool { return binaries[i].Path < binaries[j].Path })
+	if len(binaries) > maxBinaryFindings {
+		more := len(binaries) - maxBinaryFindings
+		binaries = append(binaries[:maxBinaryFindings], Finding{Kind: KindBinary, Detail: fmt.Sprintf("%d more binary files", more)})
+	}
+	report.Findings = append(report.Findings, binaries...)
+
+	if n := minifiedOnly(scripts); n > 0 {
+		report.Findings = append(report.Findings, Finding{Kind: KindMinifiedOnly, Detail: fmt.Sprintf("all %d JavaScript files over 2 KB are minified, none is readable source", n)})
+	}
+
+	report.Score = score(report.Kinds())
+	return report, nil
+}
+
+// scanScripts runs the line checks over the scripts of a tarball and returns
+// the flagged ones, install script entry points first
+func scanScripts(scripts map[string]scannedFile, entries map[string]bool) []FileEvidence {
+	var evidence []FileEvidence
+	for name, f := range scripts {
+		e := scanFile(name, f.head, entries[name])
+		if e == nil {
+			continue
+		}
+		e.SHA256 = f.sha256
+		e.Size = f.size
+		evidence = append(evidence, *e)
+	}
+
+	sort.Slice(evidence, func(i, j int) bool {
+		a, b := evidence[i], evidence[j]
+		if entries[a.Path] != entries[b.Path] {
+			return entries[a.Path]
+		}
+		return a.Path < b.Path
+	})
+	if len(evidence) > maxEvidenceFiles {
+		evidence = evidence[:maxEvidenceFiles]
+	}
+	return evidence
+}
+
+// evidenceFindings turns a flagged script into findings: obfuscation
+// wherever it is, and a payload when an install script runs the file
+func evidenceFindings(e FileEvidence, install bool) []Finding {
+	var obfuscation []string
+	for _, reason := range e.Reasons {
+		if !installOnlyReason(reason) {
+			obfuscation = append(obfuscation, reason)
+		}
+	}
+	var findings []Finding
+	if len(obfuscation) > 0 {
+		findings = append(findings, Finding{Kind: KindObfuscated, Path: e.Path, Detail: strings.Join(obfuscation, ", ")})
+	}
+	if install {
+		findings = append(findings, Finding{Kind: KindInstallScript, Path: e.Path, Detail: "run on install: " + strings.Join(e.Reasons, ", ")})
+	}
+	return findings
+}
+
+// installOnlyReason reports whether reason comes from a check that only
+// applies to install scripts
+func installOnlyReason(reason string) bool {
+	for _, check := range staticChecks {
+		if check.reason == reason {
+			return check.installOnly
+		}
+	}
+	return false
+}
+
+// installScriptFindings flags install scripts of the manifest that carry
+// their payload inline rather than running a file of the package
+func installScriptFindings(manifest []byte) []Finding {
+	var findings []Finding
+	scripts := manifestScripts(manifest)
+	for _, script := range installScripts {
+		command := scripts[script]
+		var reasons []string
+		for _, payload := range installPayloads {
+			if payload.pattern.MatchString(command) {
+				reasons = append(reasons, payload.reason)
+			}
+		}
+		if len(reasons) == 0 {
+			continue
+		}
+		if len(command) > maxScriptExcerpt {
+			command = command[:maxScriptExcerpt] + "…"
+		}
+		findings = append(findings, Finding{
+			Kind:   KindInstallScript,
+			Path:   "package.json",
+			Detail: fmt.Sprintf("%s %s: %q", script, strings.Join(reasons, ", "), command),
+		})
+	}
+	return findings
+}
+
+// binaryKind names the binary format of a file from its first bytes:
+// native executables anywhere, and binary data where a script should be.
+// Other binaries, e.g. images and fonts, return "".
+func binaryKind(name string, head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, []byte("\x7fELF")):
+		return "ELF executable"
+	case isPE(head):
+		return "Windows executable"
+	case bytes.HasPrefix(head, []byte{0xcf, 0xfa, 0xed, 0xfe}), bytes.HasPrefix(head, []byte{0xce, 0xfa, 0xed, 0xfe}),
+		bytes.HasPrefix(head, []byte{0xca, 0xfe, 0xba, 0xbe}) && !strings.HasSuffix(name, ".class"):
+		return "Mach-O executable"
+	case isScript(name) && bytes.IndexByte(head[:min(len(head), sniffBytes)], 0) >= 0:
+		return "binary data in a script"
+	}
+	return ""
+}
+
+// isPE reports whether head starts a PE file: an MZ stub pointing at the PE
+// signature. "MZ" alone is too common a start for text.
+func isPE(head []byte) bool {
+	if len(head) < 0x40 || !bytes.HasPrefix(head, []byte("MZ")) {
+		return false
+	}
+	offset := int(binary.LittleEndian.Uint32(head[0x3c:]))
+	return offset+4 <= len(head) && bytes.Equal(head[offset:offset+4], []byte("PE\x00\x00"))
+}
+
+// minifiedOnly returns how many JavaScript files the package has if all of
+// them large enough to judge are minified, and 0 otherwise
+func minifiedOnly(scripts map[string]scannedFile) int {
+	judged := 0
+	for name, f := range scripts {
+		if path.Ext(name) == ".sh" || f.size < minMinifiedBytes {
+			continue
+		}
+		lines := bytes.Count(f.head, []byte("\n")) + 1
+		if len(f.head)/lines < minifiedLineBytes {
+			return 0
+		}
+		judged++
+	}
+	return judged
+}
+
+// score combines the weights of the kinds found
+func score(kinds []string) float64 {
+	clean := 1.0
+	for _, kind := range kinds {
+		clean *= 1 - Weights[kind]
+	}
+	return math.Round((1-clean)*100) / 100
+}
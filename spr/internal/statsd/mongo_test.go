@@ -0,0 +1,68 @@
+package statsd
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+func TestEventJSON(t *testing.T) {
+	doc, err := bson.Marshal(bson.D{
+		{Key: "_id", Value: bson.NewObjectID()},
+		{Key: "timestamp", Value: int64(1700000000123456789)},
+		{Key: "eventName", Value: "openat"},
+		{Key: "processId", Value: int32(7)},
+		{Key: "args", Value: bson.A{bson.D{{Key: "name", Value: "pathname"}, {Key: "value", Value: "/etc/passwd"}}}},
+	})
+	require.NoError(t, err)
+
+	line, err := eventJSON(doc)
+	require.NoError(t, err)
+	assert.Equal(t, `{"timestamp":1700000000123456789,"eventName":"openat","processId":7,"args":[{"name":"pathname","value":"/etc/passwd"}]}`, string(line))
+}
+
+func TestEventFilter(t *testing.T) {
+	assert.Equal(t, bson.D{
+		{Key: "eventName", Value: "execve"},
+		{Key: "args", Value: bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "name", Value: "pathname"}, {Key: "value", Value: "/usr/bin/curl"}}}}},
+	}, eventFilter(EventQuery{EventName: "execve", ArgName: "pathname", Value: "/usr/bin/curl"}))
+
+	dns := eventFilter(EventQuery{EventName: "net_packet_dns_request", ArgName: "dns_questions", Value: "example.com"})
+	assert.Equal(t, bson.D{{Key: "$elemMatch", Value: bson.D{{Key: "name", Value: "dns_questions"}, {Key: "value.query", Value: "example.com"}}}}, dns[1].Value)
+}
+
+// findingStore answers Find by scanning, recording the queries it got
+type findingStore struct {
+	*DirStore
+	queries []EventQuery
+}
+
+func (s *findingStore) Find(ctx context.Context, collection string, q EventQuery) (io.ReadCloser, error) {
+	s.queries = append(s.queries, q)
+	return s.Open(ctx, collection)
+}
+
+func TestServerSpecificUsesFinder(t *testing.T) {
+	dir, err := NewDirStore(t.TempDir())
+	require.NoError(t, err)
+	store := &findingStore{DirStore: dir}
+	server := httptest.NewServer(NewServer(store, "secret").Handler())
+	t.Cleanup(server.Close)
+
+	resp, _ := upload(t, server, "pkg_left_pad", "trace.jsonl")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body := request(t, http.MethodGet, server.URL+"/specific/pkg_left_pad?dns=example.com", "secret", nil, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body["specific"], "dns_calls")
+	assert.Equal(t, []EventQuery{{EventName: "net_packet_dns_request", ArgName: "dns_questions", Value: "example.com"}}, store.queries)
+
+	resp, _ = request(t, http.MethodGet, server.URL+"/specific/pkg_missing?file=/etc/passwd", "secret", nil, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
@@ -0,0 +1,350 @@
+package statsd
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+	"github.com/acheong08/hackeurope-spr/internal/logging"
+)
+
+// MaxUploadBytes caps the size of an uploaded trace
+const MaxUploadBytes = 1 << 30
+
+// defaultSpecificLimit is the page size of /specific without a limit
+const defaultSpecificLimit = 50
+
+// Server is the stats HTTP API
+type Server struct {
+	store  Store
+	token  string
+	logger *slog.Logger
+}
+
+// NewServer creates a stats API over store. Every endpoint but /health
+// requires "Authorization: Bearer <token>"; an empty token disables auth.
+func NewServer(store Store, token string) *Server {
+	return &Server{store: store, token: token, logger: slog.Default()}
+}
+
+// SetLogger sets the structured logger
+func (s *Server) SetLogger(logger *slog.Logger) {
+	s.logger = logger
+}
+
+// Handler returns the API's routes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	mux.Handle("GET /collections", s.auth(s.handleCollections))
+	mux.Handle("POST /upload-tracee", s.auth(s.handleUpload))
+	mux.Handle("GET /stats/{collection}", s.auth(s.handleStats))
+	mux.Handle("GET /stats_per_process/{collection}", s.auth(s.handleStatsPerProcess))
+	mux.Handle("GET /specific/{collection}", s.auth(s.handleSpecific))
+	return mux
+}
+
+func (s *Server) auth(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.token != "" {
+			token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(s.token)) != 1 {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeError(w, http.StatusUnauthorized, "invalid or missing bearer token")
+				return
+			}
+		}
+		next(w, r)
+	})
+}
+
+// writeJSON writes a JSON response
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error in the {"detail": ...} shape of the Python
+// service, which clients already parse
+func writeError(w http.ResponseWriter, status int, detail string) {
+	writeJSON(w, status, map[string]string{"detail": detail})
+}
+
+func (s *Server) handleCollections(w http.ResponseWriter, r *http.Request) {
+	names, err := s.store.Collections(r.Context())
+	if err != nil {
+		s.serverError(w, "list collections", err)
+		return
+	}
+	if names == nil {
+		names = []string{}
+	}
+	writeJSON(w, http.StatusOK, names)
+}
+
+func (s *Server) handleUpload(w http.ResponseWriter, r *http.Request) {
+	collection := r.URL.Query().Get("collection_name")
+	if !ValidCollection(collection) {
+		writeError(w, http.StatusBadRequest, "Invalid collection name")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, MaxUploadBytes)
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("missing file: %v", err))
+		return
+	}
+	defer file.Close()
+	switch {
+	case strings.HasSuffix(strings.ToLower(header.Filename), ".json"),
+		strings.HasSuffix(strings.ToLower(header.Filename), ".jsonl"),
+		strings.HasSuffix(strings.ToLower(header.Filename), ".log"):
+	default:
+		writeError(w, http.StatusBadRequest, "File must be .json, .jsonl, or .log")
+		return
+	}
+
+	inserted, err := s.store.Insert(r.Context(), collection, file)
+	if err != nil {
+		s.serverError(w, "insert events", err)
+		return
+	}
+	logging.Log(s.logger, fmt.Sprintf("Inserted %d events into %s", inserted, collection), "info", logging.KeyStage, "statsd")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":             "success",
+		"collection":         collection,
+		"documents_inserted": inserted,
+	})
+}
+
+func (s *Server) handleStats(w http.ResponseWriter, r *http.Request) {
+	collection := r.PathValue("collection")
+	events, ok := s.open(w, r, collection)
+	if !ok {
+		return
+	}
+	defer events.Close()
+
+	stats, err := aggregate.NewAggregator().ProcessReader(events, collection)
+	if err != nil {
+		s.serverError(w, "aggregate "+collection, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+func (s *Server) handleStatsPerProcess(w http.ResponseWriter, r *http.Request) {
+	collection := r.PathValue("collection")
+	limit, offset, ok := pagination(w, r, 0)
+	if !ok {
+		return
+	}
+	keyMode, err := aggregate.ParseKeyMode(r.URL.Query().Get("key"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	events, ok := s.open(w, r, collection)
+	if !ok {
+		return
+	}
+	defer events.Close()
+
+	aggregator := aggregate.NewProcessAggregator()
+	aggregator.SetKeyMode(keyMode)
+	stats, err := aggregator.ProcessReader(events, collection)
+	if err != nil {
+		s.serverError(w, "aggregate "+collection, err)
+		return
+	}
+
+	// Page through the processes in name order; limit 0 returns the rest
+	names := make([]string, 0, len(stats.PerProcess))
+	for name := range stats.PerProcess {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	names = names[min(offset, len(names)):]
+	if limit > 0 {
+		names = names[:min(limit, len(names))]
+	}
+	page := make(map[string]*aggregate.ProcessSummary, len(names))
+	for _, name := range names {
+		page[name] = stats.PerProcess[name]
+	}
+	stats.PerProcess = page
+	stats.CountProcesses = len(page)
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// specificEvent is a Tracee event as returned by /specific
+type specificEvent struct {
+	Process         string                `json:"process"`
+	ProcessID       int                   `json:"process_id"`
+	ParentProcessID int                   `json:"parent_process_id,omitempty"`
+	Executable      string                `json:"executable,omitempty"`
+	Timestamp       int64                 `json:"timestamp"`
+	Args            []aggregate.TraceeArg `json:"args"`
+}
+
+func (s *Server) handleSpecific(w http.ResponseWriter, r *http.Request) {
+	collection := r.PathValue("collection")
+	limit, offset, ok := pagination(w, r, defaultSpecificLimit)
+	if !ok {
+		return
+	}
+
+	// Each drill-down matches one event type on one argument, and is
+	// reported under its own key
+	query := r.URL.Query()
+	var eventName, argName, value, resultKey string
+	switch {
+	case query.Get("dns") != "":
+		eventName, argName, value, resultKey = "net_packet_dns_request", "dns_questions", query.Get("dns"), "dns_calls"
+	case query.Get("command") != "":
+		eventName, argName, value, resultKey = "execve", "pathname", query.Get("command"), "command_calls"
+	case query.Get("file") != "":
+		eventName, argName, value, resultKey = "openat", "pathname", query.Get("file"), "file_access"
+	default:
+		writeError(w, http.StatusBadRequest, "Provide one of: dns, command, or file query parameter")
+		return
+	}
+
+	events, ok := s.find(w, r, collection, EventQuery{EventName: eventName, ArgName: argName, Value: value})
+	if !ok {
+		return
+	}
+	defer events.Close()
+
+	results := []specificEvent{}
+	matched := 0
+	scanner := bufio.NewScanner(events)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	decoder := aggregate.NewEventDecoder()
+	for scanner.Scan() && (limit == 0 || len(results) < limit) {
+		event, err := decoder.Decode(scanner.Bytes())
+		if err != nil || event.EventName != eventName || !argMatches(event.Args, argName, value) {
+			continue
+		}
+		if matched++; matched <= offset {
+			continue
+		}
+		result := specificEvent{
+			Process:    event.ProcessName,
+			ProcessID:  event.ProcessID,
+			Executable: event.Executable.Path,
+			Timestamp:  event.Timestamp,
+			Args:       event.Args,
+		}
+		if eventName == "execve" {
+			result.ParentProcessID = event.ParentProcessID
+		}
+		results = append(results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		s.serverError(w, "read "+collection, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"specific": map[string]any{resultKey: map[string]any{value: results}},
+	})
+}
+
+// argMatches reports whether the named argument equals value; DNS questions
+// match when any of their queries does
+func argMatches(args []aggregate.TraceeArg, name, value string) bool {
+	for _, arg := range args {
+		if arg.Name != name {
+			continue
+		}
+		if name == "dns_questions" {
+			var questions []struct {
+				Query string `json:"query"`
+			}
+			if json.Unmarshal(arg.Value, &questions) == nil {
+				for _, q := range questions {
+					if q.Query == value {
+						return true
+					}
+				}
+			}
+			return false
+		}
+		var s string
+		return json.Unmarshal(arg.Value, &s) == nil && s == value
+	}
+	return false
+}
+
+// open opens a collection, writing the error response if it can't
+func (s *Server) open(w http.ResponseWriter, r *http.Request, collection string) (io.ReadCloser, bool) {
+	events, err := s.store.Open(r.Context(), collection)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Collection not found")
+		return nil, false
+	}
+	if err != nil {
+		s.serverError(w, "open "+collection, err)
+		return nil, false
+	}
+	return events, true
+}
+
+// find streams the events matching q, through the store's index if it has
+// one and by scanning the collection otherwise. Either way the caller still
+// filters, so both return the same results.
+func (s *Server) find(w http.ResponseWriter, r *http.Request, collection string, q EventQuery) (io.ReadCloser, bool) {
+	finder, ok := s.store.(EventFinder)
+	if !ok {
+		return s.open(w, r, collection)
+	}
+	events, err := finder.Find(r.Context(), collection, q)
+	if errors.Is(err, ErrNotFound) {
+		writeError(w, http.StatusNotFound, "Collection not found")
+		return nil, false
+	}
+	if err != nil {
+		s.serverError(w, "query "+collection, err)
+		return nil, false
+	}
+	return events, true
+}
+
+// pagination parses the limit and offset query parameters
+func pagination(w http.ResponseWriter, r *http.Request, defaultLimit int) (limit, offset int, ok bool) {
+	limit, offset = defaultLimit, 0
+	for _, param := range []struct {
+		name string
+		dst  *int
+	}{{"limit", &limit}, {"offset", &offset}} {
+		raw := r.URL.Query().Get(param.name)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s %q", param.name, raw))
+			return 0, 0, false
+		}
+		*param.dst = n
+	}
+	return limit, offset, true
+}
+
+func (s *Server) serverError(w http.ResponseWriter, action string, err error) {
+	logging.Log(s.logger, fmt.Sprintf("Failed to %s: %v", action, err), "error", logging.KeyStage, "statsd")
+	writeError(w, http.StatusInternalServerError, "internal error")
+}
@@ -0,0 +1,154 @@
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/v2/bson"
+)
+
+const testTrace = `{"eventName":"execve","processName":"node","processId":10,"parentProcessId":1,"timestamp":1,"args":[{"name":"pathname","type":"const char*","value":"/usr/bin/curl"}]}
+{"eventName":"execve","processName":"sh","processId":11,"parentProcessId":10,"timestamp":2,"args":[{"name":"pathname","type":"const char*","value":"/usr/bin/curl"}]}
+{"eventName":"openat","processName":"node","processId":10,"timestamp":3,"args":[{"name":"pathname","type":"const char*","value":"/etc/passwd"}]}
+not json
+`
+
+// newTestServer serves a fresh DirStore, or a fresh MongoDB database when
+// MONGO_URI is set so the same tests cover MongoStore
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	var store Store
+	if uri := os.Getenv("MONGO_URI"); uri != "" {
+		mongoStore, err := NewMongoStore(t.Context(), uri, "spr_test_"+bson.NewObjectID().Hex())
+		require.NoError(t, err)
+		t.Cleanup(func() {
+			mongoStore.db.Drop(context.Background())
+			mongoStore.Close(context.Background())
+		})
+		store = mongoStore
+	} else {
+		dirStore, err := NewDirStore(t.TempDir())
+		require.NoError(t, err)
+		store = dirStore
+	}
+	server := httptest.NewServer(NewServer(store, "secret").Handler())
+	t.Cleanup(server.Close)
+	return server
+}
+
+func request(t *testing.T, method, url, token string, body *bytes.Buffer, contentType string) (*http.Response, map[string]any) {
+	t.Helper()
+	if body == nil {
+		body = &bytes.Buffer{}
+	}
+	req, err := http.NewRequest(method, url, body)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	var decoded map[string]any
+	json.NewDecoder(resp.Body).Decode(&decoded)
+	return resp, decoded
+}
+
+func upload(t *testing.T, server *httptest.Server, collection, filename string) (*http.Response, map[string]any) {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", filename)
+	require.NoError(t, err)
+	part.Write([]byte(testTrace))
+	require.NoError(t, form.Close())
+	return request(t, http.MethodPost, server.URL+"/upload-tracee?collection_name="+collection, "secret", &body, form.FormDataContentType())
+}
+
+func TestServerAuth(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, _ := request(t, http.MethodGet, server.URL+"/health", "", nil, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body := request(t, http.MethodGet, server.URL+"/collections", "", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	assert.NotEmpty(t, body["detail"])
+	resp, _ = request(t, http.MethodGet, server.URL+"/collections", "wrong", nil, "")
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp, _ = request(t, http.MethodGet, server.URL+"/collections", "secret", nil, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServerUploadAndStats(t *testing.T) {
+	server := newTestServer(t)
+
+	resp, _ := upload(t, server, "bad-name", "trace.jsonl")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = upload(t, server, "pkg_left_pad", "trace.txt")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp, body := upload(t, server, "pkg_left_pad", "trace.jsonl")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 3, body["documents_inserted"])
+
+	resp, body = request(t, http.MethodGet, server.URL+"/stats/pkg_left_pad", "secret", nil, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 3, body["total_events"])
+
+	resp, _ = request(t, http.MethodGet, server.URL+"/stats/pkg_missing", "secret", nil, "")
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, _ = request(t, http.MethodGet, server.URL+"/collections", "secret", nil, "")
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServerStatsPerProcess(t *testing.T) {
+	server := newTestServer(t)
+	resp, _ := upload(t, server, "pkg_left_pad", "trace.jsonl")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	resp, body := request(t, http.MethodGet, server.URL+"/stats_per_process/pkg_left_pad?limit=1&offset=1", "secret", nil, "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.EqualValues(t, 1, body["count_processes"])
+	assert.Contains(t, body["per_process"], "sh")
+
+	resp, _ = request(t, http.MethodGet, server.URL+"/stats_per_process/pkg_left_pad?limit=-1", "secret", nil, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp, _ = request(t, http.MethodGet, server.URL+"/stats_per_process/pkg_left_pad?key=pid", "secret", nil, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestServerSpecific(t *testing.T) {
+	server := newTestServer(t)
+	resp, _ := upload(t, server, "pkg_left_pad", "trace.jsonl")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	calls := func(query, key, value string) []any {
+		resp, body := request(t, http.MethodGet, server.URL+"/specific/pkg_left_pad?"+query, "secret", nil, "")
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		return body["specific"].(map[string]any)[key].(map[string]any)[value].([]any)
+	}
+
+	assert.Len(t, calls("command=/usr/bin/curl", "command_calls", "/usr/bin/curl"), 2)
+	page := calls("command=/usr/bin/curl&limit=1&offset=1", "command_calls", "/usr/bin/curl")
+	require.Len(t, page, 1)
+	assert.Equal(t, "sh", page[0].(map[string]any)["process"])
+	assert.EqualValues(t, 10, page[0].(map[string]any)["parent_process_id"])
+	assert.Len(t, calls("file=/etc/passwd", "file_access", "/etc/passwd"), 1)
+	assert.Empty(t, calls("dns=example.com", "dns_calls", "example.com"))
+
+	resp, _ = request(t, http.MethodGet, server.URL+"/specific/pkg_left_pad", "secret", nil, "")
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -0,0 +1,140 @@
+// Package statsd serves aggregated stats and drill-downs over stored Tracee
+// traces, the HTTP API the analysis agent's stats tools query (see
+// analysis.StatsSource). It is the Go port of mongo/main.py.
+package statsd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/acheong08/hackeurope-spr/internal/aggregate"
+)
+
+// ErrNotFound is returned for collections that don't exist
+var ErrNotFound = errors.New("collection not found")
+
+// collectionPattern restricts collection names to identifiers, as the Python
+// service did; they double as file names
+var collectionPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ValidCollection reports whether name is an acceptable collection name
+func ValidCollection(name string) bool {
+	return collectionPattern.MatchString(name)
+}
+
+// Store holds collections of Tracee events, one collection per trace
+type Store interface {
+	// Insert appends the JSON events read from r, one per line, to a
+	// collection, creating it if needed. Lines that aren't JSON objects are
+	// skipped. It returns the number of events inserted.
+	Insert(ctx context.Context, collection string, r io.Reader) (int, error)
+	// Open streams the events of a collection as JSON lines, or returns
+	// ErrNotFound
+	Open(ctx context.Context, collection string) (io.ReadCloser, error)
+	// Collections lists the collection names, sorted
+	Collections(ctx context.Context) ([]string, error)
+}
+
+// EventQuery selects the events of one type whose named argument has a
+// value. For dns_questions, the value matches any question's query.
+type EventQuery struct {
+	EventName string
+	ArgName   string
+	Value     string
+}
+
+// EventFinder is implemented by stores that can look events up by index
+// instead of streaming the whole collection. /specific uses it when the
+// store supports it.
+type EventFinder interface {
+	// Find streams the matching events of a collection as JSON lines in
+	// trace order, or returns ErrNotFound
+	Find(ctx context.Context, collection string, q EventQuery) (io.ReadCloser, error)
+}
+
+// DirStore keeps each collection as a JSONL file in a directory
+type DirStore struct {
+	dir string
+	mu  sync.Mutex // Serializes inserts
+}
+
+// NewDirStore creates a store in dir, creating the directory if needed
+func NewDirStore(dir string) (*DirStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	return &DirStore{dir: dir}, nil
+}
+
+func (s *DirStore) path(collection string) string {
+	return filepath.Join(s.dir, collection+".jsonl")
+}
+
+func (s *DirStore) Insert(ctx context.Context, collection string, r io.Reader) (int, error) {
+	if !ValidCollection(collection) {
+		return 0, fmt.Errorf("invalid collection name %q", collection)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path(collection), os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open collection: %w", err)
+	}
+	defer f.Close()
+
+	result, err := aggregate.Ingest(ctx, r, fileWriter{f}, collection, aggregate.DefaultIngestBatch)
+	return result.Events, err
+}
+
+// fileWriter appends each batch of events to a collection file in one write
+type fileWriter struct {
+	f *os.File
+}
+
+func (w fileWriter) WriteEvents(_ context.Context, _ string, events []json.RawMessage) error {
+	var buf bytes.Buffer
+	for _, event := range events {
+		buf.Write(event)
+		buf.WriteByte('\n')
+	}
+	_, err := w.f.Write(buf.Bytes())
+	return err
+}
+
+func (s *DirStore) Open(_ context.Context, collection string) (io.ReadCloser, error) {
+	if !ValidCollection(collection) {
+		return nil, ErrNotFound
+	}
+	f, err := os.Open(s.path(collection))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *DirStore) Collections(context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".jsonl"); ok && !entry.IsDir() && ValidCollection(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,46 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReportTime(t *testing.T) {
+	report := NewReport()
+	report.Time(StageWorkflows, 1234*time.Millisecond)
+	assert.Equal(t, 1.2, report.StageSeconds[StageWorkflows])
+	assert.Equal(t, SchemaVersion, report.Schema)
+}
+
+func TestClientSend(t *testing.T) {
+	var received Report
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	report := NewReport()
+	report.Packages = 3
+	report.Verdicts["malicious"] = 1
+	require.NoError(t, NewClient(server.URL).Send(context.Background(), report))
+	assert.Equal(t, 3, received.Packages)
+	assert.Equal(t, 1, received.Verdicts["malicious"])
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "slow down", http.StatusTooManyRequests)
+	}))
+	defer failing.Close()
+	err := NewClient(failing.URL).Send(context.Background(), report)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "slow down")
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 	"time"
 )
 
@@ -224,20 +223,3 @@ func (d *Detector) GetPackageJSONType(info *PackageInfo) string {
 		return "commonjs"
 	}
 }
-
-// normalizePackageName normalizes package name for URLs
-func normalizePackageName(name string) string {
-	// Replace @scope/name with scope__name for Gitea URLs
-	if strings.HasPrefix(name, "@") {
-		parts := strings.SplitN(name, "/", 2)
-		if len(parts) == 2 {
-			return parts[0][1:] + "__" + parts[1]
-		}
-	}
-	return name
-}
-
-// NormalizePackageName normalizes package name for file paths
-func NormalizePackageName(name string) string {
-	return normalizePackageName(name)
-}
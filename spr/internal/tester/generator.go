@@ -7,6 +7,8 @@ import (
 	"path/filepath"
 	"strings"
 	"text/template"
+
+	"github.com/acheong08/hackeurope-spr/internal/naming"
 )
 
 // PackageJSON represents the structure of a package.json file
@@ -42,6 +44,7 @@ type Generator struct {
 	registryURL   string
 	registryOwner string
 	registryToken string
+	tests         []string // Tests to generate — nil generates all
 }
 
 // NewGenerator creates a new test package generator
@@ -71,9 +74,7 @@ func (g *Generator) GenerateAll(name, version, outputDir string) ([]string, erro
 		return nil, fmt.Errorf("failed to detect package: %w", err)
 	}
 
-	// Create normalized directory name
-	normalizedName := NormalizePackageName(name)
-	pkgDir := filepath.Join(outputDir, fmt.Sprintf("%s@%s", normalizedName, version))
+	pkgDir := filepath.Join(outputDir, naming.DirName(name, version))
 
 	// Generate each test type
 	var generatedDirs []string
@@ -85,22 +86,34 @@ func (g *Generator) GenerateAll(name, version, outputDir string) ([]string, erro
 	}
 	generatedDirs = append(generatedDirs, installDir)
 
-	// 2. Import test (always generated)
-	importDir := filepath.Join(pkgDir, "import")
-	if err := g.generateImportTest(info, importDir); err != nil {
-		return nil, fmt.Errorf("failed to generate import test: %w", err)
+	// 2. Import test (unless deselected)
+	if g.generates(TestImport) {
+		importDir := filepath.Join(pkgDir, "import")
+		if err := g.generateImportTest(info, importDir); err != nil {
+			return nil, fmt.Errorf("failed to generate import test: %w", err)
+		}
+		generatedDirs = append(generatedDirs, importDir)
 	}
-	generatedDirs = append(generatedDirs, importDir)
 
-	// 3. Prototype pollution test (always generated)
-	protoDir := filepath.Join(pkgDir, "prototype")
-	if err := g.generatePrototypeTest(info, protoDir); err != nil {
-		return nil, fmt.Errorf("failed to generate prototype test: %w", err)
+	// 3. Prototype pollution test (unless deselected)
+	if g.generates(TestPrototype) {
+		protoDir := filepath.Join(pkgDir, "prototype")
+		if err := g.generatePrototypeTest(info, protoDir); err != nil {
+			return nil, fmt.Errorf("failed to generate prototype test: %w", err)
+		}
+		generatedDirs = append(generatedDirs, protoDir)
 	}
-	generatedDirs = append(generatedDirs, protoDir)
 
-	// 4. CLI test (only if package has bin entries)
-	if info.HasBin {
+	// 4. Long-duration observation test (always generated, only run when the
+	// workflow is dispatched with observe_minutes > 0)
+	observeDir := filepath.Join(pkgDir, "observe")
+	if err := g.generateObserveTest(info, observeDir); err != nil {
+		return nil, fmt.Errorf("failed to generate observe test: %w", err)
+	}
+	generatedDirs = append(generatedDirs, observeDir)
+
+	// 5. CLI test (only if package has bin entries, unless deselected)
+	if info.HasBin && g.generates(TestCLI) {
 		cliDir := filepath.Join(pkgDir, "cli")
 		if err := g.generateCLITest(info, cliDir); err != nil {
 			return nil, fmt.Errorf("failed to generate CLI test: %w", err)
@@ -108,13 +121,22 @@ func (g *Generator) GenerateAll(name, version, outputDir string) ([]string, erro
 		generatedDirs = append(generatedDirs, cliDir)
 	}
 
+	// 6. npx test (only if package has bin entries, unless deselected)
+	if info.HasBin && g.generates(TestNpx) {
+		npxDir := filepath.Join(pkgDir, "npx")
+		if err := g.generateNpxTest(info, npxDir); err != nil {
+			return nil, fmt.Errorf("failed to generate npx test: %w", err)
+		}
+		generatedDirs = append(generatedDirs, npxDir)
+	}
+
 	return generatedDirs, nil
 }
 
 // generateInstallTest creates the install-time test package
 func (g *Generator) generateInstallTest(info *PackageInfo, outputDir string) error {
 	data := TestPackage{
-		Name:           fmt.Sprintf("test-install-%s", NormalizePackageName(info.Name)),
+		Name:           fmt.Sprintf("test-install-%s", naming.FileSafe(info.Name)),
 		Version:        "1.0.0",
 		PackageName:    info.Name,
 		PackageVersion: info.Version,
@@ -138,7 +160,7 @@ func (g *Generator) generateInstallTest(info *PackageInfo, outputDir string) err
 // generateImportTest creates the import-time test package
 func (g *Generator) generateImportTest(info *PackageInfo, outputDir string) error {
 	data := TestPackage{
-		Name:            fmt.Sprintf("test-import-%s", NormalizePackageName(info.Name)),
+		Name:            fmt.Sprintf("test-import-%s", naming.FileSafe(info.Name)),
 		Version:         "1.0.0",
 		PackageName:     info.Name,
 		PackageVersion:  info.Version,
@@ -163,7 +185,7 @@ func (g *Generator) generateImportTest(info *PackageInfo, outputDir string) erro
 // generatePrototypeTest creates the prototype pollution test package
 func (g *Generator) generatePrototypeTest(info *PackageInfo, outputDir string) error {
 	data := TestPackage{
-		Name:            fmt.Sprintf("test-prototype-%s", NormalizePackageName(info.Name)),
+		Name:            fmt.Sprintf("test-prototype-%s", naming.FileSafe(info.Name)),
 		Version:         "1.0.0",
 		PackageName:     info.Name,
 		PackageVersion:  info.Version,
@@ -185,6 +207,31 @@ func (g *Generator) generatePrototypeTest(info *PackageInfo, outputDir string) e
 	return g.generateTestPackage("prototype-test", data, outputDir, pkgJSON, nil)
 }
 
+// generateObserveTest creates the long-duration observation test package
+func (g *Generator) generateObserveTest(info *PackageInfo, outputDir string) error {
+	data := TestPackage{
+		Name:            fmt.Sprintf("test-observe-%s", naming.FileSafe(info.Name)),
+		Version:         "1.0.0",
+		PackageName:     info.Name,
+		PackageVersion:  info.Version,
+		ModuleType:      g.detector.GetPackageJSONType(info),
+		ImportStatement: g.detector.GetImportStatement(info),
+		OutputDir:       outputDir,
+	}
+
+	// Generate package.json using proper JSON encoding
+	pkgJSON := PackageJSON{
+		Name:         data.Name,
+		Version:      data.Version,
+		Description:  fmt.Sprintf("Long-duration observation test for %s@%s", info.Name, info.Version),
+		Private:      true,
+		Type:         data.ModuleType,
+		Dependencies: map[string]string{info.Name: info.Version},
+	}
+
+	return g.generateTestPackage("observe-test", data, outputDir, pkgJSON, nil)
+}
+
 // generateCLITest creates a marker for CLI test (uses npx in workflow)
 func (g *Generator) generateCLITest(info *PackageInfo, outputDir string) error {
 	// Create directory as marker - actual test uses npx in workflow
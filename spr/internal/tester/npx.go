@@ -0,0 +1,101 @@
+package tester
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// NpxScript is the script the npx test runs in the sandbox, also the marker
+// attributing its processes to the npx phase
+const NpxScript = "run-npx.sh"
+
+// NpxTimeout bounds each npx invocation, as one-shot CLIs waiting on a
+// prompt would otherwise hang the test
+const NpxTimeout = "60s"
+
+// npxProjectName is the project scaffolding tools are asked to create
+const npxProjectName = "my-app"
+
+// IsScaffolder reports whether a package is a create-* scaffolding tool,
+// run as `npx create-something` or `npm init something`, e.g.
+// create-react-app, @vitejs/create-app or @angular/create
+func IsScaffolder(name string) bool {
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return name == "create" || strings.HasPrefix(name, "create-")
+}
+
+// NpxFixtures returns the argument sets the npx test invokes the package's
+// binary with: the usual probes, and for scaffolding tools a project to
+// create, as their users run them
+func NpxFixtures(info *PackageInfo) [][]string {
+	fixtures := [][]string{{"--version"}, {"--help"}, {}}
+	if IsScaffolder(info.Name) {
+		fixtures = append(fixtures,
+			[]string{npxProjectName},
+			[]string{npxProjectName, "--yes"},
+		)
+	}
+	return fixtures
+}
+
+// npxBinary returns the binary npx runs: the one named like the package, as
+// npx picks it, otherwise the first by name
+func npxBinary(info *PackageInfo) string {
+	bare := info.Name
+	if i := strings.LastIndex(bare, "/"); i >= 0 {
+		bare = bare[i+1:]
+	}
+	if len(info.Bin) == 0 {
+		return bare
+	}
+	names := make([]string, 0, len(info.Bin))
+	for name := range info.Bin {
+		if name == bare || name == "default" {
+			return bare
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names[0]
+}
+
+// generateNpxTest writes the npx test script: each fixture runs the binary
+// through npx in its own empty directory, non-interactively and with CI set,
+// the way one-shot CLIs are invoked rather than installed
+func (g *Generator) generateNpxTest(info *PackageInfo, outputDir string) error {
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return fmt.Errorf("failed to create npx test directory: %w", err)
+	}
+
+	spec := info.Name + "@" + info.Version
+	var b strings.Builder
+	fmt.Fprintf(&b, "#!/bin/sh\n# npx test for %s\n", spec)
+	b.WriteString("export CI=true\nn=0\nrun() {\n")
+	b.WriteString("  n=$((n + 1))\n  mkdir -p \"/npx-work/$n\" && cd \"/npx-work/$n\" || return\n")
+	fmt.Fprintf(&b, "  echo \"=== npx %s $*\"\n", spec)
+	fmt.Fprintf(&b, "  timeout %s npx --yes --package=%s -- %s \"$@\" < /dev/null || echo \"exit code $?\"\n",
+		NpxTimeout, shellQuote(spec), shellQuote(npxBinary(info)))
+	b.WriteString("}\n")
+	for _, args := range NpxFixtures(info) {
+		b.WriteString("run")
+		for _, arg := range args {
+			b.WriteString(" " + shellQuote(arg))
+		}
+		b.WriteString("\n")
+	}
+
+	if err := os.WriteFile(filepath.Join(outputDir, NpxScript), []byte(b.String()), 0755); err != nil {
+		return fmt.Errorf("failed to write npx test script: %w", err)
+	}
+	return nil
+}
+
+// shellQuote quotes s for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
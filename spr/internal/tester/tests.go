@@ -0,0 +1,85 @@
+package tester
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Behavioral test variants a workflow run can be limited to. The
+// long-duration observation test isn't among them: it runs whenever
+// observation is enabled.
+const (
+	TestInstall   = "install"
+	TestImport    = "import"
+	TestPrototype = "prototype"
+	TestCLI       = "cli" // Only generated for packages with bin entries
+	TestNpx       = "npx" // As TestCLI, runs the binary one-shot with NpxFixtures
+)
+
+// AllTests lists every selectable test, in the order they run
+var AllTests = []string{TestInstall, TestImport, TestPrototype, TestCLI, TestNpx}
+
+// ParseTests parses a comma-separated selection of tests such as
+// "install,import". Empty selects AllTests. The install test is always
+// included, as the others run against the package it installs. The result
+// is in run order.
+func ParseTests(spec string) ([]string, error) {
+	if strings.TrimSpace(spec) == "" {
+		return AllTests, nil
+	}
+	selected := map[string]bool{TestInstall: true}
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		if !isTest(name) {
+			return nil, fmt.Errorf("unknown test %q (expected %s)", name, strings.Join(AllTests, ", "))
+		}
+		selected[name] = true
+	}
+
+	var tests []string
+	for _, name := range AllTests {
+		if selected[name] {
+			tests = append(tests, name)
+		}
+	}
+	return tests, nil
+}
+
+// TestsLabel names a selection in artifact names, e.g. "install+import".
+// Selecting every test gives an empty label, so full runs keep their names.
+func TestsLabel(tests []string) string {
+	if len(tests) == 0 || len(tests) == len(AllTests) {
+		return ""
+	}
+	return strings.Join(tests, "+")
+}
+
+func isTest(name string) bool {
+	for _, test := range AllTests {
+		if test == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SetTests limits GenerateAll to the given tests; nil generates all of them
+func (g *Generator) SetTests(tests []string) {
+	g.tests = tests
+}
+
+// generates reports whether GenerateAll should produce the named test
+func (g *Generator) generates(name string) bool {
+	if g.tests == nil {
+		return true
+	}
+	for _, test := range g.tests {
+		if test == name {
+			return true
+		}
+	}
+	return false
+}
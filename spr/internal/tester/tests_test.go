@@ -0,0 +1,93 @@
+package tester
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTests(t *testing.T) {
+	tests, err := ParseTests("")
+	require.NoError(t, err)
+	assert.Equal(t, AllTests, tests)
+
+	// Install is always included and the result is in run order
+	tests, err = ParseTests(" CLI, import ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{TestInstall, TestImport, TestCLI}, tests)
+
+	tests, err = ParseTests("npx")
+	require.NoError(t, err)
+	assert.Equal(t, []string{TestInstall, TestNpx}, tests)
+
+	_, err = ParseTests("install,fuzz")
+	assert.Error(t, err)
+}
+
+func TestTestsLabel(t *testing.T) {
+	assert.Equal(t, "", TestsLabel(nil))
+	assert.Equal(t, "", TestsLabel(AllTests))
+	assert.Equal(t, "install+import", TestsLabel([]string{TestInstall, TestImport}))
+}
+
+func TestGeneratorGenerates(t *testing.T) {
+	g := NewGenerator("templates")
+	assert.True(t, g.generates(TestPrototype))
+
+	g.SetTests([]string{TestInstall, TestImport})
+	assert.True(t, g.generates(TestImport))
+	assert.False(t, g.generates(TestPrototype))
+	assert.False(t, g.generates(TestCLI))
+}
+
+func TestIsScaffolder(t *testing.T) {
+	for _, name := range []string{"create-react-app", "@vitejs/create-app", "@angular/create"} {
+		assert.True(t, IsScaffolder(name), name)
+	}
+	for _, name := range []string{"lodash", "recreate-x", "@create/utils", "creator"} {
+		assert.False(t, IsScaffolder(name), name)
+	}
+}
+
+func TestGenerateNpxTest(t *testing.T) {
+	info := &PackageInfo{Name: "@evil/create-app", Version: "1.0.0", Bin: map[string]string{"create-app": "bin.js"}, HasBin: true}
+	assert.Contains(t, NpxFixtures(info), []string{"my-app"})
+	assert.NotContains(t, NpxFixtures(&PackageInfo{Name: "prettier"}), []string{"my-app"})
+
+	dir := t.TempDir()
+	require.NoError(t, NewGenerator("templates").generateNpxTest(info, dir))
+	script, err := os.ReadFile(filepath.Join(dir, NpxScript))
+	require.NoError(t, err)
+	assert.Contains(t, string(script), "npx --yes --package='@evil/create-app@1.0.0' -- 'create-app' \"$@\" < /dev/null")
+	assert.Contains(t, string(script), "\nrun 'my-app' '--yes'\n")
+	assert.Contains(t, string(script), "\nrun\n")
+}
+
+func TestGenerateObserveTest(t *testing.T) {
+	for _, tt := range []struct {
+		moduleType PackageType
+		imports    string
+	}{
+		{TypeCommonJS, "const target = require('time-bomb');"},
+		{TypeESM, "const target = await import('time-bomb');"},
+	} {
+		t.Run(string(tt.moduleType), func(t *testing.T) {
+			info := &PackageInfo{Name: "time-bomb", Version: "1.2.3", Type: tt.moduleType}
+			dir := t.TempDir()
+			require.NoError(t, NewGenerator(filepath.Join("..", "..", "templates")).generateObserveTest(info, dir))
+
+			script, err := os.ReadFile(filepath.Join(dir, "observe.js"))
+			require.NoError(t, err)
+			assert.Contains(t, string(script), "Target: time-bomb@1.2.3")
+			assert.Contains(t, string(script), tt.imports)
+			assert.Contains(t, string(script), "process.env.OBSERVE_MINUTES")
+
+			pkgJSON, err := os.ReadFile(filepath.Join(dir, "package.json"))
+			require.NoError(t, err)
+			assert.Contains(t, string(pkgJSON), `"time-bomb": "1.2.3"`)
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package ticketing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxIssueBody stays under GitHub's 65536 character limit on issue bodies
+const maxIssueBody = 60000
+
+// GitHub files tickets as GitHub issues. Issues can't carry attachments
+// through the API, so evidence is inlined in collapsed sections, truncated
+// to fit the body limit.
+type GitHub struct {
+	BaseURL    string // API root, https://api.github.com by default
+	Owner      string
+	Repo       string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewGitHub creates a tracker filing issues in owner/repo
+func NewGitHub(owner, repo, token string) *GitHub {
+	return &GitHub{
+		BaseURL:    "https://api.github.com",
+		Owner:      owner,
+		Repo:       repo,
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// FindOpen searches the repository's open issues by title
+func (g *GitHub) FindOpen(ctx context.Context, title string) (string, error) {
+	query := fmt.Sprintf("repo:%s/%s is:issue is:open in:title %q", g.Owner, g.Repo, title)
+	var result struct {
+		Items []struct {
+			Title   string `json:"title"`
+			HTMLURL string `json:"html_url"`
+		} `json:"items"`
+	}
+	if err := g.do(ctx, http.MethodGet, "/search/issues?q="+url.QueryEscape(query), nil, &result); err != nil {
+		return "", err
+	}
+	// Search matches words, not the exact title
+	for _, item := range result.Items {
+		if item.Title == title {
+			return item.HTMLURL, nil
+		}
+	}
+	return "", nil
+}
+
+// Create opens an issue with the evidence inlined and returns its URL
+func (g *GitHub) Create(ctx context.Context, title, body string, labels []string, evidence []Attachment) (string, error) {
+	payload := map[string]any{
+		"title": title,
+		"body":  inlineEvidence(body, evidence),
+	}
+	if len(labels) > 0 {
+		payload["labels"] = labels
+	}
+	var issue struct {
+		HTMLURL string `json:"html_url"`
+	}
+	if err := g.do(ctx, http.MethodPost, fmt.Sprintf("/repos/%s/%s/issues", g.Owner, g.Repo), payload, &issue); err != nil {
+		return "", err
+	}
+	return issue.HTMLURL, nil
+}
+
+// inlineEvidence appends each attachment to body in a collapsed code block,
+// truncating them to keep the body under maxIssueBody
+func inlineEvidence(body string, evidence []Attachment) string {
+	var b strings.Builder
+	b.WriteString(body)
+	for i, a := range evidence {
+		// Share what's left equally between the remaining attachments
+		budget := (maxIssueBody - b.Len()) / (len(evidence) - i)
+		const wrapper = len("\n<details><summary></summary>\n\n```\n\n```\n</details>\n") + len("\n… truncated")
+		data := strings.ReplaceAll(string(a.Data), "```", "` ` `")
+		if room := budget - wrapper - len(a.Name); len(data) > room {
+			if room <= 0 {
+				continue
+			}
+			data = data[:room] + "\n… truncated"
+		}
+		fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n```\n%s\n```\n</details>\n", a.Name, data)
+	}
+	return b.String()
+}
+
+// do sends a GitHub API request, decoding the response into out
+func (g *GitHub) do(ctx context.Context, method, path string, payload, out any) error {
+	var body io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(g.BaseURL, "/")+path, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+g.Token)
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := g.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GitHub API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,156 @@
+package ticketing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Jira files tickets as Jira issues, with evidence uploaded as attachments
+type Jira struct {
+	BaseURL    string
+	User       string // Basic auth with Token when set, else Token is a bearer token
+	Token      string
+	Project    string
+	IssueType  string
+	HTTPClient *http.Client
+}
+
+// NewJira creates a tracker filing issues of issueType (Bug if empty) in
+// project
+func NewJira(baseURL, user, token, project, issueType string) *Jira {
+	if issueType == "" {
+		issueType = "Bug"
+	}
+	return &Jira{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		User:       user,
+		Token:      token,
+		Project:    project,
+		IssueType:  issueType,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// FindOpen searches the project's unresolved issues by summary
+func (j *Jira) FindOpen(ctx context.Context, title string) (string, error) {
+	jql := fmt.Sprintf(`project = %s AND statusCategory != Done AND summary ~ %s`, jqlString(j.Project), jqlString(jqlString(title)))
+	payload := map[string]any{"jql": jql, "fields": []string{"summary"}, "maxResults": 50}
+	var result struct {
+		Issues []struct {
+			Key    string `json:"key"`
+			Fields struct {
+				Summary string `json:"summary"`
+			} `json:"fields"`
+		} `json:"issues"`
+	}
+	if err := j.doJSON(ctx, "/rest/api/2/search", payload, &result); err != nil {
+		return "", err
+	}
+	// ~ is a text search, not an exact match
+	for _, issue := range result.Issues {
+		if issue.Fields.Summary == title {
+			return issue.Key, nil
+		}
+	}
+	return "", nil
+}
+
+// jqlString quotes s as a JQL string literal. Quoting a quoted title makes
+// ~ search for the phrase rather than any of its words.
+func jqlString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+// Create opens an issue, attaches the evidence and returns the issue key
+func (j *Jira) Create(ctx context.Context, title, body string, labels []string, evidence []Attachment) (string, error) {
+	fields := map[string]any{
+		"project":     map[string]string{"key": j.Project},
+		"summary":     title,
+		"description": body,
+		"issuetype":   map[string]string{"name": j.IssueType},
+	}
+	if len(labels) > 0 {
+		fields["labels"] = labels
+	}
+	var issue struct {
+		Key string `json:"key"`
+	}
+	if err := j.doJSON(ctx, "/rest/api/2/issue", map[string]any{"fields": fields}, &issue); err != nil {
+		return "", err
+	}
+
+	for _, a := range evidence {
+		if err := j.attach(ctx, issue.Key, a); err != nil {
+			return issue.Key, fmt.Errorf("created %s but failed to attach %s: %w", issue.Key, a.Name, err)
+		}
+	}
+	return issue.Key, nil
+}
+
+// attach uploads an attachment to an issue
+func (j *Jira) attach(ctx context.Context, key string, a Attachment) error {
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreateFormFile("file", a.Name)
+	if err != nil {
+		return err
+	}
+	part.Write(a.Data)
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.BaseURL+"/rest/api/2/issue/"+key+"/attachments", &body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", w.FormDataContentType())
+	req.Header.Set("X-Atlassian-Token", "no-check")
+	return j.send(req, nil)
+}
+
+// doJSON POSTs payload as JSON, decoding the response into out
+func (j *Jira) doJSON(ctx context.Context, path string, payload, out any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, j.BaseURL+path, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return j.send(req, out)
+}
+
+// send authenticates and sends a request, decoding the response into out
+// unless it is nil
+func (j *Jira) send(req *http.Request, out any) error {
+	req.Header.Set("Accept", "application/json")
+	if j.User != "" {
+		req.SetBasicAuth(j.User, j.Token)
+	} else {
+		req.Header.Set("Authorization", "Bearer "+j.Token)
+	}
+
+	resp, err := j.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Jira API returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
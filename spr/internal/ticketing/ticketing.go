@@ -0,0 +1,178 @@
+// Package ticketing opens a ticket per flagged package in GitHub Issues or
+// Jira, with the analysis evidence attached, unless one is already open for
+// the same package@version.
+package ticketing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Tracker kinds
+const (
+	KindGitHub = "github"
+	KindJira   = "jira"
+)
+
+// Finding is a flagged package to file a ticket for
+type Finding struct {
+	Package       string
+	Version       string
+	Confidence    float64
+	Justification string
+	Indicators    []string
+	RunID         string
+	Evidence      []Attachment // e.g. diff.json and ai-analysis.json
+}
+
+// Attachment is an evidence file
+type Attachment struct {
+	Name string
+	Data []byte
+}
+
+// Tracker is an issue tracker tickets are filed in
+type Tracker interface {
+	// FindOpen returns the ID of an open ticket titled title, or "" if none
+	FindOpen(ctx context.Context, title string) (string, error)
+	// Create opens a ticket and returns its ID
+	Create(ctx context.Context, title, body string, labels []string, evidence []Attachment) (string, error)
+}
+
+// DefaultTemplate is the ticket body used when no template is configured.
+// Templates are text/template documents executed with a Finding.
+const DefaultTemplate = `spr flagged **{{.Package}}@{{.Version}}** as malicious (confidence {{printf "%.0f" (percent .Confidence)}}%).
+
+{{.Justification}}
+{{if .Indicators}}
+Indicators:
+{{range .Indicators}}- {{.}}
+{{end}}{{end}}{{if .RunID}}
+Run: {{.RunID}}
+{{end}}
+Do not install this version. The attached diff and AI assessment hold the behavioral evidence.
+`
+
+// Filer files tickets for findings in a tracker
+type Filer struct {
+	Tracker  Tracker
+	Labels   []string
+	Template *template.Template
+}
+
+// NewFiler creates a filer; a nil tmpl uses DefaultTemplate
+func NewFiler(tracker Tracker, labels []string, tmpl *template.Template) *Filer {
+	if tmpl == nil {
+		tmpl = template.Must(ParseTemplate(DefaultTemplate))
+	}
+	return &Filer{Tracker: tracker, Labels: labels, Template: tmpl}
+}
+
+// ParseTemplate parses a ticket body template
+func ParseTemplate(text string) (*template.Template, error) {
+	return template.New("ticket").Funcs(template.FuncMap{
+		"percent": func(f float64) float64 { return f * 100 },
+	}).Parse(text)
+}
+
+// Title is the ticket title for a finding. It identifies the package@version,
+// so it is also what open tickets are matched on.
+func Title(f Finding) string {
+	return fmt.Sprintf("Malicious package: %s@%s", f.Package, f.Version)
+}
+
+// File opens a ticket for a finding unless one is already open, returning
+// the ticket's ID and whether it was created
+func (f *Filer) File(ctx context.Context, finding Finding) (string, bool, error) {
+	title := Title(finding)
+	id, err := f.Tracker.FindOpen(ctx, title)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to search open tickets: %w", err)
+	}
+	if id != "" {
+		return id, false, nil
+	}
+
+	var body bytes.Buffer
+	if err := f.Template.Execute(&body, finding); err != nil {
+		return "", false, fmt.Errorf("failed to render ticket: %w", err)
+	}
+	id, err = f.Tracker.Create(ctx, title, body.String(), f.Labels, finding.Evidence)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to create ticket: %w", err)
+	}
+	return id, true, nil
+}
+
+// Config selects and configures a tracker
+type Config struct {
+	Kind   string   // KindGitHub, KindJira or empty to disable ticketing
+	Labels []string // Labels set on every ticket
+	// Path of a text/template ticket body; empty uses DefaultTemplate
+	TemplatePath string
+
+	// GitHub Issues: repository as owner/name and a token allowed to
+	// create issues in it
+	GitHubRepo  string
+	GitHubToken string
+
+	// Jira: site URL, user and API token (basic auth), project key and
+	// issue type (defaults to Bug)
+	JiraURL       string
+	JiraUser      string
+	JiraToken     string
+	JiraProject   string
+	JiraIssueType string
+}
+
+// Filer builds the filer the config describes; nil when Kind is empty
+func (c Config) Filer() (*Filer, error) {
+	var tracker Tracker
+	switch strings.ToLower(strings.TrimSpace(c.Kind)) {
+	case "":
+		return nil, nil
+	case KindGitHub:
+		owner, repo, ok := strings.Cut(c.GitHubRepo, "/")
+		if !ok || owner == "" || repo == "" {
+			return nil, fmt.Errorf("GitHub repository must be owner/name, got %q", c.GitHubRepo)
+		}
+		if c.GitHubToken == "" {
+			return nil, fmt.Errorf("a GitHub token is required")
+		}
+		tracker = NewGitHub(owner, repo, c.GitHubToken)
+	case KindJira:
+		if c.JiraURL == "" || c.JiraProject == "" || c.JiraToken == "" {
+			return nil, fmt.Errorf("Jira URL, project and token are required")
+		}
+		tracker = NewJira(c.JiraURL, c.JiraUser, c.JiraToken, c.JiraProject, c.JiraIssueType)
+	default:
+		return nil, fmt.Errorf("unknown ticket tracker %q (expected github or jira)", c.Kind)
+	}
+
+	var tmpl *template.Template
+	if c.TemplatePath != "" {
+		data, err := os.ReadFile(c.TemplatePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ticket template: %w", err)
+		}
+		if tmpl, err = ParseTemplate(string(data)); err != nil {
+			return nil, fmt.Errorf("invalid ticket template: %w", err)
+		}
+	}
+	return NewFiler(tracker, c.Labels, tmpl), nil
+}
+
+// ParseLabels splits a comma-separated label list
+func ParseLabels(s string) []string {
+	var labels []string
+	for _, l := range strings.Split(s, ",") {
+		if l = strings.TrimSpace(l); l != "" {
+			labels = append(labels, l)
+		}
+	}
+	return labels
+}
@@ -0,0 +1,164 @@
+package ticketing
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFinding = Finding{
+	Package:       "evil",
+	Version:       "1.0.0",
+	Confidence:    0.93,
+	Justification: "Reads ~/.npmrc and posts it to a remote host",
+	Indicators:    []string{"https://evil.example/x"},
+	RunID:         "run-1",
+	Evidence:      []Attachment{{Name: "diff.json", Data: []byte(`{"per_process":{}}`)}},
+}
+
+func TestGitHubFileDeduplicates(t *testing.T) {
+	var created []map[string]any
+	var openTitles []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer tok", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/search/issues":
+			assert.Contains(t, r.URL.Query().Get("q"), "repo:acme/sec is:issue is:open")
+			// Search also returns similar titles; only the exact one counts
+			items := []map[string]string{{"title": "Malicious package: evil@1.0.0-beta", "html_url": "https://gh/1"}}
+			for _, title := range openTitles {
+				items = append(items, map[string]string{"title": title, "html_url": "https://gh/2"})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"items": items})
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/sec/issues":
+			var issue map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&issue))
+			created = append(created, issue)
+			openTitles = append(openTitles, issue["title"].(string))
+			json.NewEncoder(w).Encode(map[string]string{"html_url": "https://gh/2"})
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	gh := NewGitHub("acme", "sec", "tok")
+	gh.BaseURL = srv.URL
+	filer := NewFiler(gh, []string{"security"}, nil)
+
+	id, isNew, err := filer.File(context.Background(), testFinding)
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, "https://gh/2", id)
+	require.Len(t, created, 1)
+	assert.Equal(t, "Malicious package: evil@1.0.0", created[0]["title"])
+	assert.Equal(t, []any{"security"}, created[0]["labels"])
+	body := created[0]["body"].(string)
+	assert.Contains(t, body, "confidence 93%")
+	assert.Contains(t, body, "- https://evil.example/x")
+	assert.Contains(t, body, "<summary>diff.json</summary>")
+
+	// The open ticket is found rather than duplicated
+	id, isNew, err = filer.File(context.Background(), testFinding)
+	require.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, "https://gh/2", id)
+	assert.Len(t, created, 1)
+}
+
+func TestInlineEvidenceTruncates(t *testing.T) {
+	big := Attachment{Name: "diff.json", Data: []byte(strings.Repeat("x", 2*maxIssueBody))}
+	small := Attachment{Name: "ai-analysis.json", Data: []byte(`{"is_malicious":true}`)}
+	body := inlineEvidence("body", []Attachment{big, small})
+	assert.LessOrEqual(t, len(body), maxIssueBody)
+	assert.Contains(t, body, "… truncated")
+	assert.Contains(t, body, `{"is_malicious":true}`)
+}
+
+func TestJiraFileDeduplicates(t *testing.T) {
+	var open map[string]string // summary -> key
+	var attached []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "bot@acme.test", user)
+		assert.Equal(t, "tok", pass)
+		switch r.URL.Path {
+		case "/rest/api/2/search":
+			var req struct {
+				JQL string `json:"jql"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, `project = "SEC" AND statusCategory != Done AND summary ~ "\"Malicious package: evil@1.0.0\""`, req.JQL)
+			var issues []map[string]any
+			for summary, key := range open {
+				issues = append(issues, map[string]any{"key": key, "fields": map[string]string{"summary": summary}})
+			}
+			json.NewEncoder(w).Encode(map[string]any{"issues": issues})
+		case "/rest/api/2/issue":
+			var req struct {
+				Fields struct {
+					Project   map[string]string `json:"project"`
+					Summary   string            `json:"summary"`
+					IssueType map[string]string `json:"issuetype"`
+					Labels    []string          `json:"labels"`
+				} `json:"fields"`
+			}
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			assert.Equal(t, "SEC", req.Fields.Project["key"])
+			assert.Equal(t, "Bug", req.Fields.IssueType["name"])
+			assert.Equal(t, []string{"security"}, req.Fields.Labels)
+			open = map[string]string{req.Fields.Summary: "SEC-7"}
+			json.NewEncoder(w).Encode(map[string]string{"key": "SEC-7"})
+		case "/rest/api/2/issue/SEC-7/attachments":
+			assert.Equal(t, "no-check", r.Header.Get("X-Atlassian-Token"))
+			file, header, err := r.FormFile("file")
+			require.NoError(t, err)
+			data, _ := io.ReadAll(file)
+			assert.Equal(t, testFinding.Evidence[0].Data, data)
+			attached = append(attached, header.Filename)
+			w.Write([]byte("[]"))
+		default:
+			t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer srv.Close()
+
+	filer := NewFiler(NewJira(srv.URL+"/", "bot@acme.test", "tok", "SEC", ""), []string{"security"}, nil)
+
+	id, isNew, err := filer.File(context.Background(), testFinding)
+	require.NoError(t, err)
+	assert.True(t, isNew)
+	assert.Equal(t, "SEC-7", id)
+	assert.Equal(t, []string{"diff.json"}, attached)
+
+	id, isNew, err = filer.File(context.Background(), testFinding)
+	require.NoError(t, err)
+	assert.False(t, isNew)
+	assert.Equal(t, "SEC-7", id)
+	assert.Len(t, attached, 1)
+}
+
+func TestConfigFiler(t *testing.T) {
+	filer, err := Config{}.Filer()
+	require.NoError(t, err)
+	assert.Nil(t, filer)
+
+	_, err = Config{Kind: "github", GitHubRepo: "acme", GitHubToken: "tok"}.Filer()
+	assert.Error(t, err)
+	_, err = Config{Kind: "jira", JiraURL: "https://acme.atlassian.net"}.Filer()
+	assert.Error(t, err)
+	_, err = Config{Kind: "linear"}.Filer()
+	assert.Error(t, err)
+
+	filer, err = Config{Kind: "GitHub", GitHubRepo: "acme/sec", GitHubToken: "tok", Labels: ParseLabels(" security, ,npm")}.Filer()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"security", "npm"}, filer.Labels)
+}
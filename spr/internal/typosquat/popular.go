@@ -0,0 +1,55 @@
+package typosquat
+
+// Popular are widely depended-on npm packages, the names typosquats imitate.
+// The list favors names long enough to tell a typo from a different word;
+// Load adds local names to it.
+var Popular = []string{
+	// Frameworks and UI
+	"react", "react-dom", "react-router", "react-router-dom", "react-redux",
+	"react-scripts", "react-native", "preact", "vue", "vue-router", "vuex",
+	"angular", "@angular/core", "@angular/common", "@angular/cli", "svelte",
+	"next", "nuxt", "gatsby", "jquery", "bootstrap", "tailwindcss", "redux",
+	"redux-thunk", "mobx", "styled-components", "@emotion/react",
+	"@mui/material", "classnames", "prop-types",
+
+	// Servers and HTTP
+	"express", "koa", "fastify", "hapi", "body-parser", "cookie-parser",
+	"cors", "helmet", "morgan", "multer", "socket.io", "socket.io-client",
+	"axios", "node-fetch", "cross-fetch", "request", "superagent", "got",
+	"http-proxy", "http-proxy-middleware", "ws", "undici",
+
+	// Utilities
+	"lodash", "lodash.merge", "lodash.get", "underscore", "ramda", "async",
+	"bluebird", "moment", "moment-timezone", "dayjs", "date-fns", "luxon",
+	"uuid", "nanoid", "chalk", "colors", "debug", "commander", "yargs",
+	"minimist", "inquirer", "ora", "dotenv", "cross-env", "rimraf", "mkdirp",
+	"glob", "globby", "fs-extra", "graceful-fs", "chokidar", "semver",
+	"qs", "query-string", "validator", "joi", "yup", "zod", "ajv",
+	"immutable", "rxjs", "tslib", "core-js", "regenerator-runtime",
+	"event-emitter", "eventemitter3", "cheerio", "jsdom", "marked",
+	"markdown-it", "handlebars", "ejs", "pug", "mustache", "js-yaml", "yaml",
+	"xml2js", "fast-xml-parser", "csv-parse", "papaparse", "iconv-lite",
+	"mime", "mime-types", "ms", "bytes", "object-assign", "deepmerge",
+	"shelljs", "execa", "cross-spawn", "node-gyp", "bcrypt", "bcryptjs",
+	"jsonwebtoken", "passport", "crypto-js", "node-forge", "sharp", "jimp",
+	"puppeteer", "playwright", "electron", "ethers", "web3", "discord.js",
+	"winston", "pino", "bunyan", "log4js", "nodemailer", "redis", "ioredis",
+	"mongoose", "mongodb", "mysql", "mysql2", "pg", "sequelize", "typeorm",
+	"prisma", "@prisma/client", "knex", "sqlite3", "better-sqlite3",
+	"aws-sdk", "@aws-sdk/client-s3", "firebase", "firebase-admin",
+	"graphql", "apollo-server", "@apollo/client", "openai", "stripe",
+	"twilio", "colord", "colorette", "picocolors", "kleur", "signal-exit",
+	"string-width", "strip-ansi", "ansi-styles", "supports-color",
+	"source-map", "source-map-support",
+
+	// Build and test tooling
+	"typescript", "ts-node", "@types/node", "@types/react", "webpack",
+	"webpack-cli", "webpack-dev-server", "vite", "rollup", "esbuild",
+	"parcel", "babel-loader", "@babel/core", "@babel/preset-env",
+	"@babel/runtime", "css-loader", "style-loader", "sass", "less",
+	"postcss", "autoprefixer", "eslint", "prettier", "husky", "lint-staged",
+	"jest", "mocha", "chai", "sinon", "vitest", "nodemon", "concurrently",
+	"npm-run-all", "lerna", "nx", "turbo", "pm2", "forever", "serve",
+	"http-server", "terser", "uglify-js", "browserify", "gulp", "grunt",
+	"karma", "cypress", "supertest", "nyc", "istanbul",
+}
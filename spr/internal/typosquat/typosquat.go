@@ -0,0 +1,231 @@
+// Package typosquat flags dependencies whose names imitate popular npm
+// packages: a character or two off (axois for axios), different separators
+// (react_dom for react-dom), or look-alike characters (1odash for lodash).
+//
+// The check only compares names, so it runs before anything is uploaded or
+// any workflow is triggered, offline included.
+package typosquat
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+)
+
+// Kinds of finding
+const (
+	// KindHomoglyph is a name that reads the same as a popular one, e.g.
+	// rn for m or 0 for o
+	KindHomoglyph = "homoglyph"
+	// KindSeparator is a popular name with separators added, removed or
+	// swapped
+	KindSeparator = "separator"
+	// KindEditDistance is a popular name with characters inserted, deleted,
+	// replaced or transposed
+	KindEditDistance = "edit-distance"
+)
+
+// minTargetLength is the shortest popular name typos are looked for in;
+// shorter names are a character away from too many unrelated packages
+const minTargetLength = 5
+
+// Finding is a dependency whose name imitates a popular package
+type Finding struct {
+	Package  string `json:"package"`
+	Name     string `json:"name"`
+	Version  string `json:"version"`
+	Target   string `json:"target"` // The popular package it imitates
+	Kind     string `json:"kind"`
+	Distance int    `json:"distance,omitempty"` // Edits from Target, for KindEditDistance
+}
+
+// Checker compares names against popular packages. A nil Checker flags
+// nothing.
+type Checker struct {
+	targets []target // In order of preference when several match
+	known   map[string]bool
+}
+
+// target is a popular name and the forms it is compared in
+type target struct {
+	name     string
+	skeleton string // See confusableSkeleton
+	bare     string // See stripSeparators
+}
+
+// NewChecker creates a checker against Popular and the extra names, which
+// are also how a flagged but legitimate dependency is accepted
+func NewChecker(extra []string) *Checker {
+	c := &Checker{known: make(map[string]bool)}
+	for _, name := range append(append([]string(nil), Popular...), extra...) {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" || c.known[name] {
+			continue
+		}
+		c.known[name] = true
+		c.targets = append(c.targets, target{name: name, skeleton: confusableSkeleton(name), bare: stripSeparators(name)})
+	}
+	return c
+}
+
+// Load creates a checker against Popular and the names in a file, one per
+// line; # starts a comment. An empty path adds no names.
+func Load(path string) (*Checker, error) {
+	if path == "" {
+		return NewChecker(nil), nil
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open package names: %w", err)
+	}
+	defer f.Close()
+
+	var names []string
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		line, _, _ := strings.Cut(sc.Text(), "#")
+		if line = strings.TrimSpace(line); line != "" {
+			names = append(names, line)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read package names: %w", err)
+	}
+	return NewChecker(names), nil
+}
+
+// Match returns the popular package a name imitates and how, or ok false if
+// it imitates none. Popular names themselves never match.
+func (c *Checker) Match(name string) (imitated, kind string, distance int, ok bool) {
+	if c == nil {
+		return "", "", 0, false
+	}
+	name = strings.ToLower(name)
+	if c.known[name] {
+		return "", "", 0, false
+	}
+
+	best := -1
+	skeleton, bare := confusableSkeleton(name), stripSeparators(name)
+	for _, t := range c.targets {
+		if isScoped(t.name) != isScoped(name) {
+			// @evil/lodash is a scoped fork, not a typo of lodash
+			continue
+		}
+		switch {
+		case t.skeleton == skeleton:
+			return t.name, KindHomoglyph, 0, true
+		case t.bare == bare:
+			return t.name, KindSeparator, 0, true
+		}
+		if len(t.name) < minTargetLength {
+			continue
+		}
+		max := maxDistance(t.name)
+		if abs(len(t.name)-len(name)) > max {
+			continue
+		}
+		if d := editDistance(name, t.name); d <= max && (best < 0 || d < best) {
+			imitated, best = t.name, d
+		}
+	}
+	if best < 0 {
+		return "", "", 0, false
+	}
+	return imitated, KindEditDistance, best, true
+}
+
+// Check checks the names of nodes, returning the likely typosquats sorted by
+// package
+func (c *Checker) Check(nodes []*models.PackageNode) []Finding {
+	var findings []Finding
+	for _, node := range nodes {
+		target, kind, distance, ok := c.Match(node.Name)
+		if !ok {
+			continue
+		}
+		findings = append(findings, Finding{
+			Package:  node.ID,
+			Name:     node.Name,
+			Version:  node.Version,
+			Target:   target,
+			Kind:     kind,
+			Distance: distance,
+		})
+	}
+	sort.Slice(findings, func(i, j int) bool { return findings[i].Package < findings[j].Package })
+	return findings
+}
+
+// maxDistance is how many edits away from a popular name a typo may be
+func maxDistance(target string) int {
+	if len(target) >= 10 {
+		return 2
+	}
+	return 1
+}
+
+// homoglyphs map look-alike characters and sequences to what they imitate.
+// npm no longer accepts non-ASCII names, but older packages and other
+// registries may have them.
+var homoglyphs = strings.NewReplacer(
+	"rn", "m", "vv", "w", "cl", "d",
+	"0", "o", "1", "l", "3", "e", "5", "s", "$", "s",
+	// Cyrillic and Greek letters rendered like Latin ones
+	"а", "a", "е", "e", "о", "o", "р", "p", "с", "c", "у", "y", "х", "x",
+	"і", "i", "ј", "j", "ѕ", "s", "ο", "o", "α", "a", "ν", "v", "κ", "k",
+)
+
+// confusableSkeleton returns what a name looks like: look-alikes replaced
+// and i, l and 1 merged, since they are hard to tell apart in many fonts
+func confusableSkeleton(name string) string {
+	return strings.ReplaceAll(homoglyphs.Replace(name), "i", "l")
+}
+
+// stripSeparators drops the characters npm names separate words with
+func stripSeparators(name string) string {
+	return strings.NewReplacer("-", "", "_", "", ".", "").Replace(name)
+}
+
+func isScoped(name string) bool {
+	return strings.HasPrefix(name, "@")
+}
+
+// editDistance is the optimal string alignment distance of a and b: the
+// Levenshtein distance with adjacent transpositions counted as one edit
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	// Three rolling rows: two back (transpositions), previous and current
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
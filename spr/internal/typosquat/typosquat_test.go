@@ -0,0 +1,86 @@
+package typosquat
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/acheong08/hackeurope-spr/pkg/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatch(t *testing.T) {
+	c := NewChecker(nil)
+
+	tests := []struct {
+		name, target, kind string
+		distance           int
+	}{
+		{"axois", "axios", KindEditDistance, 1},
+		{"expres", "express", KindEditDistance, 1},
+		{"lodahs", "lodash", KindEditDistance, 1},
+		{"reakt-dom", "react-dom", KindEditDistance, 1},
+		{"jsonwebtokn", "jsonwebtoken", KindEditDistance, 1},
+		{"@types/nodde", "@types/node", KindEditDistance, 1},
+		{"react_dom", "react-dom", KindSeparator, 0},
+		{"crossenv", "cross-env", KindSeparator, 0},
+		{"1odash", "lodash", KindHomoglyph, 0},
+		{"rnongoose", "mongoose", KindHomoglyph, 0},
+		{"ехpress", "express", KindHomoglyph, 0}, // Cyrillic е and х
+		{"AXOIS", "axios", KindEditDistance, 1},
+	}
+	for _, tt := range tests {
+		target, kind, distance, ok := c.Match(tt.name)
+		if assert.True(t, ok, tt.name) {
+			assert.Equal(t, tt.target, target, tt.name)
+			assert.Equal(t, tt.kind, kind, tt.name)
+			assert.Equal(t, tt.distance, distance, tt.name)
+		}
+	}
+
+	// Popular names, unrelated names, short names and scoped forks
+	for _, name := range []string{"lodash", "react", "preact", "left-pad", "is-number", "koa", "got", "chai", "@acme/lodash", "yargs-parser"} {
+		_, _, _, ok := c.Match(name)
+		assert.False(t, ok, name)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	assert.Equal(t, 0, editDistance("lodash", "lodash"))
+	assert.Equal(t, 1, editDistance("lodahs", "lodash")) // Transposition
+	assert.Equal(t, 1, editDistance("lodsh", "lodash"))
+	assert.Equal(t, 2, editDistance("lodasher", "lodash"))
+	assert.Equal(t, 3, editDistance("abc", ""))
+}
+
+func TestLoadAcceptsListedNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "names.txt")
+	require.NoError(t, os.WriteFile(path, []byte("# accepted\nexpres\ninternal-toolkit # ours\n"), 0o644))
+
+	c, err := Load(path)
+	require.NoError(t, err)
+	_, _, _, ok := c.Match("expres")
+	assert.False(t, ok)
+	target, _, _, ok := c.Match("internal-tolkit")
+	assert.True(t, ok)
+	assert.Equal(t, "internal-toolkit", target)
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.txt"))
+	assert.Error(t, err)
+}
+
+func TestCheck(t *testing.T) {
+	nodes := []*models.PackageNode{
+		{Package: models.Package{ID: "lodash@4.17.21", Name: "lodash", Version: "4.17.21"}},
+		{Package: models.Package{ID: "loadsh@1.0.0", Name: "loadsh", Version: "1.0.0"}},
+		{Package: models.Package{ID: "axois@0.1.0", Name: "axois", Version: "0.1.0"}},
+	}
+	assert.Equal(t, []Finding{
+		{Package: "axois@0.1.0", Name: "axois", Version: "0.1.0", Target: "axios", Kind: KindEditDistance, Distance: 1},
+		{Package: "loadsh@1.0.0", Name: "loadsh", Version: "1.0.0", Target: "lodash", Kind: KindEditDistance, Distance: 1},
+	}, NewChecker(nil).Check(nodes))
+
+	var nilChecker *Checker
+	assert.Empty(t, nilChecker.Check(nodes))
+}
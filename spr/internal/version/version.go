@@ -0,0 +1,112 @@
+// Package version reports which build of spr produced a result, so artifacts
+// can be traced back to the code and analysis components behind them
+package version
+
+import (
+	"runtime"
+	"runtime/debug"
+	"sync"
+)
+
+// Set at build time, e.g.
+//
+//	go build -ldflags "-X github.com/acheong08/hackeurope-spr/internal/version.Version=v1.2.0" ./cmd/spr
+//
+// Commit and BuildDate fall back to the VCS stamp Go embeds when building
+// inside a git checkout.
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// components are the dependencies whose versions change analysis results
+var components = map[string]string{
+	"charm.land/fantasy":             "fantasy",
+	"github.com/openai/openai-go/v2": "openai-go",
+}
+
+// Info describes a build of spr
+type Info struct {
+	Version    string            `json:"version"`
+	Commit     string            `json:"commit,omitempty"`
+	Modified   bool              `json:"modified,omitempty"` // Built from a dirty tree
+	BuildDate  string            `json:"build_date,omitempty"`
+	GoVersion  string            `json:"go_version"`
+	Components map[string]string `json:"components,omitempty"`
+}
+
+var (
+	once sync.Once
+	info Info
+)
+
+// Get returns the build info of the running binary
+func Get() Info {
+	once.Do(func() {
+		info = Info{
+			Version:   Version,
+			Commit:    Commit,
+			BuildDate: BuildDate,
+			GoVersion: runtime.Version(),
+		}
+
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		// go install records the module version
+		if info.Version == "dev" && bi.Main.Version != "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.BuildDate == "" {
+					info.BuildDate = s.Value
+				}
+			case "vcs.modified":
+				info.Modified = s.Value == "true"
+			}
+		}
+		for _, dep := range bi.Deps {
+			if name, ok := components[dep.Path]; ok {
+				if info.Components == nil {
+					info.Components = make(map[string]string)
+				}
+				info.Components[name] = dep.Version
+			}
+		}
+	})
+	return info
+}
+
+// Stamp is the "generator" block embedded in generated artifacts
+func Stamp() *Info {
+	i := Get()
+	return &i
+}
+
+// String formats the build info on one line, e.g. "spr dev (abc1234, 2026-01-02T03:04:05Z)"
+func (i Info) String() string {
+	s := "spr " + i.Version
+	if i.Commit != "" {
+		commit := i.Commit
+		if len(commit) > 12 {
+			commit = commit[:12]
+		}
+		if i.Modified {
+			commit += "-dirty"
+		}
+		s += " (" + commit
+		if i.BuildDate != "" {
+			s += ", " + i.BuildDate
+		}
+		s += ")"
+	}
+	return s
+}
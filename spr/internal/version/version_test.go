@@ -0,0 +1,24 @@
+package version
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInfoString(t *testing.T) {
+	assert.Equal(t, "spr dev", Info{Version: "dev"}.String())
+	assert.Equal(t, "spr v1.2.0 (0123456789ab-dirty, 2026-01-02T03:04:05Z)", Info{
+		Version:   "v1.2.0",
+		Commit:    "0123456789abcdef",
+		Modified:  true,
+		BuildDate: "2026-01-02T03:04:05Z",
+	}.String())
+}
+
+func TestGet(t *testing.T) {
+	info := Get()
+	assert.NotEmpty(t, info.Version)
+	assert.NotEmpty(t, info.GoVersion)
+	assert.Equal(t, info, *Stamp())
+}
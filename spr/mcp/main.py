@@ -4,6 +4,8 @@ from typing import Dict, Any, List
 from mcp.server.fastmcp import FastMCP
 
 API_BASE = os.getenv("TRACE_API_BASE", "http://localhost:8001")
+API_TOKEN = os.getenv("TRACE_API_TOKEN", "")
+HEADERS = {"Authorization": f"Bearer {API_TOKEN}"} if API_TOKEN else {}
 
 mcp = FastMCP("tracee-analysis")
 
@@ -33,7 +35,7 @@ def list_collections() -> List[str]:
     # If you don’t have a list endpoint yet,
     # you should add one in FastAPI:
     # GET /collections
-    r = requests.get(f"{API_BASE}/collections")
+    r = requests.get(f"{API_BASE}/collections", headers=HEADERS)
     r.raise_for_status()
     return r.json()
 
@@ -45,7 +47,7 @@ def list_collections() -> List[str]:
 @mcp.tool()
 def get_stats(collection_name: str) -> Dict[str, Any]:
     """Get aggregated behavioral stats."""
-    r = requests.get(f"{API_BASE}/stats/{collection_name}")
+    r = requests.get(f"{API_BASE}/stats/{collection_name}", headers=HEADERS)
     r.raise_for_status()
     return r.json()
 
@@ -68,7 +70,8 @@ def get_dns_activity(
             "dns": dns,
             "limit": limit,
             "offset": offset
-        }
+        },
+        headers=HEADERS
     )
     r.raise_for_status()
     return r.json()
@@ -92,7 +95,8 @@ def get_command_activity(
             "command": command,
             "limit": limit,
             "offset": offset
-        }
+        },
+        headers=HEADERS
     )
     r.raise_for_status()
     return r.json()
@@ -116,7 +120,8 @@ def get_file_activity(
             "file": file,
             "limit": limit,
             "offset": offset
-        }
+        },
+        headers=HEADERS
     )
     r.raise_for_status()
     return r.json()
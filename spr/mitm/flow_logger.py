@@ -0,0 +1,57 @@
+"""
+mitmproxy addon that writes one JSON line per completed HTTP(S) flow.
+
+Usage:
+    mitmdump --mode transparent -s flow_logger.py --set flow_log=/tmp/tracee-out/proxy.jsonl
+
+The output is consumed by aggregate.ProxyAggregator (proxy.jsonl).
+Only metadata is recorded: method, URL, host, status and payload sizes.
+"""
+
+import json
+
+from mitmproxy import ctx, http
+
+
+class FlowLogger:
+    def __init__(self):
+        self.out = None
+
+    def load(self, loader):
+        loader.add_option(
+            name="flow_log",
+            typespec=str,
+            default="proxy.jsonl",
+            help="Path to write JSONL flow records to",
+        )
+
+    def running(self):
+        self.out = open(ctx.options.flow_log, "a", buffering=1)
+
+    def response(self, flow: http.HTTPFlow):
+        self._write(flow)
+
+    def error(self, flow: http.HTTPFlow):
+        # Record failed requests too - exfil attempts to dead C2 hosts still matter
+        self._write(flow)
+
+    def done(self):
+        if self.out:
+            self.out.close()
+
+    def _write(self, flow: http.HTTPFlow):
+        if self.out is None:
+            return
+        record = {
+            "timestamp": flow.request.timestamp_start,
+            "method": flow.request.method,
+            "url": flow.request.pretty_url,
+            "host": flow.request.pretty_host,
+            "status": flow.response.status_code if flow.response else 0,
+            "request_size": len(flow.request.raw_content or b""),
+            "response_size": len(flow.response.raw_content or b"") if flow.response else 0,
+        }
+        self.out.write(json.dumps(record) + "\n")
+
+
+addons = [FlowLogger()]
@@ -0,0 +1,86 @@
+package models
+
+// Annotation keys pipeline stages attach findings under. Stages may add their
+// own keys; these are the ones with a fixed value type.
+const (
+	AnnotationVerdict    = "verdict"    // string, one of the Verdict* values
+	AnnotationScore      = "score"      // float64, AI confidence in the verdict (0-1)
+	AnnotationVulns      = "vulns"      // []string, advisory or vulnerability IDs
+	AnnotationLicense    = "license"    // string, declared SPDX license expression
+	AnnotationProvenance = "provenance" // string, where the published tarball was built from
+	AnnotationDownloads  = "downloads"  // int, npm downloads over the last week
+	AnnotationRisk       = "risk"       // int, combined risk score (0-100), see scoring
+)
+
+// Verdict values recorded under AnnotationVerdict
+const (
+	VerdictMalicious   = "malicious"
+	VerdictSafe        = "safe"
+	VerdictClean       = "clean" // Analyzed, no behavior beyond the baseline
+	VerdictNotAnalyzed = "not-analyzed"
+	VerdictTrusted     = "trusted-provenance" // Skipped: built by a trusted publisher
+)
+
+// Annotate attaches a finding to the node, replacing any previous value under
+// key. Annotations are not synchronized: stages that run concurrently must
+// collect their results and annotate afterwards.
+func (n *PackageNode) Annotate(key string, value any) {
+	if n.Annotations == nil {
+		n.Annotations = make(map[string]any)
+	}
+	n.Annotations[key] = value
+}
+
+// Annotation returns the value attached under key, if any
+func (n *PackageNode) Annotation(key string) (any, bool) {
+	value, ok := n.Annotations[key]
+	return value, ok
+}
+
+// StringAnnotation returns the string attached under key, or "" when it is
+// missing or not a string
+func (n *PackageNode) StringAnnotation(key string) string {
+	s, _ := n.Annotations[key].(string)
+	return s
+}
+
+// FloatAnnotation returns the number attached under key. Annotations decoded
+// from JSON hold float64 already; ok is false when it is missing or not a number.
+func (n *PackageNode) FloatAnnotation(key string) (float64, bool) {
+	switch v := n.Annotations[key].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// StringsAnnotation returns the list attached under key, accepting the
+// []any a JSON round trip produces
+func (n *PackageNode) StringsAnnotation(key string) []string {
+	switch v := n.Annotations[key].(type) {
+	case []string:
+		return v
+	case []any:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// Annotate attaches a finding to the node with the given ID, reporting
+// whether the node exists
+func (g *DependencyGraph) Annotate(id, key string, value any) bool {
+	node, ok := g.Nodes[id]
+	if !ok {
+		return false
+	}
+	node.Annotate(key, value)
+	return true
+}
@@ -1,5 +1,7 @@
 package models
 
+import "sort"
+
 // Package represents a single npm package with version
 type Package struct {
 	ID      string `json:"id"`      // "lodash@4.17.21"
@@ -13,6 +15,17 @@ type PackageNode struct {
 	ResolvedURL  string            `json:"resolved"`     // tarball URL
 	Integrity    string            `json:"integrity"`    // sha512 hash
 	Dependencies map[string]string `json:"dependencies"` // name -> version
+	// IDs of the nodes the dependencies resolve to, from the lockfile's
+	// nesting; nil when the graph wasn't built from a lockfile
+	ResolvedDependencies []string `json:"resolved_dependencies,omitempty"`
+
+	// From the lockfile: only reachable through devDependencies, and has
+	// preinstall/install/postinstall scripts npm runs by default
+	Dev              bool `json:"dev,omitempty"`
+	HasInstallScript bool `json:"has_install_script,omitempty"`
+
+	// Findings attached by pipeline stages (verdict, license, ...), see Annotate
+	Annotations map[string]any `json:"annotations,omitempty"`
 }
 
 // DependencyGraph represents the complete dependency tree
@@ -44,6 +57,16 @@ func (g *DependencyGraph) GetDirectDependencies() []*PackageNode {
 		return nil
 	}
 
+	if rootNode.ResolvedDependencies != nil {
+		deps := make([]*PackageNode, 0, len(rootNode.ResolvedDependencies))
+		for _, id := range rootNode.ResolvedDependencies {
+			if node, ok := g.Nodes[id]; ok {
+				deps = append(deps, node)
+			}
+		}
+		return deps
+	}
+
 	// Build name->node lookup for O(1) access
 	nameToNode := make(map[string]*PackageNode)
 	for _, node := range g.Nodes {
@@ -60,3 +83,17 @@ func (g *DependencyGraph) GetDirectDependencies() []*PackageNode {
 	}
 	return deps
 }
+
+// GetAllDependencies returns every package in the graph but the root, direct
+// and transitive, sorted by ID
+func (g *DependencyGraph) GetAllDependencies() []*PackageNode {
+	deps := make([]*PackageNode, 0, len(g.Nodes))
+	for id, node := range g.Nodes {
+		if g.RootPackage != nil && id == g.RootPackage.ID {
+			continue
+		}
+		deps = append(deps, node)
+	}
+	sort.Slice(deps, func(i, j int) bool { return deps[i].ID < deps[j].ID })
+	return deps
+}
@@ -0,0 +1,352 @@
+package models
+
+import (
+	"slices"
+	"sort"
+)
+
+// Dependencies are followed through ResolvedDependencies when the graph was
+// built from a lockfile. Otherwise ranges are resolved by name, so when
+// several versions of a package are installed each counts as a dependency of
+// every package that depends on that name. This over-approximates, which is
+// the safe side for impact estimates.
+
+// dependencyResolver looks up the node IDs a node depends on
+type dependencyResolver struct {
+	g      *DependencyGraph
+	byName map[string][]string // Package name -> sorted node IDs
+}
+
+func (g *DependencyGraph) resolver() *dependencyResolver {
+	byName := make(map[string][]string)
+	for nodeID, n := range g.Nodes {
+		byName[n.Name] = append(byName[n.Name], nodeID)
+	}
+	for _, ids := range byName {
+		sort.Strings(ids)
+	}
+	return &dependencyResolver{g: g, byName: byName}
+}
+
+// dependencies returns the sorted IDs of the nodes n depends on. Without
+// ResolvedDependencies, an exactly installed version resolves to that node
+// alone and a range to every installed version of the name.
+func (r *dependencyResolver) dependencies(n *PackageNode) []string {
+	if n.ResolvedDependencies != nil {
+		return n.ResolvedDependencies
+	}
+	var ids []string
+	for name, version := range n.Dependencies {
+		if _, ok := r.g.Nodes[name+"@"+version]; ok {
+			ids = append(ids, name+"@"+version)
+			continue
+		}
+		for _, depID := range r.byName[name] {
+			if depID != n.ID {
+				ids = append(ids, depID)
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Dependents returns the IDs of the packages that depend on the node with the
+// given ID directly, and of all packages that depend on it transitively
+// (direct ones included), both sorted. The root package is left out.
+func (g *DependencyGraph) Dependents(id string) (direct, all []string) {
+	node, ok := g.Nodes[id]
+	if !ok {
+		return nil, nil
+	}
+
+	// Reverse edges: node ID -> IDs of the nodes depending on it
+	resolver := g.resolver()
+	dependents := make(map[string][]string)
+	for nodeID, n := range g.Nodes {
+		for _, depID := range resolver.dependencies(n) {
+			dependents[depID] = append(dependents[depID], nodeID)
+		}
+	}
+
+	seen := map[string]bool{id: true}
+	queue := []*PackageNode{node}
+	for depth := 0; len(queue) > 0; depth++ {
+		var next []*PackageNode
+		for _, n := range queue {
+			for _, depID := range dependents[n.ID] {
+				if seen[depID] {
+					continue
+				}
+				seen[depID] = true
+				if g.RootPackage != nil && depID == g.RootPackage.ID {
+					continue
+				}
+				if depth == 0 {
+					direct = append(direct, depID)
+				}
+				all = append(all, depID)
+				next = append(next, g.Nodes[depID])
+			}
+		}
+		queue = next
+	}
+
+	sort.Strings(direct)
+	sort.Strings(all)
+	return direct, all
+}
+
+// PathFromRoot returns a shortest dependency chain from the root package to
+// the node with the given ID, both included, or nil if it isn't reachable
+func (g *DependencyGraph) PathFromRoot(id string) []string {
+	if g.RootPackage == nil {
+		return nil
+	}
+	if _, ok := g.Nodes[g.RootPackage.ID]; !ok {
+		return nil
+	}
+
+	resolver := g.resolver()
+	parent := map[string]string{g.RootPackage.ID: ""}
+	queue := []string{g.RootPackage.ID}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == id {
+			var path []string
+			for at := id; at != ""; at = parent[at] {
+				path = append([]string{at}, path...)
+			}
+			return path
+		}
+
+		for _, depID := range resolver.dependencies(g.Nodes[current]) {
+			if _, seen := parent[depID]; !seen {
+				parent[depID] = current
+				queue = append(queue, depID)
+			}
+		}
+	}
+	return nil
+}
+
+// Edge is a dependency of one package on another, by node ID
+type Edge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// Edges resolves the dependencies of every node to node IDs, sorted by From
+// then To. Dependencies that aren't in the graph are left out.
+func (g *DependencyGraph) Edges() []Edge {
+	resolver := g.resolver()
+	var edges []Edge
+	for nodeID, n := range g.Nodes {
+		for _, depID := range resolver.dependencies(n) {
+			edges = append(edges, Edge{From: nodeID, To: depID})
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+	return edges
+}
+
+// Traversal orders for Walk
+type TraversalOrder int
+
+const (
+	BreadthFirst TraversalOrder = iota // Nearest dependencies first
+	DepthFirst                         // Each dependency's subtree before its siblings
+)
+
+// Walk visits the node with the given ID and everything it depends on
+// transitively, each node once, in order. visit gets the node and its depth
+// (0 for the start node, along the path it was reached by); returning false
+// skips that node's dependencies. Dependencies are visited in ID order.
+func (g *DependencyGraph) Walk(id string, order TraversalOrder, visit func(node *PackageNode, depth int) bool) {
+	start, ok := g.Nodes[id]
+	if !ok {
+		return
+	}
+	resolver := g.resolver()
+	seen := map[string]bool{id: true}
+
+	type entry struct {
+		node  *PackageNode
+		depth int
+	}
+	pending := []entry{{start, 0}}
+	for len(pending) > 0 {
+		var current entry
+		if order == DepthFirst {
+			current, pending = pending[len(pending)-1], pending[:len(pending)-1]
+		} else {
+			current, pending = pending[0], pending[1:]
+		}
+		if !visit(current.node, current.depth) {
+			continue
+		}
+
+		deps := resolver.dependencies(current.node)
+		next := make([]entry, 0, len(deps))
+		for _, depID := range deps {
+			if seen[depID] {
+				continue
+			}
+			seen[depID] = true
+			next = append(next, entry{g.Nodes[depID], current.depth + 1})
+		}
+		if order == DepthFirst {
+			// Pushed in reverse so the lowest ID is popped first
+			for i := len(next) - 1; i >= 0; i-- {
+				pending = append(pending, next[i])
+			}
+		} else {
+			pending = append(pending, next...)
+		}
+	}
+}
+
+// GetTransitiveDependencies returns the sorted IDs of every package the node
+// with the given ID depends on, directly or not
+func (g *DependencyGraph) GetTransitiveDependencies(id string) []string {
+	var ids []string
+	g.Walk(id, BreadthFirst, func(node *PackageNode, depth int) bool {
+		if depth > 0 {
+			ids = append(ids, node.ID)
+		}
+		return true
+	})
+	sort.Strings(ids)
+	return ids
+}
+
+// DependentsOf returns the sorted IDs of the packages that directly depend
+// on any installed version of the package called name, e.g. "who depends on
+// left-pad". The root package is included.
+func (g *DependencyGraph) DependentsOf(name string) []string {
+	resolver := g.resolver()
+	var ids []string
+	for nodeID, n := range g.Nodes {
+		for _, depID := range resolver.dependencies(n) {
+			if dep, ok := g.Nodes[depID]; ok && dep.Name == name {
+				ids = append(ids, nodeID)
+				break
+			}
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Subgraph returns the graph rooted at the node with the given ID, holding it
+// and everything it depends on transitively, or nil if it isn't in the graph.
+// Nodes are shared with g, not copied.
+func (g *DependencyGraph) Subgraph(id string) *DependencyGraph {
+	root, ok := g.Nodes[id]
+	if !ok {
+		return nil
+	}
+	sub := NewDependencyGraph()
+	rootPackage := root.Package
+	sub.RootPackage = &rootPackage
+	g.Walk(id, BreadthFirst, func(node *PackageNode, _ int) bool {
+		sub.AddNode(node)
+		return true
+	})
+	return sub
+}
+
+// components returns the strongly connected components of the graph in
+// dependency order: every component comes after the components it depends
+// on. Each holds sorted node IDs, and a package only forms a component with
+// others when they depend on each other in a cycle.
+func (g *DependencyGraph) components() [][]string {
+	ids := make([]string, 0, len(g.Nodes))
+	for id := range g.Nodes {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	// Tarjan's algorithm, which emits components dependencies first
+	resolver := g.resolver()
+	index := make(map[string]int, len(ids))
+	lowlink := make(map[string]int, len(ids))
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		lowlink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, depID := range resolver.dependencies(g.Nodes[id]) {
+			if _, ok := g.Nodes[depID]; !ok {
+				continue
+			}
+			if _, seen := index[depID]; !seen {
+				visit(depID)
+				lowlink[id] = min(lowlink[id], lowlink[depID])
+			} else if onStack[depID] {
+				lowlink[id] = min(lowlink[id], index[depID])
+			}
+		}
+
+		if lowlink[id] == index[id] {
+			var component []string
+			for {
+				top := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[top] = false
+				component = append(component, top)
+				if top == id {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+	for _, id := range ids {
+		if _, seen := index[id]; !seen {
+			visit(id)
+		}
+	}
+	return components
+}
+
+// TopologicalOrder returns every node ID with dependencies before their
+// dependents, so uploads and analyses can run bottom-up. Packages in a cycle
+// have no such order; they are kept next to each other, sorted by ID.
+func (g *DependencyGraph) TopologicalOrder() []string {
+	order := make([]string, 0, len(g.Nodes))
+	for _, component := range g.components() {
+		order = append(order, component...)
+	}
+	return order
+}
+
+// Cycles returns the clusters of packages that depend on each other in a
+// circle, each sorted by ID, sorted by their first ID. A package depending on
+// itself is a cluster of one.
+func (g *DependencyGraph) Cycles() [][]string {
+	resolver := g.resolver()
+	var cycles [][]string
+	for _, component := range g.components() {
+		if len(component) == 1 && !slices.Contains(resolver.dependencies(g.Nodes[component[0]]), component[0]) {
+			continue
+		}
+		cycles = append(cycles, component)
+	}
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
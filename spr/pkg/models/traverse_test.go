@@ -0,0 +1,114 @@
+package models
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testGraph: app -> a, b; a -> c; b -> c, left-pad; c -> left-pad (by range)
+func testGraph() *DependencyGraph {
+	g := NewDependencyGraph()
+	g.RootPackage = &Package{ID: "app@1.0.0", Name: "app", Version: "1.0.0"}
+	for _, n := range []*PackageNode{
+		{Package: *g.RootPackage, ResolvedDependencies: []string{"a@1.0.0", "b@1.0.0"}},
+		{Package: Package{ID: "a@1.0.0", Name: "a", Version: "1.0.0"}, ResolvedDependencies: []string{"c@1.0.0"}},
+		{Package: Package{ID: "b@1.0.0", Name: "b", Version: "1.0.0"}, ResolvedDependencies: []string{"c@1.0.0", "left-pad@1.3.0"}},
+		{Package: Package{ID: "c@1.0.0", Name: "c", Version: "1.0.0"}, Dependencies: map[string]string{"left-pad": "^1.0.0"}},
+		{Package: Package{ID: "left-pad@1.3.0", Name: "left-pad", Version: "1.3.0"}},
+		{Package: Package{ID: "unused@1.0.0", Name: "unused", Version: "1.0.0"}},
+	} {
+		g.AddNode(n)
+	}
+	return g
+}
+
+func TestWalk(t *testing.T) {
+	g := testGraph()
+	visit := func(order TraversalOrder) []string {
+		var ids []string
+		g.Walk("app@1.0.0", order, func(node *PackageNode, depth int) bool {
+			ids = append(ids, node.ID)
+			return true
+		})
+		return ids
+	}
+	assert.Equal(t, []string{"app@1.0.0", "a@1.0.0", "b@1.0.0", "c@1.0.0", "left-pad@1.3.0"}, visit(BreadthFirst))
+	assert.Equal(t, []string{"app@1.0.0", "a@1.0.0", "c@1.0.0", "left-pad@1.3.0", "b@1.0.0"}, visit(DepthFirst))
+
+	// Returning false prunes the subtree
+	var ids []string
+	g.Walk("app@1.0.0", BreadthFirst, func(node *PackageNode, depth int) bool {
+		ids = append(ids, node.ID)
+		return node.ID != "a@1.0.0" && depth < 1
+	})
+	assert.Equal(t, []string{"app@1.0.0", "a@1.0.0", "b@1.0.0"}, ids)
+}
+
+func TestGetAllDependencies(t *testing.T) {
+	var ids []string
+	for _, node := range testGraph().GetAllDependencies() {
+		ids = append(ids, node.ID)
+	}
+	assert.Equal(t, []string{"a@1.0.0", "b@1.0.0", "c@1.0.0", "left-pad@1.3.0", "unused@1.0.0"}, ids)
+}
+
+func TestGetTransitiveDependencies(t *testing.T) {
+	g := testGraph()
+	assert.Equal(t, []string{"c@1.0.0", "left-pad@1.3.0"}, g.GetTransitiveDependencies("a@1.0.0"))
+	assert.Empty(t, g.GetTransitiveDependencies("left-pad@1.3.0"))
+	assert.Empty(t, g.GetTransitiveDependencies("missing@1.0.0"))
+}
+
+func TestDependentsOf(t *testing.T) {
+	g := testGraph()
+	assert.Equal(t, []string{"b@1.0.0", "c@1.0.0"}, g.DependentsOf("left-pad"))
+	assert.Equal(t, []string{"app@1.0.0"}, g.DependentsOf("a"))
+	assert.Empty(t, g.DependentsOf("unused"))
+}
+
+func TestSubgraph(t *testing.T) {
+	g := testGraph()
+	sub := g.Subgraph("b@1.0.0")
+	require.NotNil(t, sub)
+	assert.Equal(t, "b@1.0.0", sub.RootPackage.ID)
+	assert.Len(t, sub.Nodes, 3)
+	assert.Contains(t, sub.Nodes, "left-pad@1.3.0")
+	assert.NotContains(t, sub.Nodes, "a@1.0.0")
+	assert.Nil(t, g.Subgraph("missing@1.0.0"))
+}
+
+func TestTopologicalOrder(t *testing.T) {
+	order := testGraph().TopologicalOrder()
+	require.Len(t, order, 6)
+	position := make(map[string]int)
+	for i, id := range order {
+		position[id] = i
+	}
+	assert.Less(t, position["left-pad@1.3.0"], position["c@1.0.0"])
+	assert.Less(t, position["c@1.0.0"], position["a@1.0.0"])
+	assert.Less(t, position["c@1.0.0"], position["b@1.0.0"])
+	assert.Less(t, position["b@1.0.0"], position["app@1.0.0"])
+	assert.Empty(t, testGraph().Cycles())
+}
+
+func TestCycles(t *testing.T) {
+	g := testGraph()
+	// left-pad -> a closes a -> c -> left-pad; unused depends on itself
+	g.Nodes["left-pad@1.3.0"].ResolvedDependencies = []string{"a@1.0.0"}
+	g.Nodes["unused@1.0.0"].ResolvedDependencies = []string{"unused@1.0.0"}
+
+	assert.Equal(t, [][]string{
+		{"a@1.0.0", "c@1.0.0", "left-pad@1.3.0"},
+		{"unused@1.0.0"},
+	}, g.Cycles())
+
+	// The cluster stays together, after nothing it depends on and before b
+	order := g.TopologicalOrder()
+	require.Len(t, order, 6)
+	start := slices.Index(order, "a@1.0.0")
+	assert.Equal(t, []string{"a@1.0.0", "c@1.0.0", "left-pad@1.3.0"}, order[start:start+3])
+	assert.Less(t, start, slices.Index(order, "b@1.0.0"))
+}
@@ -0,0 +1,39 @@
+// Long-duration observation of target package
+// Imports the package, then keeps the process alive so delayed or
+// date-triggered payloads (time bombs) have a chance to fire.
+// Run under faketime to simulate clock skew. The window is ended from
+// outside, on real time: the "x" and "i" modifiers speed up this process's
+// timers too, so a window timed in here would end after a tenth of the real
+// minutes under "x10". E.g.:
+//   OBSERVE_MINUTES=10 timeout --preserve-status 10m faketime -f "+30d x10" node observe.js
+
+const minutes = parseFloat(process.env.OBSERVE_MINUTES || '5');
+
+console.log('=== TEST: Long-Duration Observation ===');
+console.log('Target: {{.PackageName}}@{{.PackageVersion}}');
+console.log('Observation window:', minutes, 'minutes');
+console.log('Apparent start time:', new Date().toISOString());
+console.log('');
+
+{{if eq .ModuleType "module"}}
+// ESM import
+const target = await import('{{.PackageName}}');
+{{else}}
+// CommonJS require
+const target = require('{{.PackageName}}');
+{{end}}
+console.log('Package imported, export count:', Object.keys(target).length);
+
+// Periodic heartbeat so the log shows how far the (possibly skewed) clock advanced
+const heartbeat = setInterval(() => {
+  console.log('Heartbeat, apparent time:', new Date().toISOString());
+}, 30 * 1000);
+
+// Sent by timeout when the window has elapsed in real time
+process.on('SIGTERM', () => {
+  clearInterval(heartbeat);
+  console.log('');
+  console.log('Apparent end time:', new Date().toISOString());
+  console.log('=== Observation window elapsed ===');
+  process.exit(0);
+});